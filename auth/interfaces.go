@@ -21,3 +21,9 @@ type TokenRefresher interface {
 type TokenRefresherWithCtx interface {
 	PerformTokenRefreshCtx(ctx context.Context, refreshToken string) (accessToken, newRefreshToken string, expiresIn int64, err error)
 }
+
+// TokenValidator optionally checks with the server whether an access token is accepted.
+// It returns ErrTokenRejected if the server refuses the token.
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, accessToken string) error
+}
@@ -1,7 +1,11 @@
 package auth_test
 
 import (
+	"context"
 	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -100,3 +104,148 @@ func TestRefreshToken_WhenNoTokenInDB(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "token record does not exist")
 }
+
+// slowRefresher counts refreshes and blocks each one until release is closed.
+type slowRefresher struct {
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *slowRefresher) PerformTokenRefresh(refreshToken string) (string, string, int64, error) {
+	if m.calls.Add(1) == 1 {
+		close(m.started)
+	}
+	<-m.release
+	return "new-access-token", "new-refresh-token", 3600, nil
+}
+
+func expiredTokenStorer() *mockStorer {
+	return &mockStorer{
+		tokenToReturn: &db.Token{
+			AccessToken:  "expired-access",
+			RefreshToken: "expired-refresh",
+			ExpiresAt:    time.Now().Add(-1 * time.Hour).Format(time.RFC3339),
+		},
+	}
+}
+
+func TestRefreshTokenCtx_ConcurrentCallersShareOneRefresh(t *testing.T) {
+	refresher := &slowRefresher{started: make(chan struct{}), release: make(chan struct{})}
+	service := auth.NewService(expiredTokenStorer(), refresher)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	tokens := make([]*db.Token, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = service.RefreshTokenCtx(context.Background())
+		}(i)
+	}
+	<-refresher.started
+	time.Sleep(20 * time.Millisecond) // let the other callers find the refresh in progress
+	close(refresher.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), refresher.calls.Load(), "the token must be refreshed only once")
+	for i := 0; i < callers; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, "new-access-token", tokens[i].AccessToken)
+	}
+}
+
+func TestRefreshTokenCtx_WaiterHonorsCancellation(t *testing.T) {
+	refresher := &slowRefresher{started: make(chan struct{}), release: make(chan struct{})}
+	service := auth.NewService(expiredTokenStorer(), refresher)
+	defer close(refresher.release)
+
+	go func() { _, _ = service.RefreshTokenCtx(context.Background()) }()
+	<-refresher.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := service.RefreshTokenCtx(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+type validatingRefresher struct {
+	mockRefresher
+	err   error
+	token string
+}
+
+func (v *validatingRefresher) ValidateToken(ctx context.Context, accessToken string) error {
+	v.token = accessToken
+	return v.err
+}
+
+func TestValidate(t *testing.T) {
+	validToken := func() *db.Token {
+		return &db.Token{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Hour).Format(time.RFC3339)}
+	}
+	networkErr := errors.New("connection refused")
+
+	tests := []struct {
+		name      string
+		token     *db.Token
+		refresher auth.TokenRefresher
+		want      error
+	}{
+		{"not logged in", nil, &validatingRefresher{}, auth.ErrNotLoggedIn},
+		{"expired locally", expiredTokenStorer().tokenToReturn, &validatingRefresher{}, auth.ErrTokenExpired},
+		{"rejected by the server", validToken(), &validatingRefresher{err: auth.ErrTokenRejected}, auth.ErrTokenRejected},
+		{"server unreachable", validToken(), &validatingRefresher{err: networkErr}, networkErr},
+		{"refresher cannot validate", validToken(), &mockRefresher{}, auth.ErrValidationUnsupported},
+		{"accepted", validToken(), &validatingRefresher{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storer := &mockStorer{tokenToReturn: tt.token}
+			err := auth.NewService(storer, tt.refresher).Validate(context.Background())
+			if tt.want == nil {
+				require.NoError(t, err)
+				assert.Equal(t, "access", tt.refresher.(*validatingRefresher).token)
+			} else {
+				assert.ErrorIs(t, err, tt.want)
+			}
+			assert.False(t, storer.upsertCalled, "Validate must not refresh the token")
+		})
+	}
+}
+
+func TestCheckSession(t *testing.T) {
+	validToken := &db.Token{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Hour).Format(time.RFC3339)}
+	netErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name       string
+		token      *db.Token
+		refreshErr error
+		want       error
+	}{
+		{"not logged in", nil, nil, auth.ErrNotLoggedIn},
+		{"valid", validToken, nil, nil},
+		{"expired and refreshed", expiredTokenStorer().tokenToReturn, nil, nil},
+		{"expired and refresh refused", expiredTokenStorer().tokenToReturn, errors.New("invalid_grant"), auth.ErrSessionExpired},
+		{"expired and offline", expiredTokenStorer().tokenToReturn, netErr, netErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := auth.NewService(&mockStorer{tokenToReturn: tt.token}, &mockRefresher{errToReturn: tt.refreshErr})
+			err := service.CheckSession(context.Background())
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.want)
+			if tt.want == netErr {
+				assert.NotErrorIs(t, err, auth.ErrSessionExpired)
+			}
+		})
+	}
+}
@@ -2,17 +2,37 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/habedi/gogg/db"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// ErrNotLoggedIn means that no token is stored.
+	ErrNotLoggedIn = errors.New("no token found; please login first")
+	// ErrTokenExpired means that the stored access token has expired. It is refreshed the next time it is used.
+	ErrTokenExpired = errors.New("the access token has expired")
+	// ErrTokenRejected means that the server did not accept the access token, for example because it was revoked.
+	ErrTokenRejected = errors.New("the access token was rejected by GOG")
+	// ErrSessionExpired means that the access token has expired and could not be refreshed, so the user has to
+	// login again.
+	ErrSessionExpired = errors.New("the session has expired; please login again")
+	// ErrValidationUnsupported means that the refresher of the service cannot check tokens with the server.
+	ErrValidationUnsupported = errors.New("checking the token with the server is not supported")
 )
 
 // Service orchestrates the token refresh process using its dependencies.
+// It is safe for concurrent use; concurrent refreshes of an expired token are merged into one.
 type Service struct {
 	Storer    TokenStorer
 	Refresher TokenRefresher
+
+	refreshes singleflight.Group
 }
 
 // NewService is the constructor for our auth service.
@@ -35,8 +55,31 @@ func (s *Service) RefreshToken() (*db.Token, error) {
 	return s.RefreshTokenCtx(context.Background())
 }
 
-// RefreshTokenCtx performs refresh honoring cancellation if the refresher supports it.
+// RefreshTokenCtx returns a valid token, refreshing it first if it has expired.
+// When several goroutines find the token expired at the same time, only one of them performs the refresh
+// and the others wait for its result, so a refresh token is never used twice.
 func (s *Service) RefreshTokenCtx(ctx context.Context) (*db.Token, error) {
+	for {
+		results := s.refreshes.DoChan("token", func() (interface{}, error) {
+			return s.refresh(ctx)
+		})
+		select {
+		case res := <-results:
+			// If the refresh was only abandoned because the caller that started it was cancelled, try again
+			// with our own context.
+			if isContextError(res.Err) && ctx.Err() == nil {
+				continue
+			}
+			token, _ := res.Val.(*db.Token)
+			return token, res.Err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// refresh loads the stored token and refreshes it if it has expired, honoring cancellation if the refresher supports it.
+func (s *Service) refresh(ctx context.Context) (*db.Token, error) {
 	token, err := s.Storer.GetTokenRecord()
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve token record: %w", err)
@@ -70,6 +113,59 @@ func (s *Service) RefreshTokenCtx(ctx context.Context) (*db.Token, error) {
 	return token, nil
 }
 
+// Validate checks the stored token without refreshing it. It returns ErrNotLoggedIn if there is no token,
+// ErrTokenExpired if the token has expired locally, and ErrTokenRejected if GOG refuses it. Other errors mean
+// the token could not be checked, for example because the network is down.
+func (s *Service) Validate(ctx context.Context) error {
+	token, err := s.Storer.GetTokenRecord()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve token record: %w", err)
+	}
+	if token == nil || token.AccessToken == "" {
+		return ErrNotLoggedIn
+	}
+	valid, err := isTokenValid(token)
+	if err != nil {
+		return fmt.Errorf("failed to check token validity: %w", err)
+	}
+	if !valid {
+		return ErrTokenExpired
+	}
+	validator, ok := s.Refresher.(TokenValidator)
+	if !ok {
+		return ErrValidationUnsupported
+	}
+	return validator.ValidateToken(ctx, token.AccessToken)
+}
+
+// CheckSession checks that the stored login can still be used, refreshing the access token if it has expired.
+// It returns ErrNotLoggedIn if there is no token and ErrSessionExpired if the token could not be refreshed.
+// Failures that say nothing about the login, like network errors and cancellations, are returned as they are.
+func (s *Service) CheckSession(ctx context.Context) error {
+	token, err := s.Storer.GetTokenRecord()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve token record: %w", err)
+	}
+	if token == nil || token.AccessToken == "" {
+		return ErrNotLoggedIn
+	}
+	if valid, err := isTokenValid(token); err == nil && valid {
+		return nil
+	}
+	if _, err := s.RefreshTokenCtx(ctx); err != nil {
+		var netErr net.Error
+		if isContextError(err) || errors.As(err, &netErr) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrSessionExpired, err)
+	}
+	return nil
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
 // isTokenValid checks if the access token is still valid.
 func isTokenValid(token *db.Token) (bool, error) {
 	if token == nil {
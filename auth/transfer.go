@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/habedi/gogg/db"
+)
+
+var (
+	// ErrPassphraseRequired means that a token file is encrypted and no passphrase was given.
+	ErrPassphraseRequired = errors.New("the token file is encrypted; a passphrase is needed")
+	// ErrWrongPassphrase means that a token file could not be decrypted with the given passphrase.
+	ErrWrongPassphrase = errors.New("wrong passphrase or damaged token file")
+)
+
+// tokenFileFormat identifies the files written by EncodeTokenFile.
+const tokenFileFormat = "gogg-token"
+
+// tokenFileKDFIterations is the number of PBKDF2 iterations that turn a passphrase into the key of an
+// encrypted token file, as recommended by OWASP for PBKDF2 with SHA-256.
+const tokenFileKDFIterations = 600_000
+
+// tokenFile is a token exported with EncodeTokenFile. It has either the token or its encrypted form.
+type tokenFile struct {
+	Format    string          `json:"format"`
+	Version   int             `json:"version"`
+	Token     *tokenFields    `json:"token,omitempty"`
+	Encrypted *encryptedToken `json:"encrypted,omitempty"`
+}
+
+// tokenFields are the parts of a db.Token that are moved between machines.
+type tokenFields struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresAt    string `json:"expires_at"`
+}
+
+// encryptedToken is tokenFields as JSON, sealed with AES-256-GCM under a key derived from a passphrase.
+type encryptedToken struct {
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// EncodeTokenFile returns token as the content of a file that DecodeTokenFile reads back, to move a login to
+// another machine. With a passphrase, the token is encrypted; without one, anyone who can read the file can
+// use the GOG account.
+func EncodeTokenFile(token *db.Token, passphrase string) ([]byte, error) {
+	if token == nil || token.RefreshToken == "" {
+		return nil, ErrNotLoggedIn
+	}
+	fields := tokenFields{AccessToken: token.AccessToken, RefreshToken: token.RefreshToken, ExpiresAt: token.ExpiresAt}
+	file := tokenFile{Format: tokenFileFormat, Version: 1}
+	if passphrase == "" {
+		file.Token = &fields
+		return json.MarshalIndent(file, "", "  ")
+	}
+
+	plain, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	enc := &encryptedToken{KDF: "pbkdf2-sha256", Iterations: tokenFileKDFIterations, Salt: make([]byte, 16)}
+	if _, err := rand.Read(enc.Salt); err != nil {
+		return nil, err
+	}
+	gcm, err := tokenFileCipher(passphrase, enc.Salt, enc.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	enc.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(enc.Nonce); err != nil {
+		return nil, err
+	}
+	enc.Data = gcm.Seal(nil, enc.Nonce, plain, []byte(tokenFileFormat))
+	file.Encrypted = enc
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// TokenFileEncrypted reports whether data, the content of a token file, is encrypted.
+func TokenFileEncrypted(data []byte) bool {
+	var file tokenFile
+	return json.Unmarshal(data, &file) == nil && file.Encrypted != nil
+}
+
+// DecodeTokenFile reads a token from the content of a file written by EncodeTokenFile. An encrypted file needs
+// the passphrase it was written with; it returns ErrPassphraseRequired without one and ErrWrongPassphrase if
+// the passphrase does not match.
+func DecodeTokenFile(data []byte, passphrase string) (*db.Token, error) {
+	var file tokenFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Format != tokenFileFormat {
+		return nil, fmt.Errorf("not a token file exported by gogg")
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("unsupported token file version %d", file.Version)
+	}
+
+	fields := file.Token
+	if enc := file.Encrypted; enc != nil {
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		if enc.KDF != "pbkdf2-sha256" || enc.Iterations <= 0 || enc.Iterations > 100*tokenFileKDFIterations {
+			return nil, fmt.Errorf("unsupported key derivation %q", enc.KDF)
+		}
+		gcm, err := tokenFileCipher(passphrase, enc.Salt, enc.Iterations)
+		if err != nil {
+			return nil, err
+		}
+		if len(enc.Nonce) != gcm.NonceSize() {
+			return nil, ErrWrongPassphrase
+		}
+		plain, err := gcm.Open(nil, enc.Nonce, enc.Data, []byte(tokenFileFormat))
+		if err != nil {
+			return nil, ErrWrongPassphrase
+		}
+		fields = &tokenFields{}
+		if err := json.Unmarshal(plain, fields); err != nil {
+			return nil, fmt.Errorf("failed to parse the decrypted token: %w", err)
+		}
+	}
+	if fields == nil || fields.RefreshToken == "" {
+		return nil, fmt.Errorf("the token file has no refresh token")
+	}
+	return &db.Token{AccessToken: fields.AccessToken, RefreshToken: fields.RefreshToken, ExpiresAt: fields.ExpiresAt}, nil
+}
+
+// tokenFileCipher returns the AES-256-GCM cipher of a token file for passphrase and salt.
+func tokenFileCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,55 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenFile_RoundTrip(t *testing.T) {
+	token := &db.Token{ID: 1, AccessToken: "access", RefreshToken: "refresh", ExpiresAt: "2030-01-01T00:00:00Z"}
+	want := &db.Token{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: "2030-01-01T00:00:00Z"}
+
+	plain, err := auth.EncodeTokenFile(token, "")
+	require.NoError(t, err)
+	assert.False(t, auth.TokenFileEncrypted(plain))
+	got, err := auth.DecodeTokenFile(plain, "")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	sealed, err := auth.EncodeTokenFile(token, "correct horse")
+	require.NoError(t, err)
+	assert.True(t, auth.TokenFileEncrypted(sealed))
+	assert.NotContains(t, string(sealed), "refresh", "the token is not readable in an encrypted file")
+	got, err = auth.DecodeTokenFile(sealed, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	_, err = auth.DecodeTokenFile(sealed, "")
+	assert.ErrorIs(t, err, auth.ErrPassphraseRequired)
+	_, err = auth.DecodeTokenFile(sealed, "battery staple")
+	assert.ErrorIs(t, err, auth.ErrWrongPassphrase)
+}
+
+func TestTokenFile_Invalid(t *testing.T) {
+	_, err := auth.EncodeTokenFile(nil, "")
+	assert.ErrorIs(t, err, auth.ErrNotLoggedIn)
+
+	for _, data := range []string{
+		`not json`,
+		`{"format":"something-else","version":1}`,
+		`{"format":"gogg-token","version":2,"token":{"refresh_token":"r"}}`,
+		`{"format":"gogg-token","version":1,"token":{"access_token":"a"}}`,
+	} {
+		_, err := auth.DecodeTokenFile([]byte(data), "")
+		assert.Error(t, err, data)
+	}
+
+	plain, err := auth.EncodeTokenFile(&db.Token{RefreshToken: "refresh"}, "")
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(plain), `"format": "gogg-token"`))
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/habedi/gogg/pkg/pool"
+	"github.com/rs/zerolog/log"
+)
+
+// WithAccurateTotal makes DownloadGameFiles ask the server for the size of every file before the first file is
+// downloaded, so the overall total of the "start" progress update is the sum of the real sizes instead of the
+// sizes GOG lists in the catalogue, which are rounded and sometimes missing. This costs a redirect check and a
+// HEAD request per file up front, at most as many at a time as there are threads. The sizes found are reused, so
+// the download of a file does not send its own HEAD request; its redirect is still resolved again, because the
+// links GOG redirects to expire.
+func WithAccurateTotal() DownloadOption {
+	return func(cfg *downloadConfig) { cfg.accurateTotal = true }
+}
+
+// probeSizes finds the sizes of the files of tasks with probe, with at most threads probes at a time. It returns
+// the sizes found by the download link of their task and the total size of the files. Files whose size cannot
+// be found count with the size GOG lists for them.
+func probeSizes(ctx context.Context, tasks []downloadTask, threads int, probe func(ctx context.Context, url string) (int64, error)) (map[string]int64, int64) {
+	sizes := make(map[string]int64, len(tasks))
+	var mu sync.Mutex
+	_ = pool.Run(ctx, tasks, threads, func(ctx context.Context, task downloadTask) error {
+		size, err := probe(ctx, task.url)
+		if err != nil || size <= 0 {
+			log.Debug().Err(err).Str("file", task.fileName).Msg("Could not find the size of the file; using the size GOG lists")
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sizes[task.url] = size
+		return nil
+	})
+	var total int64
+	for _, task := range tasks {
+		if size, ok := sizes[task.url]; ok {
+			total += size
+		} else {
+			total += task.size
+		}
+	}
+	return sizes, total
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTotal returns the overall total of the "start" update in progress.
+func startTotal(t *testing.T, progress string) int64 {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(progress), "\n") {
+		var update ProgressUpdate
+		require.NoError(t, json.Unmarshal([]byte(line), &update))
+		if update.Type == "start" {
+			return update.OverallTotalBytes
+		}
+	}
+	t.Fatal("no start update")
+	return 0
+}
+
+func TestDownloadGameFiles_AccurateTotal(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	download := func(options ...DownloadOption) string {
+		var progress bytes.Buffer
+		err := DownloadGameFiles(context.Background(), "tok", fs.game(), t.TempDir(), "English", "windows", false, false, true, true, false, false, 1,
+			&progress, append([]DownloadOption{WithHTTPClient(fs.Client())}, options...)...)
+		require.NoError(t, err)
+		return progress.String()
+	}
+
+	// The catalogue lists the file as 1 MB.
+	assert.Equal(t, int64(1024*1024), startTotal(t, download()))
+	assert.Equal(t, 1, fs.heads)
+
+	fs.heads = 0
+	assert.Equal(t, int64(64*1024), startTotal(t, download(WithAccurateTotal())))
+	assert.Equal(t, 1, fs.heads, "the download reuses the size found before it")
+}
+
+func TestProbeSizes(t *testing.T) {
+	tasks := []downloadTask{
+		{url: "/a", fileName: "a", size: 100},
+		{url: "/b", fileName: "b", size: 200},
+		{url: "/c", fileName: "c", size: 300},
+	}
+	probe := func(_ context.Context, url string) (int64, error) {
+		switch url {
+		case "/a":
+			return 150, nil
+		case "/b":
+			return -1, nil // no Content-Length
+		default:
+			return 0, errors.New("network")
+		}
+	}
+
+	sizes, total := probeSizes(context.Background(), tasks, 2, probe)
+	assert.Equal(t, map[string]int64{"/a": 150}, sizes)
+	assert.Equal(t, int64(150+200+300), total, "files whose size is not found count with the size GOG lists")
+}
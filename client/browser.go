@@ -0,0 +1,117 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var (
+	// ErrBrowserNotFound means that no supported browser was found on the PATH or in the usual install locations.
+	ErrBrowserNotFound = errors.New("no Chrome, Chromium, Edge, or Brave executable found in PATH or the usual install locations")
+	// ErrInvalidBrowserPath means that the browser given with --browser-path or GOGG_BROWSER cannot be run.
+	ErrInvalidBrowserPath = errors.New("invalid browser path")
+)
+
+// browserEnv is the environment variable that overrides the browser used for logging in.
+const browserEnv = "GOGG_BROWSER"
+
+// browserNames are the executable names searched on the PATH, in order of preference.
+var browserNames = []string{
+	"google-chrome", "google-chrome-stable", "Google Chrome", "chromium", "chromium-browser", "Chromium", "chrome",
+	"msedge", "microsoft-edge", "microsoft-edge-stable", "Microsoft Edge", "brave", "brave-browser", "Brave Browser",
+}
+
+// findBrowser returns the browser to login with. A non-empty override, or else the GOGG_BROWSER environment
+// variable, is used as is if it is executable. Otherwise the PATH and then the usual install locations of
+// the current OS are searched.
+func findBrowser(override string) (string, error) {
+	if override = strings.TrimSpace(override); override == "" {
+		override = strings.TrimSpace(os.Getenv(browserEnv))
+	}
+	if override != "" {
+		if err := checkExecutable(override); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrInvalidBrowserPath, err)
+		}
+		return override, nil
+	}
+
+	for _, name := range browserNames {
+		if p, err := exec.LookPath(name); err == nil {
+			return p, nil
+		}
+	}
+	for _, p := range browserLocations(runtime.GOOS, os.Getenv) {
+		if checkExecutable(p) == nil {
+			return p, nil
+		}
+	}
+	return "", ErrBrowserNotFound
+}
+
+// browserLocations returns the usual install locations of the supported browsers on goos.
+func browserLocations(goos string, getenv func(string) string) []string {
+	switch goos {
+	case "windows":
+		var locations []string
+		for _, env := range []string{"PROGRAMFILES", "PROGRAMFILES(X86)", "LOCALAPPDATA"} {
+			root := getenv(env)
+			if root == "" {
+				continue
+			}
+			locations = append(locations,
+				filepath.Join(root, "Google", "Chrome", "Application", "chrome.exe"),
+				filepath.Join(root, "Chromium", "Application", "chrome.exe"),
+				filepath.Join(root, "Microsoft", "Edge", "Application", "msedge.exe"),
+				filepath.Join(root, "BraveSoftware", "Brave-Browser", "Application", "brave.exe"),
+			)
+		}
+		return locations
+	case "darwin":
+		var locations []string
+		for _, app := range []string{
+			"Google Chrome.app/Contents/MacOS/Google Chrome",
+			"Chromium.app/Contents/MacOS/Chromium",
+			"Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+			"Brave Browser.app/Contents/MacOS/Brave Browser",
+		} {
+			locations = append(locations, filepath.Join("/Applications", app))
+			if home := getenv("HOME"); home != "" {
+				locations = append(locations, filepath.Join(home, "Applications", app))
+			}
+		}
+		return locations
+	default:
+		return []string{
+			"/usr/bin/google-chrome",
+			"/opt/google/chrome/chrome",
+			"/usr/bin/chromium",
+			"/snap/bin/chromium",
+			"/usr/bin/microsoft-edge",
+			"/opt/microsoft/msedge/msedge",
+			"/usr/bin/brave-browser",
+			"/opt/brave.com/brave/brave",
+			"/var/lib/flatpak/exports/bin/com.google.Chrome",
+			"/var/lib/flatpak/exports/bin/org.chromium.Chromium",
+		}
+	}
+}
+
+// checkExecutable returns an error if path is not a regular file that can be run.
+func checkExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("%s is not executable", path)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindBrowser_Override(t *testing.T) {
+	dir := t.TempDir()
+	browser := filepath.Join(dir, "my-browser")
+	require.NoError(t, os.WriteFile(browser, []byte("#!/bin/sh\n"), 0o755))
+
+	got, err := findBrowser(browser)
+	require.NoError(t, err)
+	assert.Equal(t, browser, got)
+
+	t.Setenv("GOGG_BROWSER", browser)
+	got, err = findBrowser("")
+	require.NoError(t, err)
+	assert.Equal(t, browser, got, "GOGG_BROWSER is used when no path is given")
+
+	_, err = findBrowser(filepath.Join(dir, "missing"))
+	assert.ErrorIs(t, err, ErrInvalidBrowserPath)
+
+	_, err = findBrowser(dir)
+	assert.ErrorIs(t, err, ErrInvalidBrowserPath, "a directory is not a browser")
+}
+
+func TestFindBrowser_OverrideMustBeExecutable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no executable bit")
+	}
+	browser := filepath.Join(t.TempDir(), "not-executable")
+	require.NoError(t, os.WriteFile(browser, []byte("data"), 0o644))
+
+	_, err := findBrowser(browser)
+	assert.ErrorIs(t, err, ErrInvalidBrowserPath)
+	assert.Contains(t, err.Error(), "not executable")
+}
+
+func TestBrowserLocations(t *testing.T) {
+	env := map[string]string{"PROGRAMFILES": `C:\Program Files`, "HOME": "/Users/me"}
+	getenv := func(k string) string { return env[k] }
+
+	windows := browserLocations("windows", getenv)
+	assert.Contains(t, windows, filepath.Join(`C:\Program Files`, "Microsoft", "Edge", "Application", "msedge.exe"))
+	assert.Contains(t, windows, filepath.Join(`C:\Program Files`, "BraveSoftware", "Brave-Browser", "Application", "brave.exe"))
+	assert.Len(t, windows, 4, "unset locations are skipped")
+
+	mac := browserLocations("darwin", getenv)
+	assert.Contains(t, mac, "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome")
+	assert.Contains(t, mac, filepath.Join("/Users/me", "Applications", "Brave Browser.app/Contents/MacOS/Brave Browser"))
+
+	assert.Contains(t, browserLocations("linux", getenv), "/usr/bin/brave-browser")
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrByteBudgetReached means that the ByteBudget of a download was used up, so some files were not downloaded.
+var ErrByteBudgetReached = errors.New("download size limit reached")
+
+// ByteBudget caps the number of bytes downloaded by several calls of DownloadGameFiles, for example to stay
+// within the data cap of a metered connection. A file is only started if its remaining size fits into what is
+// left of the budget, and files that are already being downloaded are always finished.
+type ByteBudget struct {
+	max       int64
+	used      atomic.Int64
+	committed atomic.Int64 // bytes used plus the bytes reserved by files that are being downloaded
+}
+
+// NewByteBudget returns a ByteBudget that allows max bytes to be downloaded.
+func NewByteBudget(max int64) *ByteBudget {
+	return &ByteBudget{max: max}
+}
+
+// Max returns the number of bytes the budget allows.
+func (b *ByteBudget) Max() int64 { return b.max }
+
+// Used returns the number of bytes downloaded so far.
+func (b *ByteBudget) Used() int64 { return b.used.Load() }
+
+// Reached reports whether nothing is left of the budget.
+func (b *ByteBudget) Reached() bool { return b.Used() >= b.max }
+
+// reserve takes size bytes of the budget for a file with size bytes left to download, and reports whether the
+// file may be started. A size of zero means that the size is unknown, so the file is started as long as
+// something is left of the budget. Files that are started at the same time never reserve more than the budget.
+func (b *ByteBudget) reserve(size int64) (*budgetReservation, bool) {
+	for {
+		committed := b.committed.Load()
+		if committed >= b.max || committed+size > b.max {
+			return nil, false
+		}
+		if b.committed.CompareAndSwap(committed, committed+size) {
+			return &budgetReservation{budget: b, left: size}, true
+		}
+	}
+}
+
+// budgetReservation is the part of a ByteBudget reserved by one file. It is used by one goroutine at a time.
+type budgetReservation struct {
+	budget *ByteBudget
+	left   int64
+}
+
+// add counts n downloaded bytes. Bytes beyond the reservation, like those of a file of unknown size, are
+// taken from the budget as they arrive.
+func (r *budgetReservation) add(n int64) {
+	r.budget.used.Add(n)
+	if n <= r.left {
+		r.left -= n
+		return
+	}
+	r.budget.committed.Add(n - r.left)
+	r.left = 0
+}
+
+// release gives back the part of the reservation that the file did not download.
+func (r *budgetReservation) release() {
+	r.budget.committed.Add(-r.left)
+	r.left = 0
+}
+
+// skippedFiles counts the files of a download that were left out because the ByteBudget was used up.
+type skippedFiles struct {
+	mu    sync.Mutex
+	count int
+	bytes int64
+}
+
+func (s *skippedFiles) add(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	s.bytes += size
+}
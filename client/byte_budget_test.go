@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/habedi/gogg/pkg/pool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteBudget_ConcurrentFilesNeverReserveMoreThanTheBudget(t *testing.T) {
+	budget := NewByteBudget(100)
+	files := make([]int64, 50)
+	for i := range files {
+		files[i] = 10
+	}
+	var started atomic.Int32
+	errSkipped := errors.New("skipped")
+	pool.RunWithResults(context.Background(), files, 8, func(ctx context.Context, size int64) error {
+		reservation, ok := budget.reserve(size)
+		if !ok {
+			return errSkipped
+		}
+		defer reservation.release()
+		started.Add(1)
+		reservation.add(size)
+		return nil
+	})
+	assert.Equal(t, int32(10), started.Load())
+	assert.Equal(t, int64(100), budget.Used())
+	assert.True(t, budget.Reached())
+}
+
+func TestByteBudget_ReleaseReturnsWhatWasNotDownloaded(t *testing.T) {
+	budget := NewByteBudget(100)
+	reservation, ok := budget.reserve(80)
+	assert.True(t, ok)
+	_, ok = budget.reserve(30)
+	assert.False(t, ok, "the reserved bytes are not available to other files")
+
+	reservation.add(20)
+	reservation.release() // the file failed after 20 bytes
+	_, ok = budget.reserve(80)
+	assert.True(t, ok)
+	assert.Equal(t, int64(20), budget.Used())
+
+	// Bytes beyond a reservation, like those of a file of unknown size, are taken as they arrive.
+	budget = NewByteBudget(100)
+	unknown, ok := budget.reserve(0)
+	assert.True(t, ok)
+	unknown.add(60)
+	_, ok = budget.reserve(50)
+	assert.False(t, ok)
+}
@@ -2,14 +2,20 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/habedi/gogg/auth"
 	"github.com/habedi/gogg/db"
 	"github.com/habedi/gogg/pkg/pool"
+	"github.com/habedi/gogg/pkg/validation"
 	"github.com/rs/zerolog/log"
 )
 
@@ -17,28 +23,138 @@ func embedBase() string {
 	if v := strings.TrimSpace(os.Getenv("GOGG_EMBED_BASE")); v != "" {
 		return v
 	}
-	return "https://embed.gog.com"
+	return DefaultEmbedBase
+}
+
+// endpoint is where the requests for the games of the account go: the embed API at base, sent with client, or
+// with a client of their own if client is nil.
+type endpoint struct {
+	base   string
+	client *http.Client
+}
+
+func defaultEndpoint() endpoint { return endpoint{base: embedBase()} }
+
+func (e endpoint) ownedGamesURL() string { return e.base + "/user/data/games" }
+
+func (e endpoint) gameDetailsURL(id int) string {
+	return fmt.Sprintf("%s/account/gameDetails/%d.json", e.base, id)
+}
+
+// Reasons of a RefreshFailure.
+const (
+	RefreshRateLimited   = "rate limited"
+	RefreshAuthFailed    = "authentication failed"
+	RefreshNotFound      = "not found"
+	RefreshParseError    = "parse error"
+	RefreshNetworkError  = "network error"
+	RefreshDatabaseError = "database error"
+)
+
+// RefreshFailure is a game that could not be added to the catalogue by RefreshCatalogue.
+type RefreshFailure struct {
+	GameID int
+	Reason string // one of the Refresh* reasons
+	Err    error
+}
+
+// RefreshOption configures RefreshCatalogue.
+type RefreshOption func(*refreshConfig)
+
+type refreshConfig struct {
+	onFailure    func(RefreshFailure)
+	gameIDs      []int
+	fetchTimeout time.Duration
+}
+
+// WithRefreshFailures makes RefreshCatalogue call onFailure for every game whose details could not be fetched
+// or stored. It is called from several goroutines at the same time, and not for the games left out because
+// the refresh was cancelled.
+func WithRefreshFailures(onFailure func(RefreshFailure)) RefreshOption {
+	return func(cfg *refreshConfig) { cfg.onFailure = onFailure }
+}
+
+// WithRefreshGameIDs makes RefreshCatalogue fetch only the games with the given IDs, like the failures of an
+// earlier refresh, and update them in the catalogue instead of replacing the whole catalogue.
+func WithRefreshGameIDs(ids []int) RefreshOption {
+	return func(cfg *refreshConfig) { cfg.gameIDs = ids }
+}
+
+// WithFetchTimeout sets how long each request of RefreshCatalogue may take before it is retried, instead of
+// DefaultFetchTimeout. Slow connections may need more for the details of games with many files.
+func WithFetchTimeout(timeout time.Duration) RefreshOption {
+	return func(cfg *refreshConfig) { cfg.fetchTimeout = timeout }
+}
+
+// refreshFailureReason tells why the details of a game could not be fetched.
+func refreshFailureReason(err error) string {
+	var statusErr *HTTPStatusError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &statusErr):
+		switch statusErr.StatusCode {
+		case http.StatusTooManyRequests:
+			return RefreshRateLimited
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return RefreshAuthFailed
+		case http.StatusNotFound:
+			return RefreshNotFound
+		}
+		return RefreshNetworkError
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return RefreshParseError
+	default:
+		return RefreshNetworkError
+	}
 }
 
 // RefreshCatalogue fetches all owned game details from GOG and updates the local database via the provided repo.
 // It reports progress via the progressCb callback, which receives a value from 0.0 to 1.0.
+// Each game is stored as soon as its details arrive, so only the games being fetched are held in memory however
+// large the library is, and a refresh that is cancelled midway keeps the games it has already stored.
 func RefreshCatalogue(
 	ctx context.Context,
 	authService *auth.Service,
 	repo db.GameRepository,
 	numWorkers int,
 	progressCb func(float64),
+	opts ...RefreshOption,
 ) error {
+	return refreshCatalogue(ctx, defaultEndpoint(), authService, repo, numWorkers, progressCb, opts...)
+}
+
+func refreshCatalogue(
+	ctx context.Context,
+	api endpoint,
+	authService *auth.Service,
+	repo db.GameRepository,
+	numWorkers int,
+	progressCb func(float64),
+	opts ...RefreshOption,
+) error {
+	cfg := refreshConfig{fetchTimeout: DefaultFetchTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	fail := func(id int, reason string, err error) {
+		if cfg.onFailure != nil && ctx.Err() == nil {
+			cfg.onFailure(RefreshFailure{GameID: id, Reason: reason, Err: err})
+		}
+	}
+
 	// Prefer context-aware token refresh to honor cancellations/timeouts
 	token, err := authService.RefreshTokenCtx(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to refresh token: %w", err)
 	}
 
-	ownedURL := fmt.Sprintf("%s/user/data/games", embedBase())
-	gameIDs, err := FetchAllOwnedGameIDs(ctx, token.AccessToken, ownedURL)
-	if err != nil {
-		return fmt.Errorf("failed to fetch owned game IDs: %w", err)
+	gameIDs := cfg.gameIDs
+	if gameIDs == nil {
+		gameIDs, err = fetchAllOwnedGameIDs(ctx, api.client, token.AccessToken, api.ownedGamesURL(), cfg.fetchTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to fetch owned game IDs: %w", err)
+		}
 	}
 	if len(gameIDs) == 0 {
 		log.Info().Msg("No games found in the GOG account.")
@@ -48,8 +164,11 @@ func RefreshCatalogue(
 		return nil
 	}
 
-	if err := repo.Clear(ctx); err != nil {
-		return fmt.Errorf("failed to empty catalogue: %w", err)
+	// Only a full refresh replaces the catalogue; the games of a partial one are updated in place.
+	if cfg.gameIDs == nil {
+		if err := repo.Clear(ctx); err != nil {
+			return fmt.Errorf("failed to empty catalogue: %w", err)
+		}
 	}
 
 	var processedCount atomic.Int64
@@ -65,20 +184,183 @@ func RefreshCatalogue(
 			}
 		}()
 
-		url := fmt.Sprintf("%s/account/gameDetails/%d.json", embedBase(), id)
-		details, raw, fetchErr := FetchGameData(ctx, token.AccessToken, url)
+		details, raw, fetchErr := fetchGameData(ctx, api.client, token.AccessToken, api.gameDetailsURL(id), cfg.fetchTimeout)
 		if fetchErr != nil {
 			log.Warn().Err(fetchErr).Int("gameID", id).Msg("Failed to fetch game details")
+			fail(id, refreshFailureReason(fetchErr), fetchErr)
 			return nil
 		}
 		if details.Title != "" {
-			_ = repo.Put(ctx, db.Game{ID: id, Title: details.Title, Data: raw})
+			if putErr := repo.Put(ctx, db.Game{ID: id, Title: details.Title, Data: raw}); putErr != nil {
+				log.Warn().Err(putErr).Int("gameID", id).Msg("Failed to store game details")
+				fail(id, RefreshDatabaseError, putErr)
+			}
 		}
 
 		return nil
 	}
 
-	_ = pool.Run(ctx, gameIDs, numWorkers, workerFunc)
+	_ = pool.Run(ctx, gameIDs, validation.ClampThreadCount(numWorkers), workerFunc)
 
 	return ctx.Err()
 }
+
+// OwnedGame is a game owned by the GOG account, as returned by FetchOwnedGames.
+type OwnedGame struct {
+	ID    int    `json:"id"`
+	Title string `json:"title,omitempty"`
+}
+
+// FetchOwnedGames returns the games owned by the GOG account, sorted by ID, without changing the catalogue.
+// The IDs take only a few requests. With titles, the details of every game are fetched as well, numWorkers at
+// a time, to get its title; games whose details could not be fetched are logged and keep an empty title.
+func FetchOwnedGames(ctx context.Context, authService *auth.Service, titles bool, numWorkers int) ([]OwnedGame, error) {
+	return fetchOwnedGames(ctx, defaultEndpoint(), authService, titles, numWorkers)
+}
+
+func fetchOwnedGames(ctx context.Context, api endpoint, authService *auth.Service, titles bool, numWorkers int) ([]OwnedGame, error) {
+	token, err := authService.RefreshTokenCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	ids, err := fetchAllOwnedGameIDs(ctx, api.client, token.AccessToken, api.ownedGamesURL(), DefaultFetchTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch owned game IDs: %w", err)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	games := make([]OwnedGame, len(ids))
+	index := make(map[int]int, len(ids))
+	for i, id := range ids {
+		games[i].ID = id
+		index[id] = i
+	}
+	if !titles {
+		return games, nil
+	}
+
+	// Every worker writes only to the entry of its own game.
+	_ = pool.Run(ctx, ids, validation.ClampThreadCount(numWorkers), func(ctx context.Context, id int) error {
+		details, _, fetchErr := fetchGameData(ctx, api.client, token.AccessToken, api.gameDetailsURL(id), DefaultFetchTimeout)
+		if fetchErr != nil {
+			log.Warn().Err(fetchErr).Int("gameID", id).Msg("Failed to fetch game details")
+			return nil
+		}
+		games[index[id]].Title = details.Title
+		return nil
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+// placeholderKey marks the data of a game added by RefreshCatalogueTitles, which knows only its title.
+const placeholderKey = "gogg_placeholder"
+
+// PlaceholderData returns the data stored for a game that is known only by its title, until a refresh of its
+// details replaces it. It parses like the details of a game without files.
+func PlaceholderData(title string) string {
+	data, _ := json.Marshal(map[string]interface{}{"title": title, placeholderKey: true})
+	return string(data)
+}
+
+// IsPlaceholder reports whether data is the data of a game whose details have not been fetched yet.
+func IsPlaceholder(data string) bool {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &probe); err != nil {
+		return false
+	}
+	_, ok := probe[placeholderKey]
+	return ok
+}
+
+// ownedProductsPage is a page of the list of owned products, with the title but not the files of each game.
+type ownedProductsPage struct {
+	TotalPages int         `json:"totalPages"`
+	Products   []OwnedGame `json:"products"`
+}
+
+// fetchOwnedTitles returns the IDs and titles of the games owned by the GOG account. The list is paged, so it
+// takes a request for every hundred or so games instead of one for every game.
+func fetchOwnedTitles(ctx context.Context, accessToken string, timeout time.Duration) ([]OwnedGame, error) {
+	var games []OwnedGame
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/account/getFilteredProducts?mediaType=1&page=%d", embedBase(), page)
+		req, err := createRequest(ctx, "GET", url, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := sendRequest(req, timeout)
+		if err != nil {
+			return nil, err
+		}
+		body, err := readResponseBody(resp)
+		closeResponseBody(resp)
+		if err != nil {
+			return nil, err
+		}
+		var p ownedProductsPage
+		if err := json.Unmarshal(body, &p); err != nil {
+			return nil, fmt.Errorf("failed to parse page %d of the owned products: %w", page, err)
+		}
+		games = append(games, p.Products...)
+		if page >= p.TotalPages || len(p.Products) == 0 {
+			return games, nil
+		}
+	}
+}
+
+// TitleRefreshResult tells how RefreshCatalogueTitles changed the catalogue.
+type TitleRefreshResult struct {
+	Added   int // games that were not in the catalogue, added with placeholder data
+	Renamed int // games in the catalogue whose title changed
+}
+
+// RefreshCatalogueTitles updates the catalogue with the games owned by the GOG account and their titles,
+// without fetching the details of every game. Games that are not in the catalogue yet are added with
+// PlaceholderData, so a later RefreshCatalogue with WithRefreshGameIDs can fetch just their details. Games
+// already in the catalogue keep their details, and games that are no longer owned are kept as well.
+// Only WithFetchTimeout of opts is used.
+func RefreshCatalogueTitles(ctx context.Context, authService *auth.Service, repo db.GameRepository, opts ...RefreshOption) (TitleRefreshResult, error) {
+	var result TitleRefreshResult
+	cfg := refreshConfig{fetchTimeout: DefaultFetchTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	token, err := authService.RefreshTokenCtx(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	owned, err := fetchOwnedTitles(ctx, token.AccessToken, cfg.fetchTimeout)
+	if err != nil {
+		return result, fmt.Errorf("failed to fetch owned products: %w", err)
+	}
+
+	for _, g := range owned {
+		existing, err := repo.GetByID(ctx, g.ID)
+		if err != nil {
+			return result, fmt.Errorf("failed to read game %d: %w", g.ID, err)
+		}
+		var put db.Game
+		switch {
+		case existing == nil:
+			put = db.Game{ID: g.ID, Title: g.Title, Data: PlaceholderData(g.Title)}
+			result.Added++
+		case existing.Title != g.Title && g.Title != "":
+			put = db.Game{ID: g.ID, Title: g.Title, Data: existing.Data}
+			if IsPlaceholder(existing.Data) {
+				put.Data = PlaceholderData(g.Title)
+			}
+			result.Renamed++
+		default:
+			continue
+		}
+		if err := repo.Put(ctx, put); err != nil {
+			return result, fmt.Errorf("failed to store game %d: %w", g.ID, err)
+		}
+	}
+	return result, nil
+}
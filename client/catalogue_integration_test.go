@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -104,3 +105,109 @@ func TestIntegration_RefreshCatalogue_WithPaginationAndEdgeCases(t *testing.T) {
 		t.Fatalf("game 2 should have empty or missing title, got: %+v", g2)
 	}
 }
+
+func TestIntegration_RefreshCatalogue_ReportsFailuresAndRetries(t *testing.T) {
+	setupMemDB(t)
+
+	limited := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user/data/games":
+			json.NewEncoder(w).Encode(map[string]interface{}{"owned": []int{1, 2, 3}})
+		case "/account/gameDetails/1.json":
+			json.NewEncoder(w).Encode(map[string]interface{}{"title": "Game One", "downloads": [][]interface{}{}})
+		case "/account/gameDetails/2.json":
+			if limited {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"title": "Game Two", "downloads": [][]interface{}{}})
+		case "/account/gameDetails/3.json":
+			w.Write([]byte("{not json"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv("GOGG_EMBED_BASE", server.URL)
+	defer os.Unsetenv("GOGG_EMBED_BASE")
+
+	svc := auth.NewService(memTokenStore{}, staticRefresher{})
+	repo := db.NewGameRepository(db.GetDB())
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	reasons := make(map[int]string)
+	collect := WithRefreshFailures(func(f RefreshFailure) {
+		mu.Lock()
+		defer mu.Unlock()
+		reasons[f.GameID] = f.Reason
+	})
+	if err := RefreshCatalogue(ctx, svc, repo, 3, nil, collect); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if len(reasons) != 2 || reasons[2] != RefreshRateLimited || reasons[3] != RefreshParseError {
+		t.Fatalf("unexpected failures: %v", reasons)
+	}
+
+	// Retrying only game 2 keeps the games already in the catalogue.
+	limited = false
+	reasons = make(map[int]string)
+	if err := RefreshCatalogue(ctx, svc, repo, 3, nil, collect, WithRefreshGameIDs([]int{2})); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if len(reasons) != 0 {
+		t.Fatalf("unexpected failures on retry: %v", reasons)
+	}
+	for id, title := range map[int]string{1: "Game One", 2: "Game Two"} {
+		g, err := db.GetGameByID(id)
+		if err != nil || g == nil || g.Title != title {
+			t.Fatalf("game %d not stored correctly: %+v err=%v", id, g, err)
+		}
+	}
+}
+
+func TestIntegration_RefreshCatalogue_CancelledKeepsStoredGames(t *testing.T) {
+	setupMemDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user/data/games":
+			json.NewEncoder(w).Encode(map[string]interface{}{"owned": []int{1, 2, 3, 4, 5}})
+		case "/account/gameDetails/1.json":
+			json.NewEncoder(w).Encode(map[string]interface{}{"title": "Game One", "downloads": [][]interface{}{}})
+		case "/account/gameDetails/2.json":
+			json.NewEncoder(w).Encode(map[string]interface{}{"title": "Game Two", "downloads": [][]interface{}{}})
+		default:
+			// The refresh is interrupted while it fetches game 3.
+			cancel()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv("GOGG_EMBED_BASE", server.URL)
+	defer os.Unsetenv("GOGG_EMBED_BASE")
+
+	svc := auth.NewService(memTokenStore{}, staticRefresher{})
+	repo := db.NewGameRepository(db.GetDB())
+	// One worker fetches the games in order, so games 1 and 2 are done before game 3 is asked for.
+	if err := RefreshCatalogue(ctx, svc, repo, 1, nil); err != context.Canceled {
+		t.Fatalf("expected the refresh to be cancelled, got: %v", err)
+	}
+
+	for id, title := range map[int]string{1: "Game One", 2: "Game Two"} {
+		g, err := db.GetGameByID(id)
+		if err != nil || g == nil || g.Title != title {
+			t.Fatalf("game %d fetched before the interruption was not kept: %+v err=%v", id, g, err)
+		}
+	}
+	for _, id := range []int{3, 4, 5} {
+		if g, err := db.GetGameByID(id); err != nil || g != nil {
+			t.Fatalf("game %d should not be stored: %+v err=%v", id, g, err)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package client_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaceholderData(t *testing.T) {
+	data := client.PlaceholderData(`A "Quoted" Game`)
+	assert.True(t, client.IsPlaceholder(data))
+
+	game, err := client.ParseGameData(data)
+	require.NoError(t, err, "placeholder data parses like the details of a game without files")
+	assert.Equal(t, `A "Quoted" Game`, game.Title)
+	assert.Empty(t, game.Downloads)
+
+	details, err := json.Marshal(map[string]interface{}{"title": "Game", "downloads": []interface{}{}})
+	require.NoError(t, err)
+	assert.False(t, client.IsPlaceholder(string(details)))
+	assert.False(t, client.IsPlaceholder(""))
+	assert.False(t, client.IsPlaceholder("not json"))
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	netURL "net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/habedi/gogg/pkg/hasher"
+)
+
+// ChecksumFileSuffix is appended to the name of a file to get the name of the checksum file GOG publishes for
+// it, both on GOG's servers and next to the files downloaded with WithChecksumFiles.
+const ChecksumFileSuffix = ".xml"
+
+// WithChecksumFiles makes DownloadGameFiles store the checksum file GOG publishes for each installer and patch
+// next to it, named like the file with ChecksumFileSuffix appended, so the files can be checked with GOG's own
+// data later without a connection. The checksum file has the MD5 checksum of the whole file and of each of its
+// chunks. Files without a checksum file, like most extras, are downloaded as usual; a checksum file that cannot
+// be fetched is only logged.
+func WithChecksumFiles() DownloadOption {
+	return func(cfg *downloadConfig) { cfg.checksumFiles = true }
+}
+
+// fetchChecksumXML returns the checksum XML GOG publishes for a file, or nil if it has none. downloadURL is the
+// URL the download link of the file redirects to; GOG serves the checksum as XML at the same path with ".xml"
+// appended, like <file name="setup.exe" md5="..." total_size="..."><chunk ...>...</chunk></file>.
+func fetchChecksumXML(ctx context.Context, c *http.Client, accessToken, downloadURL string) ([]byte, error) {
+	u, err := netURL.Parse(downloadURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path += ChecksumFileSuffix
+	u.RawPath = ""
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d when fetching the checksum", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// fetchFileChecksum returns the MD5 checksum GOG publishes for a file, or "" if it has none. See fetchChecksumXML.
+func fetchFileChecksum(ctx context.Context, c *http.Client, accessToken, downloadURL string) (string, error) {
+	data, err := fetchChecksumXML(ctx, c, accessToken, downloadURL)
+	if err != nil || data == nil {
+		return "", err
+	}
+	return parseChecksumXML(data)
+}
+
+// parseChecksumXML returns the MD5 checksum of the whole file in a checksum XML of GOG.
+func parseChecksumXML(data []byte) (string, error) {
+	var info struct {
+		MD5 string `xml:"md5,attr"`
+	}
+	if err := xml.Unmarshal(data, &info); err != nil {
+		return "", fmt.Errorf("failed to parse the checksum: %w", err)
+	}
+	return strings.ToLower(strings.TrimSpace(info.MD5)), nil
+}
+
+// saveChecksumFile stores the checksum XML GOG publishes for downloadURL next to the file at path. It returns
+// false if GOG publishes none.
+func saveChecksumFile(ctx context.Context, c *http.Client, accessToken, downloadURL, path string, mode os.FileMode) (bool, error) {
+	data, err := fetchChecksumXML(ctx, c, accessToken, downloadURL)
+	if err != nil || data == nil {
+		return false, err
+	}
+	if _, err := parseChecksumXML(data); err != nil {
+		return false, err
+	}
+	return true, writeFile(path+ChecksumFileSuffix, data, mode)
+}
+
+// ReadChecksumFile returns the MD5 checksum in a checksum file of GOG stored with WithChecksumFiles.
+func ReadChecksumFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return parseChecksumXML(data)
+}
+
+// FindChecksumFiles returns the checksum files stored with WithChecksumFiles under dir, relative to dir and in
+// sorted order: the files whose name ends in ChecksumFileSuffix and that lie next to the file they are named after.
+func FindChecksumFiles(dir string) ([]string, error) {
+	var found []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ChecksumFileSuffix) {
+			return nil
+		}
+		if info, err := os.Stat(strings.TrimSuffix(path, ChecksumFileSuffix)); err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		found = append(found, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(found)
+	return found, err
+}
+
+// verifyFileChecksum reports whether the file at path matches the checksum GOG publishes for downloadURL.
+// A file without a published checksum is taken as valid.
+func verifyFileChecksum(ctx context.Context, c *http.Client, accessToken, downloadURL, path string) (bool, error) {
+	want, err := fetchFileChecksum(ctx, c, accessToken, downloadURL)
+	if err != nil || want == "" {
+		return true, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	got, err := hasher.GenerateHashFromReader(f, "md5")
+	if err != nil {
+		return false, err
+	}
+	return got == want, nil
+}
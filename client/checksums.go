@@ -0,0 +1,99 @@
+package client
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/habedi/gogg/pkg/hasher"
+)
+
+// ChecksumFileName is the name of the checksum file written to a game directory by WithChecksums.
+func ChecksumFileName(algo string) string {
+	return "CHECKSUMS." + strings.ToLower(algo)
+}
+
+// WithChecksums makes DownloadGameFiles hash each file while it is written and store the checksums in a
+// ChecksumFileName(algo) file in the game directory, in the format of md5sum and sha256sum. The file lists
+// the files of this download only. algo must be one of hasher.HashAlgorithms.
+func WithChecksums(algo string) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.checksumAlgo = strings.ToLower(algo) }
+}
+
+// checksums collects the checksums of the downloaded files per game directory.
+type checksums struct {
+	algo  string
+	mu    sync.Mutex
+	byDir map[string]map[string]string // game directory -> slash-separated path relative to it -> checksum
+}
+
+func newChecksums(algo string) (*checksums, error) {
+	if !hasher.IsValidHashAlgo(algo) {
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algo)
+	}
+	return &checksums{algo: algo, byDir: make(map[string]map[string]string)}, nil
+}
+
+// newHash returns a hash that has already consumed the first n bytes of the file at path, so a resumed
+// download only needs to feed it the rest.
+func (c *checksums) newHash(path string, n int64) (hash.Hash, error) {
+	h, err := hasher.New(c.algo)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return h, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.CopyN(h, f, n); err != nil {
+		return nil, fmt.Errorf("failed to hash the downloaded part of %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// add records the checksum h of the file at path in gameDir and returns it.
+func (c *checksums) add(gameDir, path string, h hash.Hash) string {
+	rel, err := filepath.Rel(gameDir, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byDir[gameDir] == nil {
+		c.byDir[gameDir] = make(map[string]string)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	c.byDir[gameDir][filepath.ToSlash(rel)] = sum
+	return sum
+}
+
+// write writes the checksum file of every game directory, with the files sorted by path.
+func (c *checksums) write(modes FileModes) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for dir, sums := range c.byDir {
+		paths := make([]string, 0, len(sums))
+		for p := range sums {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		var b strings.Builder
+		for _, p := range paths {
+			b.WriteString(sums[p] + "  " + p + "\n")
+		}
+		if err := writeFile(filepath.Join(dir, ChecksumFileName(c.algo)), []byte(b.String()), modes.File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
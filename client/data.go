@@ -1,21 +1,11 @@
 package client
 
-import "encoding/json"
-
-// GameLanguages is a map of language codes to their full names.
-var GameLanguages = map[string]string{
-	"en":      "English",
-	"fr":      "Français",
-	"de":      "Deutsch",
-	"es":      "Español",
-	"it":      "Italiano",
-	"ru":      "Русский",
-	"pl":      "Polski",
-	"pt-BR":   "Português do Brasil",
-	"zh-Hans": "简体中文",
-	"ja":      "日本語",
-	"ko":      "한국어",
-}
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+)
 
 // Game contains information about a game and its downloadable content like extras and DLCs.
 type Game struct {
@@ -24,6 +14,8 @@ type Game struct {
 	Downloads       []Downloadable `json:"downloads"`
 	Extras          []Extra        `json:"extras"`
 	DLCs            []DLC          `json:"dlcs"`
+	Changelog       string         `json:"changelog,omitempty"` // HTML release notes, empty for most games
+	ForumLink       string         `json:"forumLink,omitempty"`
 }
 
 // PlatformFile contains information about a platform-specific installation file.
@@ -69,7 +61,7 @@ func (gd *Game) UnmarshalJSON(data []byte) error {
 	type Alias Game
 	// Unmarshal into a temporary value to avoid aliasing into a possibly nil receiver
 	var tmp struct {
-		RawDownloads [][]interface{} `json:"downloads"`
+		RawDownloads []json.RawMessage `json:"downloads"`
 		Alias
 	}
 	if err := json.Unmarshal(data, &tmp); err != nil {
@@ -78,8 +70,20 @@ func (gd *Game) UnmarshalJSON(data []byte) error {
 	// Copy basic fields
 	*gd = Game(tmp.Alias)
 
-	// Process RawDownloads for Game.
-	gd.Downloads = parseRawDownloads(tmp.RawDownloads)
+	// Process RawDownloads for Game. GOG sends [language, platforms] pairs, while the metadata.json written
+	// by DownloadGameFiles has the marshaled Downloadable objects.
+	gd.Downloads = nil
+	for _, raw := range tmp.RawDownloads {
+		var pair []interface{}
+		if json.Unmarshal(raw, &pair) == nil {
+			gd.Downloads = append(gd.Downloads, parseRawDownloads([][]interface{}{pair})...)
+			continue
+		}
+		var download Downloadable
+		if json.Unmarshal(raw, &download) == nil && download.Language != "" {
+			gd.Downloads = append(gd.Downloads, download)
+		}
+	}
 
 	// Process DLC downloads.
 	for i, dlc := range gd.DLCs {
@@ -134,3 +138,20 @@ func parsePlatforms(data interface{}) (Platform, error) {
 
 	return platforms, nil
 }
+
+var (
+	changelogBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</li>|</h[1-6]>`)
+	changelogTags   = regexp.MustCompile(`<[^>]*>`)
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+)
+
+// ChangelogText returns the changelog of the game as plain text, or an empty string if GOG has none for it.
+func (gd *Game) ChangelogText() string {
+	text := changelogBreaks.ReplaceAllString(gd.Changelog, "\n")
+	text = html.UnescapeString(changelogTags.ReplaceAllString(text, ""))
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
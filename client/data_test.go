@@ -118,3 +118,48 @@ func TestHandlesEmptyDownloads(t *testing.T) {
 	assert.Empty(t, game.Extras)
 	assert.Empty(t, game.DLCs)
 }
+
+// TestParsesChangelog tests that the changelog and the forum link of a game are kept and the changelog is
+// converted to plain text.
+func TestParsesChangelog(t *testing.T) {
+	jsonData := `{
+		"title": "Test Game",
+		"downloads": [],
+		"extras": [],
+		"dlcs": [],
+		"forumLink": "https://embed.gog.com/forum/test_game",
+		"changelog": "<h4>Update 1.1 &amp; hotfix</h4><ul><li>Fixed crashes</li><li>Added <b>new</b> maps</li></ul><p>Thanks!<br>The team</p>"
+	}`
+	game := UnmarshalGameData(t, jsonData)
+
+	assert.Equal(t, "https://embed.gog.com/forum/test_game", game.ForumLink)
+	assert.Equal(t, "Update 1.1 & hotfix\nFixed crashes\nAdded new maps\nThanks!\nThe team", game.ChangelogText())
+
+	empty := UnmarshalGameData(t, `{"title": "Other Game", "downloads": [], "extras": [], "dlcs": []}`)
+	assert.Empty(t, empty.ChangelogText())
+}
+
+// TestHasFiles tests that a pre-order without files is told apart from games with files of their own or of a DLC.
+func TestHasFiles(t *testing.T) {
+	unreleased := UnmarshalGameData(t, `{
+		"title": "Unreleased Game",
+		"isPreOrder": true,
+		"releaseTimestamp": 1893456000,
+		"downloads": [],
+		"galaxyDownloads": [],
+		"extras": [],
+		"dlcs": []
+	}`)
+	assert.False(t, unreleased.HasFiles())
+
+	withDLC := UnmarshalGameData(t, `{
+		"title": "Test Game",
+		"downloads": [["English", {}]],
+		"extras": [],
+		"dlcs": [{"title": "Test DLC", "downloads": [["English", {"linux": [{"name": "dlc.sh", "size": "1 MB"}]}]]}]
+	}`)
+	assert.True(t, withDLC.HasFiles())
+
+	extrasOnly := UnmarshalGameData(t, `{"title": "Test Game", "downloads": [], "extras": [{"name": "Manual", "size": "1 MB"}], "dlcs": []}`)
+	assert.True(t, extrasOnly.HasFiles())
+}
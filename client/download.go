@@ -5,30 +5,59 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net"
 	"net/http"
 	netURL "net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/habedi/gogg/pkg/pool"
+	"github.com/habedi/gogg/pkg/progress"
+	"github.com/habedi/gogg/pkg/validation"
 	"github.com/rs/zerolog/log"
 )
 
 // ProgressUpdate defines the structure for progress messages.
 type ProgressUpdate struct {
-	Type              string `json:"type"` // "start", "file_progress", "status"
+	Type              string `json:"type"` // "start", "file_progress", "file_done", "status"
 	FileName          string `json:"file,omitempty"`
 	CurrentBytes      int64  `json:"current,omitempty"`
 	TotalBytes        int64  `json:"total,omitempty"`
 	OverallTotalBytes int64  `json:"overall_total,omitempty"`
+	// The fields below are only set by "file_done", which is sent once for every file when it is done with.
+	Outcome      string `json:"outcome,omitempty"`      // one of the File* outcomes
+	Received     int64  `json:"received,omitempty"`     // bytes received for the file in this run, over all attempts
+	Retries      int    `json:"retries,omitempty"`      // attempts after the first one
+	Verification string `json:"verification,omitempty"` // one of the Verification* results, with WithVerifyOnResume
+	Checksum     string `json:"checksum,omitempty"`     // the checksum written by WithChecksums
+	Error        string `json:"error,omitempty"`
 }
 
+// Outcomes of the files of a download, sent in the "file_done" progress updates.
+const (
+	FileDownloaded = "downloaded" // the file, or the rest of it, was downloaded
+	FileComplete   = "complete"   // the file was already complete on disk
+	FileSkipped    = "skipped"    // the file was left out for the download size limit
+	FileLinked     = "linked"     // the file was linked from the library, see WithLibrary
+	FileExcluded   = "excluded"   // the name of the file matches a pattern of WithExcludedFiles
+	FileFailed     = "failed"
+)
+
+// Results of checking a resumed file against the checksum GOG publishes for it, see WithVerifyOnResume.
+const (
+	VerificationPassed      = "passed"      // the file matches, or GOG publishes no checksum for it
+	VerificationMismatch    = "mismatch"    // the file does not match; a complete file is downloaded again
+	VerificationUnavailable = "unavailable" // the checksum could not be fetched or the file not read
+)
+
 // syncWriter serializes writes to an underlying writer.
 type syncWriter struct {
 	w  io.Writer
@@ -42,12 +71,18 @@ func (sw *syncWriter) Write(p []byte) (int, error) {
 }
 
 // progressReader wraps an io.Reader to send progress updates through an io.Writer.
+// When interval is set, updates are sent at most once per interval; the bytes read in between are
+// reported by the next update, and flush sends the last one once the file is read.
 type progressReader struct {
 	reader     io.Reader
 	writer     io.Writer
 	fileName   string
 	totalSize  int64
 	bytesRead  int64
+	interval   time.Duration
+	budget     *budgetReservation // counts the bytes read if set
+	lastSent   time.Time
+	pending    bool
 	updateLock sync.Mutex
 }
 
@@ -60,27 +95,55 @@ func (pr *progressReader) writeProgress(data []byte) {
 func (pr *progressReader) Read(p []byte) (int, error) {
 	n, err := pr.reader.Read(p)
 	if n > 0 {
+		if pr.budget != nil {
+			pr.budget.add(int64(n))
+		}
 		pr.updateLock.Lock()
 		pr.bytesRead += int64(n)
 		currentBytes := pr.bytesRead
+		now := time.Now()
+		complete := pr.totalSize > 0 && currentBytes >= pr.totalSize
+		send := pr.interval <= 0 || complete || now.Sub(pr.lastSent) >= pr.interval
+		if send {
+			pr.lastSent = now
+		}
+		pr.pending = !send
 		pr.updateLock.Unlock()
 
-		update := ProgressUpdate{
-			Type:         "file_progress",
-			FileName:     pr.fileName,
-			CurrentBytes: currentBytes,
-			TotalBytes:   pr.totalSize,
-		}
-		jsonUpdate, jsonErr := json.Marshal(update)
-		if jsonErr != nil {
-			log.Error().Err(jsonErr).Msg("Failed to marshal progress update")
-		} else {
-			pr.writeProgress(append(jsonUpdate, '\n'))
+		if send {
+			pr.sendUpdate(currentBytes)
 		}
 	}
 	return n, err
 }
 
+// flush sends an update with the bytes read so far if the last ones were held back by the throttle.
+func (pr *progressReader) flush() {
+	pr.updateLock.Lock()
+	pending, currentBytes := pr.pending, pr.bytesRead
+	pr.pending = false
+	pr.updateLock.Unlock()
+
+	if pending {
+		pr.sendUpdate(currentBytes)
+	}
+}
+
+func (pr *progressReader) sendUpdate(currentBytes int64) {
+	update := ProgressUpdate{
+		Type:         "file_progress",
+		FileName:     pr.fileName,
+		CurrentBytes: currentBytes,
+		TotalBytes:   pr.totalSize,
+	}
+	jsonUpdate, jsonErr := json.Marshal(update)
+	if jsonErr != nil {
+		log.Error().Err(jsonErr).Msg("Failed to marshal progress update")
+	} else {
+		pr.writeProgress(append(jsonUpdate, '\n'))
+	}
+}
+
 func ParseGameData(data string) (Game, error) {
 	var rawResponse Game
 	if err := json.Unmarshal([]byte(data), &rawResponse); err != nil {
@@ -90,25 +153,6 @@ func ParseGameData(data string) (Game, error) {
 	return rawResponse, nil
 }
 
-func ensureDirExists(path string) error {
-	err := os.MkdirAll(path, 0755)
-	if err != nil {
-		log.Error().Err(err).Msgf("Failed to create directory: %s", path)
-		return err
-	}
-
-	info, err := os.Stat(path)
-	if err != nil {
-		log.Error().Err(err).Msgf("Error checking directory %s", path)
-		return err
-	}
-	if !info.IsDir() {
-		log.Error().Msgf("Path %s exists but is not a directory", path)
-		return fmt.Errorf("path %s exists but is not a directory", path)
-	}
-	return nil
-}
-
 var pathSanitizer = strings.NewReplacer(
 	"®", "",
 	"™", "",
@@ -153,8 +197,404 @@ type downloadTask struct {
 	url      string
 	fileName string
 	subDir   string
-	resume   bool
-	flatten  bool
+	langDir  string // per-language folder, see LanguageFolders; kept when flattening
+	// langFallback is the language folder used if another file of the download already has the same path,
+	// which can happen when all languages are downloaded without language folders.
+	langFallback string
+	resume       bool
+	flatten      bool
+	date         string // release date reported by GOG, empty if unknown
+	extra        bool   // an extra rather than an installer or patch
+	patch        bool   // a patch, see IsPatchFile
+	size         int64  // size reported by GOG, zero if unknown
+}
+
+// dirs returns the game folder the file of t belongs to and the folder the file is written to.
+func (t downloadTask) dirs(downloadPath, gameFolder, platformName string, rommLayout bool, layout string) (gameDir, targetDir string) {
+	if layout == LayoutLgog {
+		gameDir = filepath.Join(downloadPath, gameFolder)
+		return gameDir, filepath.Join(gameDir, t.lgogDir(), t.langDir)
+	}
+	if plat := rommPlatform(t.subDir, platformName); rommLayout && plat != "" {
+		// RomM layout: platform/game/
+		gameDir = filepath.Join(downloadPath, plat, gameFolder)
+		return gameDir, filepath.Join(gameDir, t.langDir)
+	}
+	subDir := t.subDir
+	if t.flatten {
+		subDir = ""
+	}
+	// Files without a platform, like extras when all platforms are downloaded, stay in the game folder.
+	gameDir = filepath.Join(downloadPath, gameFolder)
+	return gameDir, filepath.Join(gameDir, SanitizePath(subDir), t.langDir)
+}
+
+// claimedPaths records the paths of the files of a download, so two files are not written to the same path.
+type claimedPaths struct {
+	mu      sync.Mutex
+	claimed map[string]string // download link of the file, by path
+}
+
+// claim reports whether path was free or already taken by the file with the download link url, and records it
+// as taken by that file, so a file that is retried gets its path again.
+func (c *claimedPaths) claim(path, url string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if owner, ok := c.claimed[path]; ok && owner != url {
+		return false
+	}
+	c.claimed[path] = url
+	return true
+}
+
+// adopt moves the first of candidates that exists to path, so that the download of the file with the download
+// link url resumes it, and reports whether it did. It does nothing if path exists already, and skips candidates
+// taken by other files of the download.
+func (c *claimedPaths) adopt(path, url string, candidates ...string) bool {
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		return false
+	}
+	for _, candidate := range candidates {
+		if candidate == path {
+			continue
+		}
+		if info, err := os.Stat(candidate); err != nil || !info.Mode().IsRegular() || !c.claim(candidate, url) {
+			continue
+		}
+		if err := os.Rename(candidate, path); err != nil {
+			log.Warn().Err(err).Str("file", candidate).Msg("Could not rename the partial download to resume it")
+			return false
+		}
+		log.Info().Str("file", path).Str("from", candidate).Msg("Resuming the partial download saved under another name")
+		return true
+	}
+	return false
+}
+
+// LanguageFolders tells whether the files of each language go into a folder named after the language code.
+type LanguageFolders string
+
+const (
+	// LanguageFoldersAuto uses language folders only when all languages are downloaded.
+	LanguageFoldersAuto LanguageFolders = "auto"
+	// LanguageFoldersAlways uses a language folder even when one language is downloaded.
+	LanguageFoldersAlways LanguageFolders = "always"
+	// LanguageFoldersNever puts the files of all languages together. A file listed for several languages is
+	// downloaded once, and a file whose name is already taken goes into its language folder.
+	LanguageFoldersNever LanguageFolders = "never"
+)
+
+// WithLanguageFolders sets whether the files of each language go into their own folder. The default is
+// LanguageFoldersAuto.
+func WithLanguageFolders(mode LanguageFolders) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.langFolders = mode }
+}
+
+// WithPreferredLanguage makes the files of language the primary ones when all languages are downloaded: they go
+// where the files of a single language would, without a language folder, while the other languages get their
+// folders as usual. Its files are also enqueued first, so without language folders, a file listed for several
+// languages is downloaded as a file of language. A game or DLC that does not offer language uses its first
+// language instead.
+func WithPreferredLanguage(language string) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.preferLang = language }
+}
+
+// ErrNoGameFolder means that the game has no usable title to name its folder after and no folder was given
+// with WithGameFolder.
+var ErrNoGameFolder = errors.New("the game has no title to name its folder after; refresh the catalogue")
+
+// ErrNoMatchingFiles means that WithStrict was set and nothing matched the selected language and platform.
+var ErrNoMatchingFiles = errors.New("no files match the selection")
+
+// ErrIncompleteFile means that a downloaded file does not have the size the server reported for it, for example
+// because the connection ended early. A resumed download keeps the partial file and continues it on a retry.
+var ErrIncompleteFile = errors.New("the downloaded file is incomplete")
+
+// ErrChecksumMismatch means that a file completed by a resumed download with WithVerifyOnResume does not match
+// the checksum GOG publishes for it. The file is removed, so a retry downloads it from the start.
+var ErrChecksumMismatch = errors.New("the downloaded file does not match its checksum")
+
+// ErrFileStalled means that no data of a file arrived for the time set with WithStallTimeout, even after the
+// file was retried. A resumed download keeps the partial file.
+var ErrFileStalled = errors.New("the download of the file stalled")
+
+// DownloadOption customizes how DownloadGameFiles downloads files.
+type DownloadOption func(*downloadConfig)
+
+// DefaultMaxConnsPerHost is how many connections DownloadGameFiles opens to one host at most, unless
+// WithMaxConnsPerHost sets another limit.
+const DefaultMaxConnsPerHost = 8
+
+// DefaultProgressInterval is the shortest interval between two progress updates of a file, unless
+// WithProgressInterval sets another one.
+const DefaultProgressInterval = 100 * time.Millisecond
+
+type downloadConfig struct {
+	httpClient       *http.Client
+	progressInterval time.Duration
+	manifestOnly     bool
+	extrasOnly       bool
+	slots            *DownloadSlots
+	installerFilter  func([]PlatformFile) []PlatformFile
+	modes            FileModes
+	preserveDate     bool
+	checksumAlgo     string
+	langFolders      LanguageFolders
+	preferLang       string
+	verifyOnResume   bool
+	strict           bool
+	byteBudget       *ByteBudget
+	metadataWriter   io.Writer
+	file             *GameFile
+	maxConnsPerHost  int
+	gameFolder       string
+	since            *sinceVersion
+	flattenExtras    *bool
+	preflight        func(PreflightReport)
+	skipSummary      func(SkipSummary)
+	noDLCExtras      bool
+	sizeRange        fileSizeRange
+	excludes         filePatterns
+	bestEffort       bool
+	fileNames        string
+	stallTimeout     time.Duration
+	layout           string
+	library          *LibraryIndex
+	linkMode         string
+	accurateTotal    bool
+	checksumFiles    bool
+	preallocate      bool
+	rateLimiter      *RateLimiter
+	embedBase        string // resolves the relative download links of GOG instead of embed.gog.com
+}
+
+// WithHTTPClient makes DownloadGameFiles send its requests with c, for example a client of an httptest.Server.
+// The redirect from GOG's download link to the file is still resolved by DownloadGameFiles itself.
+func WithHTTPClient(c *http.Client) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.httpClient = c }
+}
+
+// WithMaxConnsPerHost limits how many connections DownloadGameFiles opens to one host, so many threads do not
+// overload a single CDN host. Zero or less means no limit. It has no effect together with WithHTTPClient.
+func WithMaxConnsPerHost(n int) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.maxConnsPerHost = max(n, 0) }
+}
+
+// WithProgressInterval makes DownloadGameFiles send the progress of each file at most once per d, which saves
+// CPU when downloading many small files. A final update is always sent when a file is done. Zero or less sends
+// an update on every read.
+func WithProgressInterval(d time.Duration) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.progressInterval = d }
+}
+
+// WithFlattenExtras sets whether the extras of the game and its DLCs are put directly into the game folder,
+// instead of their "extras" folders. Without it, extras are flattened like the other files, as the flatten
+// argument of DownloadGameFiles says.
+func WithFlattenExtras(flatten bool) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.flattenExtras = &flatten }
+}
+
+// WithoutDLCExtras makes DownloadGameFiles skip the extras of the DLCs, like their soundtracks, while still
+// downloading the DLC installers and the extras of the game itself.
+func WithoutDLCExtras() DownloadOption {
+	return func(cfg *downloadConfig) { cfg.noDLCExtras = true }
+}
+
+// DefaultStallTimeout is the stall timeout of the command line and the GUI, see WithStallTimeout.
+const DefaultStallTimeout = 2 * time.Minute
+
+// stallRetries is how many times a file that stalled is retried before it fails with ErrFileStalled.
+const stallRetries = 3
+
+// WithStallTimeout makes DownloadGameFiles cancel the request of a file when no data of it arrives for d, and
+// retry the file, resuming it if resume is set, while the other files go on. Time spent waiting for the speed
+// limit does not count. Zero or less, the default, waits as long as the connection stays open.
+func WithStallTimeout(d time.Duration) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.stallTimeout = max(d, 0) }
+}
+
+// stallReader re-arms watchdog for timeout during each read of reader and stops it after the read, so the
+// watchdog only fires when the connection delivers nothing for timeout.
+type stallReader struct {
+	reader   io.Reader
+	watchdog *time.Timer
+	timeout  time.Duration
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	r.watchdog.Reset(r.timeout)
+	n, err := r.reader.Read(p)
+	r.watchdog.Stop()
+	return n, err
+}
+
+// WithBestEffort makes DownloadGameFiles go on with the other files when a file fails to download, still write
+// the metadata.json of the game, and then return a *PartialDownloadError that lists the files that failed.
+// A full volume and cancellation still stop the whole download.
+func WithBestEffort() DownloadOption {
+	return func(cfg *downloadConfig) { cfg.bestEffort = true }
+}
+
+// FileFailure is a file that failed to download with WithBestEffort.
+type FileFailure struct {
+	Name string // the name GOG lists for the file
+	Link string // the last element of the download link, which Game.FindFile accepts
+	Err  error
+}
+
+// PartialDownloadError is returned by DownloadGameFiles with WithBestEffort when some files failed to download
+// and the others were downloaded.
+type PartialDownloadError struct {
+	Title  string
+	Total  int // number of files of the download
+	Failed []FileFailure
+}
+
+func (e *PartialDownloadError) Error() string {
+	return fmt.Sprintf("%d of %d file(s) of %s failed to download, first error: %v", len(e.Failed), e.Total, e.Title, e.Failed[0].Err)
+}
+
+// WithFileSizeRange makes DownloadGameFiles skip the installers, patches, and extras whose size, as GOG reports
+// it, is below minSize or above maxSize, and log them. Zero or less means no limit, and files of unknown size are
+// always downloaded. It has no effect together with WithFile.
+func WithFileSizeRange(minSize, maxSize int64) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.sizeRange = fileSizeRange{min: minSize, max: maxSize} }
+}
+
+// fileSizeRange is the range of file sizes given with WithFileSizeRange.
+type fileSizeRange struct {
+	min, max int64
+}
+
+// allows reports whether a file of size is in the range. A size of zero is unknown and always allowed.
+func (r fileSizeRange) allows(size int64) bool {
+	if size <= 0 {
+		return true
+	}
+	return (r.min <= 0 || size >= r.min) && (r.max <= 0 || size <= r.max)
+}
+
+// WithExcludedFiles makes DownloadGameFiles skip the files whose name matches one of patterns, like "*patch*"
+// or "*4k*", and log them. A pattern is matched like path.Match, ignoring case, against the name of the file its
+// download link leads to, which is only known once the link is resolved, and against the name GOG lists for it.
+// It has no effect together with WithFile.
+func WithExcludedFiles(patterns []string) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.excludes = patterns }
+}
+
+// ValidateFilePattern checks that pattern is a valid pattern for WithExcludedFiles.
+func ValidateFilePattern(pattern string) error {
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+// filePatterns are the patterns given with WithExcludedFiles.
+type filePatterns []string
+
+// matches reports whether one of names matches one of the patterns.
+func (p filePatterns) matches(names ...string) bool {
+	for _, pattern := range p {
+		for _, name := range names {
+			if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name)); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WithManifestOnly makes DownloadGameFiles skip the game files and only write the metadata.json of the game.
+func WithManifestOnly() DownloadOption {
+	return func(cfg *downloadConfig) { cfg.manifestOnly = true }
+}
+
+// WithExtrasOnly makes DownloadGameFiles skip the installers of the game and its DLCs and download only
+// the extras. The extras of DLCs are downloaded only if DLCs are included.
+func WithExtrasOnly() DownloadOption {
+	return func(cfg *downloadConfig) { cfg.extrasOnly = true }
+}
+
+// WithDownloadSlots makes every file downloaded by DownloadGameFiles take a slot of s first, so downloads
+// sharing s never download more than s.Size() files at the same time in total.
+func WithDownloadSlots(s *DownloadSlots) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.slots = s }
+}
+
+// WithPreserveDate sets the modification time of each downloaded file to the date GOG reports for it.
+// Files without a date, or with one that cannot be parsed, keep the time they were written.
+func WithPreserveDate() DownloadOption {
+	return func(cfg *downloadConfig) { cfg.preserveDate = true }
+}
+
+// WithVerifyOnResume makes a resumed download check each file that is already complete by size against the
+// checksum GOG publishes for it, and download the file again if it does not match. Files without a published
+// checksum are kept. A file that is completed by resuming it is checked as well, and fails with
+// ErrChecksumMismatch if it does not match.
+func WithVerifyOnResume() DownloadOption {
+	return func(cfg *downloadConfig) { cfg.verifyOnResume = true }
+}
+
+// WithStrict makes DownloadGameFiles fail with ErrNoMatchingFiles instead of downloading nothing when no
+// installer or patch of the game or its DLCs matches the language and platform. With WithExtrasOnly, it fails
+// when there are no extras to download instead. It has no effect with WithSinceVersion.
+func WithStrict() DownloadOption {
+	return func(cfg *downloadConfig) { cfg.strict = true }
+}
+
+// WithByteBudget makes DownloadGameFiles count the bytes it downloads against b and leave out the files that
+// no longer fit into it. The files that are left out make DownloadGameFiles return ErrByteBudgetReached after
+// the other files are downloaded.
+func WithByteBudget(b *ByteBudget) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.byteBudget = b }
+}
+
+// WithMetadataWriter makes DownloadGameFiles also write the metadata.json of the game to w, for example to
+// print it.
+func WithMetadataWriter(w io.Writer) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.metadataWriter = w }
+}
+
+// WithFile makes DownloadGameFiles download only f, one of the files returned by Game.Files, whatever the
+// language, platform, extras, and DLC flags are.
+func WithFile(f GameFile) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.file = &f }
+}
+
+// WithGameFolder makes DownloadGameFiles put the files of the game into a folder with the given name, like one
+// made by GameFolderName, instead of one named after the title of the game.
+func WithGameFolder(name string) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.gameFolder = name }
+}
+
+// WithInstallerFilter makes DownloadGameFiles download only the installers of the game that filter returns.
+// filter is called with the files of each language and platform; the files of DLCs are not filtered.
+func WithInstallerFilter(filter func([]PlatformFile) []PlatformFile) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.installerFilter = filter }
+}
+
+// downloadClients returns the client used to download files and a copy of it that does not follow redirects.
+func downloadClients(cfg downloadConfig) (*http.Client, *http.Client) {
+	client := cfg.httpClient
+	if client == nil {
+		// This transport is configured for large file downloads. It has connection
+		// timeouts but no total timeout, preventing failures on slow networks.
+		transport := &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			MaxConnsPerHost:       cfg.maxConnsPerHost,
+			MaxIdleConnsPerHost:   cfg.maxConnsPerHost,
+		}
+		client = &http.Client{Transport: transport}
+	}
+	clientNoRedirect := *client
+	clientNoRedirect.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return client, &clientNoRedirect
 }
 
 func DownloadGameFiles(
@@ -162,27 +602,34 @@ func DownloadGameFiles(
 	accessToken string, game Game, downloadPath string,
 	gameLanguage string, platformName string, extrasFlag bool, dlcFlag bool, resumeFlag bool,
 	flattenFlag bool, skipPatchesFlag bool, rommLayout bool, numThreads int,
-	updateWriter io.Writer,
+	updateWriter io.Writer, options ...DownloadOption,
 ) error {
-	// This transport is configured for large file downloads. It has connection
-	// timeouts but no total timeout, preventing failures on slow networks.
-	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-	}
-	client := &http.Client{Transport: transport}
-	clientNoRedirect := &http.Client{
-		Transport: transport,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-	}
-
-	if err := ensureDirExists(downloadPath); err != nil {
+	cfg := downloadConfig{progressInterval: DefaultProgressInterval, modes: DefaultFileModes, maxConnsPerHost: DefaultMaxConnsPerHost}
+	for _, option := range options {
+		option(&cfg)
+	}
+	flattenExtras := flattenFlag
+	if cfg.flattenExtras != nil {
+		flattenExtras = *cfg.flattenExtras
+	}
+	client, clientNoRedirect := downloadClients(cfg)
+	gameFolder := cfg.gameFolder
+	if gameFolder == "" {
+		gameFolder = SanitizePath(game.Title)
+	}
+	if gameFolder == "" {
+		// The files would be written to the root of downloadPath.
+		return ErrNoGameFolder
+	}
+	var sums *checksums
+	if cfg.checksumAlgo != "" && !cfg.manifestOnly {
+		var err error
+		if sums, err = newChecksums(cfg.checksumAlgo); err != nil {
+			return err
+		}
+	}
+
+	if err := ensureDirExists(downloadPath, cfg.modes.Dir); err != nil {
 		log.Error().Err(err).Msgf("Failed to create download path %s", downloadPath)
 		return err
 	}
@@ -190,18 +637,25 @@ func DownloadGameFiles(
 	// Serialize all progress JSON output
 	sw := &syncWriter{w: updateWriter, mu: &sync.Mutex{}}
 
-	totalDownloadSize, err := game.EstimateStorageSize(gameLanguage, platformName, extrasFlag, dlcFlag)
+	// files is the game with only the files to download; the metadata of the whole game is still written.
+	files := game
+	if cfg.since != nil {
+		files = ChangedFiles(game, cfg.since.old, cfg.since.info)
+	}
+	if cfg.noDLCExtras {
+		files = withoutDLCExtras(files)
+	}
+	totalDownloadSize, err := files.EstimateStorageSize(gameLanguage, platformName, extrasFlag, dlcFlag)
 	if err != nil {
 		return fmt.Errorf("failed to estimate total download size: %w", err)
 	}
-	startUpdate := ProgressUpdate{Type: "start", OverallTotalBytes: totalDownloadSize}
-	jsonStart, jsonErr := json.Marshal(startUpdate)
-	if jsonErr != nil {
-		log.Error().Err(jsonErr).Msg("Failed to marshal start update")
-	} else {
-		_, _ = fmt.Fprintln(sw, string(jsonStart))
+	if cfg.manifestOnly {
+		totalDownloadSize = 0
+	} else if cfg.file != nil {
+		totalDownloadSize = cfg.file.task(resumeFlag, flattenFlag).size
+	} else if cfg.extrasOnly {
+		totalDownloadSize = estimateExtrasSize(files, extrasFlag, dlcFlag)
 	}
-
 	findFileLocation := func(ctx context.Context, url string) (string, error) {
 		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
@@ -224,8 +678,27 @@ func DownloadGameFiles(
 		}
 		return "", nil
 	}
+	headSize := func(ctx context.Context, url string) (int64, error) {
+		headReq, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+		if err != nil {
+			return 0, err
+		}
+		headReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+		headResp, err := client.Do(headReq)
+		if err != nil {
+			return 0, err
+		}
+		_ = headResp.Body.Close()
+		return headResp.ContentLength, nil
+	}
+	// probedSizes are the sizes found before the download with WithAccurateTotal, by the download link of
+	// their task. It is only read once the download has started.
+	var probedSizes map[string]int64
 
-	downloadFile := func(ctx context.Context, task downloadTask) error {
+	paths := &claimedPaths{claimed: make(map[string]string)}
+	skipped := &skippedFiles{}
+	// downloadFile downloads the file of task and fills done with what it did, for the "file_done" update.
+	downloadFile := func(ctx context.Context, task downloadTask, done *ProgressUpdate) error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
@@ -234,6 +707,10 @@ func DownloadGameFiles(
 
 		url := task.url
 		fileName := task.fileName
+		probedSize, probed := probedSizes[task.url]
+		if probed {
+			task.size = probedSize
+		}
 
 		location, err := findFileLocation(ctx, url)
 		if err != nil {
@@ -256,24 +733,71 @@ func DownloadGameFiles(
 			fileName = fileName[:q]
 		}
 
-		subDir := task.subDir
-		if task.flatten {
-			subDir = ""
+		if cfg.file == nil && cfg.excludes.matches(fileName, task.fileName) {
+			log.Info().Str("file", fileName).Msg("Skipping file that matches an excluded pattern")
+			done.FileName, done.TotalBytes, done.Outcome = fileName, task.size, FileExcluded
+			return nil
 		}
-		var targetDir string
-		if rommLayout {
-			// RomM layout: platform/game/
-			plat := strings.ToLower(strings.TrimSpace(strings.Split(subDir, string(os.PathSeparator))[0]))
-			if plat == "" {
-				plat = strings.ToLower(platformName)
+
+		gameDir, targetDir := task.dirs(downloadPath, gameFolder, platformName, rommLayout, cfg.layout)
+		originalName := fileName
+		fileName = NormalizeFileName(fileName, cfg.fileNames)
+		filePath := filepath.Join(targetDir, fileName)
+		if !paths.claim(filePath, task.url) {
+			if task.langFallback != "" {
+				targetDir = filepath.Join(targetDir, task.langFallback)
+				filePath = filepath.Join(targetDir, fileName)
+				paths.claim(filePath, task.url)
+			} else if fileName != originalName {
+				// Another file of the download has the same normalized name, so this one keeps its original name.
+				fileName = originalName
+				filePath = filepath.Join(targetDir, fileName)
+				paths.claim(filePath, task.url)
 			}
-			targetDir = filepath.Join(downloadPath, plat, SanitizePath(game.Title))
-		} else {
-			targetDir = filepath.Join(downloadPath, SanitizePath(game.Title), SanitizePath(subDir))
 		}
-		filePath := filepath.Join(targetDir, fileName)
+		done.FileName, done.TotalBytes = fileName, task.size
+		// storeChecksumFile stores GOG's checksum file of an installer or patch once the file is complete.
+		storeChecksumFile := func(ctx context.Context, url string, replace bool) {
+			if !cfg.checksumFiles || task.extra {
+				return
+			}
+			if _, err := os.Stat(filePath + ChecksumFileSuffix); err == nil && !replace {
+				return
+			}
+			if saved, err := saveChecksumFile(ctx, client, accessToken, url, filePath, cfg.modes.File); err != nil {
+				log.Warn().Err(err).Str("file", filePath).Msg("Could not store the checksum file of GOG")
+			} else if !saved {
+				log.Debug().Str("file", filePath).Msg("GOG publishes no checksum file for the file")
+			}
+		}
+		if task.extra && task.resume {
+			// The name of an extra comes from where its download link redirects to, so an earlier run in which
+			// the link did not redirect saved it under the name made from its title instead.
+			paths.adopt(filePath, task.url, filepath.Join(targetDir, task.fileName),
+				filepath.Join(targetDir, NormalizeFileName(task.fileName, cfg.fileNames)))
+		}
+
+		var reservation *budgetReservation
+		if cfg.byteBudget != nil {
+			remaining := task.size
+			if fileInfo, statErr := os.Stat(filePath); task.resume && statErr == nil {
+				remaining = max(task.size-fileInfo.Size(), 0)
+			}
+			// A file that is already complete by its reported size costs nothing.
+			if task.size > 0 && remaining == 0 {
+				reservation = &budgetReservation{budget: cfg.byteBudget}
+			} else if r, ok := cfg.byteBudget.reserve(remaining); ok {
+				reservation = r
+			} else {
+				log.Info().Str("file", filePath).Msg("Skipping file; the download size limit is reached")
+				skipped.add(remaining)
+				done.Outcome = FileSkipped
+				return nil
+			}
+			defer reservation.release()
+		}
 
-		if err := ensureDirExists(targetDir); err != nil {
+		if err := ensureDirExists(targetDir, cfg.modes.Dir); err != nil {
 			return err
 		}
 
@@ -283,12 +807,12 @@ func DownloadGameFiles(
 		if task.resume {
 			if fileInfo, statErr := os.Stat(filePath); statErr == nil {
 				startOffset = fileInfo.Size()
-				file, err = os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+				file, err = openFile(filePath, os.O_APPEND, cfg.modes.File)
 				if err != nil {
 					return err
 				}
 			} else if os.IsNotExist(statErr) {
-				file, err = os.Create(filePath)
+				file, err = openFile(filePath, os.O_TRUNC, cfg.modes.File)
 				if err != nil {
 					return err
 				}
@@ -296,35 +820,103 @@ func DownloadGameFiles(
 				return statErr
 			}
 		} else {
-			file, err = os.Create(filePath)
+			file, err = openFile(filePath, os.O_TRUNC, cfg.modes.File)
 			if err != nil {
 				return err
 			}
 		}
 		defer func() { _ = file.Close() }()
 
-		headReq, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
-		if err != nil {
-			return err
+		totalSize := probedSize
+		if !probed {
+			if totalSize, err = headSize(ctx, url); err != nil {
+				return err
+			}
 		}
-		headReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
-
-		headResp, err := client.Do(headReq)
-		if err != nil {
-			return err
+		if totalSize > 0 {
+			done.TotalBytes = totalSize
 		}
-		_ = headResp.Body.Close()
-
-		totalSize := headResp.ContentLength
-		if task.resume && totalSize > 0 && startOffset >= totalSize {
+		complete := task.resume && totalSize > 0 && startOffset >= totalSize
+		if complete && cfg.verifyOnResume {
+			valid, err := verifyFileChecksum(ctx, client, accessToken, url, filePath)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				done.Verification = VerificationUnavailable
+				log.Warn().Err(err).Str("file", filePath).Msg("Could not verify the file; keeping it")
+			} else if valid {
+				done.Verification = VerificationPassed
+			} else {
+				done.Verification = VerificationMismatch
+				log.Warn().Str("file", filePath).Msg("The file does not match its checksum; downloading it again")
+				if err := file.Close(); err != nil {
+					return err
+				}
+				if file, err = openFile(filePath, os.O_TRUNC, cfg.modes.File); err != nil {
+					return err
+				}
+				defer func() { _ = file.Close() }()
+				startOffset = 0
+				complete = false
+			}
+		}
+		if complete {
 			// File is already complete, send a final progress update for it.
 			finalUpdate := ProgressUpdate{Type: "file_progress", FileName: fileName, CurrentBytes: startOffset, TotalBytes: totalSize}
 			jsonUpdate, _ := json.Marshal(finalUpdate)
 			_, _ = fmt.Fprintln(sw, string(jsonUpdate))
+			if sums != nil {
+				h, err := sums.newHash(filePath, startOffset)
+				if err != nil {
+					return err
+				}
+				done.Checksum = sums.add(gameDir, filePath, h)
+			}
+			if cfg.preserveDate {
+				_ = file.Close()
+				setFileDate(filePath, task.date)
+			}
+			storeChecksumFile(ctx, url, false)
+			done.Outcome = FileComplete
 			return nil
 		}
 
-		getReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if cfg.library != nil && totalSize > 0 {
+			if source := cfg.library.source(ctx, client, accessToken, url, filePath, totalSize, originalName, fileName); source != "" {
+				_ = file.Close()
+				if err := linkFile(source, filePath, cfg.linkMode); err != nil {
+					log.Warn().Err(err).Str("file", filePath).Str("source", source).Msg("Could not link the file from the library; downloading it")
+					if file, err = openFile(filePath, os.O_APPEND, cfg.modes.File); err != nil {
+						return err
+					}
+					defer func() { _ = file.Close() }()
+				} else {
+					log.Info().Str("file", filePath).Str("source", source).Msg("Linked the file from the library instead of downloading it")
+					finalUpdate := ProgressUpdate{Type: "file_progress", FileName: fileName, CurrentBytes: totalSize, TotalBytes: totalSize}
+					jsonUpdate, _ := json.Marshal(finalUpdate)
+					_, _ = fmt.Fprintln(sw, string(jsonUpdate))
+					if sums != nil {
+						h, err := sums.newHash(filePath, totalSize)
+						if err != nil {
+							return err
+						}
+						done.Checksum = sums.add(gameDir, filePath, h)
+					}
+					storeChecksumFile(ctx, url, true)
+					done.Outcome = FileLinked
+					return nil
+				}
+			}
+		}
+
+		// The watchdog cancels only the request of this file, so the other files go on and the file is retried.
+		getCtx, cancelGet := context.WithCancelCause(ctx)
+		defer cancelGet(nil)
+		stalled := func() error {
+			return fmt.Errorf("%w: no data received for %s in %s", ErrFileStalled, fileName, cfg.stallTimeout)
+		}
+		getReq, err := http.NewRequestWithContext(getCtx, "GET", url, nil)
 		if err != nil {
 			return err
 		}
@@ -335,8 +927,25 @@ func DownloadGameFiles(
 			requestedRange = startOffset
 		}
 
+		var h hash.Hash
+		if sums != nil {
+			if h, err = sums.newHash(filePath, startOffset); err != nil {
+				return err
+			}
+		}
+
+		var watchdog *time.Timer
+		if cfg.stallTimeout > 0 {
+			watchdog = time.AfterFunc(cfg.stallTimeout, func() { cancelGet(ErrFileStalled) })
+		}
 		getResp, err := client.Do(getReq)
+		if watchdog != nil {
+			watchdog.Stop()
+		}
 		if err != nil {
+			if errors.Is(context.Cause(getCtx), ErrFileStalled) && ctx.Err() == nil {
+				return stalled()
+			}
 			return err
 		}
 		defer func() { _ = getResp.Body.Close() }()
@@ -350,24 +959,51 @@ func DownloadGameFiles(
 			if err := file.Close(); err != nil {
 				return err
 			}
-			file, err = os.Create(filePath) // truncate
+			file, err = openFile(filePath, os.O_TRUNC, cfg.modes.File)
 			if err != nil {
 				return err
 			}
 			defer func() { _ = file.Close() }()
 			startOffset = 0
+			if h != nil {
+				h.Reset()
+			}
+		}
+		if cfg.preallocate && totalSize > startOffset {
+			if err := preallocate(file, totalSize); err != nil {
+				if volumeErr := WrapVolumeError(filePath, err); volumeErr != err {
+					if !task.resume {
+						_ = file.Close()
+						_ = os.Remove(filePath)
+					}
+					return volumeErr
+				}
+				log.Warn().Err(err).Str("file", filePath).Msg("Failed to reserve disk space, writing the file without it")
+			}
+		}
+		var body io.Reader = getResp.Body
+		if watchdog != nil {
+			body = &stallReader{reader: body, watchdog: watchdog, timeout: cfg.stallTimeout}
 		}
-		limitedBody := wrapWithGlobalRateLimiter(getResp.Body)
+		limitedBody := wrapWithRateLimiter(body, cfg.rateLimiter)
 		progressReader := &progressReader{
 			reader:    limitedBody,
 			writer:    sw,
 			fileName:  fileName,
 			totalSize: totalSize,
 			bytesRead: startOffset,
+			interval:  cfg.progressInterval,
+			budget:    reservation,
 		}
 
+		var dst io.Writer = file
+		if h != nil {
+			dst = io.MultiWriter(file, h)
+		}
 		buffer := make([]byte, 32*1024)
-		nWritten, err := io.CopyBuffer(file, progressReader, buffer)
+		nWritten, err := io.CopyBuffer(dst, progressReader, buffer)
+		progressReader.flush()
+		done.Received = nWritten
 		if err != nil {
 			// Tolerate ErrUnexpectedEOF if we actually received the exact expected remaining bytes
 			if errors.Is(err, io.ErrUnexpectedEOF) && totalSize > 0 {
@@ -391,17 +1027,79 @@ func DownloadGameFiles(
 				_ = file.Close()
 				_ = os.Remove(filePath)
 			}
+			if errors.Is(context.Cause(getCtx), ErrFileStalled) {
+				return stalled()
+			}
+			if volumeErr := WrapVolumeError(filePath, err); volumeErr != err {
+				return volumeErr
+			}
 			return fmt.Errorf("failed to save file %s: %w", filePath, err)
 		}
+		// The file is only complete if the part that was on disk and the rest add up to the reported size.
+		if size := startOffset + nWritten; totalSize > 0 && size != totalSize {
+			if !task.resume || size > totalSize {
+				_ = file.Close()
+				_ = os.Remove(filePath)
+			}
+			return fmt.Errorf("%w: %s has %d of %d bytes", ErrIncompleteFile, filePath, size, totalSize)
+		}
+		if startOffset > 0 && cfg.verifyOnResume {
+			valid, err := verifyFileChecksum(ctx, client, accessToken, url, filePath)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				done.Verification = VerificationUnavailable
+				log.Warn().Err(err).Str("file", filePath).Msg("Could not verify the resumed file; keeping it")
+			} else if valid {
+				done.Verification = VerificationPassed
+			} else {
+				// There is no telling whether the old or the new part is bad, so the whole file goes.
+				done.Verification = VerificationMismatch
+				_ = file.Close()
+				_ = os.Remove(filePath)
+				return fmt.Errorf("%w: %s", ErrChecksumMismatch, filePath)
+			}
+		}
+		if h != nil {
+			done.Checksum = sums.add(gameDir, filePath, h)
+		}
+		if cfg.preserveDate {
+			// Close first, so nothing written on close changes the time again.
+			if err := file.Close(); err != nil {
+				return fmt.Errorf("failed to save file %s: %w", filePath, err)
+			}
+			setFileDate(filePath, task.date)
+		}
+		if cfg.library != nil {
+			cfg.library.add(filePath, startOffset+nWritten)
+		}
+		storeChecksumFile(ctx, url, true)
+		done.Outcome = FileDownloaded
 		return nil
 	}
 
 	var tasks []downloadTask
 	var tasksMutex sync.Mutex
 
+	skips := newSkipCounter()
+	if cfg.skipSummary != nil {
+		defer func() { cfg.skipSummary(skips.summary()) }()
+	}
+
+	var skippedBySize int64
 	enqueue := func(t downloadTask) {
+		if cfg.embedBase != "" {
+			t.url = rebaseManualURL(t.url, cfg.embedBase)
+		}
 		tasksMutex.Lock()
 		defer tasksMutex.Unlock()
+		if cfg.file == nil && !cfg.sizeRange.allows(t.size) {
+			log.Info().Str("file", t.fileName).Str("size", progress.FormatBytes(t.size)).Msg("Skipping file outside the file size limits")
+			skippedBySize += t.size
+			skips.add(SkipFileSize, 1)
+			return
+		}
 		tasks = append(tasks, t)
 	}
 
@@ -411,16 +1109,29 @@ func DownloadGameFiles(
 	go func() {
 		defer wg.Done()
 		enqueueErr = func() error {
-			if err := enqueueGameFiles(ctx, enqueue, game, gameLanguage, platformName, "", resumeFlag, flattenFlag, skipPatchesFlag); err != nil {
-				return err
+			if cfg.manifestOnly {
+				return nil
+			}
+			if cfg.file != nil {
+				flatten := flattenFlag
+				if cfg.file.extra {
+					flatten = flattenExtras
+				}
+				enqueue(cfg.file.task(resumeFlag, flatten))
+				return nil
+			}
+			if !cfg.extrasOnly {
+				if err := enqueueGameFiles(ctx, enqueue, filterInstallers(files, cfg.installerFilter), gameLanguage, platformName, "", resumeFlag, flattenFlag, skipPatchesFlag, cfg.langFolders, cfg.preferLang, skips); err != nil {
+					return err
+				}
 			}
 			if extrasFlag {
-				if err := enqueueExtras(ctx, enqueue, game.Extras, "extras", resumeFlag, flattenFlag); err != nil {
+				if err := enqueueExtras(ctx, enqueue, files.Extras, "extras", resumeFlag, flattenExtras, skips); err != nil {
 					return err
 				}
 			}
 			if dlcFlag {
-				if err := enqueueDLCs(ctx, enqueue, &game, gameLanguage, platformName, extrasFlag, resumeFlag, flattenFlag, skipPatchesFlag); err != nil {
+				if err := enqueueDLCs(ctx, enqueue, &files, gameLanguage, platformName, !cfg.extrasOnly, extrasFlag, resumeFlag, flattenFlag, flattenExtras, skipPatchesFlag, cfg.langFolders, cfg.preferLang, skips); err != nil {
 					return err
 				}
 			}
@@ -432,10 +1143,88 @@ func DownloadGameFiles(
 	if enqueueErr != nil {
 		return enqueueErr
 	}
+	if cfg.since != nil {
+		log.Info().Msgf("%d file(s) of %s are new or changed since the earlier download", len(tasks), game.Title)
+	}
+	// With WithSinceVersion, nothing to download only means that nothing changed, and with WithFileSizeRange
+	// that every file was too small or too large.
+	if cfg.strict && cfg.since == nil && skippedBySize == 0 && !cfg.manifestOnly && !hasMatchingFiles(tasks, cfg.extrasOnly) {
+		if cfg.extrasOnly {
+			return fmt.Errorf("%w: %s has no extras to download", ErrNoMatchingFiles, game.Title)
+		}
+		return fmt.Errorf("%w: %s has no files for language %q and platform %q", ErrNoMatchingFiles, game.Title, gameLanguage, platformName)
+	}
+
+	// Files skipped for their size are only known now, so the start is sent once the files are known.
+	overallTotal := max(totalDownloadSize-skippedBySize, 0)
+	if cfg.accurateTotal && len(tasks) > 0 {
+		probeFile := func(ctx context.Context, url string) (int64, error) {
+			location, err := findFileLocation(ctx, url)
+			if err != nil {
+				return 0, err
+			}
+			if location != "" {
+				url = location
+			}
+			return headSize(ctx, url)
+		}
+		probedSizes, overallTotal = probeSizes(ctx, tasks, validation.ClampThreadCount(numThreads), probeFile)
+		log.Info().Int("files", len(tasks)).Int("sizes_found", len(probedSizes)).Str("total", progress.FormatBytes(overallTotal)).
+			Msg("Found the sizes of the files before downloading them")
+	}
+	startUpdate := ProgressUpdate{Type: "start", OverallTotalBytes: overallTotal}
+	jsonStart, jsonErr := json.Marshal(startUpdate)
+	if jsonErr != nil {
+		log.Error().Err(jsonErr).Msg("Failed to marshal start update")
+	} else {
+		_, _ = fmt.Fprintln(sw, string(jsonStart))
+	}
 
-	downloadErrors := pool.Run(ctx, tasks, numThreads, downloadFile)
+	if cfg.preflight != nil {
+		cfg.preflight(checkTarget(downloadPath, gameFolder, platformName, rommLayout, cfg.layout, tasks))
+	}
+
+	// A full volume fails every file that comes after it, so the first such error stops the whole download.
+	workCtx, stopWork := context.WithCancelCause(ctx)
+	defer stopWork(nil)
+	worker := func(ctx context.Context, task downloadTask) error {
+		if cfg.slots != nil {
+			if err := cfg.slots.Acquire(ctx); err != nil {
+				return err
+			}
+			defer cfg.slots.Release()
+		}
+		var done ProgressUpdate
+		err := downloadFile(ctx, task, &done)
+		received, retries := done.Received, 0
+		for ; errors.Is(err, ErrFileStalled) && retries < stallRetries && ctx.Err() == nil; retries++ {
+			log.Warn().Err(err).Int("attempt", retries+1).Msg("Retrying the stalled file")
+			done = ProgressUpdate{}
+			err = downloadFile(ctx, task, &done)
+			received += done.Received
+		}
+		sendFileDone(sw, task, done, received, retries, err)
+		if err == nil {
+			skips.add(outcomeSkipReasons[done.Outcome], 1)
+		}
+		if errors.Is(err, ErrVolumeFull) {
+			stopWork(err)
+		}
+		return err
+	}
+	var downloadErrors []error
+	var failures []FileFailure
+	for _, r := range pool.RunWithResults(workCtx, tasks, validation.ClampThreadCount(numThreads), worker) {
+		if r.Err != nil {
+			downloadErrors = append(downloadErrors, r.Err)
+			failures = append(failures, FileFailure{Name: r.Item.fileName, Link: path.Base(r.Item.url), Err: r.Err})
+		}
+	}
 
-	if len(downloadErrors) > 0 {
+	if cause := context.Cause(workCtx); ctx.Err() == nil && errors.Is(cause, ErrVolumeFull) {
+		return cause
+	}
+	if len(downloadErrors) > 0 && (!cfg.bestEffort || ctx.Err() != nil) {
 		for _, err := range downloadErrors {
 			if err != context.Canceled && err != context.DeadlineExceeded {
 				log.Error().Err(err).Msg("Worker failed to download file")
@@ -448,56 +1237,210 @@ func DownloadGameFiles(
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		metadataPath := filepath.Join(downloadPath, SanitizePath(game.Title), "metadata.json")
+		metadataPath := filepath.Join(downloadPath, gameFolder, "metadata.json")
 		metadata, err := json.MarshalIndent(game, "", "  ")
 		if err == nil {
-			if ensureDirExists(filepath.Dir(metadataPath)) == nil {
-				_ = os.WriteFile(metadataPath, metadata, 0644)
+			if ensureDirExists(filepath.Dir(metadataPath), cfg.modes.Dir) == nil {
+				_ = writeFile(metadataPath, metadata, cfg.modes.File)
+			}
+			if cfg.metadataWriter != nil {
+				if _, err := fmt.Fprintln(cfg.metadataWriter, string(metadata)); err != nil {
+					return fmt.Errorf("failed to write the game metadata: %w", err)
+				}
 			}
 		}
+		if sums != nil {
+			if err := sums.write(cfg.modes); err != nil {
+				return fmt.Errorf("failed to write the checksum file: %w", err)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		for _, f := range failures {
+			log.Error().Err(f.Err).Str("file", f.Name).Msg("Failed to download file")
+		}
+		sort.Slice(failures, func(i, j int) bool { return failures[i].Name < failures[j].Name })
+		return &PartialDownloadError{Title: game.Title, Total: len(tasks), Failed: failures}
+	}
+	if skipped.count > 0 {
+		return fmt.Errorf("%w: %d file(s) of %s (%s) were not downloaded", ErrByteBudgetReached,
+			skipped.count, game.Title, progress.FormatBytes(skipped.bytes))
 	}
 
 	log.Info().Msg("Download process completed.")
 	return nil
 }
 
+// sendFileDone sends the "file_done" progress update of task, whose last attempt filled done and returned err.
+func sendFileDone(w io.Writer, task downloadTask, done ProgressUpdate, received int64, retries int, err error) {
+	done.Type, done.Received, done.Retries = "file_done", received, retries
+	if done.FileName == "" {
+		done.FileName, done.TotalBytes = task.fileName, task.size
+	}
+	if err != nil {
+		done.Outcome, done.Error = FileFailed, err.Error()
+	}
+	if jsonDone, jsonErr := json.Marshal(done); jsonErr == nil {
+		_, _ = fmt.Fprintln(w, string(jsonDone))
+	}
+}
+
+// hasMatchingFiles reports whether tasks has an installer or patch, or any file at all if extrasOnly is set.
+func hasMatchingFiles(tasks []downloadTask, extrasOnly bool) bool {
+	for _, t := range tasks {
+		if extrasOnly || !t.extra {
+			return true
+		}
+	}
+	return false
+}
+
+// DownloadInfo records the settings a game was downloaded with. It is stored as download_info.json next to
+// the metadata.json of the game and is used to check the game for updates later.
+type DownloadInfo struct {
+	Language    string `json:"language"`
+	Platform    string `json:"platform"`
+	Extras      bool   `json:"extras"`
+	DLCs        bool   `json:"dlcs"`
+	SkipPatches bool   `json:"skipPatches"`
+	Flatten     bool   `json:"flatten"`
+	Resume      bool   `json:"resume"`
+	Threads     int    `json:"threads"`
+	// ChecksumFiles are the checksum files of GOG stored with WithChecksumFiles, relative to the folder of the
+	// download_info.json.
+	ChecksumFiles []string `json:"checksumFiles,omitempty"`
+}
+
+// WriteGameMetadata writes game as metadata.json in dir, like DownloadGameFiles does, creating dir if needed
+// with modes.
+func WriteGameMetadata(dir string, game Game, modes FileModes) error {
+	data, err := json.MarshalIndent(game, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ensureDirExists(dir, modes.Dir); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "metadata.json"), data, modes.File)
+}
+
+// WriteDownloadInfo writes info as download_info.json in dir, creating dir if needed with modes.
+func WriteDownloadInfo(dir string, info DownloadInfo, modes FileModes) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ensureDirExists(dir, modes.Dir); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "download_info.json"), data, modes.File)
+}
+
 func isAbsoluteURL(u string) bool {
 	parsed, err := netURL.Parse(u)
 	return err == nil && parsed.Scheme != "" && parsed.Host != ""
 }
 
+// buildManualURL returns the URL to download a file from, given the manual URL that GOG lists for it: absolute
+// URLs are kept, protocol-relative ones like //cdn.gog.com/x get https, and paths are taken as relative to
+// embed.gog.com.
 func buildManualURL(u string) string {
 	if isAbsoluteURL(u) {
 		return u
 	}
-	return fmt.Sprintf("https://embed.gog.com%s", u)
+	if strings.HasPrefix(u, "//") {
+		return "https:" + u
+	}
+	if !strings.HasPrefix(u, "/") {
+		u = "/" + u
+	}
+	return DefaultEmbedBase + u
 }
 
-func enqueueGameFiles(ctx context.Context, enqueue func(downloadTask), game Game, lang, platform, subDirPrefix string, resume, flatten, skipPatches bool) error {
-	for _, download := range game.Downloads {
-		if !strings.EqualFold(download.Language, lang) {
+// rebaseManualURL moves a download link that buildManualURL made relative to embed.gog.com to base.
+func rebaseManualURL(u, base string) string {
+	if rest, ok := strings.CutPrefix(u, DefaultEmbedBase+"/"); ok {
+		return strings.TrimSuffix(base, "/") + "/" + rest
+	}
+	return u
+}
+
+// enqueueGameFiles enqueues the installers and patches of game, counting the files it leaves out in skips. When
+// all languages are downloaded and preferLang is set, the files of the primary language (see primaryLanguage)
+// come first and get no language folder.
+func enqueueGameFiles(ctx context.Context, enqueue func(downloadTask), game Game, lang, platform, subDirPrefix string, resume, flatten, skipPatches bool, langFolders LanguageFolders, preferLang string, skips *skipCounter) error {
+	allLanguages := strings.EqualFold(lang, AllLanguages)
+	useLangDirs := langFolders == LanguageFoldersAlways || (langFolders != LanguageFoldersNever && allLanguages)
+	downloads, primary := game.Downloads, ""
+	if allLanguages && preferLang != "" {
+		primary = primaryLanguage(downloads, preferLang)
+		downloads = make([]Downloadable, 0, len(game.Downloads))
+		for _, download := range game.Downloads {
+			if LanguageMatches(download.Language, primary) {
+				downloads = append(downloads, download)
+			}
+		}
+		for _, download := range game.Downloads {
+			if !LanguageMatches(download.Language, primary) {
+				downloads = append(downloads, download)
+			}
+		}
+	}
+	seenURLs := make(map[string]bool)
+	for _, download := range downloads {
+		if !allLanguages && !LanguageMatches(download.Language, lang) {
+			skips.add(SkipOtherLanguage, len(download.Platforms.Windows)+len(download.Platforms.Mac)+len(download.Platforms.Linux))
 			continue
 		}
+		langDir, langFallback := "", ""
+		if useLangDirs {
+			if primary == "" || !LanguageMatches(download.Language, primary) {
+				langDir = languageFolder(download.Language)
+			}
+		} else if allLanguages {
+			langFallback = languageFolder(download.Language)
+		}
 		platforms := map[string][]PlatformFile{
 			"windows": download.Platforms.Windows, "mac": download.Platforms.Mac, "linux": download.Platforms.Linux,
 		}
 		for name, files := range platforms {
 			if platform != "all" && !strings.EqualFold(platform, name) {
+				skips.add(SkipOtherPlatform, len(files))
 				continue
 			}
 			for _, file := range files {
 				if file.ManualURL == nil || *file.ManualURL == "" {
+					skips.add(SkipNoLink, 1)
 					continue
 				}
-				if skipPatches && (strings.Contains(strings.ToLower(*file.ManualURL), "patch") || strings.Contains(strings.ToLower(file.Name), "patch")) {
+				if skipPatches && IsPatchFile(file) {
+					skips.add(SkipPatch, 1)
 					continue
 				}
+				// Without language folders, a file that GOG lists for several languages is downloaded once.
+				url := buildManualURL(*file.ManualURL)
+				if !useLangDirs {
+					if seenURLs[url] {
+						continue
+					}
+					seenURLs[url] = true
+				}
 				task := downloadTask{
-					url:      buildManualURL(*file.ManualURL),
-					fileName: file.Name,
-					subDir:   filepath.Join(subDirPrefix, name),
-					resume:   resume,
-					flatten:  flatten,
+					url:          url,
+					fileName:     file.Name,
+					subDir:       filepath.Join(subDirPrefix, name),
+					langDir:      langDir,
+					langFallback: langFallback,
+					resume:       resume,
+					flatten:      flatten,
+					patch:        IsPatchFile(file),
+				}
+				if file.Date != nil {
+					task.date = *file.Date
+				}
+				if size, err := parseSizeString(file.Size); err == nil {
+					task.size = size
 				}
 				select {
 				case <-ctx.Done():
@@ -511,9 +1454,103 @@ func enqueueGameFiles(ctx context.Context, enqueue func(downloadTask), game Game
 	return nil
 }
 
-func enqueueExtras(ctx context.Context, enqueue func(downloadTask), extras []Extra, subDir string, resume, flatten bool) error {
+// primaryLanguage returns the language of downloads that matches preferLang, or the first language of downloads
+// if none does.
+func primaryLanguage(downloads []Downloadable, preferLang string) string {
+	for _, download := range downloads {
+		if LanguageMatches(download.Language, preferLang) {
+			return download.Language
+		}
+	}
+	if len(downloads) > 0 {
+		return downloads[0].Language
+	}
+	return ""
+}
+
+// filterInstallers returns a copy of game whose installers of each language and platform are filtered by filter.
+func filterInstallers(game Game, filter func([]PlatformFile) []PlatformFile) Game {
+	if filter == nil {
+		return game
+	}
+	downloads := make([]Downloadable, len(game.Downloads))
+	for i, d := range game.Downloads {
+		downloads[i] = Downloadable{Language: d.Language, Platforms: Platform{
+			Windows: filter(d.Platforms.Windows),
+			Mac:     filter(d.Platforms.Mac),
+			Linux:   filter(d.Platforms.Linux),
+		}}
+	}
+	game.Downloads = downloads
+	return game
+}
+
+// withoutDLCExtras returns a copy of game whose DLCs have no extras.
+func withoutDLCExtras(game Game) Game {
+	dlcs := make([]DLC, len(game.DLCs))
+	for i, dlc := range game.DLCs {
+		dlc.Extras = nil
+		dlcs[i] = dlc
+	}
+	game.DLCs = dlcs
+	return game
+}
+
+// IsPatchFile reports whether f is a patch rather than a full installer, judging by its name and URL.
+func IsPatchFile(f PlatformFile) bool {
+	if f.ManualURL != nil && strings.Contains(strings.ToLower(*f.ManualURL), "patch") {
+		return true
+	}
+	return strings.Contains(strings.ToLower(f.Name), "patch")
+}
+
+// onlineInstallerMarkers are the words that mark an installer as one that downloads the game while it runs, like
+// the GOG Galaxy installer, in its name or link.
+var onlineInstallerMarkers = []string{"online", "galaxy", "web installer", "webinstaller"}
+
+// IsOnlineInstaller reports whether f is an online installer, which downloads the game while it runs, rather than
+// an offline installer that contains the whole game.
+func IsOnlineInstaller(f PlatformFile) bool {
+	name := strings.ToLower(f.Name)
+	url := ""
+	if f.ManualURL != nil {
+		url = strings.ToLower(*f.ManualURL)
+	}
+	for _, marker := range onlineInstallerMarkers {
+		if strings.Contains(name, marker) || strings.Contains(url, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// estimateExtrasSize estimates the size of the extras of game and, if dlcs is set, of its DLCs.
+func estimateExtrasSize(game Game, extras, dlcs bool) int64 {
+	if !extras {
+		return 0
+	}
+	sum := func(extras []Extra) int64 {
+		var total int64
+		for _, extra := range extras {
+			if size, err := parseSizeString(extra.Size); err == nil {
+				total += size
+			}
+		}
+		return total
+	}
+	total := sum(game.Extras)
+	if dlcs {
+		for _, dlc := range game.DLCs {
+			total += sum(dlc.Extras)
+		}
+	}
+	return total
+}
+
+func enqueueExtras(ctx context.Context, enqueue func(downloadTask), extras []Extra, subDir string, resume, flatten bool, skips *skipCounter) error {
 	for _, extra := range extras {
 		if extra.ManualURL == "" {
+			skips.add(SkipNoLink, 1)
 			continue
 		}
 		fileName := SanitizePath(extra.Name)
@@ -526,6 +1563,10 @@ func enqueueExtras(ctx context.Context, enqueue func(downloadTask), extras []Ext
 			subDir:   subDir,
 			resume:   resume,
 			flatten:  flatten,
+			extra:    true,
+		}
+		if size, err := parseSizeString(extra.Size); err == nil {
+			task.size = size
 		}
 		select {
 		case <-ctx.Done():
@@ -537,15 +1578,17 @@ func enqueueExtras(ctx context.Context, enqueue func(downloadTask), extras []Ext
 	return nil
 }
 
-func enqueueDLCs(ctx context.Context, enqueue func(downloadTask), game *Game, lang, platform string, extras, resume, flatten, skipPatches bool) error {
+func enqueueDLCs(ctx context.Context, enqueue func(downloadTask), game *Game, lang, platform string, installers, extras, resume, flatten, flattenExtras, skipPatches bool, langFolders LanguageFolders, preferLang string, skips *skipCounter) error {
 	for _, dlc := range game.DLCs {
 		dlcSubDir := filepath.Join("dlcs", SanitizePath(dlc.Title))
-		dlcGame := Game{Title: dlc.Title, Downloads: dlc.ParsedDownloads}
-		if err := enqueueGameFiles(ctx, enqueue, dlcGame, lang, platform, dlcSubDir, resume, flatten, skipPatches); err != nil {
-			return err
+		if installers {
+			dlcGame := Game{Title: dlc.Title, Downloads: dlc.ParsedDownloads}
+			if err := enqueueGameFiles(ctx, enqueue, dlcGame, lang, platform, dlcSubDir, resume, flatten, skipPatches, langFolders, preferLang, skips); err != nil {
+				return err
+			}
 		}
 		if extras {
-			if err := enqueueExtras(ctx, enqueue, dlc.Extras, filepath.Join(dlcSubDir, "extras"), resume, flatten); err != nil {
+			if err := enqueueExtras(ctx, enqueue, dlc.Extras, filepath.Join(dlcSubDir, "extras"), resume, flattenExtras, skips); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,919 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFile is a file served by fakeGOG. It is reached through /downloads/<key>, which redirects to
+// /files/<name> like GOG's download links redirect to its CDN.
+type fakeFile struct {
+	key     string
+	name    string
+	content []byte
+}
+
+// fakeGOG serves the files of a fake game with redirects, Content-Length, Content-Disposition, and Range support.
+type fakeGOG struct {
+	*httptest.Server
+	files map[string]fakeFile
+
+	mu     sync.Mutex
+	ranges map[string][]string // Range headers of the GET requests, by file name
+
+	// publishChecksums makes the server publish the MD5 checksum of each file at its path with ".xml" appended.
+	publishChecksums atomic.Bool
+}
+
+func newFakeGOG(t *testing.T, files ...fakeFile) *fakeGOG {
+	t.Helper()
+	g := &fakeGOG{files: make(map[string]fakeFile), ranges: make(map[string][]string)}
+	byName := make(map[string]fakeFile)
+	for _, f := range files {
+		g.files[f.key] = f
+		byName[f.name] = f
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/downloads/", func(w http.ResponseWriter, r *http.Request) {
+		f, ok := g.files[strings.TrimPrefix(r.URL.Path, "/downloads/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("%s/files/%s?token=%s", g.URL, f.name, f.key))
+		w.WriteHeader(http.StatusFound)
+	})
+	mux.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/files/")
+		if f, ok := byName[strings.TrimSuffix(name, ".xml")]; ok && g.publishChecksums.Load() && strings.HasSuffix(name, ".xml") {
+			fmt.Fprintf(w, `<file name="%s" available="1" md5="%x" total_size="%d"/>`, f.name, md5.Sum(f.content), len(f.content))
+			return
+		}
+		f, ok := byName[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodGet {
+			g.mu.Lock()
+			g.ranges[f.name] = append(g.ranges[f.name], r.Header.Get("Range"))
+			g.mu.Unlock()
+		}
+		// The name in Content-Disposition is not used; files are named after the redirect target.
+		w.Header().Set("Content-Disposition", `attachment; filename="ignored.bin"`)
+		http.ServeContent(w, r, f.name, time.Time{}, bytes.NewReader(f.content))
+	})
+	g.Server = httptest.NewTLSServer(mux)
+	t.Cleanup(g.Close)
+	return g
+}
+
+func (g *fakeGOG) link(key string) string { return g.URL + "/downloads/" + key }
+
+func (g *fakeGOG) rangesOf(name string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.ranges[name]...)
+}
+
+func fakeContent(seed byte, size int) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = seed + byte(i%251)
+	}
+	return b
+}
+
+var fakeGameFiles = []fakeFile{
+	{key: "setup-win", name: "setup_test_game_1.0.exe", content: fakeContent(1, 48*1024)},
+	{key: "setup-linux", name: "test_game_1_0.sh", content: fakeContent(2, 32*1024)},
+	{key: "patch-win", name: "patch_test_game_1.0_to_1.1.exe", content: fakeContent(3, 8*1024)},
+	{key: "manual", name: "manual.pdf", content: fakeContent(4, 4*1024)},
+	{key: "dlc-setup-win", name: "setup_expansion_pack_1.0.exe", content: fakeContent(5, 16*1024)},
+	{key: "dlc-soundtrack", name: "expansion_soundtrack.zip", content: fakeContent(6, 2*1024)},
+}
+
+func fakeGame(g *fakeGOG) Game {
+	return Game{
+		Title: "Test Game",
+		Downloads: []Downloadable{{Language: "English", Platforms: Platform{
+			Windows: []PlatformFile{
+				{Name: "Test Game", Size: "48 KB", ManualURL: strPtr(g.link("setup-win"))},
+				{Name: "Patch 1.1", Size: "8 KB", ManualURL: strPtr(g.link("patch-win"))},
+			},
+			Linux: []PlatformFile{{Name: "Test Game", Size: "32 KB", ManualURL: strPtr(g.link("setup-linux"))}},
+		}}},
+		Extras: []Extra{{Name: "Manual", Size: "4 KB", ManualURL: g.link("manual")}},
+		DLCs: []DLC{{
+			Title: "Expansion Pack",
+			ParsedDownloads: []Downloadable{{Language: "English", Platforms: Platform{
+				Windows: []PlatformFile{{Name: "Expansion Pack", Size: "16 KB", ManualURL: strPtr(g.link("dlc-setup-win"))}},
+			}}},
+			Extras: []Extra{{Name: "Soundtrack", Size: "2 KB", ManualURL: g.link("dlc-soundtrack")}},
+		}},
+	}
+}
+
+// downloadFlags are the flags of DownloadGameFiles that change which files are downloaded and where they go.
+type downloadFlags struct {
+	platform                                         string
+	extras, dlcs, resume, flatten, skipPatches, romm bool
+	extrasOnly, noDLCExtras                          bool
+	flattenExtras                                    *bool // nil follows flatten
+	minSize, maxSize                                 int64
+	layout                                           string
+}
+
+func downloadFakeGame(ctx context.Context, g *fakeGOG, dir string, f downloadFlags) error {
+	options := []DownloadOption{WithHTTPClient(g.Client())}
+	if f.extrasOnly {
+		options = append(options, WithExtrasOnly())
+	}
+	if f.flattenExtras != nil {
+		options = append(options, WithFlattenExtras(*f.flattenExtras))
+	}
+	if f.noDLCExtras {
+		options = append(options, WithoutDLCExtras())
+	}
+	if f.minSize > 0 || f.maxSize > 0 {
+		options = append(options, WithFileSizeRange(f.minSize, f.maxSize))
+	}
+	if f.layout != "" {
+		options = append(options, WithLayout(f.layout))
+	}
+	return DownloadGameFiles(ctx, "tok", fakeGame(g), dir, "English", f.platform,
+		f.extras, f.dlcs, f.resume, f.flatten, f.skipPatches, f.romm, 2, io.Discard, options...)
+}
+
+// listFiles returns the files under dir as slash-separated relative paths with their sizes.
+func listFiles(t *testing.T, dir string) map[string]int64 {
+	t.Helper()
+	files := make(map[string]int64)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func TestDownloadGameFiles_Layout(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags downloadFlags
+		want  []string // relative paths of the downloaded files, besides metadata.json
+	}{
+		{
+			name:  "windows with extras and DLCs",
+			flags: downloadFlags{platform: "windows", extras: true, dlcs: true},
+			want: []string{
+				"test-game/windows/setup_test_game_1.0.exe",
+				"test-game/windows/patch_test_game_1.0_to_1.1.exe",
+				"test-game/extras/manual.pdf",
+				"test-game/dlcs-expansion-pack-windows/setup_expansion_pack_1.0.exe",
+				"test-game/dlcs-expansion-pack-extras/expansion_soundtrack.zip",
+			},
+		},
+		{
+			name:  "flattened",
+			flags: downloadFlags{platform: "windows", extras: true, dlcs: true, flatten: true},
+			want: []string{
+				"test-game/setup_test_game_1.0.exe",
+				"test-game/patch_test_game_1.0_to_1.1.exe",
+				"test-game/manual.pdf",
+				"test-game/setup_expansion_pack_1.0.exe",
+				"test-game/expansion_soundtrack.zip",
+			},
+		},
+		{
+			name:  "flattened extras only",
+			flags: downloadFlags{platform: "windows", extras: true, dlcs: true, flattenExtras: boolPtr(true)},
+			want: []string{
+				"test-game/windows/setup_test_game_1.0.exe",
+				"test-game/windows/patch_test_game_1.0_to_1.1.exe",
+				"test-game/manual.pdf",
+				"test-game/dlcs-expansion-pack-windows/setup_expansion_pack_1.0.exe",
+				"test-game/expansion_soundtrack.zip",
+			},
+		},
+		{
+			name:  "flattened installers only",
+			flags: downloadFlags{platform: "windows", extras: true, dlcs: true, flatten: true, flattenExtras: boolPtr(false)},
+			want: []string{
+				"test-game/setup_test_game_1.0.exe",
+				"test-game/patch_test_game_1.0_to_1.1.exe",
+				"test-game/extras/manual.pdf",
+				"test-game/setup_expansion_pack_1.0.exe",
+				"test-game/dlcs-expansion-pack-extras/expansion_soundtrack.zip",
+			},
+		},
+		{
+			name:  "without DLC extras",
+			flags: downloadFlags{platform: "windows", extras: true, dlcs: true, skipPatches: true, noDLCExtras: true},
+			want: []string{
+				"test-game/windows/setup_test_game_1.0.exe",
+				"test-game/extras/manual.pdf",
+				"test-game/dlcs-expansion-pack-windows/setup_expansion_pack_1.0.exe",
+			},
+		},
+		{
+			name:  "extras only without DLC extras",
+			flags: downloadFlags{platform: "windows", extras: true, dlcs: true, extrasOnly: true, noDLCExtras: true},
+			want:  []string{"test-game/extras/manual.pdf"},
+		},
+		{
+			name:  "files up to 10 KB",
+			flags: downloadFlags{platform: "windows", extras: true, dlcs: true, maxSize: 10 << 10},
+			want: []string{
+				"test-game/windows/patch_test_game_1.0_to_1.1.exe",
+				"test-game/extras/manual.pdf",
+				"test-game/dlcs-expansion-pack-extras/expansion_soundtrack.zip",
+			},
+		},
+		{
+			name:  "files between 10 and 20 KB",
+			flags: downloadFlags{platform: "windows", extras: true, dlcs: true, minSize: 10 << 10, maxSize: 20 << 10},
+			want:  []string{"test-game/dlcs-expansion-pack-windows/setup_expansion_pack_1.0.exe"},
+		},
+		{
+			name:  "all platforms without extras and DLCs",
+			flags: downloadFlags{platform: "all"},
+			want: []string{
+				"test-game/windows/setup_test_game_1.0.exe",
+				"test-game/windows/patch_test_game_1.0_to_1.1.exe",
+				"test-game/linux/test_game_1_0.sh",
+			},
+		},
+		{
+			name:  "linux only",
+			flags: downloadFlags{platform: "linux", extras: true},
+			want: []string{
+				"test-game/linux/test_game_1_0.sh",
+				"test-game/extras/manual.pdf",
+			},
+		},
+		{
+			name:  "without patches",
+			flags: downloadFlags{platform: "windows", skipPatches: true},
+			want:  []string{"test-game/windows/setup_test_game_1.0.exe"},
+		},
+		{
+			name:  "extras only",
+			flags: downloadFlags{platform: "windows", extras: true, dlcs: true, extrasOnly: true},
+			want: []string{
+				"test-game/extras/manual.pdf",
+				"test-game/dlcs-expansion-pack-extras/expansion_soundtrack.zip",
+			},
+		},
+		{
+			name:  "extras only without DLCs",
+			flags: downloadFlags{platform: "all", extras: true, extrasOnly: true},
+			want:  []string{"test-game/extras/manual.pdf"},
+		},
+		{
+			name:  "lgogdownloader layout",
+			flags: downloadFlags{platform: "all", extras: true, dlcs: true, layout: LayoutLgog},
+			want: []string{
+				"test-game/setup_test_game_1.0.exe",
+				"test-game/patches/patch_test_game_1.0_to_1.1.exe",
+				"test-game/test_game_1_0.sh",
+				"test-game/extras/manual.pdf",
+				"test-game/dlc/expansion_pack/setup_expansion_pack_1.0.exe",
+				"test-game/dlc/expansion_pack/extras/expansion_soundtrack.zip",
+			},
+		},
+		{
+			name:  "RomM layout",
+			flags: downloadFlags{platform: "windows", extras: true, flatten: true, romm: true},
+			want: []string{
+				"windows/test-game/setup_test_game_1.0.exe",
+				"windows/test-game/patch_test_game_1.0_to_1.1.exe",
+				"windows/test-game/manual.pdf",
+			},
+		},
+		{
+			name:  "RomM layout with all platforms",
+			flags: downloadFlags{platform: "all", extras: true, dlcs: true, romm: true},
+			want: []string{
+				"windows/test-game/setup_test_game_1.0.exe",
+				"windows/test-game/patch_test_game_1.0_to_1.1.exe",
+				"windows/test-game/setup_expansion_pack_1.0.exe",
+				"linux/test-game/test_game_1_0.sh",
+				"test-game/extras/manual.pdf",
+				"test-game/dlcs-expansion-pack-extras/expansion_soundtrack.zip",
+			},
+		},
+		{
+			name:  "RomM layout with all platforms, flattened",
+			flags: downloadFlags{platform: "all", extras: true, flatten: true, romm: true},
+			want: []string{
+				"windows/test-game/setup_test_game_1.0.exe",
+				"windows/test-game/patch_test_game_1.0_to_1.1.exe",
+				"linux/test-game/test_game_1_0.sh",
+				"test-game/manual.pdf",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newFakeGOG(t, fakeGameFiles...)
+			dir := t.TempDir()
+
+			require.NoError(t, downloadFakeGame(context.Background(), g, dir, tt.flags))
+
+			sizes := make(map[string]int64)
+			for _, f := range fakeGameFiles {
+				sizes[f.name] = int64(len(f.content))
+			}
+			got := listFiles(t, dir)
+			require.Contains(t, got, "test-game/metadata.json")
+			delete(got, "test-game/metadata.json")
+
+			gotPaths := make([]string, 0, len(got))
+			for path, size := range got {
+				gotPaths = append(gotPaths, path)
+				assert.Equal(t, sizes[filepath.Base(path)], size, "size of %s", path)
+			}
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			sort.Strings(gotPaths)
+			assert.Equal(t, want, gotPaths)
+
+			data, err := os.ReadFile(filepath.Join(dir, "test-game", "metadata.json"))
+			require.NoError(t, err)
+			var metadata struct{ Title string }
+			require.NoError(t, json.Unmarshal(data, &metadata))
+			assert.Equal(t, "Test Game", metadata.Title)
+		})
+	}
+}
+
+func TestDownloadGameFiles_ResumesInterruptedDownload(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	flags := downloadFlags{platform: "windows", extras: true, resume: true}
+	require.NoError(t, downloadFakeGame(context.Background(), g, dir, flags))
+
+	// Cut the installer short, as if the first run was interrupted.
+	installer := filepath.Join(dir, "test-game", "windows", "setup_test_game_1.0.exe")
+	require.NoError(t, os.Truncate(installer, 10000))
+
+	require.NoError(t, downloadFakeGame(context.Background(), g, dir, flags))
+
+	got, err := os.ReadFile(installer)
+	require.NoError(t, err)
+	assert.Equal(t, fakeGameFiles[0].content, got)
+	assert.Equal(t, []string{"", "bytes=10000-"}, g.rangesOf("setup_test_game_1.0.exe"))
+	// Complete files are not downloaded again.
+	assert.Equal(t, []string{""}, g.rangesOf("manual.pdf"))
+}
+
+func TestDownloadGameFiles_ManifestOnly(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+
+	err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "all",
+		true, true, true, false, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithManifestOnly())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"test-game/metadata.json"}, mapKeys(listFiles(t, dir)))
+	entries, err := os.ReadDir(filepath.Join(dir, "test-game"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no platform, extras, or DLC folders are created")
+	for _, f := range fakeGameFiles {
+		assert.Empty(t, g.rangesOf(f.name), "%s must not be downloaded", f.name)
+	}
+
+	require.NoError(t, WriteDownloadInfo(filepath.Join(dir, "test-game"), DownloadInfo{Language: "English", Platform: "all", Threads: 2}, DefaultFileModes))
+	data, err := os.ReadFile(filepath.Join(dir, "test-game", "download_info.json"))
+	require.NoError(t, err)
+	var info DownloadInfo
+	require.NoError(t, json.Unmarshal(data, &info))
+	assert.Equal(t, DownloadInfo{Language: "English", Platform: "all", Threads: 2}, info)
+}
+
+func TestDownloadGameFiles_MetadataWriter(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	var out bytes.Buffer
+
+	err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+		true, true, true, false, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithManifestOnly(), WithMetadataWriter(&out))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "test-game", "metadata.json"))
+	require.NoError(t, err)
+	assert.Equal(t, string(data)+"\n", out.String())
+}
+
+func mapKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestDownloadGameFiles_InstallerFilter(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	var calls int
+	firstOnly := func(files []PlatformFile) []PlatformFile {
+		calls++
+		if len(files) > 1 {
+			return files[:1]
+		}
+		return files
+	}
+
+	err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+		false, true, false, false, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithInstallerFilter(firstOnly))
+	require.NoError(t, err)
+
+	got := listFiles(t, dir)
+	delete(got, "test-game/metadata.json")
+	assert.Equal(t, []string{
+		"test-game/dlcs-expansion-pack-windows/setup_expansion_pack_1.0.exe",
+		"test-game/windows/setup_test_game_1.0.exe",
+	}, mapKeys(got), "the filter applies to the game but not to its DLCs")
+	assert.Equal(t, 3, calls, "the filter is called once per platform")
+}
+
+func TestDownloadGameFiles_FileModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no Unix permissions")
+	}
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := filepath.Join(t.TempDir(), "library")
+
+	err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+		false, false, false, false, false, false, 2, io.Discard, WithHTTPClient(g.Client()),
+		WithFileModes(FileModes{Dir: 0o750, File: 0o640}))
+	require.NoError(t, err)
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		require.NoError(t, err)
+		info, err := d.Info()
+		require.NoError(t, err)
+		want := os.FileMode(0o640)
+		if d.IsDir() {
+			want = 0o750
+		}
+		assert.Equal(t, want, info.Mode().Perm(), path)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestDownloadGameFiles_PreserveDate(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	game := fakeGame(g)
+	game.Downloads[0].Platforms.Windows[0].Date = strPtr("2019-11-05 12:34:56")
+	game.Downloads[0].Platforms.Windows[1].Date = strPtr("some day")
+
+	err := DownloadGameFiles(context.Background(), "tok", game, dir, "English", "windows",
+		false, false, false, false, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithPreserveDate())
+	require.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(dir, "test-game", "windows", "setup_test_game_1.0.exe"))
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(time.Date(2019, 11, 5, 12, 34, 56, 0, time.UTC)), "got %s", info.ModTime())
+
+	info, err = os.Stat(filepath.Join(dir, "test-game", "windows", "patch_test_game_1.0_to_1.1.exe"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), info.ModTime(), time.Minute, "an unparseable date is skipped")
+}
+
+func TestDownloadGameFiles_WritesChecksums(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	download := func() {
+		err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+			true, false, true, false, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithChecksums("SHA256"))
+		require.NoError(t, err)
+	}
+	sum := func(b []byte) string {
+		h := sha256.Sum256(b)
+		return hex.EncodeToString(h[:])
+	}
+	want := sum(fakeGameFiles[3].content) + "  extras/manual.pdf\n" +
+		sum(fakeGameFiles[2].content) + "  windows/patch_test_game_1.0_to_1.1.exe\n" +
+		sum(fakeGameFiles[0].content) + "  windows/setup_test_game_1.0.exe\n"
+
+	download()
+	got, err := os.ReadFile(filepath.Join(dir, "test-game", "CHECKSUMS.sha256"))
+	require.NoError(t, err)
+	assert.Equal(t, want, string(got))
+
+	// A resumed file is hashed with the part that was already on disk, and a complete one is hashed as is.
+	require.NoError(t, os.Truncate(filepath.Join(dir, "test-game", "windows", "setup_test_game_1.0.exe"), 10000))
+	download()
+	got, err = os.ReadFile(filepath.Join(dir, "test-game", "CHECKSUMS.sha256"))
+	require.NoError(t, err)
+	assert.Equal(t, want, string(got))
+	assert.Equal(t, []string{"", "bytes=10000-"}, g.rangesOf("setup_test_game_1.0.exe"))
+}
+
+func TestDownloadGameFiles_VerifyOnResume(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	installer := filepath.Join(dir, "test-game", "windows", "setup_test_game_1.0.exe")
+	download := func(options ...DownloadOption) {
+		options = append(options, WithHTTPClient(g.Client()))
+		err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+			false, false, true, false, true, false, 2, io.Discard, options...)
+		require.NoError(t, err)
+	}
+	corrupt := func() {
+		data := append([]byte(nil), fakeGameFiles[0].content...)
+		data[100] ^= 0xff
+		require.NoError(t, os.WriteFile(installer, data, 0o644))
+	}
+
+	download()
+	corrupt()
+
+	// Without a published checksum, a file that is complete by size is kept.
+	download(WithVerifyOnResume())
+	got, err := os.ReadFile(installer)
+	require.NoError(t, err)
+	assert.NotEqual(t, fakeGameFiles[0].content, got)
+	assert.Equal(t, []string{""}, g.rangesOf("setup_test_game_1.0.exe"))
+
+	g.publishChecksums.Store(true)
+	download(WithVerifyOnResume())
+	got, err = os.ReadFile(installer)
+	require.NoError(t, err)
+	assert.Equal(t, fakeGameFiles[0].content, got, "the corrupt file is downloaded again")
+	assert.Equal(t, []string{"", ""}, g.rangesOf("setup_test_game_1.0.exe"), "the file is downloaded again from the start")
+
+	// A valid file is not downloaded again.
+	download(WithVerifyOnResume())
+	assert.Len(t, g.rangesOf("setup_test_game_1.0.exe"), 2)
+}
+
+func TestDownloadGameFiles_ChecksumFiles(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	g.publishChecksums.Store(true)
+	dir := t.TempDir()
+	err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+		true, true, true, true, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithChecksumFiles())
+	require.NoError(t, err)
+
+	gameDir := filepath.Join(dir, "test-game")
+	sidecar, err := os.ReadFile(filepath.Join(gameDir, "setup_test_game_1.0.exe"+ChecksumFileSuffix))
+	require.NoError(t, err)
+	assert.Contains(t, string(sidecar), `name="setup_test_game_1.0.exe"`)
+
+	found, err := FindChecksumFiles(gameDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"patch_test_game_1.0_to_1.1.exe.xml",
+		"setup_expansion_pack_1.0.exe.xml",
+		"setup_test_game_1.0.exe.xml",
+	}, found, "extras get no checksum file")
+}
+
+func TestDownloadGameFiles_Preallocation(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	// A partial file is resumed from its length, which reserving the space of the whole file does not change.
+	setup := fakeGameFiles[0]
+	partial := filepath.Join(dir, "test-game", setup.name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(partial), 0o755))
+	require.NoError(t, os.WriteFile(partial, setup.content[:1000], 0o644))
+
+	err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+		true, true, true, true, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithPreallocation())
+	require.NoError(t, err)
+	got, err := os.ReadFile(partial)
+	require.NoError(t, err)
+	assert.Equal(t, setup.content, got)
+	files := listFiles(t, dir)
+	assert.Equal(t, int64(len(fakeGameFiles[2].content)), files["test-game/"+fakeGameFiles[2].name])
+}
+
+func TestDownloadGameFiles_ExcludedFiles(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	var updates bytes.Buffer
+	// The patch is matched by the name its link leads to, and the soundtrack by the name GOG lists for it.
+	err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+		true, true, true, false, false, false, 2, &updates, WithHTTPClient(g.Client()), WithExcludedFiles([]string{"PATCH_*", "soundtrack"}))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"test-game/metadata.json",
+		"test-game/windows/setup_test_game_1.0.exe",
+		"test-game/extras/manual.pdf",
+		"test-game/dlcs-expansion-pack-windows/setup_expansion_pack_1.0.exe",
+	}, mapKeys(listFiles(t, dir)))
+	assert.Equal(t, 2, strings.Count(updates.String(), `"outcome":"excluded"`))
+}
+
+func TestDownloadGameFiles_SkipSummary(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	download := func() SkipSummary {
+		var skips SkipSummary
+		err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+			true, true, true, false, true, false, 2, io.Discard, WithHTTPClient(g.Client()),
+			WithExcludedFiles([]string{"*soundtrack*"}), WithSkipSummary(func(s SkipSummary) { skips = s }))
+		require.NoError(t, err)
+		return skips
+	}
+
+	assert.Equal(t, SkipSummary{SkipOtherPlatform: 1, SkipPatch: 1, SkipExcluded: 1}, download())
+	// Run again, the files downloaded the first time are complete.
+	assert.Equal(t, SkipSummary{SkipOtherPlatform: 1, SkipPatch: 1, SkipExcluded: 1, SkipComplete: 3}, download())
+}
+
+func TestDownloadGameFiles_Strict(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	download := func(lang, platform string, options ...DownloadOption) (string, error) {
+		dir := t.TempDir()
+		options = append(options, WithHTTPClient(g.Client()))
+		err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, lang, platform,
+			true, true, true, true, false, false, 2, io.Discard, options...)
+		return dir, err
+	}
+
+	// Without --strict, a selection without installers downloads only the extras.
+	dir, err := download("English", "mac")
+	require.NoError(t, err)
+	assert.NotContains(t, mapKeys(listFiles(t, dir)), "test-game/setup_test_game_1.0.exe")
+
+	dir, err = download("English", "mac", WithStrict())
+	assert.ErrorIs(t, err, ErrNoMatchingFiles)
+	assert.Empty(t, listFiles(t, dir), "nothing is downloaded")
+
+	_, err = download("Deutsch", "windows", WithStrict())
+	assert.ErrorIs(t, err, ErrNoMatchingFiles)
+
+	_, err = download("English", "linux", WithStrict())
+	assert.NoError(t, err)
+
+	// With extras only, the extras are what has to match.
+	_, err = download("English", "mac", WithStrict(), WithExtrasOnly())
+	assert.NoError(t, err)
+}
+
+func TestDownloadGameFiles_ByteBudget(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	download := func(budget *ByteBudget) error {
+		return DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+			true, true, true, true, false, false, 1, io.Discard, WithHTTPClient(g.Client()), WithByteBudget(budget))
+	}
+
+	// With one worker the files go in order: the installer (48 KB) fits, the patch (8 KB) does not, the
+	// manual (4 KB) uses up the rest, and the DLC files are left out.
+	budget := NewByteBudget(52 * 1024)
+	err := download(budget)
+	require.ErrorIs(t, err, ErrByteBudgetReached)
+	assert.Contains(t, err.Error(), "3 file(s)")
+	assert.Equal(t, int64(52*1024), budget.Used())
+	assert.True(t, budget.Reached())
+	assert.Len(t, g.rangesOf("setup_test_game_1.0.exe"), 1)
+	assert.Len(t, g.rangesOf("manual.pdf"), 1)
+	assert.Empty(t, g.rangesOf("patch_test_game_1.0_to_1.1.exe"))
+	assert.Empty(t, g.rangesOf("setup_expansion_pack_1.0.exe"))
+
+	// A later run downloads what remains; the complete files cost nothing.
+	budget = NewByteBudget(26 * 1024)
+	require.NoError(t, download(budget))
+	assert.Equal(t, int64(26*1024), budget.Used())
+	assert.Len(t, g.rangesOf("setup_test_game_1.0.exe"), 1)
+	assert.Len(t, g.rangesOf("setup_expansion_pack_1.0.exe"), 1)
+}
+
+func TestGameFiles(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	game := fakeGame(g)
+
+	files := game.Files()
+	require.Len(t, files, 6)
+	for i, f := range files {
+		assert.Equal(t, i+1, f.Index)
+	}
+	assert.Equal(t, "linux", files[2].Platform)
+	assert.Equal(t, "Manual", files[3].Name)
+	assert.Equal(t, "Expansion Pack", files[4].Component)
+
+	f, err := game.FindFile("SETUP-LINUX")
+	require.NoError(t, err)
+	assert.Equal(t, 3, f.Index)
+
+	_, err = game.FindFile("Test Game")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 (Test Game, windows), 3 (Test Game, linux)")
+
+	_, err = game.FindFile("missing.exe")
+	assert.ErrorIs(t, err, ErrFileNotFound)
+	_, err = game.FileAt(7)
+	assert.ErrorIs(t, err, ErrFileNotFound)
+}
+
+func TestDownloadGameFiles_SingleFile(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	f, err := fakeGame(g).FileAt(3)
+	require.NoError(t, err)
+
+	// The language and platform flags do not apply to the picked file.
+	err = DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "Deutsch", "windows",
+		true, true, true, false, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithFile(f))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test-game/linux/test_game_1_0.sh", "test-game/metadata.json"}, mapKeys(listFiles(t, dir)))
+}
+
+func TestDownloadGameFiles_GameFolder(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+
+	err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "linux",
+		false, false, true, true, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithGameFolder("test-game-42"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test-game-42/test_game_1_0.sh", "test-game-42/metadata.json"}, mapKeys(listFiles(t, dir)))
+}
+
+func TestDownloadGameFiles_NoTitle(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	game := fakeGame(g)
+	game.Title = ""
+
+	err := DownloadGameFiles(context.Background(), "tok", game, dir, "English", "linux",
+		false, false, true, true, false, false, 2, io.Discard, WithHTTPClient(g.Client()))
+	assert.ErrorIs(t, err, ErrNoGameFolder)
+	assert.Empty(t, listFiles(t, dir), "nothing is written to the download root")
+
+	err = DownloadGameFiles(context.Background(), "tok", game, dir, "English", "linux",
+		false, false, true, true, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithGameFolder(GameFolderName(DefaultFolderTemplate, "", 42)))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"42/test_game_1_0.sh", "42/metadata.json"}, mapKeys(listFiles(t, dir)))
+}
+
+func TestDownloadGameFiles_SinceVersion(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	old := fakeGame(g)
+	old.Downloads[0].Platforms.Windows[0].Version = strPtr("1.0")
+	info := DownloadInfo{Language: "English", Platform: "windows", Extras: true, DLCs: true}
+
+	// The installer is updated and a linux file, which the earlier download did not select, is kept as it is.
+	game := fakeGame(g)
+	game.Downloads[0].Platforms.Windows[0].Version = strPtr("1.1")
+	err := DownloadGameFiles(context.Background(), "tok", game, dir, "English", "all",
+		true, true, false, true, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithSinceVersion(old, info))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"test-game/setup_test_game_1.0.exe",
+		"test-game/test_game_1_0.sh",
+		"test-game/metadata.json",
+	}, mapKeys(listFiles(t, dir)))
+
+	saved, err := ReadGameMetadata(filepath.Join(dir, "test-game"))
+	require.NoError(t, err)
+	assert.Equal(t, "1.1", *saved.Downloads[0].Platforms.Windows[0].Version, "the metadata of the whole game is written")
+}
+
+func TestDownloadGameFiles_Preflight(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	dir := t.TempDir()
+	var reports []PreflightReport
+
+	err := DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "all",
+		true, true, true, false, false, false, 2, io.Discard, WithHTTPClient(g.Client()),
+		WithPreflight(func(r PreflightReport) {
+			assert.Empty(t, listFiles(t, dir), "the check runs before any file is written")
+			reports = append(reports, r)
+		}))
+	require.NoError(t, err)
+
+	require.Len(t, reports, 1)
+	assert.Equal(t, len(listFiles(t, dir)), reports[0].Files, "every file is counted, with metadata.json")
+	assert.Empty(t, reports[0].Warnings)
+}
+
+func TestDownloadGameFiles_BestEffort(t *testing.T) {
+	var files []fakeFile
+	for _, f := range fakeGameFiles {
+		if f.key != "manual" {
+			files = append(files, f)
+		}
+	}
+	g := newFakeGOG(t, files...)
+
+	dir := t.TempDir()
+	err := downloadFakeGame(context.Background(), g, dir, downloadFlags{platform: "windows", extras: true, dlcs: true})
+	require.Error(t, err)
+	assert.NotContains(t, listFiles(t, dir), "test-game/metadata.json", "without best effort, a failed file fails the game")
+
+	dir = t.TempDir()
+	err = DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+		true, true, true, false, false, false, 2, io.Discard, WithHTTPClient(g.Client()), WithBestEffort())
+	var partial *PartialDownloadError
+	require.ErrorAs(t, err, &partial)
+	assert.Equal(t, 5, partial.Total)
+	require.Len(t, partial.Failed, 1)
+	assert.Equal(t, "manual", partial.Failed[0].Link)
+
+	got := listFiles(t, dir)
+	assert.Contains(t, got, "test-game/metadata.json")
+	assert.Contains(t, got, "test-game/windows/setup_test_game_1.0.exe")
+	assert.Contains(t, got, "test-game/dlcs-expansion-pack-extras/expansion_soundtrack.zip")
+}
+
+func TestDownloadGameFiles_VerifiesResumedFile(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	g.publishChecksums.Store(true)
+	dir := t.TempDir()
+	installer := filepath.Join(dir, "test-game", "windows", "setup_test_game_1.0.exe")
+	download := func() error {
+		return DownloadGameFiles(context.Background(), "tok", fakeGame(g), dir, "English", "windows",
+			false, false, true, false, true, false, 2, io.Discard, WithHTTPClient(g.Client()), WithVerifyOnResume())
+	}
+
+	// The part on disk is corrupt, so the assembled file does not match and is removed.
+	partial := append([]byte(nil), fakeGameFiles[0].content[:10000]...)
+	partial[100] ^= 0xff
+	require.NoError(t, os.MkdirAll(filepath.Dir(installer), 0o755))
+	require.NoError(t, os.WriteFile(installer, partial, 0o644))
+	require.ErrorIs(t, download(), ErrChecksumMismatch)
+	assert.NoFileExists(t, installer)
+
+	// The retry downloads the file from the start.
+	require.NoError(t, download())
+	got, err := os.ReadFile(installer)
+	require.NoError(t, err)
+	assert.Equal(t, fakeGameFiles[0].content, got)
+	assert.Equal(t, []string{"bytes=10000-", ""}, g.rangesOf("setup_test_game_1.0.exe"))
+}
+
+func TestDownloadGameFiles_FileNameStyle(t *testing.T) {
+	g := newFakeGOG(t,
+		fakeFile{key: "setup", name: "Setup_Test_Game_1.0.exe", content: fakeContent(1, 4*1024)},
+		fakeFile{key: "setup-copy", name: "Setup Test Game 1.0.exe", content: fakeContent(2, 2*1024)},
+		fakeFile{key: "manual", name: "Manual.PDF", content: fakeContent(3, 1024)},
+	)
+	dir := t.TempDir()
+	game := Game{
+		Title: "Test Game",
+		Downloads: []Downloadable{{Language: "English", Platforms: Platform{Windows: []PlatformFile{
+			{Name: "Test Game", Size: "4 KB", ManualURL: strPtr(g.link("setup"))},
+			{Name: "Test Game (copy)", Size: "2 KB", ManualURL: strPtr(g.link("setup-copy"))},
+		}}}},
+		Extras: []Extra{{Name: "Manual", Size: "1 KB", ManualURL: g.link("manual")}},
+	}
+
+	// Both installers are named setup_test_game_1.0.exe in lower case with underscores, so the second one keeps
+	// its original name. One thread downloads them in order.
+	err := DownloadGameFiles(context.Background(), "tok", game, dir, "English", "windows",
+		true, false, true, true, false, false, 1, io.Discard, WithHTTPClient(g.Client()), WithFileNameStyle(FileNamesLowerUnderscores))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{
+		"test-game/setup_test_game_1.0.exe": 4 * 1024,
+		"test-game/Setup Test Game 1.0.exe": 2 * 1024,
+		"test-game/manual.PDF":              1024,
+		"test-game/metadata.json":           listFiles(t, dir)["test-game/metadata.json"],
+	}, listFiles(t, dir))
+}
@@ -0,0 +1,305 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fileServer serves one file behind a GOG-like download link that redirects to the file.
+type fileServer struct {
+	*httptest.Server
+	content     []byte
+	ignoreRange bool
+	status      int // if set, GET requests for the file fail with this status
+	cutOff      int // if set, the next GET request ends after this many bytes, without a Content-Length
+	stalls      int // number of GET requests that send stallAt bytes and then nothing until the client gives up
+	stallAt     int
+	noRedirect  bool // if set, the download link serves the file itself instead of redirecting to it
+
+	mu     sync.Mutex
+	ranges []string // Range headers of the GET requests for the file
+	gets   int
+	heads  int
+}
+
+func newFileServer(t *testing.T, content []byte) *fileServer {
+	t.Helper()
+	fs := &fileServer{content: content}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/downloads/setup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if fs.noRedirect {
+			fs.serveFile(w, r)
+			return
+		}
+		http.Redirect(w, r, fs.URL+"/files/setup_game_1.0.exe?token=abc", http.StatusFound)
+	})
+	mux.HandleFunc("/files/setup_game_1.0.exe", fs.serveFile)
+	// A TLS server makes sure the injected client is used: the default client does not trust its certificate.
+	fs.Server = httptest.NewTLSServer(mux)
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func (fs *fileServer) serveFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodHead {
+		fs.mu.Lock()
+		fs.heads++
+		fs.mu.Unlock()
+	}
+	if r.Method == http.MethodGet {
+		fs.mu.Lock()
+		fs.gets++
+		fs.ranges = append(fs.ranges, r.Header.Get("Range"))
+		cutOff := fs.cutOff
+		fs.cutOff = 0
+		stall := fs.stalls > 0
+		if stall {
+			fs.stalls--
+		}
+		fs.mu.Unlock()
+		if stall {
+			start := 0
+			if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start); err == nil {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(fs.content)-1, len(fs.content)))
+				w.Header().Set("Content-Length", strconv.Itoa(len(fs.content)-start))
+				w.WriteHeader(http.StatusPartialContent)
+			} else {
+				w.Header().Set("Content-Length", strconv.Itoa(len(fs.content)))
+				w.WriteHeader(http.StatusOK)
+			}
+			_, _ = w.Write(fs.content[start:max(start, fs.stallAt)])
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		if fs.status != 0 {
+			w.WriteHeader(fs.status)
+			return
+		}
+		if cutOff > 0 {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(fs.content[:cutOff])
+			w.(http.Flusher).Flush() // sends the body chunked, so the client cannot tell it is short
+			return
+		}
+		if fs.ignoreRange {
+			r.Header.Del("Range")
+		}
+	}
+	http.ServeContent(w, r, "setup_game_1.0.exe", time.Time{}, bytes.NewReader(fs.content))
+}
+
+func (fs *fileServer) game() Game {
+	return Game{Title: "Test Game", Downloads: []Downloadable{{Language: "English", Platforms: Platform{
+		Windows: []PlatformFile{{Name: "Test Game", Size: "1 MB", ManualURL: strPtr(fs.URL + "/downloads/setup")}},
+	}}}}
+}
+
+func (fs *fileServer) download(t *testing.T, dir string, resume bool, options ...DownloadOption) error {
+	t.Helper()
+	return DownloadGameFiles(context.Background(), "tok", fs.game(), dir, "English", "windows",
+		false, false, resume, true, false, false, 1, io.Discard, append([]DownloadOption{WithHTTPClient(fs.Client())}, options...)...)
+}
+
+func testContent(size int) []byte {
+	return bytes.Repeat([]byte("0123456789abcdef"), size/16)
+}
+
+func TestDownloadGameFiles_FollowsRedirectWithInjectedClient(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	dir := t.TempDir()
+
+	require.NoError(t, fs.download(t, dir, false))
+
+	// The file is named after the redirect target, without its query string.
+	got, err := os.ReadFile(filepath.Join(dir, "test-game", "setup_game_1.0.exe"))
+	require.NoError(t, err)
+	assert.Equal(t, fs.content, got)
+	assert.Equal(t, []string{""}, fs.ranges)
+}
+
+func TestDownloadGameFiles_ResumesWithRange(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test-game", "setup_game_1.0.exe")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, fs.content[:1000], 0o644))
+
+	require.NoError(t, fs.download(t, dir, true))
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, fs.content, got)
+	assert.Equal(t, []string{"bytes=1000-"}, fs.ranges)
+}
+
+func TestDownloadGameFiles_RestartsWhenRangeIsIgnored(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	fs.ignoreRange = true
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test-game", "setup_game_1.0.exe")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", 1000)), 0o644))
+
+	require.NoError(t, fs.download(t, dir, true))
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, fs.content, got, "the partial file must be replaced when the server sends the whole file")
+}
+
+func TestDownloadGameFiles_KeepsIncompleteFileForResume(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	fs.cutOff = 5000
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test-game", "setup_game_1.0.exe")
+
+	err := fs.download(t, dir, true)
+	require.ErrorIs(t, err, ErrIncompleteFile)
+	info, statErr := os.Stat(path)
+	require.NoError(t, statErr, "the partial file must be kept for a retry")
+	assert.Equal(t, int64(5000), info.Size())
+
+	require.NoError(t, fs.download(t, dir, true))
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, fs.content, got)
+	assert.Equal(t, []string{"", "bytes=5000-"}, fs.ranges, "the retry continues the partial file")
+}
+
+func TestDownloadGameFiles_ResumesExtraSavedUnderItsTitle(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	game := Game{Title: "Test Game", Extras: []Extra{{Name: "Test Soundtrack", Size: "1 MB", ManualURL: fs.URL + "/downloads/setup"}}}
+	download := func(dir string) error {
+		return DownloadGameFiles(context.Background(), "tok", game, dir, "English", "windows", true, false, true, true, false, false, 1,
+			io.Discard, WithHTTPClient(fs.Client()))
+	}
+	dir := t.TempDir()
+	titled := filepath.Join(dir, "test-game", "test-soundtrack")
+	real := filepath.Join(dir, "test-game", "setup_game_1.0.exe")
+
+	// Without a redirect, the extra is named after its title. The run is interrupted after 5000 bytes.
+	fs.noRedirect = true
+	require.NoError(t, download(dir))
+	require.NoError(t, os.Truncate(titled, 5000))
+
+	fs.noRedirect = false
+	fs.ranges = nil
+	require.NoError(t, download(dir))
+	got, err := os.ReadFile(real)
+	require.NoError(t, err)
+	assert.Equal(t, fs.content, got)
+	assert.NoFileExists(t, titled, "the partial download is resumed under the real name")
+	assert.Equal(t, []string{"bytes=5000-"}, fs.ranges)
+}
+
+func TestDownloadGameFiles_RemovesIncompleteFileWithoutResume(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	fs.cutOff = 5000
+	dir := t.TempDir()
+
+	require.ErrorIs(t, fs.download(t, dir, false), ErrIncompleteFile)
+	assert.NoFileExists(t, filepath.Join(dir, "test-game", "setup_game_1.0.exe"))
+}
+
+func TestDownloadGameFiles_SkipsCompleteFile(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test-game", "setup_game_1.0.exe")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, fs.content, 0o644))
+
+	require.NoError(t, fs.download(t, dir, true))
+
+	assert.Zero(t, fs.gets, "a complete file must not be downloaded again")
+}
+
+func TestDownloadGameFiles_FailsOnTooManyRequests(t *testing.T) {
+	fs := newFileServer(t, testContent(1024))
+	fs.status = http.StatusTooManyRequests
+
+	err := fs.download(t, t.TempDir(), false)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 429")
+}
+
+func TestDownloadGameFiles_RetriesStalledFile(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	fs.stalls, fs.stallAt = 1, 5000
+	dir := t.TempDir()
+
+	require.NoError(t, fs.download(t, dir, true, WithStallTimeout(100*time.Millisecond)))
+
+	got, err := os.ReadFile(filepath.Join(dir, "test-game", "setup_game_1.0.exe"))
+	require.NoError(t, err)
+	assert.Equal(t, fs.content, got)
+	assert.Equal(t, []string{"", "bytes=5000-"}, fs.ranges, "the retry resumes after the bytes that arrived")
+}
+
+func TestDownloadGameFiles_SendsFileDone(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	fs.stalls, fs.stallAt = 1, 5000
+	dir := t.TempDir()
+	fileDone := func() ProgressUpdate {
+		var progress bytes.Buffer
+		err := DownloadGameFiles(context.Background(), "tok", fs.game(), dir, "English", "windows", false, false, true, true, false, false, 1,
+			&progress, WithHTTPClient(fs.Client()), WithStallTimeout(100*time.Millisecond), WithChecksums("md5"))
+		require.NoError(t, err)
+		var updates []ProgressUpdate
+		for _, line := range strings.Split(strings.TrimSpace(progress.String()), "\n") {
+			var update ProgressUpdate
+			require.NoError(t, json.Unmarshal([]byte(line), &update))
+			if update.Type == "file_done" {
+				updates = append(updates, update)
+			}
+		}
+		require.Len(t, updates, 1)
+		return updates[0]
+	}
+
+	done := fileDone()
+	assert.Equal(t, FileDownloaded, done.Outcome)
+	assert.Equal(t, "setup_game_1.0.exe", done.FileName)
+	assert.Equal(t, int64(64*1024), done.TotalBytes)
+	assert.Equal(t, int64(64*1024), done.Received, "the bytes of the stalled attempt count too")
+	assert.Equal(t, 1, done.Retries)
+	assert.Len(t, done.Checksum, 32)
+
+	done = fileDone()
+	assert.Equal(t, FileComplete, done.Outcome)
+	assert.Zero(t, done.Received)
+	assert.Zero(t, done.Retries)
+}
+
+func TestDownloadGameFiles_FailsWhenFileKeepsStalling(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	fs.stalls, fs.stallAt = 100, 5000
+	dir := t.TempDir()
+
+	err := fs.download(t, dir, true, WithStallTimeout(50*time.Millisecond))
+	require.ErrorIs(t, err, ErrFileStalled)
+	assert.Equal(t, 1+stallRetries, fs.gets)
+	info, statErr := os.Stat(filepath.Join(dir, "test-game", "setup_game_1.0.exe"))
+	require.NoError(t, statErr)
+	assert.Equal(t, int64(5000), info.Size(), "the partial file is kept for a later resume")
+}
@@ -49,3 +49,4 @@ func TestPartialCleanupOnCancel(t *testing.T) {
 }
 
 func strPtr(s string) *string { return &s }
+func boolPtr(b bool) *bool    { return &b }
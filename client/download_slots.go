@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+// DownloadSlots limits how many files are downloaded at the same time by several calls of DownloadGameFiles.
+// Each file takes a slot for as long as it is being downloaded.
+type DownloadSlots struct {
+	mu    sync.Mutex
+	size  int
+	used  int
+	freed chan struct{} // closed and replaced whenever a slot is freed or the size changes
+}
+
+// NewDownloadSlots returns DownloadSlots that allow n files to be downloaded at the same time. n is at least 1.
+func NewDownloadSlots(n int) *DownloadSlots {
+	if n < 1 {
+		n = 1
+	}
+	return &DownloadSlots{size: n, freed: make(chan struct{})}
+}
+
+// Size returns the number of files that can be downloaded at the same time.
+func (s *DownloadSlots) Size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Resize changes the number of files that can be downloaded at the same time to n, which is at least 1.
+// Files already downloading keep their slots; after shrinking, no new file starts until enough are done.
+func (s *DownloadSlots) Resize(n int) {
+	if n < 1 {
+		n = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n != s.size {
+		s.size = n
+		s.wake()
+	}
+}
+
+// Acquire waits for a free slot. It returns the error of ctx if ctx is done first.
+func (s *DownloadSlots) Acquire(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		if s.used < s.size {
+			s.used++
+			s.mu.Unlock()
+			return nil
+		}
+		freed := s.freed
+		s.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (s *DownloadSlots) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.used--
+	s.wake()
+}
+
+// wake lets every waiting Acquire check again for a free slot. s.mu must be held.
+func (s *DownloadSlots) wake() {
+	close(s.freed)
+	s.freed = make(chan struct{})
+}
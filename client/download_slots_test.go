@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadSlots_LimitsConcurrency(t *testing.T) {
+	s := NewDownloadSlots(2)
+	require.Equal(t, 2, s.Size())
+	require.NoError(t, s.Acquire(context.Background()))
+	require.NoError(t, s.Acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Acquire(ctx), context.DeadlineExceeded, "no slot is free")
+
+	s.Release()
+	assert.NoError(t, s.Acquire(context.Background()))
+	assert.Equal(t, 1, NewDownloadSlots(0).Size())
+}
+
+func TestDownloadSlots_Resize(t *testing.T) {
+	s := NewDownloadSlots(2)
+	require.NoError(t, s.Acquire(context.Background()))
+	require.NoError(t, s.Acquire(context.Background()))
+
+	// Shrinking keeps the slots in use; a new file waits until the files in use fit in the new size.
+	s.Resize(1)
+	assert.Equal(t, 1, s.Size())
+	s.Release()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Acquire(ctx), context.DeadlineExceeded)
+
+	// Growing wakes a file that is already waiting.
+	acquired := make(chan error, 1)
+	go func() { acquired <- s.Acquire(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	s.Resize(3)
+	select {
+	case err := <-acquired:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("growing the slots did not wake the waiting download")
+	}
+}
+
+func TestDownloadGameFiles_WaitsForDownloadSlots(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	slots := NewDownloadSlots(1)
+	download := func(ctx context.Context, dir string) error {
+		return DownloadGameFiles(ctx, "tok", fakeGame(g), dir, "English", "windows",
+			true, true, false, false, false, false, 4, io.Discard, WithHTTPClient(g.Client()), WithDownloadSlots(slots))
+	}
+
+	// Another download holds the only slot, so no file is downloaded.
+	require.NoError(t, slots.Acquire(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Error(t, download(ctx, t.TempDir()))
+	for _, f := range fakeGameFiles {
+		assert.Empty(t, g.rangesOf(f.name), "%s must wait for a slot", f.name)
+	}
+
+	slots.Release()
+	dir := t.TempDir()
+	require.NoError(t, download(context.Background(), dir))
+	assert.Contains(t, listFiles(t, dir), "test-game/windows/setup_test_game_1.0.exe")
+}
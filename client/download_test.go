@@ -1,8 +1,11 @@
 package client
 
 import (
+	"context"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,7 +39,7 @@ func TestDownloadSanitizePath(t *testing.T) {
 func TestDownloadEnsureDirExists(t *testing.T) {
 	tmp := t.TempDir()
 	path := filepath.Join(tmp, "subdir")
-	err := ensureDirExists(path)
+	err := ensureDirExists(path, DefaultFileModes.Dir)
 	assert.NoError(t, err)
 	info, err := os.Stat(path)
 	assert.NoError(t, err)
@@ -44,7 +47,7 @@ func TestDownloadEnsureDirExists(t *testing.T) {
 
 	filePath := filepath.Join(tmp, "file.txt")
 	os.WriteFile(filePath, []byte("data"), 0o644)
-	err = ensureDirExists(filePath)
+	err = ensureDirExists(filePath, DefaultFileModes.Dir)
 	if err == nil {
 		t.Error("Expected error when path exists and is not a directory, got nil")
 	}
@@ -160,6 +163,14 @@ func TestBuildManualURL(t *testing.T) {
 			input:    "/account/gameDetails/123.json",
 			expected: "https://embed.gog.com/account/gameDetails/123.json",
 		},
+		{
+			input:    "downloads/game/en1installer0",
+			expected: "https://embed.gog.com/downloads/game/en1installer0",
+		},
+		{
+			input:    "//cdn.gog.com/extras/manual.pdf",
+			expected: "https://cdn.gog.com/extras/manual.pdf",
+		},
 	}
 
 	for _, tt := range tests {
@@ -167,3 +178,150 @@ func TestBuildManualURL(t *testing.T) {
 		assert.Equal(t, tt.expected, result)
 	}
 }
+
+func TestEnqueue_ManualURLForms(t *testing.T) {
+	urls := map[string]string{
+		"absolute":          "https://cdn.gog.com/a",
+		"relative":          "/downloads/b",
+		"protocol-relative": "//cdn.gog.com/c",
+	}
+	want := map[string]string{
+		"absolute":          "https://cdn.gog.com/a",
+		"relative":          "https://embed.gog.com/downloads/b",
+		"protocol-relative": "https://cdn.gog.com/c",
+	}
+	var game Game
+	for name, u := range urls {
+		manual := u
+		game.Downloads = append(game.Downloads, Downloadable{Language: "English",
+			Platforms: Platform{Windows: []PlatformFile{{Name: name + ".exe", ManualURL: &manual}}}})
+		game.Extras = append(game.Extras, Extra{Name: name, ManualURL: u})
+	}
+
+	var tasks []downloadTask
+	enqueue := func(task downloadTask) { tasks = append(tasks, task) }
+	require.NoError(t, enqueueGameFiles(context.Background(), enqueue, game, "en", "windows", "", true, true, false, LanguageFoldersAuto, "", nil))
+	require.Len(t, tasks, len(urls))
+	for _, task := range tasks {
+		assert.Equal(t, want[strings.TrimSuffix(task.fileName, ".exe")], task.url, "installer %s", task.fileName)
+	}
+
+	tasks = nil
+	require.NoError(t, enqueueExtras(context.Background(), enqueue, game.Extras, "extras", true, true, nil))
+	require.Len(t, tasks, len(urls))
+	got := make(map[string]bool)
+	for _, task := range tasks {
+		got[task.url] = true
+	}
+	for name, u := range want {
+		assert.True(t, got[u], "extra %s should be downloaded from %s", name, u)
+	}
+}
+
+func TestEnqueueGameFiles_AllLanguages(t *testing.T) {
+	url := func(s string) *string { return &s }
+	game := Game{Downloads: []Downloadable{
+		{Language: "English", Platforms: Platform{Windows: []PlatformFile{{Name: "setup_en.exe", ManualURL: url("/downloads/en")}}}},
+		{Language: "Deutsch", Platforms: Platform{Windows: []PlatformFile{{Name: "setup_de.exe", ManualURL: url("/downloads/de")}}}},
+	}}
+
+	var tasks []downloadTask
+	enqueue := func(task downloadTask) { tasks = append(tasks, task) }
+
+	require.NoError(t, enqueueGameFiles(context.Background(), enqueue, game, "en", "windows", "", true, true, false, LanguageFoldersAuto, "", nil))
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "setup_en.exe", tasks[0].fileName)
+	assert.Empty(t, tasks[0].langDir)
+
+	tasks = nil
+	require.NoError(t, enqueueGameFiles(context.Background(), enqueue, game, AllLanguages, "windows", "", true, true, false, LanguageFoldersAuto, "", nil))
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "en", tasks[0].langDir)
+	assert.Equal(t, "de", tasks[1].langDir)
+}
+
+func TestEnqueueGameFiles_LanguageFolders(t *testing.T) {
+	url := func(s string) *string { return &s }
+	game := Game{Downloads: []Downloadable{
+		{Language: "English", Platforms: Platform{Windows: []PlatformFile{
+			{Name: "setup_en.exe", ManualURL: url("/downloads/en")},
+			{Name: "soundtrack", ManualURL: url("/downloads/shared")},
+		}}},
+		{Language: "Deutsch", Platforms: Platform{Windows: []PlatformFile{
+			{Name: "setup_de.exe", ManualURL: url("/downloads/de")},
+			{Name: "soundtrack", ManualURL: url("/downloads/shared")},
+		}}},
+	}}
+	enqueueAll := func(lang string, mode LanguageFolders) []downloadTask {
+		var tasks []downloadTask
+		enqueue := func(task downloadTask) { tasks = append(tasks, task) }
+		require.NoError(t, enqueueGameFiles(context.Background(), enqueue, game, lang, "windows", "", true, true, false, mode, "", nil))
+		return tasks
+	}
+
+	tasks := enqueueAll("de", LanguageFoldersAlways)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "de", tasks[0].langDir, "a single language gets its folder too")
+
+	tasks = enqueueAll(AllLanguages, LanguageFoldersNever)
+	require.Len(t, tasks, 3, "the file listed for both languages is downloaded once")
+	for _, task := range tasks {
+		assert.Empty(t, task.langDir)
+	}
+	assert.Equal(t, []string{"en", "en", "de"}, []string{tasks[0].langFallback, tasks[1].langFallback, tasks[2].langFallback})
+
+	tasks = enqueueAll(AllLanguages, LanguageFoldersAuto)
+	assert.Len(t, tasks, 4)
+}
+
+func TestEnqueueGameFiles_PreferredLanguage(t *testing.T) {
+	url := func(s string) *string { return &s }
+	game := Game{Downloads: []Downloadable{
+		{Language: "English", Platforms: Platform{Windows: []PlatformFile{
+			{Name: "setup_en.exe", ManualURL: url("/downloads/en")},
+			{Name: "soundtrack", ManualURL: url("/downloads/shared")},
+		}}},
+		{Language: "Deutsch", Platforms: Platform{Windows: []PlatformFile{
+			{Name: "setup_de.exe", ManualURL: url("/downloads/de")},
+			{Name: "soundtrack", ManualURL: url("/downloads/shared")},
+		}}},
+	}}
+	enqueueAll := func(mode LanguageFolders, preferLang string) []downloadTask {
+		var tasks []downloadTask
+		enqueue := func(task downloadTask) { tasks = append(tasks, task) }
+		require.NoError(t, enqueueGameFiles(context.Background(), enqueue, game, AllLanguages, "windows", "", true, true, false, mode, preferLang, nil))
+		return tasks
+	}
+	langDirs := func(tasks []downloadTask) []string {
+		dirs := make([]string, len(tasks))
+		for i, task := range tasks {
+			dirs[i] = task.fileName + ":" + task.langDir
+		}
+		return dirs
+	}
+
+	assert.Equal(t, []string{"setup_de.exe:", "soundtrack:", "setup_en.exe:en", "soundtrack:en"}, langDirs(enqueueAll(LanguageFoldersAuto, "de")))
+	assert.Equal(t, []string{"setup_en.exe:", "soundtrack:", "setup_de.exe:de", "soundtrack:de"}, langDirs(enqueueAll(LanguageFoldersAuto, "fr")),
+		"a language the game does not offer falls back to the first one")
+
+	tasks := enqueueAll(LanguageFoldersNever, "Deutsch")
+	require.Len(t, tasks, 3)
+	assert.Equal(t, "setup_de.exe", tasks[0].fileName)
+	assert.Equal(t, "de", tasks[1].langFallback, "the shared file is enqueued for the preferred language")
+}
+
+func TestDownloadClients_MaxConnsPerHost(t *testing.T) {
+	transportOf := func(options ...DownloadOption) *http.Transport {
+		cfg := downloadConfig{maxConnsPerHost: DefaultMaxConnsPerHost}
+		for _, option := range options {
+			option(&cfg)
+		}
+		c, noRedirect := downloadClients(cfg)
+		require.Same(t, c.Transport, noRedirect.Transport)
+		return c.Transport.(*http.Transport)
+	}
+
+	assert.Equal(t, DefaultMaxConnsPerHost, transportOf().MaxConnsPerHost)
+	assert.Equal(t, 3, transportOf(WithMaxConnsPerHost(3)).MaxConnsPerHost)
+	assert.Equal(t, 0, transportOf(WithMaxConnsPerHost(-1)).MaxConnsPerHost, "less than zero means no limit")
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Styles of the names of downloaded files, see WithFileNameStyle.
+const (
+	// FileNamesOriginal keeps the names the files have on GOG's servers.
+	FileNamesOriginal = "original"
+	// FileNamesLower writes the names in lower case, like "setup_the_witcher_1.5.exe".
+	FileNamesLower = "lower"
+	// FileNamesUnderscores replaces the spaces of the names with underscores, like "The_Witcher_Manual.pdf".
+	FileNamesUnderscores = "underscores"
+	// FileNamesLowerUnderscores does both, like "the_witcher_manual.pdf".
+	FileNamesLowerUnderscores = "lower-underscores"
+)
+
+// FileNameStyles are the supported styles of the names of downloaded files.
+var FileNameStyles = []string{FileNamesOriginal, FileNamesLower, FileNamesUnderscores, FileNamesLowerUnderscores}
+
+// ValidateFileNameStyle checks that style is one of FileNameStyles.
+func ValidateFileNameStyle(style string) error {
+	for _, s := range FileNameStyles {
+		if style == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown file name style %q; use one of %s", style, strings.Join(FileNameStyles, ", "))
+}
+
+// NormalizeFileName returns name written in style. The extension, like ".exe" or ".tar.gz", is kept as it is,
+// so tools that go by the extension see the same file type. An empty or unknown style keeps name.
+func NormalizeFileName(name, style string) string {
+	stem, ext := splitExtension(name)
+	switch style {
+	case FileNamesLower:
+		stem = strings.ToLower(stem)
+	case FileNamesUnderscores:
+		stem = strings.Join(strings.Fields(stem), "_")
+	case FileNamesLowerUnderscores:
+		stem = strings.Join(strings.Fields(strings.ToLower(stem)), "_")
+	default:
+		return name
+	}
+	if stem == "" {
+		return name
+	}
+	return stem + ext
+}
+
+// splitExtension splits name into its stem and extension. ".tar.gz" counts as one extension, like in the names
+// of GOG's Linux installers. A name that starts with a dot, like ".hidden", has no extension.
+func splitExtension(name string) (stem, ext string) {
+	if strings.HasSuffix(strings.ToLower(name), ".tar.gz") && len(name) > len(".tar.gz") {
+		cut := len(name) - len(".tar.gz")
+		return name[:cut], name[cut:]
+	}
+	dot := strings.LastIndexByte(name, '.')
+	if dot <= 0 {
+		return name, ""
+	}
+	return name[:dot], name[dot:]
+}
+
+// WithFileNameStyle makes DownloadGameFiles write the downloaded files with their names in style, one of
+// FileNameStyles. If another file of the download already has the resulting name, the file goes into its
+// language folder like other files with the same name, or else keeps its original name. The default is
+// FileNamesOriginal.
+func WithFileNameStyle(style string) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.fileNames = style }
+}
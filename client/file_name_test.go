@@ -0,0 +1,33 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeFileName(t *testing.T) {
+	tests := []struct {
+		name, style, want string
+	}{
+		{"Setup The Witcher 1.5.EXE", FileNamesOriginal, "Setup The Witcher 1.5.EXE"},
+		{"Setup The Witcher 1.5.EXE", "", "Setup The Witcher 1.5.EXE"},
+		{"Setup The Witcher 1.5.EXE", FileNamesLower, "setup the witcher 1.5.EXE"},
+		{"Setup The Witcher 1.5.EXE", FileNamesUnderscores, "Setup_The_Witcher_1.5.EXE"},
+		{"Setup  The Witcher 1.5.EXE", FileNamesLowerUnderscores, "setup_the_witcher_1.5.EXE"},
+		{"Witcher Linux 1.0.TAR.GZ", FileNamesLowerUnderscores, "witcher_linux_1.0.TAR.GZ"},
+		{"README", FileNamesLower, "readme"},
+		{".Hidden File", FileNamesLowerUnderscores, ".hidden_file"},
+		{" .exe", FileNamesUnderscores, " .exe"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormalizeFileName(tt.name, tt.style), "%q in %q", tt.name, tt.style)
+	}
+}
+
+func TestValidateFileNameStyle(t *testing.T) {
+	for _, style := range FileNameStyles {
+		assert.NoError(t, ValidateFileNameStyle(style))
+	}
+	assert.ErrorContains(t, ValidateFileNameStyle("upper"), "unknown file name style")
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// fileDateLayouts are the formats GOG has been seen to use for the date of a file, tried in order.
+var fileDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"02.01.2006",
+}
+
+// parseFileDate parses the date GOG reports for a file. Dates without a time zone are taken as UTC.
+func parseFileDate(date string) (time.Time, bool) {
+	date = strings.TrimSpace(date)
+	if date == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range fileDateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// setFileDate sets the access and modification times of path to date. Empty and unparseable dates are skipped.
+func setFileDate(path, date string) {
+	t, ok := parseFileDate(date)
+	if !ok {
+		if date != "" {
+			log.Debug().Str("file", path).Msgf("Not setting the file date; cannot parse %q", date)
+		}
+		return
+	}
+	if err := os.Chtimes(path, t, t); err != nil {
+		log.Warn().Err(err).Str("file", path).Msg("Failed to set the file date")
+	}
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFileDate(t *testing.T) {
+	day := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		want  time.Time
+		ok    bool
+	}{
+		{"2021-03-04T10:20:30+02:00", time.Date(2021, 3, 4, 8, 20, 30, 0, time.UTC), true},
+		{"2021-03-04T10:20:30+0200", time.Date(2021, 3, 4, 8, 20, 30, 0, time.UTC), true},
+		{"2021-03-04 10:20:30", time.Date(2021, 3, 4, 10, 20, 30, 0, time.UTC), true},
+		{" 2021-03-04 ", day, true},
+		{"March 4, 2021", day, true},
+		{"Mar 4, 2021", day, true},
+		{"04.03.2021", day, true},
+		{"", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseFileDate(tt.input)
+		assert.Equal(t, tt.ok, ok, tt.input)
+		assert.True(t, tt.want.Equal(got), "%q: got %s, want %s", tt.input, got, tt.want)
+	}
+}
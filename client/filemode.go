@@ -0,0 +1,90 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+// FileModes are the permissions given to the directories and files created by a download.
+type FileModes struct {
+	Dir  os.FileMode
+	File os.FileMode
+}
+
+// DefaultFileModes are the permissions used when no others are set with WithFileModes.
+var DefaultFileModes = FileModes{Dir: 0o755, File: 0o644}
+
+// WithFileModes makes DownloadGameFiles create directories and files with modes. New directories and files get
+// exactly these permissions, regardless of the umask; existing ones are left as they are.
+func WithFileModes(modes FileModes) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.modes = modes }
+}
+
+// ensureDirExists creates path and its missing parents with mode and checks that path is a directory.
+func ensureDirExists(path string, mode os.FileMode) error {
+	var missing []string
+	for p := filepath.Clean(path); ; p = filepath.Dir(p) {
+		if _, err := os.Lstat(p); !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, p)
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+
+	err := os.MkdirAll(path, mode)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to create directory: %s", path)
+		return WrapVolumeError(path, err)
+	}
+	for _, p := range missing {
+		if err := os.Chmod(p, mode); err != nil {
+			return err
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Error().Err(err).Msgf("Error checking directory %s", path)
+		return err
+	}
+	if !info.IsDir() {
+		log.Error().Msgf("Path %s exists but is not a directory", path)
+		return fmt.Errorf("path %s exists but is not a directory", path)
+	}
+	return nil
+}
+
+// openFile opens path for writing with flag, creating it if needed. A new file gets exactly mode.
+// Errors caused by a full or read-only volume are wrapped by WrapVolumeError.
+func openFile(path string, flag int, mode os.FileMode) (*os.File, error) {
+	_, statErr := os.Lstat(path)
+	file, err := os.OpenFile(path, flag|os.O_WRONLY|os.O_CREATE, mode)
+	if err != nil {
+		return nil, WrapVolumeError(path, err)
+	}
+	if os.IsNotExist(statErr) {
+		if err := file.Chmod(mode); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// writeFile writes data to path like os.WriteFile, giving a new file exactly mode.
+func writeFile(path string, data []byte, mode os.FileMode) error {
+	file, err := openFile(path, os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return WrapVolumeError(path, err)
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrFolderLocked means that another process is downloading into the same game folder.
+var ErrFolderLocked = errors.New("the game folder is locked by another download")
+
+// FolderLock is a lock on a game folder, held while a download writes into it, so that two processes never
+// write the same files. It is released when the process exits, even if it crashes.
+type FolderLock struct {
+	file *os.File
+}
+
+// FolderLockPath returns the path of the lock file of the game folder named folder in downloadPath. The lock
+// file is kept next to the folder, not in it, so it is never taken for a file of the game.
+func FolderLockPath(downloadPath, folder string) string {
+	return filepath.Join(downloadPath, "."+folder+".lock")
+}
+
+// LockGameFolder locks the game folder named folder in downloadPath, creating downloadPath if needed. If
+// another process holds the lock, it fails right away with ErrFolderLocked, naming that process if it can.
+// On systems without file locks, it always succeeds.
+func LockGameFolder(downloadPath, folder string, modes FileModes) (*FolderLock, error) {
+	if err := ensureDirExists(downloadPath, modes.Dir); err != nil {
+		return nil, err
+	}
+	path := FolderLockPath(downloadPath, folder)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, modes.File)
+		if err != nil {
+			return nil, err
+		}
+		if err := lockFile(f); err != nil {
+			_ = f.Close()
+			if !errors.Is(err, errLockHeld) {
+				return nil, err
+			}
+			holder := ""
+			if data, readErr := os.ReadFile(path); readErr == nil {
+				if pid, convErr := strconv.Atoi(strings.TrimSpace(string(data))); convErr == nil {
+					holder = fmt.Sprintf(" (process %d)", pid)
+				}
+			}
+			return nil, fmt.Errorf("%w%s: %s", ErrFolderLocked, holder, filepath.Join(downloadPath, folder))
+		}
+		// The holder before may have removed the file between opening and locking it; the lock is then on a
+		// file that no other process can find, so the new file has to be locked instead.
+		opened, statErr := f.Stat()
+		current, err := os.Stat(path)
+		if statErr != nil || err != nil || !os.SameFile(opened, current) {
+			_ = f.Close()
+			continue
+		}
+		_ = f.Truncate(0)
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+		return &FolderLock{file: f}, nil
+	}
+}
+
+// Unlock releases the lock and removes its file.
+func (l *FolderLock) Unlock() error {
+	return releaseFile(l.file)
+}
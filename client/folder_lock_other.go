@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+
+package client
+
+import (
+	"errors"
+	"os"
+)
+
+var errLockHeld = errors.New("locked")
+
+// lockFile does nothing, as files are not locked on this system.
+func lockFile(*os.File) error { return nil }
+
+func releaseFile(f *os.File) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(f.Name())
+}
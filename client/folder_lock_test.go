@@ -0,0 +1,40 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockGameFolder(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := LockGameFolder(dir, "test-game", DefaultFileModes)
+	require.NoError(t, err)
+	assert.FileExists(t, FolderLockPath(dir, "test-game"))
+
+	_, err = LockGameFolder(dir, "test-game", DefaultFileModes)
+	require.ErrorIs(t, err, ErrFolderLocked)
+	assert.Contains(t, err.Error(), fmt.Sprintf("(process %d)", os.Getpid()))
+
+	other, err := LockGameFolder(dir, "other-game", DefaultFileModes)
+	require.NoError(t, err, "other games are not locked")
+	require.NoError(t, other.Unlock())
+
+	require.NoError(t, lock.Unlock())
+	assert.NoFileExists(t, FolderLockPath(dir, "test-game"))
+	lock, err = LockGameFolder(dir, "test-game", DefaultFileModes)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+}
+
+func TestLockGameFolder_StaleFileDoesNotBlock(t *testing.T) {
+	dir := t.TempDir()
+	// A lock file left behind by a process that crashed is not locked anymore.
+	require.NoError(t, os.WriteFile(FolderLockPath(dir, "test-game"), []byte("999999\n"), 0o644))
+	lock, err := LockGameFolder(dir, "test-game", DefaultFileModes)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package client
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var errLockHeld = unix.EWOULDBLOCK
+
+func lockFile(f *os.File) error {
+	err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return errLockHeld
+	}
+	return err
+}
+
+// releaseFile removes the lock file while it is still locked, so a process that opened it meanwhile notices
+// that it is gone, and then unlocks it.
+func releaseFile(f *os.File) error {
+	removeErr := os.Remove(f.Name())
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return removeErr
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package client
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+var errLockHeld = windows.ERROR_LOCK_VIOLATION
+
+func lockFile(f *os.File) error {
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0, 1, 0, new(windows.Overlapped))
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLockHeld
+	}
+	return err
+}
+
+// releaseFile unlocks and closes the lock file before removing it, as open files cannot be removed. If another
+// process has it open by then, the file is left behind unlocked, which does not stop a later download.
+func releaseFile(f *os.File) error {
+	_ = windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, new(windows.Overlapped))
+	if err := f.Close(); err != nil {
+		return err
+	}
+	_ = os.Remove(f.Name())
+	return nil
+}
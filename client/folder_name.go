@@ -0,0 +1,166 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultFolderTemplate names the folder of a game after its title only.
+const DefaultFolderTemplate = "{title}"
+
+// Title styles of game folder names, see GameFolderNameStyle.
+const (
+	// TitleStyleSlug writes the title as lowercase ASCII words joined by dashes, like "the-witcher-3-wild-hunt".
+	TitleStyleSlug = "slug"
+	// TitleStyleRaw keeps the title as GOG shows it and only replaces the characters file systems do not allow,
+	// like "The Witcher 3 - Wild Hunt".
+	TitleStyleRaw = "raw"
+	// TitleStyleUnderscores is like TitleStyleSlug with underscores instead of dashes, like "the_witcher_3_wild_hunt",
+	// which is how lgogdownloader names game folders, see LgogName.
+	TitleStyleUnderscores = "underscores"
+)
+
+// TitleStyles are the supported title styles of game folder names.
+var TitleStyles = []string{TitleStyleSlug, TitleStyleRaw, TitleStyleUnderscores}
+
+// folderTokens matches the tokens of a folder name template.
+var folderTokens = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ValidateFolderTemplate checks that template only uses the {title} and {id} tokens and has at least one of them,
+// so every game gets its own folder.
+func ValidateFolderTemplate(template string) error {
+	for _, token := range folderTokens.FindAllString(template, -1) {
+		if token != "{title}" && token != "{id}" {
+			return fmt.Errorf("unknown token %s in folder name %q; use {title} and {id}", token, template)
+		}
+	}
+	if !strings.Contains(template, "{title}") && !strings.Contains(template, "{id}") {
+		return fmt.Errorf("folder name %q must contain {title} or {id}", template)
+	}
+	return nil
+}
+
+// ValidateTitleStyle checks that style is one of TitleStyles.
+func ValidateTitleStyle(style string) error {
+	for _, s := range TitleStyles {
+		if style == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown title style %q; use one of %s", style, strings.Join(TitleStyles, ", "))
+}
+
+// GameFolderName returns the name of the folder of the game with the given title and GOG product ID, made from
+// template by replacing {title} and {id} and sanitizing the result like SanitizePath. A game without a usable
+// title, like one whose data could not be fetched, is named after its ID. It returns an empty string only if
+// there is neither a title nor an ID.
+func GameFolderName(template, title string, id int) string {
+	return GameFolderNameStyle(template, title, id, TitleStyleSlug)
+}
+
+// GameFolderNameStyle is like GameFolderName, but writes the name in the given title style. With TitleStyleRaw,
+// the result is sanitized like RawFolderName instead of SanitizePath, and with TitleStyleUnderscores like LgogName.
+func GameFolderNameStyle(template, title string, id int, style string) string {
+	sanitize := SanitizePath
+	switch style {
+	case TitleStyleRaw:
+		sanitize = RawFolderName
+	case TitleStyleUnderscores:
+		sanitize = LgogName
+	}
+	if sanitize(title) == "" {
+		if id <= 0 {
+			return ""
+		}
+		if !strings.Contains(template, "{id}") {
+			// Otherwise all games without a title would share a folder.
+			template = "{id}"
+		}
+	}
+	return sanitize(strings.NewReplacer("{title}", title, "{id}", strconv.Itoa(id)).Replace(template))
+}
+
+// rawNameReplacer replaces the characters that Windows, macOS, or Linux do not allow in file names.
+var rawNameReplacer = strings.NewReplacer(
+	":", " - ",
+	"/", "-",
+	"\\", "-",
+	"|", "-",
+	"\"", "'",
+	"<", "",
+	">", "",
+	"?", "",
+	"*", "",
+)
+
+// RawFolderName returns name as a folder name that keeps its case, spaces, and letters of any script, with only
+// the characters that file systems do not allow replaced. Like SanitizePath, it returns an empty string if
+// nothing usable is left, and it cuts long names to 200 bytes.
+func RawFolderName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Join(strings.Fields(rawNameReplacer.Replace(name)), " ")
+	// Windows does not allow names that end with a dot or a space.
+	name = strings.TrimRight(name, ". ")
+	if strings.Trim(name, ".-' ") == "" {
+		return ""
+	}
+
+	const maxPathLength = 200
+	if len(name) > maxPathLength {
+		name = name[:maxPathLength]
+		for !utf8.ValidString(name) {
+			name = name[:len(name)-1]
+		}
+		name = strings.TrimRight(name, ". ")
+	}
+	return name
+}
+
+// rommPlatforms are the platform folders of the RomM layout.
+var rommPlatforms = []string{"windows", "mac", "linux"}
+
+// rommPlatform returns the RomM platform folder of a file stored in subDir when platformName was selected:
+// the platform in subDir, or else the selected platform. It returns an empty string for a file without a
+// platform, like an extra, when all platforms were selected.
+func rommPlatform(subDir, platformName string) string {
+	for _, part := range strings.Split(filepath.ToSlash(subDir), "/") {
+		for _, p := range rommPlatforms {
+			if strings.EqualFold(part, p) {
+				return p
+			}
+		}
+	}
+	if p := strings.ToLower(strings.TrimSpace(platformName)); p != "" && p != "all" {
+		return p
+	}
+	return ""
+}
+
+// GameDirs returns the folders under downloadPath that the files of a game stored in gameFolder can end up in.
+// That is the game folder itself, unless the RomM layout is used: then it is the game folder in each platform
+// folder that platformName selects, and, for all platforms, also the game folder itself for files without a
+// platform.
+func GameDirs(downloadPath, gameFolder string, rommLayout bool, platformName string) []string {
+	root := filepath.Join(downloadPath, gameFolder)
+	if !rommLayout {
+		return []string{root}
+	}
+	if p := rommPlatform("", platformName); p != "" {
+		return []string{filepath.Join(downloadPath, p, gameFolder)}
+	}
+	dirs := make([]string, 0, len(rommPlatforms)+1)
+	for _, p := range rommPlatforms {
+		dirs = append(dirs, filepath.Join(downloadPath, p, gameFolder))
+	}
+	return append(dirs, root)
+}
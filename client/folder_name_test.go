@@ -0,0 +1,65 @@
+package client
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameFolderName(t *testing.T) {
+	assert.Equal(t, "the-witcher-3", GameFolderName(DefaultFolderTemplate, "The Witcher 3", 1207664643))
+	assert.Equal(t, "the-witcher-3-1207664643", GameFolderName("{title}-{id}", "The Witcher 3", 1207664643))
+	assert.Equal(t, "1207664643", GameFolderName("{id}", "The Witcher 3", 1207664643))
+	assert.Equal(t, "gog-1-doom", GameFolderName("GOG {id} {title}", "DOOM", 1))
+	assert.Equal(t, "doom", GameFolderName("{title}", "DOOM", 0))
+
+	// Games without a usable title are named after their ID.
+	assert.Equal(t, "42", GameFolderName(DefaultFolderTemplate, "", 42))
+	assert.Equal(t, "42", GameFolderName("GOG {title}", "™", 42))
+	assert.Equal(t, "42", GameFolderName("{title}-{id}", " ", 42))
+	assert.Equal(t, "", GameFolderName(DefaultFolderTemplate, "", 0))
+}
+
+func TestGameFolderNameStyle_Raw(t *testing.T) {
+	assert.Equal(t, "The Witcher 3 - Wild Hunt", GameFolderNameStyle(DefaultFolderTemplate, "The Witcher 3: Wild Hunt", 1, TitleStyleRaw))
+	assert.Equal(t, "the_witcher_3_wild_hunt", GameFolderNameStyle(DefaultFolderTemplate, "The Witcher 3: Wild Hunt", 1, TitleStyleUnderscores))
+	assert.Equal(t, "the_witcher_3_1207664643", GameFolderNameStyle("{title}-{id}", "The Witcher 3", 1207664643, TitleStyleUnderscores))
+	assert.Equal(t, "Ведьмак 3 - 1207664643", GameFolderNameStyle("{title} - {id}", "Ведьмак 3", 1207664643, TitleStyleRaw))
+	assert.Equal(t, "ウィッチャー3", GameFolderNameStyle(DefaultFolderTemplate, "ウィッチャー3", 1, TitleStyleRaw))
+	assert.Equal(t, "AC-DC Live - 'Rock' Band", GameFolderNameStyle(DefaultFolderTemplate, "AC/DC Live: \"Rock\" Band?", 1, TitleStyleRaw))
+	assert.Equal(t, "Fallout™", GameFolderNameStyle(DefaultFolderTemplate, "  Fallout™... ", 1, TitleStyleRaw))
+	assert.Equal(t, "the-witcher-3-wild-hunt", GameFolderNameStyle(DefaultFolderTemplate, "The Witcher 3: Wild Hunt", 1, TitleStyleSlug))
+
+	// Games without a usable title are still named after their ID.
+	assert.Equal(t, "42", GameFolderNameStyle(DefaultFolderTemplate, "?*", 42, TitleStyleRaw))
+
+	long := RawFolderName(strings.Repeat("Ж", 150))
+	assert.LessOrEqual(t, len(long), 200)
+	assert.True(t, utf8.ValidString(long), "a long name is not cut inside a letter")
+}
+
+func TestValidateTitleStyle(t *testing.T) {
+	assert.NoError(t, ValidateTitleStyle(TitleStyleSlug))
+	assert.NoError(t, ValidateTitleStyle(TitleStyleRaw))
+	assert.Error(t, ValidateTitleStyle("ascii"))
+}
+
+func TestValidateFolderTemplate(t *testing.T) {
+	for _, template := range []string{"{title}", "{id}", "{title}-{id}", "gog_{id}_{title}"} {
+		assert.NoError(t, ValidateFolderTemplate(template), template)
+	}
+	for _, template := range []string{"", "games", "{name}-{id}", "{title}{platform}"} {
+		assert.Error(t, ValidateFolderTemplate(template), template)
+	}
+}
+
+func TestGameDirs(t *testing.T) {
+	j := filepath.Join
+	assert.Equal(t, []string{j("dl", "doom")}, GameDirs("dl", "doom", false, "all"))
+	assert.Equal(t, []string{j("dl", "linux", "doom")}, GameDirs("dl", "doom", true, "linux"))
+	assert.Equal(t, []string{j("dl", "windows", "doom"), j("dl", "mac", "doom"), j("dl", "linux", "doom"), j("dl", "doom")},
+		GameDirs("dl", "doom", true, "all"))
+}
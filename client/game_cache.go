@@ -0,0 +1,106 @@
+package client
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// DefaultGameCacheSize is how many parsed games the cache of ParseCachedGameData keeps, which is more than
+// most libraries have.
+const DefaultGameCacheSize = 2048
+
+// GameCache keeps the parsed catalogue data of the games that were parsed last, so that the features that read
+// the same game one after another, like the size estimate, the update check and the download, parse its JSON only
+// once. A game is parsed again when its data changes, as the cache is keyed by the game ID and the hash of the data.
+//
+// The games it returns share their slices with the cache, so they must not be changed in place; the functions
+// that filter a Game, like ChangedFiles, build new slices for it.
+type GameCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // of *gameCacheEntry, the most recently used first
+	entries map[int]*list.Element
+}
+
+type gameCacheEntry struct {
+	id   int
+	sum  [sha256.Size]byte
+	game Game
+}
+
+// NewGameCache returns a GameCache that keeps up to size games, evicting the least recently used one when it is
+// full. A size below 1 is taken as 1.
+func NewGameCache(size int) *GameCache {
+	if size < 1 {
+		size = 1
+	}
+	return &GameCache{size: size, order: list.New(), entries: make(map[int]*list.Element)}
+}
+
+// Parse returns data parsed like ParseGameData, from the cache if the game with the given ID was parsed with the
+// same data before. Data that fails to parse is not cached.
+func (c *GameCache) Parse(id int, data string) (Game, error) {
+	sum := sha256.Sum256([]byte(data))
+	c.mu.Lock()
+	if el, ok := c.entries[id]; ok {
+		entry := el.Value.(*gameCacheEntry)
+		if entry.sum == sum {
+			c.order.MoveToFront(el)
+			c.mu.Unlock()
+			return entry.game, nil
+		}
+		// The data of the game changed since it was parsed.
+		c.order.Remove(el)
+		delete(c.entries, id)
+	}
+	c.mu.Unlock()
+
+	game, err := ParseGameData(data)
+	if err != nil {
+		return Game{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[id]; ok {
+		// Another goroutine parsed the game at the same time.
+		c.order.Remove(el)
+	}
+	c.entries[id] = c.order.PushFront(&gameCacheEntry{id: id, sum: sum, game: game})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*gameCacheEntry).id)
+	}
+	return game, nil
+}
+
+// Len returns the number of games in the cache.
+func (c *GameCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Purge removes all games from the cache.
+func (c *GameCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[int]*list.Element)
+}
+
+// parsedGames is the cache shared by the callers of ParseCachedGameData.
+var parsedGames = NewGameCache(DefaultGameCacheSize)
+
+// ParseCachedGameData returns the catalogue data of the game with the given ID parsed like ParseGameData, from a
+// cache shared by the whole program. See GameCache for what may be done with the result.
+func ParseCachedGameData(id int, data string) (Game, error) {
+	return parsedGames.Parse(id, data)
+}
+
+// PurgeGameCache removes all games from the cache of ParseCachedGameData.
+func PurgeGameCache() {
+	parsedGames.Purge()
+}
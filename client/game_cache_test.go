@@ -0,0 +1,101 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cachedGameData(title, version string) string {
+	return `{"title":"` + title + `","downloads":[["English",{"windows":[{"manualUrl":"/w","name":"setup","size":"1 GB","version":"` +
+		version + `"}]}]],"extras":[{"name":"Manual","size":"1 MB","manualUrl":"/m"}],"dlcs":[]}`
+}
+
+func TestGameCache_Parse(t *testing.T) {
+	cache := NewGameCache(2)
+	first, err := cache.Parse(1, cachedGameData("One", "1.0"))
+	require.NoError(t, err)
+	again, err := cache.Parse(1, cachedGameData("One", "1.0"))
+	require.NoError(t, err)
+	assert.Same(t, &first.Downloads[0], &again.Downloads[0], "the same data is parsed once")
+
+	changed, err := cache.Parse(1, cachedGameData("One", "2.0"))
+	require.NoError(t, err)
+	assert.Equal(t, "2.0", *changed.Downloads[0].Platforms.Windows[0].Version, "changed data is parsed again")
+	assert.Equal(t, 1, cache.Len(), "a game has one entry")
+
+	_, err = cache.Parse(2, "{not json")
+	assert.Error(t, err)
+	assert.Equal(t, 1, cache.Len(), "data that fails to parse is not cached")
+
+	// Game 1 was used last, so game 2 is evicted for game 3.
+	_, err = cache.Parse(2, cachedGameData("Two", "1.0"))
+	require.NoError(t, err)
+	_, err = cache.Parse(1, cachedGameData("One", "2.0"))
+	require.NoError(t, err)
+	_, err = cache.Parse(3, cachedGameData("Three", "1.0"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, cache.Len())
+	assert.Contains(t, cache.entries, 1)
+	assert.NotContains(t, cache.entries, 2)
+
+	cache.Purge()
+	assert.Zero(t, cache.Len())
+}
+
+func TestGameCache_Concurrent(t *testing.T) {
+	cache := NewGameCache(8)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for id := 0; id < 16; id++ {
+				game, err := cache.Parse(id, cachedGameData(fmt.Sprint("Game ", id), "1.0"))
+				assert.NoError(t, err)
+				assert.Equal(t, fmt.Sprint("Game ", id), game.Title)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 8, cache.Len())
+}
+
+// libraryGameData returns the data of a game with the installers of several languages and platforms, DLCs and
+// extras, about the size of that of a large game in the catalogue.
+func libraryGameData(id int) string {
+	var downloads []string
+	for _, language := range []string{"English", "Deutsch", "français", "polski", "русский"} {
+		downloads = append(downloads, `["`+language+`",{"windows":[{"manualUrl":"/w","name":"setup","size":"10 GB","version":"1.0"},`+
+			`{"manualUrl":"/w2","name":"setup part 2","size":"4 GB","version":"1.0"}],`+
+			`"linux":[{"manualUrl":"/l","name":"installer","size":"10 GB","version":"1.0"}]}]`)
+	}
+	extras := strings.Repeat(`{"name":"Soundtrack","size":"500 MB","manualUrl":"/s"},`, 10)
+	return fmt.Sprintf(`{"title":"Game %d","downloads":[%s],"extras":[%s],"dlcs":[{"title":"DLC","downloads":[%s],"extras":[]}]}`,
+		id, strings.Join(downloads, ","), strings.TrimSuffix(extras, ","), strings.Join(downloads, ","))
+}
+
+// BenchmarkParseGameData and BenchmarkGameCache_Parse parse the data of the same game again and again, like the
+// library tab does for its size estimates and update checks.
+func BenchmarkParseGameData(b *testing.B) {
+	data := libraryGameData(1)
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseGameData(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGameCache_Parse(b *testing.B) {
+	data := libraryGameData(1)
+	cache := NewGameCache(DefaultGameCacheSize)
+	for i := 0; i < b.N; i++ {
+		if _, err := cache.Parse(1, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrFileNotFound means that no file of a game matched the requested name or index.
+var ErrFileNotFound = errors.New("no such file")
+
+// GameFile is one downloadable file of a game or one of its DLCs: an installer, a patch, or an extra.
+type GameFile struct {
+	Index     int    // position in the list returned by Game.Files, starting at 1
+	Component string // title of the game or the DLC
+	Language  string // empty for extras
+	Platform  string // windows, mac, or linux; empty for extras
+	Name      string
+	Size      string
+	ManualURL string
+	date      string
+	subDir    string
+	fileName  string
+	extra     bool
+	patch     bool
+}
+
+// Files returns every downloadable file of the game, then its extras, then the files and extras of each DLC,
+// in the order GOG lists them. The order is stable, so an Index can be used to pick a file later.
+// A file that GOG lists for several languages appears once per language.
+func (g Game) Files() []GameFile {
+	var files []GameFile
+	add := func(f GameFile) {
+		f.Index = len(files) + 1
+		files = append(files, f)
+	}
+	addDownloads := func(component, subDirPrefix string, downloads []Downloadable) {
+		for _, download := range downloads {
+			for _, p := range []struct {
+				name  string
+				files []PlatformFile
+			}{
+				{"windows", download.Platforms.Windows},
+				{"mac", download.Platforms.Mac},
+				{"linux", download.Platforms.Linux},
+			} {
+				for _, file := range p.files {
+					if file.ManualURL == nil || *file.ManualURL == "" {
+						continue
+					}
+					f := GameFile{
+						Component: component, Language: download.Language, Platform: p.name,
+						Name: file.Name, Size: file.Size, ManualURL: *file.ManualURL,
+						subDir: filepath.Join(subDirPrefix, p.name), fileName: file.Name, patch: IsPatchFile(file),
+					}
+					if file.Date != nil {
+						f.date = *file.Date
+					}
+					add(f)
+				}
+			}
+		}
+	}
+	addExtras := func(component, subDir string, extras []Extra) {
+		for _, extra := range extras {
+			if extra.ManualURL == "" {
+				continue
+			}
+			fileName := SanitizePath(extra.Name)
+			if ext := filepath.Ext(extra.ManualURL); ext != "" {
+				fileName += ext
+			}
+			add(GameFile{
+				Component: component, Name: extra.Name, Size: extra.Size, ManualURL: extra.ManualURL,
+				subDir: subDir, fileName: fileName, extra: true,
+			})
+		}
+	}
+
+	addDownloads(g.Title, "", g.Downloads)
+	addExtras(g.Title, "extras", g.Extras)
+	for _, dlc := range g.DLCs {
+		dlcSubDir := filepath.Join("dlcs", SanitizePath(dlc.Title))
+		addDownloads(dlc.Title, dlcSubDir, dlc.ParsedDownloads)
+		addExtras(dlc.Title, filepath.Join(dlcSubDir, "extras"), dlc.Extras)
+	}
+	return files
+}
+
+// HasFiles reports whether GOG lists a file of the game or one of its DLCs, an installer in any language or
+// for any platform or an extra. Pre-orders of games that are not released yet are owned but have none.
+func (g Game) HasFiles() bool {
+	listed := func(downloads []Downloadable, extras []Extra) bool {
+		for _, d := range downloads {
+			if len(d.Platforms.Windows)+len(d.Platforms.Mac)+len(d.Platforms.Linux) > 0 {
+				return true
+			}
+		}
+		return len(extras) > 0
+	}
+	if listed(g.Downloads, g.Extras) {
+		return true
+	}
+	for _, dlc := range g.DLCs {
+		if listed(dlc.ParsedDownloads, dlc.Extras) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindFile returns the file of the game whose name, or the last element of whose download link, is name,
+// ignoring case. A name that matches files of different links is ambiguous; the error lists their indexes.
+func (g Game) FindFile(name string) (GameFile, error) {
+	var matches []GameFile
+	seen := make(map[string]bool)
+	for _, f := range g.Files() {
+		if !strings.EqualFold(f.Name, name) && !strings.EqualFold(path.Base(f.ManualURL), name) {
+			continue
+		}
+		if !seen[f.ManualURL] {
+			seen[f.ManualURL] = true
+			matches = append(matches, f)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return GameFile{}, fmt.Errorf("%w: %q", ErrFileNotFound, name)
+	case 1:
+		return matches[0], nil
+	default:
+		indexes := make([]string, len(matches))
+		for i, f := range matches {
+			kind := f.Platform
+			if f.extra {
+				kind = "extra"
+			}
+			indexes[i] = fmt.Sprintf("%d (%s, %s)", f.Index, f.Component, kind)
+		}
+		return GameFile{}, fmt.Errorf("%q matches %d files; pick one by index: %s", name, len(matches), strings.Join(indexes, ", "))
+	}
+}
+
+// FileAt returns the file with the given index in Files.
+func (g Game) FileAt(index int) (GameFile, error) {
+	files := g.Files()
+	if index < 1 || index > len(files) {
+		return GameFile{}, fmt.Errorf("%w: index %d is not between 1 and %d", ErrFileNotFound, index, len(files))
+	}
+	return files[index-1], nil
+}
+
+// task returns the download task of the file.
+func (f GameFile) task(resume, flatten bool) downloadTask {
+	t := downloadTask{
+		url:      buildManualURL(f.ManualURL),
+		fileName: f.fileName,
+		subDir:   f.subDir,
+		resume:   resume,
+		flatten:  flatten,
+		date:     f.date,
+		extra:    f.extra,
+		patch:    f.patch,
+	}
+	if size, err := parseSizeString(f.Size); err == nil {
+		t.size = size
+	}
+	return t
+}
@@ -3,10 +3,12 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -17,6 +19,11 @@ import (
 
 var sizeRegexp = regexp.MustCompile(`(?i)^\s*([0-9]+(?:\.[0-9]+)?)\s*([a-zA-Z]+)?\s*$`)
 
+// ParseSize parses a size like "1.5 GB", "700MB", or "1024" (bytes). Units are powers of 1024.
+func ParseSize(sizeStr string) (int64, error) {
+	return parseSizeString(sizeStr)
+}
+
 func parseSizeString(sizeStr string) (int64, error) {
 	s := strings.TrimSpace(sizeStr)
 	if s == "" {
@@ -75,13 +82,22 @@ func parseSizeString(sizeStr string) (int64, error) {
 	return int64(v * mult), nil
 }
 
+// DefaultFetchTimeout is how long a request for the catalogue or the details of a game may take, including
+// reading the response, before it is given up and retried.
+const DefaultFetchTimeout = 30 * time.Second
+
 func FetchGameData(ctx context.Context, accessToken string, url string) (Game, string, error) {
+	return fetchGameData(ctx, nil, accessToken, url, DefaultFetchTimeout)
+}
+
+// fetchGameData fetches the details of a game from url with c, or with a client of its own if c is nil.
+func fetchGameData(ctx context.Context, c *http.Client, accessToken, url string, timeout time.Duration) (Game, string, error) {
 	req, err := createRequest(ctx, "GET", url, accessToken)
 	if err != nil {
 		return Game{}, "", err
 	}
 
-	resp, err := sendRequest(req)
+	resp, err := sendRequestWith(c, req, timeout)
 	if err != nil {
 		return Game{}, "", err
 	}
@@ -110,7 +126,7 @@ func FetchIdOfOwnedGames(ctx context.Context, accessToken string, apiURL string)
 		return nil, err
 	}
 
-	resp, err := sendRequest(req)
+	resp, err := sendRequest(req, DefaultFetchTimeout)
 	if err != nil {
 		return nil, err
 	}
@@ -138,47 +154,98 @@ func createRequest(ctx context.Context, method, url, accessToken string) (*http.
 	return req, nil
 }
 
-func sendRequest(req *http.Request) (*http.Response, error) {
-	client := &http.Client{Timeout: 30 * time.Second}
-	var resp *http.Response
-	var err error
+// sendRequest sends req, retrying it with a growing backoff after network and server errors. Every attempt,
+// including the reading of its response body, may take up to timeout. If every attempt fails, the error is a
+// *RetriesExhaustedError with the error of the last attempt.
+func sendRequest(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	return sendRequestWith(nil, req, timeout)
+}
+
+// sendRequestWith is sendRequest with the transport, cookies, and redirect policy of c, if c is not nil.
+func sendRequestWith(c *http.Client, req *http.Request, timeout time.Duration) (*http.Response, error) {
+	client := &http.Client{Timeout: timeout}
+	if c != nil {
+		withTimeout := *c
+		withTimeout.Timeout = timeout
+		client = &withTimeout
+	}
 
 	const maxRetries = 3
 	backoff := 1 * time.Second
 
+	var lastErr error
 	for i := 0; i < maxRetries; i++ {
-		resp, err = client.Do(req)
+		if i > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err() // cancelled while waiting, so trying again is pointless
+			}
+			backoff *= 2
+		}
+		resp, err := client.Do(req)
 		if err != nil {
+			if req.Context().Err() != nil {
+				return nil, err // cancelled, so trying again is pointless
+			}
 			log.Warn().Err(err).Int("attempt", i+1).Int("max_attempts", maxRetries).Msg("Request failed, retrying...")
-			time.Sleep(backoff)
-			backoff *= 2
+			lastErr = err
 			continue
 		}
 
 		if resp.StatusCode >= 500 {
 			log.Warn().Int("status", resp.StatusCode).Int("attempt", i+1).Int("max_attempts", maxRetries).Msg("Server error, retrying...")
 			closeResponseBody(resp)
-			time.Sleep(backoff)
-			backoff *= 2
+			lastErr = &HTTPStatusError{StatusCode: resp.StatusCode}
 			continue
 		}
-
-		break
+		return checkResponseStatus(resp)
 	}
 
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to send request after multiple retries")
-		return nil, err
+	err := &RetriesExhaustedError{Attempts: maxRetries, Err: lastErr}
+	log.Error().Err(err).Msg("Failed to send request after multiple retries")
+	return nil, err
+}
+
+// RetriesExhaustedError is returned by a request to GOG that failed on every attempt, with network errors or
+// server errors (5xx). Err is the error of the last attempt, like a *HTTPStatusError.
+type RetriesExhaustedError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	last := e.Err.Error()
+	var statusErr *HTTPStatusError
+	if errors.As(e.Err, &statusErr) {
+		last = fmt.Sprintf("HTTP %d", statusErr.StatusCode)
 	}
+	return fmt.Sprintf("gave up after %d attempts (last: %s)", e.Attempts, last)
+}
+
+func (e *RetriesExhaustedError) Unwrap() error { return e.Err }
 
+// checkResponseStatus returns resp if its status is 2xx, and closes it and returns a *HTTPStatusError otherwise.
+func checkResponseStatus(resp *http.Response) (*http.Response, error) {
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		log.Error().Int("status", resp.StatusCode).Msg("HTTP request failed with non-successful status")
 		closeResponseBody(resp)
-		return nil, fmt.Errorf("HTTP request failed with status %d", resp.StatusCode)
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode}
 	}
 	return resp, nil
 }
 
+// HTTPStatusError is returned for a request to GOG that was answered with a status other than 2xx.
+type HTTPStatusError struct {
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("HTTP request failed with status %d", e.StatusCode)
+}
+
 func readResponseBody(resp *http.Response) ([]byte, error) {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -243,6 +310,12 @@ func canonicalizeURL(u string) string {
 }
 
 func FetchAllOwnedGameIDs(ctx context.Context, accessToken, startURL string) ([]int, error) {
+	return fetchAllOwnedGameIDs(ctx, nil, accessToken, startURL, DefaultFetchTimeout)
+}
+
+// fetchAllOwnedGameIDs follows the pages of the owned games from startURL with c, or with a client of its own if
+// c is nil.
+func fetchAllOwnedGameIDs(ctx context.Context, c *http.Client, accessToken, startURL string, timeout time.Duration) ([]int, error) {
 	all := make([]int, 0, 128)
 	nextURL := canonicalizeURL(startURL)
 	seen := map[string]bool{}
@@ -258,7 +331,7 @@ func FetchAllOwnedGameIDs(ctx context.Context, accessToken, startURL string) ([]
 			return nil, err
 		}
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
-		resp, err := sendRequest(req)
+		resp, err := sendRequestWith(c, req, timeout)
 		if err != nil {
 			return nil, err
 		}
@@ -291,67 +364,170 @@ func parseOwnedGames(body []byte) ([]int, error) {
 	return response.Owned, nil
 }
 
+// StorageSizeBreakdown holds the estimated download size of a game split by content type.
+// DLC includes the installers and extras of all DLCs.
+type StorageSizeBreakdown struct {
+	Base   int64 `json:"base"`
+	Extras int64 `json:"extras"`
+	DLC    int64 `json:"dlc"`
+}
+
+// Total returns the sum of all parts of the breakdown.
+func (b StorageSizeBreakdown) Total() int64 {
+	return b.Base + b.Extras + b.DLC
+}
+
 func (g *Game) EstimateStorageSize(language, platformName string, extrasFlag, dlcFlag bool) (int64, error) {
-	var totalSizeBytes int64
+	breakdown, err := g.EstimateStorageSizeBreakdown(language, platformName, extrasFlag, dlcFlag)
+	if err != nil {
+		return 0, err
+	}
+	return breakdown.Total(), nil
+}
 
-	processFiles := func(files []PlatformFile) {
+// EstimateStorageSizeBreakdown estimates the download size for the given language and platform,
+// reporting base game files, extras, and DLCs separately. Passing AllLanguages sums the files of every language.
+func (g *Game) EstimateStorageSizeBreakdown(language, platformName string, extrasFlag, dlcFlag bool) (StorageSizeBreakdown, error) {
+	var breakdown StorageSizeBreakdown
+
+	sumFiles := func(files []PlatformFile) int64 {
+		var total int64
 		for _, file := range files {
 			if size, err := parseSizeString(file.Size); err == nil {
-				totalSizeBytes += size
+				total += size
 			}
 		}
+		return total
 	}
 
-	for _, download := range g.Downloads {
-		if !strings.EqualFold(download.Language, language) {
-			continue
-		}
-		platforms := map[string][]PlatformFile{
-			"windows": download.Platforms.Windows,
-			"mac":     download.Platforms.Mac,
-			"linux":   download.Platforms.Linux,
-		}
-		for name, files := range platforms {
-			if platformName == "all" || strings.EqualFold(platformName, name) {
-				processFiles(files)
+	sumDownloads := func(downloads []Downloadable) int64 {
+		var total int64
+		for _, download := range downloads {
+			if !strings.EqualFold(language, AllLanguages) && !LanguageMatches(download.Language, language) {
+				continue
+			}
+			platforms := map[string][]PlatformFile{
+				"windows": download.Platforms.Windows,
+				"mac":     download.Platforms.Mac,
+				"linux":   download.Platforms.Linux,
+			}
+			for name, files := range platforms {
+				if platformName == "all" || strings.EqualFold(platformName, name) {
+					total += sumFiles(files)
+				}
 			}
 		}
+		return total
 	}
 
-	if extrasFlag {
-		for _, extra := range g.Extras {
+	sumExtras := func(extras []Extra) int64 {
+		var total int64
+		for _, extra := range extras {
 			if size, err := parseSizeString(extra.Size); err == nil {
-				totalSizeBytes += size
+				total += size
 			}
 		}
+		return total
 	}
 
+	breakdown.Base = sumDownloads(g.Downloads)
+	if extrasFlag {
+		breakdown.Extras = sumExtras(g.Extras)
+	}
 	if dlcFlag {
 		for _, dlc := range g.DLCs {
-			for _, download := range dlc.ParsedDownloads {
-				if !strings.EqualFold(download.Language, language) {
+			breakdown.DLC += sumDownloads(dlc.ParsedDownloads)
+			if extrasFlag {
+				breakdown.DLC += sumExtras(dlc.Extras)
+			}
+		}
+	}
+
+	return breakdown, nil
+}
+
+// ExpectedFile describes one file that the catalogue lists for a game. Installer names are the
+// titles shown by GOG rather than file names, and sizes are rounded, so they can only be matched
+// approximately against files on disk.
+type ExpectedFile struct {
+	Name      string `json:"name"`
+	Component string `json:"component"`
+	Extra     bool   `json:"extra"`
+	Platform  string `json:"platform,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Version   string `json:"version,omitempty"`
+	FileName  string `json:"file_name,omitempty"`
+	Size      int64  `json:"size"`
+}
+
+// ExpectedFiles lists the files that a download with the same language, platform, extras, and DLC
+// options would fetch. Passing AllLanguages includes the files of every language.
+func (g *Game) ExpectedFiles(language, platformName string, extrasFlag, dlcFlag bool) []ExpectedFile {
+	var files []ExpectedFile
+
+	addDownloads := func(component string, downloads []Downloadable) {
+		for _, download := range downloads {
+			if !strings.EqualFold(language, AllLanguages) && !LanguageMatches(download.Language, language) {
+				continue
+			}
+			platforms := []struct {
+				name  string
+				files []PlatformFile
+			}{
+				{"windows", download.Platforms.Windows},
+				{"mac", download.Platforms.Mac},
+				{"linux", download.Platforms.Linux},
+			}
+			for _, p := range platforms {
+				if platformName != "all" && !strings.EqualFold(platformName, p.name) {
 					continue
 				}
-				platforms := map[string][]PlatformFile{
-					"windows": download.Platforms.Windows,
-					"mac":     download.Platforms.Mac,
-					"linux":   download.Platforms.Linux,
-				}
-				for name, files := range platforms {
-					if platformName == "all" || strings.EqualFold(platformName, name) {
-						processFiles(files)
+				for _, file := range p.files {
+					if file.ManualURL == nil || *file.ManualURL == "" {
+						continue
 					}
-				}
-			}
-			if extrasFlag {
-				for _, extra := range dlc.Extras {
-					if size, err := parseSizeString(extra.Size); err == nil {
-						totalSizeBytes += size
+					expected := ExpectedFile{
+						Name:      file.Name,
+						Component: component,
+						Platform:  p.name,
+						Language:  download.Language,
 					}
+					if file.Version != nil {
+						expected.Version = *file.Version
+					}
+					expected.Size, _ = parseSizeString(file.Size)
+					files = append(files, expected)
 				}
 			}
 		}
 	}
 
-	return totalSizeBytes, nil
+	addExtras := func(component string, extras []Extra) {
+		for _, extra := range extras {
+			if extra.ManualURL == "" {
+				continue
+			}
+			fileName := SanitizePath(extra.Name)
+			if ext := filepath.Ext(extra.ManualURL); ext != "" {
+				fileName += ext
+			}
+			expected := ExpectedFile{Name: extra.Name, Component: component, Extra: true, FileName: fileName}
+			expected.Size, _ = parseSizeString(extra.Size)
+			files = append(files, expected)
+		}
+	}
+
+	addDownloads(g.Title, g.Downloads)
+	if extrasFlag {
+		addExtras(g.Title, g.Extras)
+	}
+	if dlcFlag {
+		for _, dlc := range g.DLCs {
+			addDownloads(dlc.Title, dlc.ParsedDownloads)
+			if extrasFlag {
+				addExtras(dlc.Title, dlc.Extras)
+			}
+		}
+	}
+	return files
 }
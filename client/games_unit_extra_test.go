@@ -2,6 +2,8 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -32,3 +34,94 @@ func TestCreateRequest_AddsAuthHeader(t *testing.T) {
 		t.Fatalf("missing auth header")
 	}
 }
+
+func TestEstimateStorageSizeBreakdown_SplitsByContent(t *testing.T) {
+	g := Game{
+		Downloads: []Downloadable{
+			{Language: "English", Platforms: Platform{Windows: []PlatformFile{{Size: "1 GB"}}, Linux: []PlatformFile{{Size: "2 GB"}}}},
+			{Language: "Deutsch", Platforms: Platform{Windows: []PlatformFile{{Size: "4 GB"}}}},
+		},
+		Extras: []Extra{{Size: "10 MB"}},
+		DLCs: []DLC{{
+			ParsedDownloads: []Downloadable{{Language: "English", Platforms: Platform{Windows: []PlatformFile{{Size: "500 MB"}}}}},
+			Extras:          []Extra{{Size: "1 MB"}},
+		}},
+	}
+
+	b, err := g.EstimateStorageSizeBreakdown("english", "windows", true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Base != 1024*1024*1024 || b.Extras != 10*1024*1024 || b.DLC != 501*1024*1024 {
+		t.Fatalf("unexpected breakdown: %+v", b)
+	}
+	total, _ := g.EstimateStorageSize("english", "windows", true, true)
+	if total != b.Total() {
+		t.Fatalf("total %d does not match breakdown total %d", total, b.Total())
+	}
+
+	b, _ = g.EstimateStorageSizeBreakdown(AllLanguages, "windows", false, false)
+	if b.Base != 5*1024*1024*1024 {
+		t.Fatalf("all languages must be summed: %+v", b)
+	}
+
+	b, _ = g.EstimateStorageSizeBreakdown("english", "windows", false, false)
+	if b.Extras != 0 || b.DLC != 0 {
+		t.Fatalf("extras and DLCs must be excluded: %+v", b)
+	}
+}
+
+func TestExpectedFiles_FollowsDownloadFilters(t *testing.T) {
+	url := "/downloads/game/en1installer0"
+	version := "1.2 (gog-3)"
+	g := Game{
+		Title: "Game",
+		Downloads: []Downloadable{
+			{Language: "English", Platforms: Platform{
+				Windows: []PlatformFile{{ManualURL: &url, Name: "Game", Version: &version, Size: "1 GB"}},
+				Linux:   []PlatformFile{{ManualURL: &url, Name: "Game (Linux)", Size: "2 GB"}},
+			}},
+			{Language: "Deutsch", Platforms: Platform{Windows: []PlatformFile{{ManualURL: &url, Name: "Spiel", Size: "1 GB"}}}},
+		},
+		Extras: []Extra{{Name: "Official Manual", Size: "10 MB", ManualURL: "/downloads/game/manual.pdf"}},
+		DLCs: []DLC{{
+			Title:           "Expansion",
+			ParsedDownloads: []Downloadable{{Language: "English", Platforms: Platform{Windows: []PlatformFile{{ManualURL: &url, Name: "Expansion", Size: "500 MB"}}}}},
+		}},
+	}
+
+	files := g.ExpectedFiles("English", "windows", true, true)
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %+v", files)
+	}
+	if files[0].Name != "Game" || files[0].Version != version || files[0].Size != 1024*1024*1024 {
+		t.Fatalf("unexpected installer: %+v", files[0])
+	}
+	if !files[1].Extra || files[1].FileName != "official-manual.pdf" {
+		t.Fatalf("unexpected extra: %+v", files[1])
+	}
+	if files[2].Component != "Expansion" {
+		t.Fatalf("unexpected DLC file: %+v", files[2])
+	}
+
+	if files := g.ExpectedFiles(AllLanguages, "all", false, false); len(files) != 3 {
+		t.Fatalf("expected all base game installers, got %+v", files)
+	}
+}
+
+func TestRefreshFailureReason(t *testing.T) {
+	var parseErr error = &json.SyntaxError{}
+	cases := map[error]string{
+		&HTTPStatusError{StatusCode: http.StatusTooManyRequests}:     RefreshRateLimited,
+		&HTTPStatusError{StatusCode: http.StatusUnauthorized}:        RefreshAuthFailed,
+		&HTTPStatusError{StatusCode: http.StatusNotFound}:            RefreshNotFound,
+		&HTTPStatusError{StatusCode: http.StatusInternalServerError}: RefreshNetworkError,
+		parseErr:                 RefreshParseError,
+		errors.New("conn reset"): RefreshNetworkError,
+	}
+	for err, want := range cases {
+		if got := refreshFailureReason(err); got != want {
+			t.Errorf("refreshFailureReason(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
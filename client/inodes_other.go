@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package client
+
+// freeInodes reports false, as the number of free inodes is not read on this system.
+func freeInodes(string) (uint64, bool) {
+	return 0, false
+}
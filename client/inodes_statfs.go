@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package client
+
+import "syscall"
+
+// freeInodes returns the number of free inodes of the file system of path. It reports false if the file
+// system does not have a fixed number of inodes, like btrfs, or the number cannot be read.
+func freeInodes(path string) (uint64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil || st.Files == 0 {
+		return 0, false
+	}
+	return uint64(st.Ffree), true
+}
@@ -0,0 +1,165 @@
+package client
+
+import "strings"
+
+// AllLanguages selects the files of every available language instead of a single one.
+const AllLanguages = "all"
+
+// GameLanguages is a map of language codes to their full names as used by GOG.
+var GameLanguages = map[string]string{
+	"en":      "English",
+	"fr":      "Français",
+	"de":      "Deutsch",
+	"es":      "Español",
+	"es-MX":   "Español (AL)",
+	"it":      "Italiano",
+	"ru":      "Русский",
+	"pl":      "Polski",
+	"pt-BR":   "Português do Brasil",
+	"pt":      "Português",
+	"zh-Hans": "简体中文",
+	"zh-Hant": "繁體中文",
+	"ja":      "日本語",
+	"ko":      "한국어",
+	"cs":      "Čeština",
+	"hu":      "Magyar",
+	"nl":      "Nederlands",
+	"tr":      "Türkçe",
+	"uk":      "Українська",
+	"fi":      "Suomi",
+	"sv":      "Svenska",
+	"no":      "Norsk",
+	"da":      "Dansk",
+	"ar":      "العربية",
+	"th":      "ไทย",
+	"ro":      "Română",
+	"bg":      "Български",
+	"el":      "Ελληνικά",
+	"sk":      "Slovenčina",
+	"he":      "עברית",
+	"be":      "Беларуская",
+	"vi":      "Tiếng Việt",
+	"id":      "Bahasa Indonesia",
+	"ca":      "Català",
+	"et":      "Eesti",
+}
+
+// languageAliases maps other names of a language, such as its English name and the spellings
+// found in older GOG game data, to its language code.
+var languageAliases = map[string]string{
+	"english":                 "en",
+	"french":                  "fr",
+	"german":                  "de",
+	"spanish":                 "es",
+	"spanish (spain)":         "es",
+	"spanish (latin america)": "es-MX",
+	"latin american spanish":  "es-MX",
+	"español (latinoamérica)": "es-MX",
+	"italian":                 "it",
+	"russian":                 "ru",
+	"polish":                  "pl",
+	"portuguese (brazil)":     "pt-BR",
+	"brazilian portuguese":    "pt-BR",
+	"português (brasil)":      "pt-BR",
+	"portuguese":              "pt",
+	"portuguese (portugal)":   "pt",
+	"chinese":                 "zh-Hans",
+	"chinese (simplified)":    "zh-Hans",
+	"simplified chinese":      "zh-Hans",
+	"中文(简体)":                  "zh-Hans",
+	"chinese (traditional)":   "zh-Hant",
+	"traditional chinese":     "zh-Hant",
+	"中文(繁體)":                  "zh-Hant",
+	"japanese":                "ja",
+	"korean":                  "ko",
+	"czech":                   "cs",
+	"český":                   "cs",
+	"hungarian":               "hu",
+	"dutch":                   "nl",
+	"turkish":                 "tr",
+	"ukrainian":               "uk",
+	"finnish":                 "fi",
+	"swedish":                 "sv",
+	"norwegian":               "no",
+	"danish":                  "da",
+	"arabic":                  "ar",
+	"thai":                    "th",
+	"romanian":                "ro",
+	"bulgarian":               "bg",
+	"greek":                   "el",
+	"slovak":                  "sk",
+	"slovenský":               "sk",
+	"hebrew":                  "he",
+	"belarusian":              "be",
+	"vietnamese":              "vi",
+	"indonesian":              "id",
+	"catalan":                 "ca",
+	"estonian":                "et",
+}
+
+// languageIndex maps normalized codes, full names, and aliases to language codes.
+var languageIndex = buildLanguageIndex()
+
+func buildLanguageIndex() map[string]string {
+	index := make(map[string]string, len(GameLanguages)*2+len(languageAliases))
+	for alias, code := range languageAliases {
+		index[normalizeLanguageKey(alias)] = code
+	}
+	for code, name := range GameLanguages {
+		index[normalizeLanguageKey(code)] = code
+		index[normalizeLanguageKey(name)] = code
+	}
+	return index
+}
+
+// normalizeLanguageKey lowercases s, collapses inner whitespace, and treats '_' like '-' (as in "pt_BR").
+func normalizeLanguageKey(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.ToLower(strings.ReplaceAll(s, "_", "-"))
+}
+
+// LanguageCode returns the language code for a language code, full name, or alias such as
+// "pt-br", "Português do Brasil", or "Portuguese (Brazil)".
+func LanguageCode(language string) (string, bool) {
+	code, ok := languageIndex[normalizeLanguageKey(language)]
+	return code, ok
+}
+
+// NormalizeLanguage resolves a language code, full name, or alias to its canonical code and
+// the full name GOG uses for it in game data.
+func NormalizeLanguage(language string) (code, fullName string, ok bool) {
+	code, ok = LanguageCode(language)
+	if !ok {
+		return "", "", false
+	}
+	return code, GameLanguages[code], true
+}
+
+// LanguageFilter resolves a user-supplied language to the value used to select files for download
+// and size estimates: the full language name, or AllLanguages when every language is requested.
+func LanguageFilter(language string) (string, bool) {
+	if strings.EqualFold(strings.TrimSpace(language), AllLanguages) {
+		return AllLanguages, true
+	}
+	_, fullName, ok := NormalizeLanguage(language)
+	return fullName, ok
+}
+
+// LanguageMatches reports whether two language identifiers refer to the same language.
+// Identifiers that are not known languages are compared case-insensitively.
+func LanguageMatches(a, b string) bool {
+	codeA, okA := LanguageCode(a)
+	codeB, okB := LanguageCode(b)
+	if okA && okB {
+		return codeA == codeB
+	}
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// languageFolder returns the folder name used for a language when downloading all languages.
+func languageFolder(language string) string {
+	if code, ok := LanguageCode(language); ok {
+		return code
+	}
+	return SanitizePath(language)
+}
@@ -0,0 +1,60 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLanguage_AcceptsCodesNamesAndAliases(t *testing.T) {
+	for _, input := range []string{
+		"pt-BR",
+		"pt-br",
+		" PT_BR ",
+		"Português do Brasil",
+		"português  do brasil",
+		"Portuguese (Brazil)",
+		"  portuguese (BRAZIL) ",
+	} {
+		code, fullName, ok := client.NormalizeLanguage(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, "pt-BR", code, input)
+		assert.Equal(t, "Português do Brasil", fullName, input)
+	}
+
+	code, _, ok := client.NormalizeLanguage("English")
+	assert.True(t, ok)
+	assert.Equal(t, "en", code)
+
+	_, _, ok = client.NormalizeLanguage("Klingon")
+	assert.False(t, ok)
+}
+
+func TestLanguageCode_ReverseLookupCoversAllLanguages(t *testing.T) {
+	for code, name := range client.GameLanguages {
+		got, ok := client.LanguageCode(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, code, got, name)
+	}
+}
+
+func TestLanguageMatches(t *testing.T) {
+	assert.True(t, client.LanguageMatches("český", "Čeština"))
+	assert.True(t, client.LanguageMatches("ENGLISH", "en"))
+	assert.False(t, client.LanguageMatches("English", "Deutsch"))
+	assert.True(t, client.LanguageMatches("Klingon", "klingon"))
+}
+
+func TestLanguageFilter(t *testing.T) {
+	name, ok := client.LanguageFilter(" ALL ")
+	assert.True(t, ok)
+	assert.Equal(t, client.AllLanguages, name)
+
+	name, ok = client.LanguageFilter("de")
+	assert.True(t, ok)
+	assert.Equal(t, "Deutsch", name)
+
+	_, ok = client.LanguageFilter("xx")
+	assert.False(t, ok)
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Folder layouts of the files of a game inside its game folder, see WithLayout.
+const (
+	// LayoutGogg is Gogg's own layout, shaped by the flatten, flatten extras, and language folder options.
+	LayoutGogg = "gogg"
+	// LayoutLgog is the default layout of lgogdownloader: installers in the game folder, patches in "patches",
+	// extras in "extras", and the files of each DLC laid out the same way in "dlc/<dlc name>".
+	LayoutLgog = "lgog"
+)
+
+// Layouts are the supported folder layouts.
+var Layouts = []string{LayoutGogg, LayoutLgog}
+
+// ValidateLayout checks that layout is one of Layouts.
+func ValidateLayout(layout string) error {
+	for _, l := range Layouts {
+		if layout == l {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown layout %q; use one of %s", layout, strings.Join(Layouts, ", "))
+}
+
+// WithLayout makes DownloadGameFiles put the files into the game folder in layout, one of Layouts. With
+// LayoutLgog, the flatten options are ignored, while language folders are still used when the download asks
+// for them. The default is LayoutGogg.
+func WithLayout(layout string) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.layout = layout }
+}
+
+// LgogName returns name the way lgogdownloader names the folders of games and DLCs after GOG's game names: in
+// lower case ASCII, with words joined by underscores, like "the_witcher_3_wild_hunt".
+func LgogName(name string) string {
+	return strings.ReplaceAll(SanitizePath(name), "-", "_")
+}
+
+// lgogDir returns the folder, relative to the game folder, that lgogdownloader puts the file of t in.
+func (t downloadTask) lgogDir() string {
+	dir := ""
+	// The files of a DLC have the subfolder dlcs/<sanitized DLC title>/<platform or extras>.
+	if parts := strings.Split(filepath.ToSlash(t.subDir), "/"); len(parts) > 1 && parts[0] == "dlcs" {
+		dir = filepath.Join("dlc", strings.ReplaceAll(parts[1], "-", "_"))
+	}
+	switch {
+	case t.extra:
+		dir = filepath.Join(dir, "extras")
+	case t.patch:
+		dir = filepath.Join(dir, "patches")
+	}
+	return dir
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLayout(t *testing.T) {
+	for _, layout := range Layouts {
+		assert.NoError(t, ValidateLayout(layout))
+	}
+	err := ValidateLayout("gog")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gogg, lgog")
+}
+
+func TestLgogName(t *testing.T) {
+	assert.Equal(t, "the_witcher_3_wild_hunt", LgogName("The Witcher 3: Wild Hunt"))
+	assert.Equal(t, "baldurs_gate_ii", LgogName("Baldur's Gate II"))
+	assert.Equal(t, "", LgogName("  "))
+}
+
+func TestDownloadTask_LgogDir(t *testing.T) {
+	for _, tc := range []struct {
+		task downloadTask
+		want string
+	}{
+		{downloadTask{subDir: "windows"}, ""},
+		{downloadTask{subDir: "linux", patch: true}, "patches"},
+		{downloadTask{subDir: "extras", extra: true}, "extras"},
+		{downloadTask{subDir: filepath.Join("dlcs", "hearts-of-stone", "windows")}, filepath.Join("dlc", "hearts_of_stone")},
+		{downloadTask{subDir: filepath.Join("dlcs", "hearts-of-stone", "mac"), patch: true}, filepath.Join("dlc", "hearts_of_stone", "patches")},
+		{downloadTask{subDir: filepath.Join("dlcs", "hearts-of-stone", "extras"), extra: true}, filepath.Join("dlc", "hearts_of_stone", "extras")},
+	} {
+		assert.Equal(t, tc.want, tc.task.lgogDir(), tc.task.subDir)
+	}
+}
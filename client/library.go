@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Ways of linking a file of the library into a download, see WithLibrary.
+const (
+	LinkHard = "hardlink"
+	LinkSym  = "symlink"
+)
+
+// LinkModes are the supported ways of linking a file of the library into a download.
+var LinkModes = []string{LinkHard, LinkSym}
+
+// ValidateLinkMode checks that mode is one of LinkModes.
+func ValidateLinkMode(mode string) error {
+	for _, m := range LinkModes {
+		if mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown link mode %q; use one of %s", mode, strings.Join(LinkModes, ", "))
+}
+
+// LibraryIndex lists the files under a library root by name and size, so a download can link a file that is
+// already in the library instead of downloading it again. It is safe for concurrent use, and the files
+// downloaded with it are added to it, so the games of a batch can share their files.
+type LibraryIndex struct {
+	mu    sync.Mutex
+	files map[libraryKey][]string
+	count int
+}
+
+type libraryKey struct {
+	name string // lower case, since some file systems ignore case
+	size int64
+}
+
+// IndexLibrary walks root and returns the index of the regular files under it. Folders that cannot be read are
+// skipped with a warning.
+func IndexLibrary(root string) (*LibraryIndex, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	idx := &LibraryIndex{files: make(map[libraryKey][]string)}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Skipping a part of the library that cannot be read")
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fileInfo, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		idx.add(path, fileInfo.Size())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Len returns the number of files in the index.
+func (l *LibraryIndex) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.count
+}
+
+func (l *LibraryIndex) add(path string, size int64) {
+	key := libraryKey{name: strings.ToLower(filepath.Base(path)), size: size}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, p := range l.files[key] {
+		if p == path {
+			return
+		}
+	}
+	l.files[key] = append(l.files[key], path)
+	l.count++
+}
+
+// candidates returns the files of the index with one of names and the given size, other than target.
+func (l *LibraryIndex) candidates(target string, size int64, names ...string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var paths []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		for _, p := range l.files[libraryKey{name: strings.ToLower(name), size: size}] {
+			if !seen[p] && !samePath(p, target) {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+	return paths
+}
+
+// source returns a file of the library that is the same as the file of downloadURL, which has one of names and
+// the given size, or "" if there is none. A candidate must also match the checksum GOG publishes for the file,
+// if it publishes one.
+func (l *LibraryIndex) source(ctx context.Context, c *http.Client, accessToken, downloadURL, target string, size int64, names ...string) string {
+	for _, p := range l.candidates(target, size, names...) {
+		valid, err := verifyFileChecksum(ctx, c, accessToken, downloadURL, p)
+		if ctx.Err() != nil {
+			return ""
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("file", p).Msg("Could not check the library file against GOG's checksum; going by its name and size")
+		}
+		if valid {
+			return p
+		}
+		log.Info().Str("file", p).Msg("The library file has the same name and size but not the same checksum; not using it")
+	}
+	return ""
+}
+
+// linkFile makes target a link to source, replacing target. The link is made next to target first, so target
+// is left as it is if linking fails, like a hard link across volumes.
+func linkFile(source, target, mode string) error {
+	tmp := target + ".link"
+	_ = os.Remove(tmp)
+	var err error
+	if mode == LinkSym {
+		var abs string
+		if abs, err = filepath.Abs(source); err == nil {
+			err = os.Symlink(abs, tmp)
+		}
+	} else {
+		err = os.Link(source, tmp)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// samePath reports whether a and b name the same path once cleaned and made absolute.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// WithLibrary makes DownloadGameFiles look up each file in idx before downloading it, and link a file of the
+// library with the same name and size, and the checksum GOG publishes if there is one, into the download
+// instead, as a hard link or a symbolic link by mode, one of LinkModes. If the link cannot be made, like a hard
+// link to another volume, the file is downloaded. Linked files are reported with the FileLinked outcome, and
+// downloaded files are added to idx.
+func WithLibrary(idx *LibraryIndex, mode string) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.library, cfg.linkMode = idx, mode }
+}
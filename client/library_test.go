@@ -0,0 +1,107 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLinkMode(t *testing.T) {
+	for _, mode := range LinkModes {
+		assert.NoError(t, ValidateLinkMode(mode))
+	}
+	err := ValidateLinkMode("copy")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hardlink, symlink")
+}
+
+func TestIndexLibrary(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "Setup.exe"), []byte("12345"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "b", "setup.exe"), []byte("12345"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "manual.pdf"), []byte("123"), 0644))
+
+	idx, err := IndexLibrary(root)
+	require.NoError(t, err)
+	assert.Equal(t, 3, idx.Len())
+	assert.ElementsMatch(t, []string{filepath.Join(root, "a", "Setup.exe"), filepath.Join(root, "a", "b", "setup.exe")},
+		idx.candidates("", 5, "SETUP.EXE"))
+	assert.Equal(t, []string{filepath.Join(root, "a", "b", "setup.exe")}, idx.candidates(filepath.Join(root, "a", "Setup.exe"), 5, "setup.exe"),
+		"a file is not its own source")
+	assert.Empty(t, idx.candidates("", 4, "setup.exe"))
+
+	_, err = IndexLibrary(filepath.Join(root, "manual.pdf"))
+	assert.Error(t, err)
+	_, err = IndexLibrary(filepath.Join(root, "missing"))
+	assert.Error(t, err)
+}
+
+func TestDownloadGameFiles_LinksFromLibrary(t *testing.T) {
+	content := testContent(64 * 1024)
+	fs := newFileServer(t, content)
+	library := t.TempDir()
+	source := filepath.Join(library, "old", "setup_game_1.0.exe")
+	require.NoError(t, os.MkdirAll(filepath.Dir(source), 0755))
+	require.NoError(t, os.WriteFile(source, content, 0644))
+
+	for _, mode := range LinkModes {
+		t.Run(mode, func(t *testing.T) {
+			idx, err := IndexLibrary(library)
+			require.NoError(t, err)
+			dir := t.TempDir()
+			var progress bytes.Buffer
+			err = DownloadGameFiles(context.Background(), "tok", fs.game(), dir, "English", "windows", false, false, true, true, false, false, 1,
+				&progress, WithHTTPClient(fs.Client()), WithLibrary(idx, mode))
+			require.NoError(t, err)
+
+			target := filepath.Join(dir, "test-game", "setup_game_1.0.exe")
+			targetInfo, err := os.Lstat(target)
+			require.NoError(t, err)
+			if mode == LinkSym {
+				assert.Equal(t, os.ModeSymlink, targetInfo.Mode()&os.ModeSymlink)
+			} else {
+				sourceInfo, err := os.Stat(source)
+				require.NoError(t, err)
+				assert.True(t, os.SameFile(sourceInfo, targetInfo))
+			}
+			assert.Contains(t, progress.String(), `"outcome":"linked"`)
+		})
+	}
+	assert.Zero(t, fs.gets, "no file is downloaded")
+}
+
+func TestDownloadGameFiles_AddsDownloadsToLibrary(t *testing.T) {
+	fs := newFileServer(t, testContent(64*1024))
+	library := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(library, "setup_game_1.0.exe"), []byte("too short"), 0644))
+	idx, err := IndexLibrary(library)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, fs.download(t, dir, true, WithLibrary(idx, LinkHard)))
+	assert.Equal(t, 1, fs.gets, "a file of another size is downloaded")
+	assert.Equal(t, 2, idx.Len())
+
+	// A second game folder links the file downloaded into the first one.
+	other := t.TempDir()
+	var progress bytes.Buffer
+	err = DownloadGameFiles(context.Background(), "tok", fs.game(), other, "English", "windows", false, false, true, true, false, false, 1,
+		&progress, WithHTTPClient(fs.Client()), WithLibrary(idx, LinkHard))
+	require.NoError(t, err)
+	assert.Equal(t, 1, fs.gets)
+	for _, line := range strings.Split(strings.TrimSpace(progress.String()), "\n") {
+		var update ProgressUpdate
+		require.NoError(t, json.Unmarshal([]byte(line), &update))
+		if update.Type == "file_done" {
+			assert.Equal(t, FileLinked, update.Outcome)
+		}
+	}
+}
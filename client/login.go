@@ -8,34 +8,91 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os/exec"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/chromedp/chromedp"
+	"github.com/habedi/gogg/auth"
 	"github.com/habedi/gogg/db"
 	"github.com/rs/zerolog/log"
 )
 
-var GOGLoginURL = "https://auth.gog.com/auth?client_id=46899977096215655" +
-	"&redirect_uri=https%3A%2F%2Fembed.gog.com%2Fon_login_success%3Forigin%3Dclient" +
-	"&response_type=code&layout=client2"
-
+// GogClient logs in to GOG and refreshes and checks tokens. Its empty fields mean the defaults of GOG; the
+// environment is only read by NewGogClient.
 type GogClient struct {
 	TokenURL string
+	// OAuth is the OAuth client to log in as; its empty fields are taken from DefaultOAuthConfig.
+	OAuth OAuthConfig
+	// EmbedBase is the address of the embed API the token is checked with; empty means DefaultEmbedBase.
+	EmbedBase string
+	// HTTPClient sends the token requests; nil uses a client with a timeout of its own.
+	HTTPClient *http.Client
+	// Tokens stores the token of a login; nil stores it in the database of package db.
+	Tokens auth.TokenStorer
+}
+
+// NewGogClient returns a GogClient for GOG, with the embed API of GOGG_EMBED_BASE if it is set.
+func NewGogClient() *GogClient {
+	return &GogClient{TokenURL: DefaultTokenURL, OAuth: DefaultOAuthConfig(), EmbedBase: embedBase()}
+}
+
+// LoginURL returns the login page to open in a browser to login as the OAuth client of c.
+func (c *GogClient) LoginURL() string {
+	return c.OAuth.withDefaults().LoginURL()
+}
+
+func (c *GogClient) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Timeout: tokenRequestTimeout}
+}
+
+func (c *GogClient) embedBase() string {
+	if c.EmbedBase != "" {
+		return c.EmbedBase
+	}
+	return DefaultEmbedBase
+}
+
+func (c *GogClient) storeToken(token *db.Token) error {
+	if c.Tokens != nil {
+		return c.Tokens.UpsertTokenRecord(token)
+	}
+	return db.UpsertTokenRecord(token)
+}
+
+// tokenRequestTimeout bounds a token request even if the caller's context has no deadline.
+const tokenRequestTimeout = 30 * time.Second
+
+// postTokenForm posts a form to the token endpoint. The request is aborted when ctx is cancelled.
+func (c *GogClient) postTokenForm(ctx context.Context, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.httpClient().Do(req)
 }
 
 // PerformTokenRefresh performs a token refresh without explicit cancellation support.
 // Deprecated: prefer PerformTokenRefreshCtx for new code.
 func (c *GogClient) PerformTokenRefresh(refreshToken string) (accessToken string, newRefreshToken string, expiresIn int64, err error) {
+	return c.PerformTokenRefreshCtx(context.Background(), refreshToken)
+}
+
+// PerformTokenRefreshCtx exchanges a refresh token for a new access token. Cancelling ctx aborts the request.
+func (c *GogClient) PerformTokenRefreshCtx(ctx context.Context, refreshToken string) (accessToken string, newRefreshToken string, expiresIn int64, err error) {
+	oauth := c.OAuth.withDefaults()
 	query := url.Values{
-		"client_id":     {"46899977096215655"},
-		"client_secret": {"9d85c43b1482497dbbce61f6e4aa173a433796eeae2ca8c5f6129f2dc4de46d9"},
+		"client_id":     {oauth.ClientID},
+		"client_secret": {oauth.ClientSecret},
 		"grant_type":    {"refresh_token"},
 		"refresh_token": {refreshToken},
 	}
 
-	resp, err := http.PostForm(c.TokenURL, query)
+	resp, err := c.postTokenForm(ctx, query)
 	if err != nil {
 		return "", "", 0, fmt.Errorf("failed to post form for token refresh: %w", err)
 	}
@@ -68,12 +125,107 @@ func (c *GogClient) PerformTokenRefresh(refreshToken string) (accessToken string
 	return result.AccessToken, result.RefreshToken, result.ExpiresIn, nil
 }
 
-func (c *GogClient) Login(loginURL string, username string, password string, headless bool) error {
+// ValidateToken asks GOG whether accessToken is accepted by requesting the data of the logged-in user.
+// It returns auth.ErrTokenRejected if GOG refuses the token.
+func (c *GogClient) ValidateToken(ctx context.Context, accessToken string) error {
+	req, err := createRequest(ctx, http.MethodGet, c.embedBase()+"/userData.json", accessToken)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach GOG: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return auth.ErrTokenRejected
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d when checking the token", resp.StatusCode)
+	}
+	var user struct {
+		IsLoggedIn bool `json:"isLoggedIn"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return fmt.Errorf("failed to parse the user data: %w", err)
+	}
+	if !user.IsLoggedIn {
+		return auth.ErrTokenRejected
+	}
+	return nil
+}
+
+// LoginOption configures a call to Login.
+type LoginOption func(*loginConfig)
+
+// DefaultLoginPollInterval is how often the browser is checked for the redirect that completes a login.
+const DefaultLoginPollInterval = 500 * time.Millisecond
+
+// Default time to wait for the redirect after submitting the login form, and the extra time given once GOG
+// asks for two-step verification, a consent, or a reCAPTCHA in a browser window.
+const (
+	defaultHeadlessLoginTimeout = 30 * time.Second
+	defaultWindowLoginTimeout   = 4 * time.Minute
+	defaultInteractiveTimeout   = 5 * time.Minute
+)
+
+type loginConfig struct {
+	timeout            time.Duration
+	pollInterval       time.Duration
+	interactiveTimeout time.Duration
+	browserPath        string
+	profileDir         string
+}
+
+// WithLoginTimeout sets how long to wait for GOG to finish the login after the form is submitted.
+// A zero or negative value keeps the default, which is longer when the browser window is shown.
+func WithLoginTimeout(d time.Duration) LoginOption {
+	return func(cfg *loginConfig) { cfg.timeout = d }
+}
+
+// WithLoginPollInterval sets how often the browser is checked while waiting for the login to finish.
+// A zero or negative value keeps DefaultLoginPollInterval.
+func WithLoginPollInterval(d time.Duration) LoginOption {
+	return func(cfg *loginConfig) {
+		if d > 0 {
+			cfg.pollInterval = d
+		}
+	}
+}
+
+// WithBrowserPath sets the browser to use instead of searching for one. See findBrowser.
+func WithBrowserPath(path string) LoginOption {
+	return func(cfg *loginConfig) { cfg.browserPath = path }
+}
+
+// WithChromeProfile makes the browser use the user data directory dir, so the cookies of an existing profile,
+// like a remembered two-step verification, are used for the login.
+func WithChromeProfile(dir string) LoginOption {
+	return func(cfg *loginConfig) { cfg.profileDir = dir }
+}
+
+func newLoginConfig(headless bool, options []LoginOption) loginConfig {
+	cfg := loginConfig{pollInterval: DefaultLoginPollInterval, interactiveTimeout: defaultInteractiveTimeout}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	if cfg.timeout <= 0 {
+		cfg.timeout = defaultWindowLoginTimeout
+		if headless {
+			cfg.timeout = defaultHeadlessLoginTimeout
+		}
+	}
+	return cfg
+}
+
+func (c *GogClient) Login(loginURL string, username string, password string, headless bool, options ...LoginOption) error {
 	if username == "" || password == "" {
 		return fmt.Errorf("username and password cannot be empty")
 	}
 
-	ctx, cancel, err := createChromeContext(headless)
+	cfg := newLoginConfig(headless, options)
+	ctx, cancel, err := createChromeContext(headless, cfg)
 	if err != nil {
 		return err
 	}
@@ -81,7 +233,7 @@ func (c *GogClient) Login(loginURL string, username string, password string, hea
 
 	log.Info().Msg("Trying to login to GOG.com.")
 
-	finalURL, err := performLogin(ctx, loginURL, username, password, headless)
+	finalURL, err := performLogin(ctx, loginURL, username, password, cfg, headless)
 	if err != nil {
 		if headless {
 			log.Warn().Err(err).Msg("Headless login failed, retrying with window mode.")
@@ -89,18 +241,19 @@ func (c *GogClient) Login(loginURL string, username string, password string, hea
 
 			// Cancel the first headless context before creating a new one.
 			cancel()
+			headlessErr := err
 
 			var headedCtx context.Context
 			var headedCancel context.CancelFunc
-			headedCtx, headedCancel, err = createChromeContext(false)
+			headedCtx, headedCancel, err = createChromeContext(false, cfg)
 			if err != nil {
-				return fmt.Errorf("failed to create Chrome context: %w", err)
+				return fmt.Errorf("failed to create Chrome context: %w (headless attempt: %w)", err, headlessErr)
 			}
 			defer headedCancel() // Defer cancellation of the new headed context.
 
-			finalURL, err = performLogin(headedCtx, loginURL, username, password, false)
+			finalURL, err = performLogin(headedCtx, loginURL, username, password, newLoginConfig(false, options), false)
 			if err != nil {
-				return fmt.Errorf("failed to login: %w", err)
+				return fmt.Errorf("failed to login: %w (headless attempt: %w)", err, headlessErr)
 			}
 		} else {
 			return fmt.Errorf("failed to login: %w", err)
@@ -121,22 +274,38 @@ func (c *GogClient) Login(loginURL string, username string, password string, hea
 	log.Info().Msgf("Refresh token: %s", refreshToken[:10])
 	log.Info().Msgf("Expires at: %s", expiresAt)
 
-	return db.UpsertTokenRecord(&db.Token{AccessToken: token, RefreshToken: refreshToken, ExpiresAt: expiresAt})
+	return c.storeToken(&db.Token{AccessToken: token, RefreshToken: refreshToken, ExpiresAt: expiresAt})
 }
 
-func createChromeContext(headless bool) (context.Context, context.CancelFunc, error) {
-	var execPath string
-	// Search for browsers in order of preference
-	browserExecutables := []string{"google-chrome", "Google Chrome", "chromium", "Chromium", "chrome", "msedge", "Microsoft Edge"}
-	for _, browser := range browserExecutables {
-		if p, err := exec.LookPath(browser); err == nil {
-			execPath = p
-			break
+// LoginWithCode completes a login that was done in another browser. input is the URL GOG redirected to after the
+// login, which contains the authorization code, or the code itself.
+func (c *GogClient) LoginWithCode(input string) error {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return errors.New("the redirect URL or authorization code cannot be empty")
+	}
+	code := input
+	if strings.Contains(input, "code=") {
+		var err error
+		if code, err = extractAuthCode(input); err != nil {
+			return err
 		}
 	}
 
-	if execPath == "" {
-		return nil, nil, fmt.Errorf("no Chrome, Chromium, or Edge executable found in PATH")
+	token, refreshToken, expiresAt, err := c.exchangeCodeForToken(code)
+	if err != nil {
+		return fmt.Errorf("failed to exchange authorization code for token: %w", err)
+	}
+	if token == "" || refreshToken == "" {
+		return errors.New("GOG did not return a token for the authorization code; it may have expired or been used already")
+	}
+	return c.storeToken(&db.Token{AccessToken: token, RefreshToken: refreshToken, ExpiresAt: expiresAt})
+}
+
+func createChromeContext(headless bool, cfg loginConfig) (context.Context, context.CancelFunc, error) {
+	execPath, err := findBrowser(cfg.browserPath)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
@@ -147,6 +316,16 @@ func createChromeContext(headless bool) (context.Context, context.CancelFunc, er
 	if headless {
 		opts = append(opts, chromedp.Flag("disable-gpu", true))
 	}
+	if cfg.profileDir != "" {
+		info, err := os.Stat(cfg.profileDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid Chrome profile directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, nil, fmt.Errorf("invalid Chrome profile directory: %s is not a directory", cfg.profileDir)
+		}
+		opts = append(opts, chromedp.UserDataDir(cfg.profileDir))
+	}
 
 	allocatorCtx, cancelAllocator := chromedp.NewExecAllocator(context.Background(), opts...)
 	ctx, cancelContext := chromedp.NewContext(allocatorCtx, chromedp.WithLogf(log.Info().Msgf))
@@ -157,41 +336,16 @@ func createChromeContext(headless bool) (context.Context, context.CancelFunc, er
 	}, nil
 }
 
-func performLogin(ctx context.Context, loginURL string, username string, password string,
+func performLogin(ctx context.Context, loginURL string, username string, password string, cfg loginConfig,
 	headlessMode bool,
 ) (string, error) {
-	var timeoutCtx context.Context
-	var cancel context.CancelFunc
-	var finalURL string
-
-	if headlessMode {
-		timeoutCtx, cancel = context.WithTimeout(ctx, 30*time.Second)
-	} else {
-		timeoutCtx, cancel = context.WithTimeout(ctx, 4*time.Minute)
-	}
+	formCtx, cancel := context.WithTimeout(ctx, cfg.timeout)
 	defer cancel()
 
-	err := chromedp.Run(timeoutCtx,
-		chromedp.Navigate(loginURL),
-		chromedp.WaitVisible(`#login_username`, chromedp.ByID),
-		chromedp.SendKeys(`#login_username`, username, chromedp.ByID),
-		chromedp.SendKeys(`#login_password`, password, chromedp.ByID),
-		chromedp.Click(`#login_login`, chromedp.ByID),
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			for {
-				var currentURL string
-				if err := chromedp.Location(&currentURL).Do(ctx); err != nil {
-					return err
-				}
-				if strings.Contains(currentURL, "on_login_success") && strings.Contains(currentURL, "code=") {
-					finalURL = currentURL
-					return nil
-				}
-				time.Sleep(500 * time.Millisecond)
-			}
-		}),
-	)
-	return finalURL, err
+	if err := fillLoginForm(formCtx, chromedpBrowser{}, loginURL, username, password); err != nil {
+		return "", err
+	}
+	return waitForLoginRedirect(ctx, cfg, headlessMode, probeLoginPage)
 }
 
 func extractAuthCode(authURL string) (string, error) {
@@ -209,15 +363,16 @@ func extractAuthCode(authURL string) (string, error) {
 }
 
 func (c *GogClient) exchangeCodeForToken(code string) (string, string, string, error) {
+	oauth := c.OAuth.withDefaults()
 	query := url.Values{
-		"client_id":     {"46899977096215655"},
-		"client_secret": {"9d85c43b1482497dbbce61f6e4aa173a433796eeae2ca8c5f6129f2dc4de46d9"},
+		"client_id":     {oauth.ClientID},
+		"client_secret": {oauth.ClientSecret},
 		"grant_type":    {"authorization_code"},
 		"code":          {code},
-		"redirect_uri":  {"https://embed.gog.com/on_login_success?origin=client"},
+		"redirect_uri":  {oauth.RedirectURI},
 	}
 
-	resp, err := http.PostForm(c.TokenURL, query)
+	resp, err := c.postTokenForm(context.Background(), query)
 	if err != nil {
 		return "", "", "", fmt.Errorf("failed to exchange code for token: %w", err)
 	}
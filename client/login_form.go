@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ErrLoginFormNotFound means that a field of the GOG login form could not be found, most likely because GOG
+// changed the page.
+var ErrLoginFormNotFound = errors.New("could not find the login form on the GOG page")
+
+// loginSelectors holds the CSS selectors of the login form fields, in order of preference. The first one that is
+// visible on the page is used, so a change of the GOG page only needs a new entry here.
+var loginSelectors = struct {
+	username []string
+	password []string
+	submit   []string
+}{
+	username: []string{"#login_username", `input[name="login[username]"]`, `input[type="email"]`},
+	password: []string{"#login_password", `input[name="login[password]"]`, `input[type="password"]`},
+	submit:   []string{"#login_login", "#login_button > button", `form button[type="submit"]`},
+}
+
+// selectorPollInterval is how often the page is checked for a field of the login form.
+const selectorPollInterval = 200 * time.Millisecond
+
+// loginBrowser is the part of a browser that is needed to fill in the login form.
+type loginBrowser interface {
+	Navigate(ctx context.Context, url string) error
+	Visible(ctx context.Context, selector string) (bool, error)
+	SendKeys(ctx context.Context, selector, text string) error
+	Click(ctx context.Context, selector string) error
+}
+
+// chromedpBrowser is a loginBrowser that drives the browser of a chromedp context.
+type chromedpBrowser struct{}
+
+func (chromedpBrowser) Navigate(ctx context.Context, url string) error {
+	return chromedp.Run(ctx, chromedp.Navigate(url))
+}
+
+func (chromedpBrowser) Visible(ctx context.Context, selector string) (bool, error) {
+	quoted, err := json.Marshal(selector)
+	if err != nil {
+		return false, err
+	}
+	script := fmt.Sprintf(`(() => { const el = document.querySelector(%s);
+		return !!el && el.getClientRects().length > 0 && getComputedStyle(el).visibility !== 'hidden'; })()`, quoted)
+	var visible bool
+	err = chromedp.Run(ctx, chromedp.Evaluate(script, &visible))
+	return visible, err
+}
+
+func (chromedpBrowser) SendKeys(ctx context.Context, selector, text string) error {
+	return chromedp.Run(ctx, chromedp.SendKeys(selector, text, chromedp.ByQuery))
+}
+
+func (chromedpBrowser) Click(ctx context.Context, selector string) error {
+	return chromedp.Run(ctx, chromedp.Click(selector, chromedp.ByQuery))
+}
+
+// fillLoginForm opens the login page, fills in the credentials, and submits the form. It fails with
+// ErrLoginFormNotFound if a field does not show up before ctx is done.
+func fillLoginForm(ctx context.Context, b loginBrowser, loginURL, username, password string) error {
+	if err := b.Navigate(ctx, loginURL); err != nil {
+		return err
+	}
+	usernameSel, err := findSelector(ctx, b, "username", loginSelectors.username)
+	if err != nil {
+		return err
+	}
+	passwordSel, err := findSelector(ctx, b, "password", loginSelectors.password)
+	if err != nil {
+		return err
+	}
+	submitSel, err := findSelector(ctx, b, "login button", loginSelectors.submit)
+	if err != nil {
+		return err
+	}
+	if err := b.SendKeys(ctx, usernameSel, username); err != nil {
+		return err
+	}
+	if err := b.SendKeys(ctx, passwordSel, password); err != nil {
+		return err
+	}
+	return b.Click(ctx, submitSel)
+}
+
+// findSelector returns the first of candidates that is visible on the page, waiting for one to show up until
+// ctx is done.
+func findSelector(ctx context.Context, b loginBrowser, field string, candidates []string) (string, error) {
+	for {
+		for _, sel := range candidates {
+			visible, err := b.Visible(ctx, sel)
+			if err != nil && ctx.Err() == nil {
+				return "", err
+			}
+			if visible {
+				return sel, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("%w: no %s field matched %q before the timeout; "+
+				"as a workaround, login with 'gogg login --manual'", ErrLoginFormNotFound, field, candidates)
+		case <-time.After(selectorPollInterval):
+		}
+	}
+}
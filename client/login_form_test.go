@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLoginBrowser is a page with the given visible selectors that records what is typed and clicked.
+type fakeLoginBrowser struct {
+	visible  map[string]bool
+	keys     map[string]string
+	clicked  []string
+	navigate string
+}
+
+func newFakeLoginBrowser(selectors ...string) *fakeLoginBrowser {
+	b := &fakeLoginBrowser{visible: make(map[string]bool), keys: make(map[string]string)}
+	for _, sel := range selectors {
+		b.visible[sel] = true
+	}
+	return b
+}
+
+func (b *fakeLoginBrowser) Navigate(ctx context.Context, url string) error {
+	b.navigate = url
+	return nil
+}
+
+func (b *fakeLoginBrowser) Visible(ctx context.Context, selector string) (bool, error) {
+	return b.visible[selector], nil
+}
+
+func (b *fakeLoginBrowser) SendKeys(ctx context.Context, selector, text string) error {
+	b.keys[selector] = text
+	return nil
+}
+
+func (b *fakeLoginBrowser) Click(ctx context.Context, selector string) error {
+	b.clicked = append(b.clicked, selector)
+	return nil
+}
+
+func TestFillLoginForm_UsesPreferredSelectors(t *testing.T) {
+	b := newFakeLoginBrowser("#login_username", "#login_password", "#login_login", `input[type="password"]`)
+	require.NoError(t, fillLoginForm(context.Background(), b, "https://login.example", "user", "secret"))
+
+	assert.Equal(t, "https://login.example", b.navigate)
+	assert.Equal(t, map[string]string{"#login_username": "user", "#login_password": "secret"}, b.keys)
+	assert.Equal(t, []string{"#login_login"}, b.clicked)
+}
+
+func TestFillLoginForm_FallsBackToOtherSelectors(t *testing.T) {
+	b := newFakeLoginBrowser(`input[type="email"]`, `input[name="login[password]"]`, "#login_button > button")
+	require.NoError(t, fillLoginForm(context.Background(), b, "https://login.example", "user", "secret"))
+
+	assert.Equal(t, map[string]string{`input[type="email"]`: "user", `input[name="login[password]"]`: "secret"}, b.keys)
+	assert.Equal(t, []string{"#login_button > button"}, b.clicked)
+}
+
+func TestFillLoginForm_MissingFieldTimesOut(t *testing.T) {
+	b := newFakeLoginBrowser("#login_username", "#login_password")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := fillLoginForm(ctx, b, "https://login.example", "user", "secret")
+	require.ErrorIs(t, err, ErrLoginFormNotFound)
+	assert.Contains(t, err.Error(), "login button")
+	assert.Contains(t, err.Error(), "--manual")
+	assert.Empty(t, b.keys, "nothing is typed before every field is found")
+	assert.Empty(t, b.clicked)
+}
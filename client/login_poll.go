@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrLoginRecaptcha means that GOG asked to solve a reCAPTCHA, which cannot be done without a browser window.
+	ErrLoginRecaptcha = errors.New("GOG asked to solve a reCAPTCHA; login again with --headless=false and solve it in the browser window")
+	// ErrLoginNeedsInteraction means that GOG asked for two-step verification or a consent in a headless browser.
+	ErrLoginNeedsInteraction = errors.New("GOG asked for two-step verification or a consent, which needs the browser window")
+)
+
+// maxLoginPollBackoff caps how much the poll interval grows while the browser cannot be queried.
+const maxLoginPollBackoff = 8
+
+// loginPage is what the browser shows while a login is in progress.
+type loginPage int
+
+const (
+	loginPageWaiting loginPage = iota
+	loginPageSuccess
+	loginPageTwoFactor
+	loginPageConsent
+	loginPageRecaptcha
+)
+
+func (p loginPage) String() string {
+	switch p {
+	case loginPageSuccess:
+		return "login success"
+	case loginPageTwoFactor:
+		return "two-step verification"
+	case loginPageConsent:
+		return "consent"
+	case loginPageRecaptcha:
+		return "reCAPTCHA"
+	default:
+		return "login form"
+	}
+}
+
+// pageMarkers are the elements found on the current page by probeLoginPage.
+type pageMarkers struct {
+	Recaptcha bool `json:"recaptcha"`
+	TwoFactor bool `json:"twoFactor"`
+	Consent   bool `json:"consent"`
+}
+
+// pageMarkersScript looks for a visible reCAPTCHA challenge (the badge of an invisible reCAPTCHA does not count),
+// the code fields of two-step verification, and a consent form.
+const pageMarkersScript = `(() => {
+	const visible = (el) => { const r = el.getBoundingClientRect(); return r.width > 0 && r.height > 0 &&
+		getComputedStyle(el).visibility !== 'hidden'; };
+	const frames = [...document.querySelectorAll('iframe[src*="recaptcha"]')]
+		.filter((f) => !f.closest('.grecaptcha-badge') && visible(f));
+	return {
+		recaptcha: frames.length > 0,
+		twoFactor: !!document.querySelector('[id^="second_step_authentication"]'),
+		consent: !!document.querySelector('form[name="consent"], #consent_form'),
+	};
+})()`
+
+// classifyLoginPage tells which step of the login the browser is at from its URL and the markers on the page.
+func classifyLoginPage(currentURL string, markers pageMarkers) loginPage {
+	switch {
+	case strings.Contains(currentURL, "on_login_success") && strings.Contains(currentURL, "code="):
+		return loginPageSuccess
+	case markers.Recaptcha:
+		return loginPageRecaptcha
+	case markers.TwoFactor || strings.Contains(currentURL, "two_step"):
+		return loginPageTwoFactor
+	case markers.Consent || strings.Contains(currentURL, "consent"):
+		return loginPageConsent
+	default:
+		return loginPageWaiting
+	}
+}
+
+// loginProbe reports the URL of the browser and the step of the login it is at.
+type loginProbe func(ctx context.Context) (string, loginPage, error)
+
+func probeLoginPage(ctx context.Context) (string, loginPage, error) {
+	var currentURL string
+	var markers pageMarkers
+	err := chromedp.Run(ctx,
+		chromedp.Location(&currentURL),
+		chromedp.Evaluate(pageMarkersScript, &markers),
+	)
+	if err != nil {
+		return "", loginPageWaiting, err
+	}
+	return currentURL, classifyLoginPage(currentURL, markers), nil
+}
+
+// waitForLoginRedirect polls the browser until GOG redirects to the page with the authorization code and returns
+// its URL. When GOG asks for two-step verification, a consent, or a reCAPTCHA, a headless login fails right away,
+// and a login with a browser window gets more time for the user to finish that step.
+// Failed probes are retried with a growing interval until the deadline.
+func waitForLoginRedirect(ctx context.Context, cfg loginConfig, headless bool, probe loginProbe) (string, error) {
+	deadline := time.Now().Add(cfg.timeout)
+	seen := make(map[loginPage]bool)
+	last := loginPageWaiting
+	backoff := 1
+
+	for {
+		currentURL, page, err := probe(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			log.Debug().Err(err).Msg("Failed to check the login page; retrying.")
+			backoff = min(backoff*2, maxLoginPollBackoff)
+		} else {
+			backoff = 1
+			last = page
+			switch page {
+			case loginPageSuccess:
+				return currentURL, nil
+			case loginPageRecaptcha, loginPageTwoFactor, loginPageConsent:
+				if headless {
+					if page == loginPageRecaptcha {
+						return "", ErrLoginRecaptcha
+					}
+					return "", fmt.Errorf("%w (%s)", ErrLoginNeedsInteraction, page)
+				}
+				if !seen[page] {
+					seen[page] = true
+					log.Info().Msgf("GOG asked for %s; waiting for it to be completed in the browser window.", page)
+					fmt.Printf("GOG asked for %s. Please complete it in the browser window.\n", page)
+					deadline = later(deadline, time.Now().Add(cfg.interactiveTimeout))
+				}
+			}
+		}
+
+		if !time.Now().Before(deadline) {
+			return "", fmt.Errorf("timed out waiting for GOG to finish the login (last page: %s)", last)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(cfg.pollInterval * time.Duration(backoff)):
+		}
+	}
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
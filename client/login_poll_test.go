@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const successURL = "https://embed.gog.com/on_login_success?origin=client&code=abc"
+
+type probeStep struct {
+	url  string
+	page loginPage
+	err  error
+}
+
+// scriptedProbe returns the steps in order and repeats the last one.
+func scriptedProbe(steps ...probeStep) (loginProbe, *int) {
+	calls := 0
+	return func(ctx context.Context) (string, loginPage, error) {
+		step := steps[min(calls, len(steps)-1)]
+		calls++
+		return step.url, step.page, step.err
+	}, &calls
+}
+
+func testLoginConfig(timeout time.Duration) loginConfig {
+	return loginConfig{timeout: timeout, pollInterval: time.Millisecond, interactiveTimeout: time.Second}
+}
+
+func TestClassifyLoginPage(t *testing.T) {
+	assert.Equal(t, loginPageSuccess, classifyLoginPage(successURL, pageMarkers{Recaptcha: true}))
+	assert.Equal(t, loginPageRecaptcha, classifyLoginPage("https://login.gog.com/auth", pageMarkers{Recaptcha: true}))
+	assert.Equal(t, loginPageTwoFactor, classifyLoginPage("https://login.gog.com/login/two_step", pageMarkers{}))
+	assert.Equal(t, loginPageTwoFactor, classifyLoginPage("https://login.gog.com/auth", pageMarkers{TwoFactor: true}))
+	assert.Equal(t, loginPageConsent, classifyLoginPage("https://login.gog.com/auth", pageMarkers{Consent: true}))
+	assert.Equal(t, loginPageWaiting, classifyLoginPage("https://embed.gog.com/on_login_success?origin=client", pageMarkers{}))
+}
+
+func TestWaitForLoginRedirect_RetriesProbeErrors(t *testing.T) {
+	probe, calls := scriptedProbe(
+		probeStep{err: errors.New("navigation in progress")},
+		probeStep{page: loginPageWaiting},
+		probeStep{url: successURL, page: loginPageSuccess},
+	)
+	got, err := waitForLoginRedirect(context.Background(), testLoginConfig(time.Second), true, probe)
+	require.NoError(t, err)
+	assert.Equal(t, successURL, got)
+	assert.Equal(t, 3, *calls)
+}
+
+func TestWaitForLoginRedirect_TimesOut(t *testing.T) {
+	probe, _ := scriptedProbe(probeStep{page: loginPageWaiting})
+	_, err := waitForLoginRedirect(context.Background(), testLoginConfig(20*time.Millisecond), true, probe)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}
+
+func TestWaitForLoginRedirect_HeadlessFailsOnInteractiveSteps(t *testing.T) {
+	probe, _ := scriptedProbe(probeStep{page: loginPageRecaptcha})
+	_, err := waitForLoginRedirect(context.Background(), testLoginConfig(time.Second), true, probe)
+	assert.ErrorIs(t, err, ErrLoginRecaptcha)
+
+	probe, _ = scriptedProbe(probeStep{page: loginPageTwoFactor})
+	_, err = waitForLoginRedirect(context.Background(), testLoginConfig(time.Second), true, probe)
+	assert.ErrorIs(t, err, ErrLoginNeedsInteraction)
+}
+
+func TestWaitForLoginRedirect_WindowWaitsForTwoFactor(t *testing.T) {
+	// The two-step page shows up after the timeout would have run out, so the login only
+	// succeeds because the deadline is extended.
+	start := time.Now()
+	probe := func(ctx context.Context) (string, loginPage, error) {
+		switch elapsed := time.Since(start); {
+		case elapsed < 10*time.Millisecond:
+			return "", loginPageTwoFactor, nil
+		case elapsed < 60*time.Millisecond:
+			return "", loginPageWaiting, nil
+		default:
+			return successURL, loginPageSuccess, nil
+		}
+	}
+	got, err := waitForLoginRedirect(context.Background(), testLoginConfig(20*time.Millisecond), false, probe)
+	require.NoError(t, err)
+	assert.Equal(t, successURL, got)
+}
+
+func TestWaitForLoginRedirect_HonorsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	probe, _ := scriptedProbe(probeStep{page: loginPageWaiting})
+	_, err := waitForLoginRedirect(ctx, testLoginConfig(time.Second), true, probe)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNewLoginConfig(t *testing.T) {
+	assert.Equal(t, defaultHeadlessLoginTimeout, newLoginConfig(true, nil).timeout)
+	assert.Equal(t, defaultWindowLoginTimeout, newLoginConfig(false, nil).timeout)
+
+	cfg := newLoginConfig(true, []LoginOption{WithLoginTimeout(time.Minute), WithLoginPollInterval(0), WithChromeProfile("/profile")})
+	assert.Equal(t, time.Minute, cfg.timeout)
+	assert.Equal(t, DefaultLoginPollInterval, cfg.pollInterval)
+	assert.Equal(t, "/profile", cfg.profileDir)
+}
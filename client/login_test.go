@@ -1,12 +1,15 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
+	"github.com/habedi/gogg/auth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -83,3 +86,106 @@ func TestExchangeCodeForToken_Success(t *testing.T) {
 	require.NoError(t, parseErr)
 	assert.WithinDuration(t, expectedExpiry, actualExpiry, 5*time.Second)
 }
+
+func TestGogClient_CustomOAuthConfig(t *testing.T) {
+	oauth := OAuthConfig{ClientID: "my-id", ClientSecret: "my-secret", RedirectURI: "http://localhost/done"}
+	var forms []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		forms = append(forms, map[string]string{
+			"client_id":     r.FormValue("client_id"),
+			"client_secret": r.FormValue("client_secret"),
+			"redirect_uri":  r.FormValue("redirect_uri"),
+		})
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "a", "refresh_token": "r", "expires_in": 3600})
+	}))
+	defer server.Close()
+
+	gogClient := &GogClient{TokenURL: server.URL + "/token", OAuth: oauth}
+	_, _, _, err := gogClient.PerformTokenRefresh("my-refresh-token")
+	require.NoError(t, err)
+	_, _, _, err = gogClient.exchangeCodeForToken("my-auth-code")
+	require.NoError(t, err)
+
+	require.Len(t, forms, 2)
+	assert.Equal(t, map[string]string{"client_id": "my-id", "client_secret": "my-secret", "redirect_uri": ""}, forms[0])
+	assert.Equal(t, map[string]string{"client_id": "my-id", "client_secret": "my-secret", "redirect_uri": "http://localhost/done"}, forms[1])
+
+	loginURL, err := url.Parse(gogClient.LoginURL())
+	require.NoError(t, err)
+	assert.Equal(t, "https://auth.gog.com/auth", loginURL.Scheme+"://"+loginURL.Host+loginURL.Path, "an empty AuthURL keeps the default")
+	assert.Equal(t, "my-id", loginURL.Query().Get("client_id"))
+	assert.Equal(t, "http://localhost/done", loginURL.Query().Get("redirect_uri"))
+}
+
+func TestGogClient_DefaultOAuthConfig(t *testing.T) {
+	loginURL, err := url.Parse((&GogClient{}).LoginURL())
+	require.NoError(t, err)
+	assert.Equal(t, DefaultOAuthConfig().ClientID, loginURL.Query().Get("client_id"))
+	assert.Equal(t, "https://embed.gog.com/on_login_success?origin=client", loginURL.Query().Get("redirect_uri"))
+	assert.Equal(t, "code", loginURL.Query().Get("response_type"))
+}
+
+func TestNewGogClient_ReadsEmbedBaseOnce(t *testing.T) {
+	t.Setenv("GOGG_EMBED_BASE", "http://127.0.0.1:1")
+	gogClient := NewGogClient()
+	t.Setenv("GOGG_EMBED_BASE", "http://127.0.0.1:2")
+	assert.Equal(t, "http://127.0.0.1:1", gogClient.embedBase())
+	assert.Equal(t, DefaultEmbedBase, (&GogClient{}).embedBase(), "a client not made by NewGogClient does not read the environment")
+}
+
+func TestPerformTokenRefreshCtx_CancelledContextAbortsPromptly(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // never answer until the test is done
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := &GogClient{TokenURL: server.URL + "/token"}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, _, _, err := client.PerformTokenRefreshCtx(ctx, "my-refresh-token")
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestValidateToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+		failed  bool
+	}{
+		{name: "accepted", status: http.StatusOK, body: `{"isLoggedIn":true}`},
+		{name: "unauthorized", status: http.StatusUnauthorized, wantErr: auth.ErrTokenRejected},
+		{name: "not logged in", status: http.StatusOK, body: `{"isLoggedIn":false}`, wantErr: auth.ErrTokenRejected},
+		{name: "server error", status: http.StatusInternalServerError, failed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/userData.json", r.URL.Path)
+				assert.Equal(t, "Bearer my-token", r.Header.Get("Authorization"))
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			err := (&GogClient{EmbedBase: server.URL}).ValidateToken(context.Background(), "my-token")
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			case tt.failed:
+				require.Error(t, err)
+				assert.NotErrorIs(t, err, auth.ErrTokenRejected)
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"net/url"
+)
+
+// OAuthConfig is the OAuth client that a GogClient logs in to GOG as.
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	// AuthURL is the login page of GOG, without a query.
+	AuthURL string
+	// RedirectURI is where GOG sends the browser with the authorization code after the login.
+	RedirectURI string
+}
+
+// DefaultOAuthConfig returns the OAuth client of GOG Galaxy, which gogg logs in as.
+func DefaultOAuthConfig() OAuthConfig {
+	return OAuthConfig{
+		ClientID:     "46899977096215655",
+		ClientSecret: "9d85c43b1482497dbbce61f6e4aa173a433796eeae2ca8c5f6129f2dc4de46d9",
+		AuthURL:      "https://auth.gog.com/auth",
+		RedirectURI:  DefaultEmbedBase + "/on_login_success?origin=client",
+	}
+}
+
+// withDefaults returns c with its empty fields taken from DefaultOAuthConfig.
+func (c OAuthConfig) withDefaults() OAuthConfig {
+	d := DefaultOAuthConfig()
+	if c.ClientID == "" {
+		c.ClientID = d.ClientID
+	}
+	if c.ClientSecret == "" {
+		c.ClientSecret = d.ClientSecret
+	}
+	if c.AuthURL == "" {
+		c.AuthURL = d.AuthURL
+	}
+	if c.RedirectURI == "" {
+		c.RedirectURI = d.RedirectURI
+	}
+	return c
+}
+
+// LoginURL returns the address of the login page that redirects to RedirectURI with the authorization code.
+func (c OAuthConfig) LoginURL() string {
+	query := url.Values{
+		"client_id":     {c.ClientID},
+		"redirect_uri":  {c.RedirectURI},
+		"response_type": {"code"},
+		"layout":        {"client2"},
+	}
+	return c.AuthURL + "?" + query.Encode()
+}
@@ -0,0 +1,10 @@
+package client
+
+// WithPreallocation makes DownloadGameFiles reserve the disk space of each file before writing it, once the
+// server has reported its size. The file keeps its length until the data arrives, so resuming is not affected.
+// This avoids fragmenting very large installers on some file systems, and a volume without the space for a
+// file fails with ErrVolumeFull before the file is downloaded instead of part way through. On systems or file
+// systems that cannot reserve space, the files are written as without it.
+func WithPreallocation() DownloadOption {
+	return func(cfg *downloadConfig) { cfg.preallocate = true }
+}
@@ -0,0 +1,30 @@
+//go:build linux
+
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreallocate_KeepsLength(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "setup.exe"))
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString("partial")
+	require.NoError(t, err)
+
+	require.NoError(t, preallocate(f, 1<<20))
+	info, err := f.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("partial")), info.Size(), "the length is where a resume starts")
+	// File systems that cannot reserve space keep only the blocks of the data written.
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Blocks > 8 {
+		assert.GreaterOrEqual(t, st.Blocks*512, int64(1<<20))
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+
+package client
+
+import "os"
+
+// preallocate does nothing, as disk space is not reserved on this system.
+func preallocate(*os.File, int64) error {
+	return nil
+}
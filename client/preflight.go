@@ -0,0 +1,91 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxNameLength is the longest file or folder name, in bytes, that common file systems accept.
+const maxNameLength = 255
+
+// pathLengthLimit returns the longest path, in bytes, that the operating system accepts. On Windows it is
+// MAX_PATH, which applies unless long paths are enabled.
+func pathLengthLimit() int {
+	switch runtime.GOOS {
+	case "windows":
+		return 260
+	case "darwin":
+		return 1024
+	default:
+		return 4096
+	}
+}
+
+// PreflightReport is what checking the target file system of a download found before any file is written.
+type PreflightReport struct {
+	Files       int    // files the download writes, including metadata.json
+	Folders     int    // folders the files are written to
+	FreeInodes  uint64 // free inodes of the target file system, if InodesKnown
+	InodesKnown bool   // false if the file system does not report its inodes, like on Windows
+	LongestPath string // the longest path of a file of the download
+	Warnings    []string
+}
+
+// WithPreflight makes DownloadGameFiles check the target file system once it knows which files it downloads,
+// and pass the result to report before the first file is downloaded. The check warns when the download would
+// use more than half of the free inodes, or when a path is longer than the operating system or file system
+// accept. Installers are checked with the names GOG lists for them, which can be shorter than the names they
+// are saved with.
+func WithPreflight(report func(PreflightReport)) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.preflight = report }
+}
+
+// checkTarget builds the PreflightReport of downloading tasks to downloadPath.
+func checkTarget(downloadPath, gameFolder, platformName string, rommLayout bool, layout string, tasks []downloadTask) PreflightReport {
+	if abs, err := filepath.Abs(downloadPath); err == nil {
+		downloadPath = abs
+	}
+	report := PreflightReport{Files: 1} // metadata.json
+	folders := map[string]bool{filepath.Join(downloadPath, gameFolder): true}
+	longName := ""
+	for _, task := range tasks {
+		_, targetDir := task.dirs(downloadPath, gameFolder, platformName, rommLayout, layout)
+		folders[targetDir] = true
+		report.Files++
+		path := filepath.Join(targetDir, task.fileName)
+		if len(path) > len(report.LongestPath) {
+			report.LongestPath = path
+		}
+		rel, err := filepath.Rel(downloadPath, path)
+		if err != nil {
+			continue
+		}
+		for _, name := range strings.Split(rel, string(filepath.Separator)) {
+			if len(name) > maxNameLength && len(name) > len(longName) {
+				longName = name
+			}
+		}
+	}
+	report.Folders = len(folders)
+
+	if free, ok := freeInodes(downloadPath); ok {
+		report.FreeInodes, report.InodesKnown = free, true
+		if needed := uint64(report.Files + report.Folders); needed > free/2 {
+			report.Warnings = append(report.Warnings, fmt.Sprintf(
+				"the download writes about %d files and folders, but the file system of %s has only %d free inodes",
+				needed, downloadPath, free))
+		}
+	}
+	if limit := pathLengthLimit(); len(report.LongestPath) > limit {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"the path %s is %d characters long, more than the %d this system accepts; choose a shorter download directory or flatten the folders",
+			report.LongestPath, len(report.LongestPath), limit))
+	}
+	if longName != "" {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"the name %s is %d characters long, more than the %d most file systems accept", longName, len(longName), maxNameLength))
+	}
+	return report
+}
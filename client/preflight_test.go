@@ -0,0 +1,48 @@
+package client
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTarget(t *testing.T) {
+	dir := t.TempDir()
+	tasks := []downloadTask{
+		{fileName: "setup.exe", subDir: "windows"},
+		{fileName: "setup.sh", subDir: "linux"},
+		{fileName: "manual.pdf", subDir: "extras"},
+		{fileName: "soundtrack.zip", subDir: "extras"},
+	}
+	r := checkTarget(dir, "game", "all", false, "", tasks)
+	assert.Equal(t, 5, r.Files)
+	assert.Equal(t, 4, r.Folders, "the game folder and its windows, linux, and extras folders")
+	assert.Equal(t, filepath.Join(dir, "game", "extras", "soundtrack.zip"), r.LongestPath)
+	assert.Empty(t, r.Warnings)
+	if r.InodesKnown {
+		assert.NotZero(t, r.FreeInodes)
+	}
+
+	flat := checkTarget(dir, "game", "all", false, "", []downloadTask{{fileName: "setup.exe", subDir: "windows", flatten: true}})
+	assert.Equal(t, 1, flat.Folders)
+}
+
+func TestCheckTarget_LongPaths(t *testing.T) {
+	dir := t.TempDir()
+	longName := strings.Repeat("a", maxNameLength+1) + ".pdf"
+	r := checkTarget(dir, "game", "all", false, "", []downloadTask{{fileName: longName, subDir: "extras"}})
+	if assert.NotEmpty(t, r.Warnings) {
+		assert.Contains(t, r.Warnings[len(r.Warnings)-1], longName)
+	}
+
+	deep := filepath.Join(dir, strings.Repeat(strings.Repeat("d", 200)+string(filepath.Separator), pathLengthLimit()/200+1))
+	r = checkTarget(deep, "game", "all", false, "", []downloadTask{{fileName: "setup.exe", subDir: "windows"}})
+	assert.Greater(t, len(r.LongestPath), pathLengthLimit())
+	found := false
+	for _, w := range r.Warnings {
+		found = found || strings.Contains(w, "choose a shorter download directory")
+	}
+	assert.True(t, found, "a path longer than the system accepts is reported: %v", r.Warnings)
+}
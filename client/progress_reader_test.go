@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"io"
 	"testing"
+	"time"
 )
 
 func TestProgressReader_EmitsJSONLines(t *testing.T) {
@@ -40,3 +41,65 @@ func TestProgressReader_EmitsJSONLines(t *testing.T) {
 		t.Fatalf("expected progress updates")
 	}
 }
+
+// readAllUpdates reads pr to the end in chunks of size, flushes it, and returns the updates it sent.
+func readAllUpdates(t *testing.T, pr *progressReader, buf *bytes.Buffer, size int) []ProgressUpdate {
+	t.Helper()
+	r := make([]byte, size)
+	for {
+		_, err := pr.Read(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+	}
+	pr.flush()
+
+	var updates []ProgressUpdate
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		var u ProgressUpdate
+		if err := json.Unmarshal(scanner.Bytes(), &u); err != nil {
+			t.Fatalf("bad json: %v", err)
+		}
+		updates = append(updates, u)
+	}
+	return updates
+}
+
+func TestProgressReader_ThrottlesUpdates(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pr := &progressReader{reader: bytes.NewReader(make([]byte, 1000)), writer: buf, fileName: "file.bin",
+		totalSize: 1000, interval: time.Hour}
+
+	updates := readAllUpdates(t, pr, buf, 10)
+	// The first read is reported right away, the reads in between are held back, and the last one completes the file.
+	if len(updates) != 2 {
+		t.Fatalf("expected 2 updates, got %d: %+v", len(updates), updates)
+	}
+	if updates[0].CurrentBytes != 10 || updates[1].CurrentBytes != 1000 {
+		t.Fatalf("unexpected updates: %+v", updates)
+	}
+}
+
+func TestProgressReader_FlushSendsFinalUpdateOfUnknownSize(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pr := &progressReader{reader: bytes.NewReader(make([]byte, 1000)), writer: buf, fileName: "file.bin",
+		interval: time.Hour}
+
+	updates := readAllUpdates(t, pr, buf, 10)
+	if len(updates) != 2 || updates[1].CurrentBytes != 1000 {
+		t.Fatalf("expected the final update to report 1000 bytes, got %+v", updates)
+	}
+}
+
+func TestProgressReader_NoIntervalSendsEveryRead(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pr := &progressReader{reader: bytes.NewReader(make([]byte, 1000)), writer: buf, fileName: "file.bin", totalSize: 1000}
+
+	if updates := readAllUpdates(t, pr, buf, 10); len(updates) != 100 {
+		t.Fatalf("expected 100 updates, got %d", len(updates))
+	}
+}
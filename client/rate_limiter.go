@@ -13,6 +13,30 @@ type RateLimiter struct {
 	last   time.Time
 }
 
+// NewRateLimiter returns a limiter of bytesPerSecond for WithRateLimiter. Zero or less means no limit.
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	lim := &RateLimiter{}
+	lim.SetRate(bytesPerSecond)
+	return lim
+}
+
+// SetRate changes the limit to bytesPerSecond, also for the downloads in progress. Zero or less means no limit.
+func (lim *RateLimiter) SetRate(bytesPerSecond int64) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	lim.rate = max(bytesPerSecond, 0)
+	if lim.tokens > float64(lim.rate) {
+		lim.tokens = float64(lim.rate)
+	}
+	lim.last = time.Now()
+}
+
+// WithRateLimiter makes DownloadGameFiles limit the speed of its downloads with lim instead of
+// GlobalDownloadRateLimiter. Downloads that share lim share its limit.
+func WithRateLimiter(lim *RateLimiter) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.rateLimiter = lim }
+}
+
 var (
 	GlobalDownloadRateLimiter *RateLimiter
 	rateLimiterMu             sync.RWMutex
@@ -33,65 +57,73 @@ func SetGlobalDownloadRateLimit(bytesPerSecond int64) {
 	}
 	// Update existing limiter outside of rateLimiterMu to avoid lock ordering issues
 	rateLimiterMu.Unlock()
-	lim.mu.Lock()
-	lim.rate = bytesPerSecond
-	if lim.tokens > float64(bytesPerSecond) {
-		lim.tokens = float64(bytesPerSecond)
-	}
-	lim.last = time.Now()
-	lim.mu.Unlock()
+	lim.SetRate(bytesPerSecond)
 }
 
 type limitedReader struct {
 	under io.Reader
 	lim   *RateLimiter
+	// global makes the reader use GlobalDownloadRateLimiter as it is at each read instead of lim, so a limit
+	// that is set, changed, or removed later, like by a RateSchedule, applies to downloads in progress.
+	global bool
 }
 
 func (lr *limitedReader) Read(p []byte) (int, error) {
-	if lr.lim == nil || lr.lim.rate <= 0 {
+	lim := lr.lim
+	if lr.global {
+		lim = globalRateLimiter()
+	}
+	if lim == nil || lim.rate <= 0 {
 		return lr.under.Read(p)
 	}
-	lr.lim.mu.Lock()
+	lim.mu.Lock()
 	// Refill tokens
 	now := time.Now()
-	elapsed := now.Sub(lr.lim.last).Seconds()
+	elapsed := now.Sub(lim.last).Seconds()
 	if elapsed > 0 {
-		lr.lim.tokens += elapsed * float64(lr.lim.rate)
-		maxTokens := float64(lr.lim.rate)
-		if lr.lim.tokens > maxTokens {
-			lr.lim.tokens = maxTokens
+		lim.tokens += elapsed * float64(lim.rate)
+		maxTokens := float64(lim.rate)
+		if lim.tokens > maxTokens {
+			lim.tokens = maxTokens
 		}
-		lr.lim.last = now
+		lim.last = now
 	}
 	// Decide max bytes we can read now
-	allowed := int(lr.lim.tokens)
+	allowed := int(lim.tokens)
 	if allowed <= 0 {
 		// Need to wait for next refill cycle
-		lr.lim.mu.Unlock()
-		sleepDur := time.Duration(float64(time.Second) * (1.0 / float64(lr.lim.rate)))
+		sleepDur := time.Duration(float64(time.Second) * (1.0 / float64(lim.rate)))
+		lim.mu.Unlock()
 		time.Sleep(sleepDur)
 		return lr.Read(p)
 	}
 	if len(p) > allowed {
 		p = p[:allowed]
 	}
-	lr.lim.mu.Unlock()
+	lim.mu.Unlock()
 	n, err := lr.under.Read(p)
 	if n > 0 {
-		lr.lim.mu.Lock()
-		lr.lim.tokens -= float64(n)
-		lr.lim.mu.Unlock()
+		lim.mu.Lock()
+		lim.tokens -= float64(n)
+		lim.mu.Unlock()
 	}
 	return n, err
 }
 
-func wrapWithGlobalRateLimiter(r io.Reader) io.Reader {
+func globalRateLimiter() *RateLimiter {
 	rateLimiterMu.RLock()
-	lim := GlobalDownloadRateLimiter
-	rateLimiterMu.RUnlock()
+	defer rateLimiterMu.RUnlock()
+	return GlobalDownloadRateLimiter
+}
+
+func wrapWithGlobalRateLimiter(r io.Reader) io.Reader {
+	return &limitedReader{under: r, global: true}
+}
 
+// wrapWithRateLimiter limits the reads of r with lim, or with GlobalDownloadRateLimiter if lim is nil.
+func wrapWithRateLimiter(r io.Reader, lim *RateLimiter) io.Reader {
 	if lim == nil {
-		return r
+		return wrapWithGlobalRateLimiter(r)
 	}
 	return &limitedReader{under: r, lim: lim}
 }
@@ -361,3 +361,30 @@ func TestRateLimiter_TokenCapRespected(t *testing.T) {
 		t.Errorf("Tokens %f exceed rate %d", tokens, limiter.rate)
 	}
 }
+
+func TestWrapWithGlobalRateLimiter_FollowsLimitChanges(t *testing.T) {
+	SetGlobalDownloadRateLimit(0)
+	t.Cleanup(func() { SetGlobalDownloadRateLimit(0) })
+	wrapped := wrapWithGlobalRateLimiter(bytes.NewReader(make([]byte, 1000)))
+
+	// A limit set after the download started applies to it.
+	SetGlobalDownloadRateLimit(100)
+	buf := make([]byte, 1000)
+	n, err := wrapped.Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if n > 100 {
+		t.Errorf("Read %d bytes, should be capped by the limit set after wrapping", n)
+	}
+
+	// Removing the limit lets the rest through at once.
+	SetGlobalDownloadRateLimit(0)
+	m, err := wrapped.Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if n+m != 1000 {
+		t.Errorf("Read %d bytes in total, want 1000", n+m)
+	}
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RateScheduleFileName is the name of the file in the data directory that holds the RateSchedule.
+const RateScheduleFileName = "bandwidth_schedule.json"
+
+// RateScheduleInterval is how often RateSchedule.Apply checks whether another limit applies.
+const RateScheduleInterval = time.Minute
+
+// RateSchedule sets the download rate limit by the time of day, for example to download without a limit at
+// night and slowly during the day. It is read from a JSON file like:
+//
+//	{
+//	  "default": "0",
+//	  "windows": [
+//	    {"from": "08:00", "to": "18:00", "limit": "512KB"},
+//	    {"from": "18:00", "to": "23:00", "limit": "2MB"}
+//	  ]
+//	}
+//
+// Limits are sizes per second, and "0" means no limit. The first window that contains the local time of day
+// applies, and the default applies outside of all windows.
+type RateSchedule struct {
+	Default string       `json:"default"`
+	Windows []RateWindow `json:"windows"`
+}
+
+// RateWindow is a time of day during which a RateSchedule limits the download rate to Limit. A window whose
+// end is before its start runs past midnight, like from 22:00 to 06:00.
+type RateWindow struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Limit string `json:"limit"`
+}
+
+// LoadRateSchedule reads and validates the RateSchedule in the file at path.
+func LoadRateSchedule(path string) (*RateSchedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s RateSchedule
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid bandwidth schedule %s: %w", path, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid bandwidth schedule %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// RateSchedulePath returns the path of the RateSchedule in dataDir, the directory of the database.
+func RateSchedulePath(dataDir string) string {
+	return filepath.Join(dataDir, RateScheduleFileName)
+}
+
+// FindRateSchedule reads the RateSchedule in dataDir like LoadRateSchedule. It returns nil and no error if
+// there is no schedule.
+func FindRateSchedule(dataDir string) (*RateSchedule, error) {
+	s, err := LoadRateSchedule(RateSchedulePath(dataDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return s, err
+}
+
+// Validate checks the times and limits of the schedule.
+func (s *RateSchedule) Validate() error {
+	if _, err := parseRateLimit(s.Default); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for i, w := range s.Windows {
+		if _, err := parseTimeOfDay(w.From); err != nil {
+			return fmt.Errorf("window %d: from: %w", i+1, err)
+		}
+		if _, err := parseTimeOfDay(w.To); err != nil {
+			return fmt.Errorf("window %d: to: %w", i+1, err)
+		}
+		if _, err := parseRateLimit(w.Limit); err != nil {
+			return fmt.Errorf("window %d: limit: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// LimitAt returns the download rate limit in bytes per second at the time of day of t, or 0 for no limit.
+// The schedule must be valid.
+func (s *RateSchedule) LimitAt(t time.Time) int64 {
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range s.Windows {
+		from, _ := parseTimeOfDay(w.From)
+		to, _ := parseTimeOfDay(w.To)
+		inWindow := from <= minute && minute < to
+		if to < from {
+			inWindow = minute >= from || minute < to
+		}
+		if inWindow {
+			limit, _ := parseRateLimit(w.Limit)
+			return limit
+		}
+	}
+	limit, _ := parseRateLimit(s.Default)
+	return limit
+}
+
+// Apply sets the global download rate limit to the limit of the schedule now and then every interval, until
+// ctx is done. Downloads in progress are slowed down or sped up when the limit changes.
+func (s *RateSchedule) Apply(ctx context.Context, interval time.Duration) {
+	current := int64(-1)
+	update := func() {
+		limit := s.LimitAt(time.Now())
+		if limit == current {
+			return
+		}
+		current = limit
+		SetGlobalDownloadRateLimit(limit)
+		log.Info().Int64("bytesPerSecond", limit).Msg("Applied the download rate limit of the bandwidth schedule")
+	}
+	update()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			update()
+		}
+	}
+}
+
+// parseTimeOfDay parses a time of day like "08:30" into minutes after midnight.
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day like 08:30", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseRateLimit parses a limit like "512KB" into bytes per second. An empty limit or "0" means no limit.
+func parseRateLimit(s string) (int64, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	limit, err := parseSizeString(s)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("%q is not a size like 512KB or 2MB", s)
+	}
+	return limit, nil
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateSchedule_LimitAt(t *testing.T) {
+	s := RateSchedule{
+		Default: "0",
+		Windows: []RateWindow{
+			{From: "08:00", To: "18:00", Limit: "512KB"},
+			{From: "22:00", To: "06:00", Limit: "2MB"},
+			{From: "07:00", To: "09:00", Limit: "1MB"}, // overlaps the first window, which wins
+		},
+	}
+	require.NoError(t, s.Validate())
+	at := func(hour, minute int) time.Time { return time.Date(2024, 5, 1, hour, minute, 0, 0, time.Local) }
+
+	assert.Equal(t, int64(512*1024), s.LimitAt(at(8, 0)))
+	assert.Equal(t, int64(512*1024), s.LimitAt(at(17, 59)))
+	assert.Equal(t, int64(0), s.LimitAt(at(18, 0)))
+	assert.Equal(t, int64(2*1024*1024), s.LimitAt(at(23, 30)))
+	assert.Equal(t, int64(2*1024*1024), s.LimitAt(at(5, 59)))
+	assert.Equal(t, int64(1024*1024), s.LimitAt(at(7, 30)))
+	assert.Equal(t, int64(0), s.LimitAt(at(6, 30)))
+}
+
+func TestRateSchedule_Validate(t *testing.T) {
+	for _, s := range []RateSchedule{
+		{Default: "fast"},
+		{Windows: []RateWindow{{From: "8am", To: "18:00", Limit: "1MB"}}},
+		{Windows: []RateWindow{{From: "08:00", To: "24:00", Limit: "1MB"}}},
+		{Windows: []RateWindow{{From: "08:00", To: "18:00", Limit: "-1"}}},
+	} {
+		assert.Error(t, s.Validate(), "%+v", s)
+	}
+}
+
+func TestLoadRateSchedule(t *testing.T) {
+	dir := t.TempDir()
+	_, err := LoadRateSchedule(filepath.Join(dir, RateScheduleFileName))
+	assert.True(t, os.IsNotExist(err))
+
+	path := filepath.Join(dir, RateScheduleFileName)
+	require.NoError(t, os.WriteFile(path, []byte(`{"default": "1MB", "windows": [{"from": "00:00", "to": "06:00", "limit": ""}]}`), 0o644))
+	s, err := LoadRateSchedule(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), s.LimitAt(time.Date(2024, 5, 1, 3, 0, 0, 0, time.Local)))
+	assert.Equal(t, int64(1024*1024), s.LimitAt(time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"windows": [{"from": "00:00", "to": "06:00", "limit": "lots"}]}`), 0o644))
+	_, err = LoadRateSchedule(path)
+	assert.ErrorContains(t, err, "window 1: limit")
+}
+
+func TestFindRateSchedule(t *testing.T) {
+	dir := t.TempDir()
+	s, err := FindRateSchedule(dir)
+	require.NoError(t, err, "a missing schedule is not an error")
+	assert.Nil(t, s)
+
+	require.NoError(t, os.WriteFile(RateSchedulePath(dir), []byte(`{"default": "2MB"}`), 0o644))
+	s, err = FindRateSchedule(dir)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	assert.Equal(t, int64(2*1024*1024), s.LimitAt(time.Now()))
+
+	require.NoError(t, os.WriteFile(RateSchedulePath(dir), []byte(`{"default": "fast"}`), 0o644))
+	_, err = FindRateSchedule(dir)
+	assert.ErrorContains(t, err, "default")
+}
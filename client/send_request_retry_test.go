@@ -1,8 +1,11 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -26,7 +29,7 @@ func TestSendRequest_RetriesOn500ThenSucceeds(t *testing.T) {
 		t.Fatal(err)
 	}
 	start := time.Now()
-	resp, err := sendRequest(req)
+	resp, err := sendRequest(req, DefaultFetchTimeout)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -42,3 +45,98 @@ func TestSendRequest_RetriesOn500ThenSucceeds(t *testing.T) {
 	}
 	_ = resp.Body.Close()
 }
+
+func TestSendRequest_RetriesAfterTimeout(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			time.Sleep(300 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := sendRequest(req, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Fatalf("expected the slow attempt to time out and be retried, got %d attempts", n)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestSendRequest_ReturnsRetriesExhaustedError(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sendRequest(req, DefaultFetchTimeout)
+	var retriesErr *RetriesExhaustedError
+	if !errors.As(err, &retriesErr) {
+		t.Fatalf("expected a *RetriesExhaustedError, got %T: %v", err, err)
+	}
+	if retriesErr.Attempts != 3 || attempts.Load() != 3 {
+		t.Fatalf("expected 3 attempts, got %d (server saw %d)", retriesErr.Attempts, attempts.Load())
+	}
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected the last error to be HTTP 503, got %v", retriesErr.Err)
+	}
+	if got, want := err.Error(), "gave up after 3 attempts (last: HTTP 503)"; got != want {
+		t.Fatalf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestSendRequest_DoesNotRetryAfterCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sendRequest(req, DefaultFetchTimeout)
+	var retriesErr *RetriesExhaustedError
+	if err == nil || errors.As(err, &retriesErr) {
+		t.Fatalf("expected the cancellation error without retries, got %v", err)
+	}
+}
+
+func TestSendRequest_CancelDuringBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = sendRequest(req, DefaultFetchTimeout)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the cancellation error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Fatalf("expected the backoff to stop when the request was cancelled, took %v", elapsed)
+	}
+}
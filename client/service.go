@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/db"
+)
+
+// Default addresses of GOG used by a Service.
+const (
+	DefaultEmbedBase = "https://embed.gog.com"
+	DefaultTokenURL  = "https://auth.gog.com/token"
+)
+
+// Service is a GOG account for programs that embed gogg. It logs in, keeps the access token fresh, lists the
+// games of the account, fetches their details, and downloads them.
+//
+// The package-level functions use the endpoints from GOGG_EMBED_BASE, the database of package db, and
+// GlobalDownloadRateLimiter. A Service uses only what it is given. Several Services can be used side by side,
+// for example one against a test server, without changing the state of the process.
+type Service struct {
+	embedBase   string
+	tokenURL    string
+	oauth       OAuthConfig
+	transport   http.RoundTripper
+	rateLimiter *RateLimiter
+
+	gog  *GogClient
+	auth *auth.Service
+}
+
+// ServiceOption configures NewService.
+type ServiceOption func(*Service)
+
+// WithEmbedBase makes the Service send its requests for the games of the account to the embed API at base
+// instead of DefaultEmbedBase. Download links that GOG lists relative to the embed API are resolved against
+// base as well.
+func WithEmbedBase(base string) ServiceOption {
+	return func(s *Service) { s.embedBase = base }
+}
+
+// WithTokenURL makes the Service refresh tokens and complete logins at url instead of DefaultTokenURL.
+func WithTokenURL(url string) ServiceOption {
+	return func(s *Service) { s.tokenURL = url }
+}
+
+// WithOAuthConfig makes the Service log in as the OAuth client of cfg instead of DefaultOAuthConfig. Its empty
+// fields keep the defaults.
+func WithOAuthConfig(cfg OAuthConfig) ServiceOption {
+	return func(s *Service) { s.oauth = cfg }
+}
+
+// WithTransport makes the Service send all its requests, including the downloads, with rt, for example the
+// transport of an httptest.Server. The Service still sets the timeouts of its requests itself.
+func WithTransport(rt http.RoundTripper) ServiceOption {
+	return func(s *Service) { s.transport = rt }
+}
+
+// NewService returns a Service that keeps its token in tokens. Its downloads are not limited in speed until
+// SetRateLimit is called.
+func NewService(tokens auth.TokenStorer, opts ...ServiceOption) *Service {
+	s := &Service{
+		embedBase:   DefaultEmbedBase,
+		tokenURL:    DefaultTokenURL,
+		oauth:       DefaultOAuthConfig(),
+		rateLimiter: NewRateLimiter(0),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	var tokenClient *http.Client
+	if s.transport != nil {
+		tokenClient = &http.Client{Transport: s.transport, Timeout: tokenRequestTimeout}
+	}
+	s.gog = &GogClient{TokenURL: s.tokenURL, OAuth: s.oauth, EmbedBase: s.embedBase, HTTPClient: tokenClient, Tokens: tokens}
+	s.auth = auth.NewService(tokens, s.gog)
+	return s
+}
+
+// Auth returns the auth.Service of the Service, for the functions of the package that take one.
+func (s *Service) Auth() *auth.Service { return s.auth }
+
+// Login logs in to GOG with a browser, like GogClient.Login, and stores the token.
+func (s *Service) Login(username, password string, headless bool, options ...LoginOption) error {
+	return s.gog.Login(s.gog.LoginURL(), username, password, headless, options...)
+}
+
+// LoginWithCode completes a login done in another browser, like GogClient.LoginWithCode, and stores the token.
+func (s *Service) LoginWithCode(input string) error {
+	return s.gog.LoginWithCode(input)
+}
+
+// RefreshToken returns the stored token, refreshing it first if it has expired.
+func (s *Service) RefreshToken(ctx context.Context) (*db.Token, error) {
+	return s.auth.RefreshTokenCtx(ctx)
+}
+
+// CheckSession checks that the stored login can still be used, like auth.Service.CheckSession.
+func (s *Service) CheckSession(ctx context.Context) error {
+	return s.auth.CheckSession(ctx)
+}
+
+// OwnedGames returns the games owned by the account, like FetchOwnedGames.
+func (s *Service) OwnedGames(ctx context.Context, titles bool, numWorkers int) ([]OwnedGame, error) {
+	return fetchOwnedGames(ctx, s.endpoint(), s.auth, titles, numWorkers)
+}
+
+// GameDetails fetches the details of the game with the given ID, like FetchGameData. It also returns the
+// details as GOG sent them, which is what the catalogue stores.
+func (s *Service) GameDetails(ctx context.Context, gameID int) (Game, string, error) {
+	token, err := s.RefreshToken(ctx)
+	if err != nil {
+		return Game{}, "", fmt.Errorf("failed to refresh token: %w", err)
+	}
+	api := s.endpoint()
+	return fetchGameData(ctx, api.client, token.AccessToken, api.gameDetailsURL(gameID), DefaultFetchTimeout)
+}
+
+// RefreshCatalogue fetches the details of the games of the account into repo, like the package-level
+// RefreshCatalogue.
+func (s *Service) RefreshCatalogue(ctx context.Context, repo db.GameRepository, numWorkers int, progressCb func(float64), opts ...RefreshOption) error {
+	return refreshCatalogue(ctx, s.endpoint(), s.auth, repo, numWorkers, progressCb, opts...)
+}
+
+// SetRateLimit limits the speed of the downloads of the Service to bytesPerSecond, shared by all of them and
+// also applied to the downloads in progress. Zero or less removes the limit.
+func (s *Service) SetRateLimit(bytesPerSecond int64) {
+	s.rateLimiter.SetRate(bytesPerSecond)
+}
+
+// DownloadGame downloads the files of game like DownloadGameFiles, with a fresh token of the account, the
+// rate limit of the Service, and its transport and embed API. options are applied after those of the Service,
+// so they can replace them.
+func (s *Service) DownloadGame(
+	ctx context.Context,
+	game Game, downloadPath string,
+	gameLanguage string, platformName string, extrasFlag bool, dlcFlag bool, resumeFlag bool,
+	flattenFlag bool, skipPatchesFlag bool, rommLayout bool, numThreads int,
+	updateWriter io.Writer, options ...DownloadOption,
+) error {
+	token, err := s.RefreshToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to refresh token: %w", err)
+	}
+	serviceOptions := []DownloadOption{
+		WithRateLimiter(s.rateLimiter),
+		func(cfg *downloadConfig) { cfg.embedBase = s.embedBase },
+	}
+	if s.transport != nil {
+		serviceOptions = append(serviceOptions, WithHTTPClient(&http.Client{Transport: s.transport}))
+	}
+	return DownloadGameFiles(ctx, token.AccessToken, game, downloadPath, gameLanguage, platformName, extrasFlag, dlcFlag,
+		resumeFlag, flattenFlag, skipPatchesFlag, rommLayout, numThreads, updateWriter, append(serviceOptions, options...)...)
+}
+
+func (s *Service) endpoint() endpoint {
+	api := endpoint{base: s.embedBase}
+	if s.transport != nil {
+		api.client = &http.Client{Transport: s.transport}
+	}
+	return api
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/habedi/gogg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memTokens keeps the token of a Service in memory.
+type memTokens struct {
+	mu    sync.Mutex
+	token *db.Token
+}
+
+func (m *memTokens) GetTokenRecord() (*db.Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.token == nil {
+		return nil, nil
+	}
+	token := *m.token
+	return &token, nil
+}
+
+func (m *memTokens) UpsertTokenRecord(token *db.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved := *token
+	m.token = &saved
+	return nil
+}
+
+func validToken(access string) *db.Token {
+	return &db.Token{AccessToken: access, RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Hour).Format(time.RFC3339)}
+}
+
+func TestService_RefreshesTokenAndFetchesGames(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "old-refresh", r.PostForm.Get("refresh_token"))
+		fmt.Fprint(w, `{"access_token":"fresh","refresh_token":"new-refresh","expires_in":3600}`)
+	})
+	authorized := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer fresh" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("/user/data/games", authorized(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"owned":[2,1]}`)
+	}))
+	mux.HandleFunc("/account/gameDetails/", authorized(func(w http.ResponseWriter, r *http.Request) {
+		var id int
+		_, _ = fmt.Sscanf(r.URL.Path, "/account/gameDetails/%d.json", &id)
+		_ = json.NewEncoder(w).Encode(Game{Title: fmt.Sprintf("Game %d", id)})
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tokens := &memTokens{token: &db.Token{AccessToken: "stale", RefreshToken: "old-refresh",
+		ExpiresAt: time.Now().Add(-time.Hour).Format(time.RFC3339)}}
+	s := NewService(tokens, WithEmbedBase(server.URL), WithTokenURL(server.URL+"/token"))
+	ctx := context.Background()
+
+	token, err := s.RefreshToken(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", token.AccessToken)
+	stored, _ := tokens.GetTokenRecord()
+	assert.Equal(t, "new-refresh", stored.RefreshToken, "the refreshed token is stored in the storer of the service")
+
+	games, err := s.OwnedGames(ctx, true, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []OwnedGame{{ID: 1, Title: "Game 1"}, {ID: 2, Title: "Game 2"}}, games)
+
+	game, raw, err := s.GameDetails(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Game 2", game.Title)
+	assert.Contains(t, raw, `"title":"Game 2"`)
+}
+
+func TestService_DownloadGame(t *testing.T) {
+	g := newFakeGOG(t, fakeGameFiles...)
+	// GOG lists the download links relative to the embed API, which the service resolves against its own.
+	game := Game{Title: "Test Game", Downloads: []Downloadable{{Language: "English", Platforms: Platform{
+		Windows: []PlatformFile{{Name: "Test Game", Size: "48 KB", ManualURL: strPtr("/downloads/setup-win")}},
+	}}}}
+	SetGlobalDownloadRateLimit(0)
+	s := NewService(&memTokens{token: validToken("tok")}, WithEmbedBase(g.URL), WithTransport(g.Client().Transport))
+	s.SetRateLimit(1 << 30)
+
+	dir := t.TempDir()
+	err := s.DownloadGame(context.Background(), game, dir, "English", "windows",
+		false, false, true, true, false, false, 2, io.Discard)
+	require.NoError(t, err)
+	got, err := os.ReadFile(filepath.Join(dir, "test-game", "setup_test_game_1.0.exe"))
+	require.NoError(t, err)
+	assert.Equal(t, fakeGameFiles[0].content, got)
+	assert.Nil(t, globalRateLimiter(), "the service does not set the global rate limit")
+}
+
+func TestRebaseManualURL(t *testing.T) {
+	assert.Equal(t, "http://127.0.0.1:8080/downloads/x", rebaseManualURL(buildManualURL("/downloads/x"), "http://127.0.0.1:8080/"))
+	assert.Equal(t, "https://cdn.gog.com/x", rebaseManualURL("https://cdn.gog.com/x", "http://127.0.0.1:8080"))
+}
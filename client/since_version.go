@@ -0,0 +1,227 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReadGameMetadata reads the metadata.json that DownloadGameFiles wrote into the game folder dir.
+func ReadGameMetadata(dir string) (Game, error) {
+	var game Game
+	data, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return game, err
+	}
+	err = json.Unmarshal(data, &game)
+	return game, err
+}
+
+// ReadDownloadInfo reads the download_info.json that WriteDownloadInfo wrote into dir.
+func ReadDownloadInfo(dir string) (DownloadInfo, error) {
+	var info DownloadInfo
+	data, err := os.ReadFile(filepath.Join(dir, "download_info.json"))
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(data, &info)
+	return info, err
+}
+
+// WithSinceVersion makes DownloadGameFiles download only the files that are new or changed since an earlier
+// download of the game, made with the settings in info and with old as its metadata.json. See ChangedFiles.
+func WithSinceVersion(old Game, info DownloadInfo) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.since = &sinceVersion{old: old, info: info} }
+}
+
+// sinceVersion is the earlier download given with WithSinceVersion.
+type sinceVersion struct {
+	old  Game
+	info DownloadInfo
+}
+
+// ChangedFiles returns a copy of game without the files that an earlier download already got in the same
+// version. old is the metadata.json of that download and info its download_info.json, which tells which of
+// the files of old were downloaded at all. Installers and patches are matched by game or DLC, language,
+// platform, and name, and kept if their version changed. Extras have no version, so they are matched by their
+// download link and kept if their revision, see ExtraRevision, changed.
+func ChangedFiles(game, old Game, info DownloadInfo) Game {
+	had := downloadedRevisions(old, info)
+	changed := func(component string) func(string, string, []PlatformFile) []PlatformFile {
+		return func(language, platform string, files []PlatformFile) []PlatformFile {
+			var kept []PlatformFile
+			for _, f := range files {
+				rev, ok := had[installerKey(component, language, platform, f.Name)]
+				if !ok || rev != installerRevision(f) {
+					kept = append(kept, f)
+				}
+			}
+			return kept
+		}
+	}
+	changedExtras := func(component string, extras []Extra) []Extra {
+		var kept []Extra
+		for _, e := range extras {
+			if rev, ok := had[extraKey(component, e.ManualURL)]; !ok || rev != ExtraRevision(e) {
+				kept = append(kept, e)
+			}
+		}
+		return kept
+	}
+
+	game.Downloads = filterDownloads(game.Downloads, changed(""))
+	game.Extras = changedExtras("", game.Extras)
+	dlcs := make([]DLC, len(game.DLCs))
+	for i, dlc := range game.DLCs {
+		component := "dlc:" + SanitizePath(dlc.Title)
+		dlc.ParsedDownloads = filterDownloads(dlc.ParsedDownloads, changed(component))
+		dlc.Extras = changedExtras(component, dlc.Extras)
+		dlcs[i] = dlc
+	}
+	game.DLCs = dlcs
+	return game
+}
+
+// SelectedFiles returns the files of game that a download with the settings of info gets: the installers and
+// patches of its language and platform, and the extras and DLCs if info includes them. With ChangedFiles, it
+// tells whether an earlier download is up to date, which it is if no file is left.
+func SelectedFiles(game Game, info DownloadInfo) []GameFile {
+	if !info.Extras {
+		game.Extras = nil
+	}
+	if !info.DLCs {
+		game.DLCs = nil
+	} else if !info.Extras {
+		dlcs := make([]DLC, len(game.DLCs))
+		for i, dlc := range game.DLCs {
+			dlc.Extras = nil
+			dlcs[i] = dlc
+		}
+		game.DLCs = dlcs
+	}
+	allLanguages := strings.EqualFold(info.Language, AllLanguages)
+	var files []GameFile
+	for _, f := range game.Files() {
+		if !f.extra {
+			if !allLanguages && !LanguageMatches(f.Language, info.Language) {
+				continue
+			}
+			if info.Platform != "all" && !strings.EqualFold(info.Platform, f.Platform) {
+				continue
+			}
+			if info.SkipPatches && f.patch {
+				continue
+			}
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// downloadedRevisions returns the revisions of the files of old that a download with info got, keyed like
+// installerKey and extraKey.
+func downloadedRevisions(old Game, info DownloadInfo) map[string]string {
+	revs := make(map[string]string)
+	allLanguages := strings.EqualFold(info.Language, AllLanguages)
+	addDownloads := func(component string, downloads []Downloadable) {
+		for _, d := range downloads {
+			if !allLanguages && !LanguageMatches(d.Language, info.Language) {
+				continue
+			}
+			for platform, files := range map[string][]PlatformFile{
+				"windows": d.Platforms.Windows, "mac": d.Platforms.Mac, "linux": d.Platforms.Linux,
+			} {
+				if info.Platform != "all" && !strings.EqualFold(info.Platform, platform) {
+					continue
+				}
+				for _, f := range files {
+					if info.SkipPatches && IsPatchFile(f) {
+						continue
+					}
+					revs[installerKey(component, d.Language, platform, f.Name)] = installerRevision(f)
+				}
+			}
+		}
+	}
+	addExtras := func(component string, extras []Extra) {
+		if !info.Extras {
+			return
+		}
+		for _, e := range extras {
+			revs[extraKey(component, e.ManualURL)] = ExtraRevision(e)
+		}
+	}
+
+	addDownloads("", old.Downloads)
+	addExtras("", old.Extras)
+	if info.DLCs {
+		for _, dlc := range old.DLCs {
+			component := "dlc:" + SanitizePath(dlc.Title)
+			addDownloads(component, dlc.ParsedDownloads)
+			addExtras(component, dlc.Extras)
+		}
+	}
+	return revs
+}
+
+// filterDownloads returns a copy of downloads whose files of each language and platform are filtered by filter.
+func filterDownloads(downloads []Downloadable, filter func(language, platform string, files []PlatformFile) []PlatformFile) []Downloadable {
+	filtered := make([]Downloadable, len(downloads))
+	for i, d := range downloads {
+		filtered[i] = Downloadable{Language: d.Language, Platforms: Platform{
+			Windows: filter(d.Language, "windows", d.Platforms.Windows),
+			Mac:     filter(d.Language, "mac", d.Platforms.Mac),
+			Linux:   filter(d.Language, "linux", d.Platforms.Linux),
+		}}
+	}
+	return filtered
+}
+
+func installerKey(component, language, platform, name string) string {
+	code, ok := LanguageCode(language)
+	if !ok {
+		code = strings.ToLower(strings.TrimSpace(language))
+	}
+	return component + "|" + code + "|" + platform + "|" + name
+}
+
+func extraKey(component, manualURL string) string {
+	return component + "|extra|" + manualURL
+}
+
+// installerRevision returns the version of f, or its size if GOG lists no version for it.
+func installerRevision(f PlatformFile) string {
+	if f.Version != nil && *f.Version != "" {
+		return "version " + *f.Version
+	}
+	return "size " + f.Size
+}
+
+// ExtraRevision returns what tells the revisions of extra apart. GOG lists neither a version nor a date for
+// extras, so a replaced soundtrack or an updated manual shows only in its size.
+func ExtraRevision(extra Extra) string {
+	return "size " + extra.Size
+}
+
+// GameVersion returns the versions GOG lists for the installers and patches of game, without those of its DLCs,
+// sorted and joined by ", ". It is empty if GOG lists no version.
+func GameVersion(game Game) string {
+	seen := make(map[string]bool)
+	var versions []string
+	filterDownloads(game.Downloads, func(_, _ string, files []PlatformFile) []PlatformFile {
+		for _, f := range files {
+			if f.Version == nil {
+				continue
+			}
+			if v := strings.TrimSpace(*f.Version); v != "" && !seen[v] {
+				seen[v] = true
+				versions = append(versions, v)
+			}
+		}
+		return files
+	})
+	sort.Strings(versions)
+	return strings.Join(versions, ", ")
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedFiles(t *testing.T) {
+	file := func(name, version string) PlatformFile {
+		return PlatformFile{Name: name, Version: strPtr(version), Size: "1 GB", ManualURL: strPtr("/" + name)}
+	}
+	old := Game{
+		Downloads: []Downloadable{{Language: "English", Platforms: Platform{
+			Windows: []PlatformFile{file("Game", "1.0"), file("Patch", "1.0")},
+			Linux:   []PlatformFile{file("Game", "1.0")},
+		}}},
+		Extras: []Extra{{Name: "Manual", Size: "1 MB", ManualURL: "/manual"}},
+		DLCs: []DLC{{Title: "DLC", ParsedDownloads: []Downloadable{{Language: "English", Platforms: Platform{
+			Windows: []PlatformFile{file("DLC", "1.0")},
+		}}}}},
+	}
+	game := Game{
+		Downloads: []Downloadable{{Language: "English", Platforms: Platform{
+			Windows: []PlatformFile{file("Game", "1.1"), file("Patch", "1.0"), file("Hotfix", "1.0")},
+			Linux:   []PlatformFile{file("Game", "1.0")},
+		}}},
+		Extras: []Extra{{Name: "Manual", Size: "1 MB", ManualURL: "/manual"}, {Name: "Art", Size: "5 MB", ManualURL: "/art"}},
+		DLCs: []DLC{{Title: "DLC", ParsedDownloads: []Downloadable{{Language: "English", Platforms: Platform{
+			Windows: []PlatformFile{file("DLC", "1.0")},
+		}}}}},
+	}
+
+	changed := ChangedFiles(game, old, DownloadInfo{Language: "en", Platform: "windows", Extras: true, DLCs: true})
+	names := func(files []PlatformFile) []string {
+		var out []string
+		for _, f := range files {
+			out = append(out, f.Name)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"Game", "Hotfix"}, names(changed.Downloads[0].Platforms.Windows))
+	assert.Equal(t, []string{"Game"}, names(changed.Downloads[0].Platforms.Linux), "linux was not downloaded before")
+	assert.Equal(t, []Extra{{Name: "Art", Size: "5 MB", ManualURL: "/art"}}, changed.Extras)
+	assert.Empty(t, changed.DLCs[0].ParsedDownloads[0].Platforms.Windows)
+
+	// The DLCs and extras of a download without them count as new.
+	changed = ChangedFiles(game, old, DownloadInfo{Language: "English", Platform: "all"})
+	assert.Len(t, changed.Extras, 2)
+	assert.Equal(t, []string{"DLC"}, names(changed.DLCs[0].ParsedDownloads[0].Platforms.Windows))
+	assert.Empty(t, changed.Downloads[0].Platforms.Linux)
+	assert.Len(t, game.Downloads[0].Platforms.Windows, 3, "game is not changed")
+}
+
+func TestSelectedFiles(t *testing.T) {
+	file := func(name string) PlatformFile {
+		return PlatformFile{Name: name, Size: "1 GB", ManualURL: strPtr("/" + name)}
+	}
+	game := Game{
+		Title: "Game",
+		Downloads: []Downloadable{
+			{Language: "English", Platforms: Platform{
+				Windows: []PlatformFile{file("Game"), file("Patch 1.1")},
+				Linux:   []PlatformFile{file("Game (Linux)")},
+			}},
+			{Language: "Deutsch", Platforms: Platform{Windows: []PlatformFile{file("Spiel")}}},
+		},
+		Extras: []Extra{{Name: "Manual", Size: "1 MB", ManualURL: "/manual"}},
+		DLCs: []DLC{{Title: "DLC", ParsedDownloads: []Downloadable{{Language: "English", Platforms: Platform{
+			Windows: []PlatformFile{file("DLC")},
+		}}}, Extras: []Extra{{Name: "Artbook", Size: "1 MB", ManualURL: "/artbook"}}}},
+	}
+	names := func(files []GameFile) []string {
+		var out []string
+		for _, f := range files {
+			out = append(out, f.Name)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"Game", "Patch 1.1", "Manual", "DLC", "Artbook"},
+		names(SelectedFiles(game, DownloadInfo{Language: "English", Platform: "windows", Extras: true, DLCs: true})))
+	assert.Equal(t, []string{"Game", "DLC"},
+		names(SelectedFiles(game, DownloadInfo{Language: "English", Platform: "windows", DLCs: true, SkipPatches: true})))
+	assert.Equal(t, []string{"Game", "Game (Linux)", "Spiel", "Manual"},
+		names(SelectedFiles(game, DownloadInfo{Language: AllLanguages, Platform: "all", Extras: true, SkipPatches: true})))
+	assert.Empty(t, SelectedFiles(game, DownloadInfo{Language: "Deutsch", Platform: "mac"}))
+}
+
+func TestChangedFiles_ChangedExtraSize(t *testing.T) {
+	old := Game{
+		Extras: []Extra{{Name: "Soundtrack", Size: "100 MB", ManualURL: "/soundtrack"}, {Name: "Manual", Size: "1 MB", ManualURL: "/manual"}},
+		DLCs:   []DLC{{Title: "DLC", Extras: []Extra{{Name: "Artbook", Size: "20 MB", ManualURL: "/artbook"}}}},
+	}
+	game := Game{
+		Extras: []Extra{{Name: "Soundtrack", Size: "120 MB", ManualURL: "/soundtrack"}, {Name: "Manual", Size: "1 MB", ManualURL: "/manual"}},
+		DLCs:   []DLC{{Title: "DLC", Extras: []Extra{{Name: "Artbook", Size: "25 MB", ManualURL: "/artbook"}}}},
+	}
+
+	changed := ChangedFiles(game, old, DownloadInfo{Language: "en", Platform: "windows", Extras: true, DLCs: true})
+	assert.Equal(t, []Extra{{Name: "Soundtrack", Size: "120 MB", ManualURL: "/soundtrack"}}, changed.Extras, "the replaced soundtrack is downloaded again")
+	assert.Equal(t, []Extra{{Name: "Artbook", Size: "25 MB", ManualURL: "/artbook"}}, changed.DLCs[0].Extras)
+	assert.NotEqual(t, ExtraRevision(old.Extras[0]), ExtraRevision(game.Extras[0]))
+	assert.Equal(t, ExtraRevision(old.Extras[1]), ExtraRevision(game.Extras[1]))
+}
+
+func TestGameVersion(t *testing.T) {
+	file := func(name string, version *string) PlatformFile { return PlatformFile{Name: name, Version: version} }
+	game := Game{
+		Downloads: []Downloadable{
+			{Language: "English", Platforms: Platform{
+				Windows: []PlatformFile{file("Game", strPtr("1.2")), file("Patch", strPtr("1.1"))},
+				Linux:   []PlatformFile{file("Game", strPtr("1.2")), file("Extra", nil), file("Empty", strPtr(" "))},
+			}},
+		},
+		DLCs: []DLC{{Title: "DLC", ParsedDownloads: []Downloadable{{Language: "English", Platforms: Platform{
+			Windows: []PlatformFile{file("DLC", strPtr("3.0"))},
+		}}}}},
+	}
+	assert.Equal(t, "1.1, 1.2", GameVersion(game), "the versions of DLCs are left out")
+	assert.Empty(t, GameVersion(Game{}))
+}
+
+func TestReadGameMetadata_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	game := Game{Title: "Test Game", Downloads: []Downloadable{{Language: "English", Platforms: Platform{
+		Windows: []PlatformFile{{Name: "Test Game", Version: strPtr("1.0"), Size: "1 GB", ManualURL: strPtr("/setup")}},
+	}}}}
+	data, err := json.MarshalIndent(game, "", "  ")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "metadata.json"), data, 0o644))
+
+	got, err := ReadGameMetadata(dir)
+	require.NoError(t, err)
+	assert.Equal(t, game.Downloads, got.Downloads)
+
+	_, err = ReadGameMetadata(t.TempDir())
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Reasons why a file of a game is not downloaded, counted in a SkipSummary.
+const (
+	SkipOtherLanguage = "other-language"        // the file is for a language that was not selected
+	SkipOtherPlatform = "other-platform"        // the file is for a platform that was not selected
+	SkipPatch         = "patches"               // the file is a patch and patches are skipped
+	SkipNoLink        = "without download link" // GOG lists the file without a link to download it from
+	SkipFileSize      = "outside size limits"   // the size of the file is outside WithFileSizeRange
+	SkipExcluded      = "excluded"              // the name of the file matches a pattern of WithExcludedFiles
+	SkipByteBudget    = "over download limit"   // the file did not fit into the WithByteBudget
+	SkipComplete      = "already complete"      // the file was already complete on disk
+)
+
+// skipReasonOrder is the order of the reasons with the same count in SkipSummary.String.
+var skipReasonOrder = []string{SkipOtherLanguage, SkipOtherPlatform, SkipPatch, SkipNoLink, SkipFileSize, SkipExcluded,
+	SkipByteBudget, SkipComplete}
+
+// outcomeSkipReasons are the reasons of the outcomes of files that were not downloaded.
+var outcomeSkipReasons = map[string]string{FileComplete: SkipComplete, FileSkipped: SkipByteBudget, FileExcluded: SkipExcluded}
+
+// SkipSummary is how many files of a download were not downloaded, by the Skip* reason.
+type SkipSummary map[string]int
+
+// WithSkipSummary makes DownloadGameFiles call report with the files it did not download and why, once the
+// download is done, so the user can tell why a file is missing without reading the debug log.
+func WithSkipSummary(report func(SkipSummary)) DownloadOption {
+	return func(cfg *downloadConfig) { cfg.skipSummary = report }
+}
+
+// Total returns the number of files that were not downloaded.
+func (s SkipSummary) Total() int {
+	total := 0
+	for _, n := range s {
+		total += n
+	}
+	return total
+}
+
+// String returns the summary like "Skipped 12 file(s): 8 other-platform, 3 patches, 1 already complete", with
+// the most common reasons first.
+func (s SkipSummary) String() string {
+	reasons := make([]string, 0, len(s))
+	for reason, n := range s {
+		if n > 0 {
+			reasons = append(reasons, reason)
+		}
+	}
+	rank := func(reason string) int {
+		for i, r := range skipReasonOrder {
+			if r == reason {
+				return i
+			}
+		}
+		return len(skipReasonOrder)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if s[reasons[i]] != s[reasons[j]] {
+			return s[reasons[i]] > s[reasons[j]]
+		}
+		return rank(reasons[i]) < rank(reasons[j])
+	})
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		parts[i] = fmt.Sprintf("%d %s", s[reason], reason)
+	}
+	return fmt.Sprintf("Skipped %d file(s): %s", s.Total(), strings.Join(parts, ", "))
+}
+
+// skipCounter counts the files that a download skips, from the workers at the same time. Its methods do
+// nothing on a nil skipCounter.
+type skipCounter struct {
+	mu     sync.Mutex
+	counts SkipSummary
+}
+
+func newSkipCounter() *skipCounter {
+	return &skipCounter{counts: make(SkipSummary)}
+}
+
+func (c *skipCounter) add(reason string, n int) {
+	if c == nil || reason == "" || n <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[reason] += n
+}
+
+// summary returns a copy of the counts.
+func (c *skipCounter) summary() SkipSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := make(SkipSummary, len(c.counts))
+	for reason, n := range c.counts {
+		s[reason] = n
+	}
+	return s
+}
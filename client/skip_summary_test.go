@@ -0,0 +1,31 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipSummary_String(t *testing.T) {
+	s := SkipSummary{SkipComplete: 1, SkipPatch: 3, SkipOtherPlatform: 8, SkipExcluded: 0}
+	assert.Equal(t, 12, s.Total())
+	assert.Equal(t, "Skipped 12 file(s): 8 other-platform, 3 patches, 1 already complete", s.String())
+
+	// Reasons with the same count keep the order of skipReasonOrder.
+	s = SkipSummary{SkipComplete: 2, SkipOtherLanguage: 2, SkipNoLink: 2}
+	assert.Equal(t, "Skipped 6 file(s): 2 other-language, 2 without download link, 2 already complete", s.String())
+}
+
+func TestSkipCounter(t *testing.T) {
+	var nilCounter *skipCounter
+	nilCounter.add(SkipPatch, 1)
+
+	c := newSkipCounter()
+	c.add(SkipPatch, 2)
+	c.add(SkipPatch, 1)
+	c.add(SkipNoLink, 0)
+	c.add("", 1)
+	s := c.summary()
+	c.add(SkipPatch, 1)
+	assert.Equal(t, SkipSummary{SkipPatch: 3}, s, "the summary is a copy")
+}
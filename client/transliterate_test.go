@@ -0,0 +1,26 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestASCIITitle(t *testing.T) {
+	tests := map[string]string{
+		"The Witcher 3":           "The Witcher 3",
+		"Pokémon":                 "Pokemon",
+		"Poke\u0301mon":           "Pokemon", // decomposed é
+		"Ведьмак":                 "Vedmak",
+		"Die Gilde: Gold-Edition": "Die Gilde: Gold-Edition",
+		"Größenwahn":              "Groessenwahn",
+		"Łódź™":                   "Lodz™",
+		"ファイナルファンタジー":             "ファイナルファンタジー",
+	}
+	for title, want := range tests {
+		assert.Equal(t, want, ASCIITitle(title), title)
+	}
+
+	assert.Equal(t, "vedmak-3", GameFolderName(DefaultFolderTemplate, ASCIITitle("Ведьмак 3"), 1))
+	assert.Equal(t, "1", GameFolderName(DefaultFolderTemplate, "Ведьмак", 1))
+}
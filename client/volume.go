@@ -0,0 +1,42 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+var (
+	// ErrVolumeFull means that the volume of the download directory has no space left.
+	ErrVolumeFull = errors.New("the target volume is full")
+	// ErrVolumeReadOnly means that the volume of the download directory cannot be written to.
+	ErrVolumeReadOnly = errors.New("the target volume is read-only")
+)
+
+// WrapVolumeError turns an error of writing path into ErrVolumeFull or ErrVolumeReadOnly, with a hint on what to
+// do, if it was caused by a full or read-only volume. Other errors are returned as they are.
+func WrapVolumeError(path string, err error) error {
+	switch {
+	case err == nil || errors.Is(err, ErrVolumeFull) || errors.Is(err, ErrVolumeReadOnly):
+		return err
+	case isErrno(err, diskFullErrnos):
+		return fmt.Errorf("%w: cannot write %s; free some space or choose another download directory: %w", ErrVolumeFull, path, err)
+	case isErrno(err, readOnlyErrnos):
+		return fmt.Errorf("%w: cannot write %s; choose a writable download directory: %w", ErrVolumeReadOnly, path, err)
+	default:
+		return err
+	}
+}
+
+func isErrno(err error, errnos []syscall.Errno) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	for _, e := range errnos {
+		if errno == e {
+			return true
+		}
+	}
+	return false
+}
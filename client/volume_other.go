@@ -0,0 +1,10 @@
+//go:build !windows
+
+package client
+
+import "syscall"
+
+var (
+	diskFullErrnos = []syscall.Errno{syscall.ENOSPC, syscall.EDQUOT}
+	readOnlyErrnos = []syscall.Errno{syscall.EROFS}
+)
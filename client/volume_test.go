@@ -0,0 +1,28 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapVolumeError(t *testing.T) {
+	full := &os.PathError{Op: "write", Path: "/games/setup.exe", Err: diskFullErrnos[0]}
+	err := WrapVolumeError("/games/setup.exe", fmt.Errorf("copy failed: %w", full))
+	assert.ErrorIs(t, err, ErrVolumeFull)
+	assert.ErrorIs(t, err, full, "the original error is kept")
+	assert.Contains(t, err.Error(), "free some space")
+	assert.Same(t, err, WrapVolumeError("/games/setup.exe", err), "an error is wrapped only once")
+
+	readOnly := &os.PathError{Op: "mkdir", Path: "/games", Err: readOnlyErrnos[0]}
+	err = WrapVolumeError("/games", readOnly)
+	assert.ErrorIs(t, err, ErrVolumeReadOnly)
+	assert.NotErrorIs(t, err, ErrVolumeFull)
+
+	other := errors.New("connection reset")
+	assert.Same(t, other, WrapVolumeError("/games", other))
+	assert.NoError(t, WrapVolumeError("/games", nil))
+}
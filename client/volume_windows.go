@@ -0,0 +1,11 @@
+//go:build windows
+
+package client
+
+import "syscall"
+
+// Windows error codes: ERROR_HANDLE_DISK_FULL, ERROR_DISK_FULL, and ERROR_WRITE_PROTECT.
+var (
+	diskFullErrnos = []syscall.Errno{39, 112}
+	readOnlyErrnos = []syscall.Errno{19}
+)
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/validation"
+	"github.com/spf13/cobra"
+)
+
+func accountCmd(authService *auth.Service) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "account",
+		Short: "Query the GOG.com account without changing the catalogue",
+	}
+	cmd.AddCommand(accountGamesCmd(authService))
+	return cmd
+}
+
+func accountGamesCmd(authService *auth.Service) *cobra.Command {
+	var titles, jsonOutput bool
+	var numThreads int
+	cmd := &cobra.Command{
+		Use:         "games",
+		Short:       "List the IDs of the games owned by the account",
+		Annotations: map[string]string{needsAuthAnnotation: ""},
+		Long: "List the IDs of the games owned by the GOG account, one per line, without reading or changing the catalogue.\n" +
+			"With --titles, the details of every game are fetched to list its title too, which takes one request per game.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if e := listOwnedGames(cmd, authService, titles, numThreads, jsonOutput); e != nil {
+				reportCliErr(cmd, e)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&titles, "titles", false, "Also fetch and list the title of every game")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the games as a JSON array of objects with id (and title)")
+	addThreadsFlag(cmd, &numThreads, validation.NetworkWorkload, "fetching the titles")
+	return cmd
+}
+
+func listOwnedGames(cmd *cobra.Command, authService *auth.Service, titles bool, numThreads int, jsonOutput bool) *clierr.Error {
+	if e := validateThreadsFlag(numThreads); e != nil {
+		return e
+	}
+	// Check the login first, so a missing or expired login is told apart from a failure to fetch the games.
+	if _, err := authService.RefreshTokenCtx(cmd.Context()); err != nil {
+		return clierr.Wrap(clierr.Auth, "Failed to find or refresh the access token. Did you login?", err)
+	}
+	games, err := client.FetchOwnedGames(cmd.Context(), authService, titles, numThreads)
+	if err != nil {
+		return wrapFetchErr(clierr.Internal, "list the owned games", "Failed to list the owned games", err)
+	}
+
+	if jsonOutput {
+		if games == nil {
+			games = []client.OwnedGame{}
+		}
+		data, err := json.MarshalIndent(games, "", "  ")
+		if err != nil {
+			return clierr.New(clierr.Internal, "Failed to encode the games", err)
+		}
+		cmd.Println(string(data))
+		return nil
+	}
+	for _, game := range games {
+		if titles {
+			cmd.Printf("%d\t%s\n", game.ID, game.Title)
+		} else {
+			cmd.Println(game.ID)
+		}
+	}
+	return nil
+}
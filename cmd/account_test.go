@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOwnedGamesServer(t *testing.T) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user/data/games":
+			_, _ = w.Write([]byte(`{"owned":[30,10,20]}`))
+		case "/account/gameDetails/10.json":
+			_, _ = w.Write([]byte(`{"title":"Game Ten"}`))
+		case "/account/gameDetails/20.json":
+			_, _ = w.Write([]byte(`{"title":"Game Twenty"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("GOGG_EMBED_BASE", server.URL)
+}
+
+func TestAccountGamesCmd_ListsIDs(t *testing.T) {
+	newOwnedGamesServer(t)
+	authService := auth.NewService(&mockTokenStorer{}, &mockTokenRefresher{})
+
+	output, err := captureCombinedOutput(accountGamesCmd(authService))
+	require.NoError(t, err)
+	assert.Equal(t, "10\n20\n30\n", output)
+}
+
+func TestAccountGamesCmd_TitlesAsJSON(t *testing.T) {
+	newOwnedGamesServer(t)
+	authService := auth.NewService(&mockTokenStorer{}, &mockTokenRefresher{})
+
+	output, err := captureCombinedOutput(accountGamesCmd(authService), "--titles", "--json")
+	require.NoError(t, err)
+	var games []client.OwnedGame
+	require.NoError(t, json.Unmarshal([]byte(output), &games))
+	assert.Equal(t, []client.OwnedGame{{ID: 10, Title: "Game Ten"}, {ID: 20, Title: "Game Twenty"}, {ID: 30}}, games,
+		"a game whose details cannot be fetched is listed without a title")
+}
+
+func TestAccountGamesCmd_NotLoggedIn(t *testing.T) {
+	authService := auth.NewService(&mockTokenStorer{getTokenErr: errors.New("no token")}, &mockTokenRefresher{})
+
+	output, err := captureCombinedOutput(accountGamesCmd(authService))
+	require.NoError(t, err)
+	assert.Contains(t, output, "Did you login?")
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/habedi/gogg/pkg/progress"
+	"github.com/habedi/gogg/pkg/validation"
+	"github.com/spf13/cobra"
+)
+
+// auditOptions holds the flags of the audit command.
+type auditOptions struct {
+	language, platformName string
+	extras, dlcs           bool
+	jsonOutput             bool
+}
+
+func auditCmd(repo db.GameRepository) *cobra.Command {
+	var opts auditOptions
+	cmd := &cobra.Command{
+		Use:   "audit [gameID] [dir]",
+		Short: "Compare the downloaded files of a game with the files listed in the catalogue",
+		Long: "Compare the downloaded files of a game with the files listed in the catalogue and report missing files,\n" +
+			"unexpected files (like leftovers of an old version), and files whose size does not match.\n" +
+			"The directory can be the download directory or the game's own folder. The catalogue lists rounded sizes\n" +
+			"and no installer file names, so installers are matched by version and approximate size.",
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			gameID, e := parseGameIDArg(args[0])
+			if e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			if e := runAudit(cmd, repo, gameID, args[1], opts); e != nil {
+				reportCliErr(cmd, e)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&opts.language, "lang", "l", "en", "Game language to expect [en, fr, de, es, it, ru, pl, pt-BR, zh-Hans, ja, ko, ...]; all means all languages")
+	cmd.Flags().StringVarP(&opts.platformName, "platform", "p", "windows", "Platform to expect [all, auto, windows, mac, linux]; auto means the platform of this machine")
+	cmd.Flags().BoolVarP(&opts.extras, "extras", "e", true, "Expect extra content files? [true, false]")
+	cmd.Flags().BoolVarP(&opts.dlcs, "dlcs", "d", true, "Expect DLC files? [true, false]")
+	cmd.Flags().BoolVar(&opts.jsonOutput, "json", false, "Print the report as JSON")
+	return cmd
+}
+
+func runAudit(cmd *cobra.Command, repo db.GameRepository, gameID int, dir string, opts auditOptions) *clierr.Error {
+	if err := validation.ValidatePlatform(opts.platformName); err != nil {
+		return clierr.New(clierr.Validation, "Invalid platform", err)
+	}
+	opts.platformName = validation.ResolvePlatform(opts.platformName)
+	languageFullName, ok := client.LanguageFilter(opts.language)
+	if !ok {
+		return clierr.New(clierr.Validation, "Invalid language code", nil)
+	}
+
+	game, err := repo.GetByID(cmd.Context(), gameID)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Failed to fetch game info", err)
+	}
+	if game == nil {
+		return clierr.New(clierr.NotFound, "Game not found", nil)
+	}
+	gameData, err := client.ParseGameData(game.Data)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Failed to parse game data", err)
+	}
+
+	root := auditRoot(dir, gameData.Title, gameID)
+	expected := gameData.ExpectedFiles(languageFullName, opts.platformName, opts.extras, opts.dlcs)
+	report, err := operations.AuditGameFiles(root, expected)
+	if err != nil {
+		return clierr.New(clierr.NotFound, "Failed to read the game directory", err)
+	}
+
+	if opts.jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return clierr.New(clierr.Internal, "Failed to encode the report", err)
+		}
+		cmd.Println(string(data))
+		return nil
+	}
+	printAuditReport(cmd, gameData.Title, report)
+	return nil
+}
+
+// auditRoot returns the game's folder inside dir if it exists, and dir itself otherwise. The folder may be named
+// after the title, or after the title and the product ID as with --folder-id, the title may be written in
+// ASCII as with --ascii-titles, and in either title style of --title-style.
+func auditRoot(dir, title string, id int) string {
+	for _, style := range client.TitleStyles {
+		for _, t := range []string{title, client.ASCIITitle(title)} {
+			for _, template := range []string{client.DefaultFolderTemplate, "{title}-{id}"} {
+				gameDir := filepath.Join(dir, client.GameFolderNameStyle(template, t, id, style))
+				if info, err := os.Stat(gameDir); err == nil && info.IsDir() {
+					return gameDir
+				}
+			}
+		}
+	}
+	return dir
+}
+
+func printAuditReport(cmd *cobra.Command, title string, report operations.AuditReport) {
+	cmd.Printf("Audit of %s in %s\n", title, report.Root)
+	cmd.Printf("Matched: %d file(s)\n", len(report.Matched))
+
+	if len(report.Missing) > 0 {
+		cmd.Printf("Missing (%d):\n", len(report.Missing))
+		for _, e := range report.Missing {
+			cmd.Printf("  - %s [%s], about %s\n", e.Name, e.Component, progress.FormatBytes(e.ExpectedSize))
+		}
+	}
+	if len(report.SizeMismatches) > 0 {
+		cmd.Printf("Size mismatches (%d):\n", len(report.SizeMismatches))
+		for _, e := range report.SizeMismatches {
+			cmd.Printf("  - %s (%s): expected about %s, found %s\n", e.Path, e.Name, progress.FormatBytes(e.ExpectedSize), progress.FormatBytes(e.ActualSize))
+		}
+	}
+	if len(report.Unexpected) > 0 {
+		cmd.Printf("Unexpected (%d):\n", len(report.Unexpected))
+		for _, e := range report.Unexpected {
+			cmd.Printf("  - %s (%s)\n", e.Path, progress.FormatBytes(e.ActualSize))
+		}
+	}
+	if report.Clean() {
+		cmd.Println("No problems found.")
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const auditGameData = `{"title":"Audit Game","downloads":[["English",{"windows":[` +
+	`{"manualUrl":"/downloads/audit_game/en1installer0","name":"Audit Game","version":"1.1","size":"2 MB"}]}]],` +
+	`"extras":[{"name":"Manual","size":"1 MB","manualUrl":"/downloads/audit_game/manual.pdf"},` +
+	`{"name":"Soundtrack","size":"3 MB","manualUrl":"/downloads/audit_game/soundtrack.zip"}]}`
+
+func setupAuditFixture(t *testing.T) string {
+	t.Helper()
+	cleanDBTables(t)
+	addTestGame(t, db.NewGameRepository(db.GetDB()), 40, "Audit Game", auditGameData)
+
+	downloadDir := t.TempDir()
+	gameDir := filepath.Join(downloadDir, "audit-game")
+	for name, size := range map[string]int64{
+		"setup_audit_game_1.1.exe": 2 << 20,
+		"setup_audit_game_1.0.exe": 5,
+		"extras/manual.pdf":        1 << 20,
+		"metadata.json":            10,
+		"old_patch.zip":            5,
+	} {
+		path := filepath.Join(gameDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0o644))
+	}
+	return downloadDir
+}
+
+func TestAuditCmd_ReportsProblems(t *testing.T) {
+	downloadDir := setupAuditFixture(t)
+
+	output, err := captureCombinedOutput(auditCmd(db.NewGameRepository(db.GetDB())), "40", downloadDir)
+	require.NoError(t, err)
+	assert.Contains(t, output, "Matched: 2 file(s)")
+	assert.Contains(t, output, "Missing (1):")
+	assert.Contains(t, output, "Soundtrack [Audit Game]")
+	assert.Contains(t, output, "Unexpected (2):")
+	assert.Contains(t, output, "setup_audit_game_1.0.exe")
+	assert.NotContains(t, output, "metadata.json")
+	assert.NotContains(t, output, "No problems found.")
+}
+
+func TestAuditCmd_JSON(t *testing.T) {
+	downloadDir := setupAuditFixture(t)
+
+	output, err := captureCombinedOutput(auditCmd(db.NewGameRepository(db.GetDB())), "40", filepath.Join(downloadDir, "audit-game"), "--json", "--extras=false")
+	require.NoError(t, err)
+
+	var report operations.AuditReport
+	require.NoError(t, json.Unmarshal([]byte(output), &report))
+	assert.Len(t, report.Matched, 1)
+	assert.Empty(t, report.Missing)
+	assert.Empty(t, report.SizeMismatches)
+	assert.Len(t, report.Unexpected, 3)
+}
+
+func TestAuditCmd_GameNotFound(t *testing.T) {
+	cleanDBTables(t)
+	output, err := captureCombinedOutput(auditCmd(db.NewGameRepository(db.GetDB())), "999", t.TempDir())
+	require.NoError(t, err)
+	assert.Contains(t, output, "Game not found")
+}
+
+func TestInfoCmd_DiffInstalled(t *testing.T) {
+	downloadDir := setupAuditFixture(t)
+	oldData := strings.Replace(auditGameData, `"version":"1.1"`, `"version":"1.0"`, 1)
+	require.NoError(t, os.WriteFile(filepath.Join(downloadDir, "audit-game", "metadata.json"), []byte(oldData), 0o644))
+	repo := db.NewGameRepository(db.GetDB())
+
+	output, err := captureCombinedOutput(infoCmd(repo), "40", "--diff-installed", downloadDir)
+	require.NoError(t, err)
+	assert.Contains(t, output, "OUTDATED")
+	assert.Contains(t, output, "MISSING")
+	assert.Contains(t, output, "Soundtrack")
+	assert.Contains(t, output, "1 outdated, 1 missing, 0 with a size mismatch, 2 unexpected, 1 up to date")
+
+	output, err = captureCombinedOutput(infoCmd(repo), "40", "--diff-installed", downloadDir, "--json")
+	require.NoError(t, err)
+	var report operations.InstalledReport
+	require.NoError(t, json.Unmarshal([]byte(output), &report))
+	require.NotEmpty(t, report.Files)
+	assert.Equal(t, operations.InstalledMissing, report.Files[0].Status)
+	assert.Equal(t, operations.InstalledOutdated, report.Files[1].Status)
+	assert.Equal(t, "1.0", report.Files[1].InstalledVersion)
+	assert.Equal(t, "1.1", report.Files[1].ExpectedVersion)
+}
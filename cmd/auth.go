@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/spf13/cobra"
+)
+
+func authCmd(authService *auth.Service) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage the login to GOG.com",
+	}
+	cmd.AddCommand(authStatusCmd(authService), authExportCmd(authService), authImportCmd(authService))
+	return cmd
+}
+
+func authStatusCmd(authService *auth.Service) *cobra.Command {
+	return &cobra.Command{
+		Use:         "status",
+		Short:       "Check whether the stored login is still accepted by GOG.com",
+		Annotations: map[string]string{needsNetworkAnnotation: ""},
+		Long:        "Check the stored access token with GOG.com without refreshing it or downloading anything",
+		Args:        cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if e := checkAuthStatus(cmd, authService); e != nil {
+				reportCliErr(cmd, e)
+			}
+		},
+	}
+}
+
+func checkAuthStatus(cmd *cobra.Command, authService *auth.Service) *clierr.Error {
+	err := authService.Validate(context.Background())
+	switch {
+	case err == nil:
+		cmd.Println("Logged in. GOG accepted the access token.")
+		return nil
+	case errors.Is(err, auth.ErrTokenExpired):
+		cmd.Println("Logged in, but the access token has expired. It will be refreshed the next time it is used.")
+		return nil
+	case errors.Is(err, auth.ErrNotLoggedIn):
+		return clierr.New(clierr.Auth, "Not logged in. Run 'gogg login' first", err)
+	case errors.Is(err, auth.ErrTokenRejected):
+		return clierr.New(clierr.Auth, "GOG rejected the access token. Run 'gogg login' again", err)
+	default:
+		return clierr.Wrap(clierr.Internal, "Failed to check the access token with GOG", err)
+	}
+}
+
+// tokenPassphraseEnv is the environment variable that holds the passphrase of an encrypted token file, for
+// exporting and importing without a terminal.
+const tokenPassphraseEnv = "GOGG_TOKEN_PASSPHRASE"
+
+// readPassphrase reads a passphrase without echoing it. It is a variable so tests can override it.
+var readPassphrase = promptForPassword
+
+// tokenPassphrase returns the passphrase of a token file from GOGG_TOKEN_PASSPHRASE, or asks for it if the CLI
+// runs interactively. With confirm, it is asked twice, so a typo does not lock the file.
+func tokenPassphrase(confirm bool) (string, *clierr.Error) {
+	if p := os.Getenv(tokenPassphraseEnv); p != "" {
+		return p, nil
+	}
+	if !stdinIsTerminal() {
+		return "", clierr.New(clierr.Validation, "A passphrase is needed; set "+tokenPassphraseEnv+" when not running in a terminal", nil)
+	}
+	passphrase := readPassphrase("Passphrase: ")
+	if passphrase == "" {
+		return "", clierr.New(clierr.Validation, "The passphrase must not be empty", nil)
+	}
+	if confirm && readPassphrase("Repeat the passphrase: ") != passphrase {
+		return "", clierr.New(clierr.Validation, "The passphrases do not match", nil)
+	}
+	return passphrase, nil
+}
+
+func authExportCmd(authService *auth.Service) *cobra.Command {
+	var encrypt bool
+	cmd := &cobra.Command{
+		Use:   "export [file]",
+		Short: "Write the stored login to a file, to import it on another machine",
+		Long: "Write the stored login to a file that 'gogg auth import' reads on another machine, for example to log in\n" +
+			"on a desktop and download on a server without a browser. Anyone with the file can use the GOG account, so\n" +
+			"keep it private, encrypt it with --encrypt, and delete it once it has been imported.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if e := exportToken(cmd, authService, args[0], encrypt); e != nil {
+				reportCliErr(cmd, e)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&encrypt, "encrypt", false, "Encrypt the file with a passphrase, asked for or taken from "+tokenPassphraseEnv)
+	return cmd
+}
+
+// writePrivateFile writes data to path so that only the owner may read it, as a token file gives access to the
+// account. A file that already exists is made private before the token is written into it.
+func writePrivateFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := f.Chmod(0o600); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func exportToken(cmd *cobra.Command, authService *auth.Service, path string, encrypt bool) *clierr.Error {
+	token, err := authService.Storer.GetTokenRecord()
+	if err != nil {
+		return clierr.New(clierr.Internal, "Failed to read the stored login", err)
+	}
+	if token == nil || token.RefreshToken == "" {
+		return clierr.New(clierr.Auth, "Not logged in. Run 'gogg login' first", auth.ErrNotLoggedIn)
+	}
+	var passphrase string
+	if encrypt {
+		var e *clierr.Error
+		if passphrase, e = tokenPassphrase(true); e != nil {
+			return e
+		}
+	}
+	data, err := auth.EncodeTokenFile(token, passphrase)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Failed to encode the login", err)
+	}
+	if _, err := os.Stat(path); err == nil && !confirm(fmt.Sprintf("%s already exists. Replace it? [y/N]: ", path)) {
+		cmd.Println("Nothing was written.")
+		return nil
+	}
+	if err := writePrivateFile(path, data); err != nil {
+		return clierr.New(clierr.Internal, "Failed to write the token file", err)
+	}
+	cmd.Printf("Wrote the login to %s. Import it on the other machine with 'gogg auth import %s'.\n", path, path)
+	if encrypt {
+		cmd.Println("The file is encrypted; the same passphrase is needed to import it.")
+	} else {
+		cmd.Println("Warning: the file is not encrypted, and anyone who can read it can use your GOG account. " +
+			"Keep it private and delete it once it has been imported.")
+	}
+	return nil
+}
+
+func authImportCmd(authService *auth.Service) *cobra.Command {
+	var fromStdin bool
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Replace the stored login with one written by 'gogg auth export'",
+		Long: "Replace the stored login with one written by 'gogg auth export' on another machine. An encrypted file\n" +
+			"needs its passphrase, which is asked for or taken from " + tokenPassphraseEnv + ".\n" +
+			"With --stdin, the token file is read from a pipe instead, so it is neither in a file nor on the command line.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if fromStdin {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			var data []byte
+			var e *clierr.Error
+			if fromStdin {
+				data, e = readTokenFromStdin(cmd)
+			} else {
+				data, e = readTokenFile(args[0])
+			}
+			if e == nil {
+				e = importToken(cmd, authService, data)
+			}
+			if e != nil {
+				reportCliErr(cmd, e)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&fromStdin, "stdin", false, "Read the token file from stdin, like 'gogg auth import --stdin < token.json'; an encrypted one needs "+tokenPassphraseEnv)
+	return cmd
+}
+
+func readTokenFile(path string) ([]byte, *clierr.Error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, clierr.New(clierr.NotFound, "Failed to read the token file", err)
+	}
+	return data, nil
+}
+
+// readTokenFromStdin reads a token file piped to the command. Stdin must not be a terminal, where a pasted token
+// would be shown on the screen.
+func readTokenFromStdin(cmd *cobra.Command) ([]byte, *clierr.Error) {
+	if stdinIsTerminal() {
+		return nil, clierr.New(clierr.Validation, "--stdin reads the token file from a pipe, like 'gogg auth import --stdin < token.json'; "+
+			"to import from a terminal, give the path of the file instead", nil)
+	}
+	data, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return nil, clierr.New(clierr.Internal, "Failed to read the token file from stdin", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, clierr.New(clierr.Validation, "No token file was piped to stdin", nil)
+	}
+	return data, nil
+}
+
+func importToken(cmd *cobra.Command, authService *auth.Service, data []byte) *clierr.Error {
+	var passphrase string
+	if auth.TokenFileEncrypted(data) {
+		var e *clierr.Error
+		if passphrase, e = tokenPassphrase(false); e != nil {
+			return e
+		}
+	}
+	token, err := auth.DecodeTokenFile(data, passphrase)
+	if err != nil {
+		if errors.Is(err, auth.ErrWrongPassphrase) {
+			return clierr.New(clierr.Auth, "Failed to decrypt the token file: wrong passphrase", err)
+		}
+		return clierr.New(clierr.Validation, "Invalid token file: "+err.Error(), err)
+	}
+
+	if existing, err := authService.Storer.GetTokenRecord(); err == nil && existing != nil && existing.RefreshToken != "" {
+		if !confirm("Replace the stored login with the imported one? [y/N]: ") {
+			cmd.Println("The stored login was kept.")
+			return nil
+		}
+	}
+	if err := authService.Storer.UpsertTokenRecord(token); err != nil {
+		return clierr.New(clierr.Internal, "Failed to store the imported login", err)
+	}
+	cmd.Println("Imported the login. Check it with 'gogg auth status', and delete the token file if it is no longer needed.")
+	return nil
+}
+
+// needsAuthAnnotation marks the commands that use the GOG login, so that a missing or expired login is warned
+// about before they start. They use the network too.
+const needsAuthAnnotation = "gogg/needs-auth"
+
+// needsNetworkAnnotation marks the commands that use the network without the login of the catalogue, like login.
+const needsNetworkAnnotation = "gogg/needs-network"
+
+// offline is set by the global --offline flag.
+var offline bool
+
+// checkOffline returns an error if cmd needs the network and --offline is set. Only the annotated commands make
+// network requests, so all others run unchanged from the local catalogue and files.
+func checkOffline(cmd *cobra.Command) *clierr.Error {
+	if !offline {
+		return nil
+	}
+	_, usesLogin := cmd.Annotations[needsAuthAnnotation]
+	_, usesNetwork := cmd.Annotations[needsNetworkAnnotation]
+	if usesLogin || usesNetwork {
+		return clierr.New(clierr.Validation,
+			fmt.Sprintf("'%s' needs to connect to GOG and cannot be used with --offline", cmd.CommandPath()), nil)
+	}
+	return nil
+}
+
+// warnIfSessionExpired prints a warning if cmd uses the login and the login is missing or can no longer be
+// refreshed. The command still runs and reports its own error; the warning tells up front what to do about it.
+func warnIfSessionExpired(cmd *cobra.Command, authService *auth.Service) {
+	if _, ok := cmd.Annotations[needsAuthAnnotation]; !ok {
+		return
+	}
+	err := authService.CheckSession(cmd.Context())
+	switch {
+	case errors.Is(err, auth.ErrNotLoggedIn):
+		cmd.PrintErrln("Warning: not logged in. Run 'gogg login' first.")
+	case errors.Is(err, auth.ErrSessionExpired):
+		cmd.PrintErrln("Warning: the GOG session has expired. Run 'gogg login' again.")
+	}
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type expiredTokenStorer struct{}
+
+func (expiredTokenStorer) GetTokenRecord() (*db.Token, error) {
+	return &db.Token{
+		AccessToken:  "old-access-token",
+		RefreshToken: "refresh-token",
+		ExpiresAt:    time.Now().Add(-time.Hour).Format(time.RFC3339),
+	}, nil
+}
+
+func (expiredTokenStorer) UpsertTokenRecord(token *db.Token) error { return nil }
+
+func TestAuthStatus_ExpiredTokenIsNotAnError(t *testing.T) {
+	setLastCliErr(nil)
+	t.Cleanup(func() { setLastCliErr(nil) })
+
+	output, err := captureCombinedOutput(authStatusCmd(auth.NewService(expiredTokenStorer{}, &mockTokenRefresher{})))
+	require.NoError(t, err)
+	assert.Contains(t, output, "the access token has expired")
+	assert.Nil(t, getLastCliErr())
+}
+
+type failingTokenRefresher struct{}
+
+func (failingTokenRefresher) PerformTokenRefresh(refreshToken string) (string, string, int64, error) {
+	return "", "", 0, errors.New("invalid_grant")
+}
+
+func TestWarnIfSessionExpired(t *testing.T) {
+	run := func(needsAuth bool, service *auth.Service) string {
+		cmd := &cobra.Command{Use: "test"}
+		if needsAuth {
+			cmd.Annotations = map[string]string{needsAuthAnnotation: ""}
+		}
+		buf := new(bytes.Buffer)
+		cmd.SetErr(buf)
+		cmd.SetContext(context.Background())
+		warnIfSessionExpired(cmd, service)
+		return buf.String()
+	}
+
+	expired := auth.NewService(expiredTokenStorer{}, failingTokenRefresher{})
+	assert.Contains(t, run(true, expired), "the GOG session has expired")
+	assert.Empty(t, run(false, expired), "commands that do not use the login are not checked")
+	assert.Empty(t, run(true, auth.NewService(expiredTokenStorer{}, &mockTokenRefresher{})),
+		"a token that can be refreshed is fine")
+	notLoggedIn := auth.NewService(&mockTokenStorer{}, &mockTokenRefresher{})
+	assert.Contains(t, run(true, notLoggedIn), "not logged in")
+}
+
+func TestCheckOffline(t *testing.T) {
+	t.Cleanup(func() { offline = false })
+	root := createRootCmd(auth.NewService(&mockTokenStorer{}, &mockTokenRefresher{}), nil, db.NewGameRepository(db.GetDB()))
+	find := func(path ...string) *cobra.Command {
+		cmd, _, err := root.Find(path)
+		require.NoError(t, err)
+		return cmd
+	}
+	online := [][]string{{"download"}, {"mirror"}, {"catalogue", "refresh"}, {"account", "games"}, {"login"}, {"auth", "status"}}
+	local := [][]string{{"catalogue", "list"}, {"catalogue", "search"}, {"catalogue", "info"}, {"catalogue", "export"},
+		{"file", "size"}, {"audit"}, {"clean"}, {"auth", "export"}, {"auth", "import"}, {"gui"}}
+
+	offline = false
+	for _, path := range online {
+		assert.Nil(t, checkOffline(find(path...)), path)
+	}
+
+	offline = true
+	for _, path := range online {
+		e := checkOffline(find(path...))
+		require.NotNil(t, e, path)
+		assert.Equal(t, clierr.Validation, e.Type)
+		assert.Contains(t, e.Message, "--offline")
+	}
+	for _, path := range local {
+		assert.Nil(t, checkOffline(find(path...)), path)
+	}
+}
+
+func TestAuthExport_OverwriteMakesFilePrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+	origTerminal := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	defer func() { stdinIsTerminal = origTerminal }()
+
+	path := filepath.Join(t.TempDir(), "token.json")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+	require.NoError(t, os.Chmod(path, 0o644))
+
+	source := &memoryTokenStorer{token: &db.Token{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: "2030-01-01T00:00:00Z"}}
+	_, err := captureCombinedOutput(authExportCmd(auth.NewService(source, &mockTokenRefresher{})), path)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "a replaced token file must not stay readable by others")
+}
+
+// memoryTokenStorer keeps the token in memory.
+type memoryTokenStorer struct{ token *db.Token }
+
+func (m *memoryTokenStorer) GetTokenRecord() (*db.Token, error) { return m.token, nil }
+func (m *memoryTokenStorer) UpsertTokenRecord(token *db.Token) error {
+	m.token = token
+	return nil
+}
+
+func TestAuthExportImport(t *testing.T) {
+	origTerminal := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	defer func() { stdinIsTerminal = origTerminal }()
+
+	source := &memoryTokenStorer{token: &db.Token{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: "2030-01-01T00:00:00Z"}}
+	path := filepath.Join(t.TempDir(), "token.json")
+
+	t.Setenv(tokenPassphraseEnv, "secret")
+	output, err := captureCombinedOutput(authExportCmd(auth.NewService(source, &mockTokenRefresher{})), path, "--encrypt")
+	require.NoError(t, err)
+	assert.Contains(t, output, "The file is encrypted")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+
+	target := &memoryTokenStorer{}
+	t.Setenv(tokenPassphraseEnv, "wrong")
+	output, err = captureCombinedOutput(authImportCmd(auth.NewService(target, &mockTokenRefresher{})), path)
+	require.NoError(t, err)
+	assert.Contains(t, output, "wrong passphrase")
+	assert.Nil(t, target.token)
+
+	t.Setenv(tokenPassphraseEnv, "secret")
+	output, err = captureCombinedOutput(authImportCmd(auth.NewService(target, &mockTokenRefresher{})), path)
+	require.NoError(t, err)
+	assert.Contains(t, output, "Imported the login")
+	require.NotNil(t, target.token)
+	assert.Equal(t, "refresh", target.token.RefreshToken)
+}
+
+func TestAuthExport_WarnsAboutPlainFiles(t *testing.T) {
+	source := &memoryTokenStorer{token: &db.Token{RefreshToken: "refresh"}}
+	output, err := captureCombinedOutput(authExportCmd(auth.NewService(source, &mockTokenRefresher{})),
+		filepath.Join(t.TempDir(), "token.json"))
+	require.NoError(t, err)
+	assert.Contains(t, output, "the file is not encrypted")
+
+	output, err = captureCombinedOutput(authExportCmd(auth.NewService(&memoryTokenStorer{}, &mockTokenRefresher{})),
+		filepath.Join(t.TempDir(), "token.json"))
+	require.NoError(t, err)
+	assert.Contains(t, output, "Not logged in")
+}
+
+func TestAuthImport_Stdin(t *testing.T) {
+	origTerminal := stdinIsTerminal
+	defer func() { stdinIsTerminal = origTerminal }()
+
+	source := &db.Token{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: "2030-01-01T00:00:00Z"}
+	data, err := auth.EncodeTokenFile(source, "")
+	require.NoError(t, err)
+
+	stdinIsTerminal = func() bool { return false }
+	target := &memoryTokenStorer{}
+	cmd := authImportCmd(auth.NewService(target, &mockTokenRefresher{}))
+	cmd.SetIn(bytes.NewReader(data))
+	output, err := captureCombinedOutput(cmd, "--stdin")
+	require.NoError(t, err)
+	assert.Contains(t, output, "Imported the login")
+	require.NotNil(t, target.token)
+	assert.Equal(t, "refresh", target.token.RefreshToken)
+
+	cmd = authImportCmd(auth.NewService(&memoryTokenStorer{}, &mockTokenRefresher{}))
+	cmd.SetIn(bytes.NewReader(nil))
+	output, err = captureCombinedOutput(cmd, "--stdin")
+	require.NoError(t, err)
+	assert.Contains(t, output, "No token file was piped to stdin")
+
+	// A token pasted into a terminal would be shown on the screen.
+	stdinIsTerminal = func() bool { return true }
+	output, err = captureCombinedOutput(authImportCmd(auth.NewService(&memoryTokenStorer{}, &mockTokenRefresher{})), "--stdin")
+	require.NoError(t, err)
+	assert.Contains(t, output, "--stdin reads the token file from a pipe")
+
+	_, err = captureCombinedOutput(authImportCmd(auth.NewService(&memoryTokenStorer{}, &mockTokenRefresher{})), "--stdin", "token.json")
+	assert.Error(t, err, "--stdin takes no file")
+}
+
+func TestReadPasswordFromStdin(t *testing.T) {
+	origTerminal, origRead := stdinIsTerminal, readPassphrase
+	defer func() { stdinIsTerminal, readPassphrase = origTerminal, origRead }()
+
+	stdinIsTerminal = func() bool { return false }
+	cmd := &cobra.Command{}
+	cmd.SetIn(bytes.NewBufferString(" pass word \r\nsecond line\n"))
+	password, err := readPasswordFromStdin(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, " pass word ", password, "only the line break is removed")
+
+	cmd.SetIn(bytes.NewBufferString("no line break"))
+	password, err = readPasswordFromStdin(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, "no line break", password)
+
+	stdinIsTerminal = func() bool { return true }
+	readPassphrase = func(string) string { return "typed" }
+	password, err = readPasswordFromStdin(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, "typed", password, "a terminal is read without echo")
+}
+
+func TestLoginCmd_PasswordStdinNeedsUsername(t *testing.T) {
+	t.Cleanup(func() { setLastCliErr(nil) })
+	output, err := captureCombinedOutput(loginCmd(nil), "--password-stdin")
+	require.NoError(t, err)
+	assert.Contains(t, output, "--password-stdin needs the username in --username")
+	require.NotNil(t, getLastCliErr())
+	assert.Equal(t, clierr.Validation, getLastCliErr().Type)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/spf13/cobra"
+)
+
+func backupCmd() *cobra.Command {
+	var includeTokens bool
+	cmd := &cobra.Command{
+		Use:   "backup [file.zip]",
+		Short: "Back up the catalogue, caches, and GUI history to a zip file",
+		Long: "Back up Gogg's state to a zip file: a snapshot of the database (the game catalogue), the JSON files in\n" +
+			"the data directory (like the hash cache), and the GUI's files (like the download history and preferences).\n" +
+			"The authentication tokens are not included unless --include-tokens is given.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			manifest, err := operations.CreateBackup(cmd.Context(), args[0], includeTokens)
+			if err != nil {
+				reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to create the backup: "+err.Error(), err))
+				return
+			}
+			cmd.Printf("Backed up %d file(s) to %s\n", len(manifest.Files), args[0])
+			if includeTokens {
+				cmd.Println("The backup contains your GOG login tokens; keep it private.")
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&includeTokens, "include-tokens", false, "Include the GOG authentication tokens in the backup? [true, false]")
+	return cmd
+}
+
+func restoreCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore [file.zip]",
+		Short: "Restore the catalogue, caches, and GUI history from a backup",
+		Long: "Restore Gogg's state from a zip file created with the backup command, replacing the current database and files.\n" +
+			"If the backup does not contain authentication tokens, the current tokens are kept.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if !confirm("Replace the current catalogue, caches, and GUI history with the backup? [y/N]: ") {
+				cmd.Println("Restore skipped.")
+				return
+			}
+			manifest, err := operations.RestoreBackup(cmd.Context(), args[0])
+			if err != nil {
+				reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to restore the backup: "+err.Error(), err))
+				return
+			}
+			cmd.Printf("Restored %d file(s) from the backup made on %s\n", len(manifest.Files), manifest.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		},
+	}
+}
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/progress"
+	"github.com/habedi/gogg/pkg/validation"
+	"github.com/rs/zerolog/log"
+)
+
+// batchLogFileName is the name of the batch log kept in the download directory of a `download --all` run.
+const batchLogFileName = "batch_log.json"
+
+const (
+	batchStatusCompleted = "completed"
+	batchStatusFailed    = "failed"
+)
+
+// Orders in which a batch download processes the games.
+const (
+	batchOrderCatalogue = "catalogue"
+	batchOrderName      = "name"
+	batchOrderSizeAsc   = "size-asc"
+	batchOrderSizeDesc  = "size-desc"
+)
+
+var batchOrders = []string{batchOrderCatalogue, batchOrderName, batchOrderSizeAsc, batchOrderSizeDesc}
+
+// batchEntry records the outcome of downloading one game in a batch.
+type batchEntry struct {
+	Title     string    `json:"title"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// batchLog records the outcome of every game of a whole-library download so that a rerun
+// can skip the completed games and retry the failed ones.
+type batchLog struct {
+	path  string
+	Games map[int]*batchEntry `json:"games"`
+}
+
+// loadBatchLog reads the batch log from dir. A missing log yields an empty one.
+func loadBatchLog(dir string) (*batchLog, error) {
+	l := &batchLog{path: filepath.Join(dir, batchLogFileName), Games: make(map[int]*batchEntry)}
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse batch log %s: %w", l.path, err)
+	}
+	if l.Games == nil {
+		l.Games = make(map[int]*batchEntry)
+	}
+	return l, nil
+}
+
+// record stores the outcome of a game and writes the log to disk, so progress survives interruptions.
+func (l *batchLog) record(gameID int, title string, err error) error {
+	entry := &batchEntry{Title: title, Status: batchStatusCompleted, UpdatedAt: time.Now().UTC()}
+	if err != nil {
+		entry.Status = batchStatusFailed
+		entry.Error = err.Error()
+	}
+	l.Games[gameID] = entry
+	return l.save()
+}
+
+func (l *batchLog) save() error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// orderBatchGames sorts games in place for a batch download. Sizes are estimated from the catalogue data
+// with the language, platform, extras, and DLC options of the download; games whose size cannot be
+// estimated count as empty. The catalogue order is kept for ties.
+func orderBatchGames(games []db.Game, order string, opts downloadOptions) error {
+	switch order {
+	case batchOrderCatalogue:
+		return nil
+	case batchOrderName:
+		sort.SliceStable(games, func(i, j int) bool {
+			return strings.ToLower(games[i].Title) < strings.ToLower(games[j].Title)
+		})
+		return nil
+	case batchOrderSizeAsc, batchOrderSizeDesc:
+	default:
+		return fmt.Errorf("unknown order %q; supported orders are %s", order, strings.Join(batchOrders, ", "))
+	}
+
+	language, _ := client.LanguageFilter(opts.language)
+	sizes := make(map[int]int64, len(games))
+	for _, game := range games {
+		data, err := client.ParseCachedGameData(game.ID, game.Data)
+		if err != nil {
+			continue
+		}
+		if size, err := data.EstimateStorageSize(language, opts.platformName, opts.extras, opts.dlcs); err == nil {
+			sizes[game.ID] = size
+		}
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		if order == batchOrderSizeAsc {
+			return sizes[games[i].ID] < sizes[games[j].ID]
+		}
+		return sizes[games[i].ID] > sizes[games[j].ID]
+	})
+	return nil
+}
+
+// prepareBatchOptions checks and completes the options of a download of several games. Options that would make
+// every game fail are rejected once instead of once per game.
+func prepareBatchOptions(opts *downloadOptions) *clierr.Error {
+	if e := validateThreadsFlag(opts.numThreads); e != nil {
+		return e
+	}
+	if err := validation.ValidatePlatform(opts.platformName); err != nil {
+		return clierr.New(clierr.Validation, "Invalid platform", err)
+	}
+	// Resolved here so that ordering by size estimates the installers of the platform actually downloaded.
+	opts.platformName = validation.ResolvePlatform(opts.platformName)
+	if _, ok := client.LanguageFilter(opts.language); !ok {
+		return clierr.New(clierr.Validation, "Invalid language code", nil)
+	}
+	if e := parseModeFlags(opts); e != nil {
+		return e
+	}
+	if e := parseMaxBytesFlag(opts); e != nil {
+		return e
+	}
+	if e := parseFileSizeFlags(opts); e != nil {
+		return e
+	}
+	if e := validateExcludes(opts.excludes); e != nil {
+		return e
+	}
+	if e := validateFileNameStyle(opts.fileNames); e != nil {
+		return e
+	}
+	if e := validateLayout(*opts); e != nil {
+		return e
+	}
+	if e := validateDedupeLink(opts.dedupeLink); e != nil {
+		return e
+	}
+	if e := validateStallTimeout(opts.stallTimeout); e != nil {
+		return e
+	}
+	if e := prepareGamesConcurrency(opts); e != nil {
+		return e
+	}
+	if opts.fileName != "" || opts.fileIndex != 0 {
+		return clierr.New(clierr.Validation, "--file and --file-index cannot be combined with --all or --retry-failed", nil)
+	}
+	return nil
+}
+
+// executeBatchDownload downloads every game in the catalogue to downloadPath in the given order, recording
+// each outcome in the batch log. Games completed in an earlier run are skipped. With retryFailed, only the
+// games that failed in an earlier run are downloaded. It returns an error if the batch could not be started
+// or if any game failed to download.
+func executeBatchDownload(ctx context.Context, authService *auth.Service, downloadPath string, opts downloadOptions, retryFailed bool, order string) *clierr.Error {
+	if e := prepareBatchOptions(&opts); e != nil {
+		return e
+	}
+	if err := os.MkdirAll(downloadPath, opts.modes.Dir); err != nil {
+		return clierr.New(clierr.Internal, "Failed to create download path", err)
+	}
+	ledger, err := loadBatchLog(downloadPath)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Failed to read the batch log", err)
+	}
+
+	games, err := db.NewGameRepository(db.GetDB()).List(ctx)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Unable to list games", err)
+	}
+	if len(games) == 0 {
+		fmt.Println("Game catalogue is empty. Did you refresh the catalogue?")
+		return nil
+	}
+	if err := orderBatchGames(games, order, opts); err != nil {
+		return clierr.New(clierr.Validation, "Invalid download order: "+err.Error(), err)
+	}
+	pinned, e := pinnedGames(ctx)
+	if e != nil {
+		return e
+	}
+
+	var completed, failed, skipped, held int
+	var unreadable, noDownloads, pending []db.Game
+	var volumeFull, budgetReached bool
+	for _, game := range games {
+		entry := ledger.Games[game.ID]
+		if retryFailed && (entry == nil || entry.Status != batchStatusFailed) {
+			continue
+		}
+		if entry != nil && entry.Status == batchStatusCompleted {
+			skipped++
+			continue
+		}
+		if pinned[game.ID] {
+			held++
+			continue
+		}
+
+		// Games whose stored data is corrupt are skipped without stopping the batch.
+		if _, err := client.ParseCachedGameData(game.ID, game.Data); err != nil {
+			log.Warn().Err(err).Int("gameID", game.ID).Msg("Skipping game with unreadable catalogue data")
+			unreadable = append(unreadable, game)
+			err = fmt.Errorf("unreadable catalogue data: %w", err)
+			if opts.report != nil {
+				opts.report.finish(game.ID, game.Title, err)
+			}
+			if err := ledger.record(game.ID, game.Title, err); err != nil {
+				log.Warn().Err(err).Msg("Failed to update the batch log")
+			}
+			continue
+		}
+		// Pre-orders are not recorded, so they are downloaded by the first batch after their release.
+		if hasNoDownloads(game) {
+			noDownloads = append(noDownloads, game)
+			continue
+		}
+		pending = append(pending, game)
+	}
+
+	var mu sync.Mutex
+	forEachGame(ctx, pending, opts.gamesConcurrency, func(gameCtx context.Context, game db.Game) bool {
+		dlErr := executeDownload(gameCtx, authService, game.ID, downloadPath, opts)
+		if opts.report != nil {
+			opts.report.finish(game.ID, game.Title, dlErr)
+		}
+		if dlErr != nil && gameCtx.Err() != nil {
+			// Interrupted runs are not recorded as failures so the game is simply picked up next time.
+			return true
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if dlErr != nil {
+			failed++
+		} else {
+			completed++
+		}
+		if err := ledger.record(game.ID, game.Title, dlErr); err != nil {
+			log.Warn().Err(err).Msg("Failed to update the batch log")
+		}
+		if errors.Is(dlErr, client.ErrVolumeFull) {
+			// Every following game would fail the same way.
+			volumeFull = true
+			return true
+		}
+		if errors.Is(dlErr, client.ErrByteBudgetReached) {
+			budgetReached = true
+			return true
+		}
+		return false
+	})
+	if opts.progress != nil {
+		opts.progress.finish()
+	}
+
+	fmt.Printf("Batch finished: %d completed, %d failed, %d skipped (already completed).\n", completed, failed, skipped)
+	if ctx.Err() != nil {
+		fmt.Println("The batch was interrupted; run the same command again to continue.")
+	}
+	if volumeFull {
+		fmt.Println("The batch was stopped because the target volume is full; free some space and run the same command again to continue.")
+	}
+	if budgetReached {
+		fmt.Printf("The batch was stopped at the download size limit after %s; run the same command again to continue.\n",
+			progress.FormatBytes(opts.byteBudget.Used()))
+	}
+	if len(unreadable) > 0 {
+		fmt.Printf("%d game(s) were skipped because their catalogue data could not be read:\n", len(unreadable))
+		for _, game := range unreadable {
+			fmt.Printf("  - %s (ID %d)\n", game.Title, game.ID)
+		}
+		fmt.Println("Run 'gogg catalogue refresh' to fetch their data again, then use --retry-failed to download them.")
+	}
+	printNoDownloads(noDownloads)
+	if failed > 0 {
+		fmt.Println("Use --retry-failed to download only the games that failed.")
+	}
+	printHeldPins(held)
+	fmt.Printf("Batch log: %s\n", ledger.path)
+	if failed > 0 || len(unreadable) > 0 {
+		return clierr.New(clierr.Download, fmt.Sprintf("%d game(s) failed to download", failed+len(unreadable)), nil)
+	}
+	return nil
+}
+
+// printNoDownloads lists the games that were skipped because they have no file to download yet.
+func printNoDownloads(games []db.Game) {
+	if len(games) == 0 {
+		return
+	}
+	fmt.Printf("%d game(s) were skipped because they have no downloadable content yet, most likely pre-orders of games that are not released:\n", len(games))
+	for _, game := range games {
+		fmt.Printf("  - %s (ID %d)\n", game.Title, game.ID)
+	}
+	fmt.Println("Run 'gogg catalogue refresh' after their release to fetch their files.")
+}
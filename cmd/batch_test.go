@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noLogin has no stored token, so every download attempted with it fails before any network access.
+var noLogin = &auth.Service{Storer: testStorer{}}
+
+func TestBatchLog_RecordAndReload(t *testing.T) {
+	dir := t.TempDir()
+	l, err := loadBatchLog(dir)
+	require.NoError(t, err)
+	assert.Empty(t, l.Games)
+
+	require.NoError(t, l.record(1, "Done Game", nil))
+	require.NoError(t, l.record(2, "Broken Game", errors.New("boom")))
+
+	reloaded, err := loadBatchLog(dir)
+	require.NoError(t, err)
+	require.Len(t, reloaded.Games, 2)
+	assert.Equal(t, batchStatusCompleted, reloaded.Games[1].Status)
+	assert.Equal(t, batchStatusFailed, reloaded.Games[2].Status)
+	assert.Equal(t, "boom", reloaded.Games[2].Error)
+}
+
+func TestExecuteBatchDownload_SkipsCompletedAndRetriesFailed(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 101, "Finished Game", `{}`)
+	addTestGame(t, repo, 102, "Failed Game", `{}`)
+	addTestGame(t, repo, 103, "New Game", `{}`)
+
+	dir := t.TempDir()
+	l, err := loadBatchLog(dir)
+	require.NoError(t, err)
+	require.NoError(t, l.record(101, "Finished Game", nil))
+	require.NoError(t, l.record(102, "Failed Game", errors.New("network")))
+
+	// Without a login every attempted download fails before any network access.
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 1, gamesConcurrency: 1}
+
+	out := captureStdout2(func() {
+		executeBatchDownload(context.Background(), noLogin, dir, opts, true, batchOrderCatalogue)
+	})
+	assert.Contains(t, out, "Batch finished: 0 completed, 1 failed, 0 skipped")
+	l, err = loadBatchLog(dir)
+	require.NoError(t, err)
+	assert.NotContains(t, l.Games, 103, "--retry-failed must only process failed games")
+
+	out = captureStdout2(func() {
+		executeBatchDownload(context.Background(), noLogin, dir, opts, false, batchOrderCatalogue)
+	})
+	assert.Contains(t, out, "Batch finished: 0 completed, 2 failed, 1 skipped")
+	assert.Equal(t, 2, strings.Count(out, "Did you login?"))
+}
+
+func TestExecuteBatchDownload_RejectsInvalidOptionsOnce(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 111, "First Game", `{}`)
+	addTestGame(t, repo, 112, "Second Game", `{}`)
+	dir := t.TempDir()
+
+	for _, opts := range []downloadOptions{
+		{language: "xx", platformName: "windows", numThreads: 1, gamesConcurrency: 1},
+		{language: "en", platformName: "amiga", numThreads: 1, gamesConcurrency: 1},
+	} {
+		var e *clierr.Error
+		out := captureStdout2(func() {
+			e = executeBatchDownload(context.Background(), noLogin, dir, opts, false, batchOrderCatalogue)
+		})
+		require.NotNil(t, e)
+		assert.Equal(t, clierr.Validation, e.Type)
+		assert.NotContains(t, out, "Batch finished", "no game is attempted")
+	}
+	l, err := loadBatchLog(dir)
+	require.NoError(t, err)
+	assert.Empty(t, l.Games)
+}
+
+func TestOrderBatchGames(t *testing.T) {
+	sized := func(title, size string) db.Game {
+		return db.Game{Title: title, Data: `{"title":"` + title + `","downloads":[["English",{"windows":[{"name":"setup","size":"` + size + `"}]}]],"extras":[],"dlcs":[]}`}
+	}
+	games := func() []db.Game {
+		g := []db.Game{sized("medium", "2 GB"), sized("Big", "5 GB"), sized("alpha", "1 GB"), {Title: "Broken", Data: `not json`}}
+		for i := range g {
+			g[i].ID = i + 1
+		}
+		return g
+	}
+	titles := func(games []db.Game) []string {
+		var out []string
+		for _, g := range games {
+			out = append(out, g.Title)
+		}
+		return out
+	}
+	opts := downloadOptions{language: "en", platformName: "windows"}
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{batchOrderCatalogue, []string{"medium", "Big", "alpha", "Broken"}},
+		{batchOrderName, []string{"alpha", "Big", "Broken", "medium"}},
+		{batchOrderSizeAsc, []string{"Broken", "alpha", "medium", "Big"}},
+		{batchOrderSizeDesc, []string{"Big", "medium", "alpha", "Broken"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			g := games()
+			require.NoError(t, orderBatchGames(g, tt.order, opts))
+			assert.Equal(t, tt.want, titles(g))
+		})
+	}
+
+	assert.Error(t, orderBatchGames(games(), "random", opts))
+}
+
+func TestExecuteBatchDownload_SizeOrderWithAutoPlatform(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	// Only the installers of the platform "auto" resolves to have a size, so the order is only right if the
+	// platform is resolved before the games are ordered.
+	platform := validation.ResolvePlatform("auto")
+	sized := func(id int, title, size string) {
+		addTestGame(t, repo, id, title, `{"title":"`+title+`","downloads":[["English",{"`+platform+
+			`":[{"name":"setup","size":"`+size+`"}]}]],"extras":[],"dlcs":[]}`)
+	}
+	sized(121, "Medium", "2 GB")
+	sized(122, "Big", "5 GB")
+	sized(123, "Small", "1 GB")
+	dir := t.TempDir()
+
+	opts := downloadOptions{language: "en", platformName: "auto", numThreads: 1, gamesConcurrency: 1}
+	captureStdout2(func() {
+		executeBatchDownload(context.Background(), noLogin, dir, opts, false, batchOrderSizeAsc)
+	})
+	l, err := loadBatchLog(dir)
+	require.NoError(t, err)
+	require.Len(t, l.Games, 3)
+	ids := []int{121, 122, 123}
+	sort.Slice(ids, func(i, j int) bool { return l.Games[ids[i]].UpdatedAt.Before(l.Games[ids[j]].UpdatedAt) })
+	assert.Equal(t, []int{123, 121, 122}, ids, "games are attempted from the smallest to the biggest")
+}
+
+func TestExecuteBatchDownload_SkipsUnreadableGames(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 201, "Corrupt Game", `{"title": "Corrupt Game", "downloads": [`)
+	addTestGame(t, repo, 202, "Good Game", `{}`)
+
+	dir := t.TempDir()
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 1, gamesConcurrency: 1}
+	var e error
+	out := captureStdout2(func() {
+		if err := executeBatchDownload(context.Background(), noLogin, dir, opts, false, batchOrderCatalogue); err != nil {
+			e = err
+		}
+	})
+
+	// The corrupt game does not stop the batch; the next game is still attempted.
+	assert.Contains(t, out, "Batch finished: 0 completed, 1 failed, 0 skipped")
+	assert.Equal(t, 1, strings.Count(out, "Did you login?"))
+	assert.Contains(t, out, "1 game(s) were skipped because their catalogue data could not be read:")
+	assert.Contains(t, out, "  - Corrupt Game (ID 201)")
+	assert.Contains(t, out, "gogg catalogue refresh")
+	require.Error(t, e)
+	assert.Contains(t, e.Error(), "2 game(s) failed to download")
+
+	l, err := loadBatchLog(dir)
+	require.NoError(t, err)
+	require.Contains(t, l.Games, 201)
+	assert.Equal(t, batchStatusFailed, l.Games[201].Status)
+	assert.Contains(t, l.Games[201].Error, "unreadable catalogue data")
+}
+
+func TestExecuteBatchDownload_SkipsPinnedGames(t *testing.T) {
+	cleanDBTables(t)
+	usePins(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 101, "Pinned Game", `{}`)
+	addTestGame(t, repo, 102, "Other Game", `{}`)
+	require.NoError(t, db.NewPinRepository(db.GetDB()).Pin(context.Background(), 101, "1.0"))
+
+	dir := t.TempDir()
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 1, gamesConcurrency: 1}
+	out := captureStdout2(func() {
+		executeBatchDownload(context.Background(), noLogin, dir, opts, false, batchOrderCatalogue)
+	})
+	assert.Contains(t, out, "Batch finished: 0 completed, 1 failed, 0 skipped")
+	assert.Contains(t, out, "1 pinned game(s) were not updated")
+	l, err := loadBatchLog(dir)
+	require.NoError(t, err)
+	assert.NotContains(t, l.Games, 101)
+}
+
+func TestExecuteBatchDownload_SkipsGamesWithoutDownloads(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 301, "Unreleased Game", unreleasedGameData)
+	addTestGame(t, repo, 302, "Other Game", `{}`)
+
+	dir := t.TempDir()
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 1, gamesConcurrency: 1}
+	var e error
+	out := captureStdout2(func() {
+		if err := executeBatchDownload(context.Background(), noLogin, dir, opts, false, batchOrderCatalogue); err != nil {
+			e = err
+		}
+	})
+	assert.Contains(t, out, "Batch finished: 0 completed, 1 failed, 0 skipped")
+	assert.Contains(t, out, "1 game(s) were skipped because they have no downloadable content yet")
+	assert.Contains(t, out, "  - Unreleased Game (ID 301)")
+	require.Error(t, e)
+	assert.Contains(t, e.Error(), "1 game(s) failed to download", "the pre-order is not a failure")
+
+	l, err := loadBatchLog(dir)
+	require.NoError(t, err)
+	assert.NotContains(t, l.Games, 301, "the pre-order is downloaded by a batch after its release")
+}
@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +19,9 @@ import (
 	"github.com/habedi/gogg/client"
 	"github.com/habedi/gogg/db"
 	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/habedi/gogg/pkg/progress"
+	"github.com/habedi/gogg/pkg/search"
 	"github.com/habedi/gogg/pkg/validation"
 	"github.com/olekukonko/tablewriter"
 	"github.com/rs/zerolog/log"
@@ -30,31 +39,73 @@ func catalogueCmd(authService *auth.Service, gameRepo db.GameRepository) *cobra.
 		listCmd(gameRepo),
 		searchCmd(gameRepo),
 		infoCmd(gameRepo),
+		languagesCmd(gameRepo),
+		platformsCmd(gameRepo),
+		duplicatesCmd(gameRepo),
+		validateCmd(gameRepo),
+		pinCmd(gameRepo),
+		unpinCmd(),
+		pinsCmd(gameRepo),
 		refreshCmd(authService),
 		exportCmd(gameRepo),
+		importCmd(gameRepo),
 	)
 	return cmd
 }
 
 func listCmd(repo db.GameRepository) *cobra.Command {
-	return &cobra.Command{
+	var asciiTitles bool
+	var output string
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "Show the list of games in the catalogue",
-		Long:  "Show the list of all games in the catalogue in a tabular format",
-		Run:   func(cmd *cobra.Command, args []string) { listGames(cmd, repo) },
+		Long:  "Show the list of all games in the catalogue in a tabular format, or as JSON or YAML with --output",
+		Run:   func(cmd *cobra.Command, args []string) { listGames(cmd, repo, asciiTitles, output) },
+	}
+	cmd.Flags().BoolVar(&asciiTitles, "ascii-titles", false, "Show the titles with letters like é or Ж written in ASCII")
+	cmd.Flags().StringVarP(&output, "output", "o", outputTable, "Output format [table, json, yaml]")
+	return cmd
+}
+
+// displayTitle returns the title of a game as shown in a table, written in ASCII if asciiTitles is set.
+func displayTitle(title string, asciiTitles bool) string {
+	title = strings.ReplaceAll(title, "\n", " ")
+	if asciiTitles {
+		return client.ASCIITitle(title)
 	}
+	return title
 }
 
-func listGames(cmd *cobra.Command, repo db.GameRepository) {
+// hasNoDownloads reports whether the details of a game from GOG list no file to download, like for a pre-order
+// of a game that is not released yet. Data without a list of downloads, like that of games whose details were
+// not fetched yet, is not reported.
+func hasNoDownloads(game db.Game) bool {
+	var probe struct {
+		Downloads json.RawMessage `json:"downloads"`
+	}
+	if json.Unmarshal([]byte(game.Data), &probe) != nil || probe.Downloads == nil {
+		return false
+	}
+	parsed, err := client.ParseGameData(game.Data)
+	return err == nil && !parsed.HasFiles()
+}
+
+func listGames(cmd *cobra.Command, repo db.GameRepository, asciiTitles bool, output string) {
+	if e := validateOutputFlag(output, outputTable, outputJSON, outputYAML); e != nil {
+		reportCliErr(cmd, e)
+		return
+	}
 	log.Info().Msg("Listing all games in the catalogue...")
 	games, err := repo.List(cmd.Context())
 	if err != nil {
-		e := clierr.New(clierr.Internal, "Unable to list games", err)
-		cmd.PrintErrln(e.Message)
-		setLastCliErr(e)
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Unable to list games", err))
 		log.Error().Err(err).Msg("Failed to fetch games from the game catalogue.")
 		return
 	}
+	if output != outputTable {
+		printGameEntries(cmd, games, asciiTitles, output)
+		return
+	}
 	if len(games) == 0 {
 		cmd.Println("Game catalogue is empty. Did you refresh the catalogue?")
 		return
@@ -66,8 +117,18 @@ func listGames(cmd *cobra.Command, repo db.GameRepository) {
 	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
 	table.SetAutoWrapText(false)
 	table.SetRowLine(false)
+	var untitled, noDownloads int
 	for i, game := range games {
-		cleanedTitle := strings.ReplaceAll(game.Title, "\n", " ")
+		cleanedTitle := displayTitle(game.Title, asciiTitles)
+		if strings.TrimSpace(game.Title) == "" {
+			// Downloads of such games go into a folder named after the game ID.
+			cleanedTitle = "(missing title)"
+			untitled++
+		}
+		if hasNoDownloads(game) {
+			cleanedTitle += " (no downloadable content)"
+			noDownloads++
+		}
 		table.Append([]string{
 			fmt.Sprintf("%d", i+1),
 			fmt.Sprintf("%d", game.ID),
@@ -75,79 +136,158 @@ func listGames(cmd *cobra.Command, repo db.GameRepository) {
 		})
 	}
 	table.Render()
+	if untitled > 0 {
+		cmd.Printf("%d game(s) have no title, most likely because their data could not be fetched; "+
+			"run 'gogg catalogue refresh' to fetch it again.\n", untitled)
+	}
+	if noDownloads > 0 {
+		cmd.Printf("%d game(s) have no downloadable content yet, most likely pre-orders of games that are not released; "+
+			"'download --all' and 'mirror' skip them until a refresh of the catalogue finds their files.\n", noDownloads)
+	}
 	log.Info().Msgf("Successfully listed %d games in the catalogue.", len(games))
 }
 
+// infoSizeOptions selects the files counted by `catalogue info --size`.
+type infoSizeOptions struct {
+	language, platformName string
+	extras, dlcs           bool
+}
+
 func infoCmd(repo db.GameRepository) *cobra.Command {
-	var updatesOnly bool
+	var updatesOnly, showSize, jsonOutput, filesOnly, page bool
+	var sizeOpts infoSizeOptions
+	var installedDir, output string
+	var fields []string
 	cmd := &cobra.Command{
 		Use:   "info [gameID]",
 		Short: "Show the information about a game in the catalogue",
-		Long:  "Given a game ID, show detailed information about the game with the specified ID in JSON format",
+		Long:  "Given a game ID, show detailed information about the game with the specified ID in JSON or YAML format",
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			gameID, err := strconv.Atoi(args[0])
-			if err != nil {
-				cmd.PrintErrln("Error: Invalid game ID. It must be a number.")
+			gameID, e := parseGameIDArg(args[0])
+			if e != nil {
+				reportCliErr(cmd, e)
 				return
 			}
-			if err := validation.ValidateGameID(gameID); err != nil {
-				cmd.PrintErrln("Error:", err)
+			if e := validateOutputFlag(output, outputJSON, outputYAML); e != nil {
+				reportCliErr(cmd, e)
 				return
 			}
-			showGameInfo(cmd, repo, gameID, updatesOnly)
+			if filesOnly {
+				showGameFiles(cmd, repo, gameID)
+				return
+			}
+			if installedDir != "" {
+				if e := showInstalledDiff(cmd, repo, gameID, installedDir, sizeOpts, jsonOutput); e != nil {
+					reportCliErr(cmd, e)
+				}
+				return
+			}
+			var size *infoSizeOptions
+			if showSize {
+				size = &sizeOpts
+			}
+			printPaged(cmd, page, func() {
+				showGameInfo(cmd, repo, gameID, updatesOnly, size, jsonOutput, output, fields)
+			})
 		},
 	}
 	cmd.Flags().BoolVar(&updatesOnly, "updates", false, "Show a concise list of downloadable files and their versions")
+	cmd.Flags().BoolVar(&showSize, "size", false, "Append the estimated download size (base game, extras, and DLCs)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the game data and size estimate as a single JSON document")
+	cmd.Flags().StringVarP(&output, "output", "o", outputJSON, "Format of the game data [json, yaml]; with yaml, the size estimate is part of the document as with --json")
+	cmd.Flags().StringVarP(&sizeOpts.language, "lang", "l", "en", "Game language used for the size estimate and --diff-installed [en, fr, de, es, it, ru, pl, pt-BR, zh-Hans, ja, ko, ...]; all means all languages")
+	cmd.Flags().StringVarP(&sizeOpts.platformName, "platform", "p", "windows", "Platform used for the size estimate and --diff-installed [all, auto, windows, mac, linux]; auto means the platform of this machine")
+	cmd.Flags().BoolVarP(&sizeOpts.extras, "extras", "e", true, "Include extra content files in the size estimate and --diff-installed? [true, false]")
+	cmd.Flags().BoolVarP(&sizeOpts.dlcs, "dlcs", "d", true, "Include DLC files in the size estimate and --diff-installed? [true, false]")
+	cmd.Flags().BoolVar(&filesOnly, "files", false, "Show a numbered list of all downloadable files, to pick one with 'download --file-index'")
+	cmd.Flags().StringVar(&installedDir, "diff-installed", "", "Compare the files in this download directory or game folder with the catalogue and show what is outdated or missing")
+	cmd.Flags().StringSliceVar(&fields, "fields", nil, "Print only these top-level fields of the game data, like title,downloads,dlcs (comma-separated)")
+	cmd.Flags().BoolVar(&page, "pager", true, "Show the output in $PAGER (less by default) when stdout is a terminal? [true, false]")
+	cmd.MarkFlagsMutuallyExclusive("updates", "json", "files")
+	cmd.MarkFlagsMutuallyExclusive("updates", "files", "diff-installed")
+	cmd.MarkFlagsMutuallyExclusive("size", "diff-installed")
+	cmd.MarkFlagsMutuallyExclusive("json", "output")
+	cmd.MarkFlagsMutuallyExclusive("output", "updates", "files", "diff-installed")
+	cmd.MarkFlagsMutuallyExclusive("fields", "updates", "files", "diff-installed")
 	return cmd
 }
 
-func showGameInfo(cmd *cobra.Command, repo db.GameRepository, gameID int, updatesOnly bool) {
+func showGameInfo(cmd *cobra.Command, repo db.GameRepository, gameID int, updatesOnly bool, size *infoSizeOptions, jsonOutput bool, output string, fields []string) {
 	if gameID == 0 {
-		cmd.PrintErrln("Error: ID of the game is required to fetch information.")
+		reportCliErr(cmd, clierr.New(clierr.Validation, "ID of the game is required to fetch information.", nil))
 		return
 	}
 	log.Info().Msgf("Fetching info for game with ID=%d", gameID)
 	game, err := repo.GetByID(cmd.Context(), gameID)
 	if err != nil {
-		e := clierr.New(clierr.Internal, "Failed to fetch game info", err)
-		cmd.PrintErrln(e.Message)
-		setLastCliErr(e)
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to fetch game info", err))
 		log.Error().Err(err).Msgf("Failed to fetch info for game with ID=%d", gameID)
 		return
 	}
 	if game == nil {
-		e := clierr.New(clierr.NotFound, "Game not found", nil)
-		cmd.PrintErrln(e.Message)
-		setLastCliErr(e)
+		reportCliErr(cmd, clierr.New(clierr.NotFound, "Game not found", nil))
 		log.Info().Msgf("No game found with ID=%d", gameID)
 		cmd.Println("No game found with the specified ID. Please check the game ID.")
 		return
 	}
 
+	var breakdown client.StorageSizeBreakdown
+	if size != nil {
+		b, err := estimateInfoSize(game.Data, *size)
+		if err != nil {
+			reportCliErr(cmd, clierr.New(clierr.Validation, "Failed to estimate download size", err))
+			return
+		}
+		breakdown = b
+	}
+
 	if !updatesOnly {
 		var nestedData map[string]interface{}
 		if err := json.Unmarshal([]byte(game.Data), &nestedData); err != nil {
 			log.Error().Err(err).Msg("Failed to unmarshal nested game data")
-			cmd.PrintErrln("Error: Failed to parse nested game data.")
+			reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to parse nested game data.", err))
 			return
 		}
-		nestedDataPretty, err := json.MarshalIndent(nestedData, "", "  ")
-		if err != nil {
+		if len(fields) > 0 {
+			selected, e := selectFields(nestedData, fields)
+			if e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			nestedData = selected
+		}
+		// With --json or YAML, the size estimate is part of the document instead of following it as text.
+		singleDocument := jsonOutput || output == outputYAML
+		var document interface{} = nestedData
+		if singleDocument && size != nil {
+			document = map[string]interface{}{
+				"game": nestedData,
+				"size": map[string]interface{}{
+					"language": size.language,
+					"platform": size.platformName,
+					"base":     breakdown.Base,
+					"extras":   breakdown.Extras,
+					"dlc":      breakdown.DLC,
+					"total":    breakdown.Total(),
+				},
+			}
+		}
+		if err := printDocument(cmd, output, document); err != nil {
 			log.Error().Err(err).Msg("Failed to marshal nested game data")
-			cmd.PrintErrln("Error: Failed to format nested game data.")
+			reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to format nested game data.", err))
 			return
 		}
-		cmd.Println(string(nestedDataPretty))
+		if size != nil && !singleDocument {
+			printSizeBreakdown(cmd, *size, breakdown)
+		}
 		return
 	}
 
 	// If --updates flag is used, show the version table
 	var gameData client.Game
 	if err := json.Unmarshal([]byte(game.Data), &gameData); err != nil {
-		e := clierr.New(clierr.Internal, "Failed to parse game data", err)
-		cmd.PrintErrln(e.Message)
-		setLastCliErr(e)
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to parse game data", err))
 		return
 	}
 
@@ -188,47 +328,471 @@ func showGameInfo(cmd *cobra.Command, repo db.GameRepository, gameID int, update
 	}
 
 	table.Render()
+	if size != nil {
+		printSizeBreakdown(cmd, *size, breakdown)
+	}
+}
+
+// selectFields returns the given top-level fields of data. A field that the game data does not have is an
+// error that lists the fields it has.
+func selectFields(data map[string]interface{}, fields []string) (map[string]interface{}, *clierr.Error) {
+	selected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		value, ok := data[field]
+		if !ok {
+			known := make([]string, 0, len(data))
+			for k := range data {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return nil, clierr.New(clierr.Validation,
+				fmt.Sprintf("Unknown field %q. The game data has: %s", field, strings.Join(known, ", ")), nil)
+		}
+		selected[field] = value
+	}
+	return selected, nil
+}
+
+func showGameFiles(cmd *cobra.Command, repo db.GameRepository, gameID int) {
+	game, err := repo.GetByID(cmd.Context(), gameID)
+	if err != nil {
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to fetch game info", err))
+		return
+	}
+	if game == nil {
+		reportCliErr(cmd, clierr.New(clierr.NotFound, "Game not found", nil))
+		return
+	}
+	gameData, err := client.ParseGameData(game.Data)
+	if err != nil {
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to parse game data", err))
+		return
+	}
+
+	files := gameData.Files()
+	if len(files) == 0 {
+		cmd.Println("No downloadable files found for this game.")
+		return
+	}
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"#", "Component", "Language", "Platform", "Name", "Size"})
+	table.SetAutoWrapText(false)
+	for _, f := range files {
+		language, platform := f.Language, f.Platform
+		if platform == "" {
+			language, platform = "-", "extra"
+		}
+		table.Append([]string{strconv.Itoa(f.Index), f.Component, language, platform, f.Name, f.Size})
+	}
+	table.Render()
+}
+
+// showInstalledDiff compares the game folder of gameID in dir with the files the catalogue lists for the game now.
+// The language, platform, extras, and DLCs of the download_info.json in the folder are used for the flags that
+// are not set.
+func showInstalledDiff(cmd *cobra.Command, repo db.GameRepository, gameID int, dir string, opts infoSizeOptions, jsonOutput bool) *clierr.Error {
+	game, err := repo.GetByID(cmd.Context(), gameID)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Failed to fetch game info", err)
+	}
+	if game == nil {
+		return clierr.New(clierr.NotFound, "Game not found", nil)
+	}
+	gameData, err := client.ParseGameData(game.Data)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Failed to parse game data", err)
+	}
+
+	root := auditRoot(dir, gameData.Title, gameID)
+	var installed *client.Game
+	if meta, err := client.ReadGameMetadata(root); err == nil {
+		installed = &meta
+	}
+	if err := validation.ValidatePlatform(opts.platformName); err != nil {
+		return clierr.New(clierr.Validation, "Invalid platform", err)
+	}
+	language, ok := client.LanguageFilter(opts.language)
+	if !ok {
+		return clierr.New(clierr.Validation, "Invalid language code", nil)
+	}
+	platform := validation.ResolvePlatform(opts.platformName)
+	if info, err := client.ReadDownloadInfo(root); err == nil {
+		flags := cmd.Flags()
+		if !flags.Changed("lang") && info.Language != "" {
+			language = info.Language
+		}
+		if !flags.Changed("platform") && info.Platform != "" {
+			platform = info.Platform
+		}
+		if !flags.Changed("extras") {
+			opts.extras = info.Extras
+		}
+		if !flags.Changed("dlcs") {
+			opts.dlcs = info.DLCs
+		}
+	}
+
+	report, err := operations.DiffInstalled(root, gameData, installed, language, platform, opts.extras, opts.dlcs)
+	if err != nil {
+		return clierr.New(clierr.NotFound, "Failed to read the game directory", err)
+	}
+	if jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return clierr.New(clierr.Internal, "Failed to encode the report", err)
+		}
+		cmd.Println(string(data))
+		return nil
+	}
+
+	cmd.Printf("Installed files of %s in %s (Language: %s, Platform: %s)\n", gameData.Title, report.Root, language, platform)
+	if installed == nil {
+		cmd.Println("No metadata.json found, so the installed versions are not known.")
+	}
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Status", "Component", "Language", "Platform", "File Name", "Installed", "Available", "Path"})
+	table.SetAutoWrapText(false)
+	for _, f := range report.Files {
+		status := f.Status
+		if status != operations.InstalledUpToDate {
+			status = strings.ToUpper(status)
+		}
+		table.Append([]string{status, f.Component, f.Language, f.Platform, f.Name,
+			orNA(f.InstalledVersion), orNA(f.ExpectedVersion), f.Path})
+	}
+	table.Render()
+	if report.UpToDate() {
+		cmd.Println("All files are up to date.")
+		return nil
+	}
+	cmd.Printf("%d outdated, %d missing, %d with a size mismatch, %d unexpected, %d up to date\n",
+		report.Count(operations.InstalledOutdated), report.Count(operations.InstalledMissing),
+		report.Count(operations.InstalledIncomplete), report.Count(operations.InstalledUnexpected),
+		report.Count(operations.InstalledUpToDate))
+	return nil
+}
+
+// orNA returns s, or "N/A" if it is empty.
+func orNA(s string) string {
+	if s == "" {
+		return "N/A"
+	}
+	return s
+}
+
+// estimateInfoSize parses the raw game data and estimates its download size for the selected options.
+func estimateInfoSize(data string, opts infoSizeOptions) (client.StorageSizeBreakdown, error) {
+	if err := validation.ValidatePlatform(opts.platformName); err != nil {
+		return client.StorageSizeBreakdown{}, err
+	}
+	languageFullName, ok := client.LanguageFilter(opts.language)
+	if !ok {
+		return client.StorageSizeBreakdown{}, fmt.Errorf("invalid language code: %s", opts.language)
+	}
+	game, err := client.ParseGameData(data)
+	if err != nil {
+		return client.StorageSizeBreakdown{}, err
+	}
+	return game.EstimateStorageSizeBreakdown(languageFullName, validation.ResolvePlatform(opts.platformName), opts.extras, opts.dlcs)
+}
+
+func printSizeBreakdown(cmd *cobra.Command, opts infoSizeOptions, b client.StorageSizeBreakdown) {
+	cmd.Printf("Estimated download size (Language: %s, Platform: %s):\n", opts.language, opts.platformName)
+	cmd.Printf("  Base game: %s\n", progress.FormatBytes(b.Base))
+	cmd.Printf("  Extras:    %s\n", progress.FormatBytes(b.Extras))
+	cmd.Printf("  DLCs:      %s\n", progress.FormatBytes(b.DLC))
+	cmd.Printf("  Total:     %s\n", progress.FormatBytes(b.Total()))
+}
+
+func languagesCmd(repo db.GameRepository) *cobra.Command {
+	return &cobra.Command{
+		Use:   "languages [gameID]",
+		Short: "Show the languages available for a game",
+		Long:  "Given a game ID, show the languages its files are available in for the game and each of its DLCs, with the codes accepted by --lang",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			gameID, e := parseGameIDArg(args[0])
+			if e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			showGameLanguages(cmd, repo, gameID)
+		},
+	}
+}
+
+func showGameLanguages(cmd *cobra.Command, repo db.GameRepository, gameID int) {
+	game, err := repo.GetByID(cmd.Context(), gameID)
+	if err != nil {
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to fetch game info", err))
+		return
+	}
+	if game == nil {
+		reportCliErr(cmd, clierr.New(clierr.NotFound, "Game not found", nil))
+		return
+	}
+	gameData, err := client.ParseGameData(game.Data)
+	if err != nil {
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to parse game data", err))
+		return
+	}
+
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Component", "Language", "Code"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+
+	addLanguages := func(component string, downloads []client.Downloadable) {
+		for _, language := range distinctLanguages(downloads) {
+			code, ok := client.LanguageCode(language)
+			if !ok {
+				code = "-"
+			}
+			table.Append([]string{component, language, code})
+		}
+	}
+
+	addLanguages(gameData.Title, gameData.Downloads)
+	for _, dlc := range gameData.DLCs {
+		addLanguages(fmt.Sprintf("DLC: %s", dlc.Title), dlc.ParsedDownloads)
+	}
+	if table.NumLines() == 0 {
+		cmd.Println("No downloadable files found for this game.")
+		return
+	}
+	table.Render()
+}
+
+// distinctLanguages returns the languages of the given downloads in their original order, without duplicates.
+func distinctLanguages(downloads []client.Downloadable) []string {
+	seen := make(map[string]bool)
+	var languages []string
+	for _, dl := range downloads {
+		key := strings.ToLower(strings.TrimSpace(dl.Language))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		languages = append(languages, dl.Language)
+	}
+	return languages
+}
+
+func platformsCmd(repo db.GameRepository) *cobra.Command {
+	return &cobra.Command{
+		Use:   "platforms [gameID]",
+		Short: "Show the platforms available for a game",
+		Long:  "Given a game ID, show which platforms (windows, mac, linux) have files for the game and each of its DLCs, to help choose --platform",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			gameID, e := parseGameIDArg(args[0])
+			if e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			showGamePlatforms(cmd, repo, gameID)
+		},
+	}
+}
+
+func showGamePlatforms(cmd *cobra.Command, repo db.GameRepository, gameID int) {
+	game, err := repo.GetByID(cmd.Context(), gameID)
+	if err != nil {
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to fetch game info", err))
+		return
+	}
+	if game == nil {
+		reportCliErr(cmd, clierr.New(clierr.NotFound, "Game not found", nil))
+		return
+	}
+	gameData, err := client.ParseGameData(game.Data)
+	if err != nil {
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to parse game data", err))
+		return
+	}
+
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Component", "Windows", "Mac", "Linux"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+
+	addPlatforms := func(component string, downloads []client.Downloadable) {
+		counts := platformFileCounts(downloads)
+		row := []string{component}
+		for _, platform := range []string{"windows", "mac", "linux"} {
+			if counts[platform] == 0 {
+				row = append(row, "-")
+			} else {
+				row = append(row, fmt.Sprintf("%d file(s)", counts[platform]))
+			}
+		}
+		table.Append(row)
+	}
+
+	addPlatforms(gameData.Title, gameData.Downloads)
+	for _, dlc := range gameData.DLCs {
+		addPlatforms(fmt.Sprintf("DLC: %s", dlc.Title), dlc.ParsedDownloads)
+	}
+	table.Render()
+}
+
+// platformFileCounts returns the number of distinct downloadable files per platform in downloads,
+// counting a file listed for several languages once.
+func platformFileCounts(downloads []client.Downloadable) map[string]int {
+	seen := make(map[string]bool)
+	counts := make(map[string]int)
+	for _, dl := range downloads {
+		for platform, files := range map[string][]client.PlatformFile{
+			"windows": dl.Platforms.Windows, "mac": dl.Platforms.Mac, "linux": dl.Platforms.Linux,
+		} {
+			for _, f := range files {
+				if f.ManualURL == nil || *f.ManualURL == "" || seen[platform+"|"+*f.ManualURL] {
+					continue
+				}
+				seen[platform+"|"+*f.ManualURL] = true
+				counts[platform]++
+			}
+		}
+	}
+	return counts
 }
 
 func refreshCmd(authService *auth.Service) *cobra.Command {
 	var numThreads int
+	var fetchTimeout time.Duration
+	var catalogueOnly, onlyMissing bool
+	var gameIDs []int
 	cmd := &cobra.Command{
-		Use:   "refresh",
-		Short: "Update the catalogue with the latest data from GOG",
-		Long:  "Update the game catalogue with the latest data for the games owned by the user on GOG",
+		Use:         "refresh",
+		Short:       "Update the catalogue with the latest data from GOG",
+		Annotations: map[string]string{needsAuthAnnotation: ""},
+		Long: "Update the game catalogue with the latest data for the games owned by the user on GOG.\n\n" +
+			"With --catalogue-only, only the list of owned games and their titles is updated, which takes a few " +
+			"requests instead of one for every game. New games are added without their details; use " +
+			"--only-missing later to fetch the details of just those games.\n\n" +
+			"With --game-ids, only the details of the given games are fetched again, like the broken entries " +
+			"found by 'gogg catalogue validate', and the rest of the catalogue is kept.",
 		Run: func(cmd *cobra.Command, args []string) {
-			refreshCatalogue(cmd, authService, numThreads)
+			switch {
+			case catalogueOnly && onlyMissing:
+				reportCliErr(cmd, clierr.New(clierr.Validation, "--catalogue-only and --only-missing cannot be combined", nil))
+			case len(gameIDs) > 0 && (catalogueOnly || onlyMissing):
+				reportCliErr(cmd, clierr.New(clierr.Validation, "--game-ids cannot be combined with --catalogue-only or --only-missing", nil))
+			case catalogueOnly:
+				refreshCatalogueTitles(cmd, authService, fetchTimeout)
+			default:
+				refreshCatalogue(cmd, authService, numThreads, fetchTimeout, onlyMissing, gameIDs)
+			}
 		},
 	}
 	cmd.Flags().IntVarP(&numThreads, "threads", "t", 10,
 		"Number of worker threads to use for fetching game data [1-20]")
+	cmd.Flags().DurationVar(&fetchTimeout, "fetch-timeout", client.DefaultFetchTimeout,
+		"How long each request for the game list or the details of a game may take before it is retried (like 30s or 2m); raise it on slow connections")
+	cmd.Flags().BoolVar(&catalogueOnly, "catalogue-only", false,
+		"Only update the list of owned games and their titles, without fetching the details of every game")
+	cmd.Flags().BoolVar(&onlyMissing, "only-missing", false,
+		"Only fetch the details of the games added by --catalogue-only, without replacing the rest of the catalogue")
+	cmd.Flags().IntSliceVar(&gameIDs, "game-ids", nil,
+		"Only fetch the details of the games with these IDs, like 1207658924,1495134320, without replacing the rest of the catalogue")
 	return cmd
 }
 
-func refreshCatalogue(cmd *cobra.Command, authService *auth.Service, numThreads int) {
+// refreshCatalogueTitles runs a refresh with --catalogue-only.
+func refreshCatalogueTitles(cmd *cobra.Command, authService *auth.Service, fetchTimeout time.Duration) {
+	log.Info().Msg("Refreshing the titles of the game catalogue...")
+	if fetchTimeout <= 0 {
+		reportCliErr(cmd, clierr.New(clierr.Validation, "The fetch timeout must be greater than zero", nil))
+		return
+	}
+	const refreshFailed = "Failed to refresh catalogue. Please check the logs for details."
+	if _, err := authService.RefreshTokenCtx(cmd.Context()); err != nil {
+		reportCliErr(cmd, clierr.Wrap(clierr.Auth, refreshFailed, err))
+		log.Error().Err(err).Msg("Failed to find or refresh the access token. Did you login?")
+		return
+	}
+
+	repo := db.NewGameRepository(db.GetDB())
+	result, err := client.RefreshCatalogueTitles(cmd.Context(), authService, repo, client.WithFetchTimeout(fetchTimeout))
+	if err != nil {
+		reportCliErr(cmd, wrapFetchErr(clierr.Internal, "refresh catalogue", refreshFailed, err))
+		log.Error().Err(err).Msg("Failed to refresh the titles of the game catalogue")
+		return
+	}
+
+	cmd.Printf("Refreshed the game catalogue titles: %d new game(s), %d renamed.\n", result.Added, result.Renamed)
+	if result.Added > 0 {
+		cmd.Println("Run 'gogg catalogue refresh --only-missing' to fetch the details of the new games.")
+	}
+}
+
+// placeholderGameIDs returns the IDs of the games in the catalogue whose details have not been fetched yet.
+func placeholderGameIDs(ctx context.Context, repo db.GameRepository) ([]int, error) {
+	var ids []int
+	err := repo.Each(ctx, func(g db.Game) error {
+		if client.IsPlaceholder(g.Data) {
+			ids = append(ids, g.ID)
+		}
+		return nil
+	})
+	return ids, err
+}
+
+func refreshCatalogue(cmd *cobra.Command, authService *auth.Service, numThreads int, fetchTimeout time.Duration, onlyMissing bool, gameIDs []int) {
 	log.Info().Msg("Refreshing the game catalogue...")
-	if err := validation.ValidateThreadCount(numThreads); err != nil {
-		cmd.PrintErrln("Error:", err)
+	if e := validateThreadsFlag(numThreads); e != nil {
+		reportCliErr(cmd, e)
+		return
+	}
+	if fetchTimeout <= 0 {
+		reportCliErr(cmd, clierr.New(clierr.Validation, "The fetch timeout must be greater than zero", nil))
 		return
 	}
 
-	bar := progressbar.NewOptions(1000,
+	repo := db.NewGameRepository(db.GetDB())
+	opts := []client.RefreshOption{client.WithFetchTimeout(fetchTimeout)}
+	if onlyMissing {
+		ids, err := placeholderGameIDs(cmd.Context(), repo)
+		if err != nil {
+			reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to read the game catalogue", err))
+			return
+		}
+		if len(ids) == 0 {
+			cmd.Println("No games in the catalogue are missing their details.")
+			return
+		}
+		opts = append(opts, client.WithRefreshGameIDs(ids))
+	}
+	if len(gameIDs) > 0 {
+		opts = append(opts, client.WithRefreshGameIDs(gameIDs))
+	}
+
+	const refreshFailed = "Failed to refresh catalogue. Please check the logs for details."
+	// Check the login first, so a missing or expired login is told apart from a failure to fetch the games.
+	if _, err := authService.RefreshTokenCtx(cmd.Context()); err != nil {
+		reportCliErr(cmd, clierr.Wrap(clierr.Auth, refreshFailed, err))
+		log.Error().Err(err).Msg("Failed to find or refresh the access token. Did you login?")
+		return
+	}
+
+	bar := newProgressBar(1000, cmd.ErrOrStderr(),
 		progressbar.OptionSetDescription("Refreshing catalogue..."),
 		progressbar.OptionSetWidth(20),
 		progressbar.OptionShowIts(),
 		progressbar.OptionClearOnFinish(),
 		progressbar.OptionSetPredictTime(false),
-		progressbar.OptionSetWriter(cmd.ErrOrStderr()), // Use command's error stream
 	)
 
 	progressCb := func(progress float64) {
 		_ = bar.Set(int(progress * 1000))
 	}
 
-	repo := db.NewGameRepository(db.GetDB())
-	err := client.RefreshCatalogue(cmd.Context(), authService, repo, numThreads, progressCb)
+	err := client.RefreshCatalogue(cmd.Context(), authService, repo, numThreads, progressCb, opts...)
 	if err != nil {
-		cmd.PrintErrln("Error: Failed to refresh catalogue. Please check the logs for details.")
+		reportCliErr(cmd, wrapFetchErr(clierr.Internal, "refresh catalogue", refreshFailed, err))
 		log.Error().Err(err).Msg("Failed to refresh the game catalogue")
 		return
 	}
@@ -237,50 +801,63 @@ func refreshCatalogue(cmd *cobra.Command, authService *auth.Service, numThreads
 }
 
 func searchCmd(repo db.GameRepository) *cobra.Command {
-	var searchByIDFlag bool
+	var searchByIDFlag, asciiTitles bool
+	var output string
 	cmd := &cobra.Command{
 		Use:   "search [query]",
 		Short: "Search for games in the catalogue",
-		Long:  "Search for games in the catalogue given a query string, which can be a term in the title or a game ID",
-		Args:  cobra.ExactArgs(1),
-		Run:   func(cmd *cobra.Command, args []string) { searchGames(cmd, repo, args[0], searchByIDFlag) },
+		Long: "Search for games in the catalogue given a query string. Games whose title contains the query are listed,\n" +
+			"and if the query is a number, the game with that ID is listed first.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			searchGames(cmd, repo, args[0], searchByIDFlag, asciiTitles, output)
+		},
 	}
 	cmd.Flags().BoolVarP(&searchByIDFlag, "id", "i", false,
-		"Search by game ID instead of title?")
+		"Search only by game ID, not by title")
+	cmd.Flags().BoolVar(&asciiTitles, "ascii-titles", false, "Show the titles with letters like é or Ж written in ASCII")
+	cmd.Flags().StringVarP(&output, "output", "o", outputTable, "Output format [table, json, yaml]")
 	return cmd
 }
 
-func searchGames(cmd *cobra.Command, repo db.GameRepository, query string, searchByID bool) {
+func searchGames(cmd *cobra.Command, repo db.GameRepository, query string, searchByID, asciiTitles bool, output string) {
+	if e := validateOutputFlag(output, outputTable, outputJSON, outputYAML); e != nil {
+		reportCliErr(cmd, e)
+		return
+	}
 	var games []db.Game
 	var err error
 	ctx := cmd.Context()
 	if searchByID {
 		gameID, err := strconv.Atoi(query)
 		if err != nil {
-			cmd.PrintErrln("Error: Invalid game ID. It must be a number.")
+			reportCliErr(cmd, clierr.New(clierr.Validation, "Invalid game ID. It must be a number.", err))
 			return
 		}
 		log.Info().Msgf("Searching for game with ID=%d", gameID)
 		game, err := repo.GetByID(ctx, gameID)
 		if err != nil {
 			log.Error().Err(err).Msgf("Failed to fetch game with ID=%d", gameID)
-			cmd.PrintErrln("Error:", err)
+			reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to search games", err))
 			return
 		}
 		if game != nil {
 			games = append(games, *game)
 		}
 	} else {
-		log.Info().Msgf("Searching for games with term=%s in their title", query)
-		games, err = repo.SearchByTitle(ctx, query)
+		log.Info().Msgf("Searching for games with term=%s in their title or ID", query)
+		games, err = searchCatalogue(ctx, repo, query)
 		if err != nil {
-			setLastCliErr(clierr.New(clierr.Internal, "Failed to search games", err))
-			cmd.PrintErrln(clierr.New(clierr.Internal, "Failed to search games", err).Message)
-			log.Error().Err(err).Msgf("Failed to search games with term=%s in their title",
+			reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to search games", err))
+			log.Error().Err(err).Msgf("Failed to search games with term=%s in their title or ID",
 				query)
 			return
 		}
 	}
+	if output != outputTable {
+		printGameEntries(cmd, games, asciiTitles, output)
+		return
+	}
 	if len(games) == 0 {
 		cmd.Println("No game(s) found matching the query. Please check the search term or ID.")
 		return
@@ -296,44 +873,62 @@ func searchGames(cmd *cobra.Command, repo db.GameRepository, query string, searc
 		table.Append([]string{
 			fmt.Sprintf("%d", i+1),
 			fmt.Sprintf("%d", game.ID),
-			game.Title,
+			displayTitle(game.Title, asciiTitles),
 		})
 	}
 	table.Render()
 }
 
+// printGameEntries prints the IDs and titles of games as a JSON or YAML list. No games give an empty list.
+func printGameEntries(cmd *cobra.Command, games []db.Game, asciiTitles bool, output string) {
+	entries := make([]gameEntry, 0, len(games))
+	for _, game := range games {
+		entries = append(entries, gameEntry{ID: game.ID, Title: displayTitle(game.Title, asciiTitles), NoDownloads: hasNoDownloads(game)})
+	}
+	if err := printDocument(cmd, output, entries); err != nil {
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to format the games", err))
+	}
+}
+
+// searchCatalogue returns the games whose ID is query or whose title contains it, ranked by search.Games: the
+// game with that ID first, then the titles that start with query, the titles with a word that does, and the
+// other titles that contain it.
+func searchCatalogue(ctx context.Context, repo db.GameRepository, query string) ([]db.Game, error) {
+	games, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return search.Games(query, games), nil
+}
+
 func exportCmd(repo db.GameRepository) *cobra.Command {
-	var exportFormat string
+	var exportFormat, filter string
+	var detailed bool
 	cmd := &cobra.Command{
 		Use:   "export [exportDir]",
 		Short: "Export the game catalogue to a file",
 		Long:  "Export the game catalogue to a file in the specified path in the specified format",
 		Args:  cobra.ExactArgs(1),
-		Run:   func(cmd *cobra.Command, args []string) { exportCatalogue(cmd, repo, args[0], exportFormat) },
+		Run: func(cmd *cobra.Command, args []string) {
+			exportCatalogue(cmd, repo, args[0], exportFormat, detailed, filter)
+		},
 	}
 	cmd.Flags().StringVarP(&exportFormat, "format", "f", "csv",
 		"Format of the exported file [csv, json]")
+	cmd.Flags().BoolVar(&detailed, "detailed", false,
+		"Add platforms, languages, DLC count, estimated size, and store link columns to the CSV file")
+	cmd.Flags().StringVar(&filter, "filter", "",
+		"Export only the games whose title contains this term (case-insensitive)")
 	return cmd
 }
 
-func exportCatalogue(cmd *cobra.Command, repo db.GameRepository, exportPath, exportFormat string) {
+func exportCatalogue(cmd *cobra.Command, repo db.GameRepository, exportPath, exportFormat string, detailed bool, filter string) {
 	log.Info().Msg("Exporting the game catalogue...")
 	ctx := cmd.Context()
-	games, err := repo.List(ctx)
-	if err != nil {
-		setLastCliErr(clierr.New(clierr.Internal, "Failed to list games for export", err))
-		cmd.PrintErrln(clierr.New(clierr.Internal, "Failed to list games for export", err).Message)
-		log.Error().Err(err).Msg("Failed to list games from the repository")
-		return
-	} else if len(games) == 0 {
-		cmd.Println("No games found to export. Did you refresh the catalogue?")
-		return
-	}
 	switch exportFormat {
 	case "json", "csv":
 	default:
-		setLastCliErr(clierr.New(clierr.Validation, "Invalid export format", nil))
-		cmd.PrintErrln("Error: Invalid export format. Supported formats: json, csv")
+		reportCliErr(cmd, clierr.New(clierr.Validation, "Invalid export format. Supported formats: json, csv", nil))
 		return
 	}
 	timestamp := time.Now().Format("20060102_150405")
@@ -345,73 +940,274 @@ func exportCatalogue(cmd *cobra.Command, repo db.GameRepository, exportPath, exp
 		fileName = fmt.Sprintf("gogg_catalogue_%s.csv", timestamp)
 	}
 	filePath := filepath.Join(exportPath, fileName)
+	var count int
 	var writeErr error
 	switch exportFormat {
 	case "json":
-		writeErr = exportCatalogueToJSON(filePath, games)
+		count, writeErr = exportCatalogueToJSON(ctx, filePath, repo, filter)
 	case "csv":
-		writeErr = exportCatalogueToCSV(filePath, games)
+		count, writeErr = exportCatalogueToCSV(ctx, filePath, repo, detailed, filter)
 	}
 	if writeErr != nil {
-		setLastCliErr(clierr.New(clierr.Internal, "Failed exporting catalogue", writeErr))
-		cmd.PrintErrln(clierr.New(clierr.Internal, "Failed exporting catalogue", writeErr).Message)
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Failed exporting catalogue", writeErr))
 		log.Error().Err(writeErr).Msg("Failed to export the game catalogue.")
 		return
 	}
+	if count == 0 {
+		_ = os.Remove(filePath)
+		if filter != "" {
+			cmd.Printf("No games match the filter %q. Nothing was exported.\n", filter)
+			return
+		}
+		cmd.Println("No games found to export. Did you refresh the catalogue?")
+		return
+	}
 	cmd.Printf("Game catalogue exported successfully to: \"%s\"\n", filePath)
 }
 
-func exportCatalogueToCSV(path string, games []db.Game) error {
+// writeExportFile creates the file at path and calls write with a buffered writer for it.
+// It returns the number of games written as reported by write.
+func writeExportFile(path string, write func(w io.Writer) (int, error)) (int, error) {
 	if err := ensurePathExists(path); err != nil {
-		return err
+		return 0, err
 	}
 	file, err := os.Create(path)
 	if err != nil {
-		log.Error().Err(err).Msgf("Failed to create CSV file %s", path)
-		return err
+		log.Error().Err(err).Msgf("Failed to create export file %s", path)
+		return 0, err
+	}
+	bw := bufio.NewWriter(file)
+	count, err := write(bw)
+	if err == nil {
+		err = bw.Flush()
 	}
-	defer func() {
-		if cerr := file.Close(); cerr != nil {
-			log.Error().Err(cerr).Msgf("Failed to close CSV file %s", path)
+	if cerr := file.Close(); err == nil && cerr != nil {
+		log.Error().Err(cerr).Msgf("Failed to close export file %s", path)
+		err = cerr
+	}
+	return count, err
+}
+
+// catalogueCSVHeader and detailedCSVHeader are the columns of the CSV export without and with --detailed.
+var (
+	catalogueCSVHeader = []string{"ID", "Title"}
+	detailedCSVHeader  = []string{"ID", "Title", "Platforms", "Languages", "DLCs", "Estimated Size (Bytes)", "Store URL"}
+)
+
+// matchesExportFilter reports whether a game's title contains filter, ignoring case.
+// An empty filter matches every game.
+func matchesExportFilter(game db.Game, filter string) bool {
+	return filter == "" || strings.Contains(strings.ToLower(game.Title), strings.ToLower(filter))
+}
+
+// exportCatalogueToCSV writes the ID and title of every game matching filter to a CSV file. With detailed,
+// the platforms, languages, number of DLCs, estimated download size, and a store link are added for every game.
+func exportCatalogueToCSV(ctx context.Context, path string, repo db.GameRepository, detailed bool, filter string) (int, error) {
+	return writeExportFile(path, func(w io.Writer) (int, error) {
+		cw := csv.NewWriter(w)
+		header := catalogueCSVHeader
+		if detailed {
+			header = detailedCSVHeader
 		}
-	}()
-	if _, err := fmt.Fprintln(file, "ID,Title"); err != nil {
-		log.Error().Err(err).Msg("Failed to write CSV header to file")
-		return err
+		if err := cw.Write(header); err != nil {
+			log.Error().Err(err).Msg("Failed to write CSV header to file")
+			return 0, err
+		}
+		count := 0
+		err := repo.Each(ctx, func(game db.Game) error {
+			if !matchesExportFilter(game, filter) {
+				return nil
+			}
+			if err := cw.Write(catalogueCSVRow(game, detailed)); err != nil {
+				log.Error().Err(err).Msgf("Failed to write game %d to CSV file", game.ID)
+				return err
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return count, err
+		}
+		log.Info().Msgf("Game catalogue exported to CSV file: %s", path)
+		return count, nil
+	})
+}
+
+// catalogueCSVRow returns the CSV columns of a game. The detailed columns are left empty if the game data
+// cannot be parsed. The estimated size covers English files for all platforms, including extras and DLCs.
+func catalogueCSVRow(game db.Game, detailed bool) []string {
+	row := []string{strconv.Itoa(game.ID), game.Title}
+	if !detailed {
+		return row
 	}
-	for _, game := range games {
-		if _, err := fmt.Fprintf(file, "%d,\"%s\"\n", game.ID, game.Title); err != nil {
-			log.Error().Err(err).Msgf("Failed to write game %d to CSV file", game.ID)
-			return err
+	gameData, err := client.ParseGameData(game.Data)
+	if err != nil {
+		return append(row, "", "", "", "", storeSearchURL(game.Title))
+	}
+
+	var languages []string
+	for _, language := range distinctLanguages(gameData.Downloads) {
+		if code, ok := client.LanguageCode(language); ok {
+			language = code
 		}
+		languages = append(languages, language)
 	}
-	log.Info().Msgf("Game catalogue exported to CSV file: %s", path)
-	return nil
+	var size string
+	if englishName, ok := client.LanguageFilter("en"); ok {
+		if b, err := gameData.EstimateStorageSizeBreakdown(englishName, "all", true, true); err == nil {
+			size = strconv.FormatInt(b.Total(), 10)
+		}
+	}
+	return append(row,
+		strings.Join(gamePlatforms(gameData.Downloads), ", "),
+		strings.Join(languages, ", "),
+		strconv.Itoa(len(gameData.DLCs)),
+		size,
+		storeSearchURL(game.Title),
+	)
 }
 
-func exportCatalogueToJSON(path string, games []db.Game) error {
-	if err := ensurePathExists(path); err != nil {
-		return err
+// gamePlatforms returns the platforms that have at least one file in downloads.
+func gamePlatforms(downloads []client.Downloadable) []string {
+	var windows, mac, linux bool
+	for _, dl := range downloads {
+		windows = windows || len(dl.Platforms.Windows) > 0
+		mac = mac || len(dl.Platforms.Mac) > 0
+		linux = linux || len(dl.Platforms.Linux) > 0
 	}
-	writeGamesToJSON := func(path string, games []db.Game) error {
-		file, err := os.Create(path)
-		if err != nil {
-			log.Error().Err(err).Msgf("Failed to create JSON file %s", path)
-			return err
+	var platforms []string
+	if windows {
+		platforms = append(platforms, "windows")
+	}
+	if mac {
+		platforms = append(platforms, "mac")
+	}
+	if linux {
+		platforms = append(platforms, "linux")
+	}
+	return platforms
+}
+
+// storeSearchURL returns a link to the GOG store search for a title. The catalogue data does not
+// include the store page address of a game, so a search is the most reliable link.
+func storeSearchURL(title string) string {
+	return "https://www.gog.com/en/games?query=" + url.QueryEscape(title)
+}
+
+// exportCatalogueToJSON writes every game matching filter to a file as a JSON array. The games are streamed
+// from the repository and encoded one at a time, so memory use does not grow with the size of the catalogue.
+func exportCatalogueToJSON(ctx context.Context, path string, repo db.GameRepository, filter string) (int, error) {
+	return writeExportFile(path, func(w io.Writer) (int, error) {
+		if _, err := io.WriteString(w, "["); err != nil {
+			return 0, err
 		}
-		defer func() {
-			if cerr := file.Close(); cerr != nil {
-				log.Error().Err(cerr).Msgf("Failed to close JSON file %s", path)
+		count := 0
+		err := repo.Each(ctx, func(game db.Game) error {
+			if !matchesExportFilter(game, filter) {
+				return nil
 			}
-		}()
-		if err := json.NewEncoder(file).Encode(games); err != nil {
+			data, err := json.Marshal(game)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			count++
+			return nil
+		})
+		if err != nil {
 			log.Error().Err(err).Msg("Failed to write games to JSON file")
-			return err
+			return count, err
+		}
+		if _, err := io.WriteString(w, "]\n"); err != nil {
+			return count, err
 		}
 		log.Info().Msgf("Game catalogue exported to JSON file: %s", path)
-		return nil
+		return count, nil
+	})
+}
+
+func importCmd(repo db.GameRepository) *cobra.Command {
+	return &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import games from a JSON export into the catalogue",
+		Long: "Import the games in a JSON file made with 'catalogue export --format=json' into the catalogue.\n" +
+			"Games that are already in the catalogue are updated. This does not require logging in.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if e := importCatalogue(cmd, repo, args[0]); e != nil {
+				reportCliErr(cmd, e)
+			}
+		},
+	}
+}
+
+func importCatalogue(cmd *cobra.Command, repo db.GameRepository, path string) *clierr.Error {
+	log.Info().Msgf("Importing the game catalogue from %s", path)
+	games, err := readCatalogueExport(path)
+	if err != nil {
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid catalogue export file: %v", err), err)
+	}
+
+	ctx := cmd.Context()
+	var added, updated int
+	for _, game := range games {
+		existing, err := repo.GetByID(ctx, game.ID)
+		if err != nil {
+			return clierr.New(clierr.Internal, "Failed to read the catalogue", err)
+		}
+		if err := repo.Put(ctx, game); err != nil {
+			return clierr.New(clierr.Internal, fmt.Sprintf("Failed to import game %d", game.ID), err)
+		}
+		if existing == nil {
+			added++
+		} else {
+			updated++
+		}
+	}
+	cmd.Printf("Imported %d game(s): %d added, %d updated.\n", len(games), added, updated)
+	return nil
+}
+
+// readCatalogueExport reads and validates a JSON catalogue export. Every entry must have a positive ID,
+// a title, and game data that is valid JSON, and IDs must be unique.
+func readCatalogueExport(path string) ([]db.Game, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	dec := json.NewDecoder(bufio.NewReader(file))
+	dec.DisallowUnknownFields()
+	var games []db.Game
+	if err := dec.Decode(&games); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of games with id, title, and data: %w", err)
+	}
+	seen := make(map[int]bool, len(games))
+	for i, game := range games {
+		switch {
+		case game.ID <= 0:
+			return nil, fmt.Errorf("entry %d has an invalid ID %d", i+1, game.ID)
+		case strings.TrimSpace(game.Title) == "":
+			return nil, fmt.Errorf("entry %d (ID %d) has no title", i+1, game.ID)
+		case !json.Valid([]byte(game.Data)):
+			return nil, fmt.Errorf("entry %d (ID %d) has invalid game data", i+1, game.ID)
+		case seen[game.ID]:
+			return nil, fmt.Errorf("entry %d has a duplicate ID %d", i+1, game.ID)
+		}
+		seen[game.ID] = true
 	}
-	return writeGamesToJSON(path, games)
+	return games, nil
 }
 
 func ensurePathExists(path string) error {
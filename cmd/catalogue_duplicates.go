@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/olekukonko/tablewriter"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// editionSuffixes are the endings of titles that GOG uses for re-releases and bundles of the same game. They are
+// removed from normalized titles, longest first, so that "The Witcher: Enhanced Edition" and "The Witcher" match.
+var editionSuffixes = []string{
+	"game of the year edition", "game of the year", "goty edition", "goty",
+	"definitive edition", "enhanced edition", "complete edition", "ultimate edition", "deluxe edition",
+	"special edition", "anniversary edition", "gold edition", "directors cut", "remastered",
+}
+
+// duplicateGroup is a set of games whose titles are the same after normalization.
+type duplicateGroup struct {
+	Key   string      `json:"key" yaml:"key"`
+	Games []gameEntry `json:"games" yaml:"games"`
+}
+
+func duplicatesCmd(repo db.GameRepository) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "duplicates",
+		Short: "Show games that are likely listed more than once in the catalogue",
+		Long: "Show groups of games in the catalogue whose titles are the same when case, punctuation, a leading \"The\",\n" +
+			"and endings like \"Enhanced Edition\" or \"GOTY\" are ignored. GOG sometimes lists a game under more than one\n" +
+			"ID, for example as a re-release or as part of a bundle. Nothing is changed.",
+		Args: cobra.NoArgs,
+		Run:  func(cmd *cobra.Command, args []string) { showDuplicates(cmd, repo, output) },
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", outputTable, "Output format [table, json, yaml]")
+	return cmd
+}
+
+func showDuplicates(cmd *cobra.Command, repo db.GameRepository, output string) {
+	if e := validateOutputFlag(output, outputTable, outputJSON, outputYAML); e != nil {
+		reportCliErr(cmd, e)
+		return
+	}
+	games, err := repo.List(cmd.Context())
+	if err != nil {
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Unable to list games", err))
+		log.Error().Err(err).Msg("Failed to fetch games from the game catalogue.")
+		return
+	}
+	groups := findDuplicates(games)
+	if output != outputTable {
+		if groups == nil {
+			groups = []duplicateGroup{}
+		}
+		if err := printDocument(cmd, output, groups); err != nil {
+			reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to format the duplicates", err))
+		}
+		return
+	}
+	if len(groups) == 0 {
+		cmd.Println("No likely duplicates found in the catalogue.")
+		return
+	}
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Group", "Game ID", "Title"})
+	table.SetColMinWidth(2, 50)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(false)
+	for i, group := range groups {
+		for _, game := range group.Games {
+			table.Append([]string{fmt.Sprintf("%d", i+1), fmt.Sprintf("%d", game.ID), displayTitle(game.Title, false)})
+		}
+	}
+	table.Render()
+	cmd.Printf("%d group(s) of likely duplicates found.\n", len(groups))
+}
+
+// findDuplicates groups games by their normalized title and returns the groups with more than one game, ordered
+// by key, with the games of each group ordered by ID. Games without a title are left out.
+func findDuplicates(games []db.Game) []duplicateGroup {
+	byKey := make(map[string][]gameEntry)
+	for _, game := range games {
+		key := normalizeTitle(game.Title)
+		if key == "" {
+			continue
+		}
+		byKey[key] = append(byKey[key], gameEntry{ID: game.ID, Title: game.Title})
+	}
+	var groups []duplicateGroup
+	for key, entries := range byKey {
+		if len(entries) < 2 {
+			continue
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+		groups = append(groups, duplicateGroup{Key: key, Games: entries})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+// normalizeTitle returns title in lower case and ASCII, with apostrophes and symbols like ™ dropped, other
+// punctuation turned into spaces, and a leading "the" and the edition suffixes removed.
+func normalizeTitle(title string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(client.ASCIITitle(title)) {
+		switch {
+		case r == '\'' || r == '’' || unicode.IsSymbol(r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(' ')
+		}
+	}
+	key := strings.Join(strings.Fields(sb.String()), " ")
+	key = strings.TrimPrefix(key, "the ")
+	for trimmed := true; trimmed; {
+		trimmed = false
+		for _, suffix := range editionSuffixes {
+			if rest, ok := strings.CutSuffix(key, " "+suffix); ok {
+				key, trimmed = rest, true
+				break
+			}
+		}
+	}
+	return key
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/habedi/gogg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	for title, want := range map[string]string{
+		"The Witcher: Enhanced Edition":          "witcher",
+		"The Witcher":                            "witcher",
+		"Baldur's Gate II: Enhanced Edition":     "baldurs gate ii",
+		"Fallout: New Vegas Ultimate Edition":    "fallout new vegas",
+		"Deus Ex™ GOTY Edition":                  "deus ex",
+		"Blade Runner - Director's Cut":          "blade runner",
+		"Pokémon Remastered Game of the Year":    "pokemon",
+		"The Thing":                              "thing",
+		"GOTY":                                   "goty",
+		"  ":                                     "",
+		"Heroes of Might and Magic 3: Complete ": "heroes of might and magic 3 complete",
+	} {
+		assert.Equal(t, want, normalizeTitle(title), title)
+	}
+}
+
+func TestDuplicatesCmd(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 3, "The Witcher: Enhanced Edition", `{}`)
+	addTestGame(t, repo, 1, "The Witcher", `{}`)
+	addTestGame(t, repo, 2, "Witcher 2", `{}`)
+	addTestGame(t, repo, 4, "", `{}`)
+	addTestGame(t, repo, 5, "", `{}`)
+
+	output, err := captureCombinedOutput(duplicatesCmd(repo))
+	require.NoError(t, err)
+	assert.Contains(t, output, "The Witcher: Enhanced Edition")
+	assert.NotContains(t, output, "Witcher 2")
+	assert.Contains(t, output, "1 group(s) of likely duplicates found.")
+
+	output, err = captureCombinedOutput(duplicatesCmd(repo), "--output=json")
+	require.NoError(t, err)
+	var groups []duplicateGroup
+	require.NoError(t, json.Unmarshal([]byte(output), &groups))
+	assert.Equal(t, []duplicateGroup{{Key: "witcher", Games: []gameEntry{
+		{ID: 1, Title: "The Witcher"}, {ID: 3, Title: "The Witcher: Enhanced Edition"},
+	}}}, groups)
+}
+
+func TestDuplicatesCmd_NoDuplicates(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Test Game 1", `{}`)
+
+	output, err := captureCombinedOutput(duplicatesCmd(repo))
+	require.NoError(t, err)
+	assert.Contains(t, output, "No likely duplicates found")
+
+	output, err = captureCombinedOutput(duplicatesCmd(repo), "-o", "json")
+	require.NoError(t, err)
+	assert.JSONEq(t, "[]", output)
+}
@@ -3,20 +3,29 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
 	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 // TestMain sets up the database once for all tests in this package.
@@ -101,6 +110,82 @@ func TestListCmd(t *testing.T) {
 	assert.Contains(t, output, "Test Game 2")
 }
 
+func TestListCmd_FlagsMissingTitles(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Test Game 1", `{}`)
+	addTestGame(t, repo, 2, "", `{}`)
+	addTestGame(t, repo, 3, "Ведьмак", `{}`)
+	output, err := captureCombinedOutput(listCmd(repo))
+	require.NoError(t, err)
+	assert.Contains(t, output, "(missing title)")
+	assert.Contains(t, output, "Ведьмак")
+	assert.Contains(t, output, "1 game(s) have no title")
+}
+
+func TestListCmd_ASCIITitles(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Ведьмак", `{}`)
+	output, err := captureCombinedOutput(listCmd(repo), "--ascii-titles")
+	require.NoError(t, err)
+	assert.Contains(t, output, "Vedmak")
+	assert.NotContains(t, output, "Ведьмак")
+}
+
+func TestListCmd_OutputFormats(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Test Game 1", `{}`)
+	addTestGame(t, repo, 2, "Test Game 2", `{}`)
+	want := []gameEntry{{ID: 1, Title: "Test Game 1"}, {ID: 2, Title: "Test Game 2"}}
+
+	output, err := captureCombinedOutput(listCmd(repo), "--output=json")
+	require.NoError(t, err)
+	var fromJSON []gameEntry
+	require.NoError(t, json.Unmarshal([]byte(output), &fromJSON))
+	assert.Equal(t, want, fromJSON)
+
+	output, err = captureCombinedOutput(listCmd(repo), "-o", "yaml")
+	require.NoError(t, err)
+	var fromYAML []gameEntry
+	require.NoError(t, yaml.Unmarshal([]byte(output), &fromYAML))
+	assert.Equal(t, want, fromYAML)
+
+	output, err = captureCombinedOutput(listCmd(repo), "--output=xml")
+	require.NoError(t, err)
+	assert.Contains(t, output, `Invalid output format "xml"`)
+}
+
+// unreleasedGameData is the data GOG sends for a pre-order of a game that is not released yet.
+const unreleasedGameData = `{"title":"Unreleased Game","isPreOrder":true,"releaseTimestamp":1893456000,` +
+	`"downloads":[],"galaxyDownloads":[],"extras":[],"dlcs":[]}`
+
+func TestListCmd_FlagsGamesWithoutDownloads(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Released Game", `{"title":"Released Game","downloads":[["English",{"windows":[{"name":"setup","size":"1 GB"}]}]]}`)
+	addTestGame(t, repo, 2, "Unreleased Game", unreleasedGameData)
+	addTestGame(t, repo, 3, "Placeholder Game", client.PlaceholderData("Placeholder Game"))
+
+	output, err := captureCombinedOutput(listCmd(repo))
+	require.NoError(t, err)
+	assert.Contains(t, output, "Unreleased Game (no downloadable content)")
+	assert.NotContains(t, output, "Released Game (no downloadable content)")
+	assert.NotContains(t, output, "Placeholder Game (no downloadable content)")
+	assert.Contains(t, output, "1 game(s) have no downloadable content yet")
+
+	output, err = captureCombinedOutput(listCmd(repo), "--output=json")
+	require.NoError(t, err)
+	var entries []gameEntry
+	require.NoError(t, json.Unmarshal([]byte(output), &entries))
+	assert.Equal(t, []gameEntry{
+		{ID: 1, Title: "Released Game"},
+		{ID: 2, Title: "Unreleased Game", NoDownloads: true},
+		{ID: 3, Title: "Placeholder Game"},
+	}, entries)
+}
+
 func TestInfoCmd(t *testing.T) {
 	cleanDBTables(t)
 	repo := db.NewGameRepository(db.GetDB())
@@ -117,6 +202,194 @@ func TestInfoCmd(t *testing.T) {
 	assert.Contains(t, output, "cool game")
 }
 
+const sizedGameData = `{"title":"Sized Game","downloads":[["English",{"windows":[{"name":"setup","size":"1 GB"}]}]],` +
+	`"extras":[{"name":"manual","size":"10 MB"}],` +
+	`"dlcs":[{"title":"DLC","downloads":[["English",{"windows":[{"name":"dlc","size":"500 MB"}]}]],"extras":[]}]}`
+
+func TestInfoCmd_Size(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 11, "Sized Game", sizedGameData)
+
+	output, err := captureCombinedOutput(infoCmd(repo), "11", "--size")
+	require.NoError(t, err)
+	assert.Contains(t, output, `"title": "Sized Game"`)
+	assert.Contains(t, output, "Base game: 1.0 GiB")
+	assert.Contains(t, output, "Extras:    10.0 MiB")
+	assert.Contains(t, output, "DLCs:      500.0 MiB")
+}
+
+func TestInfoCmd_SizeJSON(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 12, "Sized Game", sizedGameData)
+
+	output, err := captureCombinedOutput(infoCmd(repo), "12", "--size", "--json", "--extras=false")
+	require.NoError(t, err)
+
+	var result struct {
+		Game map[string]interface{} `json:"game"`
+		Size struct {
+			Base   int64 `json:"base"`
+			Extras int64 `json:"extras"`
+			DLC    int64 `json:"dlc"`
+			Total  int64 `json:"total"`
+		} `json:"size"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(output), &result))
+	assert.Equal(t, "Sized Game", result.Game["title"])
+	assert.Equal(t, int64(1024*1024*1024), result.Size.Base)
+	assert.Zero(t, result.Size.Extras)
+	assert.Equal(t, int64(500*1024*1024), result.Size.DLC)
+	assert.Equal(t, result.Size.Base+result.Size.DLC, result.Size.Total)
+}
+
+func TestInfoCmd_YAML(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 12, "Sized Game", `{"title":"Sized Game","id":1207658924,"rating":4.5}`)
+
+	output, err := captureCombinedOutput(infoCmd(repo), "12", "--output=yaml")
+	require.NoError(t, err)
+	assert.Contains(t, output, "id: 1207658924", "whole numbers are not written in exponent notation")
+	assert.Contains(t, output, "rating: 4.5")
+	var game map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(output), &game))
+	assert.Equal(t, "Sized Game", game["title"])
+
+	cleanDBTables(t)
+	addTestGame(t, repo, 12, "Sized Game", sizedGameData)
+	output, err = captureCombinedOutput(infoCmd(repo), "12", "--size", "-o", "yaml")
+	require.NoError(t, err)
+	var result struct {
+		Game map[string]interface{} `yaml:"game"`
+		Size struct {
+			Base int64 `yaml:"base"`
+		} `yaml:"size"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(output), &result), "the size estimate is part of the document")
+	assert.Equal(t, "Sized Game", result.Game["title"])
+	assert.Equal(t, int64(1024*1024*1024), result.Size.Base)
+}
+
+func TestInfoCmd_Fields(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 12, "Sized Game", sizedGameData)
+
+	output, err := captureCombinedOutput(infoCmd(repo), "12", "--fields", "title,dlcs")
+	require.NoError(t, err)
+	var game map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(output), &game))
+	assert.Len(t, game, 2)
+	assert.Equal(t, "Sized Game", game["title"])
+	assert.Contains(t, game, "dlcs")
+
+	setLastCliErr(nil)
+	output, _ = captureCombinedOutput(infoCmd(repo), "12", "--fields", "title,rating")
+	require.NotNil(t, getLastCliErr())
+	assert.Equal(t, clierr.Validation, getLastCliErr().Type)
+	assert.Contains(t, output, `Unknown field "rating". The game data has: dlcs, downloads, extras, title`)
+}
+
+func TestInfoCmd_Pager(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 12, "Sized Game", sizedGameData)
+	restore := stdoutIsTerminal
+	t.Cleanup(func() { stdoutIsTerminal = restore })
+	paged := filepath.Join(t.TempDir(), "paged.txt")
+	t.Setenv("PAGER", "cp /dev/stdin "+paged)
+
+	// Without a terminal, the output is printed as is.
+	stdoutIsTerminal = func() bool { return false }
+	output, err := captureCombinedOutput(infoCmd(repo), "12", "--fields", "title")
+	require.NoError(t, err)
+	assert.Contains(t, output, `"title": "Sized Game"`)
+	assert.NoFileExists(t, paged)
+
+	stdoutIsTerminal = func() bool { return true }
+	output, err = captureCombinedOutput(infoCmd(repo), "12", "--fields", "title")
+	require.NoError(t, err)
+	assert.Empty(t, output, "the output goes to the pager")
+	data, err := os.ReadFile(paged)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"title": "Sized Game"`)
+
+	output, err = captureCombinedOutput(infoCmd(repo), "12", "--fields", "title", "--pager=false")
+	require.NoError(t, err)
+	assert.Contains(t, output, `"title": "Sized Game"`)
+
+	t.Setenv("PAGER", "cat")
+	assert.Nil(t, pagerCommand(), "cat turns paging off")
+}
+
+func TestLanguagesCmd(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	data := `{"title":"Polyglot","downloads":[["English",{"windows":[]}],["Deutsch",{"windows":[]}],["English",{"linux":[]}],["Klingon",{"windows":[]}]],` +
+		`"dlcs":[{"title":"Expansion","downloads":[["Polski",{"windows":[]}]]}]}`
+	addTestGame(t, repo, 13, "Polyglot", data)
+
+	output, err := captureCombinedOutput(languagesCmd(repo), "13")
+	require.NoError(t, err)
+	assert.Contains(t, output, "English")
+	assert.Contains(t, output, "Deutsch")
+	assert.Contains(t, output, "| de ")
+	assert.Contains(t, output, "DLC: Expansion")
+	assert.Contains(t, output, "| pl ")
+	assert.Contains(t, output, "Klingon")
+	assert.Equal(t, 1, strings.Count(output, "English"))
+}
+
+func TestInfoCmd_Files(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	data := `{"title":"Porter","downloads":[["English",{"windows":[{"manualUrl":"/w1","name":"Porter","size":"1 GB"}],` +
+		`"linux":[{"manualUrl":"/l1","name":"Porter","size":"900 MB"}]}]],"extras":[{"manualUrl":"/e1","name":"Manual","size":"2 MB"}]}`
+	addTestGame(t, repo, 15, "Porter", data)
+
+	output, err := captureCombinedOutput(infoCmd(repo), "15", "--files")
+	require.NoError(t, err)
+	assert.Regexp(t, `1\s+\|\s+Porter\s+\|\s+English\s+\|\s+windows\s+\|\s+Porter\s+\|\s+1 GB`, output)
+	assert.Regexp(t, `2\s+\|\s+Porter\s+\|\s+English\s+\|\s+linux`, output)
+	assert.Regexp(t, `3\s+\|\s+Porter\s+\|\s+-\s+\|\s+extra\s+\|\s+Manual`, output)
+
+	game, err := client.ParseGameData(data)
+	require.NoError(t, err)
+	_, e := selectGameFile(game, downloadOptions{fileName: "Porter"})
+	require.NotNil(t, e)
+	assert.Equal(t, clierr.Validation, e.Type, "an ambiguous name is a usage error")
+	f, e := selectGameFile(game, downloadOptions{fileName: "l1"})
+	require.Nil(t, e)
+	assert.Equal(t, 2, f.Index)
+	_, e = selectGameFile(game, downloadOptions{fileIndex: 4})
+	require.NotNil(t, e)
+	assert.Equal(t, clierr.NotFound, e.Type)
+}
+
+func TestPlatformsCmd(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	data := `{"title":"Porter","downloads":[` +
+		`["English",{"windows":[{"manualUrl":"/w1","name":"setup"},{"manualUrl":"/w2","name":"patch"}],"linux":[{"manualUrl":"/l1","name":"sh"}]}],` +
+		`["Deutsch",{"windows":[{"manualUrl":"/w1","name":"setup"}]}]],` +
+		`"dlcs":[{"title":"Expansion","downloads":[["English",{"windows":[{"manualUrl":"/d1","name":"dlc"}]}]]}]}`
+	addTestGame(t, repo, 14, "Porter", data)
+
+	output, err := captureCombinedOutput(platformsCmd(repo), "14")
+	require.NoError(t, err)
+	assert.Regexp(t, `Porter\s+\|\s+2 file\(s\)\s+\|\s+-\s+\|\s+1 file\(s\)`, output)
+	assert.Regexp(t, `DLC: Expansion\s+\|\s+1 file\(s\)\s+\|\s+-\s+\|\s+-`, output)
+
+	setLastCliErr(nil)
+	t.Cleanup(func() { setLastCliErr(nil) })
+	_, err = captureCombinedOutput(platformsCmd(repo), "999")
+	require.NoError(t, err)
+	require.NotNil(t, getLastCliErr())
+	assert.Equal(t, clierr.NotFound, getLastCliErr().Type)
+}
+
 func TestSearchCmd(t *testing.T) {
 	cleanDBTables(t)
 	repo := db.NewGameRepository(db.GetDB())
@@ -136,6 +409,47 @@ func TestSearchCmd(t *testing.T) {
 	assert.Contains(t, output, "ID Game")
 }
 
+func TestSearchCmd_JSONWithoutMatches(t *testing.T) {
+	cleanDBTables(t)
+	output, err := captureCombinedOutput(searchCmd(db.NewGameRepository(db.GetDB())), "nothing", "--output=json")
+	require.NoError(t, err)
+	assert.Equal(t, "[]", strings.TrimSpace(output))
+}
+
+func TestSearchCmd_MatchesIDAndTitle(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1942, "Strike Force", `{}`)
+	addTestGame(t, repo, 50, "Battle of 1942", `{}`)
+	addTestGame(t, repo, 51, "Unrelated", `{}`)
+
+	output, err := captureCombinedOutput(searchCmd(repo), "1942")
+	require.NoError(t, err)
+	assert.Contains(t, output, "Strike Force")
+	assert.Contains(t, output, "Battle of 1942")
+	assert.NotContains(t, output, "Unrelated")
+	assert.Less(t, strings.Index(output, "Strike Force"), strings.Index(output, "Battle of 1942"), "the ID match is listed first")
+
+	// A game whose ID and title both match is listed once.
+	addTestGame(t, repo, 52, "Game 52", `{}`)
+	games, err := searchCatalogue(context.Background(), repo, "52")
+	require.NoError(t, err)
+	require.Len(t, games, 1)
+	assert.Equal(t, 52, games[0].ID)
+
+	// Titles that start with the query come before the other title matches.
+	addTestGame(t, repo, 53, "Force of Nature", `{}`)
+	games, err = searchCatalogue(context.Background(), repo, "force")
+	require.NoError(t, err)
+	require.Len(t, games, 2)
+	assert.Equal(t, []int{53, 1942}, []int{games[0].ID, games[1].ID})
+
+	output, err = captureCombinedOutput(searchCmd(repo), "1942", "--id")
+	require.NoError(t, err)
+	assert.Contains(t, output, "Strike Force")
+	assert.NotContains(t, output, "Battle of 1942")
+}
+
 func TestExportCmd(t *testing.T) {
 	cleanDBTables(t)
 	repo := db.NewGameRepository(db.GetDB())
@@ -166,6 +480,173 @@ func TestExportCmd(t *testing.T) {
 	assert.True(t, foundJSONFile, "Should export at least one JSON file")
 }
 
+func TestExportCmd_JSONStreamsAllGames(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	for id := 1; id <= 150; id++ {
+		addTestGame(t, repo, id, fmt.Sprintf("Streamed Game %d", id), `{"title":"x"}`)
+	}
+	tmpExportDir := t.TempDir()
+	exportCommand := exportCmd(repo)
+	output, err := captureCombinedOutput(exportCommand, tmpExportDir, "--format", "json")
+	require.NoError(t, err)
+	assert.Contains(t, output, "exported successfully")
+
+	files, err := os.ReadDir(tmpExportDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	content, err := os.ReadFile(filepath.Join(tmpExportDir, files[0].Name()))
+	require.NoError(t, err)
+	var games []db.Game
+	require.NoError(t, json.Unmarshal(content, &games))
+	require.Len(t, games, 150)
+	assert.Equal(t, 1, games[0].ID)
+	assert.Equal(t, "Streamed Game 150", games[149].Title)
+}
+
+func TestExportCmd_Filter(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 70, "Witcher 3", `{}`)
+	addTestGame(t, repo, 71, "The Witcher Adventure Game", `{}`)
+	addTestGame(t, repo, 72, "Cyberpunk 2077", `{}`)
+
+	for _, format := range []string{"json", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			output, err := captureCombinedOutput(exportCmd(repo), dir, "--format", format, "--filter", "WITCHER")
+			require.NoError(t, err)
+			assert.Contains(t, output, "exported successfully")
+
+			files, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Len(t, files, 1)
+			content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+			require.NoError(t, err)
+			assert.Contains(t, string(content), "Witcher 3")
+			assert.Contains(t, string(content), "The Witcher Adventure Game")
+			assert.NotContains(t, string(content), "Cyberpunk")
+		})
+	}
+
+	dir := t.TempDir()
+	output, err := captureCombinedOutput(exportCmd(repo), dir, "--filter", "Baldur")
+	require.NoError(t, err)
+	assert.Contains(t, output, `No games match the filter "Baldur"`)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestExportCmd_DetailedCSV(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	data := `{"title":"Detailed Game","downloads":[["English",{"windows":[{"name":"Setup","size":"1 GB"}],"linux":[{"name":"Setup","size":"2 GB"}]}],` +
+		`["Deutsch",{"windows":[{"name":"Setup","size":"1 GB"}]}]],"extras":[{"name":"Manual","size":"1 MB"}],` +
+		`"dlcs":[{"title":"Expansion","downloads":[]}]}`
+	addTestGame(t, repo, 60, "Detailed Game", data)
+	addTestGame(t, repo, 61, "Broken Game", "not json")
+	tmpExportDir := t.TempDir()
+
+	_, err := captureCombinedOutput(exportCmd(repo), tmpExportDir, "--format", "csv", "--detailed")
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(tmpExportDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	f, err := os.Open(filepath.Join(tmpExportDir, files[0].Name()))
+	require.NoError(t, err)
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, detailedCSVHeader, records[0])
+	assert.Equal(t, []string{"60", "Detailed Game", "windows, linux", "en, de", "1",
+		strconv.FormatInt(3*1024*1024*1024+1024*1024, 10), "https://www.gog.com/en/games?query=Detailed+Game"}, records[1])
+	assert.Equal(t, []string{"61", "Broken Game", "", "", "", "", "https://www.gog.com/en/games?query=Broken+Game"}, records[2])
+}
+
+func TestExportCmd_CSVEscapesTitles(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	title := "Heroes, \"Villains\"\nand Others"
+	addTestGame(t, repo, 70, title, "{}")
+	tmpExportDir := t.TempDir()
+
+	_, err := captureCombinedOutput(exportCmd(repo), tmpExportDir, "--format", "csv")
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(tmpExportDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	f, err := os.Open(filepath.Join(tmpExportDir, files[0].Name()))
+	require.NoError(t, err)
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"ID", "Title"}, {"70", title}}, records)
+}
+
+func TestExportCmd_EmptyCatalogue(t *testing.T) {
+	cleanDBTables(t)
+	tmpExportDir := t.TempDir()
+	output, err := captureCombinedOutput(exportCmd(db.NewGameRepository(db.GetDB())), tmpExportDir, "--format", "json")
+	require.NoError(t, err)
+	assert.Contains(t, output, "No games found to export")
+	files, err := os.ReadDir(tmpExportDir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestImportCmd_RoundTripsExport(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 80, "Round Trip One", `{"title":"Round Trip One"}`)
+	addTestGame(t, repo, 81, "Round Trip Two", `{"title":"Round Trip Two"}`)
+	tmpExportDir := t.TempDir()
+	_, err := captureCombinedOutput(exportCmd(repo), tmpExportDir, "--format", "json")
+	require.NoError(t, err)
+	files, err := os.ReadDir(tmpExportDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	exportFile := filepath.Join(tmpExportDir, files[0].Name())
+
+	cleanDBTables(t)
+	addTestGame(t, repo, 81, "Old Title", "{}")
+	output, err := captureCombinedOutput(importCmd(repo), exportFile)
+	require.NoError(t, err)
+	assert.Contains(t, output, "Imported 2 game(s): 1 added, 1 updated.")
+
+	game, err := repo.GetByID(context.Background(), 81)
+	require.NoError(t, err)
+	require.NotNil(t, game)
+	assert.Equal(t, "Round Trip Two", game.Title)
+	assert.Equal(t, `{"title":"Round Trip Two"}`, game.Data)
+}
+
+func TestImportCmd_RejectsInvalidFiles(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	dir := t.TempDir()
+	cases := map[string]string{
+		"not_array.json":    `{"id":1,"title":"x","data":"{}"}`,
+		"unknown_key.json":  `[{"id":1,"title":"x","data":"{}","extra":true}]`,
+		"bad_id.json":       `[{"id":0,"title":"x","data":"{}"}]`,
+		"bad_data.json":     `[{"id":1,"title":"x","data":"{"}]`,
+		"duplicate_id.json": `[{"id":1,"title":"x","data":"{}"},{"id":1,"title":"y","data":"{}"}]`,
+	}
+	for name, content := range cases {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		output, err := captureCombinedOutput(importCmd(repo), path)
+		require.NoError(t, err)
+		assert.Contains(t, output, "Invalid catalogue export file", name)
+	}
+	games, err := repo.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, games, "invalid files must not import anything")
+}
+
 func TestRefreshCmd(t *testing.T) {
 	cleanDBTables(t)
 	storer := &mockTokenStorer{getTokenErr: errors.New("mock db error")}
@@ -180,6 +661,87 @@ func TestRefreshCmd(t *testing.T) {
 	assert.Contains(t, output, expectedErrorMsg)
 }
 
+// newProductsServer serves a GOG account that owns the games 1 ("Game One, Renamed") and 2 ("Game Two"), with the
+// list of owned products in two pages and the details of both games.
+func newProductsServer(t *testing.T) *atomic.Int32 {
+	t.Helper()
+	var detailRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/account/getFilteredProducts":
+			if r.URL.Query().Get("page") == "2" {
+				_, _ = w.Write([]byte(`{"totalPages":2,"products":[{"id":2,"title":"Game Two"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"totalPages":2,"products":[{"id":1,"title":"Game One, Renamed"}]}`))
+		case "/user/data/games":
+			_, _ = w.Write([]byte(`{"owned":[1,2]}`))
+		case "/account/gameDetails/1.json":
+			detailRequests.Add(1)
+			_, _ = w.Write([]byte(`{"title":"Game One, Renamed","downloads":[]}`))
+		case "/account/gameDetails/2.json":
+			detailRequests.Add(1)
+			_, _ = w.Write([]byte(`{"title":"Game Two","downloads":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("GOGG_EMBED_BASE", server.URL)
+	return &detailRequests
+}
+
+func TestRefreshCmd_CatalogueOnlyThenOnlyMissing(t *testing.T) {
+	cleanDBTables(t)
+	detailRequests := newProductsServer(t)
+	repo := db.NewGameRepository(db.GetDB())
+	const oldDetails = `{"title":"Game One","downloads":[]}`
+	addTestGame(t, repo, 1, "Game One", oldDetails)
+	authService := auth.NewService(&mockTokenStorer{}, &mockTokenRefresher{})
+
+	output, err := captureCombinedOutput(refreshCmd(authService), "--catalogue-only")
+	require.NoError(t, err)
+	assert.Contains(t, output, "1 new game(s), 1 renamed")
+	assert.Contains(t, output, "--only-missing")
+	assert.Zero(t, detailRequests.Load(), "a catalogue-only refresh must not fetch the details of games")
+
+	one, err := repo.GetByID(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Game One, Renamed", one.Title)
+	assert.Equal(t, oldDetails, one.Data, "the details of a game already in the catalogue are kept")
+	two, err := repo.GetByID(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Game Two", two.Title)
+	assert.True(t, client.IsPlaceholder(two.Data))
+
+	// Only the game added without details is fetched; the other keeps its data.
+	output, err = captureCombinedOutput(refreshCmd(authService), "--only-missing")
+	require.NoError(t, err)
+	assert.Contains(t, output, "Refreshed the game catalogue successfully.")
+	assert.Equal(t, int32(1), detailRequests.Load())
+	two, err = repo.GetByID(context.Background(), 2)
+	require.NoError(t, err)
+	assert.False(t, client.IsPlaceholder(two.Data))
+	one, err = repo.GetByID(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, oldDetails, one.Data)
+
+	output, err = captureCombinedOutput(refreshCmd(authService), "--only-missing")
+	require.NoError(t, err)
+	assert.Contains(t, output, "No games in the catalogue are missing their details.")
+	assert.Equal(t, int32(1), detailRequests.Load())
+}
+
+func TestRefreshCmd_CatalogueOnlyAndOnlyMissingConflict(t *testing.T) {
+	authService := auth.NewService(&mockTokenStorer{}, &mockTokenRefresher{})
+	setLastCliErr(nil)
+	output, err := captureCombinedOutput(refreshCmd(authService), "--catalogue-only", "--only-missing")
+	require.NoError(t, err)
+	assert.Contains(t, output, "cannot be combined")
+	require.NotNil(t, getLastCliErr())
+	assert.Equal(t, clierr.Validation, getLastCliErr().Type)
+}
+
 func TestCatalogueCliErr_NotFound(t *testing.T) {
 	cleanDBTables(t)
 	repo := db.NewGameRepository(db.GetDB())
@@ -199,3 +761,13 @@ func TestCatalogueCliErr_InvalidExportFormat(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, output, "Invalid export format")
 }
+
+func TestRefreshCmd_GameIDsConflict(t *testing.T) {
+	authService := auth.NewService(&mockTokenStorer{}, &mockTokenRefresher{})
+	t.Cleanup(func() { setLastCliErr(nil) })
+	output, err := captureCombinedOutput(refreshCmd(authService), "--game-ids", "1,2", "--only-missing")
+	require.NoError(t, err)
+	assert.Contains(t, output, "--game-ids cannot be combined")
+	require.NotNil(t, getLastCliErr())
+	assert.Equal(t, clierr.Validation, getLastCliErr().Type)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/olekukonko/tablewriter"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// Problems of a catalogue entry found by catalogue validate.
+const (
+	entryEmpty       = "empty data"
+	entryUnparseable = "unparseable data"
+)
+
+// invalidEntry is a game of the catalogue whose data cannot be used.
+type invalidEntry struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Problem string `json:"problem"`
+	Error   string `json:"error,omitempty"`
+}
+
+// catalogueValidation is the result of catalogue validate.
+type catalogueValidation struct {
+	Checked int `json:"checked"`
+	Valid   int `json:"valid"`
+	// Placeholders are the games added by 'catalogue refresh --catalogue-only' whose details have not been
+	// fetched yet. They are not broken, but have no files to download.
+	Placeholders int            `json:"placeholders"`
+	Invalid      []invalidEntry `json:"invalid"`
+}
+
+func validateCmd(repo db.GameRepository) *cobra.Command {
+	var jsonOutput bool
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check that the data of every game in the catalogue can be parsed",
+		Long: "Check the data of every game in the catalogue and show the games whose data is empty or cannot be parsed,\n" +
+			"with the command that fetches just those games again. Nothing is changed.",
+		Args: cobra.NoArgs,
+		Run:  func(cmd *cobra.Command, args []string) { showCatalogueValidation(cmd, repo, jsonOutput) },
+	}
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the result as JSON")
+	return cmd
+}
+
+// validateCatalogue parses the data of every game in the catalogue, a batch at a time.
+func validateCatalogue(cmd *cobra.Command, repo db.GameRepository) (catalogueValidation, error) {
+	result := catalogueValidation{Invalid: []invalidEntry{}}
+	err := repo.Each(cmd.Context(), func(g db.Game) error {
+		result.Checked++
+		if strings.TrimSpace(g.Data) == "" {
+			result.Invalid = append(result.Invalid, invalidEntry{ID: g.ID, Title: g.Title, Problem: entryEmpty})
+			return nil
+		}
+		if _, err := client.ParseGameData(g.Data); err != nil {
+			result.Invalid = append(result.Invalid, invalidEntry{ID: g.ID, Title: g.Title, Problem: entryUnparseable, Error: err.Error()})
+			return nil
+		}
+		if client.IsPlaceholder(g.Data) {
+			result.Placeholders++
+			return nil
+		}
+		result.Valid++
+		return nil
+	})
+	return result, err
+}
+
+func showCatalogueValidation(cmd *cobra.Command, repo db.GameRepository, jsonOutput bool) {
+	result, err := validateCatalogue(cmd, repo)
+	if err != nil {
+		reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to read the game catalogue", err))
+		log.Error().Err(err).Msg("Failed to read the games of the game catalogue.")
+		return
+	}
+	if jsonOutput {
+		if err := printDocument(cmd, outputJSON, result); err != nil {
+			reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to encode the result", err))
+			return
+		}
+	} else {
+		printCatalogueValidation(cmd, result)
+	}
+	if len(result.Invalid) > 0 {
+		reportCliErr(cmd, clierr.New(clierr.Validation, fmt.Sprintf("%d game(s) in the catalogue have data that cannot be used", len(result.Invalid)), nil))
+	}
+}
+
+func printCatalogueValidation(cmd *cobra.Command, result catalogueValidation) {
+	if len(result.Invalid) > 0 {
+		table := tablewriter.NewWriter(cmd.OutOrStdout())
+		table.SetHeader([]string{"Game ID", "Title", "Problem"})
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAutoWrapText(false)
+		for _, entry := range result.Invalid {
+			table.Append([]string{strconv.Itoa(entry.ID), displayTitle(entry.Title, false), entry.Problem})
+		}
+		table.Render()
+	}
+	cmd.Printf("Checked %d game(s): %d valid, %d broken, %d without details.\n",
+		result.Checked, result.Valid, len(result.Invalid), result.Placeholders)
+	if len(result.Invalid) > 0 {
+		ids := make([]string, len(result.Invalid))
+		for i, entry := range result.Invalid {
+			ids[i] = strconv.Itoa(entry.ID)
+		}
+		cmd.Printf("Run 'gogg catalogue refresh --game-ids %s' to fetch the broken games again.\n", strings.Join(ids, ","))
+	}
+	if result.Placeholders > 0 {
+		cmd.Println("Run 'gogg catalogue refresh --only-missing' to fetch the details of the games without them.")
+	}
+}
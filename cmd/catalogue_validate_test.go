@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCmd(t *testing.T) {
+	cleanDBTables(t)
+	t.Cleanup(func() { setLastCliErr(nil) })
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Good Game", `{"title": "Good Game", "downloads": []}`)
+	addTestGame(t, repo, 2, "Broken Game", `{"title": "Broken`)
+	addTestGame(t, repo, 3, "Empty Game", "  ")
+	addTestGame(t, repo, 4, "New Game", client.PlaceholderData("New Game"))
+
+	output, _ := captureCombinedOutput(validateCmd(repo))
+	assert.True(t, containsAll(output, []string{"Broken Game", entryUnparseable, "Empty Game", entryEmpty,
+		"Checked 4 game(s): 1 valid, 2 broken, 1 without details.", "gogg catalogue refresh --game-ids 2,3",
+		"gogg catalogue refresh --only-missing"}), output)
+	assert.NotContains(t, output, "Good Game")
+	require.NotNil(t, getLastCliErr())
+	assert.Equal(t, clierr.Validation, getLastCliErr().Type)
+
+	setLastCliErr(nil)
+	cmd := validateCmd(repo)
+	var stdout, stderr bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"--json"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, stderr.String(), "2 game(s) in the catalogue have data that cannot be used")
+	var result catalogueValidation
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &result), stdout.String())
+	assert.Equal(t, 4, result.Checked)
+	assert.Equal(t, 1, result.Valid)
+	assert.Equal(t, 1, result.Placeholders)
+	require.Len(t, result.Invalid, 2)
+	assert.Equal(t, invalidEntry{ID: 3, Title: "Empty Game", Problem: entryEmpty}, result.Invalid[1])
+	assert.Equal(t, 2, result.Invalid[0].ID)
+	assert.NotEmpty(t, result.Invalid[0].Error)
+}
+
+func TestValidateCmd_AllValid(t *testing.T) {
+	cleanDBTables(t)
+	t.Cleanup(func() { setLastCliErr(nil) })
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Good Game", `{"title": "Good Game", "downloads": []}`)
+
+	output, err := captureCombinedOutput(validateCmd(repo))
+	require.NoError(t, err)
+	assert.Equal(t, "Checked 1 game(s): 1 valid, 0 broken, 0 without details.\n", output)
+	assert.Nil(t, getLastCliErr())
+}
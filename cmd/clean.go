@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/habedi/gogg/pkg/progress"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func cleanCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "clean [dir]",
+		Short: "Remove incomplete downloads and orphaned checksum files from a download directory",
+		Long: "Find the files under a download directory that are left over from interrupted downloads and remove them\n" +
+			"after asking: files in a game folder that are smaller than the catalogue says (using the metadata.json that\n" +
+			"was saved with the download), temporary .part and .tmp files, and checksum files like setup.exe.sha256\n" +
+			"whose file is gone. Files that match their expected size and files the catalogue does not list are kept.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if e := runClean(cmd, args[0], dryRun); e != nil {
+				reportCliErr(cmd, e)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the files that would be removed without removing them")
+	return cmd
+}
+
+func runClean(cmd *cobra.Command, dir string, dryRun bool) *clierr.Error {
+	plan, err := operations.PlanClean(dir)
+	if err != nil {
+		return clierr.New(clierr.NotFound, "Failed to read the download directory", err)
+	}
+	if len(plan.Entries) == 0 {
+		cmd.Println("No incomplete or orphaned files found.")
+		return nil
+	}
+
+	if dryRun {
+		cmd.Printf("Dry run: %d file(s) would be removed, reclaiming %s:\n", len(plan.Entries), progress.FormatBytes(plan.TotalBytes))
+	} else {
+		cmd.Printf("Found %d file(s) to remove, reclaiming %s:\n", len(plan.Entries), progress.FormatBytes(plan.TotalBytes))
+	}
+	for _, e := range plan.Entries {
+		if e.Reason == operations.CleanIncomplete {
+			cmd.Printf("  %s (%s: %s of about %s)\n", e.Path, e.Reason, progress.FormatBytes(e.Size), progress.FormatBytes(e.ExpectedSize))
+		} else {
+			cmd.Printf("  %s (%s, %s)\n", e.Path, e.Reason, progress.FormatBytes(e.Size))
+		}
+	}
+	if dryRun {
+		return nil
+	}
+	if !confirm("Remove these files? [y/N]: ") {
+		cmd.Println("Cleaning skipped.")
+		return nil
+	}
+
+	removed := operations.RemoveFiles(plan.Files())
+	for _, f := range removed {
+		log.Info().Str("file", f).Msg("Removed leftover file")
+	}
+	cmd.Printf("Removed %d file(s).\n", len(removed))
+	return nil
+}
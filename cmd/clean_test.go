@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCleanFixture(t *testing.T) (string, string) {
+	t.Helper()
+	downloadDir := t.TempDir()
+	gameDir := filepath.Join(downloadDir, "audit-game")
+	require.NoError(t, os.MkdirAll(filepath.Join(gameDir, "extras"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(gameDir, "metadata.json"), []byte(auditGameData), 0o644))
+	for name, size := range map[string]int64{
+		"setup_audit_game_1.1.exe": 2 << 20,
+		"extras/soundtrack.zip":    1 << 10,
+		"removed.exe.sha256":       10,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(gameDir, name), make([]byte, size), 0o644))
+	}
+	return downloadDir, gameDir
+}
+
+func TestCleanCmd_DryRun(t *testing.T) {
+	downloadDir, gameDir := setupCleanFixture(t)
+
+	output, err := captureCombinedOutput(cleanCmd(), downloadDir, "--dry-run")
+	require.NoError(t, err)
+	assert.Contains(t, output, "Dry run: 2 file(s) would be removed")
+	assert.Contains(t, output, "soundtrack.zip (incomplete: 1.0 KiB of about 3.0 MiB)")
+	assert.Contains(t, output, "removed.exe.sha256 (orphaned checksum")
+	assert.NotContains(t, output, "setup_audit_game_1.1.exe")
+	assert.FileExists(t, filepath.Join(gameDir, "extras", "soundtrack.zip"))
+}
+
+func TestCleanCmd_NonInteractiveRemovesFiles(t *testing.T) {
+	downloadDir, gameDir := setupCleanFixture(t)
+	orig := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	defer func() { stdinIsTerminal = orig }()
+
+	output, err := captureCombinedOutput(cleanCmd(), downloadDir)
+	require.NoError(t, err)
+	assert.Contains(t, output, "Removed 2 file(s).")
+	assert.NoFileExists(t, filepath.Join(gameDir, "extras", "soundtrack.zip"))
+	assert.NoFileExists(t, filepath.Join(gameDir, "removed.exe.sha256"))
+	assert.FileExists(t, filepath.Join(gameDir, "setup_audit_game_1.1.exe"))
+
+	output, err = captureCombinedOutput(cleanCmd(), downloadDir)
+	require.NoError(t, err)
+	assert.Contains(t, output, "No incomplete or orphaned files found.")
+}
+
+func TestCleanCmd_YesRemovesFilesWithoutAsking(t *testing.T) {
+	downloadDir, gameDir := setupCleanFixture(t)
+	origTerminal, origYes := stdinIsTerminal, assumeYes
+	stdinIsTerminal = func() bool { return true }
+	assumeYes = true
+	defer func() { stdinIsTerminal, assumeYes = origTerminal, origYes }()
+
+	output, err := captureCombinedOutput(cleanCmd(), downloadDir)
+	require.NoError(t, err)
+	assert.Contains(t, output, "Removed 2 file(s).")
+	assert.NoFileExists(t, filepath.Join(gameDir, "extras", "soundtrack.zip"))
+}
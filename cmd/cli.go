@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/habedi/gogg/auth"
 	"github.com/habedi/gogg/client"
@@ -16,20 +18,36 @@ var exitCodeByType = map[clierr.Type]int{
 	clierr.Validation: 2,
 	clierr.NotFound:   3,
 	clierr.Download:   4,
+	clierr.Auth:       5,
+	clierr.Network:    6,
+	clierr.Partial:    7,
 	clierr.Internal:   1,
 }
 
 func Execute() {
+	// Deferred first, so it runs after the database is closed.
+	defer recoverCrash()
+	// The log is set up and the database is opened before the command line is parsed, so --log-format and
+	// --db-path are looked up directly.
+	if e := configureLogFormat(os.Args[1:]); e != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+e.Message)
+		os.Exit(exitCodeByType[e.Type])
+	}
+	if path, ok := dbPathFromArgs(os.Args[1:]); ok {
+		db.Path = path
+	}
 	initializeDatabase()
 	defer closeDatabase()
 
 	gameRepo := db.NewGameRepository(db.GetDB())
 	tokenRepo := db.NewTokenRepository(db.GetDB())
-	gogClient := &client.GogClient{TokenURL: "https://auth.gog.com/token"}
+	gogClient := client.NewGogClient()
 	authService := auth.NewServiceWithRepo(tokenRepo, gogClient)
 
 	rootCmd := createRootCmd(authService, gogClient, gameRepo)
 	rootCmd.PersistentFlags().DurationP("timeout", "T", 0, "Global timeout for command execution (like 30s or 2m). 0 means no timeout")
+	rootCmd.PersistentFlags().String(dbPathFlag, "", "Path of the database file to use for this run; takes precedence over GOGG_HOME and XDG_DATA_HOME")
+	rootCmd.PersistentFlags().String(logFormatFlag, "", "Format of the log [console, json]; takes precedence over GOGG_LOG_FORMAT (default console when stderr is a terminal, json otherwise)")
 	var cancel context.CancelFunc
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		to, err := cmd.Flags().GetDuration("timeout")
@@ -41,6 +59,13 @@ func Execute() {
 			ctx, cancel = context.WithTimeout(ctx, to)
 		}
 		cmd.SetContext(ctx)
+		if e := checkOffline(cmd); e != nil {
+			reportCliErr(cmd, e)
+			// The error has been printed and is turned into the exit code below.
+			cmd.SilenceErrors, cmd.SilenceUsage = true, true
+			return e
+		}
+		warnIfSessionExpired(cmd, authService)
 		return nil
 	}
 	rootCmd.PersistentPostRun = func(cmd *cobra.Command, args []string) {
@@ -49,7 +74,7 @@ func Execute() {
 		}
 	}
 
-	if err := rootCmd.Execute(); err != nil {
+	if err := rootCmd.Execute(); err != nil && getLastCliErr() == nil {
 		log.Error().Err(err).Msg("Command execution failed.")
 		os.Exit(1)
 	}
@@ -70,12 +95,28 @@ func createRootCmd(authService *auth.Service, gogClient *client.GogClient, gameR
 	rootCmd.AddCommand(
 		catalogueCmd(authService, gameRepo),
 		downloadCmd(authService),
+		mirrorCmd(authService),
 		versionCmd(),
 		loginCmd(gogClient),
+		authCmd(authService),
+		accountCmd(authService),
 		fileCmd(),
+		topLevelHashCmd(),
+		auditCmd(gameRepo),
+		verifyCmd(),
+		writeMetadataCmd(gameRepo),
+		inspectCmd(),
+		cleanCmd(),
+		backupCmd(),
+		restoreCmd(),
 		guiCmd(authService),
+		configCmd(),
 	)
 
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Answer yes to every confirmation, like removing files or replacing the database, for scripts and scheduled runs")
+	rootCmd.PersistentFlags().BoolVar(&noColor, noColorFlag, false, "Do not colour the output; NO_COLOR does the same. Colours and progress bars are used only on a terminal anyway")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Work only from the local catalogue and files; commands that need to connect to GOG, like download and catalogue refresh, fail instead")
+	addConfigDump(rootCmd)
 	rootCmd.CompletionOptions.HiddenDefaultCmd = true
 	rootCmd.SetHelpCommand(&cobra.Command{
 		Use:    "no-help",
@@ -85,6 +126,32 @@ func createRootCmd(authService *auth.Service, gogClient *client.GogClient, gameR
 	return rootCmd
 }
 
+// dbPathFlag is the name of the global flag that overrides the location of the database.
+const dbPathFlag = "db-path"
+
+// dbPathFromArgs returns the value of --db-path in args, accepting both "--db-path=x" and "--db-path x".
+// Arguments after "--" are not considered.
+func dbPathFromArgs(args []string) (string, bool) {
+	return flagFromArgs(args, dbPathFlag)
+}
+
+// flagFromArgs returns the value of the flag name in args, for the global flags that are needed before the
+// command line is parsed. It accepts both "--name=x" and "--name x", and ignores the arguments after "--".
+func flagFromArgs(args []string, name string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--"+name+"="); ok {
+			return value, value != ""
+		}
+		if arg == "--"+name && i+1 < len(args) {
+			return args[i+1], args[i+1] != ""
+		}
+	}
+	return "", false
+}
+
 func initializeDatabase() {
 	if err := db.InitDB(); err != nil {
 		log.Error().Err(err).Msg("Failed to initialize database")
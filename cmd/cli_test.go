@@ -46,13 +46,58 @@ func TestCreateRootCmd(t *testing.T) {
 	}
 }
 
+// keepTestDB restores the database of the package tests after a test that opens and closes its own.
+func keepTestDB(t *testing.T) {
+	t.Helper()
+	originalDB, originalPath := db.Db, db.Path
+	t.Cleanup(func() { db.Db, db.Path = originalDB, originalPath })
+}
+
 func TestInitializeAndCloseDatabase(t *testing.T) {
+	keepTestDB(t)
 	tmpDir := t.TempDir()
 	db.Path = filepath.Join(tmpDir, "games.db")
 	initializeDatabase()
 	closeDatabase()
 }
 
+func TestDBPathFromArgs(t *testing.T) {
+	for _, tc := range []struct {
+		args     []string
+		expected string
+		ok       bool
+	}{
+		{[]string{"catalogue", "list"}, "", false},
+		{[]string{"--db-path", "/tmp/a.db", "catalogue", "list"}, "/tmp/a.db", true},
+		{[]string{"catalogue", "list", "--db-path=/tmp/b.db"}, "/tmp/b.db", true},
+		{[]string{"catalogue", "list", "--db-path="}, "", false},
+		{[]string{"catalogue", "list", "--db-path"}, "", false},
+		{[]string{"file", "hash", "--", "--db-path=/tmp/c.db"}, "", false},
+	} {
+		path, ok := dbPathFromArgs(tc.args)
+		if path != tc.expected || ok != tc.ok {
+			t.Errorf("dbPathFromArgs(%q) = (%q, %v), want (%q, %v)", tc.args, path, ok, tc.expected, tc.ok)
+		}
+	}
+}
+
+func TestInitializeDatabase_CreatesParentOfDBPath(t *testing.T) {
+	keepTestDB(t)
+	t.Setenv("GOGG_HOME", t.TempDir())
+	db.ConfigurePath()
+
+	path, ok := dbPathFromArgs([]string{"--db-path", filepath.Join(t.TempDir(), "nested", "dir", "copy.db")})
+	if !ok {
+		t.Fatal("expected --db-path to be found")
+	}
+	db.Path = path
+	initializeDatabase()
+	closeDatabase()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected database at %s: %v", path, err)
+	}
+}
+
 func TestExecuteFailure(t *testing.T) {
 	if os.Getenv("TEST_EXECUTE_FAILURE") == "1" {
 		authService := auth.NewService(&mockAuthStorer{}, &mockAuthRefresher{})
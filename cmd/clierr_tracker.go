@@ -1,8 +1,59 @@
 package cmd
 
-import "github.com/habedi/gogg/pkg/clierr"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/validation"
+	"github.com/spf13/cobra"
+)
 
 var lastCliErr *clierr.Error
 
 func setLastCliErr(e *clierr.Error) { lastCliErr = e }
 func getLastCliErr() *clierr.Error  { return lastCliErr }
+
+// reportCliErr prints e to the command's error stream and records it, so the process exits with the code of its type.
+func reportCliErr(cmd *cobra.Command, e *clierr.Error) {
+	cmd.PrintErrln("Error: " + e.Message)
+	setLastCliErr(e)
+}
+
+// wrapFetchErr is clierr.Wrap for the errors of requests to GOG. A request that failed on every attempt is a
+// Network error whose message tells how often it was tried and how the last attempt failed, instead of msg.
+func wrapFetchErr(t clierr.Type, action, msg string, err error) *clierr.Error {
+	var retriesErr *client.RetriesExhaustedError
+	if errors.As(err, &retriesErr) {
+		return clierr.New(clierr.Network, fmt.Sprintf("Failed to %s: %s", action, retriesErr), err)
+	}
+	return clierr.Wrap(t, msg, err)
+}
+
+// validateThreadsFlag checks the value of a --threads flag.
+func validateThreadsFlag(threads int) *clierr.Error {
+	if err := validation.ValidateThreadCount(threads); err != nil {
+		return clierr.New(clierr.Validation, "Invalid thread count: "+err.Error(), err)
+	}
+	return nil
+}
+
+// addThreadsFlag adds the --threads flag to cmd, with the default number of workers for work of kind w.
+func addThreadsFlag(cmd *cobra.Command, threads *int, w validation.Workload, purpose string) {
+	cmd.Flags().IntVarP(threads, "threads", "t", validation.DefaultThreads(w),
+		fmt.Sprintf("Number of worker threads to use for %s [%d-%d]", purpose, validation.MinThreads, validation.MaxThreads))
+}
+
+// parseGameIDArg parses a game ID given as a command argument.
+func parseGameIDArg(arg string) (int, *clierr.Error) {
+	gameID, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, clierr.New(clierr.Validation, "Invalid game ID. It must be a positive integer.", err)
+	}
+	if err := validation.ValidateGameID(gameID); err != nil {
+		return 0, clierr.New(clierr.Validation, "Invalid game ID: "+err.Error(), err)
+	}
+	return gameID, nil
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommands_ReportErrorKinds(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Error Kinds Game", `{}`)
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing")
+	noLogin := &auth.Service{Storer: testStorer{}}
+	brokenLogin := auth.NewService(&mockTokenStorer{getTokenErr: errors.New("mock db error")}, &mockTokenRefresher{})
+
+	// Errors of a single game download are printed to stdout, like the rest of its progress output.
+	tests := []struct {
+		name   string
+		cmd    *cobra.Command
+		args   []string
+		want   clierr.Type
+		stdout bool
+	}{
+		{"info with invalid ID", infoCmd(repo), []string{"abc"}, clierr.Validation, false},
+		{"info with unknown game", infoCmd(repo), []string{"999"}, clierr.NotFound, false},
+		{"languages with non-positive ID", languagesCmd(repo), []string{"0"}, clierr.Validation, false},
+		{"search with invalid ID", searchCmd(repo), []string{"--id", "abc"}, clierr.Validation, false},
+		{"export with invalid format", exportCmd(repo), []string{dir, "--format", "xml"}, clierr.Validation, false},
+		{"import of missing file", importCmd(repo), []string{missing}, clierr.Validation, false},
+		{"refresh with invalid thread count", refreshCmd(brokenLogin), []string{"--threads", "0"}, clierr.Validation, false},
+		{"refresh with zero fetch timeout", refreshCmd(brokenLogin), []string{"--fetch-timeout", "0s"}, clierr.Validation, false},
+		{"refresh without a usable login", refreshCmd(brokenLogin), nil, clierr.Auth, false},
+		{"audit of unknown game", auditCmd(repo), []string{"999", dir}, clierr.NotFound, false},
+		{"download with invalid ID", downloadCmd(noLogin), []string{"abc", dir}, clierr.Validation, false},
+		{"download with invalid platform", downloadCmd(noLogin), []string{"1", dir, "--platform", "amiga"}, clierr.Validation, true},
+		{"download without login", downloadCmd(noLogin), []string{"1", dir}, clierr.Auth, true},
+		{"batch download with invalid order", downloadCmd(noLogin), []string{"--all", dir, "--order", "random"}, clierr.Validation, false},
+		{"file size with invalid ID", sizeCmd(), []string{"abc"}, clierr.Validation, false},
+		{"file hash with invalid algorithm", hashCmd(), []string{dir, "--algo", "crc"}, clierr.Validation, false},
+		{"file hash of missing directory", hashCmd(), []string{missing}, clierr.NotFound, false},
+		{"hash with invalid output mode", topLevelHashCmd(), []string{dir, "--output", "printer"}, clierr.Validation, false},
+		{"auth status without login", authStatusCmd(noLogin), nil, clierr.Auth, false},
+		{"restore of missing backup", restoreCmd(), []string{filepath.Join(dir, "missing.zip")}, clierr.Internal, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setLastCliErr(nil)
+			t.Cleanup(func() { setLastCliErr(nil) })
+
+			output, err := captureCombinedOutput(tt.cmd, tt.args...)
+			require.NoError(t, err)
+
+			e := getLastCliErr()
+			require.NotNil(t, e, "no error was reported; output: %s", output)
+			assert.Equal(t, tt.want, e.Type)
+			if !tt.stdout {
+				assert.Contains(t, output, "Error: "+e.Message)
+			}
+		})
+	}
+}
+
+func TestWrapFetchErr_RetriesExhausted(t *testing.T) {
+	err := fmt.Errorf("failed to fetch owned game IDs: %w",
+		&client.RetriesExhaustedError{Attempts: 3, Err: &client.HTTPStatusError{StatusCode: 503}})
+	e := wrapFetchErr(clierr.Internal, "refresh catalogue", "Failed to refresh catalogue.", err)
+	assert.Equal(t, clierr.Network, e.Type)
+	assert.Equal(t, "Failed to refresh catalogue: gave up after 3 attempts (last: HTTP 503)", e.Message)
+
+	other := wrapFetchErr(clierr.Internal, "refresh catalogue", "Failed to refresh catalogue.", errors.New("boom"))
+	assert.Equal(t, clierr.Internal, other.Type)
+	assert.Equal(t, "Failed to refresh catalogue.", other.Message)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
+)
+
+// noColorFlag is the name of the global flag that turns off the colours of the output.
+const noColorFlag = "no-color"
+
+// noColorEnv is the environment variable that turns off colours when it is set to anything, see no-color.org.
+const noColorEnv = "NO_COLOR"
+
+// noColor is set by the global --no-color flag. The log is set up before the flag is parsed, so
+// colorDisabled looks it up in the arguments too.
+var noColor bool
+
+// colorDisabled reports whether --no-color is in args or NO_COLOR is set. It accepts "--no-color" and
+// "--no-color=<bool>", and ignores the arguments after "--".
+func colorDisabled(args []string) bool {
+	if os.Getenv(noColorEnv) != "" {
+		return true
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--"+noColorFlag {
+			return true
+		}
+		if value, ok := strings.CutPrefix(arg, "--"+noColorFlag+"="); ok {
+			disabled, err := strconv.ParseBool(value)
+			return err == nil && disabled
+		}
+	}
+	return false
+}
+
+// isTerminal reports whether w is a terminal. It is a variable so tests can override it.
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// newProgressBar returns a progress bar of max units that draws itself on w. It is hidden unless w is a terminal,
+// as a log file, like that of a CI job, would get every redraw of it as a line of its own.
+func newProgressBar(max int64, w io.Writer, options ...progressbar.Option) *progressbar.ProgressBar {
+	options = append([]progressbar.Option{
+		progressbar.OptionSetWriter(w),
+		progressbar.OptionEnableColorCodes(false),
+		progressbar.OptionSetVisibility(isTerminal(w)),
+	}, options...)
+	return progressbar.NewOptions64(max, options...)
+}
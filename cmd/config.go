@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/spf13/cobra"
+)
+
+// downloadDirEnv is the environment variable with the download directory of commands that are not given one. It
+// takes precedence over the download-dir setting.
+const downloadDirEnv = "GOGG_DOWNLOAD_DIR"
+
+// configKeys maps the names of the settings of the config command to their keys in the database.
+var configKeys = map[string]string{
+	"download-dir": db.SettingDownloadDir,
+}
+
+func configCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show and change the settings stored in the database",
+		Long: "Show and change the settings stored in the database:\n" +
+			"  download-dir  the download directory of 'gogg download' when it is not given one; " + downloadDirEnv + " takes precedence",
+	}
+	cmd.AddCommand(configGetCmd(), configSetCmd(), configUnsetCmd())
+	return cmd
+}
+
+func configGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get [setting]",
+		Short: "Show one setting, or every setting",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			names := configNames()
+			if len(args) == 1 {
+				names = args
+			}
+			for _, name := range names {
+				key, e := configKey(name)
+				if e != nil {
+					reportCliErr(cmd, e)
+					return
+				}
+				value, ok, err := db.NewSettingRepository(db.GetDB()).Get(cmd.Context(), key)
+				if err != nil {
+					reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to read the settings", err))
+					return
+				}
+				if !ok {
+					value = "(not set)"
+				}
+				cmd.Printf("%s: %s\n", name, value)
+			}
+		},
+	}
+}
+
+func configSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set [setting] [value]",
+		Short: "Change a setting",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			key, e := configKey(args[0])
+			if e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			value := args[1]
+			if key == db.SettingDownloadDir {
+				// A relative directory would depend on where gogg runs.
+				abs, err := filepath.Abs(value)
+				if err != nil {
+					reportCliErr(cmd, clierr.New(clierr.Validation, fmt.Sprintf("Invalid directory %q", value), err))
+					return
+				}
+				value = abs
+			}
+			if err := db.NewSettingRepository(db.GetDB()).Set(cmd.Context(), key, value); err != nil {
+				reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to save the setting", err))
+				return
+			}
+			cmd.Printf("%s: %s\n", args[0], value)
+		},
+	}
+}
+
+func configUnsetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unset [setting]",
+		Short: "Remove a setting",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			key, e := configKey(args[0])
+			if e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			if err := db.NewSettingRepository(db.GetDB()).Unset(cmd.Context(), key); err != nil {
+				reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to remove the setting", err))
+				return
+			}
+			cmd.Printf("%s: (not set)\n", args[0])
+		},
+	}
+}
+
+// configNames returns the names of the settings in alphabetical order.
+func configNames() []string {
+	names := make([]string, 0, len(configKeys))
+	for name := range configKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func configKey(name string) (string, *clierr.Error) {
+	key, ok := configKeys[name]
+	if !ok {
+		return "", clierr.New(clierr.Validation, fmt.Sprintf("Unknown setting %q. Must be one of [%s]", name, strings.Join(configNames(), ", ")), nil)
+	}
+	return key, nil
+}
+
+// defaultDownloadDir returns the download directory to use when a command is not given one: the value of
+// GOGG_DOWNLOAD_DIR, or else the download-dir setting.
+func defaultDownloadDir(ctx context.Context) (string, *clierr.Error) {
+	if dir := strings.TrimSpace(os.Getenv(downloadDirEnv)); dir != "" {
+		return dir, nil
+	}
+	dir, ok, err := db.NewSettingRepository(db.GetDB()).Get(ctx, db.SettingDownloadDir)
+	if err != nil {
+		return "", clierr.New(clierr.Internal, "Failed to read the download-dir setting", err)
+	}
+	if !ok || dir == "" {
+		return "", clierr.New(clierr.Validation, "No download directory given. Pass one, set "+downloadDirEnv+
+			", or run 'gogg config set download-dir <dir>'", nil)
+	}
+	return dir, nil
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useSettings makes sure the settings table exists, since some tests replace the database, and empties it.
+func useSettings(t *testing.T) {
+	t.Helper()
+	require.NoError(t, db.Db.AutoMigrate(&db.Setting{}))
+	require.NoError(t, db.Db.Exec("DELETE FROM settings").Error)
+	t.Cleanup(func() { _ = db.Db.Exec("DELETE FROM settings").Error })
+}
+
+func TestConfigCmd(t *testing.T) {
+	useSettings(t)
+	t.Cleanup(func() { setLastCliErr(nil) })
+
+	output, err := captureCombinedOutput(configCmd(), "get")
+	require.NoError(t, err)
+	assert.Equal(t, "download-dir: (not set)\n", output)
+
+	output, err = captureCombinedOutput(configCmd(), "set", "download-dir", "games")
+	require.NoError(t, err)
+	abs, err := filepath.Abs("games")
+	require.NoError(t, err)
+	assert.Equal(t, "download-dir: "+abs+"\n", output, "a relative directory is stored as an absolute one")
+
+	output, err = captureCombinedOutput(configCmd(), "get", "download-dir")
+	require.NoError(t, err)
+	assert.Equal(t, "download-dir: "+abs+"\n", output)
+
+	output, err = captureCombinedOutput(configCmd(), "unset", "download-dir")
+	require.NoError(t, err)
+	assert.Equal(t, "download-dir: (not set)\n", output)
+
+	output, _ = captureCombinedOutput(configCmd(), "set", "threads", "4")
+	assert.Contains(t, output, `Unknown setting "threads"`)
+}
+
+func TestDefaultDownloadDir(t *testing.T) {
+	useSettings(t)
+	t.Setenv(downloadDirEnv, "")
+	ctx := context.Background()
+
+	_, e := defaultDownloadDir(ctx)
+	require.NotNil(t, e)
+	assert.Contains(t, e.Message, "No download directory given")
+
+	require.NoError(t, db.NewSettingRepository(db.GetDB()).Set(ctx, db.SettingDownloadDir, "/from/setting"))
+	dir, e := defaultDownloadDir(ctx)
+	require.Nil(t, e)
+	assert.Equal(t, "/from/setting", dir)
+
+	t.Setenv(downloadDirEnv, "/from/env")
+	dir, e = defaultDownloadDir(ctx)
+	require.Nil(t, e)
+	assert.Equal(t, "/from/env", dir, "the environment variable takes precedence over the setting")
+}
+
+func TestDownloadCmd_DefaultDownloadDir(t *testing.T) {
+	useSettings(t)
+	t.Cleanup(func() { setLastCliErr(nil) })
+	t.Setenv(downloadDirEnv, "")
+
+	var output string
+	captureStdout2(func() {
+		output, _ = captureCombinedOutput(downloadCmd(noLogin), "1")
+	})
+	assert.Contains(t, output, "No download directory given")
+
+	// With the environment variable, the one argument is the game and the download goes on to log in.
+	dir := t.TempDir()
+	t.Setenv(downloadDirEnv, dir)
+	setLastCliErr(nil)
+	out := captureStdout2(func() {
+		output, _ = captureCombinedOutput(downloadCmd(noLogin), "1")
+	})
+	assert.NotContains(t, output+out, "No download directory given")
+	assert.NotNil(t, getLastCliErr())
+}
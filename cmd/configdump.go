@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// configDumpFlag is the name of the global flag that prints the effective settings of a command instead of running it.
+const configDumpFlag = "config-dump"
+
+// configSetting is one entry of the effective configuration printed by --config-dump.
+type configSetting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// addConfigDump adds the --config-dump flag to root and makes every command under it honor the flag.
+func addConfigDump(root *cobra.Command) {
+	root.PersistentFlags().String(configDumpFlag, "", "Print the effective settings of the command as a table or JSON [table, json] without running it")
+	root.PersistentFlags().Lookup(configDumpFlag).NoOptDefVal = "table"
+	wrapWithConfigDump(root)
+}
+
+func wrapWithConfigDump(c *cobra.Command) {
+	if run := c.Run; run != nil {
+		c.Run = func(cmd *cobra.Command, args []string) {
+			if f := cmd.Flag(configDumpFlag); f != nil && f.Changed {
+				if e := printConfigDump(cmd, args, f.Value.String()); e != nil {
+					reportCliErr(cmd, e)
+				}
+				return
+			}
+			run(cmd, args)
+		}
+	}
+	for _, sub := range c.Commands() {
+		wrapWithConfigDump(sub)
+	}
+}
+
+// effectiveSettings returns the settings a command would run with: its arguments and flags, the values
+// derived from them, and the settings that come from the environment.
+func effectiveSettings(cmd *cobra.Command, args []string) []configSetting {
+	settings := []configSetting{{Name: "command", Value: cmd.CommandPath(), Source: "command line"}}
+	if len(args) > 0 {
+		settings = append(settings, configSetting{Name: "arguments", Value: strings.Join(args, " "), Source: "command line"})
+	}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Name == configDumpFlag || f.Name == "help" {
+			return
+		}
+		source := "default"
+		if f.Changed {
+			source = "flag"
+		}
+		settings = append(settings, configSetting{Name: "--" + f.Name, Value: f.Value.String(), Source: source})
+	})
+	if f := cmd.Flags().Lookup("lang"); f != nil {
+		language, ok := client.LanguageFilter(f.Value.String())
+		if !ok {
+			language = "(invalid language code)"
+		}
+		settings = append(settings, configSetting{Name: "language", Value: language, Source: "from --lang"})
+	}
+
+	settings = append(settings, configSetting{Name: "database", Value: db.Path, Source: databaseSource(cmd)})
+	debug := configSetting{Name: "debug logging", Value: "disabled", Source: "default"}
+	if v := strings.TrimSpace(os.Getenv("DEBUG_GOGG")); v != "" {
+		debug.Source = "env DEBUG_GOGG"
+		if v != "false" && v != "0" {
+			debug.Value = "enabled"
+		}
+	}
+	return append(settings, debug)
+}
+
+// databaseSource tells where the location of the database comes from, following the precedence of --db-path,
+// GOGG_HOME, and XDG_DATA_HOME.
+func databaseSource(cmd *cobra.Command) string {
+	if f := cmd.Flag(dbPathFlag); f != nil && f.Changed {
+		return "flag"
+	}
+	for _, env := range []string{"GOGG_HOME", "XDG_DATA_HOME"} {
+		if os.Getenv(env) != "" {
+			return "env " + env
+		}
+	}
+	return "default"
+}
+
+func printConfigDump(cmd *cobra.Command, args []string, format string) *clierr.Error {
+	settings := effectiveSettings(cmd, args)
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(settings, "", "  ")
+		if err != nil {
+			return clierr.New(clierr.Internal, "Failed to encode the settings", err)
+		}
+		cmd.Println(string(data))
+	case "table":
+		table := tablewriter.NewWriter(cmd.OutOrStdout())
+		table.SetHeader([]string{"Setting", "Value", "Source"})
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAutoWrapText(false)
+		for _, s := range settings {
+			table.Append([]string{s.Name, s.Value, s.Source})
+		}
+		table.Render()
+	default:
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid config dump format %q. Must be one of [table, json]", format), nil)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRootCmd() *cobra.Command {
+	return createRootCmd(auth.NewService(nil, nil), &client.GogClient{}, db.NewGameRepository(db.GetDB()))
+}
+
+func TestConfigDump_PrintsSettingsWithoutRunning(t *testing.T) {
+	setLastCliErr(nil)
+	t.Setenv("DEBUG_GOGG", "1")
+	dir := filepath.Join(t.TempDir(), "downloads")
+
+	output, err := captureCombinedOutput(newTestRootCmd(), "download", "1", dir, "--platform=mac", "--lang=de", "--config-dump=json")
+	require.NoError(t, err)
+
+	var settings []configSetting
+	require.NoError(t, json.Unmarshal([]byte(output), &settings))
+	byName := make(map[string]configSetting)
+	for _, s := range settings {
+		byName[s.Name] = s
+	}
+	assert.Equal(t, "gogg download", byName["command"].Value)
+	assert.Equal(t, "1 "+dir, byName["arguments"].Value)
+	assert.Equal(t, configSetting{Name: "--platform", Value: "mac", Source: "flag"}, byName["--platform"])
+	assert.Equal(t, configSetting{Name: "--threads", Value: "5", Source: "default"}, byName["--threads"])
+	assert.Equal(t, "Deutsch", byName["language"].Value)
+	assert.Equal(t, configSetting{Name: "debug logging", Value: "enabled", Source: "env DEBUG_GOGG"}, byName["debug logging"])
+	assert.Equal(t, db.Path, byName["database"].Value)
+	assert.NotContains(t, byName, "--config-dump")
+
+	assert.NoDirExists(t, dir, "the download must not run")
+	assert.Nil(t, getLastCliErr())
+}
+
+func TestConfigDump_Table(t *testing.T) {
+	output, err := captureCombinedOutput(newTestRootCmd(), "file", "hash", t.TempDir(), "--algo=sha256", "--config-dump")
+	require.NoError(t, err)
+	assert.Contains(t, output, "SETTING")
+	assert.Contains(t, output, "--algo")
+	assert.Contains(t, output, "sha256")
+	assert.NotContains(t, output, "No files found to hash.")
+}
+
+func TestConfigDump_InvalidFormat(t *testing.T) {
+	setLastCliErr(nil)
+	t.Cleanup(func() { setLastCliErr(nil) })
+
+	output, err := captureCombinedOutput(newTestRootCmd(), "version", "--config-dump=yaml")
+	require.NoError(t, err)
+	assert.Contains(t, output, "Invalid config dump format")
+	require.NotNil(t, getLastCliErr())
+	assert.Equal(t, clierr.Validation, getLastCliErr().Type)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+)
+
+// issueTrackerURL is where crash reports should be sent.
+const issueTrackerURL = "https://github.com/habedi/gogg/issues"
+
+// recoverCrash is deferred by Execute. It turns a panic into a crash report in the data directory, so the
+// user does not have to copy the stack trace from the terminal, and exits with the code of an internal error.
+// Nothing is sent anywhere. Panics in other goroutines cannot be recovered here and still crash as usual.
+func recoverCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	fmt.Fprintf(os.Stderr, "Error: Gogg crashed: %v\n", r)
+	path, err := writeCrashReport(filepath.Dir(db.Path), r, stack, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write a crash report (%v), so here is the stack trace:\n%s\n", err, stack)
+		fmt.Fprintf(os.Stderr, "Please include it in a bug report at %s\n", issueTrackerURL)
+	} else {
+		fmt.Fprintf(os.Stderr, "A crash report was written to \"%s\".\nPlease attach it to a bug report at %s\n", path, issueTrackerURL)
+	}
+	os.Exit(exitCodeByType[clierr.Internal])
+}
+
+// writeCrashReport writes the panic value r, its stack trace, and what is needed to reproduce the crash to a
+// file in dir named after now, and returns the path of the file.
+func writeCrashReport(dir string, r any, stack []byte, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Gogg version: %s\n", version)
+	fmt.Fprintf(&sb, "Go version: %s\n", goVersion)
+	fmt.Fprintf(&sb, "Platform: %s\n", platform)
+	fmt.Fprintf(&sb, "Time: %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "Command: %s\n\n", strings.Join(os.Args, " "))
+	fmt.Fprintf(&sb, "panic: %v\n\n%s", r, stack)
+
+	path := filepath.Join(dir, "crash-"+now.Format("20060102-150405")+".txt")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
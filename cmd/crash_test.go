@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCrashReport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	now := time.Date(2024, 5, 1, 13, 4, 5, 0, time.UTC)
+
+	path, err := writeCrashReport(dir, "nil context", []byte("goroutine 1 [running]:\nmain.main()"), now)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "crash-20240501-130405.txt"), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	report := string(data)
+	assert.Contains(t, report, "Gogg version: "+version)
+	assert.Contains(t, report, "Time: 2024-05-01T13:04:05Z")
+	assert.Contains(t, report, "panic: nil context")
+	assert.Contains(t, report, "goroutine 1 [running]:\nmain.main()")
+}
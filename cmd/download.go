@@ -6,11 +6,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -19,32 +19,25 @@ import (
 	"github.com/habedi/gogg/client"
 	"github.com/habedi/gogg/db"
 	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/hasher"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/habedi/gogg/pkg/progress"
 	"github.com/habedi/gogg/pkg/validation"
 	"github.com/rs/zerolog/log"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
-// formatBytes converts a byte count into a human-readable string (KB, MB, GB).
-func formatBytes(b int64) string {
-	const unit = 1024
-	if b < unit {
-		return fmt.Sprintf("%d B", b)
-	}
-	div, exp := int64(unit), 0
-	for n := b / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
-}
-
 // cliProgressWriter handles progress updates for the CLI.
 type cliProgressWriter struct {
 	bar             *progressbar.ProgressBar
 	fileProgress    map[string]struct{ current, total int64 }
 	fileBytes       map[string]int64
+	totalBytes      int64
 	downloadedBytes int64
+	speed           progress.SpeedEstimator
+	speedText       string
 	mu              sync.RWMutex
 }
 
@@ -56,30 +49,37 @@ func (cw *cliProgressWriter) Write(p []byte) (n int, err error) {
 			cw.mu.Lock()
 			switch update.Type {
 			case "start":
-				cw.bar = progressbar.NewOptions64(
+				cw.bar = newProgressBar(
 					update.OverallTotalBytes,
+					os.Stderr,
 					progressbar.OptionSetDescription("Downloading..."),
-					progressbar.OptionSetWriter(os.Stderr),
 					progressbar.OptionShowBytes(true),
 					progressbar.OptionThrottle(200*time.Millisecond),
 					progressbar.OptionClearOnFinish(),
 					progressbar.OptionSpinnerType(14),
+					progressbar.OptionSetPredictTime(false),
 				)
 				cw.fileProgress = make(map[string]struct{ current, total int64 })
 				cw.fileBytes = make(map[string]int64)
+				cw.totalBytes = update.OverallTotalBytes
 				cw.downloadedBytes = 0
+				cw.speed.Reset(time.Now())
+				cw.speedText = "Speed: N/A | ETA: N/A"
 			case "file_progress":
 				if cw.bar != nil {
 					diff := update.CurrentBytes - cw.fileBytes[update.FileName]
 					cw.fileBytes[update.FileName] = update.CurrentBytes
 					cw.downloadedBytes += diff
 					_ = cw.bar.Set64(cw.downloadedBytes)
+					if speed, ok := cw.speed.Update(time.Now(), cw.downloadedBytes); ok {
+						cw.speedText = progress.FormatSpeedAndETA(speed, cw.totalBytes-cw.downloadedBytes)
+					}
 
 					cw.fileProgress[update.FileName] = struct{ current, total int64 }{update.CurrentBytes, update.TotalBytes}
 					if update.CurrentBytes >= update.TotalBytes && update.TotalBytes > 0 {
 						delete(cw.fileProgress, update.FileName)
 					}
-					cw.bar.Describe(cw.getFileStatusString())
+					cw.bar.Describe(cw.speedText + " | " + cw.getFileStatusString())
 				}
 			}
 			cw.mu.Unlock()
@@ -107,8 +107,8 @@ func (cw *cliProgressWriter) getFileStatusString() string {
 		if len(shortName) > 25 {
 			shortName = "..." + shortName[len(shortName)-22:]
 		}
-		progress := cw.fileProgress[file]
-		sizeStr := fmt.Sprintf("%s/%s", formatBytes(progress.current), formatBytes(progress.total))
+		fp := cw.fileProgress[file]
+		sizeStr := fmt.Sprintf("%s/%s", progress.FormatBytes(fp.current), progress.FormatBytes(fp.total))
 		sb.WriteString(fmt.Sprintf("%s %s", shortName, sizeStr))
 		if i < len(files)-1 {
 			sb.WriteString(" | ")
@@ -117,228 +117,1173 @@ func (cw *cliProgressWriter) getFileStatusString() string {
 	return sb.String()
 }
 
+// downloadOptions holds the flags of the download command.
+type downloadOptions struct {
+	language      string
+	platformName  string
+	stagingDir    string
+	extras        bool
+	dlcs          bool
+	resume        bool
+	flatten       bool
+	flattenExtras *bool // nil means extras follow flatten
+	noDLCExtras   bool
+	skipPatches   bool
+	keepLatest    bool
+	pruneDryRun   bool
+	rommLayout    bool
+	manifestOnly  bool
+	extrasOnly    bool
+	installerOnly bool
+	preferKind    string // kind of installer --installer-only prefers; empty means offline
+	preserveDate  bool
+	numThreads    int
+	checksumAlgo  string
+	langFolders   string
+	preferLang    string
+	verifyResume  bool
+	strict        bool
+	maxBytes      string
+	bestEffort    bool
+	minFileSize   string
+	maxFileSize   string
+	excludes      []string
+	printMetadata bool
+	progressFmt   string
+	fileName      string
+	fileIndex     int
+	maxConns      int
+	folderName    string
+	folderID      bool
+	asciiTitles   bool
+	titleStyle    string
+	fileNames     string
+	layout        string
+	stallTimeout  time.Duration
+	accurateTotal bool
+	checksumFiles bool
+	preallocate   bool
+	perGameLog    bool
+	verify        string // verifyAfter or verifyOff
+	sinceVersion  bool
+	checkTarget   bool
+	byteBudget    *client.ByteBudget // shared by the games of a batch, made from maxBytes
+	gameLog       *gameLog           // the log of the game being downloaded, with perGameLog
+	dirMode       string
+	fileMode      string
+	modes         client.FileModes
+	minFileBytes  int64 // made from minFileSize
+	maxFileBytes  int64 // made from maxFileSize
+	// gamesConcurrency is the number of games a batch or mirror downloads at the same time. With more than one,
+	// the games share slots, made from numThreads, and progress.
+	gamesConcurrency int
+	slots            *client.DownloadSlots
+	progress         *batchProgress
+	reportPath       string
+	// report collects the outcomes of the files of the run for reportPath and for the savings of library, and
+	// is shared by the games of a batch.
+	report        *downloadReport
+	dedupeLibrary string
+	dedupeLink    string
+	library       *client.LibraryIndex // made from dedupeLibrary, shared by the games of a batch
+}
+
 func downloadCmd(authService *auth.Service) *cobra.Command {
-	var language, platformName string
-	var extrasFlag, dlcFlag, resumeFlag, flattenFlag, skipPatchesFlag, keepLatestFlag, rommLayoutFlag bool
-	var numThreads int
+	var opts downloadOptions
+	var health healthOptions
+	var allFlag, retryFailedFlag, onlyUpdatedFlag, dryRunFlag, flattenExtras bool
+	var order string
 
 	cmd := &cobra.Command{
-		Use:   "download [gameID] [downloadDir]",
-		Short: "Download game files from GOG",
-		Long:  "Download game files from GOG for the specified game ID to the specified directory",
-		Args:  cobra.ExactArgs(2),
+		Use:         "download [gameID] [downloadDir]",
+		Short:       "Download game files from GOG",
+		Annotations: map[string]string{needsAuthAnnotation: ""},
+		Long: "Download game files from GOG for the specified game ID to the specified directory.\n" +
+			"With --all, only the download directory is given and every game in the catalogue is downloaded.\n" +
+			"Without a download directory, the one in " + downloadDirEnv + " or the download-dir setting is used.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if allFlag || retryFailedFlag {
+				return cobra.MaximumNArgs(1)(cmd, args)
+			}
+			return cobra.RangeArgs(1, 2)(cmd, args)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			gameID, err := strconv.Atoi(args[0])
-			if err != nil {
-				cmd.PrintErrln("Error: Invalid game ID. It must be a positive integer.")
+			if cmd.Flags().Changed("flatten-extras") {
+				opts.flattenExtras = &flattenExtras
+			}
+			applyLayoutPreset(cmd, &opts)
+			if onlyUpdatedFlag && !allFlag {
+				reportCliErr(cmd, clierr.New(clierr.Validation, "--only-updated needs --all", nil))
+				return
+			}
+			if dryRunFlag && !onlyUpdatedFlag {
+				reportCliErr(cmd, clierr.New(clierr.Validation, "--dry-run needs --only-updated", nil))
+				return
+			}
+			if opts.reportPath != "" {
+				if e := validateReportPath(opts.reportPath); e != nil {
+					reportCliErr(cmd, e)
+					return
+				}
+			}
+			// The download directory is the last argument, and may be left out for the default one.
+			batch := allFlag || retryFailedFlag
+			if (batch && len(args) == 0) || (!batch && len(args) == 1) {
+				dir, e := defaultDownloadDir(cmd.Context())
+				if e != nil {
+					reportCliErr(cmd, e)
+					return
+				}
+				args = append(args, dir)
+			}
+			ctx, stopSchedule := context.WithCancel(cmd.Context())
+			defer stopSchedule()
+			if e := applyRateSchedule(ctx); e != nil {
+				reportCliErr(cmd, e)
 				return
 			}
-			if err := validation.ValidateGameID(gameID); err != nil {
-				cmd.PrintErrln("Error:", err)
+			if e := startHealthServer(ctx, health, authService); e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			if opts.dedupeLibrary != "" {
+				if e := indexLibrary(&opts); e != nil {
+					reportCliErr(cmd, e)
+					return
+				}
+			}
+			if opts.reportPath != "" || opts.library != nil {
+				opts.report = newDownloadReport(time.Now())
+				defer endDownloadRun(cmd, opts)
+			}
+			if onlyUpdatedFlag {
+				if e := executeUpdatedDownload(ctx, authService, args[0], opts, order, dryRunFlag); e != nil {
+					reportCliErr(cmd, e)
+				}
+				return
+			}
+			if batch {
+				if e := executeBatchDownload(ctx, authService, args[0], opts, retryFailedFlag, order); e != nil {
+					reportCliErr(cmd, e)
+				}
+				return
+			}
+			gameID, e := parseGameIDArg(args[0])
+			if e != nil {
+				reportCliErr(cmd, e)
 				return
 			}
 			downloadDir := args[1]
-			ctx := cmd.Context()
-			executeDownload(ctx, authService, gameID, downloadDir, language, platformName, extrasFlag, dlcFlag, resumeFlag, flattenFlag, skipPatchesFlag, keepLatestFlag, rommLayoutFlag, numThreads)
+			// executeDownload has already printed the error, so it is only recorded here.
+			var dlErr *clierr.Error
+			err := executeDownload(ctx, authService, gameID, downloadDir, opts)
+			if opts.report != nil {
+				opts.report.finish(gameID, "", err)
+			}
+			if errors.As(err, &dlErr) {
+				setLastCliErr(dlErr)
+			}
 		},
 	}
 
-	cmd.Flags().StringVarP(&language, "lang", "l", "en", "Game language [en, fr, de, es, it, ru, pl, pt-BR, zh-Hans, ja, ko]")
-	cmd.Flags().StringVarP(&platformName, "platform", "p", "windows", "Platform name [all, windows, mac, linux]; all means all platforms")
-	cmd.Flags().BoolVarP(&extrasFlag, "extras", "e", true, "Include extra content files? [true, false]")
-	cmd.Flags().BoolVarP(&dlcFlag, "dlcs", "d", true, "Include DLC files? [true, false]")
-	cmd.Flags().BoolVarP(&resumeFlag, "resume", "r", true, "Resume downloading? [true, false]")
-	cmd.Flags().IntVarP(&numThreads, "threads", "t", 5, "Number of worker threads to use for downloading [1-20]")
-	cmd.Flags().BoolVarP(&flattenFlag, "flatten", "f", true, "Flatten the directory structure when downloading? [true, false]")
-	cmd.Flags().BoolVarP(&skipPatchesFlag, "skip-patches", "s", false, "Skip patches when downloading? [true, false]")
-	cmd.Flags().BoolVar(&keepLatestFlag, "keep-latest", false, "Remove older installer versions after successful download (keep only highest version)")
-	cmd.Flags().BoolVar(&rommLayoutFlag, "romm", false, "Use RomM compatible folder layout (platform/game)")
+	cmd.Flags().StringVarP(&opts.language, "lang", "l", "en", "Game language [en, fr, de, es, it, ru, pl, pt-BR, zh-Hans, ja, ko, ...]; all means all languages")
+	cmd.Flags().StringVarP(&opts.platformName, "platform", "p", "windows", "Platform name [all, auto, windows, mac, linux]; all means all platforms, auto the platform of this machine")
+	cmd.Flags().BoolVarP(&opts.extras, "extras", "e", true, "Include extra content files? [true, false]")
+	cmd.Flags().BoolVarP(&opts.dlcs, "dlcs", "d", true, "Include DLC files? [true, false]")
+	cmd.Flags().BoolVarP(&opts.resume, "resume", "r", true, "Resume downloading? [true, false]")
+	cmd.Flags().BoolVar(&opts.noDLCExtras, "no-dlc-extras", false, "Skip the extras of DLCs, like their soundtracks, while still downloading the DLC installers and the extras of the game")
+	addThreadsFlag(cmd, &opts.numThreads, validation.NetworkWorkload, "downloading")
+	addStallTimeoutFlag(cmd, &opts.stallTimeout)
+	addAccurateTotalFlag(cmd, &opts.accurateTotal)
+	addChecksumFilesFlag(cmd, &opts.checksumFiles)
+	addPreallocationFlag(cmd, &opts.preallocate)
+	addPerGameLogFlag(cmd, &opts.perGameLog)
+	cmd.Flags().IntVar(&opts.maxConns, "max-conns-per-host", client.DefaultMaxConnsPerHost, "Maximum number of connections to one download server; 0 means no limit")
+	cmd.Flags().BoolVarP(&opts.flatten, "flatten", "f", true, "Flatten the directory structure when downloading? [true, false]")
+	cmd.Flags().BoolVar(&flattenExtras, "flatten-extras", false, "Put extras directly into the game folder instead of an extras folder? [true, false] (default is the value of --flatten)")
+	cmd.Flags().BoolVarP(&opts.skipPatches, "skip-patches", "s", false, "Skip patches when downloading? [true, false]")
+	cmd.Flags().BoolVar(&opts.keepLatest, "keep-latest", false, "Remove older installer versions after successful download (keep only highest version)")
+	cmd.Flags().BoolVar(&opts.pruneDryRun, "prune-dry-run", false, "Show which older installer versions would be removed and the space reclaimed, without deleting anything")
+	cmd.Flags().BoolVar(&opts.rommLayout, "romm", false, "Use RomM compatible folder layout (platform/game)")
+	cmd.Flags().BoolVar(&opts.installerOnly, "installer-only", false, "Download only the newest installer for the platform: no patches, extras, or DLCs, and older versions are removed")
+	cmd.Flags().StringVar(&opts.preferKind, "prefer", "", "With --installer-only, the kind of installer to download when a game offers both [offline, online] (default offline)")
+	cmd.Flags().BoolVar(&opts.extrasOnly, "extras-only", false, "Download only the extras (and DLC extras if --dlcs is set), without any installers")
+	cmd.Flags().BoolVar(&opts.manifestOnly, "manifest-only", false, "Only write metadata.json and download_info.json for the game, without downloading any files")
+	cmd.Flags().StringVar(&opts.stagingDir, "staging-dir", "", "Download into this directory first and move the game folder to downloadDir after a successful download")
+	cmd.Flags().BoolVar(&opts.preserveDate, "preserve-date", false, "Set the modification time of each downloaded file to the date GOG reports for it, when there is one")
+	cmd.Flags().StringVar(&opts.langFolders, "language-folders", string(client.LanguageFoldersAuto), "Put the files of each language into a folder named after the language code [auto, always, never]; auto means only with --lang=all")
+	cmd.Flags().StringVar(&opts.preferLang, "prefer-language", "", "With --lang=all, put the files of this language where a single language would go, without a language folder; games without it use their first language")
+	cmd.Flags().StringVar(&opts.verify, "verify", verifyOff, "Check the files of each game against their stored checksums after it is downloaded, hashing them concurrently [after, off]; see 'gogg verify'")
+	cmd.Flags().BoolVar(&opts.verifyResume, "verify-on-resume", false, "When resuming, check files that look complete against GOG's checksum and download them again if they do not match")
+	cmd.Flags().BoolVar(&opts.strict, "strict", false, "Fail instead of downloading nothing when no installer of the game or its DLCs matches --lang and --platform")
+	cmd.Flags().StringVar(&opts.maxBytes, "max-bytes", "", "Stop starting new files once this much has been downloaded in this run, like 20GB or 500MB; files in progress are finished")
+	cmd.Flags().BoolVar(&opts.bestEffort, "best-effort", false, "When some files of a game fail, still write its metadata.json and list the failed files with how to retry them")
+	cmd.Flags().StringVar(&opts.minFileSize, "min-file-size", "", "Skip the files that GOG lists as smaller than this, like 1MB; files of unknown size are still downloaded")
+	cmd.Flags().StringVar(&opts.maxFileSize, "max-file-size", "", "Skip the files that GOG lists as larger than this, like 4GB; files of unknown size are still downloaded")
+	addExcludeFlag(cmd, &opts.excludes)
+	cmd.Flags().BoolVar(&opts.printMetadata, "print-metadata", false, "Also print the metadata.json of the game to stdout; the other messages go to stderr then")
+	addProgressFormatFlag(cmd, &opts.progressFmt)
+	cmd.Flags().StringVar(&opts.fileName, "file", "", "Download only the file with this name or download link name, whatever --lang and --platform are")
+	cmd.Flags().IntVar(&opts.fileIndex, "file-index", 0, "Download only the file with this number in 'catalogue info --files', whatever --lang and --platform are")
+	cmd.Flags().StringVar(&opts.folderName, "folder-name", client.DefaultFolderTemplate, "Name of the game folder, made from the tokens {title} and {id} (the GOG product ID), like {title}-{id}")
+	cmd.Flags().BoolVar(&opts.folderID, "folder-id", false, "Add the GOG product ID to the name of the game folder, like {title}-{id}")
+	cmd.Flags().BoolVar(&opts.asciiTitles, "ascii-titles", false, "Write letters of the title like é or Ж in ASCII for the game folder name instead of dropping them")
+	cmd.Flags().StringVar(&opts.titleStyle, "title-style", client.TitleStyleSlug, "How the title is written in the game folder name [slug, raw, underscores]; raw keeps the title as GOG shows it, underscores joins its words with underscores")
+	cmd.Flags().StringVar(&opts.fileNames, "file-names", client.FileNamesOriginal, "How the downloaded files are named [original, lower, underscores, lower-underscores]; the extensions are kept")
+	cmd.Flags().BoolVar(&opts.sinceVersion, "since-version", false, "Download only the files that are new or changed since the earlier download in the game folder, going by its metadata.json")
+	cmd.Flags().BoolVar(&opts.checkTarget, "check-target", false, "Before downloading, warn if the target file system is low on free inodes or a path of the download is too long for it")
+	cmd.Flags().StringVar(&opts.checksumAlgo, "write-checksums", "", fmt.Sprintf("Hash the files while they are downloaded and write them to CHECKSUMS.<algo> in the game folder %v", hasher.HashAlgorithms))
+	cmd.Flags().StringVar(&opts.dirMode, "dir-mode", "0755", "Permissions (octal) of the directories created for the download")
+	cmd.Flags().StringVar(&opts.fileMode, "file-mode", "0644", "Permissions (octal) of the files created by the download")
+	cmd.Flags().BoolVar(&allFlag, "all", false, "Download every game in the catalogue; games already completed in an earlier run are skipped")
+	cmd.Flags().StringVar(&opts.reportPath, "report", "", "Write a report of the files downloaded, skipped, and failed, with the bytes, duration, speed, retries, and checksum checks, to this path at the end; a .json path gets JSON, a .txt path text")
+	cmd.Flags().BoolVar(&retryFailedFlag, "retry-failed", false, "Download only the games that failed in an earlier --all run")
+	cmd.Flags().BoolVar(&onlyUpdatedFlag, "only-updated", false, "With --all, download only the games already in the download directory whose files changed in the catalogue since; add --since-version to download only the changed files")
+	cmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "With --only-updated, list the games with updates and their new or changed files without downloading anything")
+	addGamesConcurrencyFlag(cmd, &opts.gamesConcurrency)
+	addHealthFlags(cmd, &health)
+	addLayoutFlag(cmd, &opts.layout)
+	addLibraryFlags(cmd, &opts)
+	cmd.MarkFlagsMutuallyExclusive("file", "file-index")
+	cmd.MarkFlagsMutuallyExclusive("print-metadata", "progress-format")
+	cmd.MarkFlagsMutuallyExclusive("since-version", "file")
+	cmd.MarkFlagsMutuallyExclusive("since-version", "file-index")
+	cmd.MarkFlagsMutuallyExclusive("only-updated", "retry-failed")
+	cmd.Flags().StringVar(&order, "order", batchOrderCatalogue, "Order of the games for --all, --retry-failed, and --only-updated [catalogue, name, size-asc, size-desc]")
 
 	return cmd
 }
 
-func executeDownload(ctx context.Context, authService *auth.Service, gameID int, downloadPath, language, platformName string, extrasFlag, dlcFlag, resumeFlag, flattenFlag, skipPatchesFlag, keepLatestFlag, rommLayoutFlag bool, numThreads int) {
+// executeDownload downloads the files of one game and prints the outcome.
+// It returns the *clierr.Error that was reported to the user, or nil if the download succeeded.
+func executeDownload(ctx context.Context, authService *auth.Service, gameID int, downloadPath string, opts downloadOptions) (dlErr error) {
 	log.Info().Msgf("Downloading games to %s...", downloadPath)
-	log.Info().Msgf("Language: %s, Platform: %s, Extras: %v, DLC: %v", language, platformName, extrasFlag, dlcFlag)
+	log.Info().Msgf("Language: %s, Platform: %s, Extras: %v, DLC: %v", opts.language, opts.platformName, opts.extras, opts.dlcs)
 
-	if err := validation.ValidateThreadCount(numThreads); err != nil {
-		fmt.Println(clierr.New(clierr.Validation, "Invalid thread count", err).Message)
-		return
+	if e := validateThreadsFlag(opts.numThreads); e != nil {
+		fmt.Println(e.Message)
+		return e
 	}
-	if err := validation.ValidatePlatform(platformName); err != nil {
-		fmt.Println(clierr.New(clierr.Validation, "Invalid platform", err).Message)
-		return
+	if opts.maxConns < 0 {
+		e := clierr.New(clierr.Validation, "--max-conns-per-host must be 0 (no limit) or more", nil)
+		fmt.Println(e.Message)
+		return e
 	}
-
-	var languageFullName string
-	found := false
-	for code, full := range client.GameLanguages {
-		if strings.EqualFold(code, language) {
-			languageFullName = full
-			found = true
-			break
+	if e := validateProgressFormat(opts.progressFmt); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if e := validateVerifyFlag(opts.verify); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if err := validation.ValidatePlatform(opts.platformName); err != nil {
+		e := clierr.New(clierr.Validation, "Invalid platform", err)
+		fmt.Println(e.Message)
+		return e
+	}
+	opts.platformName = validation.ResolvePlatform(opts.platformName)
+	if e := parseModeFlags(&opts); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if e := parseMaxBytesFlag(&opts); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if e := parseFileSizeFlags(&opts); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if e := validateExcludes(opts.excludes); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	switch client.LanguageFolders(opts.langFolders) {
+	case "", client.LanguageFoldersAuto, client.LanguageFoldersAlways, client.LanguageFoldersNever:
+	default:
+		e := clierr.New(clierr.Validation, fmt.Sprintf("Invalid language folders mode %q. Must be one of [auto, always, never]", opts.langFolders), nil)
+		fmt.Println(e.Message)
+		return e
+	}
+	if e := validatePreferLanguage(opts); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if e := validatePreferKind(opts); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if err := client.ValidateFolderTemplate(gameFolderTemplate(opts)); err != nil {
+		e := clierr.New(clierr.Validation, "Invalid folder name: "+err.Error(), err)
+		fmt.Println(e.Message)
+		return e
+	}
+	if e := validateTitleStyle(opts.titleStyle); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if e := validateFileNameStyle(opts.fileNames); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if e := validateLayout(opts); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if e := validateDedupeLink(opts.dedupeLink); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if e := validateStallTimeout(opts.stallTimeout); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	if opts.checksumAlgo != "" && !hasher.IsValidHashAlgo(opts.checksumAlgo) {
+		e := clierr.New(clierr.Validation, fmt.Sprintf("Invalid checksum algorithm %q. Must be one of %v", opts.checksumAlgo, hasher.HashAlgorithms), nil)
+		fmt.Println(e.Message)
+		return e
+	}
+	if opts.extrasOnly && !opts.extras {
+		e := clierr.New(clierr.Validation, "--extras-only cannot be combined with --extras=false", nil)
+		fmt.Println(e.Message)
+		return e
+	}
+	if opts.installerOnly {
+		if opts.extrasOnly {
+			e := clierr.New(clierr.Validation, "--installer-only cannot be combined with --extras-only", nil)
+			fmt.Println(e.Message)
+			return e
 		}
+		opts.extras, opts.dlcs, opts.skipPatches, opts.keepLatest = false, false, true, true
 	}
-	if !found {
-		fmt.Println(clierr.New(clierr.Validation, "Invalid language code", nil).Message)
-		for langCode, langName := range client.GameLanguages {
-			fmt.Printf("'%s' for %s\n", langCode, langName)
+
+	languageFullName, ok := client.LanguageFilter(opts.language)
+	if !ok {
+		e := clierr.New(clierr.Validation, "Invalid language code", nil)
+		fmt.Println(e.Message)
+		codes := make([]string, 0, len(client.GameLanguages))
+		for code := range client.GameLanguages {
+			codes = append(codes, code)
 		}
-		return
+		sort.Strings(codes)
+		for _, langCode := range codes {
+			fmt.Printf("'%s' for %s\n", langCode, client.GameLanguages[langCode])
+		}
+		return e
 	}
 
 	user, err := authService.RefreshTokenCtx(ctx)
 	if err != nil {
-		fmt.Println("Failed to find or refresh the access token. Did you login?")
-		return
+		e := clierr.Wrap(clierr.Auth, "Failed to find or refresh the access token. Did you login?", err)
+		fmt.Println(e.Message)
+		return e
 	}
 
 	if _, err := os.Stat(downloadPath); os.IsNotExist(err) {
 		log.Info().Msgf("Creating download path %s", downloadPath)
-		if err := os.MkdirAll(downloadPath, os.ModePerm); err != nil {
+		if err := os.MkdirAll(downloadPath, opts.modes.Dir); err != nil {
 			log.Error().Err(err).Msgf("Failed to create download path %s", downloadPath)
-			return
+			e := clierr.New(clierr.Internal, "Failed to create download path", err)
+			if msg, ok := volumeErrorMessage(client.WrapVolumeError(downloadPath, err)); ok {
+				e = clierr.New(clierr.Download, msg, err)
+			}
+			fmt.Println(e.Message)
+			return e
 		}
 	}
 
+	targetPath := downloadPath
+	if opts.stagingDir != "" && !opts.manifestOnly {
+		if samePath(opts.stagingDir, downloadPath) {
+			e := clierr.New(clierr.Validation, "Staging directory must differ from the download directory", nil)
+			fmt.Println(e.Message)
+			return e
+		}
+		if err := os.MkdirAll(opts.stagingDir, opts.modes.Dir); err != nil {
+			log.Error().Err(err).Msgf("Failed to create staging path %s", opts.stagingDir)
+			e := clierr.New(clierr.Internal, "Failed to create staging path", err)
+			fmt.Println(e.Message)
+			return e
+		}
+		targetPath = opts.stagingDir
+	}
+
 	gameRepo := db.NewGameRepository(db.GetDB())
 	game, err := gameRepo.GetByID(ctx, gameID)
 	if err != nil {
-		fmt.Println(clierr.New(clierr.Internal, "Error retrieving game from local catalogue", err).Message)
-		return
+		e := clierr.New(clierr.Internal, "Error retrieving game from local catalogue", err)
+		fmt.Println(e.Message)
+		return e
 	}
 	if game == nil {
-		fmt.Println(clierr.New(clierr.NotFound, fmt.Sprintf("Game %d not found in local catalogue", gameID), nil).Message)
-		return
+		e := clierr.New(clierr.NotFound, fmt.Sprintf("Game %d not found in local catalogue", gameID), nil)
+		fmt.Println(e.Message)
+		return e
+	}
+	if client.IsPlaceholder(game.Data) {
+		e := clierr.New(clierr.NotFound, fmt.Sprintf("The details of game %d have not been fetched yet. "+
+			"Run 'gogg catalogue refresh --only-missing' first.", gameID), nil)
+		fmt.Println(e.Message)
+		return e
 	}
-	parsedGameData, err := client.ParseGameData(game.Data)
+	parsedGameData, err := client.ParseCachedGameData(game.ID, game.Data)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to parse game details.")
-		fmt.Println("Error parsing game data from local catalogue.")
-		return
+		e := clierr.New(clierr.Internal, "Error parsing game data from local catalogue", err)
+		fmt.Println(e.Message)
+		return e
 	}
 
-	logDownloadParameters(parsedGameData, gameID, downloadPath, languageFullName, platformName, extrasFlag, dlcFlag, resumeFlag, flattenFlag, skipPatchesFlag, numThreads)
+	folder := gameFolder(opts, parsedGameData.Title, gameID)
+	// Two processes downloading the same game into the same directory would write the same files.
+	lock, err := client.LockGameFolder(downloadPath, folder, opts.modes)
+	if err != nil {
+		e := clierr.New(clierr.Internal, "Failed to lock the game folder", err)
+		if errors.Is(err, client.ErrFolderLocked) {
+			e = clierr.New(clierr.Download, fmt.Sprintf("Not downloading: %v; wait for the other download to finish and try again", err), err)
+		}
+		fmt.Println(e.Message)
+		return e
+	}
+	defer func() {
+		if err := lock.Unlock(); err != nil {
+			log.Warn().Err(err).Msg("Failed to release the lock of the game folder")
+		}
+	}()
+	var singleFile *client.GameFile
+	if opts.fileName != "" || opts.fileIndex != 0 {
+		f, e := selectGameFile(parsedGameData, opts)
+		if e != nil {
+			fmt.Println(e.Message)
+			return e
+		}
+		singleFile = &f
+	}
 
-	progressWriter := &cliProgressWriter{}
+	var since client.DownloadOption
+	if opts.sinceVersion && !opts.manifestOnly {
+		old, info, e := earlierDownload(filepath.Join(downloadPath, folder), downloadInfo(opts, languageFullName))
+		if e != nil {
+			fmt.Println(e.Message)
+			return e
+		}
+		since = client.WithSinceVersion(old, info)
+	}
+
+	logDownloadParameters(parsedGameData, gameID, downloadPath, languageFullName, opts)
+
+	if opts.manifestOnly {
+		return writeManifest(ctx, user.AccessToken, parsedGameData, downloadPath, folder, languageFullName, opts)
+	}
 
-	err = client.DownloadGameFiles(ctx, user.AccessToken, parsedGameData, downloadPath, languageFullName, platformName, extrasFlag, dlcFlag, resumeFlag, flattenFlag, skipPatchesFlag, rommLayoutFlag, numThreads, progressWriter)
+	var progressWriter io.Writer = &cliProgressWriter{}
+	if opts.progressFmt == progressFormatJSONL {
+		progressWriter = &jsonlProgressWriter{gameID: gameID}
+	} else if opts.progress != nil {
+		w, done := opts.progress.game()
+		defer done()
+		progressWriter = w
+	}
+	if opts.report != nil {
+		progressWriter = opts.report.game(gameID, parsedGameData.Title, progressWriter)
+	}
+	if opts.perGameLog {
+		gameLog, err := openGameLog(filepath.Join(downloadPath, folder), gameID, opts.modes)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to open the log of the game; downloading without it")
+		} else {
+			defer gameLog.Close()
+			defer func() { gameLog.finish(dlErr) }()
+			gameLog.record("info", "Download started", map[string]any{
+				"title": parsedGameData.Title, "path": downloadPath, "language": languageFullName,
+				"platform": opts.platformName, "extras": opts.extras, "dlcs": opts.dlcs,
+			})
+			opts.gameLog = gameLog
+			progressWriter = &gameLogWriter{log: gameLog, next: progressWriter}
+		}
+	}
+	downloadOpts := []client.DownloadOption{client.WithFileModes(opts.modes), client.WithLanguageFolders(client.LanguageFolders(opts.langFolders)),
+		client.WithMaxConnsPerHost(opts.maxConns), client.WithGameFolder(folder)}
+	if opts.preferLang != "" {
+		downloadOpts = append(downloadOpts, client.WithPreferredLanguage(opts.preferLang))
+	}
+	if opts.extrasOnly {
+		downloadOpts = append(downloadOpts, client.WithExtrasOnly())
+	}
+	if opts.installerOnly {
+		kind := opts.preferKind
+		if kind == "" {
+			kind = operations.InstallerOffline
+		}
+		downloadOpts = append(downloadOpts, client.WithInstallerFilter(func(files []client.PlatformFile) []client.PlatformFile {
+			return operations.NewestInstallers(operations.PreferredInstallers(files, kind))
+		}))
+	}
+	if opts.preserveDate {
+		downloadOpts = append(downloadOpts, client.WithPreserveDate())
+	}
+	if opts.checksumAlgo != "" {
+		downloadOpts = append(downloadOpts, client.WithChecksums(opts.checksumAlgo))
+	}
+	if opts.verifyResume {
+		downloadOpts = append(downloadOpts, client.WithVerifyOnResume())
+	}
+	if opts.strict {
+		downloadOpts = append(downloadOpts, client.WithStrict())
+	}
+	if opts.byteBudget != nil {
+		downloadOpts = append(downloadOpts, client.WithByteBudget(opts.byteBudget))
+	}
+	if opts.slots != nil {
+		downloadOpts = append(downloadOpts, client.WithDownloadSlots(opts.slots))
+	}
+	if opts.flattenExtras != nil {
+		downloadOpts = append(downloadOpts, client.WithFlattenExtras(*opts.flattenExtras))
+	}
+	if opts.noDLCExtras {
+		downloadOpts = append(downloadOpts, client.WithoutDLCExtras())
+	}
+	if opts.minFileBytes > 0 || opts.maxFileBytes > 0 {
+		downloadOpts = append(downloadOpts, client.WithFileSizeRange(opts.minFileBytes, opts.maxFileBytes))
+	}
+	if len(opts.excludes) > 0 {
+		downloadOpts = append(downloadOpts, client.WithExcludedFiles(opts.excludes))
+	}
+	if opts.bestEffort {
+		downloadOpts = append(downloadOpts, client.WithBestEffort())
+	}
+	if opts.fileNames != "" && opts.fileNames != client.FileNamesOriginal {
+		downloadOpts = append(downloadOpts, client.WithFileNameStyle(opts.fileNames))
+	}
+	if opts.stallTimeout > 0 {
+		downloadOpts = append(downloadOpts, client.WithStallTimeout(opts.stallTimeout))
+	}
+	if opts.layout != "" && opts.layout != client.LayoutGogg {
+		downloadOpts = append(downloadOpts, client.WithLayout(opts.layout))
+	}
+	if opts.accurateTotal {
+		downloadOpts = append(downloadOpts, client.WithAccurateTotal())
+	}
+	if opts.checksumFiles {
+		downloadOpts = append(downloadOpts, client.WithChecksumFiles())
+	}
+	if opts.preallocate {
+		downloadOpts = append(downloadOpts, client.WithPreallocation())
+	}
+	if opts.library != nil {
+		downloadOpts = append(downloadOpts, client.WithLibrary(opts.library, opts.dedupeLink))
+	}
+	if since != nil {
+		fmt.Fprintln(statusOutput(opts), "Downloading only the files that are new or changed since the earlier download")
+		downloadOpts = append(downloadOpts, since)
+	}
+	if singleFile != nil {
+		fmt.Fprintf(statusOutput(opts), "Downloading only file #%d: %s (%s)\n", singleFile.Index, singleFile.Name, singleFile.Component)
+		downloadOpts = append(downloadOpts, client.WithFile(*singleFile))
+	}
+	if opts.printMetadata {
+		downloadOpts = append(downloadOpts, client.WithMetadataWriter(os.Stdout))
+	}
+	if opts.checkTarget {
+		downloadOpts = append(downloadOpts, client.WithPreflight(func(r client.PreflightReport) {
+			printPreflightReport(statusOutput(opts), r)
+		}))
+	}
+
+	var skips client.SkipSummary
+	downloadOpts = append(downloadOpts, client.WithSkipSummary(func(s client.SkipSummary) { skips = s }))
+
+	err = client.DownloadGameFiles(ctx, user.AccessToken, parsedGameData, targetPath, languageFullName, opts.platformName, opts.extras, opts.dlcs, opts.resume, opts.flatten, opts.skipPatches, opts.rommLayout, opts.numThreads, progressWriter, downloadOpts...)
+	if skips.Total() > 0 {
+		fmt.Fprintf(statusOutput(opts), "\r%s\n", skips)
+		opts.gameLog.record("info", "Skipped files", map[string]any{"skipped": skips})
+	}
 	if err != nil {
+		var e *clierr.Error
+		var partial *client.PartialDownloadError
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			fmt.Println(clierr.New(clierr.Internal, "Download cancelled or timed out", err).Message)
+			e = clierr.New(clierr.Internal, "Download cancelled or timed out", err)
+		} else if msg, ok := volumeErrorMessage(err); ok {
+			e = clierr.New(clierr.Download, msg, err)
+		} else if errors.Is(err, client.ErrByteBudgetReached) {
+			e = clierr.New(clierr.Download, fmt.Sprintf("Stopped at the download size limit of %s: %v; run the download again to get the rest",
+				progress.FormatBytes(opts.byteBudget.Max()), err), err)
+		} else if errors.As(err, &partial) {
+			e = clierr.New(clierr.Partial, fmt.Sprintf("Downloaded %d of %d file(s) of %s; %d failed:",
+				partial.Total-len(partial.Failed), partial.Total, partial.Title, len(partial.Failed)), err)
+		} else if errors.Is(err, client.ErrNoMatchingFiles) {
+			e = clierr.New(clierr.NotFound, fmt.Sprintf("Nothing to download: %v; use 'gogg catalogue languages' and 'gogg catalogue platforms' to see what the game offers", err), err)
 		} else {
-			fmt.Println(clierr.New(clierr.Download, "Failed to download game files", err).Message)
+			e = clierr.New(clierr.Download, "Failed to download game files", err)
 		}
-		return
+		fmt.Println(e.Message)
+		if partial != nil {
+			printFileFailures(partial.Failed)
+		}
+		if opts.stagingDir != "" {
+			fmt.Printf("Partially downloaded files were left in the staging directory: \"%s\"\n", opts.stagingDir)
+		}
+		return e
 	}
 
-	fmt.Printf("\rGame files downloaded successfully to: \"%s\" \n", filepath.Join(downloadPath, client.SanitizePath(parsedGameData.Title)))
-	if keepLatestFlag {
-		if err := pruneOldVersions(downloadPath, parsedGameData.Title); err != nil {
+	if opts.stagingDir != "" {
+		if err := moveStagedGame(opts.stagingDir, downloadPath, folder, opts.rommLayout); err != nil {
+			e := clierr.New(clierr.Internal, "Failed to move game files from the staging directory", err)
+			fmt.Println(e.Message)
+			return e
+		}
+	}
+
+	// The download info tells a later --since-version what this download got; a download of only some files
+	// of the selection does not change that.
+	gameDirs := client.GameDirs(downloadPath, folder, opts.rommLayout, opts.platformName)
+	if singleFile == nil && !opts.extrasOnly {
+		info := downloadInfo(opts, languageFullName)
+		if opts.checksumFiles {
+			info.ChecksumFiles = checksumFiles(filepath.Join(downloadPath, folder), gameDirs)
+		}
+		if err := client.WriteDownloadInfo(filepath.Join(downloadPath, folder), info, opts.modes); err != nil {
+			log.Warn().Err(err).Msg("Failed to write download info")
+		}
+	}
+	fmt.Fprintf(statusOutput(opts), "\rGame files downloaded successfully to: %s \n", quotedDirs(gameDirs))
+	if opts.keepLatest || opts.pruneDryRun {
+		if err := pruneOldVersions(gameDirs, opts.pruneDryRun); err != nil {
 			log.Warn().Err(err).Msg("Failed to prune old versions")
 		}
 	}
+	if opts.verify == verifyAfter {
+		return verifyDownload(ctx, gameDirs, opts)
+	}
+	return nil
 }
 
-var versionPattern = regexp.MustCompile(`^(?P<prefix>.*?)(?P<ver>\d+(?:\.\d+)+)(?P<suffix>\.[^.]+)$`)
+// verifyDownload checks the downloaded files in gameDirs against their checksums and prints the outcome.
+func verifyDownload(ctx context.Context, gameDirs []string, opts downloadOptions) error {
+	var progress io.Writer = os.Stderr
+	if opts.progressFmt == progressFormatJSONL {
+		progress = nil
+	}
+	report, e := verifyDirs(ctx, gameDirs, validation.DefaultThreads(validation.LocalWorkload), progress)
+	if e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	root := gameDirs[0]
+	if len(gameDirs) > 1 {
+		root = filepath.Dir(root)
+	}
+	printVerifyReport(statusOutput(opts), root, report)
+	opts.gameLog.verified(report)
+	if e := verifyError(report); e != nil {
+		fmt.Println(e.Message)
+		return e
+	}
+	return nil
+}
+
+// writeManifest writes the metadata.json and download_info.json of a game without downloading its files,
+// so the game can be checked for updates before it is downloaded.
+func writeManifest(ctx context.Context, accessToken string, game client.Game, downloadPath, folder, language string, opts downloadOptions) error {
+	manifestOpts := []client.DownloadOption{client.WithManifestOnly(), client.WithFileModes(opts.modes), client.WithGameFolder(folder)}
+	if opts.printMetadata {
+		manifestOpts = append(manifestOpts, client.WithMetadataWriter(os.Stdout))
+	}
+	err := client.DownloadGameFiles(ctx, accessToken, game, downloadPath, language, opts.platformName, opts.extras, opts.dlcs,
+		opts.resume, opts.flatten, opts.skipPatches, opts.rommLayout, opts.numThreads, io.Discard, manifestOpts...)
+	if err != nil {
+		e := clierr.Wrap(clierr.Internal, "Failed to write the game manifest", err)
+		fmt.Println(e.Message)
+		return e
+	}
+	gameDir := filepath.Join(downloadPath, folder)
+	if err := client.WriteDownloadInfo(gameDir, downloadInfo(opts, language), opts.modes); err != nil {
+		e := clierr.New(clierr.Internal, "Failed to write the download info", err)
+		fmt.Println(e.Message)
+		return e
+	}
+	fmt.Fprintf(statusOutput(opts), "Game manifest written to: \"%s\"\n", gameDir)
+	return nil
+}
 
-func parseVersion(filename string) (prefix string, verSlice []int, suffix string, ok bool) {
-	m := versionPattern.FindStringSubmatch(filename)
-	if m == nil {
-		return "", nil, "", false
+// parseModeFlags parses --dir-mode and --file-mode into opts.modes. An empty flag keeps the default mode.
+func parseModeFlags(opts *downloadOptions) *clierr.Error {
+	opts.modes = client.DefaultFileModes
+	if opts.dirMode != "" {
+		mode, err := validation.ParseFileMode(opts.dirMode)
+		if err != nil {
+			return clierr.New(clierr.Validation, "Invalid directory mode", err)
+		}
+		opts.modes.Dir = mode
 	}
-	prefix = m[1]
-	suffix = m[3]
-	verParts := strings.Split(m[2], ".")
-	for _, p := range verParts {
-		v, err := strconv.Atoi(p)
+	if opts.fileMode != "" {
+		mode, err := validation.ParseFileMode(opts.fileMode)
 		if err != nil {
-			return "", nil, "", false
+			return clierr.New(clierr.Validation, "Invalid file mode", err)
 		}
-		verSlice = append(verSlice, v)
+		opts.modes.File = mode
 	}
-	return prefix, verSlice, suffix, true
+	return nil
 }
 
-func compareVersions(a, b []int) int { // 1 if a>b, -1 if a<b, 0 if eq
-	for i := 0; i < len(a) || i < len(b); i++ {
-		va, vb := 0, 0
-		if i < len(a) {
-			va = a[i]
+// selectGameFile returns the file of game picked by the file or file-index flag of opts.
+func selectGameFile(game client.Game, opts downloadOptions) (client.GameFile, *clierr.Error) {
+	var f client.GameFile
+	var err error
+	if opts.fileName != "" {
+		f, err = game.FindFile(opts.fileName)
+	} else {
+		f, err = game.FileAt(opts.fileIndex)
+	}
+	if err != nil {
+		t := clierr.Validation
+		if errors.Is(err, client.ErrFileNotFound) {
+			t = clierr.NotFound
 		}
-		if i < len(b) {
-			vb = b[i]
+		return client.GameFile{}, clierr.New(t, fmt.Sprintf("%v; use 'gogg catalogue info --files' to list the files of the game", err), err)
+	}
+	return f, nil
+}
+
+// downloadInfo returns the download_info.json of a download of the game in language with opts.
+func downloadInfo(opts downloadOptions, language string) client.DownloadInfo {
+	return client.DownloadInfo{
+		Language:    language,
+		Platform:    opts.platformName,
+		Extras:      opts.extras,
+		DLCs:        opts.dlcs,
+		SkipPatches: opts.skipPatches,
+		Flatten:     opts.flatten,
+		Resume:      opts.resume,
+		Threads:     opts.numThreads,
+	}
+}
+
+// checksumFiles returns the checksum files of GOG stored in gameDirs, relative to infoDir, the folder of the
+// download_info.json that lists them.
+func checksumFiles(infoDir string, gameDirs []string) []string {
+	var files []string
+	for _, dir := range gameDirs {
+		found, err := client.FindChecksumFiles(dir)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Warn().Err(err).Str("dir", dir).Msg("Failed to list the checksum files")
+			}
+			continue
 		}
-		if va > vb {
-			return 1
+		for _, name := range found {
+			rel, err := filepath.Rel(infoDir, filepath.Join(dir, filepath.FromSlash(name)))
+			if err != nil {
+				continue
+			}
+			files = append(files, filepath.ToSlash(rel))
 		}
-		if va < vb {
-			return -1
+	}
+	return files
+}
+
+// earlierDownload reads the metadata.json and download_info.json of the earlier download in the game folder dir.
+// An earlier download without a download_info.json is taken to have been made with current, the download
+// info of this one.
+func earlierDownload(dir string, current client.DownloadInfo) (client.Game, client.DownloadInfo, *clierr.Error) {
+	old, err := client.ReadGameMetadata(dir)
+	if os.IsNotExist(err) {
+		return client.Game{}, client.DownloadInfo{}, clierr.New(clierr.NotFound,
+			fmt.Sprintf("No earlier download found in \"%s\" to compare with; download the game without --since-version first", dir), err)
+	}
+	if err != nil {
+		return client.Game{}, client.DownloadInfo{}, clierr.New(clierr.Internal, "Failed to read the metadata.json of the earlier download", err)
+	}
+	info, err := client.ReadDownloadInfo(dir)
+	if os.IsNotExist(err) {
+		return old, current, nil
+	}
+	if err != nil {
+		return client.Game{}, client.DownloadInfo{}, clierr.New(clierr.Internal, "Failed to read the download_info.json of the earlier download", err)
+	}
+	return old, info, nil
+}
+
+// gameFolderTemplate returns the folder name template given by the folder-name and folder-id flags of opts.
+func gameFolderTemplate(opts downloadOptions) string {
+	template := opts.folderName
+	if template == "" {
+		template = client.DefaultFolderTemplate
+	}
+	if opts.folderID && !strings.Contains(template, "{id}") {
+		template += "-{id}"
+	}
+	return template
+}
+
+// gameFolder returns the name of the folder of a game for the folder-name, folder-id, ascii-titles, and
+// title-style flags of opts.
+func gameFolder(opts downloadOptions, title string, id int) string {
+	if opts.asciiTitles {
+		title = client.ASCIITitle(title)
+	}
+	return client.GameFolderNameStyle(gameFolderTemplate(opts), title, id, opts.titleStyle)
+}
+
+// validateTitleStyle checks the title-style flag. An empty style is the default slug style.
+func validateTitleStyle(style string) *clierr.Error {
+	if style == "" {
+		return nil
+	}
+	if err := client.ValidateTitleStyle(style); err != nil {
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid title style %q. Must be one of %v", style, client.TitleStyles), err)
+	}
+	return nil
+}
+
+func addLayoutFlag(cmd *cobra.Command, target *string) {
+	cmd.Flags().StringVar(target, "layout", client.LayoutGogg,
+		"Folder layout of the game files [gogg, lgog]; lgog is the layout of lgogdownloader, with patches, extras, and DLCs in their own folders and game folders named like the_witcher_3_wild_hunt")
+}
+
+// applyLayoutPreset makes the lgog layout name the game folders like lgogdownloader and leave out the language
+// folders, unless the title-style or language-folders flag is given.
+func applyLayoutPreset(cmd *cobra.Command, opts *downloadOptions) {
+	if opts.layout != client.LayoutLgog {
+		return
+	}
+	if !cmd.Flags().Changed("title-style") {
+		opts.titleStyle = client.TitleStyleUnderscores
+	}
+	if f := cmd.Flags().Lookup("language-folders"); f == nil || !f.Changed {
+		opts.langFolders = string(client.LanguageFoldersNever)
+	}
+}
+
+// validateLayout checks the layout flag. An empty layout is Gogg's own.
+func validateLayout(opts downloadOptions) *clierr.Error {
+	if opts.layout == "" {
+		return nil
+	}
+	if err := client.ValidateLayout(opts.layout); err != nil {
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid layout %q. Must be one of %v", opts.layout, client.Layouts), err)
+	}
+	if opts.layout == client.LayoutLgog && opts.rommLayout {
+		return clierr.New(clierr.Validation, "--layout=lgog cannot be combined with --romm", nil)
+	}
+	return nil
+}
+
+// validatePreferLanguage checks the prefer-language flag, which only applies when all languages are downloaded.
+func validatePreferLanguage(opts downloadOptions) *clierr.Error {
+	if opts.preferLang == "" {
+		return nil
+	}
+	if _, _, ok := client.NormalizeLanguage(opts.preferLang); !ok {
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid preferred language %q; use a language code like en or de", opts.preferLang), nil)
+	}
+	if !strings.EqualFold(strings.TrimSpace(opts.language), client.AllLanguages) {
+		return clierr.New(clierr.Validation, "--prefer-language needs --lang=all", nil)
+	}
+	return nil
+}
+
+// validatePreferKind checks the prefer flag, which picks between the offline and online installers of a game when
+// only one installer is downloaded.
+func validatePreferKind(opts downloadOptions) *clierr.Error {
+	if opts.preferKind == "" {
+		return nil
+	}
+	if !slices.Contains(operations.InstallerKinds, opts.preferKind) {
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid installer kind %q. Must be one of [%s]", opts.preferKind, strings.Join(operations.InstallerKinds, ", ")), nil)
+	}
+	if !opts.installerOnly {
+		return clierr.New(clierr.Validation, "--prefer needs --installer-only", nil)
+	}
+	return nil
+}
+
+func addStallTimeoutFlag(cmd *cobra.Command, target *time.Duration) {
+	cmd.Flags().DurationVar(target, "stall-timeout", client.DefaultStallTimeout,
+		"Retry a file, resuming it, when none of its data arrives for this long, like 30s or 5m; the other files go on. 0 waits as long as the connection is open")
+}
+
+func addAccurateTotalFlag(cmd *cobra.Command, target *bool) {
+	cmd.Flags().BoolVar(target, "accurate-total", false,
+		"Before downloading a game, ask the server for the size of each of its files, so the overall progress shows the real total instead of the sizes GOG lists; this sends a request per file up front")
+}
+
+func addChecksumFilesFlag(cmd *cobra.Command, target *bool) {
+	cmd.Flags().BoolVar(target, "download-checksums", false,
+		"Store the checksum file GOG publishes for each installer and patch next to it, named like the file with .xml appended, so the files can be checked offline later")
+}
+
+func addPreallocationFlag(cmd *cobra.Command, target *bool) {
+	cmd.Flags().BoolVar(target, "include-pre-allocation", false,
+		"Reserve the disk space of each file before writing it, which avoids fragmenting large installers and fails a file early when the volume is too small; does nothing on systems or file systems that cannot reserve space")
+}
+
+func addPerGameLogFlag(cmd *cobra.Command, target *bool) {
+	cmd.Flags().BoolVar(target, "per-game-log", false,
+		"Append what each download did, like the outcome, retries, and verification of every file, to a "+gameLogName+" in the folder of the game")
+}
+
+// validateStallTimeout checks the stall-timeout flag.
+func validateStallTimeout(d time.Duration) *clierr.Error {
+	if d < 0 {
+		return clierr.New(clierr.Validation, "--stall-timeout must be 0 (no timeout) or more", nil)
+	}
+	return nil
+}
+
+// validateFileNameStyle checks the file-names flag. An empty style keeps the original names.
+func validateFileNameStyle(style string) *clierr.Error {
+	if style == "" {
+		return nil
+	}
+	if err := client.ValidateFileNameStyle(style); err != nil {
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid file name style %q. Must be one of %v", style, client.FileNameStyles), err)
+	}
+	return nil
+}
+
+// parseMaxBytesFlag makes the byte budget of opts from its max-bytes flag, unless a budget was already made,
+// so the games of a batch share one budget.
+func parseMaxBytesFlag(opts *downloadOptions) *clierr.Error {
+	if opts.maxBytes == "" || opts.byteBudget != nil {
+		return nil
+	}
+	size, err := client.ParseSize(opts.maxBytes)
+	if err != nil || size <= 0 {
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid --max-bytes %q. Use a positive size like 20GB or 500MB", opts.maxBytes), err)
+	}
+	opts.byteBudget = client.NewByteBudget(size)
+	return nil
+}
+
+// parseFileSizeFlags makes the file size limits of opts from its min-file-size and max-file-size flags.
+func parseFileSizeFlags(opts *downloadOptions) *clierr.Error {
+	parse := func(flag, value string) (int64, *clierr.Error) {
+		if value == "" {
+			return 0, nil
+		}
+		size, err := client.ParseSize(value)
+		if err != nil || size <= 0 {
+			return 0, clierr.New(clierr.Validation, fmt.Sprintf("Invalid --%s %q. Use a positive size like 4GB or 500MB", flag, value), err)
 		}
+		return size, nil
+	}
+	var e *clierr.Error
+	if opts.minFileBytes, e = parse("min-file-size", opts.minFileSize); e != nil {
+		return e
+	}
+	if opts.maxFileBytes, e = parse("max-file-size", opts.maxFileSize); e != nil {
+		return e
+	}
+	if opts.minFileBytes > 0 && opts.maxFileBytes > 0 && opts.minFileBytes > opts.maxFileBytes {
+		return clierr.New(clierr.Validation, "--min-file-size cannot be larger than --max-file-size", nil)
 	}
-	return 0
+	return nil
 }
 
-func pruneOldVersions(downloadPath, title string) error {
-	root := filepath.Join(downloadPath, client.SanitizePath(title))
-	if _, err := os.Stat(root); err != nil {
-		return err
+func addExcludeFlag(cmd *cobra.Command, target *[]string) {
+	cmd.Flags().StringSliceVar(target, "exclude", nil,
+		"Skip the files whose names match this pattern, like '*patch*' or '*4k*', ignoring case; matched against the name of the downloaded file and the name GOG lists (can be repeated)")
+}
+
+// validateExcludes checks the patterns of the exclude flag.
+func validateExcludes(patterns []string) *clierr.Error {
+	for _, pattern := range patterns {
+		if err := client.ValidateFilePattern(pattern); err != nil {
+			return clierr.New(clierr.Validation, fmt.Sprintf("Invalid --exclude pattern %q", pattern), err)
+		}
+	}
+	return nil
+}
+
+// printFileFailures lists the files that failed in a --best-effort download and how to retry them.
+func printFileFailures(failures []client.FileFailure) {
+	for _, f := range failures {
+		fmt.Printf("  - %s (%s): %v\n", f.Name, f.Link, f.Err)
+	}
+	fmt.Println("Run the same command again to retry them (files that are complete are skipped), or add --file with the name in parentheses to retry one.")
+}
+
+// volumeErrorMessage returns what to tell the user if err was caused by a full or read-only target volume.
+func volumeErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, client.ErrVolumeFull):
+		return "The target volume is full. Free some space or choose another download directory, then run the download again to resume it", true
+	case errors.Is(err, client.ErrVolumeReadOnly):
+		return "The target volume is read-only. Choose a writable download directory", true
+	default:
+		return "", false
+	}
+}
+
+// printPreflightReport prints the warnings of a check of the target file system, or that it found nothing.
+func printPreflightReport(w io.Writer, r client.PreflightReport) {
+	if len(r.Warnings) == 0 {
+		fmt.Fprintf(w, "Target check passed: %d files in %d folders, longest path %d characters\n", r.Files, r.Folders, len(r.LongestPath))
+		return
+	}
+	for _, warning := range r.Warnings {
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+}
+
+// samePath reports whether a and b refer to the same directory.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
 	}
-	// Candidate extensions (installer types)
-	extAllowed := map[string]struct{}{".exe": {}, ".bin": {}, ".dmg": {}, ".sh": {}, ".zip": {}, ".tar.gz": {}, ".rar": {}}
-	latestByPrefix := make(map[string]struct {
-		file string
-		ver  []int
-	})
-	filesByPrefix := make(map[string][]string)
-	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
-		}
-		name := info.Name()
-		ext := filepath.Ext(name)
-		// handle .tar.gz
-		if strings.HasSuffix(name, ".tar.gz") {
-			ext = ".tar.gz"
-		}
-		if _, ok := extAllowed[ext]; !ok {
-			return nil
-		}
-		prefix, ver, _, ok := parseVersion(name)
-		if !ok || len(ver) == 0 {
-			return nil
-		}
-		filesByPrefix[prefix] = append(filesByPrefix[prefix], path)
-		curr, exists := latestByPrefix[prefix]
-		if !exists || compareVersions(ver, curr.ver) == 1 {
-			latestByPrefix[prefix] = struct {
-				file string
-				ver  []int
-			}{file: path, ver: ver}
+	return absA == absB
+}
+
+// quotedDirs returns the quoted directories of dirs that exist, separated by commas, or all of them if none
+// exists, so a RomM download into all platforms names only the platform folders the game has files for.
+func quotedDirs(dirs []string) string {
+	var quoted []string
+	for _, dir := range dirs {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			quoted = append(quoted, fmt.Sprintf("%q", dir))
 		}
-		return nil
-	})
-	// Remove older ones
-	for prefix, files := range filesByPrefix {
-		latest := latestByPrefix[prefix].file
-		for _, f := range files {
-			if f != latest {
-				if err := os.Remove(f); err != nil {
-					log.Warn().Err(err).Str("file", f).Msg("Failed to remove old version file")
-				}
+	}
+	if len(quoted) == 0 {
+		for _, dir := range dirs {
+			quoted = append(quoted, fmt.Sprintf("%q", dir))
+		}
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// moveStagedGame moves the game folder from stagingDir to downloadPath.
+// With the RomM layout the game is stored once per platform folder, so every platform folder is moved too.
+func moveStagedGame(stagingDir, downloadPath, gameDir string, rommLayout bool) error {
+	relDirs := []string{gameDir}
+	if rommLayout {
+		entries, err := os.ReadDir(stagingDir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
 			}
+			if _, err := os.Stat(filepath.Join(stagingDir, e.Name(), gameDir)); err == nil {
+				relDirs = append(relDirs, filepath.Join(e.Name(), gameDir))
+			}
+		}
+	}
+
+	for _, rel := range relDirs {
+		src := filepath.Join(stagingDir, rel)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		dst := filepath.Join(downloadPath, rel)
+		log.Info().Msgf("Moving %s to %s", src, dst)
+		if err := operations.MoveDir(src, dst); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func logDownloadParameters(game client.Game, gameID int, downloadPath, language, platformName string, extrasFlag, dlcFlag, resumeFlag, flattenFlag, skipPatchesFlag bool, numThreads int) {
-	fmt.Println("================================= Download Parameters =====================================")
-	fmt.Printf("Downloading \"%v\" (with game ID=\"%d\") to \"%v\"\n", game.Title, gameID, downloadPath)
-	fmt.Printf("Platform: \"%v\", Language: '%v'\n", platformName, language)
-	fmt.Printf("Include Extras: %v, Include DLCs: %v, Resume enabled: %v\n", extrasFlag, dlcFlag, resumeFlag)
-	fmt.Printf("Number of worker threads for download: %d\n", numThreads)
-	fmt.Printf("Flatten directory structure: %v\n", flattenFlag)
-	fmt.Printf("Skip patches: %v\n", skipPatchesFlag)
-	fmt.Println("============================================================================================")
+// stdinIsTerminal reports whether the CLI is running interactively. It is a variable so tests can override it.
+var stdinIsTerminal = func() bool { return term.IsTerminal(int(os.Stdin.Fd())) }
+
+// assumeYes is set by the global --yes flag.
+var assumeYes bool
+
+// confirm asks the user to confirm an action that removes or replaces data. With --yes, or when the CLI is not
+// running interactively, nobody is asked and the action goes ahead.
+func confirm(prompt string) bool {
+	if assumeYes || !stdinIsTerminal() {
+		return true
+	}
+	return confirmAction(prompt)
+}
+
+// confirmAction asks the user a yes/no question on stdin and reports whether they answered yes.
+func confirmAction(prompt string) bool {
+	fmt.Print(prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// pruneOldVersions removes the installer files in roots that newer versions replaced, after asking the user.
+func pruneOldVersions(roots []string, dryRun bool) error {
+	plan, err := operations.PlanPrune(roots...)
+	if err != nil {
+		return err
+	}
+	if len(plan.Files) == 0 {
+		fmt.Println("No older installer versions found to remove.")
+		return nil
+	}
+
+	if dryRun {
+		fmt.Printf("Dry run: %d old installer file(s) would be removed, reclaiming %s:\n", len(plan.Files), progress.FormatBytes(plan.TotalBytes))
+	} else {
+		fmt.Printf("Found %d old installer file(s) to remove, reclaiming %s:\n", len(plan.Files), progress.FormatBytes(plan.TotalBytes))
+	}
+	for _, f := range plan.Files {
+		fmt.Printf("  %s\n", f)
+	}
+	if dryRun {
+		return nil
+	}
+	if !confirm("Remove these files? [y/N]: ") {
+		fmt.Println("Pruning skipped.")
+		return nil
+	}
+
+	removed := operations.RemoveFiles(plan.Files)
+	for _, f := range removed {
+		log.Info().Str("file", f).Msg("Removed old version file")
+	}
+	fmt.Printf("Removed %d old installer file(s).\n", len(removed))
+	return nil
+}
+
+// statusOutput returns where the messages about a download go: stdout, or stderr if stdout is used for the
+// metadata of the game or for the JSON Lines progress.
+func statusOutput(opts downloadOptions) io.Writer {
+	if opts.printMetadata || opts.progressFmt == progressFormatJSONL {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+func logDownloadParameters(game client.Game, gameID int, downloadPath, language string, opts downloadOptions) {
+	w := statusOutput(opts)
+	fmt.Fprintln(w, "================================= Download Parameters =====================================")
+	fmt.Fprintf(w, "Downloading \"%v\" (with game ID=\"%d\") to \"%v\"\n", game.Title, gameID, downloadPath)
+	if opts.stagingDir != "" {
+		fmt.Fprintf(w, "Staging directory: \"%v\"\n", opts.stagingDir)
+	}
+	fmt.Fprintf(w, "Platform: \"%v\", Language: '%v'\n", opts.platformName, language)
+	fmt.Fprintf(w, "Include Extras: %v, Include DLCs: %v, Resume enabled: %v\n", opts.extras, opts.dlcs, opts.resume)
+	if opts.noDLCExtras {
+		fmt.Fprintln(w, "Skipping the extras of DLCs")
+	}
+	if opts.minFileBytes > 0 {
+		fmt.Fprintf(w, "Skipping files smaller than %s\n", progress.FormatBytes(opts.minFileBytes))
+	}
+	if opts.maxFileBytes > 0 {
+		fmt.Fprintf(w, "Skipping files larger than %s\n", progress.FormatBytes(opts.maxFileBytes))
+	}
+	if len(opts.excludes) > 0 {
+		fmt.Fprintf(w, "Skipping files matching: %s\n", strings.Join(opts.excludes, ", "))
+	}
+	fmt.Fprintf(w, "Number of worker threads for download: %d\n", opts.numThreads)
+	fmt.Fprintf(w, "Flatten directory structure: %v\n", opts.flatten)
+	if opts.flattenExtras != nil {
+		fmt.Fprintf(w, "Flatten extras: %v\n", *opts.flattenExtras)
+	}
+	fmt.Fprintf(w, "Skip patches: %v\n", opts.skipPatches)
+	fmt.Fprintln(w, "============================================================================================")
 }
@@ -3,9 +3,12 @@ package cmd
 import (
 	"context"
 	"errors"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
 	"github.com/habedi/gogg/db"
 	"github.com/habedi/gogg/pkg/clierr"
 )
@@ -34,5 +37,50 @@ func TestExecuteDownload_Cancel(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 	svc := &auth.Service{Storer: testStorer{}}
-	executeDownload(ctx, svc, 1, "/tmp", "en", "windows", false, false, true, true, false, false, false, 1)
+	executeDownload(ctx, svc, 1, "/tmp", downloadOptions{language: "en", platformName: "windows", resume: true, flatten: true, numThreads: 1})
+}
+
+func TestExecuteDownload_RefusesGameWithoutDetails(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 7, "Placeholder Game", client.PlaceholderData("Placeholder Game"))
+	svc := auth.NewService(&mockTokenStorer{}, &mockTokenRefresher{})
+
+	err := executeDownload(context.Background(), svc, 7, t.TempDir(), downloadOptions{language: "en", platformName: "windows", numThreads: 1})
+	var ce *clierr.Error
+	if !errors.As(err, &ce) || ce.Type != clierr.NotFound {
+		t.Fatalf("expected a NotFound error, got %v", err)
+	}
+	if !strings.Contains(ce.Message, "--only-missing") {
+		t.Fatalf("expected a hint to fetch the details, got %q", ce.Message)
+	}
+}
+
+func TestExecuteDownload_FailsWhenTheGameFolderIsLocked(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 61, "Locked Game", `{"title":"Locked Game","downloads":[]}`)
+	svc := auth.NewService(&mockTokenStorer{}, &mockTokenRefresher{})
+	dir := t.TempDir()
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 1, progressFmt: progressFormatJSONL}
+
+	lock, err := client.LockGameFolder(dir, "locked-game", client.DefaultFileModes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout2(func() { err = executeDownload(context.Background(), svc, 61, dir, opts) })
+	if !errors.Is(err, client.ErrFolderLocked) || !strings.Contains(out, "wait for the other download to finish") {
+		t.Fatalf("expected the download to refuse the locked folder, got %v and %q", err, out)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	captureStdout2(func() { err = executeDownload(context.Background(), svc, 61, dir, opts) })
+	if err != nil {
+		t.Fatalf("expected the download to succeed once the folder is unlocked, got %v", err)
+	}
+	if _, err := os.Stat(client.FolderLockPath(dir, "locked-game")); !os.IsNotExist(err) {
+		t.Fatalf("expected the lock to be released after the download, got %v", err)
+	}
 }
@@ -7,8 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/clierr"
 )
 
 func TestDownloadCmd_InvalidID(t *testing.T) {
@@ -42,7 +45,7 @@ func captureStdout2(f func()) string {
 func TestExecuteDownload_InvalidLanguagePrintsList(t *testing.T) {
 	// Use invalid language code to trigger early return and listing of supported languages
 	out := captureStdout2(func() {
-		executeDownload(context.Background(), nil, 1, filepath.Join(t.TempDir(), "dl"), "xx", "windows", true, true, true, true, false, false, false, 2)
+		executeDownload(context.Background(), nil, 1, filepath.Join(t.TempDir(), "dl"), downloadOptions{language: "xx", platformName: "windows", extras: true, dlcs: true, resume: true, flatten: true, numThreads: 2})
 	})
 	if out == "" {
 		t.Fatalf("expected output for invalid language")
@@ -60,3 +63,163 @@ func containsAll(s string, subs []string) bool {
 	}
 	return true
 }
+
+func TestParseMaxBytesFlag(t *testing.T) {
+	opts := downloadOptions{maxBytes: "1.5GB"}
+	if e := parseMaxBytesFlag(&opts); e != nil {
+		t.Fatalf("unexpected error: %v", e)
+	}
+	if opts.byteBudget == nil || opts.byteBudget.Max() != 1536*1024*1024 {
+		t.Fatalf("unexpected budget: %+v", opts.byteBudget)
+	}
+	budget := opts.byteBudget
+	if e := parseMaxBytesFlag(&opts); e != nil || opts.byteBudget != budget {
+		t.Fatalf("the budget of a batch should be kept, got %v", e)
+	}
+
+	for _, value := range []string{"lots", "0", "-5MB"} {
+		opts := downloadOptions{maxBytes: value}
+		if e := parseMaxBytesFlag(&opts); e == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
+
+func TestParseFileSizeFlags(t *testing.T) {
+	opts := downloadOptions{minFileSize: "1MB", maxFileSize: "4GB"}
+	if e := parseFileSizeFlags(&opts); e != nil {
+		t.Fatalf("unexpected error: %v", e)
+	}
+	if opts.minFileBytes != 1024*1024 || opts.maxFileBytes != 4*1024*1024*1024 {
+		t.Fatalf("unexpected limits: %d, %d", opts.minFileBytes, opts.maxFileBytes)
+	}
+
+	for _, o := range []downloadOptions{
+		{maxFileSize: "huge"},
+		{minFileSize: "0"},
+		{minFileSize: "2GB", maxFileSize: "1GB"},
+	} {
+		if e := parseFileSizeFlags(&o); e == nil {
+			t.Errorf("expected an error for min %q and max %q", o.minFileSize, o.maxFileSize)
+		}
+	}
+}
+
+func TestValidateExcludes(t *testing.T) {
+	if e := validateExcludes([]string{"*patch*", "setup_?.exe", "*[0-9]k*"}); e != nil {
+		t.Fatalf("unexpected error: %v", e)
+	}
+	if e := validateExcludes([]string{"*patch*", "[4k"}); e == nil || e.Type != clierr.Validation {
+		t.Fatalf("expected a validation error for a malformed pattern, got %v", e)
+	}
+}
+
+func TestExecuteDownload_InvalidTitleStyle(t *testing.T) {
+	out := captureStdout2(func() {
+		executeDownload(context.Background(), nil, 1, t.TempDir(), downloadOptions{language: "en", platformName: "windows", numThreads: 2, titleStyle: "ascii"})
+	})
+	if !containsAll(out, []string{"Invalid title style", "slug", "raw"}) {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+func TestGameFolder_TitleStyle(t *testing.T) {
+	opts := downloadOptions{folderID: true}
+	if got := gameFolder(opts, "Ведьмак 3: Дикая Охота", 7); got != "3-7" {
+		t.Fatalf("slug folder = %q", got)
+	}
+	opts.titleStyle = "raw"
+	if got := gameFolder(opts, "Ведьмак 3: Дикая Охота", 7); got != "Ведьмак 3 - Дикая Охота-7" {
+		t.Fatalf("raw folder = %q", got)
+	}
+	opts.asciiTitles = true
+	if got := gameFolder(opts, "Ведьмак 3: Дикая Охота", 7); got != "Vedmak 3 - Dikaya Okhota-7" {
+		t.Fatalf("raw ASCII folder = %q", got)
+	}
+}
+
+func TestExecuteDownload_InvalidFileNameStyle(t *testing.T) {
+	out := captureStdout2(func() {
+		executeDownload(context.Background(), nil, 1, t.TempDir(), downloadOptions{language: "en", platformName: "windows", numThreads: 2, fileNames: "upper"})
+	})
+	if !containsAll(out, []string{"Invalid file name style", "original", "lower-underscores"}) {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+func TestExecuteDownload_InvalidLayout(t *testing.T) {
+	for _, tc := range []struct {
+		opts downloadOptions
+		want string
+	}{
+		{downloadOptions{language: "en", platformName: "windows", numThreads: 2, layout: "gog"}, "Invalid layout"},
+		{downloadOptions{language: "en", platformName: "windows", numThreads: 2, layout: "lgog", rommLayout: true}, "cannot be combined with --romm"},
+	} {
+		out := captureStdout2(func() {
+			executeDownload(context.Background(), nil, 1, t.TempDir(), tc.opts)
+		})
+		if !containsAll(out, []string{tc.want}) {
+			t.Fatalf("unexpected output: %s", out)
+		}
+	}
+}
+
+func TestApplyLayoutPreset(t *testing.T) {
+	cmd := downloadCmd(noLogin)
+	opts := downloadOptions{layout: client.LayoutLgog, titleStyle: client.TitleStyleSlug, langFolders: string(client.LanguageFoldersAuto)}
+	applyLayoutPreset(cmd, &opts)
+	if opts.titleStyle != client.TitleStyleUnderscores || opts.langFolders != string(client.LanguageFoldersNever) {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	// Flags that are given win over the preset.
+	cmd = downloadCmd(noLogin)
+	if err := cmd.ParseFlags([]string{"--title-style=raw", "--language-folders=always"}); err != nil {
+		t.Fatal(err)
+	}
+	opts = downloadOptions{layout: client.LayoutLgog, titleStyle: client.TitleStyleRaw, langFolders: string(client.LanguageFoldersAlways)}
+	applyLayoutPreset(cmd, &opts)
+	if opts.titleStyle != client.TitleStyleRaw || opts.langFolders != string(client.LanguageFoldersAlways) {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+}
+
+func TestExecuteDownload_NegativeStallTimeout(t *testing.T) {
+	out := captureStdout2(func() {
+		executeDownload(context.Background(), nil, 1, t.TempDir(), downloadOptions{language: "en", platformName: "windows", numThreads: 2, stallTimeout: -time.Second})
+	})
+	if !containsAll(out, []string{"--stall-timeout must be 0"}) {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+func TestExecuteDownload_InvalidPreferLanguage(t *testing.T) {
+	for _, tc := range []struct{ lang, preferLang, want string }{
+		{"en", "de", "--prefer-language needs --lang=all"},
+		{"all", "xx", "Invalid preferred language"},
+	} {
+		out := captureStdout2(func() {
+			executeDownload(context.Background(), nil, 1, t.TempDir(), downloadOptions{language: tc.lang, preferLang: tc.preferLang, platformName: "windows", numThreads: 2})
+		})
+		if !containsAll(out, []string{tc.want}) {
+			t.Fatalf("unexpected output for --lang=%s: %s", tc.lang, out)
+		}
+	}
+}
+
+func TestExecuteDownload_InvalidPreferKind(t *testing.T) {
+	for _, tc := range []struct {
+		installerOnly bool
+		kind, want    string
+	}{
+		{false, "offline", "--prefer needs --installer-only"},
+		{true, "full", `Invalid installer kind "full". Must be one of [offline, online]`},
+	} {
+		out := captureStdout2(func() {
+			executeDownload(context.Background(), nil, 1, t.TempDir(), downloadOptions{language: "en", installerOnly: tc.installerOnly, preferKind: tc.kind, platformName: "windows", numThreads: 2})
+		})
+		if !containsAll(out, []string{tc.want}) {
+			t.Fatalf("unexpected output for --prefer=%s: %s", tc.kind, out)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupPruneFixture(t *testing.T) (string, string) {
+	t.Helper()
+	downloadDir := t.TempDir()
+	gameDir := filepath.Join(downloadDir, "test-game")
+	if err := os.MkdirAll(gameDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"setup_test_game_1.0_(100).exe",
+		"setup_test_game_1.0_(100)-1.bin",
+		"setup_test_game_1.1_(200).exe",
+		"setup_test_game_1.1_(200)-1.bin",
+	} {
+		if err := os.WriteFile(filepath.Join(gameDir, name), []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return downloadDir, gameDir
+}
+
+func TestPruneOldVersions_DryRunKeepsFiles(t *testing.T) {
+	downloadDir, gameDir := setupPruneFixture(t)
+
+	out := captureStdout2(func() {
+		if err := pruneOldVersions([]string{filepath.Join(downloadDir, "test-game")}, true); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "Dry run: 2 old installer file(s) would be removed, reclaiming 8 B") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	if !strings.Contains(out, "setup_test_game_1.0_(100)-1.bin") {
+		t.Fatalf("expected old part file to be listed: %s", out)
+	}
+	entries, _ := os.ReadDir(gameDir)
+	if len(entries) != 4 {
+		t.Fatalf("dry run must not delete files, got %d entries", len(entries))
+	}
+}
+
+func TestPruneOldVersions_NonInteractiveRemovesOldSet(t *testing.T) {
+	downloadDir, gameDir := setupPruneFixture(t)
+	orig := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	defer func() { stdinIsTerminal = orig }()
+
+	out := captureStdout2(func() {
+		if err := pruneOldVersions([]string{filepath.Join(downloadDir, "test-game")}, false); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "Removed 2 old installer file(s).") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	for _, name := range []string{"setup_test_game_1.0_(100).exe", "setup_test_game_1.0_(100)-1.bin"} {
+		if _, err := os.Stat(filepath.Join(gameDir, name)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed", name)
+		}
+	}
+	for _, name := range []string{"setup_test_game_1.1_(200).exe", "setup_test_game_1.1_(200)-1.bin"} {
+		if _, err := os.Stat(filepath.Join(gameDir, name)); err != nil {
+			t.Fatalf("expected %s to be kept: %v", name, err)
+		}
+	}
+}
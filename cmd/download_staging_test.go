@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveStagedGame_RommLayout(t *testing.T) {
+	stagingDir := t.TempDir()
+	downloadDir := t.TempDir()
+	for _, rel := range []string{
+		filepath.Join("windows", "test-game", "setup.exe"),
+		filepath.Join("linux", "test-game", "game.sh"),
+		filepath.Join("linux", "other-game", "other.sh"),
+	} {
+		path := filepath.Join(stagingDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := moveStagedGame(stagingDir, downloadDir, "test-game", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, rel := range []string{
+		filepath.Join("windows", "test-game", "setup.exe"),
+		filepath.Join("linux", "test-game", "game.sh"),
+	} {
+		if _, err := os.Stat(filepath.Join(downloadDir, rel)); err != nil {
+			t.Fatalf("expected %s to be moved: %v", rel, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(stagingDir, "linux", "other-game", "other.sh")); err != nil {
+		t.Fatalf("other games must stay in staging: %v", err)
+	}
+}
+
+func TestSamePath(t *testing.T) {
+	dir := t.TempDir()
+	if !samePath(dir, filepath.Join(dir, ".")) {
+		t.Fatal("expected paths to be equal")
+	}
+	if samePath(dir, filepath.Join(dir, "sub")) {
+		t.Fatal("expected paths to differ")
+	}
+}
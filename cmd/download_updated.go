@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/progress"
+	"github.com/rs/zerolog/log"
+)
+
+// gameUpdate is a game downloaded before with the files that are new or changed in the catalogue since.
+type gameUpdate struct {
+	game  db.Game
+	files []client.GameFile
+}
+
+// updatePlan sorts the games of the catalogue by whether their earlier download in a directory is out of date.
+type updatePlan struct {
+	Updated       []gameUpdate
+	UpToDate      int
+	NotDownloaded int // games without an earlier download in the directory
+	Pinned        int
+	Unreadable    []db.Game // games whose catalogue data or earlier download could not be read
+}
+
+// planUpdates compares every game in games with its earlier download in downloadPath, going by the metadata.json
+// and download_info.json in its game folder like --since-version, and returns which of them have updates. The
+// files of a game are those that a download with opts gets.
+func planUpdates(games []db.Game, downloadPath string, opts downloadOptions, pinned map[int]bool) updatePlan {
+	var plan updatePlan
+	language, _ := client.LanguageFilter(opts.language)
+	current := downloadInfo(opts, language)
+	for _, game := range games {
+		parsed, err := client.ParseCachedGameData(game.ID, game.Data)
+		if err != nil {
+			log.Warn().Err(err).Int("gameID", game.ID).Msg("Skipping game with unreadable catalogue data")
+			plan.Unreadable = append(plan.Unreadable, game)
+			continue
+		}
+		old, info, e := earlierDownload(filepath.Join(downloadPath, gameFolder(opts, parsed.Title, game.ID)), current)
+		if e != nil {
+			if e.Type == clierr.NotFound {
+				plan.NotDownloaded++
+			} else {
+				log.Warn().Err(e.Err).Int("gameID", game.ID).Msg(e.Message)
+				plan.Unreadable = append(plan.Unreadable, game)
+			}
+			continue
+		}
+		files := client.SelectedFiles(client.ChangedFiles(parsed, old, info), current)
+		switch {
+		case len(files) == 0:
+			plan.UpToDate++
+		case pinned[game.ID]:
+			plan.Pinned++
+		default:
+			plan.Updated = append(plan.Updated, gameUpdate{game: game, files: files})
+		}
+	}
+	return plan
+}
+
+// printUpdatePlan lists the games with updates and their new or changed files.
+func printUpdatePlan(plan updatePlan) {
+	for _, u := range plan.Updated {
+		fmt.Printf("%s (ID %d): %d new or changed file(s)\n", u.game.Title, u.game.ID, len(u.files))
+		for _, f := range u.files {
+			where := f.Platform
+			if where == "" {
+				where = "extra"
+			} else if f.Language != "" {
+				where += ", " + f.Language
+			}
+			component := ""
+			if f.Component != u.game.Title {
+				component = f.Component + ": "
+			}
+			fmt.Printf("  - %s%s (%s, %s)\n", component, f.Name, where, f.Size)
+		}
+	}
+	fmt.Printf("%d game(s) with updates, %d up to date, %d not downloaded to this directory before.\n",
+		len(plan.Updated), plan.UpToDate, plan.NotDownloaded)
+	if len(plan.Unreadable) > 0 {
+		fmt.Printf("%d game(s) were skipped because their catalogue data or earlier download could not be read.\n", len(plan.Unreadable))
+	}
+}
+
+// executeUpdatedDownload downloads the games of the catalogue that were downloaded to downloadPath before and
+// whose files changed in the catalogue since, in the given order. With --since-version in opts, only the
+// changed files are downloaded. Unlike executeBatchDownload, it keeps no batch log, as the game folders tell
+// what is up to date. With dryRun, it only lists the games and their changed files.
+func executeUpdatedDownload(ctx context.Context, authService *auth.Service, downloadPath string, opts downloadOptions, order string, dryRun bool) *clierr.Error {
+	if e := prepareBatchOptions(&opts); e != nil {
+		return e
+	}
+	games, err := db.NewGameRepository(db.GetDB()).List(ctx)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Unable to list games", err)
+	}
+	if len(games) == 0 {
+		fmt.Println("Game catalogue is empty. Did you refresh the catalogue?")
+		return nil
+	}
+	if err := orderBatchGames(games, order, opts); err != nil {
+		return clierr.New(clierr.Validation, "Invalid download order: "+err.Error(), err)
+	}
+	pinned, e := pinnedGames(ctx)
+	if e != nil {
+		return e
+	}
+	plan := planUpdates(games, downloadPath, opts, pinned)
+
+	if dryRun {
+		printUpdatePlan(plan)
+		printHeldPins(plan.Pinned)
+		return nil
+	}
+
+	pending := make([]db.Game, len(plan.Updated))
+	for i, u := range plan.Updated {
+		pending[i] = u.game
+	}
+	var updated, failed int
+	var volumeFull, budgetReached bool
+	var mu sync.Mutex
+	forEachGame(ctx, pending, opts.gamesConcurrency, func(gameCtx context.Context, game db.Game) bool {
+		dlErr := executeDownload(gameCtx, authService, game.ID, downloadPath, opts)
+		if opts.report != nil {
+			opts.report.finish(game.ID, game.Title, dlErr)
+		}
+		if dlErr != nil && gameCtx.Err() != nil {
+			return true
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if dlErr != nil {
+			failed++
+		} else {
+			updated++
+		}
+		if errors.Is(dlErr, client.ErrVolumeFull) {
+			volumeFull = true
+			return true
+		}
+		if errors.Is(dlErr, client.ErrByteBudgetReached) {
+			budgetReached = true
+			return true
+		}
+		return false
+	})
+	if opts.progress != nil {
+		opts.progress.finish()
+	}
+
+	fmt.Printf("Update finished: %d updated, %d failed, %d up to date, %d not downloaded to this directory before.\n",
+		updated, failed, plan.UpToDate, plan.NotDownloaded)
+	if ctx.Err() != nil {
+		fmt.Println("The update was interrupted; run the same command again to continue.")
+	}
+	if volumeFull {
+		fmt.Println("The update was stopped because the target volume is full; free some space and run the same command again to continue.")
+	}
+	if budgetReached {
+		fmt.Printf("The update was stopped at the download size limit after %s; run the same command again to continue.\n",
+			progress.FormatBytes(opts.byteBudget.Used()))
+	}
+	if len(plan.Unreadable) > 0 {
+		fmt.Printf("%d game(s) were skipped because their catalogue data or earlier download could not be read:\n", len(plan.Unreadable))
+		for _, game := range plan.Unreadable {
+			fmt.Printf("  - %s (ID %d)\n", game.Title, game.ID)
+		}
+	}
+	printHeldPins(plan.Pinned)
+	if failed > 0 {
+		return clierr.New(clierr.Download, fmt.Sprintf("%d game(s) failed to update", failed), nil)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// linkedGameData returns the catalogue data of a game with Windows and Linux installers of the given version that
+// can be downloaded.
+func linkedGameData(title, version string) string {
+	return `{"title":"` + title + `","downloads":[["English",{"windows":[{"manualUrl":"/w","name":"` + title +
+		`","size":"1 GB","version":"` + version + `"}],"linux":[{"manualUrl":"/l","name":"` + title +
+		`","size":"1 GB","version":"` + version + `"}]}]],"extras":[]}`
+}
+
+// downloadedBefore writes the metadata.json and download_info.json of a download of the game with data as its
+// catalogue data into dir, as if it had been downloaded with opts.
+func downloadedBefore(t *testing.T, dir string, id int, data string, opts downloadOptions) {
+	t.Helper()
+	game, err := client.ParseGameData(data)
+	require.NoError(t, err)
+	folder := filepath.Join(dir, gameFolder(opts, game.Title, id))
+	require.NoError(t, client.WriteGameMetadata(folder, game, client.DefaultFileModes))
+	require.NoError(t, client.WriteDownloadInfo(folder, downloadInfo(opts, "English"), client.DefaultFileModes))
+}
+
+func TestExecuteUpdatedDownload(t *testing.T) {
+	cleanDBTables(t)
+	usePins(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 401, "Updated Game", linkedGameData("Updated Game", "1.1"))
+	addTestGame(t, repo, 402, "Current Game", linkedGameData("Current Game", "1.0"))
+	addTestGame(t, repo, 403, "New Game", linkedGameData("New Game", "1.0"))
+	addTestGame(t, repo, 404, "Pinned Game", linkedGameData("Pinned Game", "2.0"))
+	require.NoError(t, db.NewPinRepository(db.GetDB()).Pin(context.Background(), 404, "1.0"))
+
+	dir := t.TempDir()
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 1, gamesConcurrency: 1}
+	downloadedBefore(t, dir, 401, linkedGameData("Updated Game", "1.0"), opts)
+	downloadedBefore(t, dir, 402, linkedGameData("Current Game", "1.0"), opts)
+	downloadedBefore(t, dir, 404, linkedGameData("Pinned Game", "1.0"), opts)
+
+	var e *clierr.Error
+	out := captureStdout2(func() {
+		e = executeUpdatedDownload(context.Background(), noLogin, dir, opts, batchOrderCatalogue, true)
+	})
+	require.Nil(t, e)
+	assert.True(t, containsAll(out, []string{
+		"Updated Game (ID 401): 1 new or changed file(s)",
+		"  - Updated Game (windows, English, 1 GB)",
+		"1 game(s) with updates, 1 up to date, 1 not downloaded to this directory before.",
+		"1 pinned game(s) were not updated",
+	}), out)
+	assert.NotContains(t, out, "linux", "the files of other platforms are not listed")
+	assert.NotContains(t, out, "Did you login?", "a dry run downloads nothing")
+
+	// Without a login, the download of the updated game fails before any network access.
+	out = captureStdout2(func() {
+		e = executeUpdatedDownload(context.Background(), noLogin, dir, opts, batchOrderCatalogue, false)
+	})
+	require.NotNil(t, e)
+	assert.Equal(t, clierr.Download, e.Type)
+	assert.Contains(t, out, "Update finished: 0 updated, 1 failed, 1 up to date, 1 not downloaded to this directory before.")
+	assert.Contains(t, out, "Did you login?")
+}
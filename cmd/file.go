@@ -4,14 +4,18 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
+	"time"
 
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
 	"github.com/habedi/gogg/pkg/clierr"
 	"github.com/habedi/gogg/pkg/hasher"
 	"github.com/habedi/gogg/pkg/operations"
+	"github.com/habedi/gogg/pkg/progress"
 	"github.com/habedi/gogg/pkg/validation"
 	"github.com/rs/zerolog/log"
+	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 )
 
@@ -25,7 +29,7 @@ func fileCmd() *cobra.Command {
 }
 
 func hashCmd() *cobra.Command {
-	var saveToFileFlag, cleanFlag, recursiveFlag bool
+	var saveToFileFlag, cleanFlag, recursiveFlag, cacheFlag bool
 	var algo string
 	var numThreads int
 
@@ -36,15 +40,11 @@ func hashCmd() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			dir := args[0]
 			if !hasher.IsValidHashAlgo(algo) {
-				e := clierr.New(clierr.Validation, "Unsupported hash algorithm", nil)
-				cmd.PrintErrln(e.Message)
-				setLastCliErr(e)
+				reportCliErr(cmd, clierr.New(clierr.Validation, "Unsupported hash algorithm", nil))
 				return
 			}
-			if err := validation.ValidateThreadCount(numThreads); err != nil {
-				e := clierr.New(clierr.Validation, "Invalid thread count", err)
-				cmd.PrintErrln(e.Message)
-				setLastCliErr(e)
+			if e := validateThreadsFlag(numThreads); e != nil {
+				reportCliErr(cmd, e)
 				return
 			}
 
@@ -60,6 +60,7 @@ func hashCmd() *cobra.Command {
 			files, err := operations.FindFilesToHash(dir, recursiveFlag, operations.DefaultHashExclusions)
 			if err != nil {
 				log.Error().Err(err).Msg("Error finding files to hash")
+				reportCliErr(cmd, clierr.New(clierr.NotFound, "Error finding files to hash", err))
 				return
 			}
 
@@ -68,8 +69,31 @@ func hashCmd() *cobra.Command {
 				return
 			}
 
+			bar := newProgressBar(
+				operations.TotalSize(files),
+				os.Stderr,
+				progressbar.OptionSetDescription("Hashing..."),
+				progressbar.OptionShowBytes(true),
+				progressbar.OptionThrottle(200*time.Millisecond),
+				progressbar.OptionClearOnFinish(),
+			)
+			var tracker operations.HashProgressTracker
+			hashOpts := operations.HashOptions{
+				Progress: func(file string, hashed, _ int64) {
+					_ = bar.Set64(tracker.Update(file, hashed))
+				},
+			}
+			if cacheFlag {
+				cache, err := operations.LoadHashCache(operations.DefaultHashCachePath())
+				if err != nil {
+					log.Warn().Err(err).Msg("Failed to load hash cache; hashing all files")
+				} else {
+					hashOpts.Cache = cache
+				}
+			}
+
 			cmd.Printf("Found %d files. Generating %s hashes...\n", len(files), algo)
-			resultsChan := operations.GenerateHashes(context.Background(), files, algo, numThreads)
+			resultsChan := operations.GenerateHashesWithOptions(context.Background(), files, algo, numThreads, hashOpts)
 
 			var savedFiles []string
 			for res := range resultsChan {
@@ -90,6 +114,13 @@ func hashCmd() *cobra.Command {
 				}
 			}
 
+			_ = bar.Finish()
+			if hashOpts.Cache != nil {
+				if err := hashOpts.Cache.Save(); err != nil {
+					log.Warn().Err(err).Msg("Failed to save hash cache")
+				}
+			}
+
 			if saveToFileFlag {
 				fmt.Println("Generated hash files:")
 				for _, file := range savedFiles {
@@ -102,71 +133,139 @@ func hashCmd() *cobra.Command {
 	cmd.Flags().BoolVarP(&recursiveFlag, "recursive", "r", true, "Process files in subdirectories? [true, false]")
 	cmd.Flags().BoolVarP(&saveToFileFlag, "save", "s", false, "Save hash to files? [true, false]")
 	cmd.Flags().BoolVarP(&cleanFlag, "clean", "c", false, "Remove old hash files before generating new ones? [true, false]")
-	cmd.Flags().IntVarP(&numThreads, "threads", "t", 4, "Number of worker threads to use for hashing [1-16]")
+	addThreadsFlag(cmd, &numThreads, validation.LocalWorkload, "hashing")
+	cmd.Flags().BoolVar(&cacheFlag, "cache", false, "Reuse cached hashes of files whose size and modification time are unchanged? [true, false]")
 
 	return cmd
 }
 
 func sizeCmd() *cobra.Command {
-	var language, platformName, sizeUnit string
-	var extrasFlag, dlcFlag bool
+	var language, platformName, sizeUnit, bandwidth string
+	var extrasFlag, dlcFlag, allFlag bool
+	var numThreads int
 
 	cmd := &cobra.Command{
 		Use:   "size [gameID]",
 		Short: "Show the total storage size needed to download game files",
-		Args:  cobra.ExactArgs(1),
+		Long: "Show the total storage size needed to download the files of a game, or with --all, of every game in the\n" +
+			"catalogue, estimated from the file sizes listed in the catalogue. With --at, also show how long the\n" +
+			"download would take at that bandwidth.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if allFlag {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			gameID, err := strconv.Atoi(args[0])
-			if err != nil {
-				cmd.PrintErrln("Error: Invalid game ID. It must be a positive integer.")
+			sizeUnit = strings.ToLower(sizeUnit)
+			if _, ok := sizeUnits[sizeUnit]; !ok {
+				reportCliErr(cmd, clierr.New(clierr.Validation, fmt.Sprintf("Invalid size unit: %q. Unit must be one of [gb, mb, kb, b]", sizeUnit), nil))
 				return
 			}
-
-			if err := validation.ValidateGameID(gameID); err != nil {
-				e := clierr.New(clierr.Validation, "Invalid game ID", err)
-				cmd.PrintErrln(e.Message)
-				setLastCliErr(e)
-				return
+			var speed float64
+			if bandwidth != "" {
+				var err error
+				if speed, err = progress.ParseBandwidth(bandwidth); err != nil {
+					reportCliErr(cmd, clierr.New(clierr.Validation, "Invalid bandwidth: "+err.Error(), err))
+					return
+				}
 			}
-
 			params := operations.EstimationParams{
 				LanguageCode:  strings.ToLower(language),
 				PlatformName:  platformName,
 				IncludeExtras: extrasFlag,
 				IncludeDLCs:   dlcFlag,
 			}
+			if allFlag {
+				if e := validateThreadsFlag(numThreads); e != nil {
+					reportCliErr(cmd, e)
+					return
+				}
+				if e := printCatalogueSize(cmd, params, sizeUnit, bandwidth, speed, numThreads); e != nil {
+					reportCliErr(cmd, e)
+				}
+				return
+			}
 
+			gameID, e := parseGameIDArg(args[0])
+			if e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
 			totalSizeBytes, gameData, err := operations.EstimateGameSize(gameID, params)
 			if err != nil {
-				e := clierr.New(clierr.Internal, "Error estimating storage size", err)
-				cmd.PrintErrln(e.Message)
-				setLastCliErr(e)
+				reportCliErr(cmd, clierr.New(clierr.Internal, "Error estimating storage size", err))
 				return
 			}
 
 			log.Info().Msgf("Game title: \"%s\"\n", gameData.Title)
 			log.Info().Msgf("Download parameters: Language=%s; Platform=%s; Extras=%t; DLCs=%t\n", params.LanguageCode, params.PlatformName, params.IncludeExtras, params.IncludeDLCs)
-
-			sizeUnit = strings.ToLower(sizeUnit)
-			switch sizeUnit {
-			case "gb":
-				fmt.Printf("Total download size: %.2f GB\n", float64(totalSizeBytes)/(1024*1024*1024))
-			case "mb":
-				fmt.Printf("Total download size: %.2f MB\n", float64(totalSizeBytes)/(1024*1024))
-			case "kb":
-				fmt.Printf("Total download size: %.2f KB\n", float64(totalSizeBytes)/1024)
-			case "b":
-				fmt.Printf("Total download size: %d B\n", totalSizeBytes)
-			default:
-				cmd.PrintErrf("invalid size unit: %q. Unit must be one of [gb, mb, kb, b]\n", sizeUnit)
-				return
+			fmt.Printf("Total download size: %s\n", formatSizeUnit(totalSizeBytes, sizeUnit))
+			if speed > 0 {
+				fmt.Println(formatDownloadTime(totalSizeBytes, bandwidth, speed))
 			}
 		},
 	}
-	cmd.Flags().StringVarP(&language, "lang", "l", "en", "Game language [en, fr, de, es, it, ru, pl, pt-BR, zh-Hans, ja, ko]")
-	cmd.Flags().StringVarP(&platformName, "platform", "p", "windows", "Platform name [all, windows, mac, linux]; all means all platforms")
+	cmd.Flags().StringVarP(&language, "lang", "l", "en", "Game language [en, fr, de, es, it, ru, pl, pt-BR, zh-Hans, ja, ko, ...]; all means all languages")
+	cmd.Flags().StringVarP(&platformName, "platform", "p", "windows", "Platform name [all, auto, windows, mac, linux]; all means all platforms, auto the platform of this machine")
 	cmd.Flags().BoolVarP(&extrasFlag, "extras", "e", true, "Include extra content files? [true, false]")
 	cmd.Flags().BoolVarP(&dlcFlag, "dlcs", "d", true, "Include DLC files? [true, false]")
 	cmd.Flags().StringVarP(&sizeUnit, "unit", "u", "gb", "Size unit to display [gb, mb, kb, b]")
+	cmd.Flags().StringVar(&bandwidth, "at", "", "Also show how long the download takes at this bandwidth, like 50Mbps (bits per second) or 6MB/s (bytes per second)")
+	cmd.Flags().BoolVar(&allFlag, "all", false, "Show the size of every game in the catalogue and their total, instead of one game")
+	addThreadsFlag(cmd, &numThreads, validation.LocalWorkload, "estimating the sizes with --all")
 	return cmd
 }
+
+// sizeUnits maps the units of the size command to their number of bytes.
+var sizeUnits = map[string]int64{"gb": 1 << 30, "mb": 1 << 20, "kb": 1 << 10, "b": 1}
+
+// formatSizeUnit formats bytes in one of the sizeUnits.
+func formatSizeUnit(bytes int64, unit string) string {
+	if unit == "b" {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	return fmt.Sprintf("%.2f %s", float64(bytes)/float64(sizeUnits[unit]), strings.ToUpper(unit))
+}
+
+// formatDownloadTime formats how long downloading bytes takes at speed, the bytes per second of bandwidth.
+func formatDownloadTime(bytes int64, bandwidth string, speed float64) string {
+	eta, _ := progress.ETA(bytes, speed)
+	return fmt.Sprintf("Estimated download time at %s: %s", bandwidth, eta)
+}
+
+// printCatalogueSize prints the estimated download size of every game in the catalogue and their total, and
+// with a speed, the time it takes to download them all at bandwidth.
+func printCatalogueSize(cmd *cobra.Command, params operations.EstimationParams, unit, bandwidth string, speed float64, numThreads int) *clierr.Error {
+	if _, ok := client.LanguageFilter(params.LanguageCode); !ok {
+		return clierr.New(clierr.Validation, "Invalid language code", nil)
+	}
+	games, err := db.GetCatalogue()
+	if err != nil {
+		return clierr.New(clierr.Internal, "Failed to read the catalogue", err)
+	}
+	ids := make([]int, len(games))
+	for i, g := range games {
+		ids[i] = g.ID
+	}
+
+	var total int64
+	var failed int
+	for _, r := range operations.EstimateGameSizes(cmd.Context(), ids, params, numThreads) {
+		if r.Err != nil {
+			failed++
+			log.Warn().Err(r.Err).Int("gameID", r.ID).Msg("Failed to estimate the download size")
+			continue
+		}
+		total += r.Bytes
+		fmt.Printf("%d\t%s\t%s\n", r.ID, r.Title, formatSizeUnit(r.Bytes, unit))
+	}
+	fmt.Printf("Total download size of %d game(s): %s\n", len(games)-failed, formatSizeUnit(total, unit))
+	if speed > 0 {
+		fmt.Println(formatDownloadTime(total, bandwidth, speed))
+	}
+	if failed > 0 {
+		fmt.Printf("The size of %d game(s) could not be estimated; see the log for details.\n", failed)
+	}
+	return nil
+}
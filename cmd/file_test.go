@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -82,7 +83,7 @@ func setupMemDB(t *testing.T) {
 		t.Fatalf("open db: %v", err)
 	}
 	db.Db = gormDB
-	if err := db.Db.AutoMigrate(&db.Game{}, &db.Token{}); err != nil {
+	if err := db.Db.AutoMigrate(&db.Game{}, &db.Token{}, &db.GamePin{}); err != nil {
 		t.Fatalf("migrate: %v", err)
 	}
 }
@@ -119,3 +120,76 @@ func TestSizeCmd_HappyPathAndUnits(t *testing.T) {
 		}
 	}
 }
+
+func TestSizeCmd_At(t *testing.T) {
+	setupMemDB(t)
+	raw := `{"title":"CLI Size Game","downloads":[["English", {"windows":[{"name":"setup.exe","size":"1 GB"}]}]],"extras":[],"dlcs":[]}`
+	if err := db.PutInGame(994, "CLI Size Game", raw); err != nil {
+		t.Skipf("skipping: %v", err)
+	}
+
+	// 1 GiB at 50 Mbit/s takes 171.8 seconds.
+	cmd := sizeCmd()
+	cmd.SetArgs([]string{"994", "--at", "50Mbps"})
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+	out := captureStdout(func() { cmd.Execute() })
+	if !strings.Contains(out, "Estimated download time at 50Mbps: 2m52s") {
+		t.Fatalf("expected the download time, got: %s", out)
+	}
+
+	t.Cleanup(func() { setLastCliErr(nil) })
+	cmd = sizeCmd()
+	cmd.SetArgs([]string{"994", "--at", "fast"})
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+	captureStdout(func() { cmd.Execute() })
+	if e := getLastCliErr(); e == nil || e.Type != clierr.Validation {
+		t.Fatalf("expected a validation error for an invalid bandwidth, got %v", e)
+	}
+}
+
+func TestSizeCmd_All(t *testing.T) {
+	setupMemDB(t)
+	if err := db.EmptyCatalogue(); err != nil {
+		t.Fatalf("empty catalogue: %v", err)
+	}
+	for id, size := range map[int]string{992: "1 GB", 993: "512 MB"} {
+		raw := `{"title":"Size Game ` + size + `","downloads":[["English", {"windows":[{"name":"setup.exe","size":"` + size + `"}]}]]}`
+		if err := db.PutInGame(id, "Size Game "+size, raw); err != nil {
+			t.Skipf("skipping: %v", err)
+		}
+	}
+
+	cmd := sizeCmd()
+	cmd.SetArgs([]string{"--all", "--unit", "mb", "--threads", "2"})
+	buf := new(bytes.Buffer)
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+	out := captureStdout(func() { cmd.Execute() })
+	if !strings.Contains(out, "992\tSize Game 1 GB\t1024.00 MB") || !strings.Contains(out, "993\tSize Game 512 MB\t512.00 MB") {
+		t.Fatalf("expected a line per game, got: %s", out)
+	}
+	if !strings.Contains(out, "Total download size of 2 game(s): 1536.00 MB") {
+		t.Fatalf("expected the total, got: %s", out)
+	}
+
+	// 1.5 GiB at 8 MiB/s takes 192 seconds.
+	cmd = sizeCmd()
+	cmd.SetArgs([]string{"--all", "--at", "8MB/s"})
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+	out = captureStdout(func() { cmd.Execute() })
+	if !strings.Contains(out, "Estimated download time at 8MB/s: 3m12s") {
+		t.Fatalf("expected the download time of the total, got: %s", out)
+	}
+
+	cmd = sizeCmd()
+	cmd.SetArgs([]string{"--all", "991"})
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+	if err := cmd.Execute(); err == nil {
+		t.Fatal("expected --all with a game ID to be rejected")
+	}
+}
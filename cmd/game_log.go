@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/habedi/gogg/pkg/progress"
+)
+
+// gameLogName is the name of the log that --per-game-log writes into the folder of each game.
+const gameLogName = "gogg-download.log"
+
+// gameLog is the log of the downloads of one game, kept in its folder as JSON lines like those of
+// --log-format=json. Each run is appended, so the log tells the history of the folder. It is written whatever
+// DEBUG_GOGG says, which only controls the log on stderr.
+type gameLog struct {
+	mu     sync.Mutex // the files of a game are downloaded by several workers
+	file   *os.File
+	gameID int
+}
+
+// openGameLog opens the log in dir, the folder of the game, creating the folder if it does not exist yet.
+func openGameLog(dir string, gameID int, modes client.FileModes) (*gameLog, error) {
+	if err := os.MkdirAll(dir, modes.Dir); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, gameLogName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, modes.File)
+	if err != nil {
+		return nil, err
+	}
+	return &gameLog{file: f, gameID: gameID}, nil
+}
+
+func (l *gameLog) Close() error { return l.file.Close() }
+
+// record appends an event with the given level, message, and fields to the log. It does nothing on a nil
+// gameLog, so downloads without --per-game-log can call it too.
+func (l *gameLog) record(level, message string, fields map[string]any) {
+	if l == nil {
+		return
+	}
+	event := map[string]any{"time": time.Now().Format(time.RFC3339), "level": level, "message": message, "gameID": l.gameID}
+	for k, v := range fields {
+		event[k] = v
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.file.Write(append(line, '\n'))
+}
+
+// finish records how the download ended.
+func (l *gameLog) finish(err error) {
+	if err != nil {
+		l.record("error", "Download failed", map[string]any{"error": err.Error()})
+		return
+	}
+	l.record("info", "Download finished", nil)
+}
+
+// verified records the outcome of checking the files of the download against their checksums.
+func (l *gameLog) verified(report verifyReport) {
+	for _, r := range report.Results {
+		if r.Status != operations.VerifyPassed {
+			l.record("error", "File did not pass verification", map[string]any{
+				"file": r.Path, "status": r.Status, "source": r.Source, "error": r.Error,
+			})
+		}
+	}
+	l.record("info", "Verification done", map[string]any{
+		"passed": report.Passed, "failed": report.Failed, "missing": report.Missing, "unreadable": report.Unreadable,
+	})
+}
+
+// gameLogWriter passes the progress updates of one game's download on, recording the start of the download and
+// the outcome of each file in the game's log.
+type gameLogWriter struct {
+	log  *gameLog
+	next io.Writer
+}
+
+func (w *gameLogWriter) Write(p []byte) (int, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(p)))
+	for scanner.Scan() {
+		var update client.ProgressUpdate
+		if err := json.Unmarshal(scanner.Bytes(), &update); err != nil {
+			continue
+		}
+		switch update.Type {
+		case "start":
+			w.log.record("info", "Downloading the files", map[string]any{"total": progress.FormatBytes(update.OverallTotalBytes)})
+		case "file_done":
+			level, fields := "info", map[string]any{"file": update.FileName, "outcome": update.Outcome, "received": update.Received}
+			if update.Outcome == client.FileFailed {
+				level, fields["error"] = "error", update.Error
+			}
+			if update.Retries > 0 {
+				fields["retries"] = update.Retries
+			}
+			if update.Verification != "" {
+				fields["verification"] = update.Verification
+			}
+			if update.Checksum != "" {
+				fields["checksum"] = update.Checksum
+			}
+			w.log.record(level, "File done", fields)
+		}
+	}
+	return w.next.Write(p)
+}
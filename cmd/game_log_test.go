@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameLog_RecordsDownloadEvents(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "test-game")
+	readEvents := func() []map[string]any {
+		data, err := os.ReadFile(filepath.Join(dir, gameLogName))
+		require.NoError(t, err)
+		var events []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var event map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &event), line)
+			events = append(events, event)
+		}
+		return events
+	}
+
+	gameLog, err := openGameLog(dir, 42, client.DefaultFileModes)
+	require.NoError(t, err)
+	w := &gameLogWriter{log: gameLog, next: io.Discard}
+	updates := []client.ProgressUpdate{
+		{Type: "start", OverallTotalBytes: 2048},
+		{Type: "file_progress", FileName: "setup.exe", CurrentBytes: 1024},
+		{Type: "file_done", FileName: "setup.exe", Outcome: client.FileDownloaded, Received: 1024, Retries: 2, Verification: client.VerificationPassed},
+		{Type: "file_done", FileName: "patch.exe", Outcome: client.FileFailed, Error: "HTTP 500"},
+	}
+	for _, u := range updates {
+		line, _ := json.Marshal(u)
+		_, err := w.Write(append(line, '\n'))
+		require.NoError(t, err)
+	}
+	gameLog.verified(verifyReport{Passed: 1, Failed: 1, Results: []operations.VerifyResult{
+		{Path: "setup.exe", Status: operations.VerifyPassed},
+		{Path: "patch.exe", Status: operations.VerifyMismatch, Source: operations.ChecksumSourceGOG},
+	}})
+	gameLog.finish(errors.New("1 file failed"))
+	require.NoError(t, gameLog.Close())
+
+	events := readEvents()
+	var messages []string
+	for _, e := range events {
+		assert.Equal(t, float64(42), e["gameID"])
+		messages = append(messages, e["message"].(string))
+	}
+	assert.Equal(t, []string{"Downloading the files", "File done", "File done", "File did not pass verification",
+		"Verification done", "Download failed"}, messages, "progress updates are not logged")
+	assert.Equal(t, float64(2), events[1]["retries"])
+	assert.Equal(t, client.VerificationPassed, events[1]["verification"])
+	assert.Equal(t, "error", events[2]["level"])
+	assert.Equal(t, "HTTP 500", events[2]["error"])
+	assert.Equal(t, "patch.exe", events[3]["file"])
+
+	// A later run is appended.
+	gameLog, err = openGameLog(dir, 42, client.DefaultFileModes)
+	require.NoError(t, err)
+	gameLog.finish(nil)
+	require.NoError(t, gameLog.Close())
+	events = readEvents()
+	assert.Len(t, events, 7)
+	assert.Equal(t, "Download finished", events[6]["message"])
+}
+
+func TestGameLog_NilIsNoOp(t *testing.T) {
+	var gameLog *gameLog
+	assert.NotPanics(t, func() { gameLog.verified(verifyReport{}) })
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/pool"
+	"github.com/habedi/gogg/pkg/progress"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+)
+
+// addGamesConcurrencyFlag adds the --games-concurrency flag of the commands that download many games.
+func addGamesConcurrencyFlag(cmd *cobra.Command, target *int) {
+	cmd.Flags().IntVar(target, "games-concurrency", 1,
+		"Number of games to download at the same time; --threads is then the number of files downloaded at the same time by all of them together")
+}
+
+// prepareGamesConcurrency checks --games-concurrency and --progress-format and, when more than one game is
+// downloaded at the same time, makes the games share --threads download slots and one progress bar.
+func prepareGamesConcurrency(opts *downloadOptions) *clierr.Error {
+	if opts.gamesConcurrency < 1 {
+		return clierr.New(clierr.Validation, "--games-concurrency must be 1 or more", nil)
+	}
+	if e := validateProgressFormat(opts.progressFmt); e != nil {
+		return e
+	}
+	if opts.gamesConcurrency > 1 {
+		opts.slots = client.NewDownloadSlots(opts.numThreads)
+		if opts.progressFmt != progressFormatJSONL {
+			opts.progress = newBatchProgress()
+		}
+	}
+	return nil
+}
+
+// forEachGame calls download for every game, with concurrency games at the same time, in the order of games.
+// When download returns true, no more games are started and the games still downloading are cancelled.
+func forEachGame(ctx context.Context, games []db.Game, concurrency int, download func(ctx context.Context, game db.Game) (stop bool)) {
+	workCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	pool.Run(workCtx, games, max(concurrency, 1), func(ctx context.Context, game db.Game) error {
+		if download(ctx, game) {
+			stop()
+		}
+		return nil
+	})
+}
+
+// batchProgress shows one progress bar for all the games of a batch or mirror that are downloaded at the same
+// time. Its total grows as each game starts.
+type batchProgress struct {
+	mu         sync.Mutex
+	bar        *progressbar.ProgressBar
+	total      int64
+	downloaded int64
+	active     int
+	speed      progress.SpeedEstimator
+	speedText  string
+}
+
+func newBatchProgress() *batchProgress {
+	bp := &batchProgress{speedText: "Speed: N/A | ETA: N/A"}
+	bp.speed.Reset(time.Now())
+	return bp
+}
+
+// game returns the writer for the progress updates of one game's download. The game counts as downloading
+// until done is called.
+func (bp *batchProgress) game() (w io.Writer, done func()) {
+	bp.mu.Lock()
+	bp.active++
+	bp.mu.Unlock()
+	gw := &batchGameWriter{progress: bp, fileBytes: make(map[string]int64)}
+	return gw, func() {
+		bp.mu.Lock()
+		defer bp.mu.Unlock()
+		bp.active--
+		bp.describe()
+	}
+}
+
+// add records that a game expects total more bytes and that downloaded more bytes were received.
+func (bp *batchProgress) add(total, downloaded int64) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if bp.bar == nil {
+		bp.bar = newProgressBar(0, os.Stderr,
+			progressbar.OptionSetDescription("Downloading..."),
+			progressbar.OptionShowBytes(true),
+			progressbar.OptionThrottle(200*time.Millisecond),
+			progressbar.OptionClearOnFinish(),
+			progressbar.OptionSetPredictTime(false),
+		)
+	}
+	if total != 0 {
+		bp.total += total
+		bp.bar.ChangeMax64(bp.total)
+	}
+	if downloaded != 0 {
+		bp.downloaded += downloaded
+		_ = bp.bar.Set64(bp.downloaded)
+		if speed, ok := bp.speed.Update(time.Now(), bp.downloaded); ok {
+			bp.speedText = progress.FormatSpeedAndETA(speed, bp.total-bp.downloaded)
+		}
+	}
+	bp.describe()
+}
+
+func (bp *batchProgress) describe() {
+	if bp.bar != nil {
+		bp.bar.Describe(fmt.Sprintf("%s | Downloading %d game(s)", bp.speedText, bp.active))
+	}
+}
+
+// finish removes the progress bar.
+func (bp *batchProgress) finish() {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if bp.bar != nil {
+		_ = bp.bar.Finish()
+	}
+}
+
+// batchGameWriter passes the progress updates of one game to its batchProgress.
+type batchGameWriter struct {
+	progress  *batchProgress
+	mu        sync.Mutex
+	fileBytes map[string]int64
+}
+
+func (w *batchGameWriter) Write(p []byte) (int, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(p)))
+	for scanner.Scan() {
+		var update client.ProgressUpdate
+		if err := json.Unmarshal(scanner.Bytes(), &update); err != nil {
+			continue
+		}
+		switch update.Type {
+		case "start":
+			w.progress.add(update.OverallTotalBytes, 0)
+		case "file_progress":
+			w.mu.Lock()
+			diff := update.CurrentBytes - w.fileBytes[update.FileName]
+			w.fileBytes[update.FileName] = update.CurrentBytes
+			w.mu.Unlock()
+			w.progress.add(0, diff)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/habedi/gogg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachGame_Concurrency(t *testing.T) {
+	games := make([]db.Game, 6)
+	for i := range games {
+		games[i] = db.Game{ID: i + 1}
+	}
+	var running, peak atomic.Int32
+	var mu sync.Mutex
+	var seen []int
+	forEachGame(context.Background(), games, 3, func(ctx context.Context, game db.Game) bool {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		seen = append(seen, game.ID)
+		mu.Unlock()
+		return false
+	})
+	assert.Len(t, seen, 6)
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	assert.Greater(t, peak.Load(), int32(1), "games are downloaded at the same time")
+}
+
+func TestForEachGame_Stop(t *testing.T) {
+	games := make([]db.Game, 5)
+	for i := range games {
+		games[i] = db.Game{ID: i + 1}
+	}
+	var seen []int
+	forEachGame(context.Background(), games, 1, func(ctx context.Context, game db.Game) bool {
+		seen = append(seen, game.ID)
+		return game.ID == 2
+	})
+	assert.Equal(t, []int{1, 2}, seen, "no game is started after a stop")
+}
+
+func TestBatchProgress_AddsUpGames(t *testing.T) {
+	bp := newBatchProgress()
+	w1, done1 := bp.game()
+	w2, done2 := bp.game()
+	assert.Equal(t, 2, bp.active)
+
+	fmt.Fprintln(w1, `{"type":"start","overall_total":100}`)
+	fmt.Fprintln(w2, `{"type":"start","overall_total":50}`)
+	fmt.Fprintln(w1, `{"type":"file_progress","file":"a.exe","current":40,"total":100}`)
+	fmt.Fprintln(w1, `{"type":"file_progress","file":"a.exe","current":60,"total":100}`)
+	fmt.Fprintln(w2, `{"type":"file_progress","file":"a.exe","current":10,"total":50}`)
+	done1()
+	done2()
+	bp.finish()
+
+	assert.Equal(t, int64(150), bp.total)
+	assert.Equal(t, int64(70), bp.downloaded, "files of the same name in different games are counted apart")
+	assert.Zero(t, bp.active)
+}
+
+func TestExecuteBatchDownload_GamesConcurrency(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	for id := 301; id <= 304; id++ {
+		addTestGame(t, repo, id, fmt.Sprintf("Game %d", id), `{}`)
+	}
+	dir := t.TempDir()
+
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 2, gamesConcurrency: 0}
+	e := executeBatchDownload(context.Background(), noLogin, dir, opts, false, batchOrderCatalogue)
+	require.NotNil(t, e)
+	assert.Contains(t, e.Message, "--games-concurrency")
+
+	opts.gamesConcurrency = 3
+	out := captureStdout2(func() {
+		executeBatchDownload(context.Background(), noLogin, dir, opts, false, batchOrderCatalogue)
+	})
+	assert.Contains(t, out, "Batch finished: 0 completed, 4 failed, 0 skipped")
+	l, err := loadBatchLog(dir)
+	require.NoError(t, err)
+	assert.Len(t, l.Games, 4, "every game is recorded")
+}
@@ -13,7 +13,7 @@ func guiCmd(authService *auth.Service) *cobra.Command {
 		Use:   "gui",
 		Short: "Start the Gogg GUI",
 		Run: func(cmd *cobra.Command, args []string) {
-			gui.Run(version, authService)
+			gui.Run(version, authService, offline)
 		},
 	}
 	return cmd
@@ -3,9 +3,8 @@
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/pkg/clierr"
 	"github.com/spf13/cobra"
 )
 
@@ -14,10 +13,10 @@ func guiCmd(authService *auth.Service) *cobra.Command {
 		Use:   "gui",
 		Short: "Start the Gogg GUI (not available in headless build)",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("Error: GUI is not available in this build.")
-			fmt.Println("This is a headless (CLI-only) version of Gogg.")
-			fmt.Println("To use the GUI, please download the full version for your platform")
-			fmt.Println("or build from source without the 'headless' tag.")
+			reportCliErr(cmd, clierr.New(clierr.Validation, "GUI is not available in this build.", nil))
+			cmd.PrintErrln("This is a headless (CLI-only) version of Gogg.")
+			cmd.PrintErrln("To use the GUI, please download the full version for your platform")
+			cmd.PrintErrln("or build from source without the 'headless' tag.")
 		},
 	}
 	return cmd
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/hasher"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/habedi/gogg/pkg/validation"
+	"github.com/rs/zerolog/log"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+)
+
+// Output modes of the hash command.
+const (
+	hashOutputStdout  = "stdout"
+	hashOutputSumfile = "sumfile"
+	hashOutputSidecar = "sidecar"
+)
+
+// hashOptions holds the flags of the hash command.
+type hashOptions struct {
+	algo           string
+	recursive      bool
+	numThreads     int
+	output         string
+	sumfile        string
+	excludes       []string
+	includes       []string
+	installersOnly bool
+	useCache       bool
+	noDefaults     bool
+	verbose        bool
+}
+
+func topLevelHashCmd() *cobra.Command {
+	var opts hashOptions
+
+	cmd := &cobra.Command{
+		Use:   "hash [dir]",
+		Short: "Generate checksums for the files in a directory",
+		Long: "Generate checksums for the files in a directory. The checksums can be printed, written to one checksum file,\n" +
+			"or written to a sidecar file next to each file. Checksum files use the format of md5sum and sha256sum.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runHash(cmd, args[0], opts); err != nil {
+				reportCliErr(cmd, err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.algo, "algo", "a", "md5", fmt.Sprintf("Hash algorithm to use %v", hasher.HashAlgorithms))
+	cmd.Flags().BoolVarP(&opts.recursive, "recursive", "r", true, "Process files in subdirectories? [true, false]")
+	addThreadsFlag(cmd, &opts.numThreads, validation.LocalWorkload, "hashing")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", hashOutputStdout, "Where to write the checksums [stdout, sumfile, sidecar]")
+	cmd.Flags().StringVar(&opts.sumfile, "sumfile", "", "Path of the checksum file for --output=sumfile (default is checksums.<algo> in the directory)")
+	cmd.Flags().StringSliceVarP(&opts.excludes, "exclude", "x", nil, "Additional file name patterns to exclude, like '*.bin' (can be repeated)")
+	cmd.Flags().StringSliceVarP(&opts.includes, "include", "i", nil, "Only hash files whose names match one of these patterns, like 'setup_*' (can be repeated)")
+	cmd.Flags().BoolVar(&opts.installersOnly, "installers-only", false, "Only hash installer files (.exe, .bin, .dmg, .pkg, .sh, .zip, .tar.gz, .rar) and skip other extras? [true, false]")
+	cmd.Flags().BoolVar(&opts.noDefaults, "no-default-exclusions", false, "Hash all files, including hidden and metadata files skipped by default (patterns in .goggignore still apply)")
+	cmd.Flags().BoolVar(&opts.noDefaults, "include-hidden", false, "Same as --no-default-exclusions")
+	cmd.Flags().BoolVarP(&opts.verbose, "verbose", "v", false, "Show the exclusion patterns in effect")
+	cmd.Flags().BoolVar(&opts.useCache, "cache", false, "Reuse cached hashes of files whose size and modification time are unchanged? [true, false]")
+
+	return cmd
+}
+
+func runHash(cmd *cobra.Command, dir string, opts hashOptions) *clierr.Error {
+	opts.algo = strings.ToLower(opts.algo)
+	if !hasher.IsValidHashAlgo(opts.algo) {
+		return clierr.New(clierr.Validation, "Unsupported hash algorithm", nil)
+	}
+	if e := validateThreadsFlag(opts.numThreads); e != nil {
+		return e
+	}
+	switch opts.output {
+	case hashOutputStdout, hashOutputSumfile, hashOutputSidecar:
+	default:
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid output mode %q. Must be one of [stdout, sumfile, sidecar]", opts.output), nil)
+	}
+
+	exclusions, err := operations.HashExclusions(dir, !opts.noDefaults, opts.excludes)
+	if err != nil {
+		return clierr.New(clierr.Validation, "Failed to read "+operations.HashIgnoreFileName, err)
+	}
+	if opts.verbose {
+		if len(exclusions) == 0 {
+			cmd.PrintErrln("No exclusion patterns in effect.")
+		} else {
+			cmd.PrintErrf("Excluding files matching: %s\n", strings.Join(exclusions, ", "))
+		}
+	}
+	files, err := operations.FindFilesToHash(dir, opts.recursive, exclusions)
+	if err != nil {
+		return clierr.New(clierr.NotFound, "Error finding files to hash", err)
+	}
+	files = operations.FilterFilesToHash(files, opts.installersOnly, opts.includes)
+	if len(files) == 0 {
+		cmd.PrintErrln("No files found to hash.")
+		return nil
+	}
+
+	bar := newProgressBar(
+		operations.TotalSize(files),
+		cmd.ErrOrStderr(),
+		progressbar.OptionSetDescription("Hashing..."),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionThrottle(200*time.Millisecond),
+		progressbar.OptionClearOnFinish(),
+	)
+	var tracker operations.HashProgressTracker
+	hashOpts := operations.HashOptions{
+		Progress: func(file string, hashed, _ int64) {
+			_ = bar.Set64(tracker.Update(file, hashed))
+		},
+	}
+	if opts.useCache {
+		cache, err := operations.LoadHashCache(operations.DefaultHashCachePath())
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to load hash cache; hashing all files")
+		} else {
+			hashOpts.Cache = cache
+		}
+	}
+
+	var results []operations.HashResult
+	failed := 0
+	for res := range operations.GenerateHashesWithOptions(context.Background(), files, opts.algo, opts.numThreads, hashOpts) {
+		if res.Err != nil {
+			log.Error().Err(res.Err).Str("file", res.File).Msg("Error generating hash")
+			failed++
+			continue
+		}
+		results = append(results, res)
+	}
+	_ = bar.Finish()
+	if hashOpts.Cache != nil {
+		if err := hashOpts.Cache.Save(); err != nil {
+			log.Warn().Err(err).Msg("Failed to save hash cache")
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].File < results[j].File })
+
+	switch opts.output {
+	case hashOutputStdout:
+		writeSumLines(cmd.OutOrStdout(), dir, results)
+	case hashOutputSumfile:
+		sumfile := opts.sumfile
+		if sumfile == "" {
+			sumfile = filepath.Join(dir, "checksums."+opts.algo)
+		}
+		f, err := os.Create(sumfile)
+		if err != nil {
+			return clierr.New(clierr.Internal, "Failed to create checksum file", err)
+		}
+		writeSumLines(f, filepath.Dir(sumfile), results)
+		if err := f.Close(); err != nil {
+			return clierr.New(clierr.Internal, "Failed to write checksum file", err)
+		}
+		cmd.PrintErrf("Wrote %d checksums to %s\n", len(results), sumfile)
+	case hashOutputSidecar:
+		written := 0
+		for _, res := range results {
+			if _, err := operations.WriteSidecarHash(res.File, opts.algo, res.Hash); err != nil {
+				log.Error().Err(err).Str("file", res.File).Msg("Error writing hash file")
+				failed++
+				continue
+			}
+			written++
+		}
+		cmd.PrintErrf("Wrote %d %s files\n", written, opts.algo)
+	}
+
+	if failed > 0 {
+		return clierr.New(clierr.Internal, fmt.Sprintf("Failed to hash %d file(s)", failed), nil)
+	}
+	return nil
+}
+
+// writeSumLines writes one checksum line per result, with paths relative to baseDir.
+func writeSumLines(w io.Writer, baseDir string, results []operations.HashResult) {
+	for _, res := range results {
+		path, err := filepath.Rel(baseDir, res.File)
+		if err != nil {
+			path = res.File
+		}
+		_, _ = fmt.Fprintln(w, operations.FormatSumLine(res.Hash, path))
+	}
+}
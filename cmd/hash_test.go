@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// md5 of "world" and "gogg"
+const (
+	md5World = "7d793037a0760186574b0282f2f435e7"
+	md5Gogg  = "329996c6e8f6795b0d4463eea0213806"
+)
+
+func setupHashDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"b.bin":          "world",
+		"sub/setup.exe":  "gogg",
+		"notes.txt":      "excluded by default",
+		"sub/skip.patch": "excluded by --exclude",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func runTopLevelHash(t *testing.T, args ...string) (string, string) {
+	t.Helper()
+	cmd := topLevelHashCmd()
+	cmd.SetArgs(args)
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd.SetOut(stdout)
+	cmd.SetErr(stderr)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String()
+}
+
+func TestHashCmd_StdoutOutput(t *testing.T) {
+	dir := setupHashDir(t)
+	out, _ := runTopLevelHash(t, dir, "--exclude", "*.patch")
+
+	want := md5World + "  b.bin\n" + md5Gogg + "  sub/setup.exe\n"
+	if out != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestHashCmd_SumfileOutput(t *testing.T) {
+	dir := setupHashDir(t)
+	runTopLevelHash(t, dir, "-o", "sumfile", "-x", "*.patch")
+
+	data, err := os.ReadFile(filepath.Join(dir, "checksums.md5"))
+	if err != nil {
+		t.Fatalf("expected default checksum file: %v", err)
+	}
+	want := md5World + "  b.bin\n" + md5Gogg + "  sub/setup.exe\n"
+	if string(data) != want {
+		t.Fatalf("unexpected checksum file:\n%s", data)
+	}
+
+	// Running again must not pick up the checksum file itself.
+	runTopLevelHash(t, dir, "-o", "sumfile", "-x", "*.patch")
+	data, _ = os.ReadFile(filepath.Join(dir, "checksums.md5"))
+	if string(data) != want {
+		t.Fatalf("checksum file changed on rerun:\n%s", data)
+	}
+}
+
+func TestHashCmd_SidecarOutput(t *testing.T) {
+	dir := setupHashDir(t)
+	runTopLevelHash(t, dir, "--output=sidecar", "--recursive=false")
+
+	data, err := os.ReadFile(filepath.Join(dir, "b.bin.md5"))
+	if err != nil {
+		t.Fatalf("expected sidecar file: %v", err)
+	}
+	if string(data) != md5World+"  b.bin\n" {
+		t.Fatalf("unexpected sidecar content: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "setup.exe.md5")); err == nil {
+		t.Fatal("non-recursive run must not hash files in subdirectories")
+	}
+}
+
+func TestHashCmd_InvalidOutputMode(t *testing.T) {
+	_, errOut := runTopLevelHash(t, t.TempDir(), "--output", "printer")
+	if !strings.Contains(errOut, "Invalid output mode") {
+		t.Fatalf("expected invalid output message, got: %s", errOut)
+	}
+}
+
+func TestHashCmd_InstallersOnly(t *testing.T) {
+	dir := setupHashDir(t)
+	if err := os.WriteFile(filepath.Join(dir, "sub", "wallpaper.jpg"), []byte("art"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := runTopLevelHash(t, dir, "--installers-only")
+
+	want := md5World + "  b.bin\n" + md5Gogg + "  sub/setup.exe\n"
+	if out != want {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestHashCmd_IncludeHiddenStillHonorsGoggignore(t *testing.T) {
+	dir := setupHashDir(t)
+	if err := os.WriteFile(filepath.Join(dir, ".goggignore"), []byte("*.patch\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".hidden"), []byte("gogg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errOut := runTopLevelHash(t, dir, "--include-hidden", "--recursive=false", "-v")
+
+	for _, name := range []string{".hidden", "b.bin", "notes.txt"} {
+		if !strings.Contains(out, "  "+name+"\n") {
+			t.Fatalf("expected %s to be hashed, got:\n%s", name, out)
+		}
+	}
+	if strings.Contains(out, ".goggignore") {
+		t.Fatalf("the ignore file itself must not be hashed:\n%s", out)
+	}
+	if !strings.Contains(errOut, "Excluding files matching: .goggignore, *.patch") {
+		t.Fatalf("expected effective exclusions in verbose output, got: %s", errOut)
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// healthOptions are the flags of the health endpoints of long-running commands like mirror and download --all.
+type healthOptions struct {
+	addr       string // empty means no health endpoints
+	checkLogin bool   // /readyz also needs a usable login
+}
+
+// healthCheckTimeout limits how long a readiness check may take, so a probe gets an answer before it gives up.
+const healthCheckTimeout = 10 * time.Second
+
+func addHealthFlags(cmd *cobra.Command, opts *healthOptions) {
+	cmd.Flags().StringVar(&opts.addr, "health-addr", "", "Serve /healthz and /readyz on this address while running, like :8080 or 127.0.0.1:8080, for service managers and container health checks")
+	cmd.Flags().BoolVar(&opts.checkLogin, "ready-needs-login", false, "Report /readyz as not ready when the GOG login cannot be used, not only when the database is closed")
+}
+
+// startHealthServer serves the health endpoints of opts until ctx is done. It does nothing without an address.
+func startHealthServer(ctx context.Context, opts healthOptions, authService *auth.Service) *clierr.Error {
+	if opts.addr == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		return clierr.New(clierr.Validation, fmt.Sprintf("Failed to serve the health endpoints on %s", opts.addr), err)
+	}
+	server := &http.Server{Handler: healthHandler(authService, opts.checkLogin), ReadHeaderTimeout: healthCheckTimeout}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("The health endpoints stopped")
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	log.Info().Str("addr", listener.Addr().String()).Msg("Serving /healthz and /readyz")
+	return nil
+}
+
+// healthHandler answers /healthz with 200 while the process runs, and /readyz with 200 if the database is open
+// and, with checkLogin, the login can be used. Otherwise /readyz answers 503 with the reason.
+func healthHandler(authService *auth.Service, checkLogin bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+		if err := checkReady(ctx, authService, checkLogin); err != nil {
+			http.Error(w, "not ready: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ready")
+	})
+	return mux
+}
+
+// checkReady returns why the process is not ready to work, or nil if it is.
+func checkReady(ctx context.Context, authService *auth.Service, checkLogin bool) error {
+	gormDB := db.GetDB()
+	if gormDB == nil {
+		return errors.New("the database is not open")
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return fmt.Errorf("the database is not open: %w", err)
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("the database is not reachable: %w", err)
+	}
+	if checkLogin {
+		if err := authService.CheckSession(ctx); err != nil {
+			return fmt.Errorf("the login cannot be used: %w", err)
+		}
+	}
+	return nil
+}
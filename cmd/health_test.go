@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler(t *testing.T) {
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	loggedIn := auth.NewService(expiredTokenStorer{}, &mockTokenRefresher{})
+
+	rec := get(healthHandler(noLogin, false), "/healthz")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = get(healthHandler(noLogin, false), "/readyz")
+	assert.Equal(t, http.StatusOK, rec.Code, "the login is not checked by default")
+
+	rec = get(healthHandler(noLogin, true), "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "login")
+
+	rec = get(healthHandler(loggedIn, true), "/readyz")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestHealthHandler_DatabaseClosed(t *testing.T) {
+	saved := db.Db
+	t.Cleanup(func() { db.Db = saved })
+	db.Db = nil
+
+	rec := httptest.NewRecorder()
+	healthHandler(noLogin, false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "database")
+
+	rec = httptest.NewRecorder()
+	healthHandler(noLogin, false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "the process is still alive")
+}
+
+func TestStartHealthServer(t *testing.T) {
+	require.Nil(t, startHealthServer(context.Background(), healthOptions{}, noLogin), "no address serves nothing")
+
+	e := startHealthServer(context.Background(), healthOptions{addr: "localhost:-1"}, noLogin)
+	require.NotNil(t, e)
+	assert.Equal(t, clierr.Validation, e.Type)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func inspectCmd() *cobra.Command {
+	var jsonOutput bool
+	cmd := &cobra.Command{
+		Use:   "inspect [gameDir]",
+		Short: "Show which platforms and languages of a game were downloaded",
+		Long: "Show which platforms and languages were downloaded into a game folder, as recorded in its\n" +
+			"download_info.json and as found from the installers and the platform and language folders in it.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if e := inspectGameDir(cmd, args[0], jsonOutput); e != nil {
+				reportCliErr(cmd, e)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the result as JSON")
+	return cmd
+}
+
+func inspectGameDir(cmd *cobra.Command, dir string, jsonOutput bool) *clierr.Error {
+	content, err := operations.DetectDownloaded(dir)
+	if err != nil {
+		return clierr.New(clierr.NotFound, "Failed to read the game directory", err)
+	}
+	if jsonOutput {
+		data, err := json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			return clierr.New(clierr.Internal, "Failed to encode the result", err)
+		}
+		cmd.Println(string(data))
+		return nil
+	}
+
+	recordedLanguage, recordedPlatform := "-", "-"
+	if content.Info != nil {
+		recordedLanguage, recordedPlatform = orDash(content.Info.Language), orDash(content.Info.Platform)
+	}
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"", "download_info.json", "Found in the folder"})
+	table.SetAutoWrapText(false)
+	table.Append([]string{"Platform", recordedPlatform, orDash(strings.Join(content.Platforms, ", "))})
+	table.Append([]string{"Language", recordedLanguage, orDash(strings.Join(content.Languages, ", "))})
+	table.Render()
+	if content.Info == nil {
+		cmd.Println("The folder has no download_info.json; 'gogg write-metadata' can write one.")
+	}
+	return nil
+}
+
+// orDash returns s, or "-" if it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
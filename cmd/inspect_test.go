@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectCmd(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "windows"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "windows", "setup_game_1.0.exe"), []byte("x"), 0o644))
+
+	output, err := captureCombinedOutput(inspectCmd(), dir)
+	require.NoError(t, err)
+	assert.Contains(t, output, "windows")
+	assert.Contains(t, output, "has no download_info.json")
+
+	require.NoError(t, client.WriteDownloadInfo(dir, client.DownloadInfo{Language: "English", Platform: "windows"},
+		client.DefaultFileModes))
+	output, err = captureCombinedOutput(inspectCmd(), dir, "--json")
+	require.NoError(t, err)
+	var content operations.DownloadedContent
+	require.NoError(t, json.Unmarshal([]byte(output), &content))
+	require.NotNil(t, content.Info)
+	assert.Equal(t, "English", content.Info.Language)
+	assert.Equal(t, []string{"windows"}, content.Platforms)
+}
+
+func TestInspectCmd_MissingDir(t *testing.T) {
+	output, err := captureCombinedOutput(inspectCmd(), filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Contains(t, output, "Failed to read the game directory")
+}
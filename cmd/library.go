@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+func addLibraryFlags(cmd *cobra.Command, opts *downloadOptions) {
+	cmd.Flags().StringVar(&opts.dedupeLibrary, "dedupe-library", "", "Before downloading a file, look for a file with the same name and size (and GOG's checksum) anywhere under this directory and link it instead")
+	cmd.Flags().StringVar(&opts.dedupeLink, "dedupe-link", client.LinkHard, "How files found with --dedupe-library are linked into the download [hardlink, symlink]")
+}
+
+// validateDedupeLink checks the dedupe-link flag. An empty mode means hard links.
+func validateDedupeLink(mode string) *clierr.Error {
+	if mode == "" {
+		return nil
+	}
+	if err := client.ValidateLinkMode(mode); err != nil {
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid link mode %q. Must be one of %v", mode, client.LinkModes), err)
+	}
+	return nil
+}
+
+// indexLibrary indexes the files under the dedupe-library directory into opts.library, once for the whole run.
+func indexLibrary(opts *downloadOptions) *clierr.Error {
+	if e := validateDedupeLink(opts.dedupeLink); e != nil {
+		return e
+	}
+	start := time.Now()
+	idx, err := client.IndexLibrary(opts.dedupeLibrary)
+	if err != nil {
+		return clierr.New(clierr.Validation, fmt.Sprintf("Failed to index the library in \"%s\"", opts.dedupeLibrary), err)
+	}
+	opts.library = idx
+	fmt.Fprintf(statusOutput(*opts), "Indexed %d file(s) of the library in \"%s\" in %s\n", idx.Len(), opts.dedupeLibrary,
+		time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// printLibrarySavings prints how many files were linked from the library instead of being downloaded, and the
+// bytes and, at the average speed of the run, the time that saved.
+func printLibrarySavings(w io.Writer, s reportSummary) {
+	if s.Linked == 0 {
+		fmt.Fprintln(w, "No files were found in the library; every file was downloaded.")
+		return
+	}
+	fmt.Fprintf(w, "Linked %d file(s) from the library instead of downloading them, saving %s", s.Linked, progress.FormatBytes(s.BytesSaved))
+	if s.TimeSaved > 0 {
+		fmt.Fprintf(w, " (about %s at the average speed of this run)", time.Duration(s.TimeSaved*float64(time.Second)).Round(time.Second))
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/habedi/gogg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDedupeLink(t *testing.T) {
+	for _, mode := range append([]string{""}, client.LinkModes...) {
+		assert.Nil(t, validateDedupeLink(mode), mode)
+	}
+	e := validateDedupeLink("copy")
+	require.NotNil(t, e)
+	assert.Contains(t, e.Message, "Invalid link mode")
+}
+
+func TestPrintLibrarySavings(t *testing.T) {
+	var out bytes.Buffer
+	printLibrarySavings(&out, reportSummary{})
+	assert.Contains(t, out.String(), "No files were found in the library")
+
+	out.Reset()
+	printLibrarySavings(&out, reportSummary{Linked: 2, BytesSaved: 3 * 1024 * 1024, TimeSaved: 90})
+	assert.True(t, containsAll(out.String(), []string{"Linked 2 file(s)", "3.0 MiB", "about 1m30s"}), out.String())
+}
+
+func TestDownloadReport_Linked(t *testing.T) {
+	started := time.Now()
+	report := newDownloadReport(started)
+	w := report.game(1, "Test Game", io.Discard)
+	for _, update := range []client.ProgressUpdate{
+		{Type: "file_done", FileName: "setup.exe", TotalBytes: 1000, Outcome: client.FileDownloaded, Received: 1000},
+		{Type: "file_done", FileName: "setup_2.bin", TotalBytes: 5000, Outcome: client.FileLinked},
+	} {
+		line, err := json.Marshal(update)
+		require.NoError(t, err)
+		_, err = fmt.Fprintln(w, string(line))
+		require.NoError(t, err)
+	}
+	s := report.document(started.Add(10 * time.Second)).Summary
+	assert.Equal(t, 1, s.Linked)
+	assert.Equal(t, int64(5000), s.BytesSaved)
+	assert.Equal(t, int64(100), s.AverageSpeed)
+	assert.Equal(t, 50.0, s.TimeSaved)
+}
+
+func TestDownloadCmd_DedupeLibrary(t *testing.T) {
+	t.Cleanup(func() { setLastCliErr(nil) })
+	dir := t.TempDir()
+	var output string
+	captureStdout2(func() {
+		output, _ = captureCombinedOutput(downloadCmd(noLogin), "1", dir, "--dedupe-library", filepath.Join(dir, "missing"))
+	})
+	assert.Contains(t, output, "Failed to index the library")
+
+	captureStdout2(func() {
+		output, _ = captureCombinedOutput(downloadCmd(noLogin), "1", dir, "--dedupe-library", dir, "--dedupe-link", "copy")
+	})
+	assert.Contains(t, output, "Invalid link mode")
+
+	out := captureStdout2(func() {
+		_, _ = captureCombinedOutput(downloadCmd(noLogin), "1", dir, "--dedupe-library", dir)
+	})
+	assert.True(t, containsAll(out, []string{"Indexed 0 file(s) of the library", "No files were found in the library"}), out)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+)
+
+// logFormatFlag is the name of the global flag that selects the format of the log.
+const logFormatFlag = "log-format"
+
+// logFormatEnv is the environment variable that selects the format of the log when --log-format is not given.
+const logFormatEnv = "GOGG_LOG_FORMAT"
+
+// Formats of the log.
+const (
+	logFormatConsole = "console"
+	logFormatJSON    = "json"
+)
+
+// configureLogFormat sets up the global logger with the format given by --log-format in args or by
+// GOGG_LOG_FORMAT. Without either, the log is made for people when stderr is a terminal and JSON otherwise. The
+// log is coloured only on a terminal and without --no-color and NO_COLOR.
+func configureLogFormat(args []string) *clierr.Error {
+	format, ok := flagFromArgs(args, logFormatFlag)
+	if !ok {
+		format = os.Getenv(logFormatEnv)
+	}
+	terminal := term.IsTerminal(int(os.Stderr.Fd()))
+	logger, err := newLogger(format, os.Stderr, terminal, terminal && !colorDisabled(args))
+	if err != nil {
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid log format %q. Supported formats: %s, %s",
+			format, logFormatConsole, logFormatJSON), err)
+	}
+	log.Logger = logger
+	return nil
+}
+
+// newLogger returns a logger that writes to w in format, which is console, json, or empty to pick console for
+// a terminal and JSON for anything else, like a file or a log collector. The console format is coloured with color.
+func newLogger(format string, w io.Writer, terminal, color bool) (zerolog.Logger, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = logFormatJSON
+		if terminal {
+			format = logFormatConsole
+		}
+	}
+	switch format {
+	case logFormatJSON:
+		return zerolog.New(w).With().Timestamp().Logger(), nil
+	case logFormatConsole:
+		return zerolog.New(zerolog.ConsoleWriter{Out: w, TimeFormat: "15:04:05", NoColor: !color}).
+			With().Timestamp().Logger(), nil
+	default:
+		return zerolog.Logger{}, fmt.Errorf("unknown log format %q", format)
+	}
+}
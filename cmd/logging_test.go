@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/schollz/progressbar/v3"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogger(t *testing.T) {
+	for _, tc := range []struct {
+		format   string
+		terminal bool
+		json     bool
+	}{
+		{"json", true, true},
+		{"console", false, false},
+		{" Console ", false, false},
+		{"", true, false},
+		{"", false, true},
+	} {
+		var buf bytes.Buffer
+		logger, err := newLogger(tc.format, &buf, tc.terminal, false)
+		require.NoError(t, err, tc.format)
+		logger.Info().Msg("hello")
+
+		var entry map[string]interface{}
+		isJSON := json.Unmarshal(buf.Bytes(), &entry) == nil
+		assert.Equal(t, tc.json, isJSON, "format %q, terminal %v: %s", tc.format, tc.terminal, buf.String())
+		assert.Contains(t, buf.String(), "hello")
+		if !tc.json {
+			assert.Contains(t, buf.String(), "INF")
+		}
+	}
+
+	_, err := newLogger("xml", &bytes.Buffer{}, true, true)
+	assert.Error(t, err)
+}
+
+func TestNewLogger_Color(t *testing.T) {
+	for _, color := range []bool{true, false} {
+		var buf bytes.Buffer
+		logger, err := newLogger(logFormatConsole, &buf, true, color)
+		require.NoError(t, err)
+		logger.Info().Msg("hello")
+		assert.Equal(t, color, strings.Contains(buf.String(), "\x1b["), "color %v: %q", color, buf.String())
+	}
+}
+
+func TestColorDisabled(t *testing.T) {
+	t.Setenv(noColorEnv, "")
+	assert.False(t, colorDisabled([]string{"catalogue", "list"}))
+	assert.True(t, colorDisabled([]string{"catalogue", "list", "--no-color"}))
+	assert.True(t, colorDisabled([]string{"--no-color=true", "catalogue", "list"}))
+	assert.False(t, colorDisabled([]string{"catalogue", "list", "--no-color=false"}))
+	assert.False(t, colorDisabled([]string{"hash", "--", "--no-color"}), "arguments after -- are not flags")
+
+	t.Setenv(noColorEnv, "1")
+	assert.True(t, colorDisabled(nil))
+}
+
+func TestNewProgressBar_HiddenWithoutTerminal(t *testing.T) {
+	restore := isTerminal
+	t.Cleanup(func() { isTerminal = restore })
+	for _, terminal := range []bool{true, false} {
+		isTerminal = func(io.Writer) bool { return terminal }
+		var buf bytes.Buffer
+		bar := newProgressBar(100, &buf, progressbar.OptionSetDescription("Working..."))
+		require.NoError(t, bar.Set(50))
+		assert.Equal(t, terminal, strings.Contains(buf.String(), "Working..."), "terminal %v: %q", terminal, buf.String())
+	}
+}
+
+func TestConfigureLogFormat_RejectsUnknownFormat(t *testing.T) {
+	t.Setenv(logFormatEnv, "json")
+	e := configureLogFormat([]string{"catalogue", "list", "--log-format=xml"})
+	require.NotNil(t, e)
+	assert.Contains(t, e.Message, `Invalid log format "xml"`)
+
+	t.Setenv(logFormatEnv, "yaml")
+	assert.NotNil(t, configureLogFormat(nil), "the environment variable is checked too")
+}
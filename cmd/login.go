@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/habedi/gogg/client"
 	"github.com/habedi/gogg/pkg/clierr"
@@ -14,37 +17,95 @@ import (
 
 func loginCmd(gogClient *client.GogClient) *cobra.Command {
 	var gogUsername, gogPassword string
-	var headless bool
+	var headless, manual, passwordStdin bool
+	var browserPath, profileDir string
+	var timeout, pollInterval time.Duration
 
 	cmd := &cobra.Command{
-		Use:   "login",
-		Short: "Login to GOG.com",
-		Long:  "Login to GOG.com using your username and password",
+		Use:         "login",
+		Short:       "Login to GOG.com",
+		Annotations: map[string]string{needsNetworkAnnotation: ""},
+		Long:        "Login to GOG.com using your username and password",
 		Run: func(cmd *cobra.Command, args []string) {
-			cmd.Println("Please enter your GOG username and password.")
-			gogUsername = promptForInput("GOG username: ")
-			gogPassword = promptForPassword("GOG password: ")
+			if timeout < 0 || pollInterval <= 0 {
+				reportCliErr(cmd, clierr.New(clierr.Validation, "The timeout must not be negative and the poll interval must be positive", nil))
+				return
+			}
+			if manual {
+				loginManually(cmd, gogClient)
+				return
+			}
+			if passwordStdin {
+				if gogUsername == "" {
+					reportCliErr(cmd, clierr.New(clierr.Validation, "--password-stdin needs the username in --username", nil))
+					return
+				}
+				password, err := readPasswordFromStdin(cmd)
+				if err != nil {
+					reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to read the password from stdin", err))
+					return
+				}
+				gogPassword = password
+			} else {
+				cmd.Println("Please enter your GOG username and password.")
+				if gogUsername == "" {
+					gogUsername = promptForInput("GOG username: ")
+				}
+				gogPassword = promptForPassword("GOG password: ")
+			}
 
 			if validateCredentials(gogUsername, gogPassword) {
-				if err := gogClient.Login(client.GOGLoginURL, gogUsername, gogPassword, headless); err != nil {
-					cmd.PrintErrln(clierr.New(clierr.Internal, "Failed to login to GOG.com", err).Message)
-					if strings.Contains(err.Error(), "executable found in PATH") {
-						cmd.PrintErrln("Hint: Make sure Google Chrome or Chromium is installed and accessible in your system's PATH.")
+				if err := gogClient.Login(gogClient.LoginURL(), gogUsername, gogPassword, headless,
+					client.WithLoginTimeout(timeout), client.WithLoginPollInterval(pollInterval), client.WithBrowserPath(browserPath), client.WithChromeProfile(profileDir)); err != nil {
+					if errors.Is(err, client.ErrInvalidBrowserPath) {
+						reportCliErr(cmd, clierr.New(clierr.Validation, "Failed to login to GOG.com", err))
+					} else if errors.Is(err, client.ErrBrowserNotFound) {
+						reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to login to GOG.com", err))
+						cmd.PrintErrln("Hint: Install Google Chrome, Chromium, Microsoft Edge, or Brave, or point Gogg to it with --browser-path or GOGG_BROWSER.")
+					} else {
+						reportCliErr(cmd, clierr.Wrap(clierr.Auth, "Failed to login to GOG.com", err))
+						if errors.Is(err, client.ErrLoginRecaptcha) {
+							cmd.PrintErrln("Hint: Run 'gogg login --headless=false' and solve the reCAPTCHA in the browser window.")
+						} else if errors.Is(err, client.ErrLoginFormNotFound) {
+							cmd.PrintErrln("Hint: Run 'gogg login --manual' to login in your own browser instead.")
+						}
 					}
 				} else {
 					cmd.Println("Login was successful.")
 				}
 			} else {
-				cmd.PrintErrln(clierr.New(clierr.Validation, "Username and password cannot be empty", nil).Message)
+				reportCliErr(cmd, clierr.New(clierr.Validation, "Username and password cannot be empty", nil))
 			}
 		},
 	}
 
+	cmd.Flags().StringVarP(&gogUsername, "username", "u", "", "GOG username (the email address of the account); asked for if not given")
+	cmd.Flags().BoolVar(&passwordStdin, "password-stdin", false, "Read the password from the first line of stdin instead of asking for it, like 'gogg login -u <email> --password-stdin < password.txt'")
 	cmd.Flags().BoolVarP(&headless, "headless", "n", true, "Login in headless mode without showing the browser window? [true, false]")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "How long to wait for GOG to finish the login after submitting the form (0 means 30s in headless mode and 4m otherwise)")
+	cmd.Flags().StringVar(&browserPath, "browser-path", "", "Path of the Chrome-based browser to login with (overrides GOGG_BROWSER and the automatic search)")
+	cmd.Flags().StringVar(&profileDir, "chrome-profile", "", "User data directory of an existing browser profile to reuse its cookies for the login (close the browser first)")
+	cmd.Flags().BoolVar(&manual, "manual", false, "Login in your own browser and paste the URL GOG redirects to, without starting a browser")
+	cmd.Flags().DurationVar(&pollInterval, "poll-interval", client.DefaultLoginPollInterval, "How often to check whether GOG has finished the login")
 
 	return cmd
 }
 
+// loginManually lets the user login in any browser and finishes the login with the redirect URL they paste.
+func loginManually(cmd *cobra.Command, gogClient *client.GogClient) {
+	cmd.Println("Open this URL in a browser and login to GOG.com:")
+	cmd.Println()
+	cmd.Println("  " + gogClient.LoginURL())
+	cmd.Println()
+	cmd.Println("After the login, the browser shows a page whose URL contains 'on_login_success' and 'code='.")
+	input := promptForInput("Paste that URL (or just the code): ")
+	if err := gogClient.LoginWithCode(input); err != nil {
+		reportCliErr(cmd, clierr.Wrap(clierr.Auth, "Failed to login to GOG.com", err))
+		return
+	}
+	cmd.Println("Login was successful.")
+}
+
 func promptForInput(prompt string) string {
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Print(prompt)
@@ -67,6 +128,19 @@ func promptForPassword(prompt string) string {
 	return strings.TrimSpace(string(password))
 }
 
+// readPasswordFromStdin reads the password for --password-stdin: the first line of stdin, or, if stdin is a
+// terminal, a line typed without being shown.
+func readPasswordFromStdin(cmd *cobra.Command) (string, error) {
+	if stdinIsTerminal() {
+		return readPassphrase("GOG password: "), nil
+	}
+	line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
 func validateCredentials(username, password string) bool {
 	return username != "" && password != ""
 }
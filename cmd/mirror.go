@@ -0,0 +1,504 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/validation"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// mirrorStateFileName is the name of the state file kept in the directory of a `mirror` run.
+const mirrorStateFileName = "mirror_state.json"
+
+// mirrorEntry records the last download of one game of a mirror.
+type mirrorEntry struct {
+	Title       string    `json:"title"`
+	Folder      string    `json:"folder"`
+	Fingerprint string    `json:"fingerprint"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// mirrorState records every game of a mirror, so a rerun skips the games that are up to date, downloads the
+// new and changed ones, and knows which folders it may prune.
+type mirrorState struct {
+	path  string
+	Games map[int]*mirrorEntry `json:"games"`
+}
+
+// loadMirrorState reads the mirror state from dir. A missing state yields an empty one.
+func loadMirrorState(dir string) (*mirrorState, error) {
+	s := &mirrorState{path: filepath.Join(dir, mirrorStateFileName), Games: make(map[int]*mirrorEntry)}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse mirror state %s: %w", s.path, err)
+	}
+	if s.Games == nil {
+		s.Games = make(map[int]*mirrorEntry)
+	}
+	return s, nil
+}
+
+func (s *mirrorState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// record stores the outcome of downloading a game and writes the state to disk.
+func (s *mirrorState) record(game db.Game, folder, fingerprint string, err error) error {
+	entry := &mirrorEntry{
+		Title: game.Title, Folder: folder, Fingerprint: fingerprint,
+		Status: batchStatusCompleted, UpdatedAt: time.Now().UTC(),
+	}
+	if err != nil {
+		entry.Status = batchStatusFailed
+		entry.Error = err.Error()
+	}
+	s.Games[game.ID] = entry
+	return s.save()
+}
+
+// mirrorFingerprint identifies the catalogue data of a game together with the options that decide which of its
+// files are mirrored, so a change of either makes the game be downloaded again.
+func mirrorFingerprint(game db.Game, opts downloadOptions) string {
+	h := fnv.New64a()
+	_, _ = io.WriteString(h, game.Data)
+	fmt.Fprintf(h, "\x00%s\x00%s\x00%t\x00%t\x00%t\x00%t", opts.language, opts.platformName, opts.extras, opts.dlcs, opts.skipPatches, opts.flatten)
+	// Only a --flatten-extras that differs from --flatten changes the layout, and mirrors made before it existed
+	// keep their fingerprints.
+	if opts.flattenExtras != nil && *opts.flattenExtras != opts.flatten {
+		fmt.Fprintf(h, "\x00flatten-extras=%t", *opts.flattenExtras)
+	}
+	// Likewise, --no-dlc-extras only matters when the extras of DLCs would be downloaded.
+	if opts.noDLCExtras && opts.extras && opts.dlcs {
+		_, _ = io.WriteString(h, "\x00no-dlc-extras")
+	}
+	if opts.minFileBytes > 0 || opts.maxFileBytes > 0 {
+		fmt.Fprintf(h, "\x00file-size=%d-%d", opts.minFileBytes, opts.maxFileBytes)
+	}
+	if len(opts.excludes) > 0 {
+		fmt.Fprintf(h, "\x00exclude=%q", opts.excludes)
+	}
+	if opts.fileNames != "" && opts.fileNames != client.FileNamesOriginal {
+		fmt.Fprintf(h, "\x00file-names=%s", opts.fileNames)
+	}
+	if opts.layout != "" && opts.layout != client.LayoutGogg {
+		fmt.Fprintf(h, "\x00layout=%s", opts.layout)
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// mirrorPlan sorts the games of the catalogue and the mirror state by what a mirror run does with them.
+type mirrorPlan struct {
+	New        []db.Game
+	Changed    []db.Game // changed in the catalogue, downloaded with other options, or failed before
+	UpToDate   []db.Game
+	Unreadable []db.Game
+	Prune      map[int]*mirrorEntry // games in the state that are no longer in the catalogue
+	// Renamed are owned games whose folder name changed, like after GOG renamed the game. Their old folder is
+	// moved to the new name before the download, so the files already there are not downloaded again.
+	Renamed map[int]mirrorRename
+	// Superseded are games in the state that are no longer in the catalogue but whose folder is now used by an
+	// owned game, like a re-released product with the same title. Pruning only forgets them and keeps the folder.
+	Superseded map[int]*mirrorEntry
+	// Pinned are pinned games that changed since they were mirrored. Their mirrored copy is kept as it is.
+	Pinned []db.Game
+	// NoDownloads are games without any file to download, like pre-orders of unreleased games. They are not
+	// downloaded or recorded in the state until a refresh of the catalogue finds their files.
+	NoDownloads []db.Game
+}
+
+// mirrorRename is the old and the new folder name of a mirrored game.
+type mirrorRename struct {
+	From, To string
+}
+
+// planMirror decides what to do with each game. A game is up to date if its last download completed with
+// the same fingerprint into the folder named by opts, and that folder is still in dir.
+func planMirror(games []db.Game, state *mirrorState, dir string, opts downloadOptions) mirrorPlan {
+	plan := mirrorPlan{
+		Prune:      make(map[int]*mirrorEntry),
+		Renamed:    make(map[int]mirrorRename),
+		Superseded: make(map[int]*mirrorEntry),
+	}
+	owned := make(map[int]bool, len(games))
+	usedFolders := make(map[string]bool, len(games))
+	for _, game := range games {
+		owned[game.ID] = true
+		usedFolders[gameFolder(opts, game.Title, game.ID)] = true
+		if entry := state.Games[game.ID]; entry != nil {
+			usedFolders[entry.Folder] = true
+		}
+	}
+	for _, game := range games {
+		if _, err := client.ParseCachedGameData(game.ID, game.Data); err != nil {
+			plan.Unreadable = append(plan.Unreadable, game)
+			continue
+		}
+		if hasNoDownloads(game) {
+			plan.NoDownloads = append(plan.NoDownloads, game)
+			continue
+		}
+		entry := state.Games[game.ID]
+		folder := gameFolder(opts, game.Title, game.ID)
+		if entry != nil && entry.Folder != folder && isDir(filepath.Join(dir, entry.Folder)) && !isDir(filepath.Join(dir, folder)) {
+			plan.Renamed[game.ID] = mirrorRename{From: entry.Folder, To: folder}
+			plan.Changed = append(plan.Changed, game)
+			continue
+		}
+		if entry == nil || entry.Folder != folder || !isDir(filepath.Join(dir, entry.Folder)) {
+			plan.New = append(plan.New, game)
+			continue
+		}
+		if entry.Status == batchStatusCompleted && entry.Fingerprint == mirrorFingerprint(game, opts) {
+			plan.UpToDate = append(plan.UpToDate, game)
+			continue
+		}
+		plan.Changed = append(plan.Changed, game)
+	}
+	for id, entry := range state.Games {
+		switch {
+		case owned[id]:
+		case usedFolders[entry.Folder]:
+			plan.Superseded[id] = entry
+		default:
+			plan.Prune[id] = entry
+		}
+	}
+	return plan
+}
+
+// holdPinned moves the pinned games that were mirrored before from the changed games of the plan to its pinned
+// games, so that their mirrored copy is kept. Pinned games that were never mirrored are still downloaded.
+func (p *mirrorPlan) holdPinned(pinned map[int]bool, state *mirrorState) {
+	var changed []db.Game
+	for _, game := range p.Changed {
+		if entry := state.Games[game.ID]; pinned[game.ID] && entry.Status == batchStatusCompleted {
+			p.Pinned = append(p.Pinned, game)
+			delete(p.Renamed, game.ID)
+			continue
+		}
+		changed = append(changed, game)
+	}
+	p.Changed = changed
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// mirrorOptions holds the flags of the mirror command that are not download options.
+type mirrorOptions struct {
+	prune, dryRun bool
+}
+
+func mirrorCmd(authService *auth.Service) *cobra.Command {
+	opts := downloadOptions{resume: true}
+	var mOpts mirrorOptions
+	var health healthOptions
+	var flattenExtras bool
+	cmd := &cobra.Command{
+		Use:         "mirror [dir]",
+		Short:       "Keep a copy of the whole library in a directory",
+		Annotations: map[string]string{needsAuthAnnotation: ""},
+		Long: "Download every game of the catalogue to the directory and keep it in sync on later runs.\n" +
+			"Games that are up to date are skipped, new games and games that changed since their last download\n" +
+			"are downloaded, and files that are already complete are checked against GOG's checksums.\n" +
+			"The state is kept in " + mirrorStateFileName + " in the directory, so an interrupted run can be resumed.\n" +
+			"Run 'gogg catalogue refresh' first so the catalogue lists the games you own now.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if cmd.Flags().Changed("flatten-extras") {
+				opts.flattenExtras = &flattenExtras
+			}
+			applyLayoutPreset(cmd, &opts)
+			ctx, stopSchedule := context.WithCancel(cmd.Context())
+			defer stopSchedule()
+			if e := applyRateSchedule(ctx); e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			if e := startHealthServer(ctx, health, authService); e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			if e := executeMirror(ctx, authService, args[0], opts, mOpts); e != nil {
+				reportCliErr(cmd, e)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&opts.language, "lang", "l", "en", "Game language [en, fr, de, es, it, ru, pl, pt-BR, zh-Hans, ja, ko, ...]; all means all languages")
+	cmd.Flags().StringVarP(&opts.platformName, "platform", "p", "windows", "Platform name [all, auto, windows, mac, linux]; all means all platforms, auto the platform of this machine")
+	cmd.Flags().BoolVarP(&opts.extras, "extras", "e", true, "Include extra content files? [true, false]")
+	cmd.Flags().BoolVarP(&opts.dlcs, "dlcs", "d", true, "Include DLC files? [true, false]")
+	cmd.Flags().BoolVar(&opts.noDLCExtras, "no-dlc-extras", false, "Skip the extras of DLCs, like their soundtracks, while still downloading the DLC installers and the extras of the game")
+	cmd.Flags().BoolVar(&opts.bestEffort, "best-effort", false, "When some files of a game fail, still write its metadata.json and list the failed files with how to retry them")
+	cmd.Flags().StringVar(&opts.minFileSize, "min-file-size", "", "Skip the files that GOG lists as smaller than this, like 1MB; files of unknown size are still downloaded")
+	cmd.Flags().StringVar(&opts.maxFileSize, "max-file-size", "", "Skip the files that GOG lists as larger than this, like 4GB; files of unknown size are still downloaded")
+	addExcludeFlag(cmd, &opts.excludes)
+	addThreadsFlag(cmd, &opts.numThreads, validation.NetworkWorkload, "downloading")
+	addStallTimeoutFlag(cmd, &opts.stallTimeout)
+	addAccurateTotalFlag(cmd, &opts.accurateTotal)
+	addChecksumFilesFlag(cmd, &opts.checksumFiles)
+	addPreallocationFlag(cmd, &opts.preallocate)
+	addPerGameLogFlag(cmd, &opts.perGameLog)
+	cmd.Flags().BoolVarP(&opts.flatten, "flatten", "f", true, "Flatten the directory structure when downloading? [true, false]")
+	cmd.Flags().BoolVar(&flattenExtras, "flatten-extras", false, "Put extras directly into the game folder instead of an extras folder? [true, false] (default is the value of --flatten)")
+	cmd.Flags().BoolVarP(&opts.skipPatches, "skip-patches", "s", false, "Skip patches when downloading? [true, false]")
+	cmd.Flags().StringVar(&opts.folderName, "folder-name", client.DefaultFolderTemplate, "Name of the game folders, made from the tokens {title} and {id} (the GOG product ID), like {title}-{id}")
+	cmd.Flags().BoolVar(&opts.folderID, "folder-id", false, "Add the GOG product ID to the name of the game folders, like {title}-{id}")
+	cmd.Flags().BoolVar(&opts.asciiTitles, "ascii-titles", false, "Write letters of the titles like é or Ж in ASCII for the game folder names instead of dropping them")
+	cmd.Flags().StringVar(&opts.titleStyle, "title-style", client.TitleStyleSlug, "How the titles are written in the game folder names [slug, raw, underscores]; raw keeps the titles as GOG shows them, underscores joins their words with underscores")
+	cmd.Flags().StringVar(&opts.fileNames, "file-names", client.FileNamesOriginal, "How the downloaded files are named [original, lower, underscores, lower-underscores]; the extensions are kept")
+	cmd.Flags().BoolVar(&opts.verifyResume, "verify", true, "Check files that are already complete against GOG's checksum and download them again if they do not match")
+	cmd.Flags().BoolVar(&opts.checkTarget, "check-target", false, "Before downloading each game, warn if the target file system is low on free inodes or a path of the download is too long for it")
+	addGamesConcurrencyFlag(cmd, &opts.gamesConcurrency)
+	addHealthFlags(cmd, &health)
+	addLayoutFlag(cmd, &opts.layout)
+	addProgressFormatFlag(cmd, &opts.progressFmt)
+	cmd.Flags().BoolVar(&mOpts.prune, "prune", false, "Remove the folders of mirrored games that are no longer in the catalogue")
+	cmd.Flags().BoolVar(&mOpts.dryRun, "dry-run", false, "Only show what would be downloaded and pruned")
+	return cmd
+}
+
+// executeMirror syncs the catalogue to dir. It returns an error if the mirror could not be started or if any
+// game failed to download or prune.
+func executeMirror(ctx context.Context, authService *auth.Service, dir string, opts downloadOptions, mOpts mirrorOptions) *clierr.Error {
+	if e := validateThreadsFlag(opts.numThreads); e != nil {
+		return e
+	}
+	if err := validation.ValidatePlatform(opts.platformName); err != nil {
+		return clierr.New(clierr.Validation, "Invalid platform", err)
+	}
+	opts.platformName = validation.ResolvePlatform(opts.platformName)
+	if _, ok := client.LanguageFilter(opts.language); !ok {
+		return clierr.New(clierr.Validation, "Invalid language code", nil)
+	}
+	if e := parseModeFlags(&opts); e != nil {
+		return e
+	}
+	if e := parseFileSizeFlags(&opts); e != nil {
+		return e
+	}
+	if e := validateExcludes(opts.excludes); e != nil {
+		return e
+	}
+	if e := prepareGamesConcurrency(&opts); e != nil {
+		return e
+	}
+	if err := client.ValidateFolderTemplate(gameFolderTemplate(opts)); err != nil {
+		return clierr.New(clierr.Validation, "Invalid folder name: "+err.Error(), err)
+	}
+	if e := validateTitleStyle(opts.titleStyle); e != nil {
+		return e
+	}
+	if e := validateFileNameStyle(opts.fileNames); e != nil {
+		return e
+	}
+	if e := validateLayout(opts); e != nil {
+		return e
+	}
+	if e := validateStallTimeout(opts.stallTimeout); e != nil {
+		return e
+	}
+
+	state, err := loadMirrorState(dir)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Failed to read the mirror state", err)
+	}
+	games, err := db.NewGameRepository(db.GetDB()).List(ctx)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Unable to list games", err)
+	}
+	if len(games) == 0 {
+		// An empty catalogue would make every mirrored game look de-owned.
+		fmt.Println("Game catalogue is empty. Did you refresh the catalogue?")
+		return nil
+	}
+	pinned, e := pinnedGames(ctx)
+	if e != nil {
+		return e
+	}
+	plan := planMirror(games, state, dir, opts)
+	plan.holdPinned(pinned, state)
+
+	if mOpts.dryRun {
+		printMirrorPlan(plan, mOpts.prune)
+		return nil
+	}
+	if err := os.MkdirAll(dir, opts.modes.Dir); err != nil {
+		return clierr.New(clierr.Internal, "Failed to create the mirror directory", err)
+	}
+
+	for _, id := range sortedMirrorIDs(plan.Renamed) {
+		rename := plan.Renamed[id]
+		if err := os.Rename(filepath.Join(dir, rename.From), filepath.Join(dir, rename.To)); err != nil {
+			// The game is then downloaded into its new folder and the old one is left as it is.
+			log.Warn().Err(err).Str("folder", rename.From).Msg("Failed to rename the folder of a mirrored game")
+			continue
+		}
+		fmt.Printf("Renamed %s to %s.\n", rename.From, rename.To)
+	}
+
+	var downloaded, failed, pruned int
+	var stopped bool
+	var mu sync.Mutex
+	forEachGame(ctx, append(append([]db.Game{}, plan.New...), plan.Changed...), opts.gamesConcurrency, func(gameCtx context.Context, game db.Game) bool {
+		dlErr := executeDownload(gameCtx, authService, game.ID, dir, opts)
+		mu.Lock()
+		defer mu.Unlock()
+		if dlErr != nil && gameCtx.Err() != nil {
+			stopped = true
+			return true
+		}
+		if dlErr != nil {
+			failed++
+		} else {
+			downloaded++
+		}
+		if err := state.record(game, gameFolder(opts, game.Title, game.ID), mirrorFingerprint(game, opts), dlErr); err != nil {
+			log.Warn().Err(err).Msg("Failed to update the mirror state")
+		}
+		if errors.Is(dlErr, client.ErrVolumeFull) {
+			fmt.Println("The mirror was stopped because the target volume is full; free some space and run the same command again to continue.")
+			stopped = true
+			return true
+		}
+		return false
+	})
+	if opts.progress != nil {
+		opts.progress.finish()
+	}
+	stopped = stopped || ctx.Err() != nil
+
+	prune := mOpts.prune && !stopped
+	if prune && len(plan.Prune) > 0 && !confirmMirrorPrune(plan.Prune) {
+		fmt.Println("Pruning skipped.")
+		prune = false
+	}
+	if prune {
+		for id := range plan.Superseded {
+			delete(state.Games, id)
+		}
+		for _, id := range sortedMirrorIDs(plan.Prune) {
+			entry := plan.Prune[id]
+			if err := removeMirroredGame(dir, entry.Folder); err != nil {
+				log.Error().Err(err).Str("folder", entry.Folder).Msg("Failed to prune game")
+				fmt.Printf("Failed to remove %s: %v\n", entry.Title, err)
+				failed++
+				continue
+			}
+			delete(state.Games, id)
+			pruned++
+		}
+		if err := state.save(); err != nil {
+			log.Warn().Err(err).Msg("Failed to update the mirror state")
+		}
+	}
+
+	fmt.Printf("Mirror finished: %d downloaded, %d failed, %d up to date, %d pruned.\n", downloaded, failed, len(plan.UpToDate), pruned)
+	if ctx.Err() != nil {
+		fmt.Println("The mirror was interrupted; run the same command again to continue.")
+	}
+	printHeldPins(len(plan.Pinned))
+	if len(plan.Prune) > 0 && !mOpts.prune {
+		fmt.Printf("%d mirrored game(s) are no longer in the catalogue; use --prune to remove them.\n", len(plan.Prune))
+	}
+	if len(plan.Unreadable) > 0 {
+		fmt.Printf("%d game(s) were skipped because their catalogue data could not be read; run 'gogg catalogue refresh' and try again.\n", len(plan.Unreadable))
+	}
+	if len(plan.NoDownloads) > 0 {
+		fmt.Printf("%d game(s) were skipped because they have no downloadable content yet, most likely pre-orders of games that are not released.\n", len(plan.NoDownloads))
+	}
+	fmt.Printf("Mirror state: %s\n", state.path)
+	if failed > 0 || len(plan.Unreadable) > 0 {
+		return clierr.New(clierr.Download, fmt.Sprintf("%d game(s) failed to mirror", failed+len(plan.Unreadable)), nil)
+	}
+	return nil
+}
+
+// removeMirroredGame removes the folder of a mirrored game from dir. The folder must be a direct child of dir.
+func removeMirroredGame(dir, folder string) error {
+	if folder == "" || folder == "." || folder == ".." || filepath.Base(folder) != folder {
+		return fmt.Errorf("refusing to remove unexpected folder %q", folder)
+	}
+	return os.RemoveAll(filepath.Join(dir, folder))
+}
+
+// confirmMirrorPrune lists the folders that pruning removes and asks whether to remove them.
+func confirmMirrorPrune(entries map[int]*mirrorEntry) bool {
+	fmt.Printf("%d mirrored game(s) are no longer in the catalogue and their folders will be removed:\n", len(entries))
+	for _, id := range sortedMirrorIDs(entries) {
+		fmt.Printf("  %s (%s)\n", entries[id].Title, entries[id].Folder)
+	}
+	return confirm("Remove these folders? [y/N]: ")
+}
+
+func sortedMirrorIDs[V any](entries map[int]V) []int {
+	ids := make([]int, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func printMirrorPlan(plan mirrorPlan, prune bool) {
+	for _, game := range plan.New {
+		fmt.Printf("New: %s (ID %d)\n", game.Title, game.ID)
+	}
+	for _, game := range plan.Changed {
+		if rename, ok := plan.Renamed[game.ID]; ok {
+			fmt.Printf("Renamed: %s (ID %d, folder %s to %s)\n", game.Title, game.ID, rename.From, rename.To)
+			continue
+		}
+		fmt.Printf("Changed: %s (ID %d)\n", game.Title, game.ID)
+	}
+	for _, game := range plan.Pinned {
+		fmt.Printf("Pinned, not updated: %s (ID %d)\n", game.Title, game.ID)
+	}
+	for _, id := range sortedMirrorIDs(plan.Prune) {
+		action := "Would prune"
+		if !prune {
+			action = "Not owned anymore (use --prune to remove)"
+		}
+		fmt.Printf("%s: %s (folder %s)\n", action, plan.Prune[id].Title, plan.Prune[id].Folder)
+	}
+	for _, game := range plan.Unreadable {
+		fmt.Printf("Unreadable catalogue data: %s (ID %d)\n", game.Title, game.ID)
+	}
+	for _, game := range plan.NoDownloads {
+		fmt.Printf("No downloadable content yet: %s (ID %d)\n", game.Title, game.ID)
+	}
+	fmt.Printf("Dry run: %d new, %d changed, %d up to date, %d no longer in the catalogue.\n", len(plan.New), len(plan.Changed), len(plan.UpToDate), len(plan.Prune))
+}
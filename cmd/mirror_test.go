@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanMirror(t *testing.T) {
+	dir := t.TempDir()
+	opts := downloadOptions{language: "en", platformName: "windows", extras: true}
+	games := []db.Game{
+		{ID: 1, Title: "Current Game", Data: `{"title":"Current Game"}`},
+		{ID: 2, Title: "Updated Game", Data: `{"title":"Updated Game","changelog":"new"}`},
+		{ID: 3, Title: "Failed Game", Data: `{"title":"Failed Game"}`},
+		{ID: 4, Title: "Fresh Game", Data: `{"title":"Fresh Game"}`},
+		{ID: 5, Title: "Deleted Folder", Data: `{"title":"Deleted Folder"}`},
+		{ID: 6, Title: "Broken", Data: `not json`},
+	}
+	state, err := loadMirrorState(dir)
+	require.NoError(t, err)
+	record := func(game db.Game, fingerprint string, err error) {
+		require.NoError(t, state.record(game, gameFolder(opts, game.Title, game.ID), fingerprint, err))
+	}
+	record(games[0], mirrorFingerprint(games[0], opts), nil)
+	record(db.Game{ID: 2, Title: "Updated Game", Data: `{"title":"Updated Game"}`}, "old", nil)
+	record(games[2], mirrorFingerprint(games[2], opts), errors.New("network"))
+	record(games[4], mirrorFingerprint(games[4], opts), nil)
+	record(db.Game{ID: 9, Title: "Refunded Game"}, "x", nil)
+	for _, folder := range []string{"current-game", "updated-game", "failed-game", "refunded-game"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, folder), 0o755))
+	}
+
+	ids := func(games []db.Game) []int {
+		var out []int
+		for _, g := range games {
+			out = append(out, g.ID)
+		}
+		return out
+	}
+	plan := planMirror(games, state, dir, opts)
+	assert.Equal(t, []int{1}, ids(plan.UpToDate))
+	assert.Equal(t, []int{2, 3}, ids(plan.Changed))
+	assert.Equal(t, []int{4, 5}, ids(plan.New), "a game whose folder is gone is downloaded again")
+	assert.Equal(t, []int{6}, ids(plan.Unreadable))
+	assert.Equal(t, []int{9}, sortedMirrorIDs(plan.Prune))
+
+	opts.platformName = "linux"
+	plan = planMirror(games, state, dir, opts)
+	assert.Empty(t, plan.UpToDate, "other options make every game change")
+
+	opts.platformName, opts.folderID = "windows", true
+	plan = planMirror(games, state, dir, opts)
+	assert.Empty(t, plan.UpToDate, "a game is downloaded again into its new folder")
+}
+
+func TestExecuteMirror_DryRunAndPrune(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Current Game", `{"title":"Current Game"}`)
+	games, err := repo.List(context.Background())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 2, resume: true, gamesConcurrency: 1}
+	state, err := loadMirrorState(dir)
+	require.NoError(t, err)
+	require.NoError(t, state.record(games[0], "current-game", mirrorFingerprint(games[0], opts), nil))
+	require.NoError(t, state.record(db.Game{ID: 9, Title: "Refunded Game"}, "refunded-game", "x", nil))
+	for _, folder := range []string{"current-game", "refunded-game", "not-mirrored"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, folder), 0o755))
+	}
+
+	out := captureStdout2(func() {
+		assert.Nil(t, executeMirror(context.Background(), nil, dir, opts, mirrorOptions{prune: true, dryRun: true}))
+	})
+	assert.Contains(t, out, "Would prune: Refunded Game")
+	assert.Contains(t, out, "Dry run: 0 new, 0 changed, 1 up to date, 1 no longer in the catalogue.")
+	assert.DirExists(t, filepath.Join(dir, "refunded-game"))
+
+	// Nothing needs downloading, so the run needs no login.
+	out = captureStdout2(func() {
+		assert.Nil(t, executeMirror(context.Background(), nil, dir, opts, mirrorOptions{prune: true}))
+	})
+	assert.Contains(t, out, "Mirror finished: 0 downloaded, 0 failed, 1 up to date, 1 pruned.")
+	assert.NoDirExists(t, filepath.Join(dir, "refunded-game"))
+	assert.DirExists(t, filepath.Join(dir, "current-game"))
+	assert.DirExists(t, filepath.Join(dir, "not-mirrored"), "folders the mirror did not create are kept")
+
+	state, err = loadMirrorState(dir)
+	require.NoError(t, err)
+	assert.NotContains(t, state.Games, 9)
+}
+
+func TestExecuteMirror_PruneKeepsFolderOfReleasedAgainGame(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 2, "Some Game", `{"title":"Some Game"}`)
+	games, err := repo.List(context.Background())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 2, resume: true, gamesConcurrency: 1}
+	state, err := loadMirrorState(dir)
+	require.NoError(t, err)
+	// Game 1 was replaced in the catalogue by game 2, a new product with the same title and so the same folder.
+	require.NoError(t, state.record(db.Game{ID: 1, Title: "Some Game"}, "some-game", "x", nil))
+	require.NoError(t, state.record(games[0], "some-game", mirrorFingerprint(games[0], opts), nil))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "some-game"), 0o755))
+
+	plan := planMirror(games, state, dir, opts)
+	assert.Empty(t, plan.Prune)
+	assert.Equal(t, []int{1}, sortedMirrorIDs(plan.Superseded))
+
+	out := captureStdout2(func() {
+		assert.Nil(t, executeMirror(context.Background(), nil, dir, opts, mirrorOptions{prune: true}))
+	})
+	assert.Contains(t, out, "Mirror finished: 0 downloaded, 0 failed, 1 up to date, 0 pruned.")
+	assert.DirExists(t, filepath.Join(dir, "some-game"))
+
+	state, err = loadMirrorState(dir)
+	require.NoError(t, err)
+	assert.NotContains(t, state.Games, 1, "the replaced game is forgotten")
+	assert.Contains(t, state.Games, 2)
+}
+
+func TestExecuteMirror_MovesFolderOfRenamedGame(t *testing.T) {
+	cleanDBTables(t)
+	addTestGame(t, db.NewGameRepository(db.GetDB()), 1, "New Title", `{"title":"New Title"}`)
+	dir := t.TempDir()
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 2, resume: true, gamesConcurrency: 1}
+	old := db.Game{ID: 1, Title: "Old Title", Data: `{"title":"Old Title"}`}
+	renamed := db.Game{ID: 1, Title: "New Title", Data: `{"title":"New Title"}`}
+	state, err := loadMirrorState(dir)
+	require.NoError(t, err)
+	require.NoError(t, state.record(old, "old-title", mirrorFingerprint(old, opts), nil))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "old-title"), 0o755))
+
+	plan := planMirror([]db.Game{renamed}, state, dir, opts)
+	assert.Empty(t, plan.New)
+	require.Len(t, plan.Changed, 1)
+	assert.Equal(t, mirrorRename{From: "old-title", To: "new-title"}, plan.Renamed[1])
+	assert.Empty(t, plan.Prune)
+
+	out := captureStdout2(func() {
+		assert.Nil(t, executeMirror(context.Background(), nil, dir, opts, mirrorOptions{dryRun: true}))
+	})
+	assert.Contains(t, out, "Renamed: New Title (ID 1, folder old-title to new-title)")
+	assert.DirExists(t, filepath.Join(dir, "old-title"))
+
+	// The folder is renamed even though the download then fails without a login.
+	out = captureStdout2(func() {
+		assert.NotNil(t, executeMirror(context.Background(), noLogin, dir, opts, mirrorOptions{}))
+	})
+	assert.Contains(t, out, "Renamed old-title to new-title.")
+	assert.NoDirExists(t, filepath.Join(dir, "old-title"))
+	assert.DirExists(t, filepath.Join(dir, "new-title"))
+}
+
+func TestRemoveMirroredGame_RejectsPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, folder := range []string{"", ".", "..", "a/b", "../x"} {
+		assert.Error(t, removeMirroredGame(dir, folder), folder)
+	}
+}
+
+func TestMirrorFingerprint_FlattenExtras(t *testing.T) {
+	game := db.Game{ID: 1, Title: "Game", Data: `{"title":"Game"}`}
+	opts := downloadOptions{language: "en", platformName: "windows", flatten: true}
+	same, different := true, false
+
+	withSame := opts
+	withSame.flattenExtras = &same
+	assert.Equal(t, mirrorFingerprint(game, opts), mirrorFingerprint(game, withSame))
+
+	withDifferent := opts
+	withDifferent.flattenExtras = &different
+	assert.NotEqual(t, mirrorFingerprint(game, opts), mirrorFingerprint(game, withDifferent))
+}
+
+func TestMirrorFingerprint_NoDLCExtras(t *testing.T) {
+	game := db.Game{ID: 1, Title: "Game", Data: `{"title":"Game"}`}
+	opts := downloadOptions{language: "en", platformName: "windows", extras: true, dlcs: true}
+
+	without := opts
+	without.noDLCExtras = true
+	assert.NotEqual(t, mirrorFingerprint(game, opts), mirrorFingerprint(game, without))
+
+	opts.dlcs, without.dlcs = false, false
+	assert.Equal(t, mirrorFingerprint(game, opts), mirrorFingerprint(game, without), "no DLC extras are downloaded anyway")
+}
+
+func TestMirrorFingerprint_Excludes(t *testing.T) {
+	game := db.Game{ID: 1, Title: "Game", Data: `{"title":"Game"}`}
+	opts := downloadOptions{language: "en", platformName: "windows"}
+
+	excluding := opts
+	excluding.excludes = []string{"*patch*"}
+	assert.NotEqual(t, mirrorFingerprint(game, opts), mirrorFingerprint(game, excluding))
+	excluding.excludes = []string{"*patch*", "*4k*"}
+	assert.NotEqual(t, mirrorFingerprint(game, opts), mirrorFingerprint(game, excluding))
+}
+
+func TestMirrorFingerprint_FileSizes(t *testing.T) {
+	game := db.Game{ID: 1, Title: "Game", Data: `{"title":"Game"}`}
+	opts := downloadOptions{language: "en", platformName: "windows", extras: true, dlcs: true}
+
+	limited := opts
+	limited.maxFileBytes = 4 << 30
+	assert.NotEqual(t, mirrorFingerprint(game, opts), mirrorFingerprint(game, limited))
+}
+
+func TestMirrorFingerprint_FileNames(t *testing.T) {
+	game := db.Game{ID: 1, Title: "Game", Data: `{"title":"Game"}`}
+	opts := downloadOptions{language: "en", platformName: "windows"}
+
+	original := opts
+	original.fileNames = client.FileNamesOriginal
+	assert.Equal(t, mirrorFingerprint(game, opts), mirrorFingerprint(game, original))
+
+	lower := opts
+	lower.fileNames = client.FileNamesLower
+	assert.NotEqual(t, mirrorFingerprint(game, opts), mirrorFingerprint(game, lower))
+}
+
+func TestMirrorFingerprint_Layout(t *testing.T) {
+	game := db.Game{ID: 1, Title: "Game", Data: `{"title":"Game"}`}
+	opts := downloadOptions{language: "en", platformName: "windows"}
+
+	gogg := opts
+	gogg.layout = client.LayoutGogg
+	assert.Equal(t, mirrorFingerprint(game, opts), mirrorFingerprint(game, gogg))
+
+	lgog := opts
+	lgog.layout = client.LayoutLgog
+	assert.NotEqual(t, mirrorFingerprint(game, opts), mirrorFingerprint(game, lgog))
+}
+
+func TestExecuteMirror_KeepsPinnedGames(t *testing.T) {
+	cleanDBTables(t)
+	usePins(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Pinned Game", `{"title":"Pinned Game","changelog":"new"}`)
+	addTestGame(t, repo, 2, "Pinned New Game", `{"title":"Pinned New Game"}`)
+	pins := db.NewPinRepository(db.GetDB())
+	require.NoError(t, pins.Pin(context.Background(), 1, ""))
+	require.NoError(t, pins.Pin(context.Background(), 2, ""))
+
+	dir := t.TempDir()
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 2, resume: true, gamesConcurrency: 1}
+	state, err := loadMirrorState(dir)
+	require.NoError(t, err)
+	require.NoError(t, state.record(db.Game{ID: 1, Title: "Pinned Game", Data: `{"title":"Pinned Game"}`}, "pinned-game", "old", nil))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pinned-game"), 0o755))
+
+	out := captureStdout2(func() {
+		assert.Nil(t, executeMirror(context.Background(), nil, dir, opts, mirrorOptions{dryRun: true}))
+	})
+	assert.Contains(t, out, "Pinned, not updated: Pinned Game (ID 1)")
+	assert.Contains(t, out, "New: Pinned New Game (ID 2)", "a pinned game that was never mirrored is still downloaded")
+	assert.Contains(t, out, "Dry run: 1 new, 0 changed, 0 up to date, 0 no longer in the catalogue.")
+}
+
+func TestExecuteMirror_SkipsGamesWithoutDownloads(t *testing.T) {
+	cleanDBTables(t)
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Unreleased Game", unreleasedGameData)
+	addTestGame(t, repo, 2, "Released Game", `{"title":"Released Game"}`)
+
+	dir := t.TempDir()
+	opts := downloadOptions{language: "en", platformName: "windows", numThreads: 2, resume: true, gamesConcurrency: 1}
+	out := captureStdout2(func() {
+		assert.Nil(t, executeMirror(context.Background(), nil, dir, opts, mirrorOptions{dryRun: true}))
+	})
+	assert.Contains(t, out, "No downloadable content yet: Unreleased Game (ID 1)")
+	assert.Contains(t, out, "Dry run: 1 new, 0 changed, 0 up to date, 0 no longer in the catalogue.")
+
+	out = captureStdout2(func() {
+		executeMirror(context.Background(), noLogin, dir, opts, mirrorOptions{})
+	})
+	assert.Contains(t, out, "Mirror finished: 0 downloaded, 1 failed")
+	assert.Contains(t, out, "1 game(s) were skipped because they have no downloadable content yet")
+	state, err := loadMirrorState(dir)
+	require.NoError(t, err)
+	assert.NotContains(t, state.Games, 1)
+}
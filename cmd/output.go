@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the commands that print games.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// validateOutputFlag checks that format is one of the formats a command supports.
+func validateOutputFlag(format string, supported ...string) *clierr.Error {
+	if !slices.Contains(supported, format) {
+		return clierr.New(clierr.Validation,
+			fmt.Sprintf("Invalid output format %q. Supported formats: %s", format, strings.Join(supported, ", ")), nil)
+	}
+	return nil
+}
+
+// printDocument prints v as an indented JSON or YAML document.
+func printDocument(cmd *cobra.Command, format string, v interface{}) error {
+	var data []byte
+	var err error
+	if format == outputYAML {
+		data, err = yaml.Marshal(integralNumbers(v))
+	} else {
+		data, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	cmd.Println(strings.TrimSuffix(string(data), "\n"))
+	return nil
+}
+
+// integralNumbers returns v with the whole float64 numbers in its maps and slices, which is how numbers are
+// decoded from JSON, turned into int64. YAML would print them in exponent notation otherwise, like an ID of
+// 1207658924 as 1.207658924e+09.
+func integralNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			out[k] = integralNumbers(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, e := range t {
+			out[i] = integralNumbers(e)
+		}
+		return out
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+			return int64(t)
+		}
+	}
+	return v
+}
+
+// gameEntry is a game as printed by list and search in the JSON and YAML formats.
+type gameEntry struct {
+	ID    int    `json:"id" yaml:"id"`
+	Title string `json:"title" yaml:"title"`
+	// NoDownloads is set for games without any file to download, like pre-orders of unreleased games.
+	NoDownloads bool `json:"noDownloads,omitempty" yaml:"noDownloads,omitempty"`
+}
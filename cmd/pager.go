@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// defaultPager is the pager used when PAGER is not set. Unless LESS is set, less is run with the options of
+// defaultLessOptions: quit if the output fits on one screen (F), keep colours (R), leave the output on the screen
+// when it quits (X), and quit on Ctrl-C (K).
+const (
+	defaultPager       = "less"
+	defaultLessOptions = "FRXK"
+)
+
+// stdoutIsTerminal reports whether stdout is a terminal. It is a variable so tests can override it.
+var stdoutIsTerminal = func() bool { return term.IsTerminal(int(os.Stdout.Fd())) }
+
+// pagerCommand returns the command line of the pager: PAGER, or less if PAGER is not set. It returns nil if
+// PAGER is set to nothing or to cat, which turns paging off.
+func pagerCommand() []string {
+	pager, ok := os.LookupEnv("PAGER")
+	if !ok {
+		pager = defaultPager
+	}
+	args := strings.Fields(pager)
+	if len(args) == 0 || args[0] == "cat" {
+		return nil
+	}
+	return args
+}
+
+// printPaged runs print, which prints to cmd, and shows what it printed in the pager if page is set, stdout is a
+// terminal, and there is a pager. Otherwise, the output goes where it would have gone without printPaged, so
+// piped output stays complete and raw. Quitting the pager, with q or Ctrl-C, stops the output.
+func printPaged(cmd *cobra.Command, page bool, print func()) {
+	args := pagerCommand()
+	if !page || args == nil || !stdoutIsTerminal() {
+		print()
+		return
+	}
+
+	out := cmd.OutOrStderr()
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	defer cmd.SetOut(out)
+	print()
+	if buf.Len() == 0 {
+		return
+	}
+
+	pager := exec.Command(args[0], args[1:]...)
+	pager.Stdin, pager.Stdout, pager.Stderr = &buf, os.Stdout, os.Stderr
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		pager.Env = append(os.Environ(), "LESS="+defaultLessOptions)
+	}
+	if err := pager.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The pager ran and was quit; what it showed is what the user wanted to see.
+			return
+		}
+		log.Warn().Err(err).Msgf("Failed to run the pager %q; printing the output instead", args[0])
+		_, _ = io.Copy(out, &buf)
+	}
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// Statuses of a pinned game shown by catalogue pins.
+const (
+	pinStatusCurrent = "pinned"
+	pinStatusNewer   = "pinned, newer version available"
+	pinStatusMissing = "not in catalogue"
+)
+
+// pinEntry is a pinned game as catalogue pins shows it.
+type pinEntry struct {
+	ID               int    `json:"id"`
+	Title            string `json:"title"`
+	PinnedVersion    string `json:"pinned_version"`
+	CatalogueVersion string `json:"catalogue_version"`
+	Status           string `json:"status"`
+}
+
+func pinRepo() db.PinRepository { return db.NewPinRepository(db.GetDB()) }
+
+func pinCmd(repo db.GameRepository) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pin [gameID] [version]",
+		Short: "Pin a game at the version you downloaded",
+		Long: "Pin a game at the version of its installers in the catalogue, or at the given version, so that 'gogg mirror'\n" +
+			"and 'gogg download --all' keep the copy you have instead of updating it. 'gogg catalogue pins' shows\n" +
+			"which pinned games have a newer version. Pinning a pinned game again replaces its version.",
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			gameID, e := parseGameIDArg(args[0])
+			if e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			game, err := repo.GetByID(cmd.Context(), gameID)
+			if err != nil {
+				reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to fetch the game", err))
+				return
+			}
+			if game == nil {
+				reportCliErr(cmd, clierr.New(clierr.NotFound, "Game not found", nil))
+				return
+			}
+			var version string
+			if len(args) == 2 {
+				version = args[1]
+			} else {
+				data, err := client.ParseGameData(game.Data)
+				if err != nil {
+					reportCliErr(cmd, clierr.New(clierr.Validation, "Failed to read the catalogue data of the game; give the version to pin, or run 'gogg catalogue refresh'", err))
+					return
+				}
+				version = client.GameVersion(data)
+			}
+			if err := pinRepo().Pin(cmd.Context(), gameID, version); err != nil {
+				reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to pin the game", err))
+				return
+			}
+			cmd.Printf("Pinned %s (ID %d) at version %s.\n", game.Title, gameID, displayVersion(version))
+		},
+	}
+}
+
+func unpinCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unpin [gameID]",
+		Short: "Remove the pin of a game",
+		Long:  "Remove the pin of a game, so that 'gogg mirror' and 'gogg download --all' update it again.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			gameID, e := parseGameIDArg(args[0])
+			if e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			if err := pinRepo().Unpin(cmd.Context(), gameID); err != nil {
+				reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to unpin the game", err))
+				return
+			}
+			cmd.Printf("Game %d is not pinned.\n", gameID)
+		},
+	}
+}
+
+func pinsCmd(repo db.GameRepository) *cobra.Command {
+	var jsonOutput bool
+	cmd := &cobra.Command{
+		Use:   "pins",
+		Short: "Show the pinned games and which of them have a newer version",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			entries, err := listPins(cmd.Context(), repo)
+			if err != nil {
+				reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to read the pinned games", err))
+				return
+			}
+			if jsonOutput {
+				if err := printDocument(cmd, outputJSON, entries); err != nil {
+					reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to encode the pinned games", err))
+				}
+				return
+			}
+			printPins(cmd, entries)
+		},
+	}
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the pinned games as JSON")
+	return cmd
+}
+
+// listPins returns every pinned game with the version of its installers in the catalogue. A pinned game has a
+// newer version if that version differs from the pinned one.
+func listPins(ctx context.Context, repo db.GameRepository) ([]pinEntry, error) {
+	pins, err := pinRepo().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]pinEntry, 0, len(pins))
+	for _, pin := range pins {
+		entry := pinEntry{ID: pin.GameID, PinnedVersion: pin.Version, Status: pinStatusMissing}
+		game, err := repo.GetByID(ctx, pin.GameID)
+		if err != nil {
+			return nil, err
+		}
+		if game != nil {
+			entry.Title = game.Title
+			entry.Status = pinStatusCurrent
+			if data, err := client.ParseGameData(game.Data); err == nil {
+				entry.CatalogueVersion = client.GameVersion(data)
+				if entry.CatalogueVersion != pin.Version {
+					entry.Status = pinStatusNewer
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func printPins(cmd *cobra.Command, entries []pinEntry) {
+	if len(entries) == 0 {
+		cmd.Println("No games are pinned.")
+		return
+	}
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Game ID", "Title", "Pinned Version", "Catalogue Version", "Status"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	for _, entry := range entries {
+		table.Append([]string{strconv.Itoa(entry.ID), displayTitle(entry.Title, false), displayVersion(entry.PinnedVersion),
+			displayVersion(entry.CatalogueVersion), entry.Status})
+	}
+	table.Render()
+}
+
+// displayVersion returns version, or a note that GOG lists none.
+func displayVersion(version string) string {
+	if version == "" {
+		return "(none)"
+	}
+	return version
+}
+
+// pinnedGames returns the IDs of the pinned games, which 'mirror' and 'download --all' do not update.
+func pinnedGames(ctx context.Context) (map[int]bool, *clierr.Error) {
+	pins, err := pinRepo().List(ctx)
+	if err != nil {
+		return nil, clierr.New(clierr.Internal, "Failed to read the pinned games", err)
+	}
+	pinned := make(map[int]bool, len(pins))
+	for _, pin := range pins {
+		pinned[pin.GameID] = true
+	}
+	return pinned, nil
+}
+
+// printHeldPins prints how many pinned games a run held back, if any.
+func printHeldPins(held int) {
+	if held > 0 {
+		fmt.Printf("%d pinned game(s) were not updated; see 'gogg catalogue pins' and use 'gogg catalogue unpin' to update them.\n", held)
+	}
+}
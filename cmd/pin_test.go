@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/habedi/gogg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usePins makes sure the pins table exists, since some tests replace the database, and empties it.
+func usePins(t *testing.T) {
+	t.Helper()
+	require.NoError(t, db.Db.AutoMigrate(&db.GamePin{}))
+	require.NoError(t, db.Db.Exec("DELETE FROM game_pins").Error)
+	t.Cleanup(func() { _ = db.Db.Exec("DELETE FROM game_pins").Error })
+}
+
+// versionedGameData returns the catalogue data of a game with a Windows installer of the given version.
+func versionedGameData(title, version string) string {
+	return `{"title":"` + title + `","downloads":[["English",{"windows":[{"name":"` + title + `","version":"` + version + `","size":"1 GB"}]}]]}`
+}
+
+func TestPinCmds(t *testing.T) {
+	cleanDBTables(t)
+	usePins(t)
+	t.Cleanup(func() { setLastCliErr(nil) })
+	repo := db.NewGameRepository(db.GetDB())
+	addTestGame(t, repo, 1, "Modded Game", versionedGameData("Modded Game", "1.0"))
+	addTestGame(t, repo, 2, "Other Game", versionedGameData("Other Game", "2.0"))
+
+	output, err := captureCombinedOutput(pinsCmd(repo))
+	require.NoError(t, err)
+	assert.Equal(t, "No games are pinned.\n", output)
+
+	output, err = captureCombinedOutput(pinCmd(repo), "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Pinned Modded Game (ID 1) at version 1.0.\n", output)
+	output, err = captureCombinedOutput(pinCmd(repo), "2", "1.9")
+	require.NoError(t, err)
+	assert.Equal(t, "Pinned Other Game (ID 2) at version 1.9.\n", output)
+
+	output, err = captureCombinedOutput(pinsCmd(repo))
+	require.NoError(t, err)
+	assert.True(t, containsAll(output, []string{"Modded Game", "Other Game", pinStatusNewer}), output)
+
+	// GOG releases a new version of the first game.
+	addTestGame(t, repo, 1, "Modded Game", versionedGameData("Modded Game", "1.1"))
+	output, err = captureCombinedOutput(pinsCmd(repo), "--json")
+	require.NoError(t, err)
+	var entries []pinEntry
+	require.NoError(t, json.Unmarshal([]byte(output), &entries))
+	assert.Equal(t, []pinEntry{
+		{ID: 1, Title: "Modded Game", PinnedVersion: "1.0", CatalogueVersion: "1.1", Status: pinStatusNewer},
+		{ID: 2, Title: "Other Game", PinnedVersion: "1.9", CatalogueVersion: "2.0", Status: pinStatusNewer},
+	}, entries)
+
+	_, err = captureCombinedOutput(pinCmd(repo), "2", "2.0")
+	require.NoError(t, err)
+	output, err = captureCombinedOutput(unpinCmd(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Game 1 is not pinned.\n", output)
+	entries, err = listPins(context.Background(), repo)
+	require.NoError(t, err)
+	assert.Equal(t, []pinEntry{{ID: 2, Title: "Other Game", PinnedVersion: "2.0", CatalogueVersion: "2.0", Status: pinStatusCurrent}}, entries)
+}
+
+func TestPinCmd_UnknownGame(t *testing.T) {
+	cleanDBTables(t)
+	usePins(t)
+	t.Cleanup(func() { setLastCliErr(nil) })
+	repo := db.NewGameRepository(db.GetDB())
+
+	output, _ := captureCombinedOutput(pinCmd(repo), "42")
+	assert.Contains(t, output, "Error: Game not found")
+	require.NotNil(t, getLastCliErr())
+
+	pins, err := db.NewPinRepository(db.GetDB()).List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/spf13/cobra"
+)
+
+// Formats of the download progress of the CLI.
+const (
+	progressFormatBar   = "bar"
+	progressFormatJSONL = "jsonl"
+)
+
+// addProgressFormatFlag adds the --progress-format flag of the commands that download games.
+func addProgressFormatFlag(cmd *cobra.Command, target *string) {
+	cmd.Flags().StringVar(target, "progress-format", progressFormatBar,
+		"How to show the download progress [bar, jsonl]; jsonl prints each progress update as a JSON object on its own line to stdout, and the other messages go to stderr")
+}
+
+// validateProgressFormat checks the --progress-format flag. An empty format means the progress bar.
+func validateProgressFormat(format string) *clierr.Error {
+	switch format {
+	case "", progressFormatBar, progressFormatJSONL:
+		return nil
+	default:
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid progress format %q. Must be one of [%s, %s]",
+			format, progressFormatBar, progressFormatJSONL), nil)
+	}
+}
+
+// jsonlOutput is where the JSON Lines progress goes. Its lock keeps the lines of games downloaded at the same
+// time apart.
+var (
+	jsonlOutput   io.Writer = os.Stdout
+	jsonlOutputMu sync.Mutex
+)
+
+// jsonlProgressUpdate is a progress update of the download with the ID of the game it belongs to.
+type jsonlProgressUpdate struct {
+	client.ProgressUpdate
+	GameID int `json:"game_id"`
+}
+
+// jsonlProgressWriter prints the progress updates of the download of a game as JSON Lines, one object per
+// update, with the game ID added.
+type jsonlProgressWriter struct {
+	gameID int
+}
+
+func (w *jsonlProgressWriter) Write(p []byte) (int, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(p)))
+	for scanner.Scan() {
+		var update client.ProgressUpdate
+		if err := json.Unmarshal(scanner.Bytes(), &update); err != nil {
+			continue
+		}
+		line, err := json.Marshal(jsonlProgressUpdate{ProgressUpdate: update, GameID: w.gameID})
+		if err != nil {
+			continue
+		}
+		jsonlOutputMu.Lock()
+		_, err = fmt.Fprintln(jsonlOutput, string(line))
+		jsonlOutputMu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateProgressFormat(t *testing.T) {
+	assert.Nil(t, validateProgressFormat(""))
+	assert.Nil(t, validateProgressFormat(progressFormatBar))
+	assert.Nil(t, validateProgressFormat(progressFormatJSONL))
+	e := validateProgressFormat("xml")
+	require.NotNil(t, e)
+	assert.Equal(t, clierr.Validation, e.Type)
+}
+
+func TestJSONLProgressWriter(t *testing.T) {
+	var out bytes.Buffer
+	jsonlOutput = &out
+	t.Cleanup(func() { jsonlOutput = os.Stdout })
+
+	w := &jsonlProgressWriter{gameID: 42}
+	fmt.Fprintln(w, `{"type":"start","overall_total":100}`)
+	fmt.Fprint(w, "{\"type\":\"file_progress\",\"file\":\"setup.exe\",\"current\":40,\"total\":100}\nnot json\n")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2, "one line per update, and nothing for what is not an update")
+	var update map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &update))
+	assert.Equal(t, map[string]any{"type": "file_progress", "file": "setup.exe", "current": 40.0, "total": 100.0, "game_id": 42.0}, update)
+	assert.JSONEq(t, `{"type":"start","overall_total":100,"game_id":42}`, lines[0])
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+)
+
+// applyRateSchedule applies the bandwidth schedule next to the database, if there is one, until ctx is done.
+// An invalid schedule is an error, so downloads never run at the wrong rate unnoticed.
+func applyRateSchedule(ctx context.Context) *clierr.Error {
+	schedule, err := client.FindRateSchedule(filepath.Dir(db.Path))
+	if err != nil {
+		return clierr.New(clierr.Validation, err.Error(), err)
+	}
+	if schedule != nil {
+		go schedule.Apply(ctx, client.RateScheduleInterval)
+	}
+	return nil
+}
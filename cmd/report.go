@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// downloadReport collects what the downloads of a run did, from the "file_done" progress updates of their
+// files, for --report.
+type downloadReport struct {
+	mu      sync.Mutex
+	started time.Time
+	games   []*gameReport
+	byID    map[int]*gameReport
+}
+
+// gameReport is the part of a download report about one game.
+type gameReport struct {
+	ID     int          `json:"id"`
+	Title  string       `json:"title"`
+	Status string       `json:"status"`
+	Error  string       `json:"error,omitempty"`
+	Files  []fileReport `json:"files"`
+}
+
+// fileReport is the outcome of one file, as the "file_done" progress update tells it.
+type fileReport struct {
+	Name         string `json:"name"`
+	Outcome      string `json:"outcome"`
+	Size         int64  `json:"size"`
+	Received     int64  `json:"received"`
+	Retries      int    `json:"retries"`
+	Verification string `json:"verification,omitempty"`
+	Checksum     string `json:"checksum,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// reportSummary adds up the games and files of a download report.
+type reportSummary struct {
+	Games         int            `json:"games"`
+	GamesFailed   int            `json:"games_failed"`
+	Files         int            `json:"files"`
+	Downloaded    int            `json:"downloaded"`
+	Complete      int            `json:"complete"`
+	Skipped       int            `json:"skipped"`
+	Failed        int            `json:"failed"`
+	Linked        int            `json:"linked"`
+	Excluded      int            `json:"excluded"`
+	BytesReceived int64          `json:"bytes_received"`
+	AverageSpeed  int64          `json:"average_bytes_per_second"`
+	Retries       int            `json:"retries"`
+	Verification  map[string]int `json:"verification,omitempty"`
+	// BytesSaved is the size of the files linked from the library with --dedupe-library, and TimeSaved the time
+	// their download would have taken at AverageSpeed.
+	BytesSaved int64   `json:"bytes_saved"`
+	TimeSaved  float64 `json:"time_saved_seconds"`
+}
+
+// reportDocument is a download report as it is written to the file.
+type reportDocument struct {
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished"`
+	Duration float64       `json:"duration_seconds"`
+	Summary  reportSummary `json:"summary"`
+	Games    []gameReport  `json:"games"`
+}
+
+func newDownloadReport(started time.Time) *downloadReport {
+	return &downloadReport{started: started, byID: make(map[int]*gameReport)}
+}
+
+// validateReportPath checks the --report flag. The format of the report goes by the extension of its path.
+func validateReportPath(path string) *clierr.Error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".txt":
+		return nil
+	default:
+		return clierr.New(clierr.Validation, fmt.Sprintf("Invalid report path %q. It must end in .json or .txt", path), nil)
+	}
+}
+
+// gameLocked returns the report of game id, adding it if it is not in the report yet. It needs the lock.
+func (r *downloadReport) gameLocked(id int, title string) *gameReport {
+	g := r.byID[id]
+	if g == nil {
+		g = &gameReport{ID: id, Files: []fileReport{}}
+		r.byID[id] = g
+		r.games = append(r.games, g)
+	}
+	if title != "" {
+		g.Title = title
+	}
+	return g
+}
+
+// game adds a game to the report and returns a writer that records the outcomes of its files from its progress
+// updates and passes the updates on to next.
+func (r *downloadReport) game(id int, title string, next io.Writer) io.Writer {
+	r.mu.Lock()
+	r.gameLocked(id, title)
+	r.mu.Unlock()
+	return &reportGameWriter{report: r, gameID: id, next: next}
+}
+
+// finish records the outcome of game id. An empty title keeps the one the game already has in the report.
+func (r *downloadReport) finish(id int, title string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g := r.gameLocked(id, title)
+	g.Status, g.Error = batchStatusCompleted, ""
+	if err != nil {
+		g.Status, g.Error = batchStatusFailed, err.Error()
+	}
+}
+
+func (r *downloadReport) addFile(id int, update client.ProgressUpdate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g := r.gameLocked(id, "")
+	g.Files = append(g.Files, fileReport{
+		Name:         update.FileName,
+		Outcome:      update.Outcome,
+		Size:         update.TotalBytes,
+		Received:     update.Received,
+		Retries:      update.Retries,
+		Verification: update.Verification,
+		Checksum:     update.Checksum,
+		Error:        update.Error,
+	})
+}
+
+// document returns the report of the run that ended at finished, with the files of each game ordered by name.
+func (r *downloadReport) document(finished time.Time) reportDocument {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	doc := reportDocument{Started: r.started, Finished: finished, Duration: finished.Sub(r.started).Seconds(), Games: []gameReport{}}
+	for _, g := range r.games {
+		game := *g
+		game.Files = append([]fileReport{}, g.Files...)
+		sort.SliceStable(game.Files, func(i, j int) bool { return game.Files[i].Name < game.Files[j].Name })
+		doc.Games = append(doc.Games, game)
+
+		doc.Summary.Games++
+		if game.Status == batchStatusFailed {
+			doc.Summary.GamesFailed++
+		}
+		for _, f := range game.Files {
+			doc.Summary.Files++
+			switch f.Outcome {
+			case client.FileDownloaded:
+				doc.Summary.Downloaded++
+			case client.FileComplete:
+				doc.Summary.Complete++
+			case client.FileSkipped:
+				doc.Summary.Skipped++
+			case client.FileFailed:
+				doc.Summary.Failed++
+			case client.FileLinked:
+				doc.Summary.Linked++
+				doc.Summary.BytesSaved += f.Size
+			case client.FileExcluded:
+				doc.Summary.Excluded++
+			}
+			doc.Summary.BytesReceived += f.Received
+			doc.Summary.Retries += f.Retries
+			if f.Verification != "" {
+				if doc.Summary.Verification == nil {
+					doc.Summary.Verification = make(map[string]int)
+				}
+				doc.Summary.Verification[f.Verification]++
+			}
+		}
+	}
+	if doc.Duration > 0 {
+		doc.Summary.AverageSpeed = int64(float64(doc.Summary.BytesReceived) / doc.Duration)
+	}
+	if doc.Summary.AverageSpeed > 0 {
+		doc.Summary.TimeSaved = float64(doc.Summary.BytesSaved) / float64(doc.Summary.AverageSpeed)
+	}
+	return doc
+}
+
+// write writes the report of the run that ended at finished to path, as JSON if path ends in .json and as text
+// otherwise.
+func (r *downloadReport) write(path string, finished time.Time) error {
+	doc := r.document(finished)
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var err error
+		if data, err = json.MarshalIndent(doc, "", "  "); err != nil {
+			return err
+		}
+	} else {
+		data = []byte(doc.text())
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// text returns the report as text for people to read.
+func (doc reportDocument) text() string {
+	var b strings.Builder
+	s := doc.Summary
+	fmt.Fprintln(&b, "Download report")
+	fmt.Fprintf(&b, "Started:  %s\n", doc.Started.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Finished: %s\n", doc.Finished.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Duration: %s\n", time.Duration(doc.Duration*float64(time.Second)).Round(time.Second))
+	fmt.Fprintf(&b, "Games:    %d (%d failed)\n", s.Games, s.GamesFailed)
+	fmt.Fprintf(&b, "Files:    %d (%d downloaded, %d already complete, %d skipped, %d failed)\n",
+		s.Files, s.Downloaded, s.Complete, s.Skipped, s.Failed)
+	if s.Linked > 0 {
+		fmt.Fprintf(&b, "Linked:   %d file(s) from the library, saving %s\n", s.Linked, progress.FormatBytes(s.BytesSaved))
+	}
+	if s.Excluded > 0 {
+		fmt.Fprintf(&b, "Excluded: %d file(s) matching --exclude\n", s.Excluded)
+	}
+	fmt.Fprintf(&b, "Received: %s at %s/s on average\n", progress.FormatBytes(s.BytesReceived), progress.FormatBytes(s.AverageSpeed))
+	fmt.Fprintf(&b, "Retries:  %d\n", s.Retries)
+	if len(s.Verification) > 0 {
+		fmt.Fprintf(&b, "Verified: %d passed, %d mismatched, %d could not be checked\n", s.Verification[client.VerificationPassed],
+			s.Verification[client.VerificationMismatch], s.Verification[client.VerificationUnavailable])
+	}
+	for _, g := range doc.Games {
+		fmt.Fprintf(&b, "\n%s (ID %d): %s\n", g.Title, g.ID, g.Status)
+		if g.Error != "" {
+			fmt.Fprintf(&b, "  Error: %s\n", g.Error)
+		}
+		for _, f := range g.Files {
+			details := []string{progress.FormatBytes(f.Size), "received " + progress.FormatBytes(f.Received)}
+			if f.Retries > 0 {
+				details = append(details, fmt.Sprintf("retried %d time(s)", f.Retries))
+			}
+			if f.Verification != "" {
+				details = append(details, "verification "+f.Verification)
+			}
+			if f.Checksum != "" {
+				details = append(details, "checksum "+f.Checksum)
+			}
+			fmt.Fprintf(&b, "  %-10s %s (%s)\n", f.Outcome, f.Name, strings.Join(details, ", "))
+			if f.Error != "" {
+				fmt.Fprintf(&b, "             %s\n", f.Error)
+			}
+		}
+	}
+	return b.String()
+}
+
+// reportGameWriter passes the progress updates of one game's download on, recording the outcomes of its files
+// in a downloadReport.
+type reportGameWriter struct {
+	report *downloadReport
+	gameID int
+	next   io.Writer
+}
+
+func (w *reportGameWriter) Write(p []byte) (int, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(p)))
+	for scanner.Scan() {
+		var update client.ProgressUpdate
+		if err := json.Unmarshal(scanner.Bytes(), &update); err == nil && update.Type == "file_done" {
+			w.report.addFile(w.gameID, update)
+		}
+	}
+	return w.next.Write(p)
+}
+
+// endDownloadRun prints what linking files from the library saved, if it was used, and writes the report of the
+// run to the --report path, if there is one. A report that cannot be written does not hide how the downloads
+// went, so it only sets the exit code if they succeeded.
+func endDownloadRun(cmd *cobra.Command, opts downloadOptions) {
+	finished := time.Now()
+	if opts.library != nil {
+		printLibrarySavings(statusOutput(opts), opts.report.document(finished).Summary)
+	}
+	if opts.reportPath == "" {
+		return
+	}
+	if err := opts.report.write(opts.reportPath, finished); err != nil {
+		e := clierr.New(clierr.Internal, "Failed to write the download report", err)
+		cmd.PrintErrln("Error: " + e.Message)
+		if getLastCliErr() == nil {
+			setLastCliErr(e)
+		}
+		return
+	}
+	fmt.Fprintf(statusOutput(opts), "Download report written to: %s\n", opts.reportPath)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/habedi/gogg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateReportPath(t *testing.T) {
+	for _, path := range []string{"report.json", "out/REPORT.TXT"} {
+		assert.Nil(t, validateReportPath(path), path)
+	}
+	for _, path := range []string{"report", "report.yaml"} {
+		e := validateReportPath(path)
+		require.NotNil(t, e, path)
+		assert.Contains(t, e.Message, ".json or .txt")
+	}
+}
+
+func TestDownloadReport(t *testing.T) {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	report := newDownloadReport(started)
+	w := report.game(1, "Test Game", io.Discard)
+	for _, update := range []client.ProgressUpdate{
+		{Type: "file_progress", FileName: "setup.exe", CurrentBytes: 100, TotalBytes: 200},
+		{Type: "file_done", FileName: "setup.exe", TotalBytes: 200, Outcome: client.FileDownloaded, Received: 150, Retries: 1,
+			Verification: client.VerificationPassed, Checksum: "abc"},
+		{Type: "file_done", FileName: "manual.pdf", TotalBytes: 50, Outcome: client.FileFailed, Error: "HTTP 500"},
+	} {
+		line, err := json.Marshal(update)
+		require.NoError(t, err)
+		_, err = fmt.Fprintln(w, string(line))
+		require.NoError(t, err)
+	}
+	report.finish(1, "", fmt.Errorf("1 of 2 file(s) failed"))
+	report.finish(2, "Other Game", nil)
+
+	doc := report.document(started.Add(10 * time.Second))
+	assert.Equal(t, 10.0, doc.Duration)
+	assert.Equal(t, reportSummary{Games: 2, GamesFailed: 1, Files: 2, Downloaded: 1, Failed: 1, BytesReceived: 150, AverageSpeed: 15,
+		Retries: 1, Verification: map[string]int{client.VerificationPassed: 1}}, doc.Summary)
+	require.Len(t, doc.Games, 2)
+	assert.Equal(t, "Test Game", doc.Games[0].Title)
+	assert.Equal(t, batchStatusFailed, doc.Games[0].Status)
+	assert.Equal(t, []string{"manual.pdf", "setup.exe"}, []string{doc.Games[0].Files[0].Name, doc.Games[0].Files[1].Name})
+	assert.Equal(t, batchStatusCompleted, doc.Games[1].Status)
+	assert.Empty(t, doc.Games[1].Files)
+
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "report.json")
+	require.NoError(t, report.write(jsonPath, started.Add(10*time.Second)))
+	data, err := os.ReadFile(jsonPath)
+	require.NoError(t, err)
+	var written reportDocument
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, doc.Summary, written.Summary)
+
+	textPath := filepath.Join(dir, "report.txt")
+	require.NoError(t, report.write(textPath, started.Add(10*time.Second)))
+	data, err = os.ReadFile(textPath)
+	require.NoError(t, err)
+	assert.True(t, containsAll(string(data), []string{"Duration: 10s", "Files:    2 (1 downloaded, 0 already complete, 0 skipped, 1 failed)",
+		"Test Game (ID 1): failed", "failed     manual.pdf", "HTTP 500", "retried 1 time(s), verification passed, checksum abc"}), string(data))
+}
+
+func TestDownloadCmd_Report(t *testing.T) {
+	t.Cleanup(func() { setLastCliErr(nil) })
+	dir := t.TempDir()
+	var output string
+	captureStdout2(func() {
+		output, _ = captureCombinedOutput(downloadCmd(noLogin), "1", dir, "--report", filepath.Join(dir, "report.yaml"))
+	})
+	assert.Contains(t, output, "It must end in .json or .txt")
+	assert.NoFileExists(t, filepath.Join(dir, "report.yaml"))
+
+	// The game fails, since there is no login, and the report says so.
+	reportPath := filepath.Join(dir, "report.json")
+	captureStdout2(func() {
+		_, _ = captureCombinedOutput(downloadCmd(noLogin), "1", dir, "--report", reportPath)
+	})
+	data, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+	var doc reportDocument
+	require.NoError(t, json.Unmarshal(data, &doc))
+	require.Len(t, doc.Games, 1)
+	assert.Equal(t, 1, doc.Games[0].ID)
+	assert.Equal(t, batchStatusFailed, doc.Games[0].Status)
+	assert.NotEmpty(t, doc.Games[0].Error)
+}
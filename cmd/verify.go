@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/habedi/gogg/pkg/validation"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+)
+
+// Values of the verify flag of download.
+const (
+	verifyOff   = "off"
+	verifyAfter = "after"
+)
+
+// verifyReport is the result of checking the files of a directory against their checksums.
+type verifyReport struct {
+	Passed     int                       `json:"passed"`
+	Failed     int                       `json:"failed"`
+	Missing    int                       `json:"missing"`
+	Unreadable int                       `json:"unreadable"`
+	Results    []operations.VerifyResult `json:"results"`
+}
+
+func (r verifyReport) ok() bool { return r.Failed+r.Missing+r.Unreadable == 0 }
+
+func verifyCmd() *cobra.Command {
+	var numThreads int
+	var jsonOutput bool
+	cmd := &cobra.Command{
+		Use:   "verify [dir]",
+		Short: "Check downloaded files against the checksums stored with them",
+		Long: "Check the files under a directory against the checksums stored with them: the checksum files of GOG saved\n" +
+			"by 'download --download-checksums', the CHECKSUMS.<algo> files of 'download --write-checksums', and the files\n" +
+			"written by 'gogg hash'. A file with several checksums is checked against GOG's. The files are hashed\n" +
+			"concurrently, and files without a checksum are not checked.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if e := validateThreadsFlag(numThreads); e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			var progress io.Writer
+			if !jsonOutput {
+				progress = cmd.ErrOrStderr()
+			}
+			report, e := verifyDirs(cmd.Context(), []string{args[0]}, numThreads, progress)
+			if e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			if jsonOutput {
+				if err := printDocument(cmd, outputJSON, report); err != nil {
+					reportCliErr(cmd, clierr.New(clierr.Internal, "Failed to encode the result", err))
+					return
+				}
+			} else {
+				printVerifyReport(cmd.OutOrStdout(), args[0], report)
+			}
+			if e := verifyError(report); e != nil {
+				reportCliErr(cmd, e)
+			}
+		},
+	}
+	addThreadsFlag(cmd, &numThreads, validation.LocalWorkload, "hashing")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the result of every file as JSON")
+	return cmd
+}
+
+// verifyDirs checks the files under dirs against their checksums, numThreads at a time, showing the progress on
+// progress unless it is nil.
+func verifyDirs(ctx context.Context, dirs []string, numThreads int, progress io.Writer) (verifyReport, *clierr.Error) {
+	var expected []operations.ExpectedChecksum
+	for _, dir := range dirs {
+		found, err := operations.FindExpectedChecksums(dir)
+		if err != nil {
+			return verifyReport{}, clierr.New(clierr.NotFound, "Failed to read the checksums in "+dir, err)
+		}
+		expected = append(expected, found...)
+	}
+
+	var hashOpts operations.HashOptions
+	var bar *progressbar.ProgressBar
+	if progress != nil && len(expected) > 0 {
+		files := make([]string, len(expected))
+		for i, e := range expected {
+			files[i] = e.Path
+		}
+		bar = newProgressBar(
+			operations.TotalSize(files),
+			progress,
+			progressbar.OptionSetDescription("Verifying..."),
+			progressbar.OptionShowBytes(true),
+			progressbar.OptionThrottle(200*time.Millisecond),
+			progressbar.OptionClearOnFinish(),
+		)
+		var tracker operations.HashProgressTracker
+		hashOpts.Progress = func(file string, hashed, _ int64) {
+			_ = bar.Set64(tracker.Update(file, hashed))
+		}
+	}
+	results, err := operations.VerifyChecksums(ctx, expected, numThreads, hashOpts)
+	if bar != nil {
+		_ = bar.Finish()
+	}
+	if err != nil {
+		return verifyReport{}, clierr.New(clierr.Internal, "Verification cancelled", err)
+	}
+	return verifyReport{
+		Passed:     operations.CountVerifyResults(results, operations.VerifyPassed),
+		Failed:     operations.CountVerifyResults(results, operations.VerifyMismatch),
+		Missing:    operations.CountVerifyResults(results, operations.VerifyMissing),
+		Unreadable: operations.CountVerifyResults(results, operations.VerifyError),
+		Results:    results,
+	}, nil
+}
+
+// printVerifyReport prints the files that did not pass, with paths relative to root, and a summary.
+func printVerifyReport(w io.Writer, root string, report verifyReport) {
+	if len(report.Results) == 0 {
+		fmt.Fprintln(w, "No checksums found to verify; download with --download-checksums or --write-checksums to store them.")
+		return
+	}
+	for _, r := range report.Results {
+		if r.Status == operations.VerifyPassed {
+			continue
+		}
+		path := r.Path
+		if rel, err := filepath.Rel(root, r.Path); err == nil {
+			path = rel
+		}
+		switch r.Status {
+		case operations.VerifyMismatch:
+			fmt.Fprintf(w, "FAILED   %s (%s %s from %s, got %s)\n", path, r.Algo, r.Expected, r.Source, r.Actual)
+		case operations.VerifyMissing:
+			fmt.Fprintf(w, "MISSING  %s (listed in a %s checksum file)\n", path, r.Source)
+		default:
+			fmt.Fprintf(w, "ERROR    %s: %s\n", path, r.Error)
+		}
+	}
+	fmt.Fprintf(w, "Verified %d file(s): %d passed, %d failed, %d missing, %d unreadable.\n",
+		len(report.Results), report.Passed, report.Failed, report.Missing, report.Unreadable)
+}
+
+// verifyError returns the error of a verification with files that did not pass, or nil.
+func verifyError(report verifyReport) *clierr.Error {
+	if report.ok() {
+		return nil
+	}
+	return clierr.New(clierr.Download, fmt.Sprintf("%d file(s) did not pass verification",
+		report.Failed+report.Missing+report.Unreadable), nil)
+}
+
+// validateVerifyFlag checks the verify flag of download. Commands without the flag leave it empty, which is off.
+func validateVerifyFlag(mode string) *clierr.Error {
+	switch mode {
+	case "", verifyOff, verifyAfter:
+		return nil
+	}
+	return clierr.New(clierr.Validation, fmt.Sprintf("Invalid verify mode %q. Must be one of [%s, %s]", mode, verifyAfter, verifyOff), nil)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupVerifyFixture writes a game folder with two files listed in a CHECKSUMS.md5, one of which is corrupt.
+func setupVerifyFixture(t *testing.T) string {
+	t.Helper()
+	gameDir := filepath.Join(t.TempDir(), "test-game")
+	require.NoError(t, os.MkdirAll(gameDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(gameDir, "setup.exe"), []byte("installer"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(gameDir, "patch.exe"), []byte("corrupt"), 0o644))
+	sums := fmt.Sprintf("%x  setup.exe\n%x  patch.exe\n", md5.Sum([]byte("installer")), md5.Sum([]byte("patch")))
+	require.NoError(t, os.WriteFile(filepath.Join(gameDir, "CHECKSUMS.md5"), []byte(sums), 0o644))
+	return gameDir
+}
+
+func TestVerifyCmd_ReportsMismatches(t *testing.T) {
+	t.Cleanup(func() { setLastCliErr(nil) })
+	gameDir := setupVerifyFixture(t)
+
+	output, err := captureCombinedOutput(verifyCmd(), gameDir)
+	require.NoError(t, err)
+	assert.True(t, containsAll(output, []string{
+		"FAILED   patch.exe (md5",
+		"Verified 2 file(s): 1 passed, 1 failed, 0 missing, 0 unreadable.",
+		"1 file(s) did not pass verification",
+	}), output)
+}
+
+func TestVerifyCmd_JSON(t *testing.T) {
+	t.Cleanup(func() { setLastCliErr(nil) })
+	gameDir := setupVerifyFixture(t)
+	require.NoError(t, os.WriteFile(filepath.Join(gameDir, "patch.exe"), []byte("patch"), 0o644))
+
+	output, err := captureCombinedOutput(verifyCmd(), gameDir, "--json")
+	require.NoError(t, err)
+	var report verifyReport
+	require.NoError(t, json.Unmarshal([]byte(output), &report), output)
+	assert.Equal(t, 2, report.Passed)
+	assert.True(t, report.ok())
+}
+
+func TestVerifyDownload_FailsOnMismatch(t *testing.T) {
+	gameDir := setupVerifyFixture(t)
+
+	var err error
+	output := captureStdout2(func() {
+		err = verifyDownload(context.Background(), []string{gameDir}, downloadOptions{verify: verifyAfter, progressFmt: progressFormatJSONL})
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 file(s) did not pass verification")
+	assert.Contains(t, output, "did not pass verification")
+
+	require.NoError(t, os.WriteFile(filepath.Join(gameDir, "patch.exe"), []byte("patch"), 0o644))
+	assert.NoError(t, verifyDownload(context.Background(), []string{gameDir}, downloadOptions{verify: verifyAfter, progressFmt: progressFormatJSONL}))
+}
+
+func TestValidateVerifyFlag(t *testing.T) {
+	for _, mode := range []string{"", verifyOff, verifyAfter} {
+		assert.Nil(t, validateVerifyFlag(mode), mode)
+	}
+	assert.NotNil(t, validateVerifyFlag("before"))
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/clierr"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/habedi/gogg/pkg/validation"
+	"github.com/spf13/cobra"
+)
+
+// writeMetadataOptions holds the flags of the write-metadata command. They override the options guessed from
+// the game folder only when they are given.
+type writeMetadataOptions struct {
+	language     string
+	platformName string
+	extras       bool
+	dlcs         bool
+	flatten      bool
+	skipPatches  bool
+}
+
+func writeMetadataCmd(repo db.GameRepository) *cobra.Command {
+	var opts writeMetadataOptions
+	cmd := &cobra.Command{
+		Use:   "write-metadata [gameID] [dir]",
+		Short: "Write the metadata.json and download_info.json of a game that was downloaded without them",
+		Long: "Write the metadata.json and download_info.json of an existing download, so that updates of the game can be\n" +
+			"detected from now on. The metadata is the game as the catalogue lists it now, so the files in the folder\n" +
+			"are taken to be the current versions. The download options (language, platform, extras, DLCs, layout)\n" +
+			"are guessed from the folder unless given with the flags. The directory can be the download directory or\n" +
+			"the game's own folder.",
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			gameID, e := parseGameIDArg(args[0])
+			if e != nil {
+				reportCliErr(cmd, e)
+				return
+			}
+			if e := writeGameMetadata(cmd, repo, gameID, args[1], opts); e != nil {
+				reportCliErr(cmd, e)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&opts.language, "lang", "l", "en", "Language the game was downloaded in [en, fr, de, es, it, ru, pl, pt-BR, zh-Hans, ja, ko, ...]; all means all languages")
+	cmd.Flags().StringVarP(&opts.platformName, "platform", "p", "windows", "Platform the game was downloaded for [all, auto, windows, mac, linux]")
+	cmd.Flags().BoolVarP(&opts.extras, "extras", "e", true, "Whether the extras were downloaded [true, false]")
+	cmd.Flags().BoolVarP(&opts.dlcs, "dlcs", "d", true, "Whether the DLCs were downloaded [true, false]")
+	cmd.Flags().BoolVarP(&opts.flatten, "flatten", "f", true, "Whether the files were downloaded without platform folders [true, false]")
+	cmd.Flags().BoolVarP(&opts.skipPatches, "skip-patches", "s", false, "Whether the patches were skipped [true, false]")
+	return cmd
+}
+
+func writeGameMetadata(cmd *cobra.Command, repo db.GameRepository, gameID int, dir string, opts writeMetadataOptions) *clierr.Error {
+	if err := validation.ValidatePlatform(opts.platformName); err != nil {
+		return clierr.New(clierr.Validation, "Invalid platform", err)
+	}
+	opts.platformName = validation.ResolvePlatform(opts.platformName)
+	languageFullName, ok := client.LanguageFilter(opts.language)
+	if !ok {
+		return clierr.New(clierr.Validation, "Invalid language code", nil)
+	}
+
+	game, err := repo.GetByID(cmd.Context(), gameID)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Failed to fetch game info", err)
+	}
+	if game == nil {
+		return clierr.New(clierr.NotFound, "Game not found", nil)
+	}
+	gameData, err := client.ParseGameData(game.Data)
+	if err != nil {
+		return clierr.New(clierr.Internal, "Failed to parse game data", err)
+	}
+
+	root := auditRoot(dir, gameData.Title, gameID)
+	info, err := operations.GuessDownloadInfo(root, gameData)
+	if err != nil {
+		return clierr.New(clierr.NotFound, "Failed to read the game directory", err)
+	}
+	info.Threads = validation.DefaultThreads(validation.NetworkWorkload)
+	flags := cmd.Flags()
+	if flags.Changed("lang") {
+		info.Language = languageFullName
+	}
+	if flags.Changed("platform") || info.Platform == "" {
+		info.Platform = opts.platformName
+	}
+	if flags.Changed("extras") {
+		info.Extras = opts.extras
+	}
+	if flags.Changed("dlcs") {
+		info.DLCs = opts.dlcs
+	}
+	if flags.Changed("flatten") {
+		info.Flatten = opts.flatten
+	}
+	if flags.Changed("skip-patches") {
+		info.SkipPatches = opts.skipPatches
+	}
+
+	for _, name := range []string{"metadata.json", "download_info.json"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			if !confirm("The game folder already has a metadata.json or download_info.json. Replace them? [y/N]: ") {
+				cmd.Println("Nothing was written.")
+				return nil
+			}
+			break
+		}
+	}
+	if err := client.WriteGameMetadata(root, gameData, client.DefaultFileModes); err != nil {
+		return clierr.New(clierr.Internal, "Failed to write the metadata.json", err)
+	}
+	if err := client.WriteDownloadInfo(root, info, client.DefaultFileModes); err != nil {
+		return clierr.New(clierr.Internal, "Failed to write the download_info.json", err)
+	}
+
+	cmd.Printf("Wrote metadata.json and download_info.json of %s to %s\n", gameData.Title, root)
+	cmd.Printf("Language: %s, Platform: %s, Extras: %v, DLCs: %v, Flatten: %v, Skip patches: %v\n",
+		info.Language, info.Platform, info.Extras, info.DLCs, info.Flatten, info.SkipPatches)
+	report, err := operations.DiffInstalled(root, gameData, nil, info.Language, info.Platform, info.Extras, info.DLCs)
+	if n := report.Count(operations.InstalledMissing) + report.Count(operations.InstalledIncomplete); err == nil && n > 0 {
+		cmd.Printf("%d file(s) of the catalogue are missing or differ in size, so they may be outdated; see them with 'gogg catalogue info %d --diff-installed \"%s\"'.\n",
+			n, gameID, root)
+	}
+	return nil
+}
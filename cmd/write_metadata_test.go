@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readDownloadInfo(t *testing.T, gameDir string) client.DownloadInfo {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(gameDir, "download_info.json"))
+	require.NoError(t, err)
+	var info client.DownloadInfo
+	require.NoError(t, json.Unmarshal(data, &info))
+	return info
+}
+
+func TestWriteMetadataCmd_GuessesDownloadInfo(t *testing.T) {
+	downloadDir := setupAuditFixture(t)
+	orig := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	defer func() { stdinIsTerminal = orig }()
+
+	output, err := captureCombinedOutput(writeMetadataCmd(db.NewGameRepository(db.GetDB())), "40", downloadDir)
+	require.NoError(t, err)
+	assert.Contains(t, output, "Wrote metadata.json and download_info.json of Audit Game")
+	assert.Contains(t, output, "1 file(s) of the catalogue are missing or differ in size")
+
+	gameDir := filepath.Join(downloadDir, "audit-game")
+	info := readDownloadInfo(t, gameDir)
+	assert.Equal(t, "windows", info.Platform)
+	assert.Equal(t, "English", info.Language)
+	assert.True(t, info.Extras)
+	assert.True(t, info.Flatten)
+
+	data, err := os.ReadFile(filepath.Join(gameDir, "metadata.json"))
+	require.NoError(t, err)
+	var game client.Game
+	require.NoError(t, json.Unmarshal(data, &game))
+	assert.Equal(t, "Audit Game", game.Title)
+}
+
+func TestWriteMetadataCmd_FlagsOverrideGuesses(t *testing.T) {
+	downloadDir := setupAuditFixture(t)
+	orig := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	defer func() { stdinIsTerminal = orig }()
+
+	_, err := captureCombinedOutput(writeMetadataCmd(db.NewGameRepository(db.GetDB())), "40", downloadDir,
+		"--lang=de", "--extras=false")
+	require.NoError(t, err)
+
+	info := readDownloadInfo(t, filepath.Join(downloadDir, "audit-game"))
+	assert.Equal(t, "Deutsch", info.Language)
+	assert.False(t, info.Extras)
+	assert.Equal(t, "windows", info.Platform)
+}
+
+func TestWriteMetadataCmd_UnknownGame(t *testing.T) {
+	cleanDBTables(t)
+	output, err := captureCombinedOutput(writeMetadataCmd(db.NewGameRepository(db.GetDB())), "999", t.TempDir())
+	require.NoError(t, err)
+	assert.Contains(t, output, "Game not found")
+}
@@ -1,8 +1,14 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -91,14 +97,53 @@ func createDBDirectory() error {
 // It returns an error if the database connection fails to open.
 func openDatabase() error {
 	var err error
-	Db, err = gorm.Open(sqlite.Open(Path), &gorm.Config{})
+	Db, err = gorm.Open(sqlite.Open(dataSourceName(Path)), &gorm.Config{})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialize database")
 		return err
 	}
+	if err := registerInFlightTracking(Db); err != nil {
+		log.Error().Err(err).Msg("Failed to register database callbacks")
+		return err
+	}
 	return nil
 }
 
+// busyTimeout is how long a connection waits for a lock held by another connection before failing
+// with "database is locked".
+const busyTimeout = 5 * time.Second
+
+// dataSourceName returns the SQLite connection string for path. Every connection uses write-ahead logging,
+// so readers (like the GUI) do not block writers (like the refresh workers) and vice versa, and waits for
+// busyTimeout when the database is locked.
+func dataSourceName(path string) string {
+	return path + "?_journal_mode=WAL&_busy_timeout=" + strconv.FormatInt(busyTimeout.Milliseconds(), 10)
+}
+
+// inFlight counts the database operations that have started but not finished. Shutdown waits for it to reach zero.
+var inFlight atomic.Int64
+
+// registerInFlightTracking adds callbacks around every kind of GORM operation that keep inFlight up to date.
+func registerInFlightTracking(gdb *gorm.DB) error {
+	start := func(*gorm.DB) { inFlight.Add(1) }
+	finish := func(*gorm.DB) { inFlight.Add(-1) }
+	cb := gdb.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("gogg:start_create", start),
+		cb.Create().After("*").Register("gogg:finish_create", finish),
+		cb.Query().Before("*").Register("gogg:start_query", start),
+		cb.Query().After("*").Register("gogg:finish_query", finish),
+		cb.Update().Before("*").Register("gogg:start_update", start),
+		cb.Update().After("*").Register("gogg:finish_update", finish),
+		cb.Delete().Before("*").Register("gogg:start_delete", start),
+		cb.Delete().After("*").Register("gogg:finish_delete", finish),
+		cb.Row().Before("*").Register("gogg:start_row", start),
+		cb.Row().After("*").Register("gogg:finish_row", finish),
+		cb.Raw().Before("*").Register("gogg:start_raw", start),
+		cb.Raw().After("*").Register("gogg:finish_raw", finish),
+	)
+}
+
 // migrateTables creates the tables if they don't exist.
 // It returns an error if the table migration fails.
 func migrateTables() error {
@@ -111,6 +156,21 @@ func migrateTables() error {
 		log.Error().Err(err).Msg("Failed to auto-migrate database")
 		return err
 	}
+
+	if err := Db.AutoMigrate(&GameTag{}); err != nil {
+		log.Error().Err(err).Msg("Failed to auto-migrate database")
+		return err
+	}
+
+	if err := Db.AutoMigrate(&Setting{}); err != nil {
+		log.Error().Err(err).Msg("Failed to auto-migrate database")
+		return err
+	}
+
+	if err := Db.AutoMigrate(&GamePin{}); err != nil {
+		log.Error().Err(err).Msg("Failed to auto-migrate database")
+		return err
+	}
 	return nil
 }
 
@@ -126,11 +186,75 @@ func configureLogger() {
 	}
 }
 
+// Snapshot writes a consistent copy of the open database to dst, which must not exist yet.
+// Unless includeTokens is true, the stored authentication tokens are removed from the copy.
+func Snapshot(ctx context.Context, dst string, includeTokens bool) error {
+	if Db == nil {
+		return errors.New("database connection is not initialized")
+	}
+	if err := Db.WithContext(ctx).Exec("VACUUM INTO ?", dst).Error; err != nil {
+		return err
+	}
+	if includeTokens {
+		return nil
+	}
+
+	snapshot, err := gorm.Open(sqlite.Open(dst), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return err
+	}
+	sqlDB, err := snapshot.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+	// VACUUM rewrites the file so that no trace of the deleted tokens is left in free pages.
+	if err := snapshot.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&Token{}).Error; err != nil {
+		return err
+	}
+	return snapshot.Exec("VACUUM").Error
+}
+
 // GetDB provides read-only access to the underlying *gorm.DB reference.
 func GetDB() *gorm.DB { return Db }
 
-// Shutdown closes the database ignoring the error (for interrupt handling).
-func Shutdown() { _ = CloseDB() }
+// shutdownPollInterval is how often Shutdown checks whether the in-flight operations have finished.
+const shutdownPollInterval = 10 * time.Millisecond
+
+// Shutdown waits until the in-flight database operations have finished or ctx is done, checkpoints the
+// write-ahead log if the database uses WAL mode, and closes the database.
+// The database is closed even if ctx expires first; in that case the context error is returned.
+func Shutdown(ctx context.Context) error {
+	var waitErr error
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for inFlight.Load() > 0 && waitErr == nil {
+		select {
+		case <-ctx.Done():
+			waitErr = ctx.Err()
+			log.Warn().Int64("operations", inFlight.Load()).Msg("Closing the database with operations still in flight")
+		case <-ticker.C:
+		}
+	}
+	if waitErr == nil {
+		checkpointWAL()
+	}
+	return errors.Join(waitErr, CloseDB())
+}
+
+// checkpointWAL moves the contents of the write-ahead log into the database file if WAL mode is enabled.
+func checkpointWAL() {
+	if Db == nil {
+		return
+	}
+	var mode string
+	if err := Db.Raw("PRAGMA journal_mode").Scan(&mode).Error; err != nil || !strings.EqualFold(mode, "wal") {
+		return
+	}
+	if err := Db.Exec("PRAGMA wal_checkpoint(TRUNCATE)").Error; err != nil {
+		log.Warn().Err(err).Msg("Failed to checkpoint the write-ahead log")
+	}
+}
 
 // CloseDB closes the database connection.
 // It returns an error if the database connection fails to close.
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
@@ -111,7 +112,7 @@ func TestShutdown_IgnoresError(t *testing.T) {
 		}
 	}()
 
-	Shutdown()
+	_ = Shutdown(context.Background())
 }
 
 func TestGetDB_ReturnsGlobalDb(t *testing.T) {
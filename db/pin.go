@@ -0,0 +1,14 @@
+package db
+
+import "time"
+
+// GamePin holds a game at the version the user downloaded, so that it is not updated by 'mirror' and
+// 'download --all', and its updates are shown as available for a pinned game instead of as updates.
+// Pins are local metadata, like tags, and are kept when the catalogue is refreshed.
+type GamePin struct {
+	GameID int `gorm:"primaryKey;autoIncrement:false" json:"game_id"`
+	// Version is the version of the game's installers when it was pinned, as client.GameVersion returns it,
+	// or the version the user gave. It may be empty if GOG lists no version.
+	Version  string    `json:"version"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -13,16 +14,60 @@ type GameRepository interface {
 	Put(ctx context.Context, g Game) error
 	GetByID(ctx context.Context, id int) (*Game, error)
 	List(ctx context.Context) ([]Game, error)
+	// Each calls fn for every game in ID order, loading the games in small batches so that memory use
+	// stays bounded for large catalogues. Iteration stops at the first error returned by fn.
+	Each(ctx context.Context, fn func(Game) error) error
 	SearchByTitle(ctx context.Context, titleSubstr string) ([]Game, error)
 	Clear(ctx context.Context) error
 }
 
+// eachBatchSize is the number of games loaded at a time by GameRepository.Each.
+const eachBatchSize = 100
+
 // TokenRepository defines decoupled operations for token persistence.
 type TokenRepository interface {
 	Get(ctx context.Context) (*Token, error)
 	Upsert(ctx context.Context, token *Token) error
 }
 
+// TagRepository defines operations for the user-defined tags of games.
+// Tags are normalized with NormalizeTag before they are stored or looked up.
+type TagRepository interface {
+	Add(ctx context.Context, gameID int, tag string) error
+	Remove(ctx context.Context, gameID int, tag string) error
+	// ForGame returns the tags of a game in alphabetical order.
+	ForGame(ctx context.Context, gameID int) ([]string, error)
+	// List returns every tag in use in alphabetical order.
+	List(ctx context.Context) ([]string, error)
+	// GameIDs returns the IDs of the games with the given tag.
+	GameIDs(ctx context.Context, tag string) ([]int, error)
+	// Rename renames a tag on every game. Renaming to a tag that is already in use merges the two.
+	Rename(ctx context.Context, oldTag, newTag string) error
+	// Delete removes a tag from every game.
+	Delete(ctx context.Context, tag string) error
+}
+
+// SettingRepository defines operations for the user settings of Gogg, see Setting.
+type SettingRepository interface {
+	// Get returns the value of a setting, and false if it is not set.
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key, value string) error
+	// Unset removes a setting. Removing a setting that is not set is not an error.
+	Unset(ctx context.Context, key string) error
+}
+
+// PinRepository defines operations for the pins of games, see GamePin.
+type PinRepository interface {
+	// Pin pins a game at version, replacing its pin if it is already pinned.
+	Pin(ctx context.Context, gameID int, version string) error
+	// Unpin removes the pin of a game. Unpinning a game that is not pinned is not an error.
+	Unpin(ctx context.Context, gameID int) error
+	// Get returns the pin of a game, or nil if it is not pinned.
+	Get(ctx context.Context, gameID int) (*GamePin, error)
+	// List returns every pin in game ID order.
+	List(ctx context.Context) ([]GamePin, error)
+}
+
 // gormGameRepo is a GORM-backed implementation of GameRepository.
 // Use constructor NewGameRepository to obtain an instance.
 type gormGameRepo struct{ db *gorm.DB }
@@ -31,12 +76,33 @@ type gormGameRepo struct{ db *gorm.DB }
 // Use constructor NewTokenRepository to obtain an instance.
 type gormTokenRepo struct{ db *gorm.DB }
 
+// gormTagRepo is a GORM-backed implementation of TagRepository.
+// Use constructor NewTagRepository to obtain an instance.
+type gormTagRepo struct{ db *gorm.DB }
+
+// gormSettingRepo is a GORM-backed implementation of SettingRepository.
+// Use constructor NewSettingRepository to obtain an instance.
+type gormSettingRepo struct{ db *gorm.DB }
+
+// gormPinRepo is a GORM-backed implementation of PinRepository.
+// Use constructor NewPinRepository to obtain an instance.
+type gormPinRepo struct{ db *gorm.DB }
+
 // NewGameRepository creates a GameRepository. Accepts *gorm.DB to avoid global access.
 func NewGameRepository(db *gorm.DB) GameRepository { return &gormGameRepo{db: db} }
 
 // NewTokenRepository creates a TokenRepository. Accepts *gorm.DB to avoid global access.
 func NewTokenRepository(db *gorm.DB) TokenRepository { return &gormTokenRepo{db: db} }
 
+// NewTagRepository creates a TagRepository. Accepts *gorm.DB to avoid global access.
+func NewTagRepository(db *gorm.DB) TagRepository { return &gormTagRepo{db: db} }
+
+// NewSettingRepository creates a SettingRepository. Accepts *gorm.DB to avoid global access.
+func NewSettingRepository(db *gorm.DB) SettingRepository { return &gormSettingRepo{db: db} }
+
+// NewPinRepository creates a PinRepository. Accepts *gorm.DB to avoid global access.
+func NewPinRepository(db *gorm.DB) PinRepository { return &gormPinRepo{db: db} }
+
 func (r *gormGameRepo) Put(ctx context.Context, g Game) error {
 	return r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&g).Error
 }
@@ -61,6 +127,18 @@ func (r *gormGameRepo) List(ctx context.Context) ([]Game, error) {
 	return games, nil
 }
 
+func (r *gormGameRepo) Each(ctx context.Context, fn func(Game) error) error {
+	var batch []Game
+	return r.db.WithContext(ctx).FindInBatches(&batch, eachBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, g := range batch {
+			if err := fn(g); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
 func (r *gormGameRepo) SearchByTitle(ctx context.Context, titleSubstr string) ([]Game, error) {
 	var games []Game
 	if err := r.db.WithContext(ctx).Where("title LIKE ?", "%"+titleSubstr+"%").Find(&games).Error; err != nil {
@@ -92,3 +170,119 @@ func (r *gormTokenRepo) Upsert(ctx context.Context, token *Token) error {
 		DoUpdates: clause.AssignmentColumns([]string{"access_token", "refresh_token", "expires_at"}),
 	}).Create(token).Error
 }
+
+func (r *gormTagRepo) Add(ctx context.Context, gameID int, tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&GameTag{GameID: gameID, Tag: tag}).Error
+}
+
+func (r *gormTagRepo) Remove(ctx context.Context, gameID int, tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Where("game_id = ? AND tag = ?", gameID, tag).Delete(&GameTag{}).Error
+}
+
+func (r *gormTagRepo) ForGame(ctx context.Context, gameID int) ([]string, error) {
+	var tags []string
+	err := r.db.WithContext(ctx).Model(&GameTag{}).Where("game_id = ?", gameID).Order("tag").Pluck("tag", &tags).Error
+	return tags, err
+}
+
+func (r *gormTagRepo) List(ctx context.Context) ([]string, error) {
+	var tags []string
+	err := r.db.WithContext(ctx).Model(&GameTag{}).Distinct("tag").Order("tag").Pluck("tag", &tags).Error
+	return tags, err
+}
+
+func (r *gormTagRepo) GameIDs(ctx context.Context, tag string) ([]int, error) {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	err = r.db.WithContext(ctx).Model(&GameTag{}).Where("tag = ?", tag).Order("game_id").Pluck("game_id", &ids).Error
+	return ids, err
+}
+
+func (r *gormTagRepo) Rename(ctx context.Context, oldTag, newTag string) error {
+	oldTag, err := NormalizeTag(oldTag)
+	if err != nil {
+		return err
+	}
+	newTag, err = NormalizeTag(newTag)
+	if err != nil {
+		return err
+	}
+	if oldTag == newTag {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("INSERT OR IGNORE INTO game_tags (game_id, tag) SELECT game_id, ? FROM game_tags WHERE tag = ?", newTag, oldTag).Error; err != nil {
+			return err
+		}
+		return tx.Where("tag = ?", oldTag).Delete(&GameTag{}).Error
+	})
+}
+
+func (r *gormTagRepo) Delete(ctx context.Context, tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Where("tag = ?", tag).Delete(&GameTag{}).Error
+}
+
+func (r *gormSettingRepo) Get(ctx context.Context, key string) (string, bool, error) {
+	var setting Setting
+	err := r.db.WithContext(ctx).Where("key = ?", key).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return setting.Value, true, nil
+}
+
+func (r *gormSettingRepo) Set(ctx context.Context, key, value string) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+	}).Create(&Setting{Key: key, Value: value}).Error
+}
+
+func (r *gormSettingRepo) Unset(ctx context.Context, key string) error {
+	return r.db.WithContext(ctx).Where("key = ?", key).Delete(&Setting{}).Error
+}
+
+func (r *gormPinRepo) Pin(ctx context.Context, gameID int, version string) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&GamePin{GameID: gameID, Version: version, PinnedAt: time.Now().UTC()}).Error
+}
+
+func (r *gormPinRepo) Unpin(ctx context.Context, gameID int) error {
+	return r.db.WithContext(ctx).Where("game_id = ?", gameID).Delete(&GamePin{}).Error
+}
+
+func (r *gormPinRepo) Get(ctx context.Context, gameID int) (*GamePin, error) {
+	var pin GamePin
+	err := r.db.WithContext(ctx).First(&pin, "game_id = ?", gameID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pin, nil
+}
+
+func (r *gormPinRepo) List(ctx context.Context) ([]GamePin, error) {
+	var pins []GamePin
+	err := r.db.WithContext(ctx).Order("game_id").Find(&pins).Error
+	return pins, err
+}
@@ -2,7 +2,12 @@ package db_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/habedi/gogg/db"
@@ -43,6 +48,89 @@ func TestGameRepositoryBasicCRUD(t *testing.T) {
 	require.Len(t, all, 0)
 }
 
+func TestGameRepositoryEach(t *testing.T) {
+	temp := t.TempDir()
+	db.Path = filepath.Join(temp, "games.db")
+	require.NoError(t, db.InitDB())
+	t.Cleanup(func() { _ = db.CloseDB() })
+
+	repo := db.NewGameRepository(db.GetDB())
+	ctx := context.Background()
+	for id := 250; id >= 1; id-- {
+		require.NoError(t, repo.Put(ctx, db.Game{ID: id, Title: fmt.Sprintf("Game %d", id), Data: "{}"}))
+	}
+
+	// Visits every game in ID order across batches
+	var ids []int
+	require.NoError(t, repo.Each(ctx, func(g db.Game) error {
+		ids = append(ids, g.ID)
+		return nil
+	}))
+	require.Len(t, ids, 250)
+	for i, id := range ids {
+		require.Equal(t, i+1, id)
+	}
+
+	// Stops at the first error
+	stop := errors.New("stop")
+	visited := 0
+	err := repo.Each(ctx, func(g db.Game) error {
+		visited++
+		if visited == 3 {
+			return stop
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, stop)
+	require.Equal(t, 3, visited)
+}
+
+func TestGameRepositoryConcurrentReadWrite(t *testing.T) {
+	temp := t.TempDir()
+	db.Path = filepath.Join(temp, "games.db")
+	require.NoError(t, db.InitDB())
+	t.Cleanup(func() { _ = db.CloseDB() })
+
+	var mode string
+	require.NoError(t, db.GetDB().Raw("PRAGMA journal_mode").Scan(&mode).Error)
+	require.Equal(t, "wal", strings.ToLower(mode))
+
+	repo := db.NewGameRepository(db.GetDB())
+	ctx := context.Background()
+	const writers, readers, perWriter = 4, 4, 50
+	errs := make(chan error, writers*perWriter+readers*perWriter)
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				id := w*perWriter + i + 1
+				errs <- repo.Put(ctx, db.Game{ID: id, Title: fmt.Sprintf("Game %d", id), Data: "{}"})
+			}
+		}(w)
+	}
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				_, err := repo.List(ctx)
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	all, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, writers*perWriter)
+}
+
 func TestTokenRepositoryUpsertAndGet(t *testing.T) {
 	temp := t.TempDir()
 	db.Path = filepath.Join(temp, "games.db")
@@ -66,3 +154,178 @@ func TestTokenRepositoryUpsertAndGet(t *testing.T) {
 	require.NotNil(t, tok)
 	require.Equal(t, "a", tok.AccessToken)
 }
+
+// setupLargeCatalogue fills a fresh database with games whose data blobs are about 20 KB each.
+func setupLargeCatalogue(b *testing.B, games int) db.GameRepository {
+	b.Helper()
+	db.Path = filepath.Join(b.TempDir(), "games.db")
+	require.NoError(b, db.InitDB())
+	b.Cleanup(func() { _ = db.CloseDB() })
+
+	repo := db.NewGameRepository(db.GetDB())
+	data := `{"description":"` + strings.Repeat("x", 20*1024) + `"}`
+	for id := 1; id <= games; id++ {
+		require.NoError(b, repo.Put(context.Background(), db.Game{ID: id, Title: fmt.Sprintf("Game %d", id), Data: data}))
+	}
+	return repo
+}
+
+func heapInUse() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapInuse
+}
+
+// BenchmarkGameRepositoryList and BenchmarkGameRepositoryEach report the peak heap in use while
+// visiting a large catalogue; List holds every game at once while Each only holds one batch.
+func BenchmarkGameRepositoryList(b *testing.B) {
+	repo := setupLargeCatalogue(b, 2000)
+	ctx := context.Background()
+	var peak uint64
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		games, err := repo.List(ctx)
+		require.NoError(b, err)
+		if h := heapInUse(); h > peak {
+			peak = h
+		}
+		runtime.KeepAlive(games)
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MiB")
+}
+
+func BenchmarkGameRepositoryEach(b *testing.B) {
+	repo := setupLargeCatalogue(b, 2000)
+	ctx := context.Background()
+	var peak uint64
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		visited := 0
+		require.NoError(b, repo.Each(ctx, func(g db.Game) error {
+			visited++
+			if visited%100 == 0 {
+				if h := heapInUse(); h > peak {
+					peak = h
+				}
+			}
+			return nil
+		}))
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MiB")
+}
+
+func TestTagRepositoryCRUD(t *testing.T) {
+	temp := t.TempDir()
+	db.Path = filepath.Join(temp, "games.db")
+	require.NoError(t, db.InitDB())
+	t.Cleanup(func() { _ = db.CloseDB() })
+
+	tags := db.NewTagRepository(db.GetDB())
+	ctx := context.Background()
+
+	require.NoError(t, tags.Add(ctx, 1, "  to   play "))
+	require.NoError(t, tags.Add(ctx, 1, "to play"), "adding a tag twice is a no-op")
+	require.NoError(t, tags.Add(ctx, 1, "favourite"))
+	require.NoError(t, tags.Add(ctx, 2, "to play"))
+	require.Error(t, tags.Add(ctx, 3, "   "))
+	require.Error(t, tags.Add(ctx, 3, strings.Repeat("x", 65)))
+
+	got, err := tags.ForGame(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"favourite", "to play"}, got)
+
+	all, err := tags.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"favourite", "to play"}, all)
+
+	ids, err := tags.GameIDs(ctx, "to play")
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, ids)
+
+	// Renaming into an existing tag merges the two.
+	require.NoError(t, tags.Rename(ctx, "favourite", "to play"))
+	all, err = tags.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"to play"}, all)
+
+	require.NoError(t, tags.Rename(ctx, "to play", "backlog"))
+	require.NoError(t, tags.Remove(ctx, 2, "backlog"))
+	ids, err = tags.GameIDs(ctx, "backlog")
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, ids)
+
+	// Tags are kept when the catalogue is cleared.
+	require.NoError(t, db.NewGameRepository(db.GetDB()).Clear(ctx))
+	got, err = tags.ForGame(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"backlog"}, got)
+
+	require.NoError(t, tags.Delete(ctx, "backlog"))
+	all, err = tags.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, all)
+}
+
+func TestSettingRepository(t *testing.T) {
+	temp := t.TempDir()
+	db.Path = filepath.Join(temp, "games.db")
+	require.NoError(t, db.InitDB())
+	t.Cleanup(func() { _ = db.CloseDB() })
+
+	settings := db.NewSettingRepository(db.GetDB())
+	ctx := context.Background()
+
+	_, ok, err := settings.Get(ctx, db.SettingDownloadDir)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, settings.Set(ctx, db.SettingDownloadDir, "/games"))
+	require.NoError(t, settings.Set(ctx, db.SettingDownloadDir, "/library/games"), "setting a value again replaces it")
+	value, ok, err := settings.Get(ctx, db.SettingDownloadDir)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "/library/games", value)
+
+	require.NoError(t, settings.Unset(ctx, db.SettingDownloadDir))
+	require.NoError(t, settings.Unset(ctx, db.SettingDownloadDir), "unsetting a setting that is not set is a no-op")
+	_, ok, err = settings.Get(ctx, db.SettingDownloadDir)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestPinRepository(t *testing.T) {
+	temp := t.TempDir()
+	db.Path = filepath.Join(temp, "games.db")
+	require.NoError(t, db.InitDB())
+	t.Cleanup(func() { _ = db.CloseDB() })
+
+	pins := db.NewPinRepository(db.GetDB())
+	ctx := context.Background()
+
+	pin, err := pins.Get(ctx, 1)
+	require.NoError(t, err)
+	require.Nil(t, pin)
+
+	require.NoError(t, pins.Pin(ctx, 2, "1.0"))
+	require.NoError(t, pins.Pin(ctx, 1, "2.0"))
+	require.NoError(t, pins.Pin(ctx, 1, "2.1"), "pinning a pinned game again replaces its pin")
+	pin, err = pins.Get(ctx, 1)
+	require.NoError(t, err)
+	require.NotNil(t, pin)
+	require.Equal(t, "2.1", pin.Version)
+	require.False(t, pin.PinnedAt.IsZero())
+
+	list, err := pins.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, 1, list[0].GameID)
+	require.Equal(t, 2, list[1].GameID)
+
+	require.NoError(t, pins.Unpin(ctx, 1))
+	require.NoError(t, pins.Unpin(ctx, 1), "unpinning a game that is not pinned is a no-op")
+	pin, err = pins.Get(ctx, 1)
+	require.NoError(t, err)
+	require.Nil(t, pin)
+}
@@ -0,0 +1,13 @@
+package db
+
+// Keys of the settings stored with SettingRepository.
+const (
+	// SettingDownloadDir is the download directory used when a command is not given one.
+	SettingDownloadDir = "download_dir"
+)
+
+// Setting is a user setting of Gogg, like the default download directory, stored by its key.
+type Setting struct {
+	Key   string `gorm:"primaryKey" json:"key"`
+	Value string `json:"value"`
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// slowInsert inserts many rows with one statement so that the write is still running when Shutdown is called.
+const slowInsert = `WITH RECURSIVE seq(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < 300000)
+INSERT INTO games (id, title, data) SELECT n, 'Game ' || n, '{}' FROM seq`
+
+func TestShutdown_WaitsForInFlightWrite(t *testing.T) {
+	oldPath, oldDb := Path, Db
+	t.Cleanup(func() { Path, Db = oldPath, oldDb })
+	Path = filepath.Join(t.TempDir(), "games.db")
+	if err := InitDB(); err != nil {
+		t.Fatalf("InitDB() error: %v", err)
+	}
+
+	writeErr := make(chan error, 1)
+	go func() { writeErr <- Db.Exec(slowInsert).Error }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for inFlight.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("write did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error: %v", err)
+	}
+	if err := <-writeErr; err != nil {
+		t.Fatalf("in-flight write failed: %v", err)
+	}
+
+	if err := InitDB(); err != nil {
+		t.Fatalf("reopening the database: %v", err)
+	}
+	defer func() { _ = CloseDB() }()
+	var count int64
+	if err := Db.Model(&Game{}).Count(&count).Error; err != nil {
+		t.Fatalf("Count() error: %v", err)
+	}
+	if count != 300000 {
+		t.Fatalf("expected the in-flight write to be committed, got %d rows", count)
+	}
+}
+
+func TestShutdown_TimesOut(t *testing.T) {
+	oldDb := Db
+	t.Cleanup(func() { Db = oldDb; inFlight.Store(0) })
+	Db = nil
+	inFlight.Store(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := Shutdown(ctx); err == nil {
+		t.Fatal("expected Shutdown() to report the expired context")
+	}
+}
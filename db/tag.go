@@ -0,0 +1,29 @@
+package db
+
+import (
+	"errors"
+	"strings"
+)
+
+// maxTagLength is the maximum length of a tag in bytes.
+const maxTagLength = 64
+
+// GameTag assigns a user-defined tag (like "to play" or "installed") to a game.
+// Tags are local metadata, separate from GOG's genres, and are kept when the catalogue is refreshed.
+type GameTag struct {
+	GameID int    `gorm:"primaryKey;autoIncrement:false" json:"game_id"`
+	Tag    string `gorm:"primaryKey;index" json:"tag"`
+}
+
+// NormalizeTag trims a tag and collapses runs of whitespace inside it.
+// It returns an error if the tag is empty or too long.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.Join(strings.Fields(tag), " ")
+	if tag == "" {
+		return "", errors.New("tag cannot be empty")
+	}
+	if len(tag) > maxTagLength {
+		return "", errors.New("tag is too long (max 64 characters)")
+	}
+	return tag, nil
+}
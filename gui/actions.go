@@ -2,10 +2,15 @@ package gui
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -17,7 +22,17 @@ import (
 	"github.com/habedi/gogg/db"
 )
 
+// refreshThreads is the number of games fetched at the same time when refreshing the catalogue,
+// matching the default of the CLI's catalogue refresh.
+const refreshThreads = 10
+
 func RefreshCatalogueAction(win fyne.Window, authService *auth.Service, onFinish func()) {
+	refreshCatalogue(win, authService, nil, onFinish)
+}
+
+// refreshCatalogue refreshes the catalogue, or only the games with ids if ids is not nil, and then shows the
+// outcome, listing the games that failed with a button to try them again.
+func refreshCatalogue(win fyne.Window, authService *auth.Service, ids []int, onFinish func()) {
 	progress := widget.NewProgressBar()
 	statusLabel := widget.NewLabel("Preparing to refresh...")
 	ctx, cancel := context.WithCancel(context.Background())
@@ -34,8 +49,19 @@ func RefreshCatalogueAction(win fyne.Window, authService *auth.Service, onFinish
 			})
 		}
 
+		var failuresMu sync.Mutex
+		var failures []client.RefreshFailure
+		opts := []client.RefreshOption{client.WithRefreshFailures(func(f client.RefreshFailure) {
+			failuresMu.Lock()
+			defer failuresMu.Unlock()
+			failures = append(failures, f)
+		})}
+		if ids != nil {
+			opts = append(opts, client.WithRefreshGameIDs(ids))
+		}
+
 		repo := db.NewGameRepository(db.GetDB())
-		err := client.RefreshCatalogue(ctx, authService, repo, 10, progressCb)
+		err := client.RefreshCatalogue(ctx, authService, repo, refreshThreads, progressCb, opts...)
 
 		runOnMain(func() {
 			dlg.Hide()
@@ -53,11 +79,13 @@ func RefreshCatalogueAction(win fyne.Window, authService *auth.Service, onFinish
 			} else if err != nil {
 				showErrorDialog(win, "Failed to refresh catalogue", err)
 			} else {
-				games, dbErr := repo.List(context.Background())
-				if dbErr != nil {
-					dialog.ShowInformation("Success", "Successfully refreshed catalogue.", win)
+				successMsg := "Successfully refreshed catalogue."
+				if games, dbErr := repo.List(context.Background()); dbErr == nil {
+					successMsg = fmt.Sprintf("Successfully refreshed catalogue.\nYour library now contains %d games.", len(games))
+				}
+				if len(failures) > 0 {
+					showRefreshFailures(win, authService, successMsg, failures, onFinish)
 				} else {
-					successMsg := fmt.Sprintf("Successfully refreshed catalogue.\nYour library now contains %d games.", len(games))
 					dialog.ShowInformation("Success", successMsg, win)
 				}
 				SignalCatalogueUpdated() // Signal that the update is complete
@@ -68,7 +96,35 @@ func RefreshCatalogueAction(win fyne.Window, authService *auth.Service, onFinish
 	}()
 }
 
-func ExportCatalogueAction(win fyne.Window, format string) {
+// showRefreshFailures shows the games that a refresh could not fetch and why, with a button that refreshes
+// only those games again.
+func showRefreshFailures(win fyne.Window, authService *auth.Service, summary string, failures []client.RefreshFailure, onFinish func()) {
+	sort.Slice(failures, func(i, j int) bool { return failures[i].GameID < failures[j].GameID })
+	ids := make([]int, len(failures))
+	lines := make([]string, len(failures))
+	for i, f := range failures {
+		ids[i] = f.GameID
+		lines[i] = fmt.Sprintf("Game %d: %s (%v)", f.GameID, f.Reason, f.Err)
+	}
+	list := widget.NewLabel(strings.Join(lines, "\n"))
+	list.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(
+		widget.NewLabel(fmt.Sprintf("%s\n%d game(s) could not be fetched:", summary, len(failures))),
+		nil, nil, nil,
+		container.NewVScroll(list),
+	)
+	d := dialog.NewCustomConfirm("Refresh Finished with Errors", "Retry Failed", "Close", content, func(retry bool) {
+		if retry {
+			refreshCatalogue(win, authService, ids, onFinish)
+		}
+	}, win)
+	d.Resize(fyne.NewSize(550, 400))
+	d.Show()
+}
+
+// ExportCatalogueAction asks for a file and exports games to it as CSV or JSON.
+// If games is nil, the whole catalogue is exported.
+func ExportCatalogueAction(win fyne.Window, format string, games []db.Game) {
 	defaultName := fmt.Sprintf("gogg_catalogue.%s", format)
 	fileDialog := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
 		if err != nil {
@@ -80,39 +136,22 @@ func ExportCatalogueAction(win fyne.Window, format string) {
 		}
 		defer uc.Close()
 
-		games, err := db.GetCatalogue()
-		if err != nil {
-			showErrorDialog(win, "Failed to read catalogue from database", err)
-			return
+		if games == nil {
+			games, err = db.GetCatalogue()
+			if err != nil {
+				showErrorDialog(win, "Failed to read catalogue from database", err)
+				return
+			}
 		}
 		if len(games) == 0 {
-			dialog.ShowInformation("Info", "Catalogue is empty. Nothing to export.", win)
+			dialog.ShowInformation("Info", "No games to export.", win)
 			return
 		}
 
-		var exportErr error
-		if format == "json" {
-			enc := json.NewEncoder(uc)
-			enc.SetIndent("", "  ")
-			exportErr = enc.Encode(games)
-		} else { // csv
-			if _, err := fmt.Fprintln(uc, "ID,Title"); err != nil {
-				exportErr = err
-			} else {
-				for _, g := range games {
-					title := strings.ReplaceAll(g.Title, "\"", "\"\"")
-					if _, err := fmt.Fprintf(uc, "%d,\"%s\"\n", g.ID, title); err != nil {
-						exportErr = err
-						break
-					}
-				}
-			}
-		}
-
-		if exportErr != nil {
+		if exportErr := writeGamesExport(uc, format, games); exportErr != nil {
 			showErrorDialog(win, "Failed to write export file", exportErr)
 		} else {
-			dialog.ShowInformation("Success", "Data exported successfully.", win)
+			dialog.ShowInformation("Success", fmt.Sprintf("Exported %d game(s) successfully.", len(games)), win)
 		}
 	}, win)
 	fileDialog.SetFileName(defaultName)
@@ -121,6 +160,26 @@ func ExportCatalogueAction(win fyne.Window, format string) {
 	fileDialog.Show()
 }
 
+// writeGamesExport writes games to w as a JSON array (format "json") or as a CSV list of IDs and titles.
+func writeGamesExport(w io.Writer, format string, games []db.Game) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(games)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"ID", "Title"}); err != nil {
+		return err
+	}
+	for _, g := range games {
+		if err := cw.Write([]string{strconv.Itoa(g.ID), g.Title}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
 func showErrorDialog(win fyne.Window, msg string, err error) {
 	detail := msg
 	if err != nil {
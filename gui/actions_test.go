@@ -0,0 +1,25 @@
+package gui
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/habedi/gogg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteGamesExport(t *testing.T) {
+	games := []db.Game{{ID: 1, Title: "First, Game", Data: "{}"}, {ID: 2, Title: "Second", Data: "{}"}}
+
+	var csvOut bytes.Buffer
+	require.NoError(t, writeGamesExport(&csvOut, "csv", games))
+	assert.Equal(t, "ID,Title\n1,\"First, Game\"\n2,Second\n", csvOut.String())
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, writeGamesExport(&jsonOut, "json", games[1:]))
+	var decoded []db.Game
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	assert.Equal(t, games[1:], decoded)
+}
@@ -1,12 +1,19 @@
 package gui
 
 import (
+	"errors"
+	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 
 	"github.com/rs/zerolog/log"
 )
 
+// linuxTerminals are the terminal emulators tried, in order, when $TERMINAL is not set.
+var linuxTerminals = []string{"x-terminal-emulator", "gnome-terminal", "konsole", "xfce4-terminal", "kitty", "alacritty", "xterm"}
+
 // openFolder opens the specified path in the system's default file explorer.
 func openFolder(path string) {
 	var cmd *exec.Cmd
@@ -22,3 +29,86 @@ func openFolder(path string) {
 		log.Error().Err(err).Str("path", path).Msg("Failed to open folder")
 	}
 }
+
+// revealInFileManager opens the parent of path in the system's file manager with path selected.
+// On Linux, file managers that do not implement the freedesktop FileManager1 interface just open the parent folder.
+func revealInFileManager(path string) {
+	args := revealArgs(runtime.GOOS, path)
+	cmd := exec.Command(args[0], args[1:]...)
+	var err error
+	switch runtime.GOOS {
+	case "windows", "darwin":
+		// Explorer exits with a non-zero status even when it succeeds, so only starting it is checked.
+		err = startDetached(cmd)
+	default:
+		// The D-Bus call returns quickly and fails if no file manager implements the interface.
+		if err = cmd.Run(); err != nil {
+			err = startDetached(exec.Command("xdg-open", filepath.Dir(path)))
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Failed to reveal folder")
+	}
+}
+
+// openTerminal opens a terminal window with path as the working directory.
+func openTerminal(path string) {
+	args, err := terminalArgs(runtime.GOOS, os.Getenv("TERMINAL"), exec.LookPath)
+	if err == nil {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = path
+		if runtime.GOOS == "darwin" {
+			cmd.Args = append(cmd.Args, path)
+		}
+		err = startDetached(cmd)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Failed to open terminal")
+	}
+}
+
+// revealArgs returns the command that selects path in the file manager of the given OS.
+func revealArgs(goos, path string) []string {
+	switch goos {
+	case "windows":
+		return []string{"explorer", "/select," + path}
+	case "darwin":
+		return []string{"open", "-R", path}
+	default:
+		uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+		return []string{"dbus-send", "--session", "--print-reply", "--dest=org.freedesktop.FileManager1",
+			"--type=method_call", "/org/freedesktop/FileManager1", "org.freedesktop.FileManager1.ShowItems",
+			"array:string:" + uri, "string:"}
+	}
+}
+
+// terminalArgs returns the command that opens a terminal on the given OS. The terminal starts in the
+// working directory of the command (on macOS, the directory is passed as an extra argument).
+// On Linux, the terminal named by $TERMINAL is preferred over the known terminal emulators.
+func terminalArgs(goos, preferred string, lookPath func(string) (string, error)) ([]string, error) {
+	switch goos {
+	case "windows":
+		return []string{"cmd", "/C", "start", "cmd"}, nil
+	case "darwin":
+		return []string{"open", "-a", "Terminal"}, nil
+	}
+	candidates := linuxTerminals
+	if preferred != "" {
+		candidates = append([]string{preferred}, candidates...)
+	}
+	for _, name := range candidates {
+		if p, err := lookPath(name); err == nil {
+			return []string{p}, nil
+		}
+	}
+	return nil, errors.New("no terminal emulator found; set the TERMINAL environment variable")
+}
+
+// startDetached starts cmd without waiting for it to exit, reaping it in the background.
+func startDetached(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
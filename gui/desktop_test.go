@@ -0,0 +1,46 @@
+package gui
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevealArgs(t *testing.T) {
+	assert.Equal(t, []string{"explorer", `/select,C:\Games\Some Game`}, revealArgs("windows", `C:\Games\Some Game`))
+	assert.Equal(t, []string{"open", "-R", "/games/Some Game"}, revealArgs("darwin", "/games/Some Game"))
+
+	args := revealArgs("linux", "/games/Some Game")
+	assert.Equal(t, "dbus-send", args[0])
+	assert.Contains(t, args, "array:string:file:///games/Some%20Game")
+}
+
+func TestTerminalArgs(t *testing.T) {
+	installed := func(names ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			for _, n := range names {
+				if n == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+	}
+
+	args, err := terminalArgs("darwin", "", installed())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"open", "-a", "Terminal"}, args)
+
+	args, err = terminalArgs("linux", "", installed("xterm", "konsole"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/usr/bin/konsole"}, args, "known terminals are tried in order")
+
+	args, err = terminalArgs("linux", "foot", installed("xterm", "foot"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/usr/bin/foot"}, args, "$TERMINAL takes precedence")
+
+	_, err = terminalArgs("linux", "", installed())
+	assert.ErrorContains(t, err, "no terminal emulator found")
+}
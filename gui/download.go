@@ -6,43 +6,32 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/data/binding"
-	"github.com/habedi/gogg/auth"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
 	"github.com/habedi/gogg/client"
-	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/habedi/gogg/pkg/progress"
+	"github.com/habedi/gogg/pkg/validation"
 	"github.com/rs/zerolog/log"
 )
 
 var (
 	ErrDownloadInProgress = errors.New("download already in progress")
+	ErrOffline            = errors.New("downloads are not available in offline mode")
 	activeDownloads       = make(map[int]struct{})
 	activeDownloadsMutex  = &sync.Mutex{}
 )
 
-func formatBytes(b int64) string {
-	const unit = 1024
-	if b < unit {
-		return fmt.Sprintf("%d B", b)
-	}
-	div, exp := int64(unit), 0
-	for n := b / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
-}
-
 type progressUpdater struct {
 	task              *DownloadTask
 	totalBytes        int64
@@ -51,10 +40,7 @@ type progressUpdater struct {
 	fileProgress      map[string]struct{ current, total int64 }
 	mu                sync.Mutex
 	incompleteMessage []byte
-	lastUpdateTime    time.Time
-	lastBytes         int64
-	speeds            []float64
-	speedAvgSize      int
+	speed             progress.SpeedEstimator
 }
 
 func (pu *progressUpdater) Write(p []byte) (n int, err error) {
@@ -76,7 +62,7 @@ func (pu *progressUpdater) Write(p []byte) (n int, err error) {
 		switch update.Type {
 		case "start":
 			pu.totalBytes = update.OverallTotalBytes
-			pu.lastUpdateTime = time.Now()
+			pu.speed.Reset(time.Now())
 		case "file_progress":
 			diff := update.CurrentBytes - pu.fileBytes[update.FileName]
 			pu.downloadedBytes += diff
@@ -104,42 +90,11 @@ func (pu *progressUpdater) Write(p []byte) (n int, err error) {
 }
 
 func (pu *progressUpdater) updateSpeedAndETA() {
-	now := time.Now()
-	elapsed := now.Sub(pu.lastUpdateTime).Seconds()
-
-	if elapsed < 1.0 {
+	avgSpeed, ok := pu.speed.Update(time.Now(), pu.downloadedBytes)
+	if !ok {
 		return
 	}
-
-	bytesSinceLast := pu.downloadedBytes - pu.lastBytes
-	currentSpeed := float64(bytesSinceLast) / elapsed
-
-	if pu.speedAvgSize == 0 {
-		pu.speedAvgSize = 5
-	}
-	pu.speeds = append(pu.speeds, currentSpeed)
-	if len(pu.speeds) > pu.speedAvgSize {
-		pu.speeds = pu.speeds[1:]
-	}
-
-	var totalSpeed float64
-	for _, s := range pu.speeds {
-		totalSpeed += s
-	}
-	avgSpeed := totalSpeed / float64(len(pu.speeds))
-
-	pu.lastUpdateTime = now
-	pu.lastBytes = pu.downloadedBytes
-
-	detailsStr := fmt.Sprintf("Speed: %s/s", formatBytes(int64(avgSpeed)))
-	remainingBytes := pu.totalBytes - pu.downloadedBytes
-	if avgSpeed > 0 && remainingBytes > 0 {
-		etaSeconds := float64(remainingBytes) / avgSpeed
-		duration, _ := time.ParseDuration(fmt.Sprintf("%fs", math.Round(etaSeconds)))
-		detailsStr += fmt.Sprintf(" | ETA: %s", duration.Truncate(time.Second).String())
-	}
-
-	_ = pu.task.Details.Set(detailsStr)
+	_ = pu.task.Details.Set(progress.FormatSpeedAndETA(avgSpeed, pu.totalBytes-pu.downloadedBytes))
 }
 
 func (pu *progressUpdater) updateFileStatusText() {
@@ -169,21 +124,24 @@ func (pu *progressUpdater) updateFileStatusText() {
 			displayName = "..." + displayName[len(displayName)-maxFilenameLen+3:]
 		}
 
-		progress := pu.fileProgress[file]
+		fp := pu.fileProgress[file]
 		percentage := 0
-		if progress.total > 0 {
-			percentage = int((float64(progress.current) / float64(progress.total)) * 100)
+		if fp.total > 0 {
+			percentage = int((float64(fp.current) / float64(fp.total)) * 100)
 		}
-		sizeStr := fmt.Sprintf("%s/%s", formatBytes(progress.current), formatBytes(progress.total))
+		sizeStr := fmt.Sprintf("%s/%s", progress.FormatBytes(fp.current), progress.FormatBytes(fp.total))
 		sb.WriteString(fmt.Sprintf("%s: %s (%d%%)\n", displayName, sizeStr, percentage))
 	}
 
 	_ = pu.task.FileStatus.Set(strings.TrimSpace(sb.String()))
 }
 
-func executeDownload(authService *auth.Service, dm *DownloadManager, game db.Game,
-	downloadPath, language, platformName string, extrasFlag, dlcFlag, resumeFlag,
-	flattenFlag, skipPatchesFlag, keepLatestFlag, rommLayoutFlag bool, numThreads int) error {
+// executeDownload starts the download q with numThreads workers in the background and adds its task to dm.
+func executeDownload(dm *DownloadManager, q queuedDownload, numThreads int) error {
+	authService, game, downloadPath, language := q.authService, q.game, q.downloadPath, q.language
+	platformName := validation.ResolvePlatform(q.platformName)
+	extrasFlag, dlcFlag, resumeFlag, flattenFlag := q.extrasFlag, q.dlcFlag, q.resumeFlag, q.flattenFlag
+	skipPatchesFlag, keepLatestFlag, rommLayoutFlag := q.skipPatchesFlag, q.keepLatestFlag, q.rommLayoutFlag
 
 	activeDownloadsMutex.Lock()
 	if _, exists := activeDownloads[game.ID]; exists {
@@ -206,24 +164,21 @@ func executeDownload(authService *auth.Service, dm *DownloadManager, game db.Gam
 
 		ctx, cancel := context.WithCancel(context.Background())
 
-		parsedGameData, err := client.ParseGameData(game.Data)
+		parsedGameData, err := client.ParseCachedGameData(game.ID, game.Data)
 		if err != nil {
 			fmt.Printf("Error parsing game data for %s: %v\n", game.Title, err)
 			cancel()
 			return
 		}
-		var targetDir string
-		if rommLayoutFlag {
-			plat := strings.ToLower(platformName)
-			if plat == "all" { // show root for mixed
-				targetDir = downloadPath
-			} else {
-				targetDir = filepath.Join(downloadPath, plat, client.SanitizePath(parsedGameData.Title))
-			}
-		} else {
-			targetDir = filepath.Join(downloadPath, client.SanitizePath(parsedGameData.Title))
+		folder := client.GameFolderName(client.DefaultFolderTemplate, parsedGameData.Title, game.ID)
+		gameDirs := client.GameDirs(downloadPath, folder, rommLayoutFlag, platformName)
+		// A RomM download into all platforms is spread over the platform folders, so it opens at the root.
+		targetDir := downloadPath
+		if len(gameDirs) == 1 {
+			targetDir = gameDirs[0]
 		}
 
+		params := q.params()
 		task := &DownloadTask{
 			ID:           game.ID,
 			InstanceID:   time.Now(),
@@ -235,12 +190,13 @@ func executeDownload(authService *auth.Service, dm *DownloadManager, game db.Gam
 			CancelFunc:   cancel,
 			FileStatus:   binding.NewString(),
 			DownloadPath: targetDir,
+			Params:       &params,
 		}
 		_ = task.Status.Set("Preparing...")
 		_ = task.Details.Set("Speed: N/A | ETA: N/A")
 		_ = dm.AddTask(task)
 
-		fyne.CurrentApp().Preferences().SetString("lastUsedDownloadPath", downloadPath)
+		fyne.CurrentApp().Preferences().SetString(downloadPathPref, downloadPath)
 
 		token, err := authService.RefreshTokenCtx(ctx)
 		if err != nil {
@@ -255,10 +211,18 @@ func executeDownload(authService *auth.Service, dm *DownloadManager, game db.Gam
 			fileProgress: make(map[string]struct{ current, total int64 }),
 		}
 
+		var skips client.SkipSummary
 		err = client.DownloadGameFiles(
 			ctx, token.AccessToken, parsedGameData, downloadPath, language, platformName,
 			extrasFlag, dlcFlag, resumeFlag, flattenFlag, skipPatchesFlag, rommLayoutFlag, numThreads,
-			updater,
+			updater, client.WithDownloadSlots(dm.downloadSlots()), client.WithGameFolder(folder),
+			client.WithStallTimeout(client.DefaultStallTimeout),
+			client.WithPreflight(func(r client.PreflightReport) {
+				if len(r.Warnings) > 0 && !confirmPreflightWarnings(game.Title, r.Warnings) {
+					cancel()
+				}
+			}),
+			client.WithSkipSummary(func(s client.SkipSummary) { skips = s }),
 		)
 
 		if err != nil {
@@ -275,22 +239,17 @@ func executeDownload(authService *auth.Service, dm *DownloadManager, game db.Gam
 		}
 
 		task.State = StateCompleted
-		_ = task.Status.Set(fmt.Sprintf("Download completed. Files are stored in: %s", targetDir))
-		_ = task.Details.Set("")
+		_ = task.Status.Set(fmt.Sprintf("Download completed. Files are stored in: %s", strings.Join(existingDirs(gameDirs), ", ")))
+		if skips.Total() > 0 {
+			_ = task.Details.Set(skips.String())
+		} else {
+			_ = task.Details.Set("")
+		}
 		_ = task.Progress.Set(1.0)
 		_ = task.FileStatus.Set("")
 		go PlayNotificationSound()
 		// Persist download info for future update checks.
-		info := struct {
-			Language    string `json:"language"`
-			Platform    string `json:"platform"`
-			Extras      bool   `json:"extras"`
-			DLCs        bool   `json:"dlcs"`
-			SkipPatches bool   `json:"skipPatches"`
-			Flatten     bool   `json:"flatten"`
-			Resume      bool   `json:"resume"`
-			Threads     int    `json:"threads"`
-		}{
+		info := client.DownloadInfo{
 			Language:    language,
 			Platform:    platformName,
 			Extras:      extrasFlag,
@@ -300,123 +259,79 @@ func executeDownload(authService *auth.Service, dm *DownloadManager, game db.Gam
 			Resume:      resumeFlag,
 			Threads:     numThreads,
 		}
-		if data, mErr := json.MarshalIndent(info, "", "  "); mErr == nil {
-			_ = os.MkdirAll(targetDir, 0755)
-			_ = os.WriteFile(filepath.Join(targetDir, "download_info.json"), data, 0644)
+		if err := client.WriteDownloadInfo(filepath.Join(downloadPath, folder), info, client.DefaultFileModes); err != nil {
+			log.Warn().Err(err).Msg("Failed to write download info")
 		}
 
 		if keepLatestFlag {
-			if err := guiPruneOldVersions(downloadPath, parsedGameData.Title, rommLayoutFlag, platformName); err != nil {
-				log.Warn().Err(err).Msg("Failed to prune old versions (GUI)")
-			}
+			confirmPruneOldVersions(gameDirs)
 		}
 	}()
 
 	return nil
 }
 
-var guiVersionPattern = regexp.MustCompile(`^(?P<prefix>.*?)(?P<ver>\d+(?:\.\d+)+)(?P<suffix>\.[^.]+)$`)
-
-func guiParseVersion(filename string) (prefix string, verSlice []int, ok bool) {
-	m := guiVersionPattern.FindStringSubmatch(filename)
-	if m == nil {
-		return "", nil, false
-	}
-	prefix = m[1]
-	parts := strings.Split(m[2], ".")
-	for _, p := range parts {
-		v, err := strconv.Atoi(p)
-		if err != nil {
-			return "", nil, false
+// existingDirs returns the directories of dirs that exist, or all of them if none exists.
+func existingDirs(dirs []string) []string {
+	var existing []string
+	for _, dir := range dirs {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			existing = append(existing, dir)
 		}
-		verSlice = append(verSlice, v)
 	}
-	return prefix, verSlice, true
+	if len(existing) == 0 {
+		return dirs
+	}
+	return existing
 }
 
-func guiCompareVersions(a, b []int) int {
-	for i := 0; i < len(a) || i < len(b); i++ {
-		va, vb := 0, 0
-		if i < len(a) {
-			va = a[i]
-		}
-		if i < len(b) {
-			vb = b[i]
-		}
-		if va > vb {
-			return 1
-		}
-		if va < vb {
-			return -1
-		}
-	}
-	return 0
+// confirmPruneOldVersions lists the old installer files found under roots and removes them
+// only after the user confirms.
+// confirmPreflightWarnings shows the warnings of checking the target file system for the download of title,
+// and waits until the user chooses whether to download anyway.
+func confirmPreflightWarnings(title string, warnings []string) bool {
+	answer := make(chan bool, 1)
+	runOnMain(func() {
+		msg := widget.NewLabel(fmt.Sprintf("The download of %s may fail on the target file system:\n\n%s",
+			title, strings.Join(warnings, "\n")))
+		msg.Wrapping = fyne.TextWrapWord
+		win := fyne.CurrentApp().Driver().AllWindows()[0]
+		d := dialog.NewCustomConfirm("Check the Download Directory", "Download Anyway", "Cancel", msg,
+			func(confirmed bool) { answer <- confirmed }, win)
+		d.Resize(fyne.NewSize(600, 250))
+		d.Show()
+	})
+	return <-answer
 }
 
-func guiPruneOldVersions(rootPath, title string, romm bool, platformName string) error {
-	// Determine roots to scan
-	var roots []string
-	if romm {
-		plats := []string{"windows", "mac", "linux"}
-		if strings.ToLower(platformName) != "all" {
-			plats = []string{strings.ToLower(platformName)}
-		}
-		for _, p := range plats {
-			roots = append(roots, filepath.Join(rootPath, p, client.SanitizePath(title)))
-		}
-	} else {
-		roots = []string{filepath.Join(rootPath, client.SanitizePath(title))}
+func confirmPruneOldVersions(roots []string) {
+	plan, err := operations.PlanPrune(roots...)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to find old versions to prune (GUI)")
+		return
+	}
+	if len(plan.Files) == 0 {
+		return
 	}
-	extAllowed := map[string]struct{}{".exe": {}, ".bin": {}, ".dmg": {}, ".sh": {}, ".zip": {}, ".tar.gz": {}, ".rar": {}}
-	for _, root := range roots {
-		if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
-			continue
+	runOnMain(func() {
+		list := container.NewVBox()
+		for _, f := range plan.Files {
+			list.Add(widget.NewLabel(f))
 		}
-		latest := map[string][]int{}
-		filesByPrefix := map[string][]string{}
-		_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-			if err != nil || info.IsDir() {
-				return nil
+		scroll := container.NewVScroll(list)
+		scroll.SetMinSize(fyne.NewSize(600, 250))
+		msg := widget.NewLabel(fmt.Sprintf("The following %d old installer file(s) will be removed, reclaiming %s:", len(plan.Files), progress.FormatBytes(plan.TotalBytes)))
+		msg.Wrapping = fyne.TextWrapWord
+		content := container.NewBorder(msg, nil, nil, nil, scroll)
+		win := fyne.CurrentApp().Driver().AllWindows()[0]
+		dialog.ShowCustomConfirm("Remove Old Versions", "Remove", "Keep", content, func(confirmed bool) {
+			if !confirmed {
+				return
 			}
-			name := info.Name()
-			ext := filepath.Ext(name)
-			if strings.HasSuffix(name, ".tar.gz") {
-				ext = ".tar.gz"
-			}
-			if _, ok := extAllowed[ext]; !ok {
-				return nil
-			}
-			prefix, ver, ok := guiParseVersion(name)
-			if !ok {
-				return nil
-			}
-			filesByPrefix[prefix] = append(filesByPrefix[prefix], path)
-			if cur, ok := latest[prefix]; !ok || guiCompareVersions(ver, cur) == 1 {
-				latest[prefix] = ver
-			}
-			return nil
-		})
-		for _, paths := range filesByPrefix {
-			// find the latest file among paths
-			var best string
-			var bestVer []int
-			for _, p := range paths {
-				name := filepath.Base(p)
-				_, ver, ok := guiParseVersion(name)
-				if !ok {
-					continue
-				}
-				if best == "" || guiCompareVersions(ver, bestVer) == 1 {
-					best = p
-					bestVer = ver
-				}
-			}
-			for _, p := range paths {
-				if p != best {
-					_ = os.Remove(p)
-				}
-			}
-		}
-	}
-	return nil
+			go func() {
+				removed := operations.RemoveFiles(plan.Files)
+				log.Info().Int("count", len(removed)).Msg("Removed old installer versions (GUI)")
+			}()
+		}, win)
+	})
 }
@@ -13,3 +13,13 @@ func SignalCatalogueUpdated() {
 		return
 	} // The value doesn't matter, only the change event.
 }
+
+// cachesCleared signals that the cached library state was cleared from Settings.
+// Its value counts the resets, so that every reset is a change.
+var cachesCleared = binding.NewInt()
+
+// SignalCachesCleared sends a notification that the cached library state has been cleared.
+func SignalCachesCleared() {
+	n, _ := cachesCleared.Get()
+	_ = cachesCleared.Set(n + 1)
+}
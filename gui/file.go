@@ -10,7 +10,6 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"sync/atomic"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -23,6 +22,7 @@ import (
 	"github.com/habedi/gogg/db"
 	"github.com/habedi/gogg/pkg/hasher"
 	"github.com/habedi/gogg/pkg/operations"
+	"github.com/habedi/gogg/pkg/validation"
 	"github.com/rs/zerolog/log"
 )
 
@@ -149,16 +149,26 @@ func HashUI(win fyne.Window) fyne.CanvasObject {
 	algoSelect.SetSelected(initialAlgo)
 	header.SetTexts("File Path", fmt.Sprintf("Hash (%s)", initialAlgo))
 
-	threadsSelect := widget.NewSelect([]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}, func(s string) {
+	threadsSelect := widget.NewSelect(threadOptions(), func(s string) {
 		prefs.SetString("hashUI.threads", s)
 	})
-	threadsSelect.SetSelected(prefs.StringWithFallback("hashUI.threads", "4"))
+	threadsSelect.SetSelected(prefs.StringWithFallback("hashUI.threads", strconv.Itoa(validation.DefaultThreads(validation.LocalWorkload))))
 
 	recursiveCheck := widget.NewCheck("Recursive", func(b bool) {
 		prefs.SetBool("hashUI.recursive", b)
 	})
 	recursiveCheck.SetChecked(prefs.BoolWithFallback("hashUI.recursive", true))
 
+	installersOnlyCheck := widget.NewCheck("Installers only (skip extras like wallpapers)", func(b bool) {
+		prefs.SetBool("hashUI.installersOnly", b)
+	})
+	installersOnlyCheck.SetChecked(prefs.BoolWithFallback("hashUI.installersOnly", false))
+
+	cacheCheck := widget.NewCheck("Reuse cached hashes of unchanged files", func(b bool) {
+		prefs.SetBool("hashUI.useCache", b)
+	})
+	cacheCheck.SetChecked(prefs.BoolWithFallback("hashUI.useCache", false))
+
 	form := widget.NewForm(
 		widget.NewFormItem("Directory", pathContainer),
 		widget.NewFormItem("Algorithm", algoSelect),
@@ -169,7 +179,7 @@ func HashUI(win fyne.Window) fyne.CanvasObject {
 	progressBar := widget.NewProgressBar()
 	progressBar.Hide()
 
-	topContent := container.NewVBox(form, recursiveCheck, generateBtn, progressBar)
+	topContent := container.NewVBox(form, recursiveCheck, installersOnlyCheck, cacheCheck, generateBtn, progressBar)
 
 	resultsData := binding.NewUntypedList()
 
@@ -207,8 +217,8 @@ func HashUI(win fyne.Window) fyne.CanvasObject {
 				generateBtn.Enable()
 				progressBar.Hide()
 			})
-			numThreads, _ := strconv.Atoi(threadsSelect.Selected)
-			generateHashFilesUI(dir, algoSelect.Selected, recursiveCheck.Checked, numThreads, resultsData, progressBar)
+			numThreads := parseThreads(threadsSelect.Selected)
+			generateHashFilesUI(dir, algoSelect.Selected, recursiveCheck.Checked, installersOnlyCheck.Checked, cacheCheck.Checked, numThreads, resultsData, progressBar)
 		}()
 	}
 
@@ -245,23 +255,52 @@ func HashUI(win fyne.Window) fyne.CanvasObject {
 	return container.NewBorder(topContent, bottomBar, nil, nil, listContainer)
 }
 
-func generateHashFilesUI(dir, algo string, recursive bool, numThreads int, results binding.UntypedList, progress *widget.ProgressBar) {
-	filesToProcess, err := operations.FindFilesToHash(dir, recursive, operations.DefaultHashExclusions)
+func generateHashFilesUI(dir, algo string, recursive, installersOnly, useCache bool, numThreads int, results binding.UntypedList, progress *widget.ProgressBar) {
+	exclusions, err := operations.HashExclusions(dir, true, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("GUI: Failed to read hash exclusions")
+		return
+	}
+	filesToProcess, err := operations.FindFilesToHash(dir, recursive, exclusions)
 	if err != nil {
 		log.Error().Err(err).Msg("GUI: Failed to find files to hash")
 		return
 	}
+	filesToProcess = operations.FilterFilesToHash(filesToProcess, installersOnly, nil)
 
-	totalFiles := len(filesToProcess)
-	if totalFiles == 0 {
+	if len(filesToProcess) == 0 {
 		return
 	}
+	totalBytes := operations.TotalSize(filesToProcess)
 	runOnMain(func() {
-		progress.Max = float64(totalFiles)
+		progress.Max = float64(totalBytes)
 	})
 
-	var processedCount atomic.Int64
-	resultsChan := operations.GenerateHashes(context.Background(), filesToProcess, algo, numThreads)
+	// Progress is reported in bytes so that hashing a single very large file still moves the bar.
+	var tracker operations.HashProgressTracker
+	hashOpts := operations.HashOptions{
+		Progress: func(file string, hashed, _ int64) {
+			done := tracker.Update(file, hashed)
+			runOnMain(func() {
+				progress.SetValue(float64(done))
+			})
+		},
+	}
+	if useCache {
+		cache, err := operations.LoadHashCache(operations.DefaultHashCachePath())
+		if err != nil {
+			log.Warn().Err(err).Msg("GUI: Failed to load hash cache; hashing all files")
+		} else {
+			hashOpts.Cache = cache
+			defer func() {
+				if err := cache.Save(); err != nil {
+					log.Warn().Err(err).Msg("GUI: Failed to save hash cache")
+				}
+			}()
+		}
+	}
+
+	resultsChan := operations.GenerateHashesWithOptions(context.Background(), filesToProcess, algo, numThreads, hashOpts)
 
 	for res := range resultsChan {
 		if res.Err == nil {
@@ -275,11 +314,6 @@ func generateHashFilesUI(dir, algo string, recursive bool, numThreads int, resul
 		} else {
 			log.Error().Err(res.Err).Str("file", res.File).Msg("GUI: Error hashing file")
 		}
-
-		newCount := processedCount.Add(1)
-		runOnMain(func() {
-			progress.SetValue(float64(newCount))
-		})
 	}
 }
 
@@ -391,12 +425,13 @@ func SizeUI(win fyne.Window) fyne.CanvasObject {
 		langCodes = append(langCodes, code)
 	}
 	sort.Strings(langCodes)
+	langCodes = append(langCodes, client.AllLanguages)
 	langSelect := widget.NewSelect(langCodes, func(s string) {
 		prefs.SetString("sizeUI.language", s)
 	})
 	langSelect.SetSelected(prefs.StringWithFallback("sizeUI.language", "en"))
 
-	platformSelect := widget.NewSelect([]string{"all", "windows", "mac", "linux"}, func(s string) {
+	platformSelect := widget.NewSelect([]string{"all", "auto", "windows", "mac", "linux"}, func(s string) {
 		prefs.SetString("sizeUI.platform", s)
 	})
 	platformSelect.SetSelected(prefs.StringWithFallback("sizeUI.platform", "windows"))
@@ -718,7 +753,7 @@ func estimateMultipleGamesUI(
 	}
 
 	// Add header information
-	langFullName := client.GameLanguages[languageCode]
+	langFullName, _ := client.LanguageFilter(languageCode)
 	headerRows := []interface{}{
 		sizeResult{"=== Estimation Settings ===", ""},
 		sizeResult{"Platform", platformName},
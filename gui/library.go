@@ -1,16 +1,20 @@
 package gui
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"math"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -23,6 +27,9 @@ import (
 	"github.com/habedi/gogg/auth"
 	"github.com/habedi/gogg/client"
 	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/pool"
+	"github.com/habedi/gogg/pkg/search"
+	"github.com/habedi/gogg/pkg/validation"
 )
 
 // libraryTab holds all the components of the library tab UI.
@@ -105,82 +112,152 @@ type updateStatus struct {
 	Downloaded bool
 	HasUpdate  bool
 	Diff       []string // human-readable changes
+	// Fingerprint identifies the inputs the status was computed from (the game data, the download folder,
+	// and the update options). A game whose fingerprint has not changed is not recomputed.
+	Fingerprint string `json:",omitempty"`
 }
 
-// computeUpdateStatus recomputes and caches status for provided games.
-func computeUpdateStatus(dm *DownloadManager, games []db.Game) {
-	prefs := fyne.CurrentApp().Preferences()
-	includeExtrasUpdates := prefs.BoolWithFallback("downloadForm.includeExtrasUpdates", false)
-	includeDLCUpdates := prefs.BoolWithFallback("downloadForm.includeDLCUpdates", false)
-	scanDirs := prefs.BoolWithFallback("downloadForm.scanDirsForDownloads", true)
-	includePatchUpdates := prefs.BoolWithFallback("downloadForm.includePatchUpdates", false)
-	langPref := prefs.StringWithFallback("downloadForm.language", "en")
-	platformPref := prefs.StringWithFallback("downloadForm.platform", "windows")
-	for _, game := range games {
-		// Downloaded determination
-		downloaded := isGameDownloaded(dm, game.ID)
-		var dir string
-		if !downloaded && scanDirs {
-			if d, ok := getGameDownloadDirectory(dm, game); ok {
-				dir = d
-				downloaded = true
-			}
+// updateOptions are the preferences (and filters) that affect the update status of a game.
+type updateOptions struct {
+	includeExtras, includeDLCs, includePatches bool
+	scanDirs                                   bool
+	language, platform                         string
+}
+
+// updateStatusWorkers is the maximum number of games whose update status is computed at the same time.
+var updateStatusWorkers = min(runtime.NumCPU(), 8)
+
+// readUpdateOptions reads the update options. It reads the active filters, so it must be called on the
+// main thread.
+func readUpdateOptions(prefs fyne.Preferences) updateOptions {
+	return updateOptions{
+		includeExtras:  prefs.BoolWithFallback("downloadForm.includeExtrasUpdates", false),
+		includeDLCs:    prefs.BoolWithFallback("downloadForm.includeDLCUpdates", false),
+		includePatches: prefs.BoolWithFallback("downloadForm.includePatchUpdates", false),
+		// Finding updates needs the download folders, so the "Has update only" filter scans them even when
+		// scanning is turned off.
+		scanDirs: prefs.BoolWithFallback("downloadForm.scanDirsForDownloads", true) || filterHasUpdateOnly,
+		language: prefs.StringWithFallback("downloadForm.language", "en"),
+		platform: validation.ResolvePlatform(prefs.StringWithFallback("downloadForm.platform", "windows")),
+	}
+}
+
+// computeUpdateStatus recomputes and caches status for provided games. The games are processed by a
+// bounded pool of workers, and games whose inputs have not changed since the last run are skipped.
+// It does not touch any widgets, so it can be called from any goroutine.
+func computeUpdateStatus(dm *DownloadManager, games []db.Game, opts updateOptions) {
+	_ = pool.Run(context.Background(), games, updateStatusWorkers, func(_ context.Context, game db.Game) error {
+		setUpdateStatus(game.ID, computeGameUpdateStatus(dm, game, opts))
+		return nil
+	})
+	persistUpdateStatusCache()
+}
+
+// computeGameUpdateStatus returns the update status of a game, reusing the cached status if its
+// fingerprint matches.
+func computeGameUpdateStatus(dm *DownloadManager, game db.Game, opts updateOptions) updateStatus {
+	// Downloaded determination
+	downloaded := isGameDownloaded(dm, game.ID)
+	var dir string
+	if !downloaded && opts.scanDirs {
+		if d, ok := getGameDownloadDirectory(dm, game); ok {
+			dir = d
+			downloaded = true
 		}
-		if downloaded && dir == "" {
-			// history path if available
-			if p, ok := getLastCompletedDownloadDir(dm, game.ID); ok {
-				dir = p
-			}
+	}
+	if downloaded && dir == "" {
+		// history path if available
+		if p, ok := getLastCompletedDownloadDir(dm, game.ID); ok {
+			dir = p
 		}
+	}
 
-		status := updateStatus{Downloaded: downloaded}
-		if downloaded && dir != "" {
-			oldMeta, err1 := readDownloadedMetadata(dm, game.ID)
-			if err1 != nil && scanDirs { // try reading direct dir if fallback path differs
-				metaPath := filepath.Join(dir, "metadata.json")
-				if b, err2 := os.ReadFile(metaPath); err2 == nil {
-					var gm client.Game
-					if json.Unmarshal(b, &gm) == nil {
-						oldMeta = &gm
-					}
+	fingerprint := updateFingerprint(game, downloaded, dir, opts)
+	if cached, ok := getUpdateStatus(game.ID); ok && cached.Fingerprint == fingerprint {
+		return cached
+	}
+
+	status := updateStatus{Downloaded: downloaded, Fingerprint: fingerprint}
+	if downloaded && dir != "" {
+		oldMeta, err1 := readDownloadedMetadata(dm, game.ID)
+		if err1 != nil && opts.scanDirs { // try reading direct dir if fallback path differs
+			metaPath := filepath.Join(dir, "metadata.json")
+			if b, err2 := os.ReadFile(metaPath); err2 == nil {
+				var gm client.Game
+				if json.Unmarshal(b, &gm) == nil {
+					oldMeta = &gm
 				}
 			}
-			current, err3 := client.ParseGameData(game.Data)
-			if err3 == nil && oldMeta != nil {
-				infoLang, infoPlatform := readDownloadInfo(dir)
-				lang := langPref
-				platform := platformPref
-				if infoLang != "" {
-					lang = infoLang
-				}
-				if infoPlatform != "" {
-					platform = infoPlatform
-				}
-				oldMap := buildVersionMapExtended(*oldMeta, lang, platform, includeExtrasUpdates, includeDLCUpdates, includePatchUpdates)
-				newMap := buildVersionMapExtended(current, lang, platform, includeExtrasUpdates, includeDLCUpdates, includePatchUpdates)
-				diff := make([]string, 0)
-				for k, newVer := range newMap {
-					oldVer, ok := oldMap[k]
-					if !ok {
-						diff = append(diff, "NEW: "+k+" version="+newVer)
-					} else if newVer != oldVer {
-						diff = append(diff, "CHANGED: "+k+" "+oldVer+" -> "+newVer)
-					}
-				}
-				if len(diff) > 0 {
-					status.HasUpdate = true
-					status.Diff = diff
+		}
+		current, err3 := client.ParseCachedGameData(game.ID, game.Data)
+		if err3 == nil && oldMeta != nil {
+			// A missing or unreadable download_info.json leaves the options as they are.
+			info, _ := client.ReadDownloadInfo(dir)
+			lang := opts.language
+			platform := opts.platform
+			if info.Language != "" {
+				lang = info.Language
+			}
+			if info.Platform != "" {
+				platform = info.Platform
+			}
+			oldMap := buildVersionMapExtended(*oldMeta, lang, platform, opts.includeExtras, opts.includeDLCs, opts.includePatches)
+			newMap := buildVersionMapExtended(current, lang, platform, opts.includeExtras, opts.includeDLCs, opts.includePatches)
+			diff := make([]string, 0)
+			for k, newVer := range newMap {
+				oldVer, ok := oldMap[k]
+				if !ok {
+					diff = append(diff, "NEW: "+k+" version="+newVer)
+				} else if newVer != oldVer {
+					diff = append(diff, "CHANGED: "+k+" "+oldVer+" -> "+newVer)
 				}
 			}
+			if len(diff) > 0 {
+				sort.Strings(diff)
+				status.HasUpdate = true
+				status.Diff = diff
+			}
 		}
-		updateStatusCache[game.ID] = status
 	}
-	persistUpdateStatusCache()
+	return status
+}
+
+// updateCheckRevision is hashed into the fingerprints of update statuses, so that the statuses cached before the
+// update check changed, like before it compared the sizes of extras, are computed again.
+const updateCheckRevision = "2"
+
+// updateFingerprint hashes the inputs of a game's update status: its catalogue data, whether and where it
+// was downloaded, the modification times of the files read from the download folder, and the options.
+func updateFingerprint(game db.Game, downloaded bool, dir string, opts updateOptions) string {
+	h := fnv.New64a()
+	_, _ = io.WriteString(h, updateCheckRevision+"\x00"+game.Data)
+	fmt.Fprintf(h, "\x00%t\x00%s\x00%+v", downloaded, dir, opts)
+	if dir != "" {
+		for _, name := range []string{"metadata.json", "download_info.json"} {
+			if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				fmt.Fprintf(h, "\x00%s:%d:%d", name, info.Size(), info.ModTime().UnixNano())
+			}
+		}
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+func getUpdateStatus(gameID int) (updateStatus, bool) {
+	updateStatusMu.RLock()
+	defer updateStatusMu.RUnlock()
+	st, ok := updateStatusCache[gameID]
+	return st, ok
+}
+
+func setUpdateStatus(gameID int, st updateStatus) {
+	updateStatusMu.Lock()
+	defer updateStatusMu.Unlock()
+	updateStatusCache[gameID] = st
 }
 
 // hasGameUpdateCached now reads cache
 func hasGameUpdateCached(gameID int) (bool, []string) {
-	st, ok := updateStatusCache[gameID]
+	st, ok := getUpdateStatus(gameID)
 	if !ok {
 		return false, nil
 	}
@@ -189,14 +266,18 @@ func hasGameUpdateCached(gameID int) (bool, []string) {
 
 // isGameDownloadedCached uses cache
 func isGameDownloadedCached(gameID int) bool {
-	st, ok := updateStatusCache[gameID]
+	st, ok := getUpdateStatus(gameID)
 	if !ok {
 		return false
 	}
 	return st.Downloaded
 }
 
-var updateStatusCache = make(map[int]updateStatus)
+// updateStatusCache is guarded by updateStatusMu, because statuses are computed by background workers.
+var (
+	updateStatusCache = make(map[int]updateStatus)
+	updateStatusMu    sync.RWMutex
+)
 var updateStatusFileURI fyne.URI
 
 func initUpdateStatusPersistence() {
@@ -228,6 +309,8 @@ func loadPersistedUpdateStatus() {
 	if json.Unmarshal(data, &raw) != nil {
 		return
 	}
+	updateStatusMu.Lock()
+	defer updateStatusMu.Unlock()
 	for k, v := range raw {
 		if id, convErr := strconv.Atoi(k); convErr == nil {
 			updateStatusCache[id] = v
@@ -235,15 +318,20 @@ func loadPersistedUpdateStatus() {
 	}
 }
 
+// updateStatusPersistMu serializes the writes of the update status file, so that computations finishing
+// together write it one after the other, each with the statuses known by then.
+var updateStatusPersistMu sync.Mutex
+
+// persistUpdateStatusCache writes the update status cache to disk. The file is written to a temporary
+// file first and then renamed over the old one, so it is never left half written.
 func persistUpdateStatusCache() {
 	if updateStatusFileURI == nil {
 		return
 	}
-	writer, err := storage.Writer(updateStatusFileURI)
-	if err != nil {
-		return
-	}
-	defer writer.Close()
+	updateStatusPersistMu.Lock()
+	defer updateStatusPersistMu.Unlock()
+
+	updateStatusMu.RLock()
 	out := make(map[string]updateStatus, len(updateStatusCache))
 	for id, st := range updateStatusCache {
 		// Limit diff length persisted
@@ -252,15 +340,37 @@ func persistUpdateStatusCache() {
 		}
 		out[strconv.Itoa(id)] = st
 	}
-	enc := json.NewEncoder(writer)
-	_ = enc.Encode(out)
+	updateStatusMu.RUnlock()
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return
+	}
+	path := updateStatusFileURI.Path()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+	}
 }
 
 func clearPersistedUpdateStatus() {
+	updateStatusMu.Lock()
 	updateStatusCache = make(map[int]updateStatus)
+	updateStatusMu.Unlock()
 	persistUpdateStatusCache()
 }
 
+// clearLibraryCaches clears the cached update status (in memory and on disk), the size estimates, and the parsed
+// catalogue data.
+func clearLibraryCaches() {
+	clearPersistedUpdateStatus()
+	sizeCache = make(map[int]int64)
+	client.PurgeGameCache()
+}
+
 // Size cache
 var sizeCache = make(map[int]int64)
 
@@ -270,10 +380,10 @@ func estimateGameSize(game db.Game) int64 {
 	}
 	prefs := fyne.CurrentApp().Preferences()
 	lang := prefs.StringWithFallback("downloadForm.language", "en")
-	platform := prefs.StringWithFallback("downloadForm.platform", "windows")
+	platform := validation.ResolvePlatform(prefs.StringWithFallback("downloadForm.platform", "windows"))
 	extras := prefs.BoolWithFallback("downloadForm.extras", true)
 	dlcs := prefs.BoolWithFallback("downloadForm.dlcs", true)
-	parsed, err := client.ParseGameData(game.Data)
+	parsed, err := client.ParseCachedGameData(game.ID, game.Data)
 	if err != nil {
 		sizeCache[game.ID] = 0
 		return 0
@@ -287,29 +397,71 @@ func estimateGameSize(game db.Game) int64 {
 	return sz
 }
 
+// Download states a game can be filtered by.
+const (
+	downloadStateAny           = "Any"
+	downloadStateDownloaded    = "Downloaded"
+	downloadStateNotDownloaded = "Not downloaded"
+)
+
 // Active filters
 var (
-	filterDownloadedOnly bool
-	filterHasUpdateOnly  bool
-	filterSizeMin        int64
-	filterSizeMax        int64
+	filterDownloadState = downloadStateAny
+	filterHasUpdateOnly bool
+	filterSizeMin       int64
+	filterSizeMax       int64
+	filterTag           string
 )
 
 func resetFilters() {
-	filterDownloadedOnly = false
+	filterDownloadState = downloadStateAny
 	filterHasUpdateOnly = false
 	filterSizeMin = 0
 	filterSizeMax = 0
+	filterTag = ""
+}
+
+// loadFilters restores the filters saved by saveFilters.
+func loadFilters(prefs fyne.Preferences) {
+	filterDownloadState = prefs.StringWithFallback("libraryFilter.downloadState", downloadStateAny)
+	switch filterDownloadState {
+	case downloadStateAny, downloadStateDownloaded, downloadStateNotDownloaded:
+	default:
+		filterDownloadState = downloadStateAny
+	}
+	filterHasUpdateOnly = prefs.BoolWithFallback("libraryFilter.hasUpdate", false)
+	filterSizeMin = parseSizeInput(prefs.String("libraryFilter.sizeMin"))
+	filterSizeMax = parseSizeInput(prefs.String("libraryFilter.sizeMax"))
+	filterTag = prefs.String("libraryFilter.tag")
+}
+
+// saveFilters saves the active filters, so they are kept across sessions.
+func saveFilters(prefs fyne.Preferences) {
+	prefs.SetString("libraryFilter.downloadState", filterDownloadState)
+	prefs.SetBool("libraryFilter.hasUpdate", filterHasUpdateOnly)
+	prefs.SetString("libraryFilter.sizeMin", strconv.FormatInt(filterSizeMin, 10))
+	prefs.SetString("libraryFilter.sizeMax", strconv.FormatInt(filterSizeMax, 10))
+	prefs.SetString("libraryFilter.tag", filterTag)
 }
 
 func passesFilters(game db.Game) bool {
-	st, ok := updateStatusCache[game.ID]
-	if filterDownloadedOnly && (!ok || !st.Downloaded) {
+	st, ok := getUpdateStatus(game.ID)
+	downloaded := ok && st.Downloaded
+	if filterDownloadState == downloadStateDownloaded && !downloaded {
+		return false
+	}
+	if filterDownloadState == downloadStateNotDownloaded && downloaded {
 		return false
 	}
-	if filterHasUpdateOnly && (!ok || !st.HasUpdate) {
+	// The updates of pinned games are not shown as updates.
+	if filterHasUpdateOnly && (!ok || !st.HasUpdate || isGamePinned(game.ID)) {
 		return false
 	}
+	if filterTag != "" {
+		if _, tagged := filterTagGames[game.ID]; !tagged {
+			return false
+		}
+	}
 	if filterSizeMin > 0 || filterSizeMax > 0 {
 		sz := estimateGameSize(game)
 		if filterSizeMin > 0 && sz < filterSizeMin {
@@ -354,9 +506,27 @@ func newFiltersButton(refresh func()) *widget.Button {
 	var dlg *dialog.CustomDialog
 	btn := widget.NewButtonWithIcon("Filters", theme.SearchIcon(), func() {
 		// Inputs
-		downloadedChk := widget.NewCheck("Downloaded only", func(b bool) { filterDownloadedOnly = b })
-		downloadedChk.SetChecked(filterDownloadedOnly)
-		updateChk := widget.NewCheck("Has update", func(b bool) { filterHasUpdateOnly = b })
+		downloadState := widget.NewRadioGroup([]string{downloadStateAny, downloadStateDownloaded, downloadStateNotDownloaded}, func(v string) {
+			if v == "" {
+				v = downloadStateAny
+			}
+			filterDownloadState = v
+		})
+		downloadState.Horizontal = true
+		downloadState.Required = true
+		downloadState.SetSelected(filterDownloadState)
+		updateChk := widget.NewCheck("Has update only", func(b bool) { filterHasUpdateOnly = b })
+		tagSelect := widget.NewSelect(tagFilterOptions(), func(v string) {
+			if v == allTagsOption {
+				v = ""
+			}
+			filterTag = v
+		})
+		if filterTag == "" {
+			tagSelect.SetSelected(allTagsOption)
+		} else {
+			tagSelect.SetSelected(filterTag)
+		}
 		updateChk.SetChecked(filterHasUpdateOnly)
 		sizeMinEntry := widget.NewEntry()
 		if filterSizeMin > 0 {
@@ -369,18 +539,26 @@ func newFiltersButton(refresh func()) *widget.Button {
 		applyBtn := widget.NewButtonWithIcon("Apply", theme.ConfirmIcon(), func() {
 			filterSizeMin = parseSizeInput(sizeMinEntry.Text)
 			filterSizeMax = parseSizeInput(sizeMaxEntry.Text)
+			saveFilters(fyne.CurrentApp().Preferences())
+			refresh()
+			dlg.Hide()
+		})
+		resetBtn := widget.NewButtonWithIcon("Reset", theme.ViewRefreshIcon(), func() {
+			resetFilters()
+			saveFilters(fyne.CurrentApp().Preferences())
 			refresh()
 			dlg.Hide()
 		})
-		resetBtn := widget.NewButtonWithIcon("Reset", theme.ViewRefreshIcon(), func() { resetFilters(); refresh(); dlg.Hide() })
 		content := container.NewVBox(
 			widget.NewLabelWithStyle("Filters", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), widget.NewSeparator(),
 			container.NewGridWithColumns(2, widget.NewLabel("Min Size"), sizeMinEntry, widget.NewLabel("Max Size"), sizeMaxEntry),
-			container.NewGridWithColumns(2, downloadedChk, updateChk),
+			widget.NewLabel("Download State"), downloadState,
+			updateChk,
+			container.NewGridWithColumns(2, widget.NewLabel("Tag"), tagSelect),
 			container.NewHBox(applyBtn, resetBtn),
 		)
 		dlg = dialog.NewCustom("Library Filters", "Close", content, fyne.CurrentApp().Driver().AllWindows()[0])
-		dlg.Resize(fyne.NewSize(400, 260))
+		dlg.Resize(fyne.NewSize(420, 360))
 		dlg.Show()
 	})
 	btn.Importance = widget.MediumImportance
@@ -399,15 +577,25 @@ func LibraryTabUI(win fyne.Window, authService *auth.Service, dm *DownloadManage
 		return &libraryTab{content: content, searchEntry: widget.NewEntry()} // Return dummy entry
 	}
 
+	loadFilters(fyne.CurrentApp().Preferences())
 	allGames, _ := db.GetCatalogue()
 	gamesListBinding := binding.NewUntypedList()
 	selectedGameBinding := binding.NewUntyped()
+	// displayedGames returns the games currently shown in the list, after searching and filtering.
+	displayedGames := func() []db.Game {
+		items, _ := gamesListBinding.Get()
+		games := make([]db.Game, 0, len(items))
+		for _, it := range items {
+			games = append(games, it.(db.Game))
+		}
+		return games
+	}
 	isSortAscending := true
 
 	gameCountLabel := widget.NewLabel("")
 
 	searchEntry := widget.NewEntry()
-	searchEntry.SetPlaceHolder("Type game title to search...")
+	searchEntry.SetPlaceHolder("Type game title or ID to search...")
 	clearSearchBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
 		searchEntry.SetText("")
 	})
@@ -415,8 +603,21 @@ func LibraryTabUI(win fyne.Window, authService *auth.Service, dm *DownloadManage
 	clearSearchBtn.Hide()
 
 	var gameListWidget *widget.List
+	// statusGeneration counts the updates of the displayed games, so that the result of an outdated
+	// background status computation is dropped.
+	statusGeneration := 0
+	applyFilters := func(games []db.Game) {
+		filtered := []db.Game{}
+		for _, g := range games {
+			if passesFilters(g) {
+				filtered = append(filtered, g)
+			}
+		}
+		_ = gamesListBinding.Set(untypedSlice(filtered))
+		gameCountLabel.SetText(fmt.Sprintf("%d games found", len(filtered)))
+	}
 	updateDisplayedGames := func() {
-		searchTerm := strings.ToLower(searchEntry.Text)
+		searchTerm := strings.TrimSpace(searchEntry.Text)
 		displayGames := make([]db.Game, len(allGames))
 		copy(displayGames, allGames)
 
@@ -430,28 +631,27 @@ func LibraryTabUI(win fyne.Window, authService *auth.Service, dm *DownloadManage
 			})
 		}
 
-		if searchTerm != "" {
-			filtered := make([]db.Game, 0)
-			for _, game := range displayGames {
-				if strings.Contains(strings.ToLower(game.Title), searchTerm) {
-					filtered = append(filtered, game)
+		// The matches are ranked like in 'catalogue search', keeping the sort order within each rank.
+		displayGames = search.Games(searchTerm, displayGames)
+
+		// Apply the filters with the cached statuses right away, then again once the statuses of the
+		// displayed games have been recomputed in the background.
+		refreshTagFilter()
+		refreshPinnedGames()
+		applyFilters(displayGames)
+		statusGeneration++
+		generation := statusGeneration
+		opts := readUpdateOptions(fyne.CurrentApp().Preferences())
+		go func() {
+			computeUpdateStatus(dm, displayGames, opts)
+			runOnMain(func() {
+				if generation != statusGeneration {
+					return // a newer update is in progress
 				}
-			}
-			displayGames = filtered
-		}
-
-		_ = gamesListBinding.Set(untypedSlice(displayGames))
-		// Recompute cache only for displayed games for efficiency
-		computeUpdateStatus(dm, displayGames)
-		// Apply post-filter pass
-		filtered := []db.Game{}
-		for _, g := range displayGames {
-			if passesFilters(g) {
-				filtered = append(filtered, g)
-			}
-		}
-		_ = gamesListBinding.Set(untypedSlice(filtered))
-		gameCountLabel.SetText(fmt.Sprintf("%d games found", len(filtered)))
+				applyFilters(displayGames)
+				gameListWidget.Refresh()
+			})
+		}()
 		if searchTerm == "" {
 			clearSearchBtn.Hide()
 		} else {
@@ -491,14 +691,18 @@ func LibraryTabUI(win fyne.Window, authService *auth.Service, dm *DownloadManage
 				iconDownloaded.Show()
 				hasUpd, diff := hasGameUpdateCached(game.ID)
 				if hasUpd {
+					title := "Update details"
 					updateBtn.Show()
+					updateBtn.SetIcon(theme.DownloadIcon())
 					updateBtn.SetText(fmt.Sprintf("%d", len(diff)))
+					if isGamePinned(game.ID) {
+						// A pinned game keeps its version, so its update is only shown as available.
+						title = "Pinned, newer version available"
+						updateBtn.SetIcon(theme.InfoIcon())
+						updateBtn.SetText("Pinned")
+					}
 					updateBtn.OnTapped = func() {
-						content := container.NewVBox()
-						for _, line := range diff {
-							content.Add(widget.NewLabel(line))
-						}
-						dialog.ShowCustom("Update details", "Close", container.NewVScroll(content), fyne.CurrentApp().Driver().AllWindows()[0])
+						dialog.ShowCustom(title, "Close", container.NewVScroll(updateDetailsContent(game, diff)), fyne.CurrentApp().Driver().AllWindows()[0])
 					}
 				} else {
 					updateBtn.Hide()
@@ -520,15 +724,11 @@ func LibraryTabUI(win fyne.Window, authService *auth.Service, dm *DownloadManage
 
 	// Refresh icons when download tasks change
 	dm.Tasks.AddListener(binding.NewDataListener(func() {
-		computeUpdateStatus(dm, func() []db.Game { // recalc for current displayed games
-			items, _ := gamesListBinding.Get()
-			gs := make([]db.Game, 0, len(items))
-			for _, it := range items {
-				gs = append(gs, it.(db.Game))
-			}
-			return gs
-		}())
-		gameListWidget.Refresh()
+		games, opts := displayedGames(), readUpdateOptions(fyne.CurrentApp().Preferences())
+		go func() {
+			computeUpdateStatus(dm, games, opts) // recalc for current displayed games
+			runOnMain(gameListWidget.Refresh)
+		}()
 	}))
 
 	var refreshBtn *widget.Button
@@ -541,13 +741,17 @@ func LibraryTabUI(win fyne.Window, authService *auth.Service, dm *DownloadManage
 	}
 
 	if len(allGames) == 0 {
+		refreshNowBtn := widget.NewButton("Refresh Catalogue Now", func() {
+			refreshBtn.Disable()
+			RefreshCatalogueAction(win, authService, onFinishRefresh)
+		})
+		if dm.offline {
+			refreshNowBtn.Disable()
+		}
 		placeholder := container.NewCenter(container.NewVBox(
 			widget.NewIcon(theme.InfoIcon()),
 			widget.NewLabel("Your library is empty or hasn't been synced."),
-			widget.NewButton("Refresh Catalogue Now", func() {
-				refreshBtn.Disable()
-				RefreshCatalogueAction(win, authService, onFinishRefresh)
-			}),
+			refreshNowBtn,
 		))
 		listContent.Add(placeholder)
 	} else {
@@ -560,12 +764,18 @@ func LibraryTabUI(win fyne.Window, authService *auth.Service, dm *DownloadManage
 		refreshBtn.Disable()
 		RefreshCatalogueAction(win, authService, onFinishRefresh)
 	})
+	if dm.offline {
+		refreshBtn.Disable()
+	}
 
 	var exportBtn *widget.Button
 	exportBtn = widget.NewButtonWithIcon("Export", theme.DocumentSaveIcon(), func() {
 		popup := widget.NewPopUpMenu(fyne.NewMenu("",
-			fyne.NewMenuItem("Export Game List as CSV", func() { ExportCatalogueAction(win, "csv") }),
-			fyne.NewMenuItem("Export Full Catalogue as JSON", func() { ExportCatalogueAction(win, "json") }),
+			fyne.NewMenuItem("Export Game List as CSV", func() { ExportCatalogueAction(win, "csv", nil) }),
+			fyne.NewMenuItem("Export Full Catalogue as JSON", func() { ExportCatalogueAction(win, "json", nil) }),
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Export Displayed Games as CSV", func() { ExportCatalogueAction(win, "csv", displayedGames()) }),
+			fyne.NewMenuItem("Export Displayed Games as JSON", func() { ExportCatalogueAction(win, "json", displayedGames()) }),
 		), win.Canvas())
 		popup.ShowAtPosition(win.Content().Position().Add(fyne.NewPos(exportBtn.Position().X, exportBtn.Position().Y+exportBtn.Size().Height)))
 	})
@@ -595,7 +805,7 @@ func LibraryTabUI(win fyne.Window, authService *auth.Service, dm *DownloadManage
 	detailTitle.Alignment = fyne.TextAlignCenter
 	detailTitle.TextStyle = fyne.TextStyle{Bold: true}
 
-	accordion := createDetailsAccordion(win, authService, dm, selectedGameBinding)
+	accordion := createDetailsAccordion(win, authService, dm, selectedGameBinding, updateDisplayedGames)
 	topBox := container.NewVBox(detailTitle, widget.NewSeparator())
 	rightPane := container.NewBorder(topBox, nil, nil, nil, accordion)
 	accordion.Hide()
@@ -618,9 +828,12 @@ func LibraryTabUI(win fyne.Window, authService *auth.Service, dm *DownloadManage
 	}))
 	// Initialize persistence caches once UI is set up
 	initUpdateStatusPersistence()
-	catalogueUpdated.AddListener(binding.NewDataListener(func() {
-		clearPersistedUpdateStatus()
-		sizeCache = make(map[int]int64)
+	catalogueUpdated.AddListener(binding.NewDataListener(clearLibraryCaches))
+	cachesCleared.AddListener(binding.NewDataListener(func() {
+		runOnMain(func() {
+			updateDisplayedGames()
+			gameListWidget.Refresh()
+		})
 	}))
 	return &libraryTab{content: container.NewHSplit(leftPane, rightPane), searchEntry: searchEntry}
 }
@@ -633,9 +846,15 @@ func untypedSlice(games []db.Game) []interface{} {
 	return out
 }
 
-func createDetailsAccordion(win fyne.Window, authService *auth.Service, dm *DownloadManager, selectedGame binding.Untyped) *widget.Accordion {
+func createDetailsAccordion(win fyne.Window, authService *auth.Service, dm *DownloadManager, selectedGame binding.Untyped, onGameChanged func()) *widget.Accordion {
 	downloadForm := createDownloadForm(win, authService, dm, selectedGame)
-	accordion := widget.NewAccordion(widget.NewAccordionItem("Download Options", downloadForm))
+	tagsEditor := createTagsEditor(win, selectedGame, onGameChanged)
+	pinEditor := createPinEditor(win, selectedGame, onGameChanged)
+	accordion := widget.NewAccordion(
+		widget.NewAccordionItem("Download Options", downloadForm),
+		widget.NewAccordionItem("Tags", tagsEditor),
+		widget.NewAccordionItem("Pin", pinEditor),
+	)
 	accordion.Open(0)
 	return accordion
 }
@@ -643,12 +862,7 @@ func createDetailsAccordion(win fyne.Window, authService *auth.Service, dm *Down
 func createDownloadForm(win fyne.Window, authService *auth.Service, dm *DownloadManager, selectedGame binding.Untyped) fyne.CanvasObject {
 	prefs := fyne.CurrentApp().Preferences()
 	downloadPathEntry := widget.NewEntry()
-	lastUsedPath := prefs.String("lastUsedDownloadPath")
-	if lastUsedPath == "" {
-		lastUsedPath = prefs.StringWithFallback("downloadForm.path", "")
-	}
-	downloadPathEntry.SetText(lastUsedPath)
-	downloadPathEntry.OnChanged = func(s string) { prefs.SetString("downloadForm.path", s) }
+	downloadPathEntry.SetText(savedDownloadPath(prefs))
 	downloadPathEntry.SetPlaceHolder("Enter download path")
 	browseBtn := widget.NewButton("Browse...", func() {
 		fd := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
@@ -667,12 +881,13 @@ func createDownloadForm(win fyne.Window, authService *auth.Service, dm *Download
 		langCodes = append(langCodes, code)
 	}
 	sort.Strings(langCodes)
+	langCodes = append(langCodes, client.AllLanguages)
 	langSelect := widget.NewSelect(langCodes, func(s string) { prefs.SetString("downloadForm.language", s) })
 	langSelect.SetSelected(prefs.StringWithFallback("downloadForm.language", "en"))
-	platformSelect := widget.NewSelect([]string{"windows", "mac", "linux", "all"}, func(s string) { prefs.SetString("downloadForm.platform", s) })
+	platformSelect := widget.NewSelect([]string{"auto", "windows", "mac", "linux", "all"}, func(s string) { prefs.SetString("downloadForm.platform", s) })
 	platformSelect.SetSelected(prefs.StringWithFallback("downloadForm.platform", "windows"))
-	threadsSelect := widget.NewSelect([]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}, func(s string) { prefs.SetString("downloadForm.threads", s) })
-	threadsSelect.SetSelected(prefs.StringWithFallback("downloadForm.threads", "5"))
+	threadsSelect := widget.NewSelect(threadOptions(), func(s string) { prefs.SetString("downloadForm.threads", s) })
+	threadsSelect.SetSelected(prefs.StringWithFallback("downloadForm.threads", strconv.Itoa(validation.DefaultThreads(validation.NetworkWorkload))))
 
 	extrasCheck := widget.NewCheck("Include Extras", func(b bool) { prefs.SetBool("downloadForm.extras", b) })
 	extrasCheck.SetChecked(prefs.BoolWithFallback("downloadForm.extras", true))
@@ -699,19 +914,49 @@ func createDownloadForm(win fyne.Window, authService *auth.Service, dm *Download
 		_ = fyne.CurrentApp().OpenURL(parseURL(url))
 	})
 
+	// formDownload returns the download of the selected game with the current form options.
+	formDownload := func() (queuedDownload, bool) {
+		gameRaw, _ := selectedGame.Get()
+		if gameRaw == nil {
+			return queuedDownload{}, false
+		}
+		threads := parseThreads(threadsSelect.Selected)
+		langFull, _ := client.LanguageFilter(langSelect.Selected)
+		return queuedDownload{authService: authService, game: gameRaw.(db.Game), downloadPath: downloadPathEntry.Text, language: langFull, platformName: platformSelect.Selected, extrasFlag: extrasCheck.Checked, dlcFlag: dlcsCheck.Checked, resumeFlag: resumeCheck.Checked, flattenFlag: flattenCheck.Checked, skipPatchesFlag: skipPatchesCheck.Checked, keepLatestFlag: keepLatestCheck.Checked, rommLayoutFlag: rommCheck.Checked, numThreads: threads}, true
+	}
+
+	copyToClipboard := func(what, text string) {
+		fyne.CurrentApp().Clipboard().SetContent(text)
+		fyne.CurrentApp().SendNotification(fyne.NewNotification("Copied to Clipboard", what+" was copied."))
+	}
+	var copyBtn *widget.Button
+	copyBtn = widget.NewButtonWithIcon("Copy...", theme.ContentCopyIcon(), func() {
+		q, ok := formDownload()
+		if !ok {
+			return
+		}
+		menu := fyne.NewMenu("",
+			fyne.NewMenuItem("Copy Game ID", func() { copyToClipboard("The game ID", strconv.Itoa(q.game.ID)) }),
+			fyne.NewMenuItem("Copy Download Command", func() {
+				copyToClipboard("The download command", downloadCommandLine(runtime.GOOS, q, langSelect.Selected))
+			}),
+		)
+		c := fyne.CurrentApp().Driver().CanvasForObject(copyBtn)
+		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(copyBtn)
+		widget.ShowPopUpMenuAtPosition(menu, c, pos.Add(fyne.NewPos(0, copyBtn.Size().Height)))
+	})
+
 	downloadBtn := widget.NewButtonWithIcon("Download Game", theme.DownloadIcon(), func() {
 		if downloadPathEntry.Text == "" {
 			showErrorDialog(win, "Download path cannot be empty.", nil)
 			return
 		}
-		gameRaw, _ := selectedGame.Get()
-		if gameRaw == nil {
+		q, ok := formDownload()
+		if !ok {
 			return
 		}
-		game := gameRaw.(db.Game)
-		threads, _ := strconv.Atoi(threadsSelect.Selected)
-		langFull := client.GameLanguages[langSelect.Selected]
-		err := dm.QueueOrStart(queuedDownload{authService: authService, game: game, downloadPath: downloadPathEntry.Text, language: langFull, platformName: platformSelect.Selected, extrasFlag: extrasCheck.Checked, dlcFlag: dlcsCheck.Checked, resumeFlag: resumeCheck.Checked, flattenFlag: flattenCheck.Checked, skipPatchesFlag: skipPatchesCheck.Checked, keepLatestFlag: keepLatestCheck.Checked, rommLayoutFlag: rommCheck.Checked, numThreads: threads})
+		game := q.game
+		err := dm.QueueOrStart(q)
 		if err != nil {
 			if errors.Is(err, ErrDownloadInProgress) {
 				dialog.ShowInformation("In Progress", "This game is already being downloaded.", win)
@@ -723,6 +968,9 @@ func createDownloadForm(win fyne.Window, authService *auth.Service, dm *Download
 		}
 	})
 	downloadBtn.Importance = widget.HighImportance
+	if dm.offline {
+		downloadBtn.Disable()
+	}
 
 	form := widget.NewForm(
 		widget.NewFormItem("Download Path", pathContainer),
@@ -731,7 +979,50 @@ func createDownloadForm(win fyne.Window, authService *auth.Service, dm *Download
 		widget.NewFormItem("Threads", threadsSelect),
 	)
 	checkboxes := container.New(layout.NewGridLayout(2), extrasCheck, dlcsCheck, resumeCheck, flattenCheck, skipPatchesCheck, keepLatestCheck, rommCheck)
-	return container.NewVBox(form, checkboxes, layout.NewSpacer(), gogdbBtn, downloadBtn)
+	return container.NewVBox(form, checkboxes, layout.NewSpacer(), container.NewGridWithColumns(2, gogdbBtn, copyBtn), downloadBtn)
+}
+
+// downloadCommandLine returns a gogg download command for q that can be pasted into a shell of the given OS.
+// langCode is the language code selected in the form, since q holds the full language name.
+func downloadCommandLine(goos string, q queuedDownload, langCode string) string {
+	path := "<download_dir>" // a placeholder for the user to fill in
+	if q.downloadPath != "" {
+		path = shellQuote(goos, q.downloadPath)
+	}
+	args := []string{"gogg", "download", strconv.Itoa(q.game.ID), path,
+		"--lang=" + shellQuote(goos, langCode),
+		"--platform=" + q.platformName,
+		"--threads=" + strconv.Itoa(q.numThreads),
+		"--extras=" + strconv.FormatBool(q.extrasFlag),
+		"--dlcs=" + strconv.FormatBool(q.dlcFlag),
+		"--resume=" + strconv.FormatBool(q.resumeFlag),
+		"--flatten=" + strconv.FormatBool(q.flattenFlag),
+		"--skip-patches=" + strconv.FormatBool(q.skipPatchesFlag),
+	}
+	if q.keepLatestFlag {
+		args = append(args, "--keep-latest")
+	}
+	if q.rommLayoutFlag {
+		args = append(args, "--romm")
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes s for a shell of the given OS if it contains characters the shell would interpret.
+func shellQuote(goos, s string) string {
+	safe := "-_./:"
+	if goos == "windows" {
+		safe += `\`
+	}
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(safe, r))
+	}) < 0 {
+		return s
+	}
+	if goos == "windows" {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 func newUpdateSettingsButton(prefs fyne.Preferences, dm *DownloadManager, refresh func()) *widget.Button {
@@ -764,31 +1055,39 @@ func getGameDownloadDirectory(dm *DownloadManager, game db.Game) (string, bool)
 	if path, ok := getLastCompletedDownloadDir(dm, game.ID); ok {
 		return path, true
 	}
-	root := fyne.CurrentApp().Preferences().String("lastUsedDownloadPath")
+	root := savedDownloadPath(fyne.CurrentApp().Preferences())
 	if root == "" {
 		return "", false
 	}
-	candidate := filepath.Join(root, client.SanitizePath(game.Title))
+	candidate := filepath.Join(root, client.GameFolderName(client.DefaultFolderTemplate, game.Title, game.ID))
 	if _, err := os.Stat(filepath.Join(candidate, "metadata.json")); err == nil {
 		return candidate, true
 	}
 	return "", false
 }
 
-func readDownloadInfo(downloadDir string) (language, platform string) {
-	infoPath := filepath.Join(downloadDir, "download_info.json")
-	b, err := os.ReadFile(infoPath)
+// updateDetailsContent lists the changed files of a game, followed by the changelog and a link to the forum
+// of the game when GOG provides them.
+func updateDetailsContent(game db.Game, diff []string) fyne.CanvasObject {
+	content := container.NewVBox()
+	for _, line := range diff {
+		content.Add(widget.NewLabel(line))
+	}
+	parsed, err := client.ParseCachedGameData(game.ID, game.Data)
 	if err != nil {
-		return "", ""
+		return content
 	}
-	var info struct {
-		Language string `json:"language"`
-		Platform string `json:"platform"`
+	if changelog := parsed.ChangelogText(); changelog != "" {
+		content.Add(widget.NewSeparator())
+		content.Add(widget.NewLabelWithStyle("Changelog", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		changelogLabel := widget.NewLabel(changelog)
+		changelogLabel.Wrapping = fyne.TextWrapWord
+		content.Add(changelogLabel)
 	}
-	if json.Unmarshal(b, &info) != nil {
-		return "", ""
+	if u := parseURL(parsed.ForumLink); parsed.ForumLink != "" && u != nil {
+		content.Add(widget.NewHyperlink("Open the game's forum for release notes", u))
 	}
-	return info.Language, info.Platform
+	return content
 }
 
 func isPatchFile(f client.PlatformFile) bool {
@@ -835,7 +1134,7 @@ func buildVersionMapExtended(g client.Game, language, platform string, includeEx
 	}
 	if includeExtras {
 		for _, e := range g.Extras {
-			m["extras|"+e.Name] = ""
+			m["extras|"+e.Name] = client.ExtraRevision(e)
 		}
 	}
 	if includeDLCs {
@@ -856,7 +1155,7 @@ func buildVersionMapExtended(g client.Game, language, platform string, includeEx
 			}
 			if includeExtras {
 				for _, e := range dlc.Extras {
-					m["dlc_extras:"+client.SanitizePath(dlc.Title)+"|"+e.Name] = ""
+					m["dlc_extras:"+client.SanitizePath(dlc.Title)+"|"+e.Name] = client.ExtraRevision(e)
 				}
 			}
 		}
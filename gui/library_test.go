@@ -0,0 +1,231 @@
+package gui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/test"
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassesFilters_DownloadState(t *testing.T) {
+	test.NewTempApp(t)
+	t.Cleanup(func() {
+		resetFilters()
+		updateStatusCache = make(map[int]updateStatus)
+	})
+	updateStatusCache = map[int]updateStatus{
+		1: {Downloaded: true},
+		2: {Downloaded: true, HasUpdate: true},
+	}
+	downloaded, updated, missing := db.Game{ID: 1}, db.Game{ID: 2}, db.Game{ID: 3}
+
+	resetFilters()
+	assert.True(t, passesFilters(downloaded))
+	assert.True(t, passesFilters(missing))
+
+	filterDownloadState = downloadStateDownloaded
+	assert.True(t, passesFilters(downloaded))
+	assert.False(t, passesFilters(missing))
+
+	filterDownloadState = downloadStateNotDownloaded
+	assert.False(t, passesFilters(downloaded))
+	assert.True(t, passesFilters(missing))
+
+	filterDownloadState = downloadStateAny
+	filterHasUpdateOnly = true
+	assert.False(t, passesFilters(downloaded))
+	assert.True(t, passesFilters(updated))
+
+	pinnedGames = map[int]db.GamePin{2: {GameID: 2, Version: "1.0"}}
+	assert.False(t, passesFilters(updated), "the update of a pinned game is not shown as an update")
+	pinnedGames = nil
+
+	filterHasUpdateOnly = false
+	filterTag = "to play"
+	filterTagGames = map[int]struct{}{3: {}}
+	assert.False(t, passesFilters(downloaded))
+	assert.True(t, passesFilters(missing))
+}
+
+func TestFilters_PersistAcrossSessions(t *testing.T) {
+	prefs := test.NewTempApp(t).Preferences()
+	t.Cleanup(resetFilters)
+
+	filterDownloadState = downloadStateNotDownloaded
+	filterHasUpdateOnly = true
+	filterSizeMin = 1 << 30
+	filterSizeMax = 0
+	filterTag = "to play"
+	saveFilters(prefs)
+
+	resetFilters()
+	loadFilters(prefs)
+	assert.Equal(t, downloadStateNotDownloaded, filterDownloadState)
+	assert.True(t, filterHasUpdateOnly)
+	assert.Equal(t, int64(1<<30), filterSizeMin)
+	assert.Zero(t, filterSizeMax)
+	assert.Equal(t, "to play", filterTag)
+
+	prefs.SetString("libraryFilter.downloadState", "bogus")
+	loadFilters(prefs)
+	assert.Equal(t, downloadStateAny, filterDownloadState)
+}
+
+func TestDownloadCommandLine(t *testing.T) {
+	q := queuedDownload{
+		game: db.Game{ID: 1207658924}, downloadPath: "/home/me/My Games", language: "English", platformName: "linux",
+		extrasFlag: true, dlcFlag: false, resumeFlag: true, flattenFlag: true, numThreads: 4, keepLatestFlag: true,
+	}
+	assert.Equal(t,
+		"gogg download 1207658924 '/home/me/My Games' --lang=en --platform=linux --threads=4 --extras=true --dlcs=false"+
+			" --resume=true --flatten=true --skip-patches=false --keep-latest",
+		downloadCommandLine("linux", q, "en"))
+
+	q.downloadPath = `D:\Games`
+	q.keepLatestFlag = false
+	q.rommLayoutFlag = true
+	assert.Equal(t,
+		`gogg download 1207658924 D:\Games --lang=pt-BR --platform=linux --threads=4 --extras=true --dlcs=false`+
+			" --resume=true --flatten=true --skip-patches=false --romm",
+		downloadCommandLine("windows", q, "pt-BR"))
+
+	q.downloadPath = ""
+	assert.Contains(t, downloadCommandLine("linux", q, "en"), " <download_dir> ")
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "/games/gog", shellQuote("linux", "/games/gog"))
+	assert.Equal(t, `'it'\''s here'`, shellQuote("linux", "it's here"))
+	assert.Equal(t, `'C:\Games'`, shellQuote("linux", `C:\Games`))
+	assert.Equal(t, `C:\Games`, shellQuote("windows", `C:\Games`))
+	assert.Equal(t, `"C:\My Games"`, shellQuote("windows", `C:\My Games`))
+	assert.Equal(t, "''", shellQuote("linux", ""))
+}
+
+func TestBuildVersionMapExtended_ExtraSize(t *testing.T) {
+	old := client.Game{
+		Extras: []client.Extra{{Name: "Soundtrack", Size: "100 MB"}},
+		DLCs:   []client.DLC{{Title: "DLC", Extras: []client.Extra{{Name: "Artbook", Size: "20 MB"}}}},
+	}
+	current := client.Game{
+		Extras: []client.Extra{{Name: "Soundtrack", Size: "120 MB"}},
+		DLCs:   []client.DLC{{Title: "DLC", Extras: []client.Extra{{Name: "Artbook", Size: "20 MB"}}}},
+	}
+
+	oldMap := buildVersionMapExtended(old, "English", "windows", true, true, false)
+	newMap := buildVersionMapExtended(current, "English", "windows", true, true, false)
+	assert.NotEqual(t, oldMap["extras|Soundtrack"], newMap["extras|Soundtrack"], "a replaced extra is an update")
+	assert.Equal(t, oldMap["dlc_extras:dlc|Artbook"], newMap["dlc_extras:dlc|Artbook"])
+	assert.Empty(t, buildVersionMapExtended(current, "English", "windows", false, false, false), "extras are left out unless asked for")
+}
+
+func TestComputeGameUpdateStatus_ReusesUnchangedResults(t *testing.T) {
+	test.NewTempApp(t)
+	t.Cleanup(func() { updateStatusCache = make(map[int]updateStatus) })
+	updateStatusCache = make(map[int]updateStatus)
+
+	dir := t.TempDir()
+	data := `{"title":"Cached Game","downloads":[["English",{"windows":[{"name":"setup","version":"1.0","size":"1 MB"}]}]],"extras":[],"dlcs":[]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(data), 0o644))
+
+	dm := &DownloadManager{Tasks: binding.NewUntypedList()}
+	require.NoError(t, dm.Tasks.Append(&DownloadTask{ID: 7, State: StateCompleted, DownloadPath: dir, InstanceID: time.Now()}))
+	game := db.Game{ID: 7, Title: "Cached Game", Data: data}
+	opts := updateOptions{language: "English", platform: "windows"}
+
+	computeUpdateStatus(dm, []db.Game{game}, opts)
+	st, ok := getUpdateStatus(7)
+	require.True(t, ok)
+	assert.True(t, st.Downloaded)
+	assert.False(t, st.HasUpdate)
+	require.NotEmpty(t, st.Fingerprint)
+
+	// An unchanged game is not recomputed.
+	setUpdateStatus(7, updateStatus{Downloaded: true, Diff: []string{"sentinel"}, Fingerprint: st.Fingerprint})
+	assert.Equal(t, []string{"sentinel"}, computeGameUpdateStatus(dm, game, opts).Diff)
+
+	// New catalogue data changes the fingerprint, so the update is found.
+	game.Data = strings.Replace(data, `"version":"1.0"`, `"version":"2.0"`, 1)
+	st = computeGameUpdateStatus(dm, game, opts)
+	assert.True(t, st.HasUpdate)
+	assert.NotContains(t, st.Diff, "sentinel")
+}
+
+func TestPersistUpdateStatusCache_ConcurrentWrites(t *testing.T) {
+	test.NewTempApp(t)
+	path := filepath.Join(t.TempDir(), "update_status_cache.json")
+	origURI := updateStatusFileURI
+	updateStatusFileURI = storage.NewFileURI(path)
+	t.Cleanup(func() {
+		updateStatusFileURI = origURI
+		updateStatusCache = make(map[int]updateStatus)
+	})
+	updateStatusCache = make(map[int]updateStatus)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			setUpdateStatus(id, updateStatus{Downloaded: true, Diff: []string{strings.Repeat("x", id*100)}})
+			persistUpdateStatusCache()
+		}(i)
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var raw map[string]updateStatus
+	require.NoError(t, json.Unmarshal(data, &raw), "the cache file is valid JSON")
+	assert.Len(t, raw, 20, "the last write has every status")
+	assert.NoFileExists(t, path+".tmp")
+
+	updateStatusCache = make(map[int]updateStatus)
+	loadPersistedUpdateStatus()
+	assert.True(t, isGameDownloadedCached(20))
+}
+
+// BenchmarkEstimateGameSize estimates the sizes of a library, as the library tab does after the download settings
+// change, with the catalogue data parsed for every estimate (cold) and taken from the parsed game cache (warm).
+func BenchmarkEstimateGameSize(b *testing.B) {
+	test.NewTempApp(b)
+	games := make([]db.Game, 500)
+	for i := range games {
+		games[i] = db.Game{ID: i + 1, Title: "Game", Data: `{"title":"Game","downloads":[["English",{"windows":[` +
+			`{"manualUrl":"/w","name":"setup","size":"10 GB","version":"1.0"},{"manualUrl":"/w2","name":"setup part 2","size":"4 GB","version":"1.0"}],` +
+			`"linux":[{"manualUrl":"/l","name":"installer","size":"10 GB","version":"1.0"}]}]],` +
+			`"extras":[{"name":"Soundtrack","size":"500 MB","manualUrl":"/s"},{"name":"Manual","size":"10 MB","manualUrl":"/m"}],"dlcs":[]}`}
+	}
+	b.Cleanup(func() {
+		sizeCache = make(map[int]int64)
+		client.PurgeGameCache()
+	})
+	for _, warm := range []bool{false, true} {
+		name := "cold"
+		if warm {
+			name = "warm"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				sizeCache = make(map[int]int64)
+				if !warm {
+					client.PurgeGameCache()
+				}
+				for _, game := range games {
+					estimateGameSize(game)
+				}
+			}
+		})
+	}
+}
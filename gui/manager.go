@@ -3,18 +3,23 @@ package gui
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
 	"github.com/habedi/gogg/db"
 	"github.com/rs/zerolog/log"
 )
@@ -38,25 +43,70 @@ type DownloadTask struct {
 	CancelFunc   context.CancelFunc
 	FileStatus   binding.String
 	DownloadPath string
+	// Params are the options the download was started with. Tasks from the history of older versions have none.
+	Params *DownloadParams
 }
 
 // PersistentDownloadTask is a serializable representation of a finished task.
 type PersistentDownloadTask struct {
-	ID           int       `json:"id"`
-	InstanceID   time.Time `json:"instance_id"`
-	State        int       `json:"state"`
-	Title        string    `json:"title"`
-	StatusText   string    `json:"status_text"`
-	DownloadPath string    `json:"download_path"`
+	ID           int             `json:"id"`
+	InstanceID   time.Time       `json:"instance_id"`
+	State        int             `json:"state"`
+	Title        string          `json:"title"`
+	StatusText   string          `json:"status_text"`
+	DownloadPath string          `json:"download_path"`
+	Params       *DownloadParams `json:"params,omitempty"`
+}
+
+// DownloadParams are the options a download was started with. They are shown on its task and kept in the
+// download history, so the download can be retried.
+type DownloadParams struct {
+	DownloadPath string `json:"download_path"`
+	Language     string `json:"language"`
+	Platform     string `json:"platform"`
+	Extras       bool   `json:"extras"`
+	DLCs         bool   `json:"dlcs"`
+	Resume       bool   `json:"resume"`
+	Flatten      bool   `json:"flatten"`
+	SkipPatches  bool   `json:"skip_patches"`
+	KeepLatest   bool   `json:"keep_latest"`
+	RommLayout   bool   `json:"romm_layout"`
+	Threads      int    `json:"threads"`
+}
+
+// Summary returns the options in one line for the task card.
+func (p DownloadParams) Summary() string {
+	parts := []string{"Language: " + p.Language, "Platform: " + p.Platform}
+	for _, opt := range []struct {
+		name string
+		on   bool
+	}{
+		{"Extras", p.Extras}, {"DLCs", p.DLCs}, {"Resume", p.Resume}, {"Flatten", p.Flatten},
+		{"Skip patches", p.SkipPatches}, {"Keep latest", p.KeepLatest}, {"RomM layout", p.RommLayout},
+	} {
+		if opt.on {
+			parts = append(parts, opt.name)
+		}
+	}
+	parts = append(parts, fmt.Sprintf("Threads: %d", p.Threads))
+	return strings.Join(parts, " | ")
 }
 
 type DownloadManager struct {
 	mu          sync.RWMutex
 	Tasks       binding.UntypedList
 	historyPath fyne.URI
+	authService *auth.Service // for retrying downloads
 	queue       []queuedDownload
+	slotsMu     sync.Mutex
+	slots       *client.DownloadSlots
+	rates       *rateControl // nil until the GUI applies the speed limit
+	offline     bool         // set by --offline; downloads are refused
 }
 
+// defaultThreadBudget is the default number of files downloaded at the same time by all downloads together.
+const defaultThreadBudget = 10
+
 type queuedDownload struct {
 	authService     *auth.Service
 	game            db.Game
@@ -73,7 +123,27 @@ type queuedDownload struct {
 	numThreads      int
 }
 
-func NewDownloadManager() *DownloadManager {
+// params returns the options of q.
+func (q queuedDownload) params() DownloadParams {
+	return DownloadParams{
+		DownloadPath: q.downloadPath, Language: q.language, Platform: q.platformName,
+		Extras: q.extrasFlag, DLCs: q.dlcFlag, Resume: q.resumeFlag, Flatten: q.flattenFlag,
+		SkipPatches: q.skipPatchesFlag, KeepLatest: q.keepLatestFlag, RommLayout: q.rommLayoutFlag,
+		Threads: q.numThreads,
+	}
+}
+
+// queued returns the download of game with the options p.
+func (p DownloadParams) queued(authService *auth.Service, game db.Game) queuedDownload {
+	return queuedDownload{
+		authService: authService, game: game, downloadPath: p.DownloadPath, language: p.Language,
+		platformName: p.Platform, extrasFlag: p.Extras, dlcFlag: p.DLCs, resumeFlag: p.Resume,
+		flattenFlag: p.Flatten, skipPatchesFlag: p.SkipPatches, keepLatestFlag: p.KeepLatest,
+		rommLayoutFlag: p.RommLayout, numThreads: p.Threads,
+	}
+}
+
+func NewDownloadManager(authService *auth.Service) *DownloadManager {
 	a := fyne.CurrentApp()
 	historyURI, err := storage.Child(a.Storage().RootURI(), "download_history.json")
 	if err != nil {
@@ -83,6 +153,7 @@ func NewDownloadManager() *DownloadManager {
 	dm := &DownloadManager{
 		Tasks:       binding.NewUntypedList(),
 		historyPath: historyURI,
+		authService: authService,
 	}
 
 	dm.loadHistory()
@@ -138,6 +209,7 @@ func (dm *DownloadManager) loadHistory() {
 			Details:      binding.NewString(),
 			FileStatus:   binding.NewString(),
 			CancelFunc:   nil,
+			Params:       pTask.Params,
 		})
 	}
 	_ = dm.Tasks.Set(uiTasks)
@@ -162,6 +234,7 @@ func (dm *DownloadManager) PersistHistory() {
 				Title:        task.Title,
 				StatusText:   status,
 				DownloadPath: task.DownloadPath,
+				Params:       task.Params,
 			})
 		}
 	}
@@ -189,14 +262,19 @@ func DownloadsTabUI(dm *DownloadManager) fyne.CanvasObject {
 			title.Truncation = fyne.TextTruncateEllipsis
 
 			actionBtn := widget.NewButtonWithIcon("Action", theme.CancelIcon(), nil)
+			moreBtn := widget.NewButtonWithIcon("", theme.MoreHorizontalIcon(), nil)
+			moreBtn.Importance = widget.LowImportance
 			clearBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
 			clearBtn.Importance = widget.LowImportance
 
-			actionBox := container.NewHBox(actionBtn, clearBtn)
+			actionBox := container.NewHBox(actionBtn, moreBtn, clearBtn)
 			topRow := container.NewBorder(nil, nil, nil, actionBox, title)
 
 			status := widget.NewLabel("Status")
 			status.Wrapping = fyne.TextWrapWord
+			params := widget.NewLabel("")
+			params.TextStyle = fyne.TextStyle{Italic: true}
+			params.Wrapping = fyne.TextWrapWord
 			details := widget.NewLabel("Details")
 			details.TextStyle = fyne.TextStyle{Italic: true}
 			details.Wrapping = fyne.TextWrapWord
@@ -221,6 +299,7 @@ func DownloadsTabUI(dm *DownloadManager) fyne.CanvasObject {
 				topRow,
 				widget.NewSeparator(),
 				status,
+				params,
 				progressBox,
 				paddedFileStatus,
 			)
@@ -255,13 +334,15 @@ func DownloadsTabUI(dm *DownloadManager) fyne.CanvasObject {
 			topRow := contentVBox.Objects[0].(*fyne.Container)
 			// Objects[1] is separator
 			status := contentVBox.Objects[2].(*widget.Label)
-			progressBox := contentVBox.Objects[3].(*fyne.Container)
-			paddedFileStatus := contentVBox.Objects[4].(*fyne.Container)
+			params := contentVBox.Objects[3].(*widget.Label)
+			progressBox := contentVBox.Objects[4].(*fyne.Container)
+			paddedFileStatus := contentVBox.Objects[5].(*fyne.Container)
 
 			actionBox := topRow.Objects[1].(*fyne.Container)
 			title := topRow.Objects[0].(*widget.Label)
 			actionBtn := actionBox.Objects[0].(*widget.Button)
-			clearBtn := actionBox.Objects[1].(*widget.Button)
+			moreBtn := actionBox.Objects[1].(*widget.Button)
+			clearBtn := actionBox.Objects[2].(*widget.Button)
 
 			details := progressBox.Objects[0].(*widget.Label)
 			progress := progressBox.Objects[1].(*widget.ProgressBar)
@@ -271,6 +352,12 @@ func DownloadsTabUI(dm *DownloadManager) fyne.CanvasObject {
 			fileStatus := fileStatusScroll.Content.(*widget.Label)
 
 			title.SetText(task.Title)
+			if task.Params != nil {
+				params.SetText(task.Params.Summary())
+				params.Show()
+			} else {
+				params.Hide()
+			}
 			status.Bind(task.Status)
 			details.Bind(task.Details)
 			progress.Bind(task.Progress)
@@ -296,16 +383,38 @@ func DownloadsTabUI(dm *DownloadManager) fyne.CanvasObject {
 				actionBtn.SetText("Open Folder")
 				actionBtn.OnTapped = func() { openFolder(task.DownloadPath) }
 				actionBtn.Enable()
+				moreBtn.OnTapped = func() {
+					menu := fyne.NewMenu("",
+						fyne.NewMenuItem("Reveal in File Manager", func() { revealInFileManager(task.DownloadPath) }),
+						fyne.NewMenuItem("Open Terminal Here", func() { openTerminal(task.DownloadPath) }),
+					)
+					c := fyne.CurrentApp().Driver().CanvasForObject(moreBtn)
+					pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(moreBtn)
+					widget.ShowPopUpMenuAtPosition(menu, c, pos.Add(fyne.NewPos(0, moreBtn.Size().Height)))
+				}
+				moreBtn.Show()
 				clearBtn.Show()
 			case StateCancelled, StateError:
-				actionBtn.SetIcon(theme.ErrorIcon())
-				actionBtn.SetText("Error")
-				if task.State == StateCancelled {
-					actionBtn.SetIcon(theme.CancelIcon())
-					actionBtn.SetText("Cancelled")
+				if task.Params != nil {
+					actionBtn.SetIcon(theme.ViewRefreshIcon())
+					actionBtn.SetText("Retry")
+					actionBtn.OnTapped = func() {
+						if err := dm.Retry(task); err != nil {
+							dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+						}
+					}
+					actionBtn.Enable()
+				} else {
+					actionBtn.SetIcon(theme.ErrorIcon())
+					actionBtn.SetText("Error")
+					if task.State == StateCancelled {
+						actionBtn.SetIcon(theme.CancelIcon())
+						actionBtn.SetText("Cancelled")
+					}
+					actionBtn.OnTapped = nil
+					actionBtn.Disable()
 				}
-				actionBtn.OnTapped = nil
-				actionBtn.Disable()
+				moreBtn.Hide()
 				clearBtn.Show()
 			default: // Preparing, Downloading
 				actionBtn.SetIcon(theme.CancelIcon())
@@ -316,30 +425,103 @@ func DownloadsTabUI(dm *DownloadManager) fyne.CanvasObject {
 					}
 				}
 				actionBtn.Enable()
+				moreBtn.Hide()
 				clearBtn.Hide()
 			}
 		},
 	)
 
-	clearAllBtn := widget.NewButton("Clear All Finished", func() {
-		dm.mu.Lock()
-		currentTasks, _ := dm.Tasks.Get()
-		keptTasks := make([]interface{}, 0)
-		for _, taskRaw := range currentTasks {
-			task := taskRaw.(*DownloadTask)
-			if task.State != StateCompleted && task.State != StateCancelled && task.State != StateError {
-				keptTasks = append(keptTasks, task)
+	cancelAllBtn := widget.NewButtonWithIcon("Cancel All Active", theme.CancelIcon(), func() {
+		dialog.ShowConfirm("Cancel All Downloads", "Cancel every running download and remove the queued ones?", func(ok bool) {
+			if ok {
+				dm.CancelAll()
 			}
-		}
-		_ = dm.Tasks.Set(keptTasks)
-		dm.mu.Unlock()
-		dm.PersistHistory()
+		}, fyne.CurrentApp().Driver().AllWindows()[0])
 	})
-	bottomBar := container.NewHBox(layout.NewSpacer(), clearAllBtn)
+	clearAllBtn := widget.NewButton("Clear All Finished", dm.ClearFinished)
+	bottomBar := container.NewHBox(layout.NewSpacer(), cancelAllBtn, clearAllBtn)
 
 	return container.NewBorder(nil, bottomBar, nil, nil, list)
 }
 
+// ClearFinished removes the completed, cancelled, and failed downloads from the list and the download history.
+func (dm *DownloadManager) ClearFinished() {
+	dm.mu.Lock()
+	currentTasks, _ := dm.Tasks.Get()
+	keptTasks := make([]interface{}, 0)
+	for _, taskRaw := range currentTasks {
+		task := taskRaw.(*DownloadTask)
+		if task.State != StateCompleted && task.State != StateCancelled && task.State != StateError {
+			keptTasks = append(keptTasks, task)
+		}
+	}
+	_ = dm.Tasks.Set(keptTasks)
+	dm.mu.Unlock()
+	dm.PersistHistory()
+}
+
+// Retry removes the failed or cancelled task from the list and starts or queues its download again with the
+// same options, resuming the files that were partly downloaded. The game is read from the catalogue again.
+func (dm *DownloadManager) Retry(task *DownloadTask) error {
+	if task.Params == nil {
+		return errors.New("the options of this download are not known")
+	}
+	game, err := db.GetGameByID(task.ID)
+	if err != nil {
+		return err
+	}
+	if game == nil {
+		return fmt.Errorf("game %d is not in the catalogue anymore", task.ID)
+	}
+	q := task.Params.queued(dm.authService, *game)
+	q.resumeFlag = true
+	if err := dm.QueueOrStart(q); err != nil {
+		return err
+	}
+	dm.mu.Lock()
+	currentTasks, _ := dm.Tasks.Get()
+	keptTasks := make([]interface{}, 0, len(currentTasks))
+	for _, tRaw := range currentTasks {
+		if tRaw.(*DownloadTask) != task {
+			keptTasks = append(keptTasks, tRaw)
+		}
+	}
+	_ = dm.Tasks.Set(keptTasks)
+	dm.mu.Unlock()
+	dm.PersistHistory()
+	return nil
+}
+
+// CancelAll empties the queue, removes the placeholders of the queued downloads from the list, and cancels the
+// downloads that are preparing or running. It returns the number of downloads cancelled or removed.
+func (dm *DownloadManager) CancelAll() int {
+	dm.mu.Lock()
+	dm.queue = nil
+	currentTasks, _ := dm.Tasks.Get()
+	keptTasks := make([]interface{}, 0, len(currentTasks))
+	var cancels []context.CancelFunc
+	for _, taskRaw := range currentTasks {
+		task := taskRaw.(*DownloadTask)
+		if task.State == StatePreparing || task.State == StateDownloading {
+			if task.CancelFunc == nil {
+				// A placeholder of a queued download.
+				continue
+			}
+			cancels = append(cancels, task.CancelFunc)
+		}
+		keptTasks = append(keptTasks, task)
+	}
+	_ = dm.Tasks.Set(keptTasks)
+	removed := len(currentTasks) - len(keptTasks)
+	dm.mu.Unlock()
+
+	// The downloads mark their own tasks as cancelled once they notice.
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return removed + len(cancels)
+}
+
 func (dm *DownloadManager) activeCount() int {
 	dm.mu.RLock()
 	defer dm.mu.RUnlock()
@@ -366,7 +548,42 @@ func (dm *DownloadManager) maxConcurrent() int {
 	return fyne.CurrentApp().Preferences().IntWithFallback("download.maxConcurrent", 2)
 }
 
+func (dm *DownloadManager) threadBudget() int {
+	return fyne.CurrentApp().Preferences().IntWithFallback("download.threadBudget", defaultThreadBudget)
+}
+
+// downloadSlots returns the slots shared by all downloads, which keep the total number of files downloaded
+// at the same time within the thread budget. A changed budget resizes the same slots, so downloads that
+// are already running keep counting against it.
+func (dm *DownloadManager) downloadSlots() *client.DownloadSlots {
+	budget := dm.threadBudget()
+	dm.slotsMu.Lock()
+	defer dm.slotsMu.Unlock()
+	if dm.slots == nil {
+		dm.slots = client.NewDownloadSlots(budget)
+	} else {
+		dm.slots.Resize(budget)
+	}
+	return dm.slots
+}
+
+// shareThreads returns the number of workers for a new download that asked for requested workers when
+// active downloads are already running: an equal share of the budget, but at least one and at most requested.
+func shareThreads(requested, budget, active int) int {
+	share := budget / (active + 1)
+	if share < 1 {
+		share = 1
+	}
+	if requested > 0 && requested < share {
+		return requested
+	}
+	return share
+}
+
 func (dm *DownloadManager) QueueOrStart(q queuedDownload) error {
+	if dm.offline {
+		return ErrOffline
+	}
 	// Prevent duplicate active or queued
 	dm.mu.RLock()
 	all, _ := dm.Tasks.Get()
@@ -378,8 +595,9 @@ func (dm *DownloadManager) QueueOrStart(q queuedDownload) error {
 		}
 	}
 	dm.mu.RUnlock()
-	if dm.activeCount() < dm.maxConcurrent() {
-		return executeDownload(q.authService, dm, q.game, q.downloadPath, q.language, q.platformName, q.extrasFlag, q.dlcFlag, q.resumeFlag, q.flattenFlag, q.skipPatchesFlag, q.keepLatestFlag, q.rommLayoutFlag, q.numThreads)
+	if active := dm.activeCount(); active < dm.maxConcurrent() {
+		threads := shareThreads(q.numThreads, dm.threadBudget(), active)
+		return executeDownload(dm, q, threads)
 	}
 	// Enqueue
 	dm.mu.Lock()
@@ -403,7 +621,8 @@ func (dm *DownloadManager) QueueOrStart(q queuedDownload) error {
 
 func (dm *DownloadManager) startNextIfAvailable() {
 	for {
-		if dm.activeCount() >= dm.maxConcurrent() {
+		active := dm.activeCount()
+		if active >= dm.maxConcurrent() {
 			return
 		}
 		dm.mu.Lock()
@@ -428,6 +647,7 @@ func (dm *DownloadManager) startNextIfAvailable() {
 		}
 		_ = dm.Tasks.Set(filtered)
 		dm.mu.Unlock()
-		_ = executeDownload(next.authService, dm, next.game, next.downloadPath, next.language, next.platformName, next.extrasFlag, next.dlcFlag, next.resumeFlag, next.flattenFlag, next.skipPatchesFlag, next.keepLatestFlag, next.rommLayoutFlag, next.numThreads)
+		threads := shareThreads(next.numThreads, dm.threadBudget(), active)
+		_ = executeDownload(dm, next, threads)
 	}
 }
@@ -0,0 +1,145 @@
+package gui
+
+import (
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/test"
+	"github.com/habedi/gogg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestShareThreads(t *testing.T) {
+	tests := []struct {
+		name                      string
+		requested, budget, active int
+		want                      int
+	}{
+		{"first download gets what it asked for", 5, 10, 0, 5},
+		{"first download is capped by the budget", 15, 10, 0, 10},
+		{"second download gets half the budget", 8, 10, 1, 5},
+		{"third download gets a third of the budget", 8, 9, 2, 3},
+		{"at least one worker when the budget is used up", 5, 2, 4, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, shareThreads(tt.requested, tt.budget, tt.active))
+		})
+	}
+}
+
+func TestDownloadManager_DownloadSlotsFollowBudget(t *testing.T) {
+	a := test.NewTempApp(t)
+	a.Preferences().SetInt("download.threadBudget", 4)
+	dm := &DownloadManager{}
+	slots := dm.downloadSlots()
+	assert.Equal(t, 4, slots.Size())
+
+	// Running downloads hold the same slots, so a new budget resizes them instead of replacing them.
+	a.Preferences().SetInt("download.threadBudget", 2)
+	assert.Same(t, slots, dm.downloadSlots())
+	assert.Equal(t, 2, slots.Size())
+}
+
+func TestDownloadManager_QueueOrStartOffline(t *testing.T) {
+	dm := &DownloadManager{Tasks: binding.NewUntypedList(), offline: true}
+	err := dm.QueueOrStart(queuedDownload{game: db.Game{ID: 1, Title: "Game"}, numThreads: 2})
+	assert.ErrorIs(t, err, ErrOffline)
+	assert.Equal(t, 0, dm.Tasks.Length(), "nothing is started or queued")
+	assert.Empty(t, dm.queue)
+}
+
+func TestDownloadManager_CancelAll(t *testing.T) {
+	dm := &DownloadManager{Tasks: binding.NewUntypedList(), queue: []queuedDownload{{}, {}}}
+	var cancelled []int
+	task := func(id, state int, cancellable bool) *DownloadTask {
+		tk := &DownloadTask{ID: id, State: state, InstanceID: time.Now(), Status: binding.NewString()}
+		if cancellable {
+			tk.CancelFunc = func() { cancelled = append(cancelled, id) }
+		}
+		return tk
+	}
+	for _, tk := range []*DownloadTask{
+		task(1, StateDownloading, true),
+		task(2, StatePreparing, true),
+		task(3, StatePreparing, false), // queued placeholder
+		task(4, StateCompleted, false),
+		task(5, StateError, false),
+	} {
+		require.NoError(t, dm.Tasks.Append(tk))
+	}
+
+	assert.Equal(t, 3, dm.CancelAll())
+	assert.ElementsMatch(t, []int{1, 2}, cancelled)
+	assert.Empty(t, dm.queue)
+
+	all, _ := dm.Tasks.Get()
+	var ids []int
+	for _, tRaw := range all {
+		ids = append(ids, tRaw.(*DownloadTask).ID)
+	}
+	assert.Equal(t, []int{1, 2, 4, 5}, ids)
+}
+
+func TestDownloadManager_Retry(t *testing.T) {
+	a := test.NewTempApp(t)
+	// No free download slots, so the retried download is queued instead of started.
+	a.Preferences().SetInt("download.maxConcurrent", 0)
+	history, err := storage.Child(a.Storage().RootURI(), "download_history.json")
+	require.NoError(t, err)
+	gormDB, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	db.Db = gormDB
+	require.NoError(t, db.Db.AutoMigrate(&db.Game{}))
+	require.NoError(t, db.PutInGame(8, "Failed Game", `{"title":"Failed Game"}`))
+
+	dm := &DownloadManager{Tasks: binding.NewUntypedList(), historyPath: history}
+	params := DownloadParams{DownloadPath: "/games", Language: "English", Platform: "linux", Threads: 3}
+	failed := &DownloadTask{ID: 8, State: StateError, InstanceID: time.Now(), Status: binding.NewString(), Params: &params}
+	unknown := &DownloadTask{ID: 9, State: StateCancelled, InstanceID: time.Now(), Status: binding.NewString()}
+	require.NoError(t, dm.Tasks.Append(failed))
+	require.NoError(t, dm.Tasks.Append(unknown))
+
+	require.NoError(t, dm.Retry(failed))
+	require.Len(t, dm.queue, 1)
+	assert.Equal(t, "Failed Game", dm.queue[0].game.Title)
+	assert.Equal(t, "/games", dm.queue[0].downloadPath)
+	assert.Equal(t, 3, dm.queue[0].numThreads)
+	assert.True(t, dm.queue[0].resumeFlag)
+
+	all, _ := dm.Tasks.Get()
+	require.Len(t, all, 2)
+	assert.Same(t, unknown, all[0])
+	placeholder := all[1].(*DownloadTask)
+	assert.Equal(t, 8, placeholder.ID)
+	assert.Equal(t, StatePreparing, placeholder.State)
+
+	assert.Error(t, dm.Retry(unknown))
+	unknown.Params = &params
+	assert.EqualError(t, dm.Retry(unknown), "game 9 is not in the catalogue anymore")
+}
+
+func TestDownloadManager_HistoryKeepsParams(t *testing.T) {
+	a := test.NewTempApp(t)
+	history, err := storage.Child(a.Storage().RootURI(), "download_history.json")
+	require.NoError(t, err)
+	dm := &DownloadManager{Tasks: binding.NewUntypedList(), historyPath: history}
+	params := queuedDownload{downloadPath: "/games", language: "English", platformName: "windows", extrasFlag: true, numThreads: 5}.params()
+	status := binding.NewString()
+	require.NoError(t, dm.Tasks.Append(&DownloadTask{ID: 1, State: StateError, InstanceID: time.Now(), Status: status, Params: &params}))
+	dm.PersistHistory()
+
+	loaded := &DownloadManager{Tasks: binding.NewUntypedList(), historyPath: history}
+	loaded.loadHistory()
+	all, _ := loaded.Tasks.Get()
+	require.Len(t, all, 1)
+	require.NotNil(t, all[0].(*DownloadTask).Params)
+	assert.Equal(t, params, *all[0].(*DownloadTask).Params)
+	assert.Equal(t, "Language: English | Platform: windows | Extras | Threads: 5", params.Summary())
+}
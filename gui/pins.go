@@ -0,0 +1,113 @@
+package gui
+
+import (
+	"context"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/rs/zerolog/log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/widget"
+)
+
+// pinnedGames holds the pins of the pinned games. Their updates are shown as available for a pinned game
+// instead of as updates.
+var pinnedGames map[int]db.GamePin
+
+func pinRepo() db.PinRepository { return db.NewPinRepository(db.GetDB()) }
+
+// refreshPinnedGames loads the pinned games.
+func refreshPinnedGames() {
+	pins, err := pinRepo().List(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load pinned games")
+		return
+	}
+	pinnedGames = make(map[int]db.GamePin, len(pins))
+	for _, pin := range pins {
+		pinnedGames[pin.GameID] = pin
+	}
+}
+
+func isGamePinned(gameID int) bool {
+	_, ok := pinnedGames[gameID]
+	return ok
+}
+
+// createPinEditor shows whether the selected game is pinned and lets the user pin and unpin it.
+// onChange is called after the pin of a game changes.
+func createPinEditor(win fyne.Window, selectedGame binding.Untyped, onChange func()) fyne.CanvasObject {
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+	pinCheck := widget.NewCheck("Pin at the version in the catalogue", nil)
+
+	selected := func() (db.Game, bool) {
+		raw, _ := selectedGame.Get()
+		if raw == nil {
+			return db.Game{}, false
+		}
+		return raw.(db.Game), true
+	}
+
+	// loading is set while the check is updated to show the pin, so that it does not pin the game again.
+	var loading bool
+	reload := func() {
+		game, ok := selected()
+		if !ok {
+			return
+		}
+		pin, err := pinRepo().Get(context.Background(), game.ID)
+		if err != nil {
+			showErrorDialog(win, "Failed to load the pin", err)
+			return
+		}
+		loading = true
+		pinCheck.SetChecked(pin != nil)
+		loading = false
+		if pin == nil {
+			status.SetText("Not pinned. Pinned games are not updated by 'gogg mirror' and 'gogg download --all'.")
+			return
+		}
+		text := "Pinned at version " + displayVersion(pin.Version) + "."
+		if data, err := client.ParseCachedGameData(game.ID, game.Data); err == nil && client.GameVersion(data) != pin.Version {
+			text += " A newer version is available: " + displayVersion(client.GameVersion(data)) + "."
+		}
+		status.SetText(text)
+	}
+
+	pinCheck.OnChanged = func(pinned bool) {
+		game, ok := selected()
+		if loading || !ok {
+			return
+		}
+		var err error
+		if pinned {
+			var version string
+			if data, parseErr := client.ParseCachedGameData(game.ID, game.Data); parseErr == nil {
+				version = client.GameVersion(data)
+			}
+			err = pinRepo().Pin(context.Background(), game.ID, version)
+		} else {
+			err = pinRepo().Unpin(context.Background(), game.ID)
+		}
+		if err != nil {
+			showErrorDialog(win, "Failed to change the pin", err)
+		}
+		reload()
+		onChange()
+	}
+
+	selectedGame.AddListener(binding.NewDataListener(reload))
+	return container.NewVBox(pinCheck, status)
+}
+
+// displayVersion returns version, or a note that GOG lists none.
+func displayVersion(version string) string {
+	if version == "" {
+		return "(none)"
+	}
+	return version
+}
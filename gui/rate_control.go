@@ -0,0 +1,59 @@
+package gui
+
+import (
+	"context"
+	"sync"
+
+	"github.com/habedi/gogg/client"
+)
+
+// rateControl decides between the speed limit of the settings and the bandwidth schedule. A speed limit set
+// in the settings takes precedence and turns the schedule off; without one, the schedule applies.
+type rateControl struct {
+	mu       sync.Mutex
+	schedule *client.RateSchedule // nil if there is no valid schedule
+	stop     context.CancelFunc   // stops the schedule while it is applied
+}
+
+func newRateControl(schedule *client.RateSchedule) *rateControl {
+	return &rateControl{schedule: schedule}
+}
+
+// setSpeedLimit applies a speed limit of kbps KB/s from the settings. A limit of 0 or less means no limit set
+// in the settings, so the schedule applies again if there is one.
+func (rc *rateControl) setSpeedLimit(kbps int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if kbps > 0 {
+		if rc.stop != nil {
+			rc.stop()
+			rc.stop = nil
+		}
+		client.SetGlobalDownloadRateLimit(int64(kbps) * 1024)
+		return
+	}
+	if rc.schedule == nil {
+		client.SetGlobalDownloadRateLimit(0)
+		return
+	}
+	if rc.stop == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		rc.stop = cancel
+		go rc.schedule.Apply(ctx, client.RateScheduleInterval)
+	}
+}
+
+// scheduleStatus describes whether the bandwidth schedule is applied, for the settings. It is empty if there
+// is no schedule.
+func (rc *rateControl) scheduleStatus() string {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	switch {
+	case rc.schedule == nil:
+		return ""
+	case rc.stop != nil:
+		return "The bandwidth schedule sets the speed limit. Setting a limit here turns it off."
+	default:
+		return "The bandwidth schedule is off while a speed limit is set here. Clear the limit to turn it on."
+	}
+}
@@ -0,0 +1,33 @@
+package gui
+
+import (
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateControl_SpeedLimitTurnsScheduleOff(t *testing.T) {
+	t.Cleanup(func() { client.SetGlobalDownloadRateLimit(0) })
+	rc := newRateControl(&client.RateSchedule{Default: "1MB"})
+
+	rc.setSpeedLimit(0)
+	assert.NotNil(t, rc.stop, "without a speed limit the schedule applies")
+	assert.Contains(t, rc.scheduleStatus(), "The bandwidth schedule sets the speed limit")
+
+	rc.setSpeedLimit(500)
+	assert.Nil(t, rc.stop)
+	assert.Contains(t, rc.scheduleStatus(), "is off while a speed limit is set")
+
+	rc.setSpeedLimit(0)
+	assert.NotNil(t, rc.stop, "clearing the speed limit turns the schedule on again")
+	rc.setSpeedLimit(1) // stops the schedule
+}
+
+func TestRateControl_WithoutSchedule(t *testing.T) {
+	t.Cleanup(func() { client.SetGlobalDownloadRateLimit(0) })
+	rc := newRateControl(nil)
+	rc.setSpeedLimit(0)
+	assert.Nil(t, rc.stop)
+	assert.Empty(t, rc.scheduleStatus())
+}
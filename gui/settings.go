@@ -1,7 +1,9 @@
 package gui
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/habedi/gogg/client"
@@ -13,7 +15,49 @@ import (
 	"fyne.io/fyne/v2/widget"
 )
 
-func SettingsTabUI(win fyne.Window) fyne.CanvasObject {
+// downloadPathPref is the preference holding the download directory. It is updated from Settings and
+// whenever a download starts, and the download form starts with it.
+const downloadPathPref = "downloadForm.path"
+
+// speedLimitPref is the preference holding the speed limit in KB/s set in Settings; 0 means no limit.
+const speedLimitPref = "download.maxSpeedKBps"
+
+// legacyDownloadPathPref held the last used download directory in older versions. It took precedence
+// over downloadPathPref when the download form was created.
+const legacyDownloadPathPref = "lastUsedDownloadPath"
+
+// savedDownloadPath returns the saved download directory. A directory saved under the legacy preference
+// is moved to downloadPathPref the first time it is read.
+func savedDownloadPath(prefs fyne.Preferences) string {
+	if legacy := prefs.String(legacyDownloadPathPref); legacy != "" {
+		prefs.SetString(downloadPathPref, legacy)
+		prefs.RemoveValue(legacyDownloadPathPref)
+	}
+	return prefs.String(downloadPathPref)
+}
+
+// validateDownloadDir checks that path is an existing directory that files can be written to.
+func validateDownloadDir(path string) error {
+	if path == "" {
+		return errors.New("empty directory path")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("cannot access directory: %w", err)
+	}
+	if !info.IsDir() {
+		return errors.New("path is a file, not a directory")
+	}
+	f, err := os.CreateTemp(path, ".gogg-write-test-*")
+	if err != nil {
+		return fmt.Errorf("directory is not writable: %w", err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
+}
+
+func SettingsTabUI(win fyne.Window, dm *DownloadManager) fyne.CanvasObject {
 	prefs := fyne.CurrentApp().Preferences()
 	a := fyne.CurrentApp()
 
@@ -133,15 +177,32 @@ func SettingsTabUI(win fyne.Window) fyne.CanvasObject {
 	})
 	maxConcSelect.SetSelected(fmt.Sprintf("%d", prefs.IntWithFallback("download.maxConcurrent", 2)))
 
+	threadBudgetSelect := widget.NewSelect(threadOptions(), func(s string) {
+		prefs.SetInt("download.threadBudget", parseThreads(s))
+	})
+	threadBudgetSelect.SetSelected(fmt.Sprintf("%d", prefs.IntWithFallback("download.threadBudget", defaultThreadBudget)))
+
 	speedEntry := widget.NewEntry()
 	speedEntry.SetPlaceHolder("Speed limit KB/s (0=unlimited)")
-	if v := prefs.IntWithFallback("download.maxSpeedKBps", 0); v > 0 {
+	if v := prefs.IntWithFallback(speedLimitPref, 0); v > 0 {
 		speedEntry.SetText(fmt.Sprintf("%d", v))
 	}
+	scheduleLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+	setSpeedLimit := func(kbps int) {
+		prefs.SetInt(speedLimitPref, kbps)
+		if dm.rates == nil {
+			client.SetGlobalDownloadRateLimit(int64(max(kbps, 0)) * 1024)
+			return
+		}
+		dm.rates.setSpeedLimit(kbps)
+		scheduleLabel.SetText(dm.rates.scheduleStatus())
+	}
+	if dm.rates != nil {
+		scheduleLabel.SetText(dm.rates.scheduleStatus())
+	}
 	speedEntry.OnChanged = func(s string) {
 		if s == "" {
-			prefs.SetInt("download.maxSpeedKBps", 0)
-			client.SetGlobalDownloadRateLimit(0)
+			setSpeedLimit(0)
 			return
 		}
 		var val int
@@ -149,17 +210,76 @@ func SettingsTabUI(win fyne.Window) fyne.CanvasObject {
 		if err != nil {
 			return
 		}
-		prefs.SetInt("download.maxSpeedKBps", val)
-		if val <= 0 {
-			client.SetGlobalDownloadRateLimit(0)
-		} else {
-			client.SetGlobalDownloadRateLimit(int64(val) * 1024)
+		setSpeedLimit(val)
+	}
+	// --- Download Directory ---
+	downloadDirLabel := widget.NewLabel("")
+	showDownloadDir := func(path string) {
+		if path == "" {
+			path = "Not set"
 		}
+		downloadDirLabel.SetText(path)
 	}
+	showDownloadDir(savedDownloadPath(prefs))
+
+	selectDownloadDirBtn := widget.NewButton("Select Download Directory...", func() {
+		fd := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, win)
+				return
+			}
+			if uri == nil {
+				return
+			}
+			path := uri.Path()
+			if err := validateDownloadDir(path); err != nil {
+				showErrorDialog(win, fmt.Sprintf("Can't use %s as the download directory.\n\nChoose an existing directory you have write access to.", path), err)
+				return
+			}
+			prefs.SetString(downloadPathPref, path)
+			showDownloadDir(path)
+		}, win)
+		fd.Resize(fyne.NewSize(800, 600))
+		fd.Show()
+	})
+	downloadDirBox := container.NewVBox(
+		widget.NewLabel("Default download directory:"),
+		downloadDirLabel,
+		widget.NewLabelWithStyle("Used by the download form. Starting a download also updates it.", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+		selectDownloadDirBtn,
+	)
+
 	limitsBox := container.NewVBox(widget.NewLabel("Download Limits"), widget.NewForm(
 		widget.NewFormItem("Max Concurrent", maxConcSelect),
+		widget.NewFormItem("Total Threads", threadBudgetSelect),
 		widget.NewFormItem("Speed Limit", speedEntry),
-	))
+	), scheduleLabel)
+
+	// --- Caches ---
+	clearCachesBtn := widget.NewButton("Clear Caches...", func() {
+		historyCheck := widget.NewCheck("Also clear the download history", nil)
+		content := container.NewVBox(
+			widget.NewLabel("This clears the cached update status and size estimates of the library.\n"+
+				"They are recomputed the next time the library is shown."),
+			historyCheck,
+		)
+		dialog.ShowCustomConfirm("Clear Caches", "Clear", "Cancel", content, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			clearLibraryCaches()
+			if historyCheck.Checked {
+				dm.ClearFinished()
+			}
+			SignalCachesCleared()
+			dialog.ShowInformation("Caches Cleared", "The caches have been cleared.", win)
+		}, win)
+	})
+	cacheBox := container.NewVBox(
+		widget.NewLabel("Caches"),
+		widget.NewLabelWithStyle("Clear them if the library shows wrong downloaded or update states.", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+		clearCachesBtn,
+	)
 
 	// --- Layout ---
 	mainCard := widget.NewCard("Settings", "", container.NewVBox(
@@ -170,7 +290,11 @@ func SettingsTabUI(win fyne.Window) fyne.CanvasObject {
 		soundCheck,
 		soundConfigBox,
 		widget.NewSeparator(),
+		downloadDirBox,
+		widget.NewSeparator(),
 		limitsBox,
+		widget.NewSeparator(),
+		cacheBox,
 	))
 
 	return container.NewCenter(mainCard)
@@ -0,0 +1,45 @@
+package gui
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDownloadDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, validateDownloadDir(dir))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries, "the write check must not leave files behind")
+
+	assert.ErrorContains(t, validateDownloadDir(""), "empty directory path")
+	assert.ErrorContains(t, validateDownloadDir(filepath.Join(dir, "missing")), "cannot access directory")
+
+	file := filepath.Join(dir, "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("x"), 0o644))
+	assert.ErrorContains(t, validateDownloadDir(file), "not a directory")
+
+	if runtime.GOOS != "windows" && os.Geteuid() != 0 {
+		readOnly := filepath.Join(dir, "readonly")
+		assert.NoError(t, os.Mkdir(readOnly, 0o500))
+		assert.ErrorContains(t, validateDownloadDir(readOnly), "not writable")
+	}
+}
+
+func TestSavedDownloadPath_MigratesLegacyPreference(t *testing.T) {
+	prefs := test.NewTempApp(t).Preferences()
+	assert.Empty(t, savedDownloadPath(prefs))
+
+	prefs.SetString(downloadPathPref, "/old/form/path")
+	prefs.SetString(legacyDownloadPathPref, "/last/used")
+	assert.Equal(t, "/last/used", savedDownloadPath(prefs))
+	assert.Empty(t, prefs.String(legacyDownloadPathPref))
+
+	prefs.SetString(downloadPathPref, "/from/settings")
+	assert.Equal(t, "/from/settings", savedDownloadPath(prefs))
+}
@@ -2,8 +2,10 @@ package gui
 
 import (
 	"net/url"
+	"strconv"
 
 	"fyne.io/fyne/v2"
+	"github.com/habedi/gogg/pkg/validation"
 )
 
 // runOnMain schedules fn to run on the main Fyne thread
@@ -19,3 +21,21 @@ func parseURL(urlStr string) *url.URL {
 	}
 	return u
 }
+
+// threadOptions returns the choices of a thread count select, matching the range the CLI accepts.
+func threadOptions() []string {
+	options := make([]string, 0, validation.MaxThreads-validation.MinThreads+1)
+	for i := validation.MinThreads; i <= validation.MaxThreads; i++ {
+		options = append(options, strconv.Itoa(i))
+	}
+	return options
+}
+
+// parseThreads parses the value of a thread count select, clamped to the accepted range.
+func parseThreads(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return validation.MinThreads
+	}
+	return validation.ClampThreadCount(n)
+}
@@ -0,0 +1,19 @@
+package gui
+
+import (
+	"testing"
+
+	"github.com/habedi/gogg/pkg/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreadOptionsMatchValidation(t *testing.T) {
+	options := threadOptions()
+	assert.Equal(t, "1", options[0])
+	assert.Len(t, options, validation.MaxThreads)
+	for _, o := range options {
+		assert.NoError(t, validation.ValidateThreadCount(parseThreads(o)))
+	}
+	assert.Equal(t, validation.MaxThreads, parseThreads("50"))
+	assert.Equal(t, validation.MinThreads, parseThreads(""))
+}
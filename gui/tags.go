@@ -0,0 +1,196 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/habedi/gogg/db"
+	"github.com/rs/zerolog/log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// allTagsOption is the tag filter option that shows games regardless of their tags.
+const allTagsOption = "All tags"
+
+// filterTagGames holds the IDs of the games with the tag of the tag filter.
+var filterTagGames map[int]struct{}
+
+func tagRepo() db.TagRepository { return db.NewTagRepository(db.GetDB()) }
+
+// refreshTagFilter loads the games that have the tag of the tag filter.
+func refreshTagFilter() {
+	filterTagGames = nil
+	if filterTag == "" {
+		return
+	}
+	ids, err := tagRepo().GameIDs(context.Background(), filterTag)
+	if err != nil {
+		log.Error().Err(err).Str("tag", filterTag).Msg("Failed to load tagged games")
+		return
+	}
+	filterTagGames = make(map[int]struct{}, len(ids))
+	for _, id := range ids {
+		filterTagGames[id] = struct{}{}
+	}
+}
+
+// tagFilterOptions returns the options of the tag filter: every tag in use, plus the active filter tag
+// (so a filter whose tag was removed from every game can still be seen and changed).
+func tagFilterOptions() []string {
+	tags, err := tagRepo().List(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list tags")
+	}
+	options := []string{allTagsOption}
+	found := filterTag == ""
+	for _, tag := range tags {
+		options = append(options, tag)
+		found = found || tag == filterTag
+	}
+	if !found {
+		options = append(options, filterTag)
+	}
+	return options
+}
+
+// createTagsEditor shows the tags of the selected game and lets the user add and remove them.
+// onChange is called after the tags of any game change.
+func createTagsEditor(win fyne.Window, selectedGame binding.Untyped, onChange func()) fyne.CanvasObject {
+	tagsBox := container.NewHBox()
+	tagEntry := widget.NewSelectEntry(nil)
+	tagEntry.SetPlaceHolder("Add a tag (like \"to play\")")
+
+	selectedID := func() (int, bool) {
+		raw, _ := selectedGame.Get()
+		if raw == nil {
+			return 0, false
+		}
+		return raw.(db.Game).ID, true
+	}
+
+	var reload func()
+	reload = func() {
+		tagsBox.RemoveAll()
+		if all, err := tagRepo().List(context.Background()); err == nil {
+			tagEntry.SetOptions(all)
+		}
+		gameID, ok := selectedID()
+		if !ok {
+			return
+		}
+		tags, err := tagRepo().ForGame(context.Background(), gameID)
+		if err != nil {
+			showErrorDialog(win, "Failed to load tags", err)
+			return
+		}
+		if len(tags) == 0 {
+			tagsBox.Add(widget.NewLabelWithStyle("No tags", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}))
+		}
+		for _, tag := range tags {
+			btn := widget.NewButtonWithIcon(tag, theme.CancelIcon(), func() {
+				if err := tagRepo().Remove(context.Background(), gameID, tag); err != nil {
+					showErrorDialog(win, "Failed to remove tag", err)
+					return
+				}
+				reload()
+				onChange()
+			})
+			btn.Importance = widget.LowImportance
+			tagsBox.Add(btn)
+		}
+	}
+
+	addTag := func() {
+		gameID, ok := selectedID()
+		if !ok || tagEntry.Text == "" {
+			return
+		}
+		if err := tagRepo().Add(context.Background(), gameID, tagEntry.Text); err != nil {
+			showErrorDialog(win, "Failed to add tag", err)
+			return
+		}
+		tagEntry.SetText("")
+		reload()
+		onChange()
+	}
+	tagEntry.OnSubmitted = func(string) { addTag() }
+	addBtn := widget.NewButtonWithIcon("Add", theme.ContentAddIcon(), addTag)
+	manageBtn := widget.NewButtonWithIcon("Manage Tags...", theme.SettingsIcon(), func() {
+		showManageTagsDialog(win, func() {
+			reload()
+			onChange()
+		})
+	})
+
+	selectedGame.AddListener(binding.NewDataListener(reload))
+	return container.NewVBox(
+		container.NewHScroll(tagsBox),
+		container.NewBorder(nil, nil, nil, addBtn, tagEntry),
+		manageBtn,
+	)
+}
+
+// showManageTagsDialog lists every tag in use and lets the user rename or delete them.
+// onChange is called after a tag is renamed or deleted.
+func showManageTagsDialog(win fyne.Window, onChange func()) {
+	rows := container.NewVBox()
+
+	var reload func()
+	reload = func() {
+		rows.RemoveAll()
+		tags, err := tagRepo().List(context.Background())
+		if err != nil {
+			showErrorDialog(win, "Failed to list tags", err)
+			return
+		}
+		if len(tags) == 0 {
+			rows.Add(widget.NewLabel("No tags yet. Add tags to games in the Catalogue tab."))
+		}
+		for _, tag := range tags {
+			renameBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+				nameEntry := widget.NewEntry()
+				nameEntry.SetText(tag)
+				dialog.ShowForm("Rename Tag", "Rename", "Cancel", []*widget.FormItem{widget.NewFormItem("New name", nameEntry)}, func(ok bool) {
+					if !ok {
+						return
+					}
+					if err := tagRepo().Rename(context.Background(), tag, nameEntry.Text); err != nil {
+						showErrorDialog(win, "Failed to rename tag", err)
+						return
+					}
+					if filterTag == tag {
+						filterTag, _ = db.NormalizeTag(nameEntry.Text)
+						saveFilters(fyne.CurrentApp().Preferences())
+					}
+					reload()
+					onChange()
+				}, win)
+			})
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				dialog.ShowConfirm("Delete Tag", fmt.Sprintf("Remove the tag %q from every game?", tag), func(ok bool) {
+					if !ok {
+						return
+					}
+					if err := tagRepo().Delete(context.Background(), tag); err != nil {
+						showErrorDialog(win, "Failed to delete tag", err)
+						return
+					}
+					reload()
+					onChange()
+				}, win)
+			})
+			rows.Add(container.NewBorder(nil, nil, nil, container.NewHBox(renameBtn, deleteBtn), widget.NewLabel(tag)))
+		}
+	}
+	reload()
+
+	dlg := dialog.NewCustom("Manage Tags", "Close", container.NewVScroll(rows), win)
+	dlg.Resize(fyne.NewSize(420, 360))
+	dlg.Show()
+}
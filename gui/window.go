@@ -1,24 +1,41 @@
 package gui
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
+	"time"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/habedi/gogg/auth"
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/rs/zerolog/log"
 )
 
-func Run(version string, authService *auth.Service) {
-	myApp := app.NewWithID("com.github.habedi.gogg")
+// Run starts the GUI. With offline set, the catalogue can be browsed but the actions that connect to GOG are
+// disabled.
+func Run(version string, authService *auth.Service, offline bool) {
+	myApp := app.NewWithID(operations.GUIAppID)
 	myApp.SetIcon(AppLogo)
 
 	myApp.Settings().SetTheme(CreateThemeFromPreferences())
 
 	myWindow := myApp.NewWindow("GOGG GUI")
-	dm := NewDownloadManager()
+	dm := NewDownloadManager(authService)
+	dm.offline = offline
 	prefs := myApp.Preferences()
 
+	// A speed limit saved in the settings takes precedence over the bandwidth schedule.
+	dm.rates = newRateControl(loadRateSchedule())
+	dm.rates.setSpeedLimit(prefs.IntWithFallback(speedLimitPref, 0))
+
 	width := prefs.FloatWithFallback("windowWidth", 960)
 	height := prefs.FloatWithFallback("windowHeight", 640)
 	myWindow.Resize(fyne.NewSize(float32(width), float32(height)))
@@ -35,7 +52,7 @@ func Run(version string, authService *auth.Service) {
 		container.NewTabItemWithIcon("Catalogue", theme.ListIcon(), library.content),
 		container.NewTabItemWithIcon("Downloads", theme.DownloadIcon(), DownloadsTabUI(dm)),
 		container.NewTabItemWithIcon("File Ops", theme.DocumentIcon(), FileTabUI(myWindow)),
-		container.NewTabItemWithIcon("Settings", theme.SettingsIcon(), SettingsTabUI(myWindow)),
+		container.NewTabItemWithIcon("Settings", theme.SettingsIcon(), SettingsTabUI(myWindow, dm)),
 		container.NewTabItemWithIcon("About", theme.HelpIcon(), ShowAboutUI(version)),
 	)
 
@@ -47,7 +64,12 @@ func Run(version string, authService *auth.Service) {
 
 	mainTabs.SetTabLocation(container.TabLocationTop)
 
-	myWindow.SetContent(mainTabs)
+	banner := newSessionBanner()
+	if !offline {
+		go checkSession(authService, banner)
+	}
+
+	myWindow.SetContent(container.NewBorder(banner.content, nil, nil, nil, mainTabs))
 	mainTabs.SelectIndex(0) // Programmatically select the first tab to trigger OnSelected.
 
 	myWindow.ShowAndRun()
@@ -67,3 +89,46 @@ func FileTabUI(win fyne.Window) fyne.CanvasObject {
 		fileTabs,
 	)
 }
+
+// loadRateSchedule reads the bandwidth schedule next to the database. An invalid schedule is logged and
+// ignored, so the GUI still starts.
+func loadRateSchedule() *client.RateSchedule {
+	schedule, err := client.FindRateSchedule(filepath.Dir(db.Path))
+	if err != nil {
+		log.Warn().Err(err).Msg("Ignoring the bandwidth schedule")
+		return nil
+	}
+	return schedule
+}
+
+// sessionCheckTimeout bounds the check of the login at startup, so a slow network does not keep it pending.
+const sessionCheckTimeout = 30 * time.Second
+
+// sessionBanner is the warning shown above the tabs when the login has expired.
+type sessionBanner struct {
+	content *fyne.Container
+}
+
+func newSessionBanner() *sessionBanner {
+	b := &sessionBanner{}
+	label := widget.NewLabelWithStyle("Session expired — please re-login with 'gogg login' from your terminal.",
+		fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	closeBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), func() { b.content.Hide() })
+	closeBtn.Importance = widget.LowImportance
+	b.content = container.NewHBox(widget.NewIcon(theme.WarningIcon()), label, layout.NewSpacer(), closeBtn)
+	b.content.Hide()
+	return b
+}
+
+// checkSession checks the login in the background and shows the banner if it has expired, so that actions
+// which need it do not fail later without an explanation. A missing login is shown by the catalogue tab, and
+// failures to reach GOG say nothing about the login, so neither shows the banner.
+func checkSession(authService *auth.Service, banner *sessionBanner) {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionCheckTimeout)
+	defer cancel()
+	err := authService.CheckSession(ctx)
+	if errors.Is(err, auth.ErrSessionExpired) {
+		log.Warn().Err(err).Msg("The GOG session has expired")
+		runOnMain(banner.content.Show)
+	}
+}
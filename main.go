@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"strings"
+	"time"
 
 	"github.com/habedi/gogg/cmd"
 	"github.com/habedi/gogg/db"
@@ -11,6 +13,9 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// shutdownTimeout bounds how long an interrupted run waits for in-flight database operations.
+const shutdownTimeout = 3 * time.Second
+
 func main() {
 	log.Info().Msg("Gogg starting up")
 	configureLogLevelFromEnv()
@@ -20,8 +25,12 @@ func main() {
 		func(msg string) { log.Warn().Msg(msg) }, // avoid log.Fatal to keep control flow explicit
 		func(code int) {
 			log.Info().Msg("Shutdown initiated")
-			// graceful cleanup
-			db.Shutdown()
+			// graceful cleanup: give in-flight database writes a moment to finish
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			if err := db.Shutdown(ctx); err != nil {
+				log.Warn().Err(err).Msg("Database shutdown did not complete cleanly")
+			}
+			cancel()
 			log.Info().Msg("Shutdown complete")
 			os.Exit(code)
 		},
@@ -1,12 +1,21 @@
 package clierr
 
+import (
+	"context"
+	"errors"
+	"net"
+)
+
 // Type categorizes a CLI-facing error for consistent messaging & potential exit codes.
 type Type string
 
 const (
 	Validation Type = "validation"
+	Auth       Type = "auth"
+	Network    Type = "network"
 	NotFound   Type = "not_found"
 	Download   Type = "download"
+	Partial    Type = "partial"
 	Internal   Type = "internal"
 )
 
@@ -22,3 +31,22 @@ func (e *Error) Unwrap() error { return e.Err }
 
 // New constructs a new CLI Error.
 func New(t Type, msg string, err error) *Error { return &Error{Type: t, Message: msg, Err: err} }
+
+// Wrap constructs a CLI Error for err, classifying it by its cause: network failures get the Network type,
+// and everything else gets t.
+func Wrap(t Type, msg string, err error) *Error {
+	if IsNetwork(err) {
+		t = Network
+	}
+	return New(t, msg, err)
+}
+
+// IsNetwork reports whether err was caused by a network failure, like a refused connection or a DNS error.
+// Cancellations and deadlines of a context are not network failures.
+func IsNetwork(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -1,7 +1,11 @@
 package clierr
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"testing"
 )
 
@@ -154,8 +158,8 @@ func TestNew(t *testing.T) {
 
 func TestError_Types(t *testing.T) {
 	// Test that all type constants are defined correctly
-	types := []Type{Validation, NotFound, Download, Internal}
-	expected := []string{"validation", "not_found", "download", "internal"}
+	types := []Type{Validation, Auth, Network, NotFound, Download, Internal}
+	expected := []string{"validation", "auth", "network", "not_found", "download", "internal"}
 
 	for i, typ := range types {
 		if string(typ) != expected[i] {
@@ -245,3 +249,31 @@ func TestError_SpecialCharacters(t *testing.T) {
 		t.Errorf("Special characters not preserved: got %q, want %q", err.Error(), specialMsg)
 	}
 }
+
+func TestWrap_ClassifiesNetworkErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Type
+	}{
+		{"nil error", nil, Auth},
+		{"plain error", errors.New("bad token"), Auth},
+		{"dial error", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, Network},
+		{"url error", &url.Error{Op: "Get", URL: "https://gog.com", Err: &net.DNSError{Err: "no such host", Name: "gog.com"}}, Network},
+		{"wrapped url error", fmt.Errorf("failed to fetch: %w", &url.Error{Op: "Get", URL: "https://gog.com", Err: errors.New("EOF")}), Network},
+		{"deadline exceeded", fmt.Errorf("request: %w", context.DeadlineExceeded), Auth},
+		{"canceled", context.Canceled, Auth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Wrap(Auth, "msg", tt.err)
+			if e.Type != tt.want {
+				t.Errorf("Wrap().Type = %v, want %v", e.Type, tt.want)
+			}
+			if e.Err != tt.err {
+				t.Errorf("Wrap().Err = %v, want %v", e.Err, tt.err)
+			}
+		})
+	}
+}
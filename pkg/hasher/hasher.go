@@ -25,25 +25,30 @@ func IsValidHashAlgo(algo string) bool {
 	return false
 }
 
-// GenerateHashFromReader calculates the hash of content from an io.Reader.
-func GenerateHashFromReader(reader io.Reader, algo string) (string, error) {
-	var h hash.Hash
+// New returns a new hash.Hash for algo, which is one of HashAlgorithms.
+func New(algo string) (hash.Hash, error) {
 	switch strings.ToLower(algo) {
 	case "md5":
-		h = md5.New()
+		return md5.New(), nil
 	case "sha1":
-		h = sha1.New()
+		return sha1.New(), nil
 	case "sha256":
-		h = sha256.New()
+		return sha256.New(), nil
 	case "sha512":
-		h = sha512.New()
+		return sha512.New(), nil
 	default:
-		return "", fmt.Errorf("unsupported hash algorithm: %s", algo)
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algo)
 	}
+}
 
+// GenerateHashFromReader calculates the hash of content from an io.Reader.
+func GenerateHashFromReader(reader io.Reader, algo string) (string, error) {
+	h, err := New(algo)
+	if err != nil {
+		return "", err
+	}
 	if _, err := io.Copy(h, reader); err != nil {
 		return "", err
 	}
-
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
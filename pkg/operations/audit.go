@@ -0,0 +1,221 @@
+package operations
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/habedi/gogg/client"
+)
+
+// catalogueVersionPattern extracts the numeric part of a version string from the catalogue, like "1.2.3" from "1.2.3 (gog-4)".
+var catalogueVersionPattern = regexp.MustCompile(`\d+(?:\.\d+)*`)
+
+// AuditEntry describes one finding of an audit. Expected files carry their catalogue name and component,
+// local files their path relative to the audited directory; size mismatches carry both.
+type AuditEntry struct {
+	Name         string `json:"name,omitempty"`
+	Component    string `json:"component,omitempty"`
+	Platform     string `json:"platform,omitempty"`
+	Language     string `json:"language,omitempty"`
+	Path         string `json:"path,omitempty"`
+	ExpectedSize int64  `json:"expected_size,omitempty"`
+	ActualSize   int64  `json:"actual_size,omitempty"`
+}
+
+// AuditReport is the result of comparing a local directory with the files listed in the catalogue.
+type AuditReport struct {
+	Root           string       `json:"root"`
+	Matched        []AuditEntry `json:"matched"`
+	Missing        []AuditEntry `json:"missing"`
+	Unexpected     []AuditEntry `json:"unexpected"`
+	SizeMismatches []AuditEntry `json:"size_mismatches"`
+}
+
+// Clean reports whether the audit found no missing, unexpected, or mismatched files.
+func (r AuditReport) Clean() bool {
+	return len(r.Missing) == 0 && len(r.Unexpected) == 0 && len(r.SizeMismatches) == 0
+}
+
+type auditLocalFile struct {
+	path    string
+	rel     string
+	size    int64
+	matched bool
+}
+
+// sizeTolerance returns how far the size of a file on disk may differ from the catalogue size.
+// The catalogue rounds sizes (for example to "1.2 GB"), so an exact comparison is not possible.
+func sizeTolerance(expected int64) int64 {
+	const minTolerance = 1 << 20
+	if t := expected / 20; t > minTolerance {
+		return t
+	}
+	return minTolerance
+}
+
+func withinTolerance(expected, actual int64) bool {
+	return sizeDistance(expected, actual) <= sizeTolerance(expected)
+}
+
+func parseCatalogueVersion(version string) []int {
+	m := catalogueVersionPattern.FindString(version)
+	if m == "" {
+		return nil
+	}
+	var ver []int
+	for _, p := range strings.Split(m, ".") {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return nil
+		}
+		ver = append(ver, v)
+	}
+	return ver
+}
+
+func versionMatches(expected []int, path string) bool {
+	if expected == nil {
+		return false
+	}
+	f, ok := parseInstallerFile(path)
+	return ok && compareVersions(f.version, expected) == 0
+}
+
+// AuditGameFiles compares the files under root with the expected files of a game.
+// Files skipped by hashing (metadata, checksum files, and patterns in .goggignore) are ignored.
+// Extras are matched by file name; installers by version and approximate size, because the catalogue
+// does not list their file names. A file matched by name or version whose size is off is reported as a
+// size mismatch, and files that match nothing are reported as unexpected (like leftovers of an old version).
+func AuditGameFiles(root string, expected []client.ExpectedFile) (AuditReport, error) {
+	if _, err := os.Stat(root); err != nil {
+		return AuditReport{Root: root}, err
+	}
+	exclusions, err := HashExclusions(root, true, nil)
+	if err != nil {
+		return AuditReport{Root: root}, err
+	}
+	return auditGameFiles(root, expected, exclusions)
+}
+
+// auditGameFiles is AuditGameFiles with the patterns of the files to ignore given by the caller.
+func auditGameFiles(root string, expected []client.ExpectedFile, exclusions []string) (AuditReport, error) {
+	report := AuditReport{Root: root}
+	paths, err := FindFilesToHash(root, true, exclusions)
+	if err != nil {
+		return report, err
+	}
+
+	local := make([]*auditLocalFile, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return report, err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		local = append(local, &auditLocalFile{path: path, rel: filepath.ToSlash(rel), size: info.Size()})
+	}
+
+	// Match the files that can be identified by name first, then the largest files, which are the least ambiguous.
+	order := make([]client.ExpectedFile, len(expected))
+	copy(order, expected)
+	sort.SliceStable(order, func(i, j int) bool {
+		if (order[i].FileName != "") != (order[j].FileName != "") {
+			return order[i].FileName != ""
+		}
+		return order[i].Size > order[j].Size
+	})
+
+	// Files are matched in passes of decreasing certainty, so a file that can be identified by its name or
+	// version is not taken first by another expected file of a similar size.
+	tierOf := func(exp client.ExpectedFile, version []int, f *auditLocalFile) int {
+		switch {
+		case exp.FileName != "" && strings.EqualFold(filepath.Base(f.path), exp.FileName):
+			return 0
+		case exp.Size > 0 && withinTolerance(exp.Size, f.size) && !exp.Extra && versionMatches(version, f.path):
+			return 1
+		case exp.Size > 0 && withinTolerance(exp.Size, f.size):
+			return 2
+		}
+		return 3
+	}
+	found := make([]bool, len(order))
+	for tier := 0; tier < 3; tier++ {
+		for i, exp := range order {
+			if found[i] {
+				continue
+			}
+			version := parseCatalogueVersion(exp.Version)
+			var best *auditLocalFile
+			for _, f := range local {
+				if f.matched || tierOf(exp, version, f) != tier {
+					continue
+				}
+				if best == nil || sizeDistance(exp.Size, f.size) < sizeDistance(exp.Size, best.size) {
+					best = f
+				}
+			}
+			if best == nil {
+				continue
+			}
+			found[i], best.matched = true, true
+			entry := AuditEntry{Name: exp.Name, Component: exp.Component, Platform: exp.Platform, Language: exp.Language,
+				Path: best.rel, ExpectedSize: exp.Size, ActualSize: best.size}
+			if exp.Size > 0 && !withinTolerance(exp.Size, best.size) {
+				report.SizeMismatches = append(report.SizeMismatches, entry)
+			} else {
+				report.Matched = append(report.Matched, entry)
+			}
+		}
+	}
+	var unmatched []client.ExpectedFile
+	for i, exp := range order {
+		if !found[i] {
+			unmatched = append(unmatched, exp)
+		}
+	}
+
+	// An installer of the expected version with the wrong size is most likely incomplete or corrupt.
+	for _, exp := range unmatched {
+		version := parseCatalogueVersion(exp.Version)
+		var found *auditLocalFile
+		if !exp.Extra {
+			for _, f := range local {
+				if !f.matched && versionMatches(version, f.path) {
+					found = f
+					break
+				}
+			}
+		}
+		if found == nil {
+			report.Missing = append(report.Missing, AuditEntry{Name: exp.Name, Component: exp.Component,
+				Platform: exp.Platform, Language: exp.Language, ExpectedSize: exp.Size})
+			continue
+		}
+		found.matched = true
+		report.SizeMismatches = append(report.SizeMismatches, AuditEntry{
+			Name: exp.Name, Component: exp.Component, Platform: exp.Platform, Language: exp.Language,
+			Path: found.rel, ExpectedSize: exp.Size, ActualSize: found.size,
+		})
+	}
+
+	for _, f := range local {
+		if !f.matched {
+			report.Unexpected = append(report.Unexpected, AuditEntry{Path: f.rel, ActualSize: f.size})
+		}
+	}
+	return report, nil
+}
+
+func sizeDistance(a, b int64) int64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
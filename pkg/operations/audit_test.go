@@ -0,0 +1,105 @@
+package operations_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mib = 1 << 20
+
+func writeSizedFile(t *testing.T, dir, name string, size int64) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(size))
+	require.NoError(t, f.Close())
+}
+
+func TestAuditGameFiles_CategorizesFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, dir, "setup_game_1.1_(200).exe", 10*mib)
+	writeSizedFile(t, dir, "setup_game_1.1_(200)-1.bin", 20*mib)
+	writeSizedFile(t, dir, "setup_game_1.0_(100).exe", 10*mib)
+	writeSizedFile(t, dir, "extras/manual.pdf", 2*mib)
+	writeSizedFile(t, dir, "metadata.json", 100)
+
+	expected := []client.ExpectedFile{
+		{Name: "Game", Component: "Game", Version: "1.1", Size: 10 * mib},
+		{Name: "Game (Part 2 of 2)", Component: "Game", Version: "1.1", Size: 50 * mib},
+		{Name: "Manual", Component: "Game", Extra: true, FileName: "manual.pdf", Size: 2 * mib},
+		{Name: "Soundtrack", Component: "Game", Extra: true, FileName: "soundtrack.zip", Size: 30 * mib},
+	}
+
+	report, err := operations.AuditGameFiles(dir, expected)
+	require.NoError(t, err)
+
+	require.Len(t, report.Matched, 2)
+	assert.ElementsMatch(t, []string{"setup_game_1.1_(200).exe", "extras/manual.pdf"},
+		[]string{report.Matched[0].Path, report.Matched[1].Path})
+
+	require.Len(t, report.SizeMismatches, 1)
+	assert.Equal(t, "setup_game_1.1_(200)-1.bin", report.SizeMismatches[0].Path)
+	assert.Equal(t, int64(50*mib), report.SizeMismatches[0].ExpectedSize)
+	assert.Equal(t, int64(20*mib), report.SizeMismatches[0].ActualSize)
+
+	require.Len(t, report.Missing, 1)
+	assert.Equal(t, "Soundtrack", report.Missing[0].Name)
+
+	require.Len(t, report.Unexpected, 1)
+	assert.Equal(t, "setup_game_1.0_(100).exe", report.Unexpected[0].Path)
+	assert.False(t, report.Clean())
+}
+
+func TestAuditGameFiles_ToleratesRoundedSizes(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, dir, "setup_game_2.0.sh", 1180*mib)
+
+	report, err := operations.AuditGameFiles(dir, []client.ExpectedFile{
+		{Name: "Game", Component: "Game", Version: "2.0", Size: 1229 * mib}, // listed as "1.2 GB"
+	})
+	require.NoError(t, err)
+	assert.Len(t, report.Matched, 1)
+	assert.True(t, report.Clean())
+}
+
+func TestAuditGameFiles_ExtraOfSimilarSizeDoesNotTakeIdentifiedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, dir, "setup_game_1.1_(200).exe", 2*mib)
+	writeSizedFile(t, dir, "extras/manual.pdf", 2*mib)
+
+	// The soundtrack is larger, so it is matched first, and both files are within its size tolerance.
+	report, err := operations.AuditGameFiles(dir, []client.ExpectedFile{
+		{Name: "Game", Component: "Game", Version: "1.1", Size: 2 * mib},
+		{Name: "Manual", Component: "Game", Extra: true, FileName: "manual.pdf", Size: 2 * mib},
+		{Name: "Soundtrack", Component: "Game", Extra: true, FileName: "soundtrack.zip", Size: 3 * mib},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, report.Matched, 2)
+	for _, m := range report.Matched {
+		switch m.Name {
+		case "Game":
+			assert.Equal(t, "setup_game_1.1_(200).exe", m.Path)
+		case "Manual":
+			assert.Equal(t, "extras/manual.pdf", m.Path)
+		default:
+			t.Errorf("unexpected match of %s to %s", m.Name, m.Path)
+		}
+	}
+	require.Len(t, report.Missing, 1)
+	assert.Equal(t, "Soundtrack", report.Missing[0].Name)
+	assert.Empty(t, report.Unexpected)
+}
+
+func TestAuditGameFiles_MissingDirectory(t *testing.T) {
+	_, err := operations.AuditGameFiles(filepath.Join(t.TempDir(), "missing"), nil)
+	assert.Error(t, err)
+}
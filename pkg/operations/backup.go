@@ -0,0 +1,290 @@
+package operations
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/habedi/gogg/db"
+)
+
+// GUIAppID is the application ID of the GUI. Fyne keeps the GUI's preferences and storage files
+// (like the download history) in a directory named after it.
+const GUIAppID = "com.github.habedi.gogg"
+
+// Entry names inside a backup archive.
+const (
+	backupManifestName = "manifest.json"
+	backupDatabaseName = "data/games.db"
+	backupDataPrefix   = "data/"
+	backupGUIPrefix    = "gui/"
+)
+
+// BackupManifest describes the contents of a backup archive.
+type BackupManifest struct {
+	CreatedAt     time.Time `json:"created_at"`
+	IncludeTokens bool      `json:"include_tokens"`
+	Files         []string  `json:"files"`
+}
+
+// GUIStorageDir returns the directory where Fyne stores the GUI's preferences and files.
+// It mirrors the location Fyne uses on desktop systems.
+func GUIStorageDir() string {
+	home, _ := os.UserHomeDir()
+	var root string
+	switch runtime.GOOS {
+	case "darwin":
+		root = filepath.Join(home, "Library", "Preferences", "fyne")
+	case "windows":
+		root = filepath.Join(home, "AppData", "Roaming", "fyne")
+	default:
+		configDir, _ := os.UserConfigDir()
+		root = filepath.Join(configDir, "fyne")
+	}
+	return filepath.Join(root, GUIAppID)
+}
+
+// CreateBackup writes a zip archive to zipPath with a snapshot of the database, the JSON files in the data
+// directory (like the hash cache), and the GUI's storage files (like the download history and preferences).
+// Unless includeTokens is true, the authentication tokens are left out of the database snapshot.
+func CreateBackup(ctx context.Context, zipPath string, includeTokens bool) (BackupManifest, error) {
+	manifest := BackupManifest{CreatedAt: time.Now().UTC(), IncludeTokens: includeTokens}
+
+	tmpDir, err := os.MkdirTemp("", "gogg-backup-")
+	if err != nil {
+		return manifest, err
+	}
+	defer os.RemoveAll(tmpDir)
+	snapshot := filepath.Join(tmpDir, "games.db")
+	if err := db.Snapshot(ctx, snapshot, includeTokens); err != nil {
+		return manifest, fmt.Errorf("failed to snapshot the database: %w", err)
+	}
+
+	sources := map[string]string{backupDatabaseName: snapshot}
+	dataFiles, err := filepath.Glob(filepath.Join(filepath.Dir(db.Path), "*.json"))
+	if err != nil {
+		return manifest, err
+	}
+	for _, file := range dataFiles {
+		sources[backupDataPrefix+filepath.Base(file)] = file
+	}
+	guiFiles, err := os.ReadDir(GUIStorageDir())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return manifest, err
+	}
+	for _, entry := range guiFiles {
+		if entry.Type().IsRegular() {
+			sources[backupGUIPrefix+entry.Name()] = filepath.Join(GUIStorageDir(), entry.Name())
+		}
+	}
+	for name := range sources {
+		manifest.Files = append(manifest.Files, name)
+	}
+	sort.Strings(manifest.Files)
+
+	if err := os.MkdirAll(filepath.Dir(zipPath), 0o750); err != nil {
+		return manifest, err
+	}
+	tmpZip := zipPath + ".tmp"
+	if err := writeBackupArchive(tmpZip, manifest, sources); err != nil {
+		_ = os.Remove(tmpZip)
+		return manifest, err
+	}
+	return manifest, os.Rename(tmpZip, zipPath)
+}
+
+func writeBackupArchive(zipPath string, manifest BackupManifest, sources map[string]string) error {
+	out, err := os.OpenFile(zipPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(out)
+
+	writeErr := func() error {
+		w, err := zw.Create(backupManifestName)
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(w).Encode(manifest); err != nil {
+			return err
+		}
+		for _, name := range manifest.Files {
+			if err := addFileToZip(zw, name, sources[name]); err != nil {
+				return fmt.Errorf("failed to add %s to the backup: %w", sources[name], err)
+			}
+		}
+		return zw.Close()
+	}()
+	if closeErr := out.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	return writeErr
+}
+
+func addFileToZip(zw *zip.Writer, name, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
+
+// RestoreBackup restores a backup created by CreateBackup. The database is closed, replaced, and reopened.
+// If the backup does not include authentication tokens, the tokens of the current database are kept.
+func RestoreBackup(ctx context.Context, zipPath string) (BackupManifest, error) {
+	var manifest BackupManifest
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return manifest, err
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	mf, ok := files[backupManifestName]
+	if !ok {
+		return manifest, fmt.Errorf("%s is not a Gogg backup: %s is missing", zipPath, backupManifestName)
+	}
+	if err := readZipJSON(mf, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	if _, ok := files[backupDatabaseName]; !ok {
+		return manifest, fmt.Errorf("%s is not a Gogg backup: the database is missing", zipPath)
+	}
+
+	// Work out every target before touching anything, so an invalid archive changes nothing.
+	targets := make(map[string]string, len(manifest.Files))
+	for _, name := range manifest.Files {
+		if _, ok := files[name]; !ok {
+			return manifest, fmt.Errorf("backup is incomplete: %s is missing", name)
+		}
+		target, err := restoreTarget(name)
+		if err != nil {
+			return manifest, err
+		}
+		targets[name] = target
+	}
+
+	var savedToken *db.Token
+	if !manifest.IncludeTokens && db.GetDB() != nil {
+		savedToken, err = db.NewTokenRepository(db.GetDB()).Get(ctx)
+		if err != nil {
+			return manifest, fmt.Errorf("failed to read the current tokens: %w", err)
+		}
+	}
+	if err := db.CloseDB(); err != nil {
+		return manifest, err
+	}
+
+	if err := restoreFiles(manifest.Files, files, targets); err != nil {
+		// Files are replaced one by one, so the database is still whole; keep it usable.
+		if reopenErr := db.InitDB(); reopenErr != nil {
+			return manifest, errors.Join(err, fmt.Errorf("failed to reopen the database: %w", reopenErr))
+		}
+		return manifest, err
+	}
+
+	if err := db.InitDB(); err != nil {
+		return manifest, fmt.Errorf("failed to open the restored database: %w", err)
+	}
+	if savedToken != nil {
+		if err := db.NewTokenRepository(db.GetDB()).Upsert(ctx, savedToken); err != nil {
+			return manifest, fmt.Errorf("failed to keep the current tokens: %w", err)
+		}
+	}
+	return manifest, nil
+}
+
+// restoreFiles extracts the named archive entries to their targets. Each file is replaced in one step.
+func restoreFiles(names []string, files map[string]*zip.File, targets map[string]string) error {
+	for _, name := range names {
+		if name == backupDatabaseName {
+			for _, suffix := range []string{"-wal", "-shm"} {
+				if err := os.Remove(targets[name] + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+			}
+		}
+		if err := extractZipFile(files[name], targets[name]); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", targets[name], err)
+		}
+	}
+	return nil
+}
+
+// restoreTarget maps an archive entry to the file it is restored to. Only plain file names directly
+// under the known prefixes are accepted.
+func restoreTarget(name string) (string, error) {
+	if name == backupDatabaseName {
+		return db.Path, nil
+	}
+	for prefix, dir := range map[string]string{backupDataPrefix: filepath.Dir(db.Path), backupGUIPrefix: GUIStorageDir()} {
+		base, ok := strings.CutPrefix(name, prefix)
+		if ok && base != "" && base != "." && base != ".." && !strings.ContainsAny(base, `/\`) {
+			return filepath.Join(dir, base), nil
+		}
+	}
+	return "", fmt.Errorf("unexpected file in backup: %s", name)
+}
+
+func readZipJSON(f *zip.File, v interface{}) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(v)
+}
+
+func extractZipFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return err
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tmp := target + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, target)
+}
@@ -0,0 +1,135 @@
+package operations_test
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupBackupEnv opens a fresh database in a temporary data directory and points the GUI storage
+// directory at a temporary home.
+func setupBackupEnv(t *testing.T) (db.GameRepository, db.TokenRepository) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	oldPath, oldDb := db.Path, db.Db
+	db.Path = filepath.Join(t.TempDir(), "games.db")
+	require.NoError(t, db.InitDB())
+	t.Cleanup(func() {
+		_ = db.CloseDB()
+		db.Path, db.Db = oldPath, oldDb
+	})
+	return db.NewGameRepository(db.GetDB()), db.NewTokenRepository(db.GetDB())
+}
+
+func TestBackupAndRestore_KeepsCurrentTokensByDefault(t *testing.T) {
+	games, tokens := setupBackupEnv(t)
+	ctx := context.Background()
+	require.NoError(t, games.Put(ctx, db.Game{ID: 1, Title: "Backed Up Game", Data: "{}"}))
+	require.NoError(t, tokens.Upsert(ctx, &db.Token{AccessToken: "old", RefreshToken: "old-refresh"}))
+	cachePath := filepath.Join(filepath.Dir(db.Path), "hash_cache.json")
+	require.NoError(t, os.WriteFile(cachePath, []byte(`{}`), 0o600))
+	historyPath := filepath.Join(operations.GUIStorageDir(), "download_history.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(historyPath), 0o750))
+	require.NoError(t, os.WriteFile(historyPath, []byte(`[]`), 0o600))
+
+	zipPath := filepath.Join(t.TempDir(), "backup.zip")
+	manifest, err := operations.CreateBackup(ctx, zipPath, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"data/games.db", "data/hash_cache.json", "gui/download_history.json"}, manifest.Files)
+
+	// Change the state after the backup.
+	require.NoError(t, games.Clear(ctx))
+	require.NoError(t, tokens.Upsert(ctx, &db.Token{AccessToken: "new", RefreshToken: "new-refresh"}))
+	require.NoError(t, os.Remove(cachePath))
+	require.NoError(t, os.Remove(historyPath))
+
+	_, err = operations.RestoreBackup(ctx, zipPath)
+	require.NoError(t, err)
+
+	game, err := db.NewGameRepository(db.GetDB()).GetByID(ctx, 1)
+	require.NoError(t, err)
+	require.NotNil(t, game)
+	assert.Equal(t, "Backed Up Game", game.Title)
+	token, err := db.NewTokenRepository(db.GetDB()).Get(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.Equal(t, "new", token.AccessToken, "tokens that were not backed up must not be replaced")
+	assert.FileExists(t, cachePath)
+	assert.FileExists(t, historyPath)
+}
+
+func TestBackupAndRestore_IncludeTokens(t *testing.T) {
+	_, tokens := setupBackupEnv(t)
+	ctx := context.Background()
+	require.NoError(t, tokens.Upsert(ctx, &db.Token{AccessToken: "old", RefreshToken: "old-refresh"}))
+
+	zipPath := filepath.Join(t.TempDir(), "backup.zip")
+	_, err := operations.CreateBackup(ctx, zipPath, true)
+	require.NoError(t, err)
+	require.NoError(t, tokens.Upsert(ctx, &db.Token{AccessToken: "new", RefreshToken: "new-refresh"}))
+
+	manifest, err := operations.RestoreBackup(ctx, zipPath)
+	require.NoError(t, err)
+	assert.True(t, manifest.IncludeTokens)
+	token, err := db.NewTokenRepository(db.GetDB()).Get(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.Equal(t, "old", token.AccessToken)
+}
+
+func TestRestoreBackup_RejectsOtherArchives(t *testing.T) {
+	setupBackupEnv(t)
+	zipPath := filepath.Join(t.TempDir(), "not-a-backup.zip")
+	require.NoError(t, os.WriteFile(zipPath, []byte("not a zip"), 0o600))
+
+	_, err := operations.RestoreBackup(context.Background(), zipPath)
+	assert.Error(t, err)
+	assert.NotNil(t, db.GetDB(), "the database must stay open when nothing was restored")
+}
+
+func TestRestoreBackup_CorruptArchiveKeepsDatabaseOpen(t *testing.T) {
+	games, _ := setupBackupEnv(t)
+	ctx := context.Background()
+	require.NoError(t, games.Put(ctx, db.Game{ID: 1, Title: "Current Game", Data: "{}"}))
+	zipPath := filepath.Join(t.TempDir(), "backup.zip")
+	_, err := operations.CreateBackup(ctx, zipPath, false)
+	require.NoError(t, err)
+
+	// Damage the compressed database in the archive; the archive itself still opens.
+	zr, err := zip.OpenReader(zipPath)
+	require.NoError(t, err)
+	var offset, size int64
+	for _, f := range zr.File {
+		if f.Name == "data/games.db" {
+			offset, err = f.DataOffset()
+			require.NoError(t, err)
+			size = int64(f.CompressedSize64)
+		}
+	}
+	require.NoError(t, zr.Close())
+	require.NotZero(t, size)
+	data, err := os.ReadFile(zipPath)
+	require.NoError(t, err)
+	for i := offset + size/4; i < offset+size/2; i++ {
+		data[i] ^= 0xff
+	}
+	require.NoError(t, os.WriteFile(zipPath, data, 0o600))
+
+	_, err = operations.RestoreBackup(ctx, zipPath)
+	require.Error(t, err)
+	game, err := db.NewGameRepository(db.GetDB()).GetByID(ctx, 1)
+	require.NoError(t, err, "the database must be open again after a failed restore")
+	require.NotNil(t, game)
+	assert.Equal(t, "Current Game", game.Title)
+}
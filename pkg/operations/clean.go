@@ -0,0 +1,141 @@
+package operations
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/hasher"
+)
+
+// Reasons why PlanClean lists a file.
+const (
+	CleanIncomplete = "incomplete"
+	CleanTemporary  = "temporary"
+	CleanOrphaned   = "orphaned checksum"
+)
+
+// CleanEntry is a file that PlanClean found to be removable, with the reason why.
+type CleanEntry struct {
+	Path         string
+	Reason       string
+	Size         int64
+	ExpectedSize int64
+}
+
+// CleanPlan describes the files that would be removed by cleaning a download directory.
+type CleanPlan struct {
+	Entries    []CleanEntry
+	TotalBytes int64
+}
+
+// Files returns the paths of the files in the plan.
+func (p CleanPlan) Files() []string {
+	files := make([]string, len(p.Entries))
+	for i, e := range p.Entries {
+		files[i] = e.Path
+	}
+	return files
+}
+
+// tempSuffixes are the extensions of files that are only written while a file is downloaded or saved.
+var tempSuffixes = []string{".part", ".tmp"}
+
+// PlanClean finds the files under root that are left over from interrupted downloads, without removing
+// anything:
+//   - incomplete files: files in a game folder (a folder with a metadata.json) that are smaller than the
+//     catalogue size of the file they are matched with, as found by AuditGameFiles. Files that match their
+//     expected size are never listed, and neither are files the catalogue does not know.
+//   - temporary files: files ending in .part or .tmp.
+//   - orphaned checksums: sidecar files like "setup.exe.sha256" whose file is gone.
+//
+// With the RomM layout, the per-platform folders of a game are checked against the game's metadata too.
+func PlanClean(root string) (CleanPlan, error) {
+	var plan CleanPlan
+	if _, err := os.Stat(root); err != nil {
+		return plan, err
+	}
+	seen := make(map[string]bool)
+	add := func(e CleanEntry) {
+		if seen[e.Path] {
+			return
+		}
+		seen[e.Path] = true
+		plan.Entries = append(plan.Entries, e)
+		plan.TotalBytes += e.Size
+	}
+
+	var gameDirs []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		name := info.Name()
+		if name == "metadata.json" {
+			gameDirs = append(gameDirs, filepath.Dir(path))
+			return nil
+		}
+		for _, suffix := range tempSuffixes {
+			if strings.HasSuffix(name, suffix) {
+				add(CleanEntry{Path: path, Reason: CleanTemporary, Size: info.Size()})
+				return nil
+			}
+		}
+		if orphanedSidecar(path) {
+			add(CleanEntry{Path: path, Reason: CleanOrphaned, Size: info.Size()})
+		}
+		return nil
+	})
+	if err != nil {
+		return CleanPlan{}, err
+	}
+
+	for _, dir := range gameDirs {
+		game, err := client.ReadGameMetadata(dir)
+		if err != nil {
+			continue
+		}
+		// Expect every file of the game, so that a file is only listed if it is too small for all of them.
+		expected := game.ExpectedFiles(client.AllLanguages, "all", true, true)
+		for _, d := range client.GameDirs(filepath.Dir(dir), filepath.Base(dir), true, "all") {
+			if info, err := os.Stat(d); err != nil || !info.IsDir() {
+				continue
+			}
+			report, err := AuditGameFiles(d, expected)
+			if err != nil {
+				return CleanPlan{}, err
+			}
+			for _, m := range report.SizeMismatches {
+				if m.ActualSize < m.ExpectedSize {
+					add(CleanEntry{Path: filepath.Join(d, filepath.FromSlash(m.Path)), Reason: CleanIncomplete,
+						Size: m.ActualSize, ExpectedSize: m.ExpectedSize})
+				}
+			}
+		}
+	}
+
+	sort.Slice(plan.Entries, func(i, j int) bool { return plan.Entries[i].Path < plan.Entries[j].Path })
+	return plan, nil
+}
+
+// orphanedSidecar reports whether path is a checksum sidecar written by WriteSidecarHash for a file that no
+// longer exists. Checksum files of whole directories, like CHECKSUMS.sha256, are not sidecars.
+func orphanedSidecar(path string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	base := strings.TrimSuffix(path, "."+ext)
+	if strings.EqualFold(filepath.Base(base), "checksums") {
+		return false
+	}
+	for _, algo := range hasher.HashAlgorithms {
+		if ext == algo {
+			_, err := os.Stat(base)
+			return os.IsNotExist(err)
+		}
+	}
+	return false
+}
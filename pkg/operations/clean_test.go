@@ -0,0 +1,76 @@
+package operations_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cleanGameMetadata = `{"title":"Clean Game","downloads":[["English",{"windows":[` +
+	`{"manualUrl":"/downloads/clean_game/en1installer0","name":"Clean Game","version":"1.1","size":"20 MB"}],"linux":[` +
+	`{"manualUrl":"/downloads/clean_game/en3installer0","name":"Clean Game","version":"1.1","size":"30 MB"}]}]],` +
+	`"extras":[{"name":"Manual","size":"3 MB","manualUrl":"/downloads/clean_game/manual.pdf"}]}`
+
+func cleanPaths(plan operations.CleanPlan, root string) map[string]string {
+	paths := make(map[string]string)
+	for _, e := range plan.Entries {
+		rel, _ := filepath.Rel(root, e.Path)
+		paths[filepath.ToSlash(rel)] = e.Reason
+	}
+	return paths
+}
+
+func TestPlanClean(t *testing.T) {
+	root := t.TempDir()
+	gameDir := filepath.Join(root, "clean-game")
+	require.NoError(t, os.MkdirAll(gameDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(gameDir, "metadata.json"), []byte(cleanGameMetadata), 0600))
+	writeSizedFile(t, gameDir, "setup_clean_game_1.1_(100).exe", 5*mib)
+	writeSizedFile(t, gameDir, "clean_game_1_1_100.sh", 30*mib)
+	writeSizedFile(t, gameDir, "extras/manual.pdf", 1*mib)
+	writeSizedFile(t, gameDir, "setup_clean_game_1.0_(90).exe", 2*mib)
+	writeSizedFile(t, gameDir, "clean_game_1_1_100.sh.sha256", 80)
+	writeSizedFile(t, gameDir, "gone.exe.sha256", 80)
+	writeSizedFile(t, gameDir, "CHECKSUMS.sha256", 80)
+	writeSizedFile(t, gameDir, "big.zip.part", 4*mib)
+	writeSizedFile(t, root, "other/setup.exe.md5", 40)
+
+	plan, err := operations.PlanClean(root)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"clean-game/setup_clean_game_1.1_(100).exe": operations.CleanIncomplete,
+		"clean-game/extras/manual.pdf":              operations.CleanIncomplete,
+		"clean-game/gone.exe.sha256":                operations.CleanOrphaned,
+		"clean-game/big.zip.part":                   operations.CleanTemporary,
+		"other/setup.exe.md5":                       operations.CleanOrphaned,
+	}, cleanPaths(plan, root))
+	assert.Equal(t, int64(5*mib+1*mib+4*mib+80+40), plan.TotalBytes)
+	for _, e := range plan.Entries {
+		if e.Path == filepath.Join(gameDir, "setup_clean_game_1.1_(100).exe") {
+			assert.Equal(t, int64(20*mib), e.ExpectedSize)
+		}
+	}
+}
+
+func TestPlanClean_RommPlatformFolders(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "clean-game"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "clean-game", "metadata.json"), []byte(cleanGameMetadata), 0600))
+	writeSizedFile(t, root, "windows/clean-game/setup_clean_game_1.1_(100).exe", 20*mib)
+	writeSizedFile(t, root, "linux/clean-game/clean_game_1.1_(100).sh", 10*mib)
+
+	plan, err := operations.PlanClean(root)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"linux/clean-game/clean_game_1.1_(100).sh": operations.CleanIncomplete,
+	}, cleanPaths(plan, root))
+}
+
+func TestPlanClean_MissingRoot(t *testing.T) {
+	_, err := operations.PlanClean(filepath.Join(t.TempDir(), "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
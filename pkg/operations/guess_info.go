@@ -0,0 +1,236 @@
+package operations
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/habedi/gogg/client"
+)
+
+// installerPlatforms maps the extensions of GOG installers to their platforms.
+var installerPlatforms = map[string]string{
+	".exe": "windows", ".bin": "windows", ".msi": "windows",
+	".pkg": "mac", ".dmg": "mac",
+	".sh": "linux", ".deb": "linux",
+}
+
+// GuessDownloadInfo guesses the options that the files in the game folder root were downloaded with, for
+// downloads that have no download_info.json. It goes by the folder layout and the file names only, so it is a
+// best guess:
+//   - the platform is that of the installer extensions found (like .exe or .sh), or "all" for several;
+//   - the language is "all" if there are folders for several languages, else the language of the language
+//     folder, the only language of the game, or English if the game has it;
+//   - extras count as downloaded if there is an extras folder or a file named like an extra of the game;
+//   - DLCs count as downloaded if there is a DLC folder or a file named after a DLC;
+//   - the files count as flattened if installers lie outside of platform folders like windows;
+//   - patches count as skipped if the game has patches and no file is named like a patch.
+func GuessDownloadInfo(root string, game client.Game) (client.DownloadInfo, error) {
+	info := client.DownloadInfo{Resume: true}
+	if _, err := os.Stat(root); err != nil {
+		return info, err
+	}
+	exclusions, err := HashExclusions(root, true, nil)
+	if err != nil {
+		return info, err
+	}
+	paths, err := FindFilesToHash(root, true, exclusions)
+	if err != nil {
+		return info, err
+	}
+
+	extraNames := make(map[string]bool)
+	for _, f := range game.ExpectedFiles(client.AllLanguages, "all", true, true) {
+		if f.Extra && f.FileName != "" {
+			extraNames[strings.ToLower(f.FileName)] = true
+		}
+	}
+	var dlcSlugs []string
+	for _, dlc := range game.DLCs {
+		if slug := fileSlug(dlc.Title); slug != "" {
+			dlcSlugs = append(dlcSlugs, slug)
+		}
+	}
+
+	platforms := make(map[string]bool)
+	languages := make(map[string]bool)
+	var patches bool
+	for _, path := range paths {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			continue
+		}
+		name := strings.ToLower(filepath.Base(path))
+		inPlatformDir := false
+		for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/") {
+			if strings.HasPrefix(dir, "dlcs") {
+				info.DLCs = true
+			}
+			if strings.HasSuffix(dir, "extras") {
+				info.Extras = true
+			}
+			for _, p := range []string{"windows", "mac", "linux"} {
+				if dir == p || strings.HasSuffix(dir, "-"+p) {
+					inPlatformDir = true
+				}
+			}
+			if code, ok := client.LanguageCode(dir); ok && dir == code {
+				languages[code] = true
+			}
+		}
+		if extraNames[name] {
+			info.Extras = true
+			continue
+		}
+		for _, slug := range dlcSlugs {
+			if strings.Contains(fileSlug(name), slug) {
+				info.DLCs = true
+			}
+		}
+		platform, ok := installerPlatforms[filepath.Ext(name)]
+		if !ok {
+			continue
+		}
+		platforms[platform] = true
+		if strings.Contains(name, "patch") {
+			patches = true
+		}
+		if !inPlatformDir {
+			info.Flatten = true
+		}
+	}
+
+	switch len(platforms) {
+	case 0:
+	case 1:
+		for p := range platforms {
+			info.Platform = p
+		}
+	default:
+		info.Platform = "all"
+	}
+	info.Language = guessLanguage(languages, game)
+	info.SkipPatches = !patches && gameHasPatches(game)
+	return info, nil
+}
+
+// guessLanguage guesses the language of a download from the codes of the language folders found in it and
+// the languages that game offers.
+func guessLanguage(folders map[string]bool, game client.Game) string {
+	if len(folders) > 1 {
+		return client.AllLanguages
+	}
+	for code := range folders {
+		if _, fullName, ok := client.NormalizeLanguage(code); ok {
+			return fullName
+		}
+	}
+	var offered []string
+	seen := make(map[string]bool)
+	for _, d := range game.Downloads {
+		if _, fullName, ok := client.NormalizeLanguage(d.Language); ok && !seen[fullName] {
+			seen[fullName] = true
+			offered = append(offered, fullName)
+		}
+	}
+	english := client.GameLanguages["en"]
+	if len(offered) == 1 || (len(offered) > 1 && !seen[english]) {
+		return offered[0]
+	}
+	return english
+}
+
+func gameHasPatches(game client.Game) bool {
+	for _, d := range game.Downloads {
+		for _, files := range [][]client.PlatformFile{d.Platforms.Windows, d.Platforms.Mac, d.Platforms.Linux} {
+			for _, f := range files {
+				if client.IsPatchFile(f) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// fileSlug writes s in lower case with every run of other characters than letters and digits replaced by one
+// underscore, like GOG names its installers: "Expansion Pack" becomes "expansion_pack".
+func fileSlug(s string) string {
+	var b strings.Builder
+	pending := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if pending && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			pending = false
+			b.WriteRune(r)
+		} else {
+			pending = true
+		}
+	}
+	return b.String()
+}
+
+// DownloadedContent is what DetectDownloaded found in a game folder.
+type DownloadedContent struct {
+	// Info is the download_info.json of the folder, or nil if it has none or it cannot be read.
+	Info *client.DownloadInfo `json:"downloadInfo,omitempty"`
+	// Platforms are the platforms of the installers and platform folders found, sorted.
+	Platforms []string `json:"platforms"`
+	// Languages are the languages of the language folders found, sorted. Downloads of a single language have no
+	// language folders unless they were made with --language-folders=always.
+	Languages []string `json:"languages"`
+}
+
+// DetectDownloaded finds out which platforms and languages were downloaded into the game folder root, from its
+// download_info.json and from the installers and folders in it.
+func DetectDownloaded(root string) (DownloadedContent, error) {
+	content := DownloadedContent{Platforms: []string{}, Languages: []string{}}
+	if _, err := os.Stat(root); err != nil {
+		return content, err
+	}
+	if info, err := client.ReadDownloadInfo(root); err == nil {
+		content.Info = &info
+	}
+	exclusions, err := HashExclusions(root, true, nil)
+	if err != nil {
+		return content, err
+	}
+	paths, err := FindFilesToHash(root, true, exclusions)
+	if err != nil {
+		return content, err
+	}
+
+	platforms := make(map[string]bool)
+	languages := make(map[string]bool)
+	for _, path := range paths {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			continue
+		}
+		if platform, ok := installerPlatforms[strings.ToLower(filepath.Ext(path))]; ok {
+			platforms[platform] = true
+		}
+		for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/") {
+			for _, p := range []string{"windows", "mac", "linux"} {
+				if dir == p || strings.HasSuffix(dir, "-"+p) {
+					platforms[p] = true
+				}
+			}
+			if code, ok := client.LanguageCode(dir); ok && dir == code {
+				languages[client.GameLanguages[code]] = true
+			}
+		}
+	}
+	for p := range platforms {
+		content.Platforms = append(content.Platforms, p)
+	}
+	for l := range languages {
+		content.Languages = append(content.Languages, l)
+	}
+	sort.Strings(content.Platforms)
+	sort.Strings(content.Languages)
+	return content, nil
+}
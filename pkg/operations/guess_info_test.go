@@ -0,0 +1,97 @@
+package operations_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func guessTestGame() client.Game {
+	setup, patch, linux := "/downloads/game/en1installer0", "/downloads/game/en1patch1", "/downloads/game/en3installer0"
+	return client.Game{
+		Title: "Game",
+		Downloads: []client.Downloadable{
+			{Language: "English", Platforms: client.Platform{
+				Windows: []client.PlatformFile{{Name: "Game", ManualURL: &setup}, {Name: "Patch 1.1", ManualURL: &patch}},
+				Linux:   []client.PlatformFile{{Name: "Game", ManualURL: &linux}},
+			}},
+			{Language: "Deutsch", Platforms: client.Platform{Windows: []client.PlatformFile{{Name: "Game", ManualURL: &setup}}}},
+		},
+		Extras: []client.Extra{{Name: "Manual", ManualURL: "/downloads/game/manual.pdf"}},
+		DLCs:   []client.DLC{{Title: "Expansion Pack"}},
+	}
+}
+
+func TestGuessDownloadInfo_Flattened(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, dir, "setup_game_1.0.exe", 100)
+	writeSizedFile(t, dir, "manual.pdf", 10)
+
+	info, err := operations.GuessDownloadInfo(dir, guessTestGame())
+	require.NoError(t, err)
+	assert.Equal(t, client.DownloadInfo{Language: "English", Platform: "windows", Extras: true, Flatten: true,
+		SkipPatches: true, Resume: true}, info)
+}
+
+func TestGuessDownloadInfo_PlatformFolders(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, dir, "windows/setup_game_1.0.exe", 100)
+	writeSizedFile(t, dir, "windows/patch_game_1.0_to_1.1.exe", 10)
+	writeSizedFile(t, dir, "linux/game_1_0.sh", 100)
+	writeSizedFile(t, dir, "extras/manual.pdf", 10)
+	writeSizedFile(t, dir, "dlcs-expansion-pack-windows/setup_expansion_pack_1.0.exe", 50)
+
+	info, err := operations.GuessDownloadInfo(dir, guessTestGame())
+	require.NoError(t, err)
+	assert.Equal(t, client.DownloadInfo{Language: "English", Platform: "all", Extras: true, DLCs: true, Resume: true}, info)
+}
+
+func TestGuessDownloadInfo_LanguageFolders(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, dir, "windows/en/setup_game_1.0.exe", 100)
+	writeSizedFile(t, dir, "windows/de/setup_game_1.0.exe", 100)
+
+	info, err := operations.GuessDownloadInfo(dir, guessTestGame())
+	require.NoError(t, err)
+	assert.Equal(t, client.AllLanguages, info.Language)
+	assert.False(t, info.Flatten)
+
+	dir = t.TempDir()
+	writeSizedFile(t, dir, "de/setup_game_1.0.exe", 100)
+	info, err = operations.GuessDownloadInfo(dir, guessTestGame())
+	require.NoError(t, err)
+	assert.Equal(t, "Deutsch", info.Language)
+	assert.True(t, info.Flatten)
+}
+
+func TestDetectDownloaded(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, dir, "windows/en/setup_game_1.0.exe", 100)
+	writeSizedFile(t, dir, "windows/de/setup_game_1.0.exe", 100)
+	writeSizedFile(t, dir, "linux/en/game_1_0.sh", 100)
+	writeSizedFile(t, dir, "extras/manual.pdf", 10)
+	require.NoError(t, client.WriteDownloadInfo(dir, client.DownloadInfo{Language: client.AllLanguages, Platform: "all"},
+		client.DefaultFileModes))
+
+	content, err := operations.DetectDownloaded(dir)
+	require.NoError(t, err)
+	require.NotNil(t, content.Info)
+	assert.Equal(t, "all", content.Info.Platform)
+	assert.Equal(t, []string{"linux", "windows"}, content.Platforms)
+	assert.Equal(t, []string{"Deutsch", "English"}, content.Languages)
+
+	dir = t.TempDir()
+	writeSizedFile(t, dir, "setup_game_1.0.pkg", 100)
+	content, err = operations.DetectDownloaded(dir)
+	require.NoError(t, err)
+	assert.Nil(t, content.Info)
+	assert.Equal(t, []string{"mac"}, content.Platforms)
+	assert.Empty(t, content.Languages)
+
+	_, err = operations.DetectDownloaded(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
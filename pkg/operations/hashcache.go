@@ -0,0 +1,107 @@
+package operations
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/habedi/gogg/db"
+)
+
+// hashCacheFileName is the name of the hash cache file stored next to the database.
+const hashCacheFileName = "hash_cache.json"
+
+// hashCacheEntry is the cached hash of a file together with the size and modification time it was computed for.
+type hashCacheEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+	Hash    string `json:"hash"`
+}
+
+// HashCache stores previously computed file hashes keyed by algorithm and path.
+// A cached hash is only used while the file's size and modification time are unchanged.
+// It is safe for concurrent use.
+type HashCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]hashCacheEntry
+	dirty   bool
+}
+
+// DefaultHashCachePath returns the location of the hash cache inside Gogg's data directory.
+func DefaultHashCachePath() string {
+	return filepath.Join(filepath.Dir(db.Path), hashCacheFileName)
+}
+
+// LoadHashCache reads the hash cache from path. A missing file yields an empty cache.
+func LoadHashCache(path string) (*HashCache, error) {
+	c := &HashCache{path: path, entries: make(map[string]hashCacheEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, err
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]hashCacheEntry)
+	}
+	return c, nil
+}
+
+func hashCacheKey(path, algo string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return strings.ToLower(algo) + "|" + path
+}
+
+// Lookup returns the cached hash of path if the file has not changed since it was hashed.
+func (c *HashCache) Lookup(path, algo string, info os.FileInfo) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[hashCacheKey(path, algo)]
+	if !ok || entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() {
+		return "", false
+	}
+	return entry.Hash, true
+}
+
+// Store records the hash of path for the given file state.
+func (c *HashCache) Store(path, algo string, info os.FileInfo, hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[hashCacheKey(path, algo)] = hashCacheEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Hash: hash}
+	c.dirty = true
+}
+
+// Save writes the cache to disk if it has changed since it was loaded.
+func (c *HashCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,65 @@
+package operations_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hashWithCache(t *testing.T, file string, cache *operations.HashCache) operations.HashResult {
+	t.Helper()
+	results := operations.GenerateHashesWithOptions(context.Background(), []string{file}, "md5", 1, operations.HashOptions{Cache: cache})
+	res := <-results
+	require.NoError(t, res.Err)
+	return res
+}
+
+func TestHashCache_ReusesHashOfUnchangedFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "game.bin")
+	require.NoError(t, os.WriteFile(file, []byte("gogg-test"), 0600))
+	cachePath := filepath.Join(dir, "cache", "hash_cache.json")
+
+	cache, err := operations.LoadHashCache(cachePath)
+	require.NoError(t, err)
+	first := hashWithCache(t, file, cache)
+	assert.False(t, first.Cached)
+	require.NoError(t, cache.Save())
+
+	reloaded, err := operations.LoadHashCache(cachePath)
+	require.NoError(t, err)
+	second := hashWithCache(t, file, reloaded)
+	assert.True(t, second.Cached)
+	assert.Equal(t, first.Hash, second.Hash)
+}
+
+func TestHashCache_ModifiedFileBustsCache(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "game.bin")
+	require.NoError(t, os.WriteFile(file, []byte("gogg-test"), 0600))
+
+	cache, err := operations.LoadHashCache(filepath.Join(dir, "hash_cache.json"))
+	require.NoError(t, err)
+	first := hashWithCache(t, file, cache)
+
+	// Same size, different content and modification time.
+	require.NoError(t, os.WriteFile(file, []byte("gogg-TEST"), 0600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(file, later, later))
+
+	second := hashWithCache(t, file, cache)
+	assert.False(t, second.Cached)
+	assert.NotEqual(t, first.Hash, second.Hash)
+}
+
+func TestLoadHashCache_MissingFile(t *testing.T) {
+	cache, err := operations.LoadHashCache(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+	assert.NotNil(t, cache)
+}
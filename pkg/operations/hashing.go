@@ -2,8 +2,10 @@ package operations
 
 import (
 	"context"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -13,9 +15,73 @@ import (
 
 // HashResult represents the result of a single file hashing operation.
 type HashResult struct {
-	File string
-	Hash string
-	Err  error
+	File   string
+	Hash   string
+	Err    error
+	Cached bool // true if the hash was taken from the hash cache
+}
+
+// HashOptions holds optional settings for GenerateHashesWithOptions.
+type HashOptions struct {
+	// Cache, if set, is consulted before hashing a file and updated with newly computed hashes.
+	Cache *HashCache
+	// Progress, if set, is called while a file is hashed with the number of bytes of that file hashed so far
+	// and the file size. It is called from the worker goroutines, so it must be safe for concurrent use.
+	Progress func(file string, hashed, total int64)
+}
+
+// hashProgressInterval is the number of bytes hashed between two progress reports for the same file.
+const hashProgressInterval = 1 << 20
+
+// progressReader counts the bytes read from r and reports them to a HashOptions.Progress callback.
+type progressReader struct {
+	r        io.Reader
+	file     string
+	total    int64
+	read     int64
+	reported int64
+	report   func(file string, hashed, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if p.read-p.reported >= hashProgressInterval || (err == io.EOF && p.read != p.reported) {
+		p.reported = p.read
+		p.report(p.file, p.read, p.total)
+	}
+	return n, err
+}
+
+// HashProgressTracker turns the per-file reports of HashOptions.Progress into a running total of bytes
+// hashed across all files. The zero value is ready to use and it is safe for concurrent use.
+type HashProgressTracker struct {
+	mu      sync.Mutex
+	perFile map[string]int64
+	done    int64
+}
+
+// Update records that hashed bytes of file have been processed and returns the new overall total.
+func (t *HashProgressTracker) Update(file string, hashed int64) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.perFile == nil {
+		t.perFile = make(map[string]int64)
+	}
+	t.done += hashed - t.perFile[file]
+	t.perFile[file] = hashed
+	return t.done
+}
+
+// TotalSize returns the combined size of the given files, skipping files that cannot be read.
+func TotalSize(files []string) int64 {
+	var total int64
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
 }
 
 // DefaultHashExclusions is the list of patterns to exclude from hashing.
@@ -25,7 +91,37 @@ var DefaultHashExclusions = []string{
 	"*.md5", "*.sha1", "*.sha256", "*.sha512", "*.cksum", "*.sum", "*.sig", "*.asc", "*.gpg",
 }
 
+// HashIgnoreFileName is the name of the optional file in a directory that lists extra file name
+// patterns to exclude from hashing, one per line. Empty lines and lines starting with '#' are ignored.
+const HashIgnoreFileName = ".goggignore"
+
+// HashExclusions returns the exclusion patterns for hashing dir: DefaultHashExclusions (unless
+// useDefaults is false), the patterns listed in the directory's .goggignore file (if present), and extra.
+func HashExclusions(dir string, useDefaults bool, extra []string) ([]string, error) {
+	var exclusions []string
+	if useDefaults {
+		exclusions = append(exclusions, DefaultHashExclusions...)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, HashIgnoreFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		exclusions = append(exclusions, HashIgnoreFileName)
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			exclusions = append(exclusions, line)
+		}
+	}
+	return append(exclusions, extra...), nil
+}
+
 // FindFilesToHash walks a directory and returns a slice of file paths to be processed.
+// The files are sorted by their slash-separated path relative to dir, so the order (and any checksum
+// file generated from it) is the same on every platform.
 func FindFilesToHash(dir string, recursive bool, exclusions []string) ([]string, error) {
 	var filesToProcess []string
 	walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -46,11 +142,67 @@ func FindFilesToHash(dir string, recursive bool, exclusions []string) ([]string,
 		filesToProcess = append(filesToProcess, path)
 		return nil
 	})
+	sortByRelativePath(dir, filesToProcess)
 	return filesToProcess, walkErr
 }
 
+func sortByRelativePath(dir string, files []string) {
+	key := func(path string) string {
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			return filepath.ToSlash(rel)
+		}
+		return filepath.ToSlash(path)
+	}
+	sort.SliceStable(files, func(i, j int) bool { return key(files[i]) < key(files[j]) })
+}
+
+// IsInstallerFile reports whether path has one of the installer extensions that are considered when pruning
+// old versions, like .exe, .bin, .dmg, .pkg, .sh, or .tar.gz.
+func IsInstallerFile(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	for ext := range installerExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterFilesToHash keeps only the files that are installers (if installersOnly is set) and whose
+// names match at least one of the include patterns (if any are given).
+func FilterFilesToHash(files []string, installersOnly bool, includes []string) []string {
+	if !installersOnly && len(includes) == 0 {
+		return files
+	}
+	filtered := make([]string, 0, len(files))
+	for _, f := range files {
+		if installersOnly && !IsInstallerFile(f) {
+			continue
+		}
+		if len(includes) > 0 && !matchesAny(filepath.Base(f), includes) {
+			continue
+		}
+		filtered = append(filtered, f)
+	}
+	return filtered
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
 // GenerateHashes concurrently generates hashes for a list of files.
 func GenerateHashes(ctx context.Context, files []string, algo string, numThreads int) <-chan HashResult {
+	return GenerateHashesWithOptions(ctx, files, algo, numThreads, HashOptions{})
+}
+
+// GenerateHashesWithOptions is like GenerateHashes but accepts optional settings such as a hash cache.
+func GenerateHashesWithOptions(ctx context.Context, files []string, algo string, numThreads int, opts HashOptions) <-chan HashResult {
 	tasks := make(chan string, len(files))
 	results := make(chan HashResult, len(files))
 
@@ -67,17 +219,7 @@ func GenerateHashes(ctx context.Context, files []string, algo string, numThreads
 				default:
 				}
 
-				func() {
-					file, err := os.Open(filePath)
-					if err != nil {
-						results <- HashResult{File: filePath, Err: err}
-						return
-					}
-					defer file.Close()
-
-					hash, err := hasher.GenerateHashFromReader(file, algo)
-					results <- HashResult{File: filePath, Hash: hash, Err: err}
-				}()
+				results <- hashFile(filePath, algo, opts)
 			}
 		}()
 	}
@@ -95,6 +237,51 @@ func GenerateHashes(ctx context.Context, files []string, algo string, numThreads
 	return results
 }
 
+func hashFile(filePath, algo string, opts HashOptions) HashResult {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return HashResult{File: filePath, Err: err}
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return HashResult{File: filePath, Err: err}
+	}
+	if opts.Cache != nil {
+		if hash, ok := opts.Cache.Lookup(filePath, algo, info); ok {
+			if opts.Progress != nil {
+				opts.Progress(filePath, info.Size(), info.Size())
+			}
+			return HashResult{File: filePath, Hash: hash, Cached: true}
+		}
+	}
+
+	var reader io.Reader = file
+	if opts.Progress != nil {
+		reader = &progressReader{r: file, file: filePath, total: info.Size(), report: opts.Progress}
+	}
+	hash, err := hasher.GenerateHashFromReader(reader, algo)
+	if err == nil && opts.Cache != nil {
+		opts.Cache.Store(filePath, algo, info, hash)
+	}
+	return HashResult{File: filePath, Hash: hash, Err: err}
+}
+
+// FormatSumLine formats a hash and a file path as a line of a checksum file in the format used by
+// md5sum and sha256sum ("<hash>  <path>"), so the output can be checked with those tools.
+func FormatSumLine(hash, path string) string {
+	return hash + "  " + filepath.ToSlash(path)
+}
+
+// WriteSidecarHash writes the hash of a file to a sidecar file next to it, named after the file
+// with the algorithm as an extra extension (like "setup.exe.sha256"). It returns the sidecar path.
+func WriteSidecarHash(file, algo, hash string) (string, error) {
+	sidecar := file + "." + strings.ToLower(algo)
+	line := FormatSumLine(hash, filepath.Base(file)) + "\n"
+	return sidecar, os.WriteFile(sidecar, []byte(line), 0644)
+}
+
 // CleanHashes walks a directory and removes files with extensions matching known hash algorithms.
 func CleanHashes(dir string, recursive bool) error {
 	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
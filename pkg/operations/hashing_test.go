@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"sync"
 	"testing"
 
 	"github.com/habedi/gogg/pkg/operations"
@@ -85,3 +86,95 @@ func TestCleanHashes(t *testing.T) {
 	_, err = os.Stat(filepath.Join(dir, "subdir", "sub.txt"))
 	assert.NoError(t, err, "Regular file should still exist")
 }
+
+func TestGenerateHashesWithOptions_ReportsProgress(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "big.bin")
+	size := int64(3*1024*1024 + 123)
+	require.NoError(t, os.WriteFile(filePath, make([]byte, size), 0600))
+
+	var mu sync.Mutex
+	var reports []int64
+	opts := operations.HashOptions{Progress: func(file string, hashed, total int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, filePath, file)
+		assert.Equal(t, size, total)
+		reports = append(reports, hashed)
+	}}
+
+	res := <-operations.GenerateHashesWithOptions(context.Background(), []string{filePath}, "sha1", 1, opts)
+	require.NoError(t, res.Err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, reports)
+	assert.Greater(t, len(reports), 1, "large files should report intermediate progress")
+	assert.True(t, sort.SliceIsSorted(reports, func(i, j int) bool { return reports[i] < reports[j] }))
+	assert.Equal(t, size, reports[len(reports)-1])
+	assert.Equal(t, size, operations.TotalSize([]string{filePath, filepath.Join(dir, "missing")}))
+}
+
+func TestFilterFilesToHash(t *testing.T) {
+	files := []string{
+		filepath.Join("game", "setup_game_1.0.exe"),
+		filepath.Join("game", "setup_game_1.0-1.bin"),
+		filepath.Join("game", "game_1.0.tar.gz"),
+		filepath.Join("game", "extras", "wallpaper.jpg"),
+		filepath.Join("game", "extras", "soundtrack.zip"),
+	}
+
+	assert.Equal(t, files, operations.FilterFilesToHash(files, false, nil))
+	assert.Equal(t, []string{
+		filepath.Join("game", "setup_game_1.0.exe"),
+		filepath.Join("game", "setup_game_1.0-1.bin"),
+		filepath.Join("game", "game_1.0.tar.gz"),
+		filepath.Join("game", "extras", "soundtrack.zip"),
+	}, operations.FilterFilesToHash(files, true, nil))
+	assert.Equal(t, []string{
+		filepath.Join("game", "setup_game_1.0.exe"),
+	}, operations.FilterFilesToHash(files, true, []string{"setup_*.exe"}))
+	assert.Equal(t, []string{
+		filepath.Join("game", "extras", "wallpaper.jpg"),
+	}, operations.FilterFilesToHash(files, false, []string{"*.jpg"}))
+}
+
+func TestFindFilesToHash_StableOrder(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"b.bin",
+		"a/z.bin",
+		"a.bin",
+		"a/b/c.bin",
+		"a-b.bin",
+	)
+
+	files, err := operations.FindFilesToHash(dir, true, nil)
+	require.NoError(t, err)
+
+	var rel []string
+	for _, f := range files {
+		r, err := filepath.Rel(dir, f)
+		require.NoError(t, err)
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	// A plain walk would list the files of directory "a" before "a-b.bin" and "a.bin".
+	assert.Equal(t, []string{"a-b.bin", "a.bin", "a/b/c.bin", "a/z.bin", "b.bin"}, rel)
+}
+
+func TestHashExclusions(t *testing.T) {
+	dir := t.TempDir()
+
+	exclusions, err := operations.HashExclusions(dir, false, []string{"*.tmp"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.tmp"}, exclusions)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, operations.HashIgnoreFileName), []byte("# comment\n\n*.iso\n  *.bak  \n"), 0600))
+	exclusions, err = operations.HashExclusions(dir, true, nil)
+	require.NoError(t, err)
+	assert.Subset(t, exclusions, operations.DefaultHashExclusions)
+	assert.Contains(t, exclusions, "*.iso")
+	assert.Contains(t, exclusions, "*.bak")
+	assert.Contains(t, exclusions, operations.HashIgnoreFileName)
+	assert.NotContains(t, exclusions, "# comment")
+}
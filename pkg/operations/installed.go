@@ -0,0 +1,156 @@
+package operations
+
+import (
+	"os"
+	"sort"
+
+	"github.com/habedi/gogg/client"
+)
+
+// States of the files in an InstalledReport, from the most to the least pressing.
+const (
+	InstalledMissing    = "missing"
+	InstalledOutdated   = "outdated"
+	InstalledIncomplete = "size mismatch"
+	InstalledUnexpected = "unexpected"
+	InstalledUpToDate   = "up to date"
+)
+
+var installedStateOrder = map[string]int{
+	InstalledMissing: 0, InstalledOutdated: 1, InstalledIncomplete: 2, InstalledUnexpected: 3, InstalledUpToDate: 4,
+}
+
+// InstalledFile compares one file that the catalogue lists for a game with what is in the game's folder.
+// InstalledVersion is the version in the metadata.json of the download, and Path is empty for missing files.
+type InstalledFile struct {
+	Status           string `json:"status"`
+	Name             string `json:"name,omitempty"`
+	Component        string `json:"component,omitempty"`
+	Platform         string `json:"platform,omitempty"`
+	Language         string `json:"language,omitempty"`
+	InstalledVersion string `json:"installed_version,omitempty"`
+	ExpectedVersion  string `json:"expected_version,omitempty"`
+	Path             string `json:"path,omitempty"`
+	ExpectedSize     int64  `json:"expected_size,omitempty"`
+	ActualSize       int64  `json:"actual_size,omitempty"`
+}
+
+// InstalledReport is the result of DiffInstalled.
+type InstalledReport struct {
+	Root  string          `json:"root"`
+	Files []InstalledFile `json:"files"`
+}
+
+// Count returns how many files of the report have status.
+func (r InstalledReport) Count(status string) int {
+	n := 0
+	for _, f := range r.Files {
+		if f.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// UpToDate reports whether every file of the report is up to date.
+func (r InstalledReport) UpToDate() bool {
+	return r.Count(InstalledUpToDate) == len(r.Files)
+}
+
+// installedExclusions are the files in a game folder that DiffInstalled ignores: the files gogg writes next to
+// a download and those of the operating system. Unlike with DefaultHashExclusions, leftover documents like
+// *.txt or *.html files are reported as unexpected.
+var installedExclusions = []string{
+	".git", ".DS_Store", "Thumbs.db", "desktop.ini", "metadata.json",
+	"*.md5", "*.sha1", "*.sha256", "*.sha512", "*.cksum", "*.sum",
+}
+
+// DiffInstalled compares the files under root with the expected files of current, the game as the catalogue
+// lists it now, matching them like AuditGameFiles but ignoring only installedExclusions and .goggignore.
+// installed is the metadata.json that was saved with the download, or nil if there is none. A file is
+// outdated if its version (or, for extras and files without a version, its size) in installed differs from
+// current; without installed, files can only be found missing or of the wrong size. Files in root that match
+// nothing are listed as unexpected, which is often where the old version of an outdated file is. The files
+// are sorted with the most pressing states first.
+func DiffInstalled(root string, current client.Game, installed *client.Game, language, platformName string, extras, dlcs bool) (InstalledReport, error) {
+	report := InstalledReport{Root: root}
+	expected := current.ExpectedFiles(language, platformName, extras, dlcs)
+	if _, err := os.Stat(root); err != nil {
+		return report, err
+	}
+	exclusions, err := HashExclusions(root, false, installedExclusions)
+	if err != nil {
+		return report, err
+	}
+	audit, err := auditGameFiles(root, expected, exclusions)
+	if err != nil {
+		return report, err
+	}
+
+	old := make(map[string]client.ExpectedFile)
+	if installed != nil {
+		for _, f := range installed.ExpectedFiles(client.AllLanguages, "all", true, true) {
+			old[installedKey(f.Component, f.Language, f.Platform, f.Name)] = f
+		}
+	}
+	versions := make(map[string]client.ExpectedFile, len(expected))
+	for _, f := range expected {
+		versions[installedKey(f.Component, f.Language, f.Platform, f.Name)] = f
+	}
+
+	add := func(e AuditEntry, found bool) {
+		f := InstalledFile{Name: e.Name, Component: e.Component, Platform: e.Platform, Language: e.Language,
+			Path: e.Path, ExpectedSize: e.ExpectedSize, ActualSize: e.ActualSize}
+		key := installedKey(e.Component, e.Language, e.Platform, e.Name)
+		exp := versions[key]
+		f.ExpectedVersion = exp.Version
+		o, known := old[key]
+		if known {
+			f.InstalledVersion = o.Version
+		}
+		changed := known && (o.Version != exp.Version || (exp.Version == "" && o.Size != exp.Size))
+		switch {
+		case changed:
+			f.Status = InstalledOutdated
+		case !found:
+			f.Status = InstalledMissing
+		case e.ExpectedSize > 0 && !withinTolerance(e.ExpectedSize, e.ActualSize):
+			f.Status = InstalledIncomplete
+		default:
+			f.Status = InstalledUpToDate
+		}
+		report.Files = append(report.Files, f)
+	}
+	for _, e := range audit.Matched {
+		add(e, true)
+	}
+	for _, e := range audit.SizeMismatches {
+		add(e, true)
+	}
+	for _, e := range audit.Missing {
+		add(e, false)
+	}
+	for _, e := range audit.Unexpected {
+		report.Files = append(report.Files, InstalledFile{Status: InstalledUnexpected, Path: e.Path, ActualSize: e.ActualSize})
+	}
+
+	sort.SliceStable(report.Files, func(i, j int) bool {
+		a, b := report.Files[i], report.Files[j]
+		if a.Status != b.Status {
+			return installedStateOrder[a.Status] < installedStateOrder[b.Status]
+		}
+		if a.Component != b.Component {
+			return a.Component < b.Component
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Path < b.Path
+	})
+	return report, nil
+}
+
+// installedKey identifies an expected file of a game. Extras have no language or platform.
+func installedKey(component, language, platform, name string) string {
+	return component + "|" + language + "|" + platform + "|" + name
+}
@@ -0,0 +1,65 @@
+package operations_test
+
+import (
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func installedTestGame(version string, extras ...client.Extra) client.Game {
+	url := "/downloads/game/en1installer0"
+	return client.Game{
+		Title: "Game",
+		Downloads: []client.Downloadable{{Language: "English", Platforms: client.Platform{
+			Windows: []client.PlatformFile{{Name: "Game", Version: &version, Size: "10 MB", ManualURL: &url}},
+		}}},
+		Extras: extras,
+	}
+}
+
+func TestDiffInstalled(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, dir, "setup_game_1.0_(100).exe", 10*mib)
+	writeSizedFile(t, dir, "extras/manual.pdf", 2*mib)
+	writeSizedFile(t, dir, "extras/old_notes.txt", 100)
+
+	manual := client.Extra{Name: "Manual", Size: "2 MB", ManualURL: "/downloads/game/manual.pdf"}
+	soundtrack := client.Extra{Name: "Soundtrack", Size: "3 MB", ManualURL: "/downloads/game/soundtrack.zip"}
+	installed := installedTestGame("1.0", manual)
+	current := installedTestGame("1.1", manual, soundtrack)
+
+	report, err := operations.DiffInstalled(dir, current, &installed, "English", "windows", true, false)
+	require.NoError(t, err)
+
+	require.Len(t, report.Files, 4)
+	assert.Equal(t, operations.InstalledFile{Status: operations.InstalledMissing, Name: "Soundtrack", Component: "Game",
+		ExpectedSize: 3 * mib}, report.Files[0])
+	outdated := report.Files[1]
+	assert.Equal(t, operations.InstalledOutdated, outdated.Status)
+	assert.Equal(t, "1.0", outdated.InstalledVersion)
+	assert.Equal(t, "1.1", outdated.ExpectedVersion)
+	assert.Equal(t, "setup_game_1.0_(100).exe", outdated.Path)
+	assert.Equal(t, "windows", outdated.Platform)
+	assert.Equal(t, operations.InstalledUnexpected, report.Files[2].Status)
+	assert.Equal(t, "extras/old_notes.txt", report.Files[2].Path)
+	assert.Equal(t, operations.InstalledUpToDate, report.Files[3].Status)
+	assert.Equal(t, "extras/manual.pdf", report.Files[3].Path)
+
+	assert.Equal(t, 1, report.Count(operations.InstalledOutdated))
+	assert.False(t, report.UpToDate())
+}
+
+func TestDiffInstalled_WithoutMetadata(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, dir, "setup_game_1.1_(200).exe", 10*mib)
+
+	report, err := operations.DiffInstalled(dir, installedTestGame("1.1"), nil, "English", "windows", true, true)
+	require.NoError(t, err)
+	require.Len(t, report.Files, 1)
+	assert.Equal(t, operations.InstalledUpToDate, report.Files[0].Status)
+	assert.Empty(t, report.Files[0].InstalledVersion, "the installed version is not known without metadata.json")
+	assert.True(t, report.UpToDate())
+}
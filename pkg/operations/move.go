@@ -0,0 +1,96 @@
+package operations
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// MoveDir moves the contents of src into dst, merging with (and overwriting) any files already in dst.
+// Directories created in dst get the permissions of their counterparts in src.
+// Each file is renamed when possible; if the rename fails, for example because src and dst are on
+// different devices, the file is copied, its size verified, and the original removed.
+// src is removed once all files have been moved.
+func MoveDir(src, dst string) error {
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return mkdirLike(path, target)
+		}
+		return moveFile(path, target)
+	})
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// mkdirLike creates dst with the permissions of the directory src if dst does not exist yet.
+func mkdirLike(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chmod(dst, info.Mode().Perm())
+}
+
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if n != info.Size() {
+		return fmt.Errorf("size mismatch copying %s: wrote %d of %d bytes", src, n, info.Size())
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,38 @@
+package operations_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveDir_MergesIntoExistingDestination(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "game")
+	dst := filepath.Join(t.TempDir(), "game")
+	writeFiles(t, src, "setup_game_1.1.exe", "extras/manual.pdf")
+	writeFiles(t, dst, "setup_game_1.0.exe", "extras/manual.pdf")
+	require.NoError(t, os.WriteFile(filepath.Join(src, "extras", "manual.pdf"), []byte("new manual"), 0600))
+
+	require.NoError(t, operations.MoveDir(src, dst))
+
+	assert.ElementsMatch(t, []string{
+		"setup_game_1.0.exe",
+		"setup_game_1.1.exe",
+		"extras/manual.pdf",
+	}, remainingFiles(t, dst))
+	data, err := os.ReadFile(filepath.Join(dst, "extras", "manual.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, "new manual", string(data))
+
+	_, err = os.Stat(src)
+	assert.True(t, os.IsNotExist(err), "source directory should be removed")
+}
+
+func TestMoveDir_MissingSource(t *testing.T) {
+	err := operations.MoveDir(filepath.Join(t.TempDir(), "missing"), t.TempDir())
+	assert.Error(t, err)
+}
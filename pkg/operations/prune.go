@@ -0,0 +1,269 @@
+package operations
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/habedi/gogg/client"
+	"github.com/rs/zerolog/log"
+)
+
+// installerPattern matches GOG installer file names such as "setup_game_1.2.3_(12345).exe"
+// and their part files such as "setup_game_1.2.3_(12345)-1.bin".
+var installerPattern = regexp.MustCompile(`^(?P<prefix>.*?)(?P<ver>\d+(?:\.\d+)+)(?P<tail>(?:_\([^)]*\))*)(?:-(?P<part>\d+))?(?P<ext>\.tar\.gz|\.[^.]+)$`)
+
+// installerExtensions lists the file types considered for pruning, mapped to their installer family.
+// Windows installers ship as a single .exe plus optional .bin parts, so both belong to the same family.
+var installerExtensions = map[string]string{
+	".exe": ".exe", ".bin": ".exe", ".dmg": ".dmg", ".pkg": ".pkg", ".sh": ".sh",
+	".zip": ".zip", ".tar.gz": ".tar.gz", ".rar": ".rar",
+}
+
+// installerFile holds the parsed components of an installer file name.
+type installerFile struct {
+	path    string
+	prefix  string
+	version []int
+	setKey  string // version plus build tail; shared by all files of one installer set
+	family  string
+	isPart  bool
+}
+
+// installerSet groups the files that together make up one version of an installer.
+type installerSet struct {
+	version     []int
+	files       []string
+	hasMainFile bool
+}
+
+func parseInstallerFile(path string) (installerFile, bool) {
+	m := installerPattern.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return installerFile{}, false
+	}
+	family, ok := installerExtensions[strings.ToLower(m[5])]
+	if !ok {
+		return installerFile{}, false
+	}
+	var ver []int
+	for _, p := range strings.Split(m[2], ".") {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return installerFile{}, false
+		}
+		ver = append(ver, v)
+	}
+	return installerFile{
+		path:    path,
+		prefix:  m[1],
+		version: ver,
+		setKey:  m[2] + m[3],
+		family:  family,
+		isPart:  m[4] != "",
+	}, true
+}
+
+// compareVersions returns 1 if a > b, -1 if a < b, and 0 if they are equal.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		va, vb := 0, 0
+		if i < len(a) {
+			va = a[i]
+		}
+		if i < len(b) {
+			vb = b[i]
+		}
+		if va > vb {
+			return 1
+		}
+		if va < vb {
+			return -1
+		}
+	}
+	return 0
+}
+
+// NewestInstallers returns the installers of the newest version among files, leaving out patches.
+// Installers split into parts share a version, so every part of the newest version is returned.
+// Files without a version are only returned if none of the installers has one.
+func NewestInstallers(files []client.PlatformFile) []client.PlatformFile {
+	var newest []int
+	var installers []client.PlatformFile
+	for _, f := range files {
+		if client.IsPatchFile(f) {
+			continue
+		}
+		installers = append(installers, f)
+		if f.Version == nil {
+			continue
+		}
+		if v := parseCatalogueVersion(*f.Version); v != nil && (newest == nil || compareVersions(v, newest) > 0) {
+			newest = v
+		}
+	}
+	if newest == nil {
+		return installers
+	}
+	var kept []client.PlatformFile
+	for _, f := range installers {
+		if f.Version != nil && compareVersions(parseCatalogueVersion(*f.Version), newest) == 0 {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// Kinds of installers for PreferredInstallers.
+const (
+	InstallerOffline = "offline" // contains the whole game
+	InstallerOnline  = "online"  // downloads the game while it runs
+)
+
+// InstallerKinds lists the kinds of installers in the order they are shown to the user.
+var InstallerKinds = []string{InstallerOffline, InstallerOnline}
+
+// PreferredInstallers returns the installers of the given kind among files, leaving out patches. If there is no
+// installer of that kind, it returns all installers, so a game that offers only the other kind still gets one.
+func PreferredInstallers(files []client.PlatformFile, kind string) []client.PlatformFile {
+	var installers, preferred []client.PlatformFile
+	for _, f := range files {
+		if client.IsPatchFile(f) {
+			continue
+		}
+		installers = append(installers, f)
+		if client.IsOnlineInstaller(f) == (kind == InstallerOnline) {
+			preferred = append(preferred, f)
+		}
+	}
+	if len(preferred) == 0 {
+		return installers
+	}
+	return preferred
+}
+
+// FindOldVersionFiles walks root and returns the files that belong to installer sets older than
+// the newest one. Files are grouped by directory, name prefix, and installer family, and each
+// version (for example an .exe together with its .bin parts) is kept or removed as a whole.
+// A set that is missing its main file (only part files are present) is only kept if no complete set exists.
+func FindOldVersionFiles(root string) ([]string, error) {
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+
+	// product key -> set key -> set
+	products := make(map[string]map[string]*installerSet)
+	walkErr := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		f, ok := parseInstallerFile(path)
+		if !ok {
+			return nil
+		}
+		productKey := filepath.Dir(path) + "|" + f.prefix + "|" + f.family
+		sets, exists := products[productKey]
+		if !exists {
+			sets = make(map[string]*installerSet)
+			products[productKey] = sets
+		}
+		set, exists := sets[f.setKey]
+		if !exists {
+			set = &installerSet{version: f.version}
+			sets[f.setKey] = set
+		}
+		set.files = append(set.files, path)
+		if !f.isPart {
+			set.hasMainFile = true
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	var old []string
+	for _, sets := range products {
+		if len(sets) < 2 {
+			continue
+		}
+		var keep string
+		for key, set := range sets {
+			if keep == "" {
+				keep = key
+				continue
+			}
+			best := sets[keep]
+			if set.hasMainFile != best.hasMainFile {
+				if set.hasMainFile {
+					keep = key
+				}
+				continue
+			}
+			if c := compareVersions(set.version, best.version); c == 1 || (c == 0 && key > keep) {
+				keep = key
+			}
+		}
+		for key, set := range sets {
+			if key != keep {
+				old = append(old, set.files...)
+			}
+		}
+	}
+	sort.Strings(old)
+	return old, nil
+}
+
+// PrunePlan describes the files that would be removed by pruning old installer versions.
+type PrunePlan struct {
+	Files      []string
+	TotalBytes int64
+}
+
+// PlanPrune finds the old installer files under the given roots without removing anything.
+// Roots that do not exist are skipped.
+func PlanPrune(roots ...string) (PrunePlan, error) {
+	var plan PrunePlan
+	for _, root := range roots {
+		if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+			continue
+		}
+		files, err := FindOldVersionFiles(root)
+		if err != nil {
+			return PrunePlan{}, err
+		}
+		for _, f := range files {
+			if info, err := os.Stat(f); err == nil {
+				plan.TotalBytes += info.Size()
+			}
+		}
+		plan.Files = append(plan.Files, files...)
+	}
+	return plan, nil
+}
+
+// RemoveFiles deletes the given files and returns the ones that were actually removed.
+func RemoveFiles(files []string) []string {
+	removed := make([]string, 0, len(files))
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			log.Warn().Err(err).Str("file", f).Msg("Failed to remove file")
+			continue
+		}
+		removed = append(removed, f)
+	}
+	return removed
+}
+
+// PruneOldVersions removes all installer sets under root except the newest one for each installer.
+// It returns the list of files that were removed.
+func PruneOldVersions(root string) ([]string, error) {
+	files, err := FindOldVersionFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	return RemoveFiles(files), nil
+}
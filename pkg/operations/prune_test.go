@@ -0,0 +1,175 @@
+package operations_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0600))
+	}
+}
+
+func remainingFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var names []string
+	require.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	}))
+	return names
+}
+
+func TestPruneOldVersions_MultiPartInstallers(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"setup_the_witcher_3_4.04_(64bit)_(62012).exe",
+		"setup_the_witcher_3_4.04_(64bit)_(62012)-1.bin",
+		"setup_the_witcher_3_4.04_(64bit)_(62012)-2.bin",
+		"setup_the_witcher_3_4.04a_redkit_update_2_(64bit)_(72470).exe",
+		"setup_the_witcher_3_4.05_(64bit)_(78012).exe",
+		"setup_the_witcher_3_4.05_(64bit)_(78012)-1.bin",
+		"setup_the_witcher_3_4.05_(64bit)_(78012)-2.bin",
+		"setup_the_witcher_3_4.05_(64bit)_(78012)-3.bin",
+		"the_witcher_3_manual.pdf",
+	)
+
+	removed, err := operations.PruneOldVersions(dir)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "setup_the_witcher_3_4.04_(64bit)_(62012).exe"),
+		filepath.Join(dir, "setup_the_witcher_3_4.04_(64bit)_(62012)-1.bin"),
+		filepath.Join(dir, "setup_the_witcher_3_4.04_(64bit)_(62012)-2.bin"),
+	}, removed)
+	assert.ElementsMatch(t, []string{
+		"setup_the_witcher_3_4.04a_redkit_update_2_(64bit)_(72470).exe",
+		"setup_the_witcher_3_4.05_(64bit)_(78012).exe",
+		"setup_the_witcher_3_4.05_(64bit)_(78012)-1.bin",
+		"setup_the_witcher_3_4.05_(64bit)_(78012)-2.bin",
+		"setup_the_witcher_3_4.05_(64bit)_(78012)-3.bin",
+		"the_witcher_3_manual.pdf",
+	}, remainingFiles(t, dir))
+}
+
+func TestPruneOldVersions_LegacyNamesAndPlatforms(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"setup_game_1.2.3.exe",
+		"setup_game_1.2.3-1.bin",
+		"setup_game_1.10.0.exe",
+		"setup_game_1.10.0-1.bin",
+		"windows/setup_game_2.0.exe",
+		"linux/game_1.0.0.tar.gz",
+		"linux/game_1.1.0.tar.gz",
+	)
+
+	_, err := operations.PruneOldVersions(dir)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		"setup_game_1.10.0.exe",
+		"setup_game_1.10.0-1.bin",
+		"windows/setup_game_2.0.exe",
+		"linux/game_1.1.0.tar.gz",
+	}, remainingFiles(t, dir))
+}
+
+func TestFindOldVersionFiles_IncompleteNewestSetIsNotKept(t *testing.T) {
+	dir := t.TempDir()
+	// The newest version only has its part files (the main .exe is missing), so the
+	// complete older set must be kept instead of mixing versions.
+	writeFiles(t, dir,
+		"setup_game_1.0_(100).exe",
+		"setup_game_1.0_(100)-1.bin",
+		"setup_game_1.1_(200)-1.bin",
+	)
+
+	old, err := operations.FindOldVersionFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "setup_game_1.1_(200)-1.bin")}, old)
+}
+
+func TestFindOldVersionFiles_NonExistentDir(t *testing.T) {
+	_, err := operations.FindOldVersionFiles(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestNewestInstallers(t *testing.T) {
+	file := func(name, version, url string) client.PlatformFile {
+		f := client.PlatformFile{Name: name, ManualURL: &url}
+		if version != "" {
+			f.Version = &version
+		}
+		return f
+	}
+	names := func(files []client.PlatformFile) []string {
+		var out []string
+		for _, f := range files {
+			out = append(out, f.Name)
+		}
+		return out
+	}
+
+	files := []client.PlatformFile{
+		file("Game (Part 1 of 2)", "1.2.0 (gog-3)", "/downloads/game/en1installer0"),
+		file("Game (Part 2 of 2)", "1.2.0 (gog-3)", "/downloads/game/en1installer1"),
+		file("Game", "1.10.0", "/downloads/game/en1installer2"),
+		file("Patch 1.0 to 1.10", "1.10.0", "/downloads/game/en1patch0"),
+		file("Game (old)", "1.1", "/downloads/game/en1installer3"),
+	}
+	assert.Equal(t, []string{"Game"}, names(operations.NewestInstallers(files)))
+	assert.Equal(t, []string{"Game (Part 1 of 2)", "Game (Part 2 of 2)"}, names(operations.NewestInstallers(files[:2])))
+
+	unversioned := []client.PlatformFile{
+		file("Game", "", "/downloads/game/en1installer0"),
+		file("Patch", "", "/downloads/game/en1patch0"),
+	}
+	assert.Equal(t, []string{"Game"}, names(operations.NewestInstallers(unversioned)))
+	assert.Empty(t, operations.NewestInstallers(nil))
+}
+
+func TestPreferredInstallers(t *testing.T) {
+	file := func(name, url string) client.PlatformFile {
+		version := "1.0"
+		return client.PlatformFile{Name: name, ManualURL: &url, Version: &version}
+	}
+	names := func(files []client.PlatformFile) []string {
+		var out []string
+		for _, f := range files {
+			out = append(out, f.Name)
+		}
+		return out
+	}
+
+	// A game that offers its offline installer in two parts, a GOG Galaxy installer, and a patch.
+	files := []client.PlatformFile{
+		file("Game (Part 1 of 2)", "/downloads/game/en1installer0"),
+		file("Game (Part 2 of 2)", "/downloads/game/en1installer1"),
+		file("Game (Galaxy Installer)", "/downloads/game/en1installer2"),
+		file("Patch 0.9 to 1.0", "/downloads/game/en1patch0"),
+	}
+	assert.Equal(t, []string{"Game (Part 1 of 2)", "Game (Part 2 of 2)"},
+		names(operations.NewestInstallers(operations.PreferredInstallers(files, operations.InstallerOffline))))
+	assert.Equal(t, []string{"Game (Galaxy Installer)"},
+		names(operations.NewestInstallers(operations.PreferredInstallers(files, operations.InstallerOnline))))
+
+	// A game without an installer of the preferred kind gets the installers it has.
+	assert.Equal(t, []string{"Game (Part 1 of 2)", "Game (Part 2 of 2)"},
+		names(operations.PreferredInstallers(files[:2], operations.InstallerOnline)))
+	assert.Empty(t, operations.PreferredInstallers(files[3:], operations.InstallerOffline), "patches are left out")
+}
@@ -1,11 +1,14 @@
 package operations
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/habedi/gogg/client"
 	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/pool"
+	"github.com/habedi/gogg/pkg/validation"
 )
 
 // EstimationParams contains all parameters for calculating storage size.
@@ -31,15 +34,42 @@ func EstimateGameSize(gameID int, params EstimationParams) (int64, *client.Game,
 		return 0, nil, fmt.Errorf("failed to unmarshal game data for ID %d: %w", gameID, err)
 	}
 
-	langFullName, ok := client.GameLanguages[params.LanguageCode]
+	langFullName, ok := client.LanguageFilter(params.LanguageCode)
 	if !ok {
 		return 0, &nestedData, fmt.Errorf("invalid language code: %s", params.LanguageCode)
 	}
 
-	totalSizeBytes, err := nestedData.EstimateStorageSize(langFullName, params.PlatformName, params.IncludeExtras, params.IncludeDLCs)
+	totalSizeBytes, err := nestedData.EstimateStorageSize(langFullName, validation.ResolvePlatform(params.PlatformName), params.IncludeExtras, params.IncludeDLCs)
 	if err != nil {
 		return 0, &nestedData, fmt.Errorf("failed to calculate storage size: %w", err)
 	}
 
 	return totalSizeBytes, &nestedData, nil
 }
+
+// GameSize is the result of EstimateGameSizes for one game. Title is empty if the game could not be read.
+type GameSize struct {
+	ID    int
+	Title string
+	Bytes int64
+	Err   error
+}
+
+// EstimateGameSizes estimates the download sizes of the games with the given IDs like EstimateGameSize, using
+// numThreads workers. The results are in the order of gameIDs.
+func EstimateGameSizes(ctx context.Context, gameIDs []int, params EstimationParams, numThreads int) []GameSize {
+	results := make([]GameSize, len(gameIDs))
+	indexes := make([]int, len(gameIDs))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	_ = pool.Run(ctx, indexes, validation.ClampThreadCount(numThreads), func(_ context.Context, i int) error {
+		size, game, err := EstimateGameSize(gameIDs[i], params)
+		results[i] = GameSize{ID: gameIDs[i], Bytes: size, Err: err}
+		if game != nil {
+			results[i].Title = game.Title
+		}
+		return nil
+	})
+	return results
+}
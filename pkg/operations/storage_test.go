@@ -1,6 +1,7 @@
 package operations_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/habedi/gogg/db"
@@ -79,3 +80,22 @@ func TestEstimateGameSize(t *testing.T) {
 		assert.Contains(t, err.Error(), "game with ID 999 not found")
 	})
 }
+
+func TestEstimateGameSizes(t *testing.T) {
+	setupTestDB(t)
+	for id, size := range map[int]string{1: "1 GB", 2: "2 GB", 3: "512 MB"} {
+		raw := `{"title":"Game","downloads":[["English",{"windows":[{"name":"setup.exe","size":"` + size + `"}]}]]}`
+		require.NoError(t, db.PutInGame(id, "Game", raw))
+	}
+
+	params := operations.EstimationParams{LanguageCode: "en", PlatformName: "windows"}
+	results := operations.EstimateGameSizes(context.Background(), []int{3, 1, 999, 2}, params, 2)
+	require.Len(t, results, 4)
+	assert.Equal(t, []int{3, 1, 999, 2}, []int{results[0].ID, results[1].ID, results[2].ID, results[3].ID})
+	assert.Equal(t, int64(512<<20), results[0].Bytes)
+	assert.Equal(t, int64(1<<30), results[1].Bytes)
+	assert.Equal(t, "Game", results[1].Title)
+	assert.Error(t, results[2].Err)
+	assert.Empty(t, results[2].Title)
+	assert.Equal(t, int64(2<<30), results[3].Bytes)
+}
@@ -0,0 +1,196 @@
+package operations
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/habedi/gogg/client"
+	"github.com/habedi/gogg/pkg/hasher"
+)
+
+// Sources of the checksums that VerifyChecksums checks files against, from the most to the least trusted.
+const (
+	// ChecksumSourceGOG is a checksum file of GOG stored next to a file by 'download --download-checksums'.
+	ChecksumSourceGOG = "gog"
+	// ChecksumSourceSumfile is a line of a checksum file in the format of md5sum, like the CHECKSUMS.<algo> of
+	// 'download --write-checksums' or the checksums.<algo> of 'hash --output=sumfile'.
+	ChecksumSourceSumfile = "sumfile"
+	// ChecksumSourceSidecar is a <file>.<algo> file written by 'hash --output=sidecar'.
+	ChecksumSourceSidecar = "sidecar"
+)
+
+var checksumSourceRank = map[string]int{ChecksumSourceGOG: 0, ChecksumSourceSumfile: 1, ChecksumSourceSidecar: 2}
+
+// Statuses of a VerifyResult.
+const (
+	VerifyPassed   = "passed"
+	VerifyMismatch = "mismatch" // the file does not match its checksum
+	VerifyMissing  = "missing"  // a checksum file lists the file, but it does not exist
+	VerifyError    = "error"    // the file could not be read
+)
+
+// ExpectedChecksum is a file and the checksum it should have.
+type ExpectedChecksum struct {
+	Path   string
+	Algo   string
+	Sum    string
+	Source string // one of the ChecksumSource* sources
+}
+
+// VerifyResult is the result of checking a file against its checksum.
+type VerifyResult struct {
+	Path     string `json:"path"`
+	Source   string `json:"source"`
+	Algo     string `json:"algo"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// FindExpectedChecksums returns the checksums stored under root, sorted by path. A file with checksums from
+// several sources gets the one of the most trusted source.
+func FindExpectedChecksums(root string) ([]ExpectedChecksum, error) {
+	byPath := make(map[string]ExpectedChecksum)
+	add := func(e ExpectedChecksum) {
+		e.Sum = strings.ToLower(e.Sum)
+		if old, ok := byPath[e.Path]; !ok || checksumSourceRank[e.Source] < checksumSourceRank[old.Source] {
+			byPath[e.Path] = e
+		}
+	}
+
+	gogFiles, err := client.FindChecksumFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range gogFiles {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		sum, err := client.ReadChecksumFile(path)
+		if err != nil || sum == "" {
+			continue
+		}
+		add(ExpectedChecksum{Path: strings.TrimSuffix(path, client.ChecksumFileSuffix), Algo: "md5", Sum: sum, Source: ChecksumSourceGOG})
+	}
+
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name := d.Name()
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+		if !hasher.IsValidHashAlgo(ext) {
+			return nil
+		}
+		if strings.EqualFold(strings.TrimSuffix(name, filepath.Ext(name)), "checksums") {
+			sums, err := readSumfile(path)
+			if err != nil {
+				return nil
+			}
+			for rel, sum := range sums {
+				add(ExpectedChecksum{Path: filepath.Join(filepath.Dir(path), filepath.FromSlash(rel)), Algo: ext, Sum: sum, Source: ChecksumSourceSumfile})
+			}
+			return nil
+		}
+		file := strings.TrimSuffix(path, filepath.Ext(name))
+		if info, err := os.Stat(file); err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		sums, err := readSumfile(path)
+		if err != nil {
+			return nil
+		}
+		for _, sum := range sums {
+			add(ExpectedChecksum{Path: file, Algo: ext, Sum: sum, Source: ChecksumSourceSidecar})
+			break
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	expected := make([]ExpectedChecksum, 0, len(byPath))
+	for _, e := range byPath {
+		expected = append(expected, e)
+	}
+	sort.Slice(expected, func(i, j int) bool { return expected[i].Path < expected[j].Path })
+	return expected, nil
+}
+
+// readSumfile reads the lines of a checksum file in the format of md5sum, "<hash>  <path>" with an optional
+// '*' before the path, into the checksums by path.
+func readSumfile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		sum, file, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok {
+			continue
+		}
+		file = strings.TrimPrefix(strings.TrimLeft(file, " "), "*")
+		if sum != "" && file != "" {
+			sums[file] = sum
+		}
+	}
+	return sums, scanner.Err()
+}
+
+// VerifyChecksums hashes the files of expected, numThreads at a time, and compares them with their checksums.
+// The results are sorted by path. opts.Progress is called with the bytes of each file hashed so far. If ctx is
+// cancelled, the files checked until then are returned with the error of ctx.
+func VerifyChecksums(ctx context.Context, expected []ExpectedChecksum, numThreads int, opts HashOptions) ([]VerifyResult, error) {
+	results := make([]VerifyResult, 0, len(expected))
+	byAlgo := make(map[string][]string)
+	byPath := make(map[string]ExpectedChecksum, len(expected))
+	for _, e := range expected {
+		if _, err := os.Stat(e.Path); os.IsNotExist(err) {
+			results = append(results, VerifyResult{Path: e.Path, Source: e.Source, Algo: e.Algo, Expected: e.Sum, Status: VerifyMissing})
+			continue
+		}
+		byAlgo[e.Algo] = append(byAlgo[e.Algo], e.Path)
+		byPath[e.Path] = e
+	}
+
+	algos := make([]string, 0, len(byAlgo))
+	for algo := range byAlgo {
+		algos = append(algos, algo)
+	}
+	sort.Strings(algos)
+	for _, algo := range algos {
+		for res := range GenerateHashesWithOptions(ctx, byAlgo[algo], algo, numThreads, opts) {
+			e := byPath[res.File]
+			result := VerifyResult{Path: e.Path, Source: e.Source, Algo: e.Algo, Expected: e.Sum, Actual: res.Hash}
+			switch {
+			case res.Err != nil:
+				result.Status, result.Error = VerifyError, res.Err.Error()
+			case !strings.EqualFold(res.Hash, e.Sum):
+				result.Status = VerifyMismatch
+			default:
+				result.Status = VerifyPassed
+			}
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	return results, ctx.Err()
+}
+
+// CountVerifyResults returns how many of results have the given status.
+func CountVerifyResults(results []VerifyResult, status string) int {
+	n := 0
+	for _, r := range results {
+		if r.Status == status {
+			n++
+		}
+	}
+	return n
+}
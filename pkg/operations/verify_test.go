@@ -0,0 +1,69 @@
+package operations_test
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/habedi/gogg/pkg/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindExpectedChecksumsAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write("setup.exe", "installer")
+	write("patch.exe", "patch")
+	write("extras/manual.pdf", "manual")
+	write("extras/map.pdf", "map")
+
+	// GOG's checksum is preferred over the one of the download, which would not match.
+	write("setup.exe.xml", fmt.Sprintf(`<file name="setup.exe" md5="%x" total_size="9"/>`, md5.Sum([]byte("installer"))))
+	write("CHECKSUMS.sha256", fmt.Sprintf("%x  setup.exe\n%x  patch.exe\n%x  extras/manual.pdf\n%x  gone.exe\n",
+		sha256.Sum256([]byte("other")), sha256.Sum256([]byte("patch")), sha256.Sum256([]byte("corrupt")), sha256.Sum256([]byte("gone"))))
+	write("extras/map.pdf.md5", fmt.Sprintf("%x  map.pdf\n", md5.Sum([]byte("map"))))
+
+	expected, err := operations.FindExpectedChecksums(dir)
+	require.NoError(t, err)
+	sources := make(map[string]string)
+	for _, e := range expected {
+		rel, _ := filepath.Rel(dir, e.Path)
+		sources[filepath.ToSlash(rel)] = e.Source + "/" + e.Algo
+	}
+	assert.Equal(t, map[string]string{
+		"setup.exe":         "gog/md5",
+		"patch.exe":         "sumfile/sha256",
+		"extras/manual.pdf": "sumfile/sha256",
+		"extras/map.pdf":    "sidecar/md5",
+		"gone.exe":          "sumfile/sha256",
+	}, sources)
+
+	var tracker operations.HashProgressTracker
+	results, err := operations.VerifyChecksums(context.Background(), expected, 2, operations.HashOptions{
+		Progress: func(file string, n, _ int64) { tracker.Update(file, n) },
+	})
+	require.NoError(t, err)
+	statuses := make(map[string]string)
+	for _, r := range results {
+		rel, _ := filepath.Rel(dir, r.Path)
+		statuses[filepath.ToSlash(rel)] = r.Status
+	}
+	assert.Equal(t, map[string]string{
+		"setup.exe":         operations.VerifyPassed,
+		"patch.exe":         operations.VerifyPassed,
+		"extras/manual.pdf": operations.VerifyMismatch,
+		"extras/map.pdf":    operations.VerifyPassed,
+		"gone.exe":          operations.VerifyMissing,
+	}, statuses)
+	assert.Equal(t, 3, operations.CountVerifyResults(results, operations.VerifyPassed))
+	assert.Equal(t, int64(len("installer")+len("patch")+len("manual")+len("map")), tracker.Update("", 0))
+}
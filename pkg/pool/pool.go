@@ -2,18 +2,65 @@ package pool
 
 import (
 	"context"
+	"fmt"
+	"runtime/debug"
 	"sync"
 )
 
 // WorkerFunc defines the function signature for a worker that processes an item and may return an error.
 type WorkerFunc[T any] func(ctx context.Context, item T) error
 
-// Run executes a worker pool. It processes a slice of items concurrently.
-// It returns a slice containing any errors that occurred during processing.
+// Result is the outcome of one item processed by RunWithResults.
+type Result[T any] struct {
+	Item T
+	Err  error
+}
+
+// PanicError is the error of an item whose worker panicked, with the value it panicked with and the stack of
+// the worker at that point.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("worker panicked: %v\n%s", e.Value, e.Stack)
+}
+
+// runWorker runs workerFunc on item, turning a panic into a *PanicError, so that one bad item does not take
+// down the whole pool.
+func runWorker[T any](ctx context.Context, item T, workerFunc WorkerFunc[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return workerFunc(ctx, item)
+}
+
+// Run executes a worker pool. It processes a slice of items concurrently, with numWorkers workers, or one if
+// numWorkers is less than one.
+// It returns a slice containing any errors that occurred during processing, including a *PanicError for each
+// item whose worker panicked.
 func Run[T any](ctx context.Context, items []T, numWorkers int, workerFunc WorkerFunc[T]) []error {
+	var allErrors []error
+	for _, r := range RunWithResults(ctx, items, numWorkers, workerFunc) {
+		if r.Err != nil {
+			allErrors = append(allErrors, r.Err)
+		}
+	}
+	return allErrors
+}
+
+// RunWithResults is like Run, but returns the outcome of every item that was processed, in the order they
+// finished, so that the items that failed are known. Items that were not started because ctx was cancelled
+// are not included.
+func RunWithResults[T any](ctx context.Context, items []T, numWorkers int, workerFunc WorkerFunc[T]) []Result[T] {
+	// Without a worker, nothing would take the items and the pool would hang.
+	numWorkers = max(numWorkers, 1)
 	var wg sync.WaitGroup
 	taskChan := make(chan T, numWorkers)
-	errChan := make(chan error, len(items))
+	resultChan := make(chan Result[T], len(items))
 
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
@@ -24,9 +71,7 @@ func Run[T any](ctx context.Context, items []T, numWorkers int, workerFunc Worke
 				case <-ctx.Done():
 					return
 				default:
-					if err := workerFunc(ctx, item); err != nil {
-						errChan <- err
-					}
+					resultChan <- Result[T]{Item: item, Err: runWorker(ctx, item, workerFunc)}
 				}
 			}
 		}()
@@ -44,11 +89,11 @@ OUT:
 	close(taskChan)
 
 	wg.Wait()
-	close(errChan)
+	close(resultChan)
 
-	var allErrors []error
-	for err := range errChan {
-		allErrors = append(allErrors, err)
+	var results []Result[T]
+	for r := range resultChan {
+		results = append(results, r)
 	}
-	return allErrors
+	return results
 }
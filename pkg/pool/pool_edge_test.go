@@ -3,6 +3,7 @@ package pool
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -62,16 +63,47 @@ func TestRun_MoreWorkersThanItems(t *testing.T) {
 }
 
 func TestRun_ZeroWorkers(t *testing.T) {
-	t.Skip("Skipping zero workers test - causes deadlock by design")
-	// Zero workers means no goroutines to consume from the channel
-	// This would cause deadlock when trying to send items
-	// The pool package expects numWorkers >= 1
+	testRunWithTooFewWorkers(t, 0)
 }
 
 func TestRun_NegativeWorkers(t *testing.T) {
-	t.Skip("Skipping negative workers test - causes issues with goroutine creation")
-	// Negative workers would cause issues with the loop
-	// The pool package expects numWorkers >= 1
+	testRunWithTooFewWorkers(t, -3)
+}
+
+// testRunWithTooFewWorkers checks that Run processes every item with one worker when numWorkers is less than one.
+func testRunWithTooFewWorkers(t *testing.T, numWorkers int) {
+	t.Helper()
+	var processed, running, maxRunning int32
+	worker := func(ctx context.Context, item int) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&processed, 1)
+		return nil
+	}
+
+	done := make(chan []error)
+	go func() { done <- Run(context.Background(), []int{1, 2, 3, 4, 5}, numWorkers, worker) }()
+	select {
+	case errs := <-done:
+		if len(errs) != 0 {
+			t.Errorf("Expected no errors, got %d", len(errs))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run with %d workers did not return", numWorkers)
+	}
+	if processed != 5 {
+		t.Errorf("Expected 5 items to be processed, got %d", processed)
+	}
+	if maxRunning != 1 {
+		t.Errorf("Expected one worker, got %d running at once", maxRunning)
+	}
 }
 
 func TestRun_AllItemsReturnError(t *testing.T) {
@@ -112,10 +144,34 @@ func TestRun_MixedSuccessAndFailure(t *testing.T) {
 }
 
 func TestRun_WorkerPanic(t *testing.T) {
-	t.Skip("Skipping panic test - panics in goroutines can cause test hangs")
-	// Panics in worker goroutines would crash the goroutine
-	// and potentially leave the pool in an inconsistent state
-	// Testing panic recovery is complex and can hang tests
+	var processed int32
+	worker := func(ctx context.Context, item int) error {
+		if item == 3 {
+			panic("bad item")
+		}
+		atomic.AddInt32(&processed, 1)
+		return nil
+	}
+
+	items := []int{1, 2, 3, 4, 5}
+	errs := Run(context.Background(), items, 2, worker)
+
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d", len(errs))
+	}
+	var panicErr *PanicError
+	if !errors.As(errs[0], &panicErr) {
+		t.Fatalf("Expected a *PanicError, got %T", errs[0])
+	}
+	if panicErr.Value != "bad item" {
+		t.Errorf("Expected the panic value %q, got %v", "bad item", panicErr.Value)
+	}
+	if !strings.Contains(errs[0].Error(), "bad item") || len(panicErr.Stack) == 0 {
+		t.Errorf("Expected the error to have the panic value and the stack, got %q", errs[0].Error())
+	}
+	if processed != 4 {
+		t.Errorf("Expected the other 4 items to be processed, got %d", processed)
+	}
 }
 
 func TestRun_SlowWorkers(t *testing.T) {
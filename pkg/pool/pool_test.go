@@ -76,3 +76,24 @@ func TestPool_ContextCancellation(t *testing.T) {
 	// But it should be much less than the total number of items.
 	assert.Less(t, processedCount.Load(), int64(len(items)), "Pool should stop processing after context is cancelled")
 }
+
+func TestPool_RunWithResults(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6}
+	workerFunc := func(ctx context.Context, item int) error {
+		if item%2 == 0 {
+			return errors.New("even")
+		}
+		return nil
+	}
+
+	results := pool.RunWithResults(context.Background(), items, 3, workerFunc)
+
+	require.Len(t, results, len(items))
+	var failed []int
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r.Item)
+		}
+	}
+	assert.ElementsMatch(t, []int{2, 4, 6}, failed)
+}
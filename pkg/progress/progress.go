@@ -0,0 +1,106 @@
+// Package progress provides the byte formatting and the download speed and ETA estimates shared by the CLI and the GUI.
+package progress
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SampleInterval is the shortest interval the download speed is measured over.
+const SampleInterval = time.Second
+
+// Window is the number of speed samples averaged into the estimated speed.
+const Window = 5
+
+// FormatBytes converts a byte count into a human-readable string using binary units (KiB, MiB, GiB, ...).
+func FormatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// SpeedEstimator computes a smoothed download speed: the speed of each interval of at least SampleInterval,
+// averaged over the last Window intervals.
+type SpeedEstimator struct {
+	lastTime  time.Time
+	lastBytes int64
+	speeds    []float64
+}
+
+// Reset starts a new measurement at now with no bytes downloaded.
+func (s *SpeedEstimator) Reset(now time.Time) {
+	s.lastTime, s.lastBytes, s.speeds = now, 0, nil
+}
+
+// Update records that downloaded bytes were received by now. It returns the averaged speed in bytes per second
+// and true when a new sample was taken, or false if less than SampleInterval passed since the last one.
+func (s *SpeedEstimator) Update(now time.Time, downloaded int64) (float64, bool) {
+	elapsed := now.Sub(s.lastTime)
+	if elapsed < SampleInterval {
+		return 0, false
+	}
+	s.speeds = append(s.speeds, float64(downloaded-s.lastBytes)/elapsed.Seconds())
+	if len(s.speeds) > Window {
+		s.speeds = s.speeds[1:]
+	}
+	s.lastTime, s.lastBytes = now, downloaded
+
+	var total float64
+	for _, speed := range s.speeds {
+		total += speed
+	}
+	return total / float64(len(s.speeds)), true
+}
+
+// ETA returns the time left to download the remaining bytes at speed, rounded to the second.
+// It returns false if there is nothing left to download or the speed is not positive.
+func ETA(remaining int64, speed float64) (time.Duration, bool) {
+	if speed <= 0 || remaining <= 0 {
+		return 0, false
+	}
+	return time.Duration(math.Round(float64(remaining)/speed)) * time.Second, true
+}
+
+// FormatSpeedAndETA formats a download speed and, when it is known, the time left to download the remaining bytes.
+func FormatSpeedAndETA(speed float64, remaining int64) string {
+	text := fmt.Sprintf("Speed: %s/s", FormatBytes(int64(speed)))
+	if eta, ok := ETA(remaining, speed); ok {
+		text += fmt.Sprintf(" | ETA: %s", eta)
+	}
+	return text
+}
+
+var bandwidthRegexp = regexp.MustCompile(`^\s*([0-9]+(?:\.[0-9]+)?)\s*([kKmMgGtT]?)(i?)(bit|b|B)(?:ps|/s)?\s*$`)
+
+// ParseBandwidth parses a bandwidth like "50Mbps" or "100 Mbit/s", in bits per second with the powers of 1000
+// that connections are advertised in, or like "6MB/s", in bytes per second with the powers of 1024 of
+// FormatBytes, into bytes per second. The bandwidth must be positive.
+func ParseBandwidth(s string) (float64, error) {
+	m := bandwidthRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("%q is not a bandwidth like 50Mbps or 6MB/s", s)
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%q is not a positive bandwidth", s)
+	}
+	exp := 0.0
+	if m[2] != "" {
+		exp = float64(strings.Index("kmgt", strings.ToLower(m[2])) + 1)
+	}
+	if m[4] == "B" {
+		return value * math.Pow(1024, exp), nil
+	}
+	return value * math.Pow(1000, exp) / 8, nil
+}
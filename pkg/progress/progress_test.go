@@ -0,0 +1,113 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatBytes(t *testing.T) {
+	cases := []struct {
+		in   int64
+		want string
+	}{
+		{0, "0 B"},
+		{999, "999 B"},
+		{1024, "1.0 KiB"},
+		{1024*1024 + 512*1024, "1.5 MiB"},
+		{5 << 30, "5.0 GiB"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, FormatBytes(c.in), "FormatBytes(%d)", c.in)
+	}
+}
+
+func TestSpeedEstimator_SmoothsOverSamples(t *testing.T) {
+	const mib = 1 << 20
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var s SpeedEstimator
+	s.Reset(start)
+
+	// Updates within a second of the last sample are ignored.
+	_, ok := s.Update(start.Add(500*time.Millisecond), mib/2)
+	assert.False(t, ok)
+
+	// 1 MiB/s for three seconds, then 3 MiB/s.
+	downloaded := int64(0)
+	var speeds []float64
+	for i := 1; i <= 8; i++ {
+		if i <= 3 {
+			downloaded += mib
+		} else {
+			downloaded += 3 * mib
+		}
+		speed, ok := s.Update(start.Add(time.Duration(i)*time.Second), downloaded)
+		assert.True(t, ok)
+		speeds = append(speeds, speed)
+	}
+
+	assert.InDelta(t, mib, speeds[2], 1)
+	// The speed rises gradually instead of jumping to the new rate.
+	assert.InDelta(t, 1.5*mib, speeds[3], 1)
+	assert.InDelta(t, (1+1+1+3+3)*mib/5.0, speeds[4], 1)
+	// Once the window only holds samples at the new rate, the average matches it.
+	assert.InDelta(t, 3*mib, speeds[7], 1)
+}
+
+func TestSpeedEstimator_UsesElapsedTime(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var s SpeedEstimator
+	s.Reset(start)
+
+	speed, ok := s.Update(start.Add(4*time.Second), 4000)
+	assert.True(t, ok)
+	assert.InDelta(t, 1000, speed, 0.001)
+}
+
+func TestETA(t *testing.T) {
+	eta, ok := ETA(100<<20, 1<<20)
+	assert.True(t, ok)
+	assert.Equal(t, 100*time.Second, eta)
+
+	eta, ok = ETA(1500, 1000)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, eta, "rounded to the nearest second")
+
+	_, ok = ETA(1000, 0)
+	assert.False(t, ok)
+	_, ok = ETA(0, 1000)
+	assert.False(t, ok)
+}
+
+func TestFormatSpeedAndETA(t *testing.T) {
+	assert.Equal(t, "Speed: 1.0 MiB/s | ETA: 1m40s", FormatSpeedAndETA(1<<20, 100<<20))
+	assert.Equal(t, "Speed: 512 B/s | ETA: 2s", FormatSpeedAndETA(512, 1000))
+	assert.Equal(t, "Speed: 0 B/s", FormatSpeedAndETA(0, 1000))
+	assert.Equal(t, "Speed: 2.0 KiB/s", FormatSpeedAndETA(2048, 0))
+}
+
+func TestParseBandwidth(t *testing.T) {
+	cases := []struct {
+		in   string
+		want float64
+	}{
+		{"50Mbps", 50e6 / 8},
+		{"100 Mbit/s", 100e6 / 8},
+		{"1gbps", 1e9 / 8},
+		{"800bps", 100},
+		{"6MB/s", 6 << 20},
+		{"1.5 GiB/s", 1.5 * (1 << 30)},
+		{"512KB", 512 << 10},
+	}
+	for _, c := range cases {
+		got, err := ParseBandwidth(c.in)
+		if assert.NoError(t, err, c.in) {
+			assert.InDelta(t, c.want, got, 1e-6, c.in)
+		}
+	}
+	for _, in := range []string{"", "fast", "50", "0Mbps", "-5MB/s", "5 XB/s"} {
+		_, err := ParseBandwidth(in)
+		assert.Error(t, err, in)
+	}
+}
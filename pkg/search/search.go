@@ -0,0 +1,80 @@
+// Package search ranks the games of the catalogue by how well they match a query, the same way for the CLI and
+// the GUI.
+package search
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/habedi/gogg/db"
+)
+
+// Match is how well a game matches a query. A lower Match is a better one.
+type Match int
+
+// Matches from the best to the worst.
+const (
+	MatchID         Match = iota // the query is the ID of the game
+	MatchPrefix                  // the title starts with the query
+	MatchWordPrefix              // a word of the title after the first starts with the query
+	MatchSubstring               // the title contains the query
+	NoMatch
+)
+
+// Score returns how well the game with the given title and ID matches query. Titles are matched ignoring case
+// and the spaces around query. IDs match only as a whole, so "194" does not match the game with ID 1942.
+func Score(query, title string, id int) Match {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return NoMatch
+	}
+	if n, err := strconv.Atoi(query); err == nil && n == id {
+		return MatchID
+	}
+	title = strings.ToLower(title)
+	switch i := strings.Index(title, query); {
+	case i < 0:
+		return NoMatch
+	case i == 0:
+		return MatchPrefix
+	}
+	prev := ' '
+	for i, r := range title {
+		if isWordRune(r) && !isWordRune(prev) && strings.HasPrefix(title[i:], query) {
+			return MatchWordPrefix
+		}
+		prev = r
+	}
+	return MatchSubstring
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Games returns the games that match query, the best matches first. Games that match equally well keep their
+// order in games, so a list sorted by title stays sorted within each kind of match. An empty query matches
+// every game.
+func Games(query string, games []db.Game) []db.Game {
+	if strings.TrimSpace(query) == "" {
+		return games
+	}
+	type scored struct {
+		game  db.Game
+		match Match
+	}
+	var matches []scored
+	for _, g := range games {
+		if m := Score(query, g.Title, g.ID); m != NoMatch {
+			matches = append(matches, scored{g, m})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].match < matches[j].match })
+	result := make([]db.Game, len(matches))
+	for i, m := range matches {
+		result[i] = m.game
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/habedi/gogg/db"
+	"github.com/habedi/gogg/pkg/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		query, title string
+		id           int
+		want         search.Match
+	}{
+		{"1942", "Strike Force", 1942, search.MatchID},
+		{" 1942 ", "Battle of 1942", 50, search.MatchWordPrefix},
+		{"194", "Strike Force", 1942, search.NoMatch},
+		{"51", "Battle of 1942", 50, search.NoMatch},
+		{"witcher", "Witcher 2", 2, search.MatchPrefix},
+		{"WITCHER", "The Witcher 3: Wild Hunt", 3, search.MatchWordPrefix},
+		{"wild", "The Witcher 3: Wild Hunt", 3, search.MatchWordPrefix},
+		{"itch", "The Witcher 3: Wild Hunt", 3, search.MatchSubstring},
+		{"ведьм", "Ведьмак", 4, search.MatchPrefix},
+		{"hunt", "Witcher 3:Hunt", 5, search.MatchWordPrefix},
+		{"", "Anything", 6, search.NoMatch},
+		{"doom", "Quake", 7, search.NoMatch},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, search.Score(tt.query, tt.title, tt.id), "%q in %q", tt.query, tt.title)
+	}
+}
+
+func TestGames_RanksMatches(t *testing.T) {
+	games := []db.Game{
+		{ID: 1, Title: "Alpha Witcher Tales"},
+		{ID: 2, Title: "Switcher"},
+		{ID: 3, Title: "The Witcher"},
+		{ID: 4, Title: "Witcher 2"},
+		{ID: 5, Title: "Quake"},
+		{ID: 6, Title: "Witcher 3"},
+	}
+	titles := func(games []db.Game) []string {
+		var out []string
+		for _, g := range games {
+			out = append(out, g.Title)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"Witcher 2", "Witcher 3", "Alpha Witcher Tales", "The Witcher", "Switcher"},
+		titles(search.Games("witcher", games)), "prefix, then word prefix, then substring, each in the given order")
+
+	byID := []db.Game{{ID: 7, Title: "Answer 42"}, {ID: 42, Title: "Strike Force"}, {ID: 8, Title: "Quake"}}
+	assert.Equal(t, []string{"Strike Force", "Answer 42"}, titles(search.Games("42", byID)), "the ID match comes first")
+	assert.Len(t, search.Games("  ", byID), 3, "an empty query matches every game")
+}
@@ -2,6 +2,10 @@ package validation
 
 import (
 	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -9,6 +13,7 @@ const (
 	MaxThreads = 20
 )
 
+// ValidateThreadCount checks that threads is within MinThreads and MaxThreads.
 func ValidateThreadCount(threads int) error {
 	if threads < MinThreads || threads > MaxThreads {
 		return fmt.Errorf("thread count must be between %d and %d, got %d", MinThreads, MaxThreads, threads)
@@ -16,6 +21,35 @@ func ValidateThreadCount(threads int) error {
 	return nil
 }
 
+// ClampThreadCount returns threads limited to the range accepted by ValidateThreadCount.
+func ClampThreadCount(threads int) int {
+	return min(max(threads, MinThreads), MaxThreads)
+}
+
+// Workload is a kind of work spread over a pool of workers, which decides the default number of workers.
+type Workload int
+
+const (
+	// LocalWorkload is work bound by the CPU and the disk, like hashing files or estimating download sizes from
+	// the catalogue.
+	LocalWorkload Workload = iota
+	// NetworkWorkload is work bound by requests to GOG, like downloading files.
+	NetworkWorkload
+)
+
+// DefaultNetworkThreads is the default number of workers for NetworkWorkload. It does not grow with the machine,
+// because more connections mostly get throttled by GOG.
+const DefaultNetworkThreads = 5
+
+// DefaultThreads returns the default number of workers for work of kind w: one per CPU for LocalWorkload, and
+// DefaultNetworkThreads for NetworkWorkload. The CLI and the GUI use it for the defaults of their thread counts.
+func DefaultThreads(w Workload) int {
+	if w == NetworkWorkload {
+		return DefaultNetworkThreads
+	}
+	return ClampThreadCount(runtime.NumCPU())
+}
+
 func ValidateGameID(id int) error {
 	if id <= 0 {
 		return fmt.Errorf("game ID must be a positive integer, got %d", id)
@@ -40,12 +74,47 @@ func ValidateLanguageCode(code string, validCodes map[string]string) error {
 func ValidatePlatform(platform string) error {
 	validPlatforms := map[string]bool{
 		"all":     true,
+		"auto":    true,
 		"windows": true,
 		"mac":     true,
 		"linux":   true,
 	}
 	if !validPlatforms[platform] {
-		return fmt.Errorf("invalid platform: %s (must be one of: all, windows, mac, linux)", platform)
+		return fmt.Errorf("invalid platform: %s (must be one of: all, auto, windows, mac, linux)", platform)
 	}
 	return nil
 }
+
+// ResolvePlatform returns the platform matching the current OS if platform is "auto", and platform otherwise.
+func ResolvePlatform(platform string) string {
+	if platform != "auto" {
+		return platform
+	}
+	return platformForOS(runtime.GOOS)
+}
+
+// platformForOS maps a GOOS value to a GOG platform. Systems GOG has no installers for get the Windows
+// installers, which was the default before "auto" existed.
+func platformForOS(goos string) string {
+	switch goos {
+	case "darwin":
+		return "mac"
+	case "linux":
+		return "linux"
+	default:
+		return "windows"
+	}
+}
+
+// ParseFileMode parses an octal permission like "0755" or "750". Only the permission bits are accepted.
+func ParseFileMode(s string) (os.FileMode, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0o"), "0O")
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || s == "" {
+		return 0, fmt.Errorf("invalid octal file mode %q", s)
+	}
+	if mode > 0o777 {
+		return 0, fmt.Errorf("file mode %#o has bits other than the permissions", mode)
+	}
+	return os.FileMode(mode), nil
+}
@@ -1,6 +1,8 @@
 package validation
 
 import (
+	"os"
+	"runtime"
 	"testing"
 )
 
@@ -81,6 +83,7 @@ func TestValidatePlatform(t *testing.T) {
 		{"windows", "windows", false},
 		{"mac", "mac", false},
 		{"linux", "linux", false},
+		{"auto", "auto", false},
 		{"invalid", "ios", true},
 		{"empty", "", true},
 	}
@@ -94,3 +97,72 @@ func TestValidatePlatform(t *testing.T) {
 		})
 	}
 }
+
+func TestResolvePlatform(t *testing.T) {
+	for _, p := range []string{"all", "windows", "mac", "linux"} {
+		if got := ResolvePlatform(p); got != p {
+			t.Errorf("ResolvePlatform(%q) = %q, want it unchanged", p, got)
+		}
+	}
+	if got := ResolvePlatform("auto"); got != platformForOS(runtime.GOOS) {
+		t.Errorf("ResolvePlatform(\"auto\") = %q, want %q", got, platformForOS(runtime.GOOS))
+	}
+
+	tests := map[string]string{"windows": "windows", "darwin": "mac", "linux": "linux", "freebsd": "windows"}
+	for goos, want := range tests {
+		if got := platformForOS(goos); got != want {
+			t.Errorf("platformForOS(%q) = %q, want %q", goos, got, want)
+		}
+	}
+}
+
+func TestClampThreadCount(t *testing.T) {
+	tests := map[int]int{-3: MinThreads, 0: MinThreads, 1: 1, 10: 10, MaxThreads: MaxThreads, 64: MaxThreads}
+	for in, want := range tests {
+		if got := ClampThreadCount(in); got != want {
+			t.Errorf("ClampThreadCount(%d) = %d, want %d", in, got, want)
+		}
+		if err := ValidateThreadCount(ClampThreadCount(in)); err != nil {
+			t.Errorf("ClampThreadCount(%d) is not a valid thread count: %v", in, err)
+		}
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"0755", 0o755, false},
+		{"750", 0o750, false},
+		{"0o640", 0o640, false},
+		{"0", 0, false},
+		{"", 0, true},
+		{"0789", 0, true},
+		{"rwxr-xr-x", 0, true},
+		{"4755", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseFileMode(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFileMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFileMode(%q) = %#o, want %#o", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultThreads(t *testing.T) {
+	if got := DefaultThreads(NetworkWorkload); got != DefaultNetworkThreads {
+		t.Errorf("DefaultThreads(NetworkWorkload) = %d, want %d", got, DefaultNetworkThreads)
+	}
+	want := min(max(runtime.NumCPU(), MinThreads), MaxThreads)
+	if got := DefaultThreads(LocalWorkload); got != want {
+		t.Errorf("DefaultThreads(LocalWorkload) = %d, want %d", got, want)
+	}
+}